@@ -0,0 +1,154 @@
+// Package clock provides the current time and timers through an
+// interface, so that code that waits on time can be tested with a
+// Fake clock, advanced deterministically, instead of by sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers.
+type Clock interface {
+	Now() time.Time
+
+	// After waits for d to elapse and then
+	// sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTicker returns a Ticker that sends the
+	// current time on its channel every d.
+	NewTicker(d time.Duration) *Ticker
+}
+
+// A Ticker holds a channel that delivers ticks of a Clock at
+// intervals. As with time.Ticker, it drops ticks for a slow receiver.
+type Ticker struct {
+	C    <-chan time.Time
+	stop func()
+}
+
+// Stop turns off t. It does not close t.C.
+func (t *Ticker) Stop() { t.stop() }
+
+// Real is the Clock of the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) *Ticker {
+	t := time.NewTicker(d)
+	return &Ticker{C: t.C, stop: t.Stop}
+}
+
+// Fake is a Clock whose time changes only when
+// its Advance method is called. It is safe to use
+// from multiple goroutines.
+type Fake struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration // nonzero for tickers
+	c      chan time.Time
+}
+
+// NewFake returns a Fake clock set to t.
+func NewFake(t time.Time) *Fake {
+	f := &Fake{now: t}
+	f.cond.L = &f.mu
+	return f
+}
+
+// Now returns f's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives f's time
+// once Advance has moved it forward by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	f.add(&waiter{at: f.Now().Add(d), c: c})
+	return c
+}
+
+// NewTicker returns a Ticker that ticks each time
+// Advance moves f's time past a multiple of d.
+func (f *Fake) NewTicker(d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	c := make(chan time.Time, 1)
+	w := &waiter{at: f.Now().Add(d), period: d, c: c}
+	f.add(w)
+	return &Ticker{C: c, stop: func() { f.remove(w) }}
+}
+
+func (f *Fake) add(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+func (f *Fake) remove(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves f's time forward by d, firing, in order,
+// the timers and ticks that come due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := f.now.Add(d)
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool {
+			return f.waiters[i].at.Before(f.waiters[j].at)
+		})
+		if len(f.waiters) == 0 || f.waiters[0].at.After(end) {
+			break
+		}
+		w := f.waiters[0]
+		f.now = w.at
+		select {
+		case w.c <- w.at:
+		default: // drop the tick, as time.Ticker does
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil waits until at least n timers or
+// tickers are waiting on f. Tests use it to make
+// sure the code under test is waiting before
+// calling Advance.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
@@ -0,0 +1,81 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAfter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+	c := f.After(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-c:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	select {
+	case got := <-c:
+		want := start.Add(time.Second)
+		if !got.Equal(want) {
+			t.Errorf("fired at %s, want %s", got, want)
+		}
+	default:
+		t.Fatal("timer didn't fire")
+	}
+	got, want := f.Now(), start.Add(time.Second)
+	if !got.Equal(want) {
+		t.Errorf("Now() = %s, want %s", got, want)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(time.Unix(1000, 0))
+	tick := f.NewTicker(time.Second)
+
+	var n int
+	for i := 0; i < 3; i++ {
+		f.Advance(time.Second)
+		select {
+		case <-tick.C:
+			n++
+		default:
+		}
+	}
+	if n != 3 {
+		t.Errorf("got %d ticks, want 3", n)
+	}
+
+	// Ticks beyond the channel's buffer are dropped.
+	f.Advance(5 * time.Second)
+	<-tick.C
+	select {
+	case <-tick.C:
+		t.Error("got more than one tick for a slow receiver")
+	default:
+	}
+
+	tick.Stop()
+	f.Advance(time.Second)
+	select {
+	case <-tick.C:
+		t.Error("got a tick after Stop")
+	default:
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(time.Unix(1000, 0))
+	done := make(chan struct{})
+	go func() {
+		<-f.After(time.Minute)
+		close(done)
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	<-done
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"chain/core/fetch"
+	"chain/core/rpc"
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// importBatchSize is the number of blocks
+// import-blocks sends in each request.
+const importBatchSize = 100
+
+// exportBlocks writes the Core's blocks to a block file, for
+// import-blocks on a Core that can't reach the generator. If
+// the file already has blocks, it appends the ones after them,
+// so an interrupted export can be rerun to finish it, and a
+// later one extends the file.
+//
+// With -anonymize-salt-file, it writes blocks with their
+// reference data and asset definitions replaced by hashes salted
+// with the contents of the file, for sharing with support. See
+// fetch.Anonymizer. The salt is read from a file so that it
+// doesn't show up in the process list or shell history. To
+// extend an anonymized file, the blocks already in it are
+// anonymized again, and must come out the same.
+func exportBlocks(client *rpc.Client, args []string) {
+	const usage = "usage: corectl export-blocks [-start height] [-end height] [-anonymize-salt-file file] file"
+	var flags flag.FlagSet
+	start := flags.Uint64("start", 1, "first block `height` to export, if the file is new")
+	end := flags.Uint64("end", 0, "last block `height` to export (default the current height)")
+	saltFile := flags.String("anonymize-salt-file", "", "if set, anonymize blocks, hashing data with the secret salt in this `file`")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fatalln(usage)
+	}
+
+	var anon *fetch.Anonymizer
+	if *saltFile != "" {
+		salt, err := ioutil.ReadFile(*saltFile)
+		if err != nil {
+			fatalln("error:", err)
+		}
+		salt = bytes.TrimSpace(salt)
+		if len(salt) == 0 {
+			fatalln("error: empty salt file", *saltFile)
+		}
+		anon = fetch.NewAnonymizer(salt)
+	}
+
+	f, err := os.OpenFile(flags.Arg(0), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		fatalln("error:", err)
+	}
+
+	// Find where an earlier export left off.
+	var first, last *legacy.Block
+	if fi.Size() == 0 {
+		err = fetch.WriteBlockFileHeader(f)
+	} else {
+		err = fetch.ReadBlockFile(f, func(b *legacy.Block) error {
+			if first == nil {
+				first = b
+			}
+			last = b
+			return nil
+		})
+	}
+	if err != nil {
+		fatalln("error:", err)
+	}
+	next := *start
+	var lastHash *bc.Hash // of the Core's copy of the last block written
+	if last != nil {
+		next = last.Height + 1
+		h := last.Hash()
+		lastHash = &h
+	}
+	if last != nil && anon != nil {
+		// Anonymize the blocks in the file again, to pick up
+		// where the anonymizer left off.
+		var b *legacy.Block
+		for height := first.Height; height <= last.Height; height++ {
+			blocks := fetchBlocks(client, height, height)
+			if len(blocks) == 0 {
+				fatalln("error: the Core has no block", height)
+			}
+			b = blocks[0]
+			h := b.Hash()
+			lastHash = &h
+			err = anon.AnonymizeBlock(b)
+			if err != nil {
+				fatalln("error: anonymizing block", height, err)
+			}
+		}
+		if b.Hash() != last.Hash() {
+			fatalln("error: the file was not anonymized with this salt")
+		}
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		fatalln("error:", err)
+	}
+
+	firstNew := next
+	for *end == 0 || next <= *end {
+		blocks := fetchBlocks(client, next, *end)
+		if len(blocks) == 0 {
+			break
+		}
+		for _, b := range blocks {
+			h := b.Hash()
+			if b.Height != next || (lastHash != nil && b.PreviousBlockHash != *lastHash) {
+				fatalln("error: block", b.Height, "doesn't follow the last block in the file")
+			}
+			lastHash = &h
+			if anon != nil {
+				err = anon.AnonymizeBlock(b)
+				if err != nil {
+					fatalln("error: anonymizing block", next, err)
+				}
+			}
+			var buf bytes.Buffer
+			_, err = b.WriteTo(&buf)
+			if err != nil {
+				fatalln("error: encoding block", next, err)
+			}
+			err = fetch.WriteFileBlock(f, buf.Bytes())
+			if err != nil {
+				fatalln("error:", err)
+			}
+			next++
+		}
+		err = f.Sync()
+		if err != nil {
+			fatalln("error:", err)
+		}
+	}
+	if next == firstNew {
+		fmt.Println("no new blocks")
+		return
+	}
+	fmt.Printf("exported blocks %d-%d\n", firstNew, next-1)
+}
+
+// fetchBlocks returns a batch of the Core's blocks, starting at
+// height start and ending at or before height end, or the
+// current height if end is 0. It returns none if there are no
+// blocks after start.
+func fetchBlocks(client *rpc.Client, start, end uint64) []*legacy.Block {
+	req := map[string]uint64{"start_height": start, "end_height": end}
+	var resp struct {
+		Blocks []chainjson.HexBytes `json:"blocks"`
+	}
+	err := client.Call(context.Background(), "/export-blocks", req, &resp)
+	dieOnRPCError(err)
+	var blocks []*legacy.Block
+	for i, raw := range resp.Blocks {
+		b := new(legacy.Block)
+		err = b.Decode(raw)
+		if err != nil {
+			fatalln("error: decoding block", start+uint64(i), err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// importBlocks applies the blocks in a block file to the Core,
+// which validates each one as if fetched from the generator.
+// Blocks the Core already has are skipped, so an interrupted
+// import can be rerun to finish it.
+func importBlocks(client *rpc.Client, args []string) {
+	const usage = "usage: corectl import-blocks file"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fatalln("error:", err)
+	}
+	defer f.Close()
+
+	var info struct {
+		BlockHeight uint64 `json:"block_height"`
+	}
+	err = client.Call(context.Background(), "/info", nil, &info)
+	dieOnRPCError(err)
+	height := info.BlockHeight
+
+	var batch []*legacy.Block
+	send := func() {
+		req := map[string]interface{}{"blocks": batch}
+		var resp struct {
+			BlockHeight uint64 `json:"block_height"`
+		}
+		err := client.Call(context.Background(), "/import-blocks", req, &resp)
+		dieOnRPCError(err)
+		height = resp.BlockHeight
+		batch = batch[:0]
+	}
+	err = fetch.ReadBlockFile(f, func(b *legacy.Block) error {
+		// Send the Core's latest block too, so
+		// it can check the file matches its chain.
+		if b.Height < info.BlockHeight {
+			return nil
+		}
+		batch = append(batch, b)
+		if len(batch) == importBatchSize {
+			send()
+		}
+		return nil
+	})
+	if err != nil {
+		fatalln("error:", err)
+	}
+	if len(batch) > 0 {
+		send()
+	}
+	fmt.Println("block height", height)
+}
@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -19,13 +21,16 @@ import (
 	"chain/core"
 	"chain/core/accesstoken"
 	"chain/core/config"
+	"chain/core/genesis"
 	"chain/core/rpc"
 	"chain/crypto/ed25519"
+	chainjson "chain/encoding/json"
 	"chain/env"
 	"chain/errors"
 	"chain/generated/rev"
 	"chain/log"
 	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
 )
 
 // config vars
@@ -70,6 +75,11 @@ var commands = map[string]*command{
 	"rm":                   {rm},
 	"set":                  {set},
 	"wait":                 {wait},
+	"export-key":           {exportKey},
+	"import-key":           {importKey},
+	"rotate-keystore":      {rotateKeyStore},
+	"export-blocks":        {exportBlocks},
+	"import-blocks":        {importBlocks},
 }
 
 func main() {
@@ -115,6 +125,7 @@ func configGenerator(client *rpc.Client, args []string) {
 	var flags flag.FlagSet
 	maxIssuanceWindow := flags.Duration("w", 24*time.Hour, "the maximum issuance window `duration` for this generator")
 	flagK := flags.String("k", "", "local `pubkey` for signing blocks")
+	flagGenesis := flags.String("genesis", "", "genesis ceremony transcript `file` with the initial block to use")
 
 	flags.Usage = func() {
 		fmt.Println(usage)
@@ -170,7 +181,29 @@ func configGenerator(client *rpc.Client, args []string) {
 		BlockPub:            blockPub,
 	}
 
-	err = client.Call(context.Background(), "/configure", conf, nil)
+	req := struct {
+		*config.Config
+		InitialBlock *legacy.Block `json:"initial_block,omitempty"`
+	}{Config: conf}
+	if *flagGenesis != "" {
+		b, err := ioutil.ReadFile(*flagGenesis)
+		if err != nil {
+			fatalln("error:", err)
+		}
+		var t genesis.Transcript
+		err = json.Unmarshal(b, &t)
+		if err != nil {
+			fatalln("error: parsing genesis transcript:", err)
+		}
+		err = t.Verify()
+		if err != nil {
+			fatalln("error:", err)
+		}
+		req.InitialBlock = t.Proposal.InitialBlock
+		conf.MaxIssuanceWindowMs = t.Proposal.MaxIssuanceWindowMS
+	}
+
+	err = client.Call(context.Background(), "/configure", req, nil)
 	dieOnRPCError(err)
 
 	wait(client, nil)
@@ -192,6 +225,76 @@ func createBlockKeyPair(client *rpc.Client, args []string) {
 	fmt.Printf("%x\n", pub.Pub)
 }
 
+// exportKey prints a MockHSM key, encrypted under
+// the passphrase in $KEY_PASSPHRASE, for import-key.
+func exportKey(client *rpc.Client, args []string) {
+	const usage = "usage: corectl export-key [chain_kd|ed25519] [pubkey]"
+	if len(args) != 2 {
+		fatalln(usage)
+	}
+	pub, err := hex.DecodeString(args[1])
+	if err != nil {
+		fatalln("error: invalid pubkey:", err)
+	}
+	passphrase := os.Getenv("KEY_PASSPHRASE")
+	if passphrase == "" {
+		fatalln("error: KEY_PASSPHRASE must be set")
+	}
+
+	req := struct {
+		Type       string             `json:"type"`
+		Pub        chainjson.HexBytes `json:"pub"`
+		Passphrase string             `json:"passphrase"`
+	}{args[0], pub, passphrase}
+	var resp struct {
+		SealedKey json.RawMessage `json:"sealed_key"`
+	}
+	err = client.Call(context.Background(), "/mockhsm/export-key", req, &resp)
+	dieOnRPCError(err)
+	fmt.Println(string(resp.SealedKey))
+}
+
+// importKey reads a key from export-key on stdin and
+// adds it to the MockHSM, decrypting it with the
+// passphrase in $KEY_PASSPHRASE.
+func importKey(client *rpc.Client, args []string) {
+	if len(args) != 0 {
+		fatalln("error: import-key takes no args")
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fatalln("error: reading key:", err)
+	}
+	req := struct {
+		SealedKey  json.RawMessage `json:"sealed_key"`
+		Passphrase string          `json:"passphrase"`
+	}{bytes.TrimSpace(data), os.Getenv("KEY_PASSPHRASE")}
+	var resp struct {
+		Type string             `json:"type"`
+		Pub  chainjson.HexBytes `json:"pub"`
+	}
+	err = client.Call(context.Background(), "/mockhsm/import-key", req, &resp)
+	dieOnRPCError(err)
+	fmt.Printf("%s %x\n", resp.Type, resp.Pub)
+}
+
+// rotateKeyStore re-encrypts the MockHSM key store file
+// under the passphrase in $KEYSTORE_PASSPHRASE.
+func rotateKeyStore(client *rpc.Client, args []string) {
+	if len(args) != 0 {
+		fatalln("error: rotate-keystore takes no args")
+	}
+	passphrase := os.Getenv("KEYSTORE_PASSPHRASE")
+	if passphrase == "" {
+		fatalln("error: KEYSTORE_PASSPHRASE must be set")
+	}
+	req := struct {
+		Passphrase string `json:"passphrase"`
+	}{passphrase}
+	err := client.Call(context.Background(), "/mockhsm/rotate-keystore", req, nil)
+	dieOnRPCError(err)
+}
+
 func createToken(client *rpc.Client, args []string) {
 	const usage = "usage: corectl create-token [-net] [name] [policy]"
 	var flags flag.FlagSet
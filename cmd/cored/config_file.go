@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chain/core/config"
+	"chain/crypto/ed25519"
+	"chain/database/pg"
+	"chain/database/sinkdb"
+	"chain/env"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// readConfigFile reads the config file named with the -config
+// flag. It is a JSON object describing the node, such as
+//
+//	{
+//		"database_url": "postgres://core@db.example.com/core",
+//		"listen": ":1999",
+//		"index_transactions": true,
+//		"finality_pins": ["account", "asset", "tx"],
+//		"configure": {
+//			"is_signer": true,
+//			"generator_url": "https://generator.example.com:1999",
+//			"generator_access_token": "token:secret",
+//			"blockchain_id": "<hex>"
+//		}
+//	}
+//
+// Each key but "configure" sets the env var of the same name in
+// upper case; a variable set in the environment takes precedence.
+// The "configure" object, if any, is the Core's configuration, as
+// in a request to /configure. It configures an unconfigured Core
+// at startup, and must match the configuration of a configured
+// one. readConfigFile returns it, or nil if it's absent.
+func readConfigFile(path string) (*config.Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config file")
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(b, &raw)
+	serr, ok := err.(*json.SyntaxError)
+	if ok {
+		line := 1 + bytes.Count(b[:serr.Offset], []byte("\n"))
+		return nil, errors.Wrapf(err, "%s:%d", path, line)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	var conf *config.Config
+	confJSON, ok := raw["configure"]
+	if ok {
+		delete(raw, "configure")
+		conf = new(config.Config)
+		dec := json.NewDecoder(bytes.NewReader(confJSON))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(conf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: configure", path)
+		}
+		err = checkConfigure(conf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: configure", path)
+		}
+	}
+
+	settings := make(map[string]interface{})
+	for key, v := range raw {
+		var x interface{}
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		err = dec.Decode(&x)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: %s", path, key)
+		}
+		settings[key] = x
+	}
+	err = env.Load(settings)
+	return conf, errors.Wrap(err, path)
+}
+
+// checkConfigure checks that c describes a
+// role the Core can be configured with.
+func checkConfigure(c *config.Config) error {
+	var problems []string
+	switch {
+	case c.IsGenerator && c.GeneratorUrl != "":
+		problems = append(problems, "a generator must not have a generator_url")
+	case c.IsGenerator && c.BlockchainId != nil:
+		problems = append(problems, "a generator creates its blockchain, so must not have a blockchain_id")
+	case !c.IsGenerator && c.GeneratorUrl == "":
+		problems = append(problems, "generator_url is required unless is_generator is true")
+	case !c.IsGenerator && c.BlockchainId == nil:
+		problems = append(problems, "blockchain_id is required unless is_generator is true")
+	}
+	if len(c.BlockPub) > 0 && len(c.BlockPub) != ed25519.PublicKeySize {
+		problems = append(problems, fmt.Sprintf("block_pub must be %d bytes", ed25519.PublicKeySize))
+	}
+	if len(c.BlockPub) > 0 && !c.IsSigner {
+		problems = append(problems, "block_pub is only for a Core with is_signer true")
+	}
+	for i, s := range c.Signers {
+		_, err := url.Parse(s.Url)
+		if err != nil || s.Url == "" {
+			problems = append(problems, fmt.Sprintf("signers[%d] needs a valid url", i))
+		}
+		if len(s.Pubkey) != ed25519.PublicKeySize {
+			problems = append(problems, fmt.Sprintf("signers[%d] pubkey must be %d bytes", i, ed25519.PublicKeySize))
+		}
+	}
+	if len(c.Signers) > 0 && !c.IsGenerator {
+		problems = append(problems, "signers are only for a Core with is_generator true")
+	}
+	if c.Id != "" || c.ConfiguredAt != 0 || len(c.CostTableHash) > 0 {
+		problems = append(problems, "id, configured_at and cost_table_hash are set by the Core")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// configureFromFile configures an unconfigured Core with
+// fileConf, from the config file, and returns the result.
+func configureFromFile(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.Client, fileConf *config.Config) (*config.Config, error) {
+	c := *fileConf
+	if c.IsGenerator && c.MaxIssuanceWindowMs == 0 {
+		c.MaxIssuanceWindowMs = bc.DurationMillis(24 * time.Hour)
+	}
+	err := config.Configure(ctx, db, sdb, httpClient, &c, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring from config file")
+	}
+	return config.Load(ctx, db, sdb)
+}
+
+// checkConfigFile checks that the Core's configuration, conf,
+// has the role described by the config file, fileConf.
+func checkConfigFile(conf, fileConf *config.Config) error {
+	var problems []string
+	if conf.IsGenerator != fileConf.IsGenerator {
+		problems = append(problems, fmt.Sprintf("is_generator is %t, but the Core is configured with %t", fileConf.IsGenerator, conf.IsGenerator))
+	}
+	if conf.IsSigner != fileConf.IsSigner {
+		problems = append(problems, fmt.Sprintf("is_signer is %t, but the Core is configured with %t", fileConf.IsSigner, conf.IsSigner))
+	}
+	if conf.GeneratorUrl != fileConf.GeneratorUrl {
+		problems = append(problems, fmt.Sprintf("generator_url is %q, but the Core is configured with %q", fileConf.GeneratorUrl, conf.GeneratorUrl))
+	}
+	if fileConf.BlockchainId != nil && *fileConf.BlockchainId != *conf.BlockchainId {
+		problems = append(problems, fmt.Sprintf("blockchain_id is %x, but the Core is configured with %x", fileConf.BlockchainId.Bytes(), conf.BlockchainId.Bytes()))
+	}
+	if len(fileConf.BlockPub) > 0 && !bytes.Equal(fileConf.BlockPub, conf.BlockPub) {
+		problems = append(problems, fmt.Sprintf("block_pub is %x, but the Core is configured with %x", fileConf.BlockPub, conf.BlockPub))
+	}
+	if len(problems) > 0 {
+		return errors.New("config file does not match the Core's configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
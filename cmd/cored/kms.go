@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"chain/core/blocksigner"
+	"chain/errors"
+	chainlog "chain/log"
+)
+
+// kmsSigner returns a block signer for the cloud KMS keys listed
+// in spec, a comma-separated list, in order of preference, of
+//
+//	aws:<region>:<key id, ARN, or alias>
+//	gcp:<key version resource name>
+//
+// Every key must be a copy of the block signing key. AWS
+// credentials come from the usual environment variables, shared
+// credentials file, or instance role; GCP credentials come from
+// the instance's service account.
+func kmsSigner(ctx context.Context, spec string) *blocksigner.KMSSigner {
+	s := new(blocksigner.KMSSigner)
+	var sess *session.Session
+	for _, key := range strings.Split(spec, ",") {
+		parts := strings.SplitN(key, ":", 3)
+		switch {
+		case parts[0] == "aws" && len(parts) == 3:
+			if sess == nil {
+				var err error
+				sess, err = session.NewSession()
+				if err != nil {
+					chainlog.Fatalkv(ctx, chainlog.KeyError, errors.Wrap(err, "creating AWS session"))
+				}
+			}
+			s.Backends = append(s.Backends, &blocksigner.AWSKMS{
+				Region:      parts[1],
+				KeyID:       parts[2],
+				Credentials: sess.Config.Credentials,
+			})
+		case parts[0] == "gcp" && len(parts) >= 2:
+			s.Backends = append(s.Backends, &blocksigner.GCPKMS{
+				KeyVersion: strings.TrimPrefix(key, "gcp:"),
+			})
+		default:
+			chainlog.Fatalkv(ctx, chainlog.KeyError, errors.New("invalid BLOCK_SIGNER_KMS key "+key))
+		}
+	}
+	return s
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"encoding/hex"
 	"expvar"
 	"flag"
 	"fmt"
@@ -26,14 +27,17 @@ import (
 	"chain/core/accesstoken"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/eventlog"
 	"chain/core/generator"
 	"chain/core/migrate"
 	"chain/core/rpc"
 	"chain/core/txdb"
 	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/database/sqlutil"
+	"chain/encoding/bufpool"
 	"chain/env"
 	"chain/errors"
 	"chain/generated/rev"
@@ -46,6 +50,7 @@ import (
 	"chain/net/raft"
 	"chain/protocol"
 	"chain/protocol/bc"
+	"chain/protocol/state"
 )
 
 const (
@@ -58,8 +63,9 @@ var (
 	rootCAs       = env.String("ROOT_CA_CERTS", "") // file path
 	listenAddr    = env.String("LISTEN", ":1999")
 	dbURL         = env.String("DATABASE_URL", "postgres:///core?sslmode=disable")
-	splunkAddr    = os.Getenv("SPLUNKADDR")
-	logFile       = os.Getenv("LOGFILE")
+	queryDBURL    = env.String("QUERY_DATABASE_URL", "") // defaults to DATABASE_URL
+	splunkAddr    = env.String("SPLUNKADDR", "")
+	logFile       = env.String("LOGFILE", "")
 	logSize       = env.Int("LOGSIZE", 5e6) // 5MB
 	logCount      = env.Int("LOGCOUNT", 9)
 	logQueries    = env.Bool("LOG_QUERIES", false)
@@ -67,6 +73,34 @@ var (
 	rpsToken      = env.Int("RATELIMIT_TOKEN", 0)       // reqs/sec
 	rpsRemoteAddr = env.Int("RATELIMIT_REMOTE_ADDR", 0) // reqs/sec
 	indexTxs      = env.Bool("INDEX_TRANSACTIONS", true)
+	maxPending    = env.Int("MAX_PENDING_BLOCKS", 0)     // 0 means no limit
+	maxTxWeight   = env.Int("MAX_TX_WEIGHT", 0)          // 0 means no limit
+	callbackURL   = env.String("BLOCK_CALLBACK_URL", "") // this core's URL, as the generator reaches it
+	eventLogFile  = env.String("EVENT_LOG_FILE", "")
+	finalityPins  = env.String("FINALITY_PINS", "")              // comma-separated, e.g. "account,asset,tx"
+	approvalKey   = env.SecretString("SIGNING_APPROVAL_KEY", "") // if set, large txs need approval to sign
+	approvalLimit = env.Int("SIGNING_APPROVAL_THRESHOLD", 0)     // largest amount of an asset signed without approval
+	stateTreeDir  = env.String("STATE_TREE_DIR", "")             // if set, keep most of the state tree on disk here
+	keystoreFile  = env.String("KEYSTORE_FILE", "")              // if set, MockHSM keys are kept encrypted in this file
+	keystorePass  = env.SecretString("KEYSTORE_PASSPHRASE", "")
+	stmtTimeout   = env.Duration("STATEMENT_TIMEOUT", 30*time.Second)
+	indexTimeout  = env.Duration("INDEXER_STATEMENT_TIMEOUT", 10*time.Minute)
+	migrTimeout   = env.Duration("MIGRATION_STATEMENT_TIMEOUT", 0) // 0 means no timeout
+	canaryPeriod  = env.Duration("CANARY_PERIOD", 0)               // if set, run a canary transaction this often
+	signerPolicy  = env.String("SIGNER_POLICY_FILE", "")           // if set, block signing rules are read from this file
+	signerKMS     = env.String("BLOCK_SIGNER_KMS", "")             // if set, sign blocks with these cloud KMS keys; see kms.go
+	sha3MaxIdle   = env.Int("SHA3POOL_MAX_IDLE", 0)                // if set, keep at most this many idle SHA3 hashes
+	bufMaxRetain  = env.Int("BUFPOOL_MAX_RETAINED", 0)             // if set, pool no buffers larger than this many bytes
+	noChecks      = env.String("SUBMIT_CHECKS_DISABLED", "")       // comma-separated, e.g. "serialization,compliance"
+	shadowOf      = env.String("SHADOW_OF", "")                    // if set, run as a shadow of the production core at this URL
+	shadowToken   = env.SecretString("SHADOW_ACCESS_TOKEN", "")    // for calls to the production core
+	shadowPeriod  = env.Duration("SHADOW_PERIOD", time.Minute)     // how often a shadow compares results with production
+	reindexDelay  = env.Duration("REINDEX_BLOCK_DELAY", 0)         // pause after each block a reindex rebuilds
+	pendingFile   = env.String("PENDING_BLOCK_FILE", "")           // if set, the generator keeps its pending block here; single-process clusters only
+	washBlocks    = env.Int("WASH_DETECTION_BLOCKS", 0)            // if set, flag assets returning to an account within this many blocks
+	complianceURL = env.String("COMPLIANCE_CHECK_URL", "")         // if set, submitted txs must be approved by the service at this URL
+	complianceTok = env.SecretString("COMPLIANCE_CHECK_TOKEN", "") // for calls to the compliance service
+	blockVersion  = env.Int("BLOCK_VERSION", 1)                    // generators only; 2 or more lets blocks include replay-protected txs
 	home          = config.HomeDirFromEnvironment()
 
 	version string // initialized in init()
@@ -80,7 +114,7 @@ var (
 
 	// By default, a core is not able to reset its data.
 	// This feature can be turned on with the reset build tag.
-	resetIfAllowedAndRequested = func(pg.DB, *sinkdb.DB) {}
+	resetIfAllowedAndRequested = func(db, queryDB pg.DB, sdb *sinkdb.DB) {}
 
 	// See localhost_auth.go.
 	builtinGrants []*authz.Grant
@@ -110,6 +144,7 @@ func init() {
 
 func main() {
 	v := flag.Bool("version", false, "print version information")
+	configFile := flag.String("config", "", "read settings from this JSON `file`")
 	flag.Parse()
 
 	if !*v {
@@ -134,8 +169,20 @@ func main() {
 	maybeMonitorIfOnWindows() // special-case windows
 
 	ctx := context.Background()
+	var fileConf *config.Config
+	if *configFile != "" {
+		var err error
+		fileConf, err = readConfigFile(*configFile)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	}
 	env.Parse()
 	warnCompat(ctx)
+	sha3pool.SetMaxIdle(*sha3MaxIdle)
+	if *bufMaxRetain > 0 {
+		bufpool.SetMaxRetained(*bufMaxRetain)
+	}
 
 	listener, err := net.Listen("tcp", *listenAddr)
 	if err != nil {
@@ -186,6 +233,11 @@ func main() {
 	if *logQueries {
 		driver = sqlutil.LogDriver(driver)
 	}
+	driver = pg.TimeoutDriver(driver, pg.Timeouts{
+		Interactive: *stmtTimeout,
+		Indexer:     *indexTimeout,
+		Migration:   *migrTimeout,
+	})
 	sql.Register("coredpg", driver)
 	db, err := sql.Open("coredpg", *dbURL)
 	if err != nil {
@@ -194,11 +246,45 @@ func main() {
 	db.SetMaxOpenConns(*maxDBConns)
 	db.SetMaxIdleConns(*maxDBConns)
 
+	// A shadow core must have its own database, or its own
+	// schema, as with DATABASE_URL's search_path parameter.
+	// Check before migrating it, in case it's production's.
+	var shadowProdID string
+	if *shadowOf != "" {
+		shadowProdID, err = core.CheckShadowOf(ctx, &rpc.Client{
+			BaseURL:     *shadowOf,
+			AccessToken: *shadowToken,
+			Version:     version,
+			Client:      httpClient,
+		}, db)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	}
+
 	err = migrate.Run(db)
 	if err != nil {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
 
+	// The annotated query tables can live in a separate
+	// database, so query load doesn't compete with
+	// consensus-critical storage.
+	var queryDB pg.DB
+	if *queryDBURL != "" {
+		qdb, err := sql.Open("coredpg", *queryDBURL)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		qdb.SetMaxOpenConns(*maxDBConns)
+		qdb.SetMaxIdleConns(*maxDBConns)
+		err = migrate.Run(qdb)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, errors.Wrap(err, "migrating query database"))
+		}
+		queryDB = qdb
+	}
+
 	accessTokens := &accesstoken.CredentialStore{DB: db}
 
 	// We add handlers to our serve mux in two phases. In the first phase, we start
@@ -256,12 +342,29 @@ func main() {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
 
-	resetIfAllowedAndRequested(db, sdb)
+	resetIfAllowedAndRequested(db, queryDB, sdb)
+	initDevHSM(db)
 
 	conf, err := config.Load(ctx, db, sdb)
 	if err != nil && errors.Root(err) != raft.ErrUninitialized {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
+	switch {
+	case fileConf != nil && conf != nil:
+		err = checkConfigFile(conf, fileConf)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	case fileConf != nil && errors.Root(err) == raft.ErrUninitialized:
+		// The Core can't be configured until it has
+		// joined or initialized a raft cluster.
+		chainlog.Printf(ctx, "Not configuring from config file until the raft cluster is initialized.")
+	case fileConf != nil:
+		conf, err = configureFromFile(ctx, db, sdb, httpClient, fileConf)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	}
 	confOpts, err := core.Config(ctx, db, sdb)
 	if err != nil {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
@@ -283,12 +386,28 @@ func main() {
 	chainlog.SetPrefix(append([]interface{}{"app", "cored", "version", version, "processID", processID}, race...)...)
 	chainlog.SetOutput(logWriter())
 
+	settings := core.NodeSettings(*configFile, env.Values())
 	var h http.Handler
 	if conf != nil {
-		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, core.UseTLS(tlsConfig))
+		opts := []core.RunOption{core.UseTLS(tlsConfig), settings}
+		if queryDB != nil {
+			opts = append(opts, core.QueryDB(queryDB))
+		}
+		if *shadowOf != "" {
+			opts = append(opts, core.Shadow(&rpc.Client{
+				BaseURL:      *shadowOf,
+				AccessToken:  *shadowToken,
+				ProcessID:    processID,
+				CoreID:       conf.Id,
+				Version:      version,
+				BlockchainID: conf.BlockchainId.String(),
+				Client:       httpClient,
+			}, shadowProdID, *shadowPeriod))
+		}
+		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, opts...)
 	} else {
 		var opts []core.RunOption
-		opts = append(opts, core.UseTLS(tlsConfig))
+		opts = append(opts, core.UseTLS(tlsConfig), settings)
 		opts = append(opts, enableMockHSM(db)...)
 		chainlog.Printf(ctx, "Launching as unconfigured Core.")
 		h = core.RunUnconfigured(ctx, confOpts, db, sdb, *listenAddr, opts...)
@@ -334,14 +453,70 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
 	store := txdb.NewStore(db)
+	var diskStore *state.DiskStore
+	if *stateTreeDir != "" {
+		diskStore = state.NewDiskStore(*stateTreeDir)
+		store.DiskStore = diskStore
+	}
 	c, err := protocol.NewChain(ctx, *conf.BlockchainId, store, heights)
 	if err != nil {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
+	c.DiskStore = diskStore
+	c.Costs, err = config.CostTable(conf)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
+	costsHash := c.Costs.Hash()
+	costTableHash := hex.EncodeToString(costsHash[:])
 
 	var localSigner *blocksigner.BlockSigner
 
 	opts = append(opts, core.IndexTransactions(*indexTxs))
+	opts = append(opts, core.ReindexDelay(*reindexDelay))
+	if *washBlocks > 0 {
+		opts = append(opts, core.WashDetection(uint64(*washBlocks)))
+	}
+	if *eventLogFile != "" {
+		f, err := os.OpenFile(*eventLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		opts = append(opts, core.PublishEvents(eventlog.NewWriterPublisher(f)))
+	}
+	if *finalityPins != "" {
+		opts = append(opts, core.FinalityPins(strings.Split(*finalityPins, ",")...))
+	}
+	if *noChecks != "" {
+		known := make(map[string]bool)
+		for _, name := range core.SubmitChecks {
+			known[name] = !core.RequiredSubmitChecks[name]
+		}
+		names := strings.Split(*noChecks, ",")
+		for _, name := range names {
+			if !known[name] {
+				chainlog.Fatalkv(ctx, chainlog.KeyError, "cannot disable submit check "+name)
+			}
+		}
+		opts = append(opts, core.DisableSubmitChecks(names...))
+	}
+	if *approvalKey != "" {
+		opts = append(opts, core.SigningApproval([]byte(*approvalKey), uint64(*approvalLimit)))
+	}
+	if *canaryPeriod > 0 {
+		opts = append(opts, core.Canary(*canaryPeriod))
+	}
+	if *complianceURL != "" {
+		opts = append(opts, core.ComplianceCheck(core.RemoteComplianceCheck(&rpc.Client{
+			BaseURL:      *complianceURL,
+			AccessToken:  *complianceTok,
+			ProcessID:    processID,
+			CoreID:       conf.Id,
+			Version:      version,
+			BlockchainID: conf.BlockchainId.String(),
+			Client:       httpClient,
+		})))
+	}
 	opts = append(opts, enableMockHSM(db)...)
 	// Add any configured API request rate limits.
 	if *rpsToken > 0 {
@@ -354,6 +529,14 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 	if conf.IsSigner {
 		localSigner = initializeLocalSigner(ctx, confOpts, conf, db, c, processID, httpClient)
 		opts = append(opts, core.BlockSigner(localSigner.ValidateAndSignBlock))
+		if *signerPolicy != "" {
+			p, err := blocksigner.LoadPolicy(*signerPolicy)
+			if err != nil {
+				chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+			}
+			localSigner.Policy = p
+			opts = append(opts, core.BlockSignerPolicy(p))
+		}
 	}
 
 	// The Core is either configured as a generator or not. If it's configured
@@ -363,28 +546,49 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 	//
 	// If the Core is not a generator, provide an RPC client for the generator
 	// so that the Core can replicate blocks.
+	//
+	// A standby Core saves the pending block it takes over when
+	// promoted in the store its generator will use.
+	pendingStore := pendingFileStore{generator.NewFileBlockStore(*pendingFile), sdb}
+	if *pendingFile != "" {
+		opts = append(opts, core.PendingBlockStore(pendingStore))
+	}
 	if conf.IsGenerator {
 		var signers []generator.BlockSigner
 		if localSigner != nil {
 			signers = append(signers, localSigner)
 		}
-		for _, signer := range remoteSignerInfo(ctx, processID, conf.BlockchainId.String(), conf, httpClient) {
+		for _, signer := range remoteSignerInfo(ctx, processID, conf.BlockchainId.String(), costTableHash, conf, httpClient) {
 			signers = append(signers, signer)
 		}
 		c.MaxIssuanceWindow = bc.MillisDuration(conf.MaxIssuanceWindowMs)
+		c.BlockVersion = uint64(*blockVersion)
 
 		gen := generator.New(c, signers, db)
+		gen.MaxPendingBlocks = uint64(*maxPending)
+		gen.MaxTxWeight = int64(*maxTxWeight)
+		if *pendingFile != "" {
+			err = pendingStore.check()
+			if err != nil {
+				chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+			}
+			gen.PendingStore = pendingStore
+		}
 		opts = append(opts, core.GeneratorLocal(gen))
 	} else {
 		opts = append(opts, core.GeneratorRemote(&rpc.Client{
-			BaseURL:      conf.GeneratorUrl,
-			AccessToken:  conf.GeneratorAccessToken,
-			ProcessID:    processID,
-			CoreID:       conf.Id,
-			Version:      version,
-			BlockchainID: conf.BlockchainId.String(),
-			Client:       httpClient,
+			BaseURL:       conf.GeneratorUrl,
+			AccessToken:   conf.GeneratorAccessToken,
+			ProcessID:     processID,
+			CoreID:        conf.Id,
+			Version:       version,
+			BlockchainID:  conf.BlockchainId.String(),
+			CostTableHash: costTableHash,
+			Client:        httpClient,
 		}))
+		if *callbackURL != "" {
+			opts = append(opts, core.BlockCallback(*callbackURL))
+		}
 	}
 
 	// Start up the Core. This will start up the various Core subsystems,
@@ -398,7 +602,11 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 
 func initializeLocalSigner(ctx context.Context, confOpts *config.Options, conf *config.Config, db pg.DB, c *protocol.Chain, processID string, httpClient *http.Client) *blocksigner.BlockSigner {
 	var hsm blocksigner.Signer
-	hsm = mockHSM(db)
+	if *signerKMS != "" {
+		hsm = kmsSigner(ctx, *signerKMS)
+	} else {
+		hsm = mockHSM(db)
+	}
 
 	if hsm == nil {
 		hsm = &blocksigner.EnclaveClient{
@@ -417,7 +625,7 @@ func initializeLocalSigner(ctx context.Context, confOpts *config.Options, conf *
 	return s
 }
 
-func remoteSignerInfo(ctx context.Context, processID, blockchainID string, conf *config.Config, httpClient *http.Client) (a []*remoteSigner) {
+func remoteSignerInfo(ctx context.Context, processID, blockchainID, costTableHash string, conf *config.Config, httpClient *http.Client) (a []*remoteSigner) {
 	for _, signer := range conf.Signers {
 		u, err := url.Parse(signer.Url)
 		if err != nil {
@@ -427,13 +635,14 @@ func remoteSignerInfo(ctx context.Context, processID, blockchainID string, conf
 			chainlog.Fatalkv(ctx, chainlog.KeyError, errors.Wrap(err), "at", "decoding signer public key")
 		}
 		client := &rpc.Client{
-			BaseURL:      u.String(),
-			AccessToken:  signer.AccessToken,
-			ProcessID:    processID,
-			CoreID:       conf.Id,
-			Version:      version,
-			BlockchainID: blockchainID,
-			Client:       httpClient,
+			BaseURL:       u.String(),
+			AccessToken:   signer.AccessToken,
+			ProcessID:     processID,
+			CoreID:        conf.Id,
+			Version:       version,
+			BlockchainID:  blockchainID,
+			CostTableHash: costTableHash,
+			Client:        httpClient,
 		}
 		a = append(a, &remoteSigner{Client: client, Key: ed25519.PublicKey(signer.Pubkey)})
 	}
@@ -458,15 +667,15 @@ func (s *remoteSigner) String() string {
 
 func logWriter() io.Writer {
 	dropmsg := []byte("\nlog data dropped\n")
-	rotation := &errlog{w: rotation.Create(logFile, *logSize, *logCount)}
-	splunk := &errlog{w: splunk.New(splunkAddr, dropmsg)}
+	rotation := &errlog{w: rotation.Create(*logFile, *logSize, *logCount)}
+	splunk := &errlog{w: splunk.New(*splunkAddr, dropmsg)}
 
 	switch {
-	case logFile != "" && splunkAddr != "":
+	case *logFile != "" && *splunkAddr != "":
 		return io.MultiWriter(rotation, splunk)
-	case logFile != "" && splunkAddr == "":
+	case *logFile != "" && *splunkAddr == "":
 		return rotation
-	case logFile == "" && splunkAddr != "":
+	case *logFile == "" && *splunkAddr != "":
 		return splunk
 	}
 	return os.Stdout
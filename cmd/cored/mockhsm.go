@@ -3,21 +3,57 @@
 package main
 
 import (
+	"context"
+	"sync"
+
 	"chain/core"
 	"chain/core/blocksigner"
 	"chain/core/config"
 	"chain/core/mockhsm"
 	"chain/database/pg"
+	chainlog "chain/log"
 )
 
 func init() {
 	config.BuildConfig.MockHSM = true
 }
 
+var (
+	hsmOnce sync.Once
+	hsm     *mockhsm.HSM
+)
+
+// devHSM returns the process's one MockHSM. It keeps its
+// keys in the encrypted file KEYSTORE_FILE, if that is
+// set, and otherwise in db. Either way, block signing and
+// the key endpoints share it.
+func devHSM(db pg.DB) *mockhsm.HSM {
+	hsmOnce.Do(func() {
+		if *keystoreFile == "" {
+			hsm = mockhsm.New(db)
+		} else {
+			store, err := mockhsm.OpenFileStore(*keystoreFile, *keystorePass)
+			if err != nil {
+				chainlog.Fatalkv(context.Background(), chainlog.KeyError, err)
+			}
+			hsm = mockhsm.NewWithStore(store)
+		}
+		config.DevHSM = hsm
+	})
+	return hsm
+}
+
+// initDevHSM sets up the process's MockHSM before the Core is
+// configured, so the block-signing key that configuring may
+// create goes in the same key store that signs blocks.
+func initDevHSM(db pg.DB) {
+	devHSM(db)
+}
+
 func enableMockHSM(db pg.DB) []core.RunOption {
-	return []core.RunOption{core.MockHSM(mockhsm.New(db))}
+	return []core.RunOption{core.MockHSM(devHSM(db))}
 }
 
 func mockHSM(db pg.DB) blocksigner.Signer {
-	return mockhsm.New(db)
+	return devHSM(db)
 }
@@ -8,6 +8,8 @@ import (
 	"chain/database/pg"
 )
 
+func initDevHSM(pg.DB) {}
+
 func enableMockHSM(pg.DB) []core.RunOption {
 	return nil
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"chain/core/generator"
+	"chain/database/sinkdb"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+var errPendingFileCluster = errors.New("PENDING_BLOCK_FILE requires a single-process cluster")
+
+// pendingFileStore is the generator's BlockStore when
+// PENDING_BLOCK_FILE is set. Another process that took over
+// as generator couldn't read this process's file, so it
+// refuses to work once the raft cluster has other members.
+type pendingFileStore struct {
+	*generator.FileBlockStore
+	sdb *sinkdb.DB
+}
+
+func (s pendingFileStore) check() error {
+	n := s.sdb.NumMembers()
+	if n > 1 {
+		return errors.WithDetailf(errPendingFileCluster, "the cluster has %d members", n)
+	}
+	return nil
+}
+
+func (s pendingFileStore) GetPendingBlock(ctx context.Context) (*legacy.Block, error) {
+	err := s.check()
+	if err != nil {
+		return nil, err
+	}
+	return s.FileBlockStore.GetPendingBlock(ctx)
+}
+
+func (s pendingFileStore) SavePendingBlock(ctx context.Context, b *legacy.Block) error {
+	err := s.check()
+	if err != nil {
+		return err
+	}
+	return s.FileBlockStore.SavePendingBlock(ctx, b)
+}
@@ -19,7 +19,7 @@ var reset = env.String("RESET", "")
 
 func init() {
 	config.BuildConfig.Reset = true
-	resetIfAllowedAndRequested = func(db pg.DB, sdb *sinkdb.DB) {
+	resetIfAllowedAndRequested = func(db, queryDB pg.DB, sdb *sinkdb.DB) {
 		if *reset != "" {
 			os.Setenv("RESET", "")
 
@@ -33,6 +33,9 @@ func init() {
 			default:
 				log.Fatalkv(ctx, log.KeyError, fmt.Errorf("unrecognized argument to reset: %s", *reset))
 			}
+			if err == nil && queryDB != nil {
+				err = coreunsafe.ResetQueryDB(ctx, queryDB)
+			}
 			if err != nil {
 				log.Fatalkv(ctx, log.KeyError, err)
 			}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 
+	"chain/core/txbuilder"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
 )
@@ -25,6 +26,7 @@ On Mac OS X, to decode an item from the pasteboard,
 	pbpaste|decode block
 	pbpaste|decode blockheader
 	pbpaste|decode script
+	pbpaste|decode template
 `
 
 func fatalf(format string, args ...interface{}) {
@@ -110,7 +112,19 @@ func main() {
 		if err != nil {
 			fatalf("error decoding: %s", err)
 		}
-		prettyPrint(tx)
+		prettyPrint(legacy.NewTxJSON(&tx))
+	case "template":
+		b := make([]byte, len(data)/2)
+		_, err := hex.Decode(b, data)
+		if err != nil {
+			fatalf("err decoding hex: %s", err)
+		}
+
+		tpl, err := txbuilder.DecodeInterchange(b)
+		if err != nil {
+			fatalf("error decoding: %s", err)
+		}
+		prettyPrint(tpl)
 	default:
 		fatalf("unrecognized entity `%s`", args[0])
 	}
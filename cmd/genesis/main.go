@@ -0,0 +1,217 @@
+/*
+Command genesis runs a genesis ceremony, in which the block
+signers of a new blockchain agree on its initial block.
+
+Usage:
+
+	genesis propose [-t timestamp] [-w duration] QUORUM PUBKEY... >proposal
+	genesis sign PRIVATEKEY_FILE <proposal >signature
+	genesis message <proposal
+	genesis finalize PROPOSAL_FILE SIGNATURE_FILE... >transcript
+	genesis verify [-id blockchain_id] <transcript
+
+One participant runs propose with every signer's block-signing
+public key, hex-encoded, in the order the generator lists them:
+the generator's own key first, if it signs blocks, then the keys
+of its block signers. The proposal holds the initial block built
+from them, with the given quorum and timestamp.
+
+Each signer runs sign offline with the private key for its
+public key, in the raw format written by "ed25519 gen". It
+rebuilds the initial block from the proposal's parameters,
+prints them to stderr for the signer to check, and writes the
+signer's signature of the proposal. A signer whose key is in an
+HSM can instead sign the hex-encoded output of message, and
+write its signature as a JSON object with hex-encoded "pubkey"
+and "signature" fields.
+
+Once every signer has signed, finalize checks the signatures
+and writes a transcript with the proposal and signatures.
+Configure the generator with it, using "corectl
+config-generator -genesis transcript". Anyone can check the
+transcript later with verify, optionally against a blockchain
+ID.
+*/
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"chain/core/genesis"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "propose":
+		propose(args)
+	case "sign":
+		sign(args)
+	case "message":
+		p := readProposal(os.Stdin)
+		fmt.Println(hex.EncodeToString(p.Message()))
+	case "finalize":
+		finalize(args)
+	case "verify":
+		verify(args)
+	default:
+		usage()
+	}
+}
+
+func propose(args []string) {
+	var flags flag.FlagSet
+	ts := flags.String("t", "", "initial block `timestamp`, in RFC 3339 format (default now)")
+	window := flags.Duration("w", 24*time.Hour, "the generator's maximum issuance window `duration`")
+	flags.Usage = usage
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 2 {
+		usage()
+	}
+
+	timestamp := time.Now()
+	if *ts != "" {
+		var err error
+		timestamp, err = time.Parse(time.RFC3339, *ts)
+		must(err)
+	}
+	quorum, err := strconv.Atoi(args[0])
+	must(err)
+	var pubkeys []ed25519.PublicKey
+	for _, s := range args[1:] {
+		pub, err := hex.DecodeString(s)
+		must(err)
+		pubkeys = append(pubkeys, ed25519.PublicKey(pub))
+	}
+
+	p, err := genesis.NewProposal(pubkeys, quorum, timestamp, *window)
+	must(err)
+	writeJSON(p)
+}
+
+func sign(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	priv, err := ioutil.ReadFile(args[0])
+	must(err)
+	if len(priv) != ed25519.PrivateKeySize {
+		fatalf("private key file has %d bytes, want %d", len(priv), ed25519.PrivateKeySize)
+	}
+	p := readProposal(os.Stdin)
+	sig, err := genesis.Sign(p, ed25519.PrivateKey(priv))
+	must(err)
+	describe(p)
+	writeJSON(sig)
+}
+
+func finalize(args []string) {
+	if len(args) < 2 {
+		usage()
+	}
+	f, err := os.Open(args[0])
+	must(err)
+	p := readProposal(f)
+	var sigs []*genesis.Signature
+	for _, name := range args[1:] {
+		b, err := ioutil.ReadFile(name)
+		must(err)
+		sig := new(genesis.Signature)
+		err = json.Unmarshal(b, sig)
+		must(errors.Wrapf(err, "parsing %s", name))
+		sigs = append(sigs, sig)
+	}
+	t, err := genesis.Finalize(p, sigs)
+	must(err)
+	writeJSON(t)
+}
+
+func verify(args []string) {
+	var flags flag.FlagSet
+	id := flags.String("id", "", "expected `blockchain_id`")
+	flags.Usage = usage
+	flags.Parse(args)
+
+	var t genesis.Transcript
+	b, err := ioutil.ReadAll(os.Stdin)
+	must(err)
+	must(json.Unmarshal(b, &t))
+	must(t.Verify())
+	if *id != "" {
+		var want bc.Hash
+		must(want.UnmarshalText([]byte(*id)))
+		got := t.Proposal.InitialBlock.Hash()
+		if got != want {
+			fatalf("initial block ID %x, want %x", got.Bytes(), want.Bytes())
+		}
+	}
+	describe(t.Proposal)
+	fmt.Println("OK")
+}
+
+func readProposal(f *os.File) *genesis.Proposal {
+	b, err := ioutil.ReadAll(f)
+	must(err)
+	p := new(genesis.Proposal)
+	must(json.Unmarshal(b, p))
+	must(p.Verify())
+	return p
+}
+
+// describe prints the parameters of p to stderr.
+func describe(p *genesis.Proposal) {
+	id := p.InitialBlock.Hash()
+	fmt.Fprintf(os.Stderr, "blockchain ID: %x\n", id.Bytes())
+	fmt.Fprintf(os.Stderr, "timestamp: %s\n", p.InitialBlock.Time().Format(time.RFC3339Nano))
+	fmt.Fprintf(os.Stderr, "max issuance window: %s\n", bc.MillisDuration(p.MaxIssuanceWindowMS))
+	fmt.Fprintf(os.Stderr, "quorum: %d of %d\n", p.Quorum, len(p.Pubkeys))
+	for _, pub := range p.Pubkeys {
+		fmt.Fprintf(os.Stderr, "  %x\n", []byte(pub))
+	}
+}
+
+func writeJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	must(err)
+	os.Stdout.Write(append(b, '\n'))
+}
+
+func usage() {
+	opts := []string{
+		"propose [-t timestamp] [-w duration] QUORUM PUBHEX... >proposal",
+		"sign PRIVATEKEY_FILE <proposal >signature",
+		"message <proposal",
+		"finalize PROPOSAL_FILE SIGNATURE_FILE... >transcript",
+		"verify [-id blockchain_id] <transcript",
+	}
+	fmt.Fprintln(os.Stderr, "Usage:")
+	for _, o := range opts {
+		fmt.Fprintf(os.Stderr, "\t%s %s\n", os.Args[0], o)
+	}
+	os.Exit(1)
+}
+
+func must(err error) {
+	if err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "genesis: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,57 @@
+// Command signerpolicy checks a block signer policy file
+// against a file of test blocks and transactions.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"chain/core/blocksigner"
+)
+
+const help = `
+Usage: signerpolicy policy.json tests.json
+
+Command signerpolicy checks that the block signer policy in
+policy.json (see SIGNER_POLICY_FILE in cored) allows or rejects
+each block in tests.json as expected. Each test gives the header
+of the previous block and either a block or its timestamp and
+transactions, all hex-encoded; see blocksigner.PolicyTest.
+
+It exits with status 1 if any test fails.
+`
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprint(os.Stderr, help)
+		os.Exit(2)
+	}
+
+	policy, err := blocksigner.LoadPolicy(os.Args[1])
+	if err != nil {
+		fatalf("%s\n", err)
+	}
+	tests, err := blocksigner.LoadPolicyTests(os.Args[2])
+	if err != nil {
+		fatalf("%s\n", err)
+	}
+
+	var failed int
+	for i, err := range policy.RunTests(tests) {
+		if err != nil {
+			fmt.Println("FAIL", err)
+			failed++
+			continue
+		}
+		fmt.Println("ok  ", tests[i].Name)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d tests failed\n", failed, len(tests))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,198 @@
+// Command standbyd runs a coordinator for a primary Chain Core and
+// a standby Core, usually in another region, that replicates its
+// blocks. It checks that the standby's blocks match the primary's,
+// and if the primary fails, fences it and promotes the standby to
+// generator.
+//
+// Settings are read from the environment:
+//
+//	PRIMARY_URL, PRIMARY_ACCESS_TOKEN  the primary Core
+//	STANDBY_URL, STANDBY_ACCESS_TOKEN  the standby Core
+//	PROMOTION_FILE     JSON file with the block_signers, quorum,
+//	                   and max_issuance_window_ms the standby
+//	                   uses as generator
+//	CHECK_INTERVAL     time between checks (default 5s)
+//	FAILURE_THRESHOLD  consecutive failed checks of the primary
+//	                   that trigger promotion; 0 disables
+//	                   automatic promotion (default 3)
+//	MAX_LAG            most blocks the standby can be behind
+//	                   a failed primary and be promoted
+//	                   automatically
+//	FENCE_CMD          shell command that fences the primary by
+//	                   other means if its API doesn't respond,
+//	                   then prints the last block the primary
+//	                   committed as JSON, {"height": ...,
+//	                   "block_id": ...}, with the primary's
+//	                   pending block in "pending_block", if
+//	                   known; the standby must have the last
+//	                   block to be promoted
+//	PROMOTE_CMD        shell command run after promotion, for
+//	                   example to update DNS
+//	ALERT_CMD          shell command run when a check finds a
+//	                   problem
+//	LISTEN             address for the health and promote
+//	                   endpoints (default :1998)
+//	TLS_CERT_FILE, TLS_KEY_FILE, ROOT_CA_CERTS
+//	                   client TLS settings, as for cored
+//
+// The commands run with the status of the latest check, as JSON,
+// in the environment variable STANDBY_STATUS.
+//
+// GET /health responds with that status, with code 503 if the
+// check found a problem. POST /promote promotes the standby.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"chain/core"
+	"chain/core/rpc"
+	"chain/core/standby"
+	"chain/env"
+	"chain/errors"
+	"chain/log"
+)
+
+var (
+	primaryURL       = env.String("PRIMARY_URL", "")
+	primaryToken     = env.String("PRIMARY_ACCESS_TOKEN", "")
+	standbyURL       = env.String("STANDBY_URL", "")
+	standbyToken     = env.String("STANDBY_ACCESS_TOKEN", "")
+	promotionFile    = env.String("PROMOTION_FILE", "")
+	checkInterval    = env.Duration("CHECK_INTERVAL", 5*time.Second)
+	failureThreshold = env.Int("FAILURE_THRESHOLD", 3)
+	maxLag           = env.Int("MAX_LAG", 0)
+	fenceCmd         = env.String("FENCE_CMD", "")
+	promoteCmd       = env.String("PROMOTE_CMD", "")
+	alertCmd         = env.String("ALERT_CMD", "")
+	listenAddr       = env.String("LISTEN", ":1998")
+	tlsCert          = env.String("TLS_CERT_FILE", "")
+	tlsKey           = env.String("TLS_KEY_FILE", "")
+	rootCAs          = env.String("ROOT_CA_CERTS", "")
+)
+
+func main() {
+	env.Parse()
+	ctx := context.Background()
+	if *primaryURL == "" || *standbyURL == "" {
+		log.Fatalkv(ctx, log.KeyError, "PRIMARY_URL and STANDBY_URL are required")
+	}
+
+	httpClient := &http.Client{}
+	tlsConfig, err := core.TLSConfig(*tlsCert, *tlsKey, *rootCAs)
+	if err != nil && err != core.ErrNoTLS {
+		log.Fatalkv(ctx, log.KeyError, err)
+	}
+	if tlsConfig != nil {
+		httpClient.Transport = &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}
+	}
+
+	c := &standby.Coordinator{
+		Primary:          &standby.RemoteNode{Client: &rpc.Client{BaseURL: *primaryURL, AccessToken: *primaryToken, Client: httpClient}},
+		Standby:          &standby.RemoteNode{Client: &rpc.Client{BaseURL: *standbyURL, AccessToken: *standbyToken, Client: httpClient}},
+		Interval:         *checkInterval,
+		FailureThreshold: *failureThreshold,
+		MaxLag:           uint64(*maxLag),
+	}
+	if *promotionFile != "" {
+		b, err := ioutil.ReadFile(*promotionFile)
+		if err != nil {
+			log.Fatalkv(ctx, log.KeyError, err)
+		}
+		err = json.Unmarshal(b, &c.Promotion)
+		if err != nil {
+			log.Fatalkv(ctx, log.KeyError, errors.Wrap(err, "parsing promotion file"))
+		}
+	} else if *failureThreshold > 0 {
+		log.Fatalkv(ctx, log.KeyError, "PROMOTION_FILE is required for automatic promotion")
+	}
+	if *fenceCmd != "" {
+		c.Fencer = func(ctx context.Context) (*standby.Fence, error) {
+			var out bytes.Buffer
+			err := runHook(ctx, *fenceCmd, c.Status(), &out)
+			if err != nil {
+				return nil, err
+			}
+			f := new(standby.Fence)
+			err = json.Unmarshal(out.Bytes(), f)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing fence command output")
+			}
+			return f, nil
+		}
+	}
+	if *promoteCmd != "" {
+		c.OnPromote = func(ctx context.Context, st standby.Status) error {
+			return runHook(ctx, *promoteCmd, st, os.Stdout)
+		}
+	}
+	if *alertCmd != "" {
+		c.OnAlert = func(ctx context.Context, st standby.Status) {
+			err := runHook(ctx, *alertCmd, st, os.Stdout)
+			if err != nil {
+				log.Error(ctx, err, "alert hook")
+			}
+		}
+	}
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, req *http.Request) {
+		st := c.Status()
+		w.Header().Set("Content-Type", "application/json")
+		if !st.Healthy() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(st)
+	})
+	http.HandleFunc("/promote", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		err := c.Promote(req.Context(), "manual promotion")
+		if err != nil {
+			log.Error(req.Context(), err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	go func() {
+		err := http.ListenAndServe(*listenAddr, nil)
+		if err != nil {
+			log.Fatalkv(ctx, log.KeyError, err)
+		}
+	}()
+
+	c.Run(ctx)
+	log.Printkv(ctx, "at", "coordinator stopped", "promoted", c.Status().Promoted)
+	select {} // keep serving /health
+}
+
+// runHook runs the shell command cmd with st in the
+// environment as STANDBY_STATUS, writing its output to stdout.
+func runHook(ctx context.Context, cmd string, st standby.Status, stdout io.Writer) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Env = append(os.Environ(), "STANDBY_STATUS="+string(b))
+	c.Stdout = stdout
+	c.Stderr = os.Stderr
+	return errors.Wrapf(c.Run(), "running %q", cmd)
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/golang/groupcache/lru"
 	"github.com/lib/pq"
 
+	"chain/clock"
 	"chain/core/pin"
+	"chain/core/query"
 	"chain/core/signers"
 	"chain/core/txbuilder"
 	"chain/crypto/ed25519/chainkd"
@@ -32,6 +34,7 @@ var (
 func NewManager(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Manager {
 	return &Manager{
 		db:          db,
+		queryDB:     db,
 		chain:       chain,
 		utxoDB:      newReserver(db, chain, pinStore),
 		pinStore:    pinStore,
@@ -44,6 +47,7 @@ func NewManager(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Manager {
 // Manager stores accounts and their associated control programs.
 type Manager struct {
 	db       pg.DB
+	queryDB  pg.DB
 	chain    *protocol.Chain
 	utxoDB   *reserver
 	indexer  Saver
@@ -65,17 +69,34 @@ func (m *Manager) IndexAccounts(indexer Saver) {
 	m.indexer = indexer
 }
 
-// ExpireReservations removes reservations that have expired periodically.
-// It blocks until the context is canceled.
-func (m *Manager) ExpireReservations(ctx context.Context, period time.Duration) {
-	ticks := time.Tick(period)
+// SetQueryDB sets the database holding the annotated query
+// tables, which RescanAccount repairs. It defaults to the
+// Manager's own database.
+func (m *Manager) SetQueryDB(db pg.DB) {
+	m.queryDB = db
+}
+
+// SetClock sets the clock that times reservation expiry,
+// in place of the real one, for testing.
+func (m *Manager) SetClock(c clock.Clock) {
+	m.utxoDB.clock = c
+}
+
+// ReapReservations periodically releases UTXO reservations that
+// have expired or whose UTXOs have been spent, forgets cached
+// UTXOs that have been spent, and deletes expired holds. It
+// blocks until the context is canceled.
+func (m *Manager) ReapReservations(ctx context.Context, period time.Duration) {
+	ticker := m.utxoDB.clock.NewTicker(period)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf(ctx, "Deposed, ExpireReservations exiting")
+			log.Printf(ctx, "Deposed, ReapReservations exiting")
 			return
-		case <-ticks:
-			err := m.utxoDB.ExpireReservations(ctx)
+		case <-ticker.C:
+			m.utxoDB.reap(ctx)
+			err := m.deleteExpiredHolds(ctx)
 			if err != nil {
 				log.Error(ctx, err)
 			}
@@ -83,6 +104,12 @@ func (m *Manager) ExpireReservations(ctx context.Context, period time.Duration)
 	}
 }
 
+// ReaperStats returns the totals released by ReapReservations
+// since the Manager was created.
+func (m *Manager) ReaperStats() ReaperStats {
+	return m.utxoDB.reaperStats()
+}
+
 type Account struct {
 	*signers.Signer
 	Alias string
@@ -91,16 +118,30 @@ type Account struct {
 
 // Create creates a new Account.
 func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int, alias string, tags map[string]interface{}, clientToken string) (*Account, error) {
+	tagsParam, err := tagsToNullString(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	signer, err := signers.Create(ctx, m.db, "account", xpubs, quorum, clientToken)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
+	return m.saveAccount(ctx, signer, alias, tags, tagsParam)
+}
 
-	tagsParam, err := tagsToNullString(tags)
+// saveAccount stores the account with the given signer,
+// alias, and tags, and indexes it for queries.
+func (m *Manager) saveAccount(ctx context.Context, signer *signers.Signer, alias string, tags map[string]interface{}, tagsParam *stdsql.NullString) (*Account, error) {
+	err := insertAccount(ctx, m.db, signer, alias, tagsParam)
 	if err != nil {
 		return nil, err
 	}
+	return m.indexAccount(ctx, signer, alias, tags)
+}
 
+// insertAccount stores the account with the given signer in db.
+func insertAccount(ctx context.Context, db pg.DB, signer *signers.Signer, alias string, tagsParam *stdsql.NullString) error {
 	aliasSQL := stdsql.NullString{
 		String: alias,
 		Valid:  alias != "",
@@ -110,20 +151,23 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 		INSERT INTO accounts (account_id, alias, tags) VALUES ($1, $2, $3)
 		ON CONFLICT (account_id) DO UPDATE SET alias = $2, tags = $3
 	`
-	_, err = m.db.ExecContext(ctx, q, signer.ID, aliasSQL, tagsParam)
+	_, err := db.ExecContext(ctx, q, signer.ID, aliasSQL, tagsParam)
 	if pg.IsUniqueViolation(err) {
-		return nil, errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
-	} else if err != nil {
-		return nil, errors.Wrap(err)
+		return errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
 	}
+	return errors.Wrap(err)
+}
 
+// indexAccount indexes the stored account
+// with the given signer, and returns it.
+func (m *Manager) indexAccount(ctx context.Context, signer *signers.Signer, alias string, tags map[string]interface{}) (*Account, error) {
 	account := &Account{
 		Signer: signer,
 		Alias:  alias,
 		Tags:   tags,
 	}
 
-	err = m.indexAnnotatedAccount(ctx, account)
+	err := m.indexAnnotatedAccount(ctx, account)
 	if err != nil {
 		return nil, errors.Wrap(err, "indexing annotated account")
 	}
@@ -131,6 +175,137 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	return account, nil
 }
 
+// CreateRequest holds the parameters for one account
+// in a call to CreateBatch.
+type CreateRequest struct {
+	XPubs       []chainkd.XPub
+	Quorum      int
+	Alias       string
+	Tags        map[string]interface{}
+	ClientToken string
+}
+
+// CreateBatch creates a new Account for each element of reqs. Signers
+// and accounts are each inserted with a single statement. The
+// returned slices correspond 1:1 with reqs; for each element exactly
+// one of the account or the error is non-nil.
+func (m *Manager) CreateBatch(ctx context.Context, reqs []CreateRequest) ([]*Account, []error) {
+	params := make([]signers.CreateParams, len(reqs))
+	for i, req := range reqs {
+		params[i] = signers.CreateParams{
+			XPubs:       req.XPubs,
+			Quorum:      req.Quorum,
+			ClientToken: req.ClientToken,
+		}
+	}
+	accSigners, errs := signers.CreateBatch(ctx, m.db, "account", params)
+
+	var (
+		accounts   = make([]*Account, len(reqs))
+		accountIDs pq.StringArray
+		aliases    []stdsql.NullString
+		tags       []*stdsql.NullString
+		ordinals   []int
+	)
+	for i, req := range reqs {
+		if errs[i] != nil {
+			continue
+		}
+		tagsParam, err := tagsToNullString(req.Tags)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		accountIDs = append(accountIDs, accSigners[i].ID)
+		aliases = append(aliases, stdsql.NullString{String: req.Alias, Valid: req.Alias != ""})
+		tags = append(tags, tagsParam)
+		ordinals = append(ordinals, i)
+	}
+	if len(ordinals) == 0 {
+		return accounts, errs
+	}
+
+	// Rows that conflict on either the account ID (a retried client
+	// token) or the alias are skipped rather than aborting the whole
+	// batch. They're sorted out below.
+	const q = `
+		INSERT INTO accounts (account_id, alias, tags)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::jsonb[])
+		ON CONFLICT DO NOTHING
+		RETURNING account_id
+	`
+	inserted := make(map[string]bool, len(ordinals))
+	err := pg.ForQueryRows(ctx, m.db, q, accountIDs, pq.Array(aliases), pq.Array(tags), func(id string) {
+		inserted[id] = true
+	})
+	if err != nil {
+		err = errors.Wrap(err, "batch inserting accounts")
+		for _, i := range ordinals {
+			errs[i] = err
+		}
+		return accounts, errs
+	}
+
+	var skipped pq.StringArray
+	for _, id := range accountIDs {
+		if !inserted[id] {
+			skipped = append(skipped, id)
+		}
+	}
+	// A skipped row that exists was stored by an earlier request
+	// with the same client token. Its account is re-indexed as
+	// stored, not as this request describes it.
+	type stored struct {
+		alias string
+		tags  map[string]interface{}
+	}
+	existing := make(map[string]*stored, len(skipped))
+	if len(skipped) > 0 {
+		const existingQ = `SELECT account_id, COALESCE(alias, ''), tags FROM accounts WHERE account_id = ANY($1::text[])`
+		err = pg.ForQueryRows(ctx, m.db, existingQ, skipped, func(id, alias string, tags []byte) error {
+			row := &stored{alias: alias}
+			if len(tags) > 0 {
+				err := json.Unmarshal(tags, &row.tags)
+				if err != nil {
+					return errors.Wrapf(err, "decoding tags of account %s", id)
+				}
+			}
+			existing[id] = row
+			return nil
+		})
+		if err != nil {
+			err = errors.Wrap(err, "looking up existing accounts")
+			for _, i := range ordinals {
+				errs[i] = err
+			}
+			return accounts, errs
+		}
+	}
+
+	for _, i := range ordinals {
+		id := accSigners[i].ID
+		account := &Account{
+			Signer: accSigners[i],
+			Alias:  reqs[i].Alias,
+			Tags:   reqs[i].Tags,
+		}
+		row, ok := existing[id]
+		if ok {
+			account.Alias, account.Tags = row.alias, row.tags
+		} else if !inserted[id] {
+			errs[i] = errors.WithDetail(ErrDuplicateAlias, "an account with the provided alias already exists")
+			continue
+		}
+		err = m.indexAnnotatedAccount(ctx, account)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "indexing annotated account")
+			continue
+		}
+		accounts[i] = account
+	}
+	return accounts, errs
+}
+
 // UpdateTags modifies the tags of the specified account. The account may be
 // identified either by ID or Alias, but not both.
 func (m *Manager) UpdateTags(ctx context.Context, id, alias *string, tags map[string]interface{}) error {
@@ -174,14 +349,27 @@ func (m *Manager) UpdateTags(ctx context.Context, id, alias *string, tags map[st
 		}
 	}
 
+	// Record the tags being replaced, so that
+	// earlier tags remain available as history.
 	const q = `
+		WITH history AS (
+			INSERT INTO tag_history (object_type, object_id, tags)
+			SELECT 'account', account_id, tags FROM accounts WHERE account_id = $2
+		)
 		UPDATE accounts
 		SET tags = $1
 		WHERE account_id = $2
 	`
-	_, err = m.db.ExecContext(ctx, q, tagsParam, signer.ID)
-	if err != nil {
+	err = pg.RunInTx(ctx, m.db, func(db pg.DB) error {
+		err := query.LockTagHistory(ctx, db)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, q, tagsParam, signer.ID)
 		return errors.Wrap(err, "update entry in accounts table")
+	})
+	if err != nil {
+		return err
 	}
 
 	return errors.Wrap(m.indexAnnotatedAccount(ctx, &Account{
@@ -239,6 +427,7 @@ type controlProgram struct {
 	keyIndex       uint64
 	controlProgram []byte
 	change         bool
+	vault          bool
 	expiresAt      time.Time
 }
 
@@ -247,16 +436,17 @@ func (m *Manager) createControlProgram(ctx context.Context, accountID string, ch
 	if err != nil {
 		return nil, err
 	}
+	err = m.checkWatchOnly(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
 
 	idx, err := m.nextIndex(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	path := signers.Path(account, signers.AccountKeySpace, idx)
-	derivedXPubs := chainkd.DeriveXPubs(account.XPubs, path)
-	derivedPKs := chainkd.XPubKeys(derivedXPubs)
-	control, err := vmutil.P2SPMultiSigProgram(derivedPKs, account.Quorum)
+	control, err := deriveControlProgram(account, idx)
 	if err != nil {
 		return nil, err
 	}
@@ -269,6 +459,15 @@ func (m *Manager) createControlProgram(ctx context.Context, accountID string, ch
 	}, nil
 }
 
+// deriveControlProgram returns the account's control
+// program at key index idx.
+func deriveControlProgram(account *signers.Signer, idx uint64) ([]byte, error) {
+	path := signers.Path(account, signers.AccountKeySpace, idx)
+	derivedXPubs := chainkd.DeriveXPubs(account.XPubs, path)
+	derivedPKs := chainkd.XPubKeys(derivedXPubs)
+	return vmutil.P2SPMultiSigProgram(derivedPKs, account.Quorum)
+}
+
 // CreateControlProgram creates a control program
 // that is tied to the Account and stores it in the database.
 func (m *Manager) CreateControlProgram(ctx context.Context, accountID string, change bool, expiresAt time.Time) ([]byte, error) {
@@ -333,9 +532,12 @@ func (m *Manager) nextIndex(ctx context.Context) (uint64, error) {
 }
 
 func tagsToNullString(tags map[string]interface{}) (*stdsql.NullString, error) {
+	err := query.ValidateTags(tags)
+	if err != nil {
+		return nil, err
+	}
 	var tagsJSON []byte
 	if len(tags) != 0 {
-		var err error
 		tagsJSON, err = json.Marshal(tags)
 		if err != nil {
 			return nil, errors.Wrap(err)
@@ -69,6 +69,40 @@ func TestCreateAccountReusedAlias(t *testing.T) {
 	}
 }
 
+func TestCreateBatch(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+	keys := []chainkd.XPub{testutil.TestXPub}
+
+	tags := map[string]interface{}{"x": "y"}
+	existing, err := m.Create(ctx, keys, 1, "existing", tags, "existing-token")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	reqs := []CreateRequest{
+		{XPubs: keys, Quorum: 1, Alias: "a"},
+		{XPubs: keys, Quorum: 1, Alias: "existing", ClientToken: "new-token"},
+		{XPubs: keys, Quorum: 1, Alias: "renamed", ClientToken: "existing-token"},
+	}
+	accounts, errs := m.CreateBatch(ctx, reqs)
+	if errs[0] != nil || accounts[0] == nil || accounts[0].Alias != "a" {
+		t.Errorf("CreateBatch(a) = %v, %v", accounts[0], errs[0])
+	}
+	if errors.Root(errs[1]) != ErrDuplicateAlias {
+		t.Errorf("CreateBatch(existing alias) error = %v, want %v", errs[1], ErrDuplicateAlias)
+	}
+
+	// A retried client token returns the account as it was stored.
+	if errs[2] != nil {
+		testutil.FatalErr(t, errs[2])
+	}
+	if accounts[2].ID != existing.ID || accounts[2].Alias != "existing" || !testutil.DeepEqual(accounts[2].Tags, tags) {
+		t.Errorf("retried token got %s %q %v, want %s %q %v", accounts[2].ID, accounts[2].Alias, accounts[2].Tags, existing.ID, "existing", tags)
+	}
+}
+
 func TestCreateControlProgram(t *testing.T) {
 	// use pgtest.NewDB for deterministic postgres sequences
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
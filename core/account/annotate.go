@@ -15,6 +15,12 @@ import (
 
 var empty = json.RawMessage(`{}`)
 
+// Annotator returns a query.Annotator, named query.AccountAnnotator,
+// that adds account data to transactions with AnnotateTxs.
+func (m *Manager) Annotator() query.Annotator {
+	return query.NewAnnotator(query.AccountAnnotator, m.AnnotateTxs)
+}
+
 // AnnotateTxs adds account data to transactions
 func (m *Manager) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx) error {
 	var (
@@ -3,9 +3,11 @@ package account
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"chain/core/signers"
 	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/log"
@@ -35,6 +37,11 @@ type spendAction struct {
 	AccountID     string        `json:"account_id"`
 	ReferenceData chainjson.Map `json:"reference_data"`
 	ClientToken   *string       `json:"client_token"`
+
+	// HoldID, if set, is the ID of a hold on the account's funds
+	// that the spend settles. The spend may use the held funds,
+	// and releases the hold once the transaction is built.
+	HoldID string `json:"hold_id"`
 }
 
 func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
@@ -58,13 +65,23 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 		AssetID:   *a.AssetId,
 		AccountID: a.AccountID,
 	}
-	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, a.ClientToken, b.MaxTime())
+	var hold *Hold
+	if a.HoldID != "" {
+		hold, err = a.accounts.checkSettlement(ctx, src, a.Amount, a.HoldID)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, a.ClientToken, b.MaxTime(), hold)
 	if err != nil {
 		return errors.Wrap(err, "reserving utxos")
 	}
 
 	// Cancel the reservation if the build gets rolled back.
 	b.OnRollback(canceler(ctx, a.accounts, res.ID))
+	if a.HoldID != "" {
+		b.OnBuilt(func(tx *legacy.Tx) error { return a.accounts.setSettlingTx(ctx, a.HoldID, tx.ID) })
+	}
 
 	for _, r := range res.UTXOs {
 		txInput, sigInst, err := utxoToInputs(ctx, acct, r, a.ReferenceData)
@@ -91,6 +108,7 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 			return errors.Wrap(err, "adding change output")
 		}
 	}
+	a.accounts.checkPolicyOnBuild(ctx, b, a.AccountID, *a.AssetId)
 	return nil
 }
 
@@ -134,7 +152,255 @@ func (a *spendUTXOAction) Build(ctx context.Context, b *txbuilder.TemplateBuilde
 	if err != nil {
 		return err
 	}
-	return b.AddInput(txInput, sigInst)
+	err = b.AddInput(txInput, sigInst)
+	if err != nil {
+		return err
+	}
+	a.accounts.checkPolicyOnBuild(ctx, b, res.Source.AccountID, res.Source.AssetID)
+	return nil
+}
+
+// ErrSigningKeys is returned when building a transaction that
+// spends outputs the given signing keys cannot authorize.
+var ErrSigningKeys = errors.New("signing keys do not control output")
+
+func (m *Manager) NewSpendUTXOsAction(outputIDs []bc.Hash, signingKeys []chainkd.XPub) txbuilder.Action {
+	return &spendUTXOsAction{
+		accounts:    m,
+		OutputIDs:   outputIDs,
+		SigningKeys: signingKeys,
+	}
+}
+
+func (m *Manager) DecodeSpendUTXOsAction(data []byte) (txbuilder.Action, error) {
+	a := &spendUTXOsAction{accounts: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// spendUTXOsAction spends exactly the given account outputs,
+// for coin control. Each output must belong to an account that
+// the signing keys can sign for, so that the caller can finish
+// the transaction with the keys it expects to use.
+type spendUTXOsAction struct {
+	accounts    *Manager
+	OutputIDs   []bc.Hash      `json:"output_ids"`
+	SigningKeys []chainkd.XPub `json:"signing_keys"`
+
+	ReferenceData chainjson.Map `json:"reference_data"`
+	ClientToken   *string       `json:"client_token"`
+}
+
+func (a *spendUTXOsAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if len(a.OutputIDs) == 0 {
+		missing = append(missing, "output_ids")
+	}
+	if len(a.SigningKeys) == 0 {
+		missing = append(missing, "signing_keys")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	spends := make(map[string][]bc.AssetID)
+	for _, outputID := range a.OutputIDs {
+		var clientToken *string
+		if a.ClientToken != nil {
+			// Each reservation needs its own token.
+			token := fmt.Sprintf("%s-%x", *a.ClientToken, outputID.Bytes())
+			clientToken = &token
+		}
+		res, err := a.accounts.utxoDB.ReserveUTXO(ctx, outputID, clientToken, b.MaxTime())
+		if err != nil {
+			return errors.Wrapf(err, "reserving output %x", outputID.Bytes())
+		}
+		b.OnRollback(canceler(ctx, a.accounts, res.ID))
+
+		acct, err := a.accounts.findByID(ctx, res.Source.AccountID)
+		if err != nil {
+			return err
+		}
+		if !canSign(acct, a.SigningKeys) {
+			return errors.WithDetailf(ErrSigningKeys, "output %x needs %d of the keys of account %s", outputID.Bytes(), acct.Quorum, acct.ID)
+		}
+		txInput, sigInst, err := utxoToInputs(ctx, acct, res.UTXOs[0], a.ReferenceData)
+		if err != nil {
+			return err
+		}
+		err = b.AddInput(txInput, sigInst)
+		if err != nil {
+			return err
+		}
+		spends[acct.ID] = append(spends[acct.ID], res.Source.AssetID)
+	}
+	for accountID, assetIDs := range spends {
+		a.accounts.checkPolicyOnBuild(ctx, b, accountID, assetIDs...)
+	}
+	return nil
+}
+
+// canSign reports whether keys include enough of the account's
+// keys to meet its quorum.
+func canSign(acct *signers.Signer, keys []chainkd.XPub) bool {
+	var n int
+	for _, xpub := range acct.XPubs {
+		for _, k := range keys {
+			if k == xpub {
+				n++
+				break
+			}
+		}
+	}
+	return n >= acct.Quorum
+}
+
+// ErrBadDestination is returned for a transfer
+// destination that isn't exactly one of an account
+// and a control program.
+var ErrBadDestination = errors.New("invalid transfer destination")
+
+func (m *Manager) DecodeTransferAction(data []byte) (txbuilder.Action, error) {
+	a := &transferAction{accounts: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// transferAction spends from one account to any number of
+// destinations, which may hold different assets. UTXOs are
+// reserved once per asset, and each asset gets at most one
+// change output.
+type transferAction struct {
+	accounts      *Manager
+	AccountID     string                `json:"account_id"`
+	Destinations  []transferDestination `json:"destinations"`
+	ReferenceData chainjson.Map         `json:"reference_data"`
+	ClientToken   *string               `json:"client_token"`
+}
+
+// transferDestination is one leg of a transfer. Exactly one of
+// AccountID or Program must be set.
+type transferDestination struct {
+	bc.AssetAmount
+	AccountID     string             `json:"account_id"`
+	Program       chainjson.HexBytes `json:"control_program"`
+	ReferenceData chainjson.Map      `json:"reference_data"`
+}
+
+func (a *transferAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.AccountID == "" {
+		missing = append(missing, "account_id")
+	}
+	if len(a.Destinations) == 0 {
+		missing = append(missing, "destinations")
+	}
+	for i, dest := range a.Destinations {
+		if dest.AssetId == nil || dest.AssetId.IsZero() {
+			missing = append(missing, fmt.Sprintf("destinations[%d].asset_id", i))
+		}
+		if dest.AccountID == "" && len(dest.Program) == 0 {
+			missing = append(missing, fmt.Sprintf("destinations[%d].account_id", i))
+		}
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	// Total the destinations by asset, keeping the assets
+	// in the order they first appear.
+	var (
+		assetIDs []bc.AssetID
+		totals   = make(map[bc.AssetID]bc.Amount)
+	)
+	for i, dest := range a.Destinations {
+		if dest.AccountID != "" && len(dest.Program) > 0 {
+			return errors.WithDetailf(ErrBadDestination, "destination %d has both account_id and control_program", i)
+		}
+		if dest.Amount == 0 {
+			return errors.WithDetailf(txbuilder.ErrBadAmount, "destination %d has a zero amount", i)
+		}
+		assetID := *dest.AssetId
+		total, ok := totals[assetID]
+		if !ok {
+			assetIDs = append(assetIDs, assetID)
+			total = bc.ZeroAmount(assetID)
+		}
+		amount, err := bc.NewAmountFrom(&dest.AssetAmount)
+		if err == nil {
+			total, err = total.Add(amount)
+		}
+		if err != nil {
+			return errors.Sub(txbuilder.ErrBadAmount, err)
+		}
+		totals[assetID] = total
+	}
+
+	acct, err := a.accounts.findByID(ctx, a.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "get account info")
+	}
+
+	for _, assetID := range assetIDs {
+		// Reservations are idempotent per client token,
+		// so each asset needs a token of its own.
+		var clientToken *string
+		if a.ClientToken != nil {
+			token := *a.ClientToken + ":" + assetID.String()
+			clientToken = &token
+		}
+		src := source{
+			AssetID:   assetID,
+			AccountID: a.AccountID,
+		}
+		res, err := a.accounts.utxoDB.Reserve(ctx, src, totals[assetID].Units(), clientToken, b.MaxTime(), nil)
+		if err != nil {
+			return errors.Wrap(err, "reserving utxos")
+		}
+		b.OnRollback(canceler(ctx, a.accounts, res.ID))
+
+		for _, r := range res.UTXOs {
+			txInput, sigInst, err := utxoToInputs(ctx, acct, r, a.ReferenceData)
+			if err != nil {
+				return errors.Wrap(err, "creating inputs")
+			}
+			err = b.AddInput(txInput, sigInst)
+			if err != nil {
+				return errors.Wrap(err, "adding inputs")
+			}
+		}
+
+		if res.Change > 0 {
+			acp, err := a.accounts.createControlProgram(ctx, a.AccountID, true, b.MaxTime())
+			if err != nil {
+				return errors.Wrap(err, "creating control program")
+			}
+			a.accounts.insertControlProgramDelayed(ctx, b, acp)
+
+			err = b.AddOutput(legacy.NewTxOutput(assetID, res.Change, acp.controlProgram, nil))
+			if err != nil {
+				return errors.Wrap(err, "adding change output")
+			}
+		}
+	}
+
+	for _, dest := range a.Destinations {
+		prog := []byte(dest.Program)
+		if dest.AccountID != "" {
+			acp, err := a.accounts.createControlProgram(ctx, dest.AccountID, false, b.MaxTime())
+			if err != nil {
+				return errors.Wrap(err, "creating control program")
+			}
+			a.accounts.insertControlProgramDelayed(ctx, b, acp)
+			prog = acp.controlProgram
+		}
+		err = b.AddOutput(legacy.NewTxOutput(*dest.AssetId, dest.Amount, prog, dest.ReferenceData))
+		if err != nil {
+			return errors.Wrap(err, "adding output")
+		}
+	}
+	a.accounts.checkPolicyOnBuild(ctx, b, a.AccountID, assetIDs...)
+	return nil
 }
 
 // Best-effort cancellation attempt to put in txbuilder.BuildResult.Rollback.
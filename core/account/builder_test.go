@@ -3,6 +3,7 @@ package account_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 	"chain/core/pin"
 	"chain/core/query"
 	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -119,6 +122,59 @@ func TestAccountSourceUTXOReserve(t *testing.T) {
 	}
 }
 
+func TestSpendUTXOsAction(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID          = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset          = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+		_, _, outputID = coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset, 2, accID)
+	)
+
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	otherXPub := testutil.TestXPrv.Child([]byte{1}, false).XPub()
+	source := accounts.NewSpendUTXOsAction([]bc.Hash{outputID}, []chainkd.XPub{otherXPub})
+	builder := txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err := source.Build(ctx, builder)
+	if errors.Root(err) != account.ErrSigningKeys {
+		t.Fatalf("got error %v, want %v", err, account.ErrSigningKeys)
+	}
+
+	source = accounts.NewSpendUTXOsAction([]bc.Hash{outputID}, []chainkd.XPub{testutil.TestXPub})
+	builder = txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err = source.Build(ctx, builder)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, tx, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Inputs) != 1 {
+		t.Fatalf("got %d inputs, want 1", len(tx.Inputs))
+	}
+	gotID, err := tx.Inputs[0].SpentOutputID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotID != outputID {
+		t.Errorf("spent output = %x, want %x", gotID.Bytes(), outputID.Bytes())
+	}
+}
+
 func TestAccountSourceReserveIdempotency(t *testing.T) {
 	var (
 		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
@@ -191,6 +247,108 @@ func TestAccountSourceReserveIdempotency(t *testing.T) {
 	}
 }
 
+func TestTransferAction(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		srcID  = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		destID = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset1 = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+		asset2 = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+
+	coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset1, 5, srcID)
+	coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset2, 5, srcID)
+
+	coretest.CreatePins(ctx, t, pinStore)
+	// Make a block so that account UTXOs are available to spend.
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	data, err := json.Marshal(map[string]interface{}{
+		"account_id": srcID,
+		"destinations": []map[string]interface{}{
+			{"account_id": destID, "asset_id": asset1, "amount": 1},
+			{"control_program": "51", "asset_id": asset2, "amount": 2},
+			{"account_id": destID, "asset_id": asset1, "amount": 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	action, err := accounts.DecodeTransferAction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := txbuilder.NewBuilder(time.Now().Add(5 * time.Minute))
+	err = action.Build(ctx, builder)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, tx, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One input per asset, a change output per asset,
+	// and an output per destination.
+	if len(tx.Inputs) != 2 {
+		t.Errorf("got %d inputs, want 2", len(tx.Inputs))
+	}
+	if len(tx.Outputs) != 5 {
+		t.Fatalf("got %d outputs, want 5", len(tx.Outputs))
+	}
+	totals := make(map[bc.AssetID]uint64)
+	for _, out := range tx.Outputs {
+		if programInAccount(ctx, t, db, out.ControlProgram, srcID) {
+			totals[*out.AssetId] += out.Amount
+		}
+	}
+	if totals[asset1] != 2 || totals[asset2] != 3 {
+		t.Errorf("got change %v, want 2 of asset1 and 3 of asset2", totals)
+	}
+}
+
+func TestTransferActionErrors(t *testing.T) {
+	asset1 := bc.AssetID{V0: 1}
+	cases := []struct {
+		dest    map[string]interface{}
+		wantErr error
+	}{
+		{map[string]interface{}{"account_id": "acc1", "control_program": "51", "asset_id": asset1, "amount": 1}, account.ErrBadDestination},
+		{map[string]interface{}{"account_id": "acc1", "asset_id": asset1, "amount": 0}, txbuilder.ErrBadAmount},
+		{map[string]interface{}{"asset_id": asset1, "amount": 1}, txbuilder.ErrMissingFields},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(map[string]interface{}{
+			"account_id":   "acc0",
+			"destinations": []map[string]interface{}{c.dest},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		action, err := new(account.Manager).DecodeTransferAction(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = action.Build(context.Background(), txbuilder.NewBuilder(time.Now().Add(time.Minute)))
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("destination %v: Build error = %v, want %v", c.dest, err, c.wantErr)
+		}
+	}
+}
+
 func programInAccount(ctx context.Context, t testing.TB, db pg.DB, program []byte, account string) bool {
 	const q = `SELECT signer_id=$1 FROM account_control_programs WHERE control_program=$2`
 	var in bool
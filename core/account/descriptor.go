@@ -0,0 +1,272 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"chain/core/signers"
+	"chain/crypto/ed25519/chainkd"
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// A descriptor describes how an account's control programs are
+// derived, so that a wallet or signing tool outside the Core can
+// derive them, and a Core can watch an account whose keys are
+// managed elsewhere. It has the form
+//
+//	p2sp(QUORUM,XPUB,...)/PATH/*#CHECKSUM
+//
+// Each control program is a P2SP multisig program requiring
+// QUORUM signatures from the keys derived from the hex-encoded
+// XPUBs, in ascending order, along the path PATH/INDEX, for the program's
+// index. PATH is the hex-encoded first element of the derivation
+// path: the account key space, 01, followed by the account's key
+// index, as 8 little-endian bytes. INDEX is the program's index,
+// as 8 little-endian bytes. CHECKSUM is the first 4 bytes of the
+// SHA3-256 hash of the rest of the descriptor, hex-encoded.
+
+var (
+	// ErrBadDescriptor is returned for a
+	// descriptor that can't be parsed.
+	ErrBadDescriptor = errors.New("invalid account descriptor")
+
+	// ErrWatchOnly is returned when creating a control program
+	// for a watch-only account, whose programs are derived by
+	// the wallet that manages its keys.
+	ErrWatchOnly = errors.New("account is watch-only")
+)
+
+// maxImportPrograms bounds the number of control
+// programs derived by a single ImportDescriptor.
+const maxImportPrograms = 10000
+
+// Descriptor holds the parameters of an account descriptor.
+type Descriptor struct {
+	XPubs    []chainkd.XPub
+	Quorum   int
+	KeyIndex uint64
+}
+
+func descriptorFor(s *signers.Signer) Descriptor {
+	return Descriptor{XPubs: s.XPubs, Quorum: s.Quorum, KeyIndex: s.KeyIndex}
+}
+
+func (d Descriptor) signer() *signers.Signer {
+	return &signers.Signer{XPubs: d.XPubs, Quorum: d.Quorum, KeyIndex: d.KeyIndex}
+}
+
+// String returns the descriptor in text form.
+func (d Descriptor) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "p2sp(%d", d.Quorum)
+	for _, xpub := range d.XPubs {
+		fmt.Fprintf(&buf, ",%x", xpub[:])
+	}
+	path := signers.Path(d.signer(), signers.AccountKeySpace)
+	fmt.Fprintf(&buf, ")/%x/*", path[0])
+	return buf.String() + "#" + descriptorChecksum(buf.String())
+}
+
+// MarshalText fulfills the encoding.TextMarshaler interface.
+func (d Descriptor) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText fulfills the encoding.TextUnmarshaler interface.
+func (d *Descriptor) UnmarshalText(text []byte) error {
+	parsed, err := ParseDescriptor(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ParseDescriptor parses a descriptor in text form.
+func ParseDescriptor(s string) (d Descriptor, err error) {
+	hash := strings.LastIndex(s, "#")
+	if hash < 0 {
+		return d, errors.WithDetail(ErrBadDescriptor, "missing checksum")
+	}
+	body, sum := s[:hash], s[hash+1:]
+	if sum != descriptorChecksum(body) {
+		return d, errors.WithDetail(ErrBadDescriptor, "checksum doesn't match")
+	}
+
+	if !strings.HasPrefix(body, "p2sp(") || !strings.HasSuffix(body, "/*") {
+		return d, errors.WithDetail(ErrBadDescriptor, "want p2sp(QUORUM,XPUB,...)/PATH/*")
+	}
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "p2sp("), "/*")
+	end := strings.Index(body, ")/")
+	if end < 0 {
+		return d, errors.WithDetail(ErrBadDescriptor, "want p2sp(QUORUM,XPUB,...)/PATH/*")
+	}
+	args, pathHex := strings.Split(body[:end], ","), body[end+2:]
+
+	d.Quorum, err = strconv.Atoi(args[0])
+	if err != nil {
+		return d, errors.WithDetailf(ErrBadDescriptor, "bad quorum %q", args[0])
+	}
+	for i, arg := range args[1:] {
+		var xpub chainkd.XPub
+		err = xpub.UnmarshalText([]byte(arg))
+		if err != nil {
+			return d, errors.WithDetailf(ErrBadDescriptor, "bad xpub %q", arg)
+		}
+		// The Core keeps an account's keys sorted, so it
+		// can only derive the programs of sorted keys.
+		if i > 0 && bytes.Compare(xpub[:], d.XPubs[i-1][:]) <= 0 {
+			return d, errors.WithDetail(ErrBadDescriptor, "xpubs must be distinct and in ascending order")
+		}
+		d.XPubs = append(d.XPubs, xpub)
+	}
+	if len(d.XPubs) == 0 || d.Quorum < 1 || d.Quorum > len(d.XPubs) {
+		return d, errors.WithDetailf(ErrBadDescriptor, "quorum %d of %d keys", d.Quorum, len(d.XPubs))
+	}
+
+	path, err := hex.DecodeString(pathHex)
+	if err != nil || len(path) != 9 || path[0] != byte(signers.AccountKeySpace) {
+		return d, errors.WithDetailf(ErrBadDescriptor, "bad account path %q", pathHex)
+	}
+	d.KeyIndex = binary.LittleEndian.Uint64(path[1:])
+	return d, nil
+}
+
+func descriptorChecksum(s string) string {
+	var sum [32]byte
+	sha3pool.Sum256(sum[:], []byte(s))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Program returns the descriptor's control program at index idx.
+func (d Descriptor) Program(idx uint64) ([]byte, error) {
+	return deriveControlProgram(d.signer(), idx)
+}
+
+// Descriptor returns the descriptor of the account.
+func (m *Manager) Descriptor(ctx context.Context, accountID string) (Descriptor, error) {
+	account, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return descriptorFor(account), nil
+}
+
+// ImportDescriptor creates a watch-only account whose control
+// programs are those of the descriptor d, as derived by the
+// wallet that manages its keys. It derives count programs, at
+// least one, starting at index first, and stores them, so the Core
+// tracks outputs sent to them. The account, its signer, and its
+// programs are stored in one database transaction. Since the Core can't know which other
+// programs the wallet uses, it can't create new control programs
+// or receivers for the account. Importing more programs for an
+// existing account is done by calling ImportDescriptor again
+// with the account's client token.
+//
+// Outputs already on the blockchain are found
+// by rescanning the account. See RescanAccount.
+func (m *Manager) ImportDescriptor(ctx context.Context, d Descriptor, first, count uint64, alias string, tags map[string]interface{}, clientToken string) (*Account, error) {
+	if count == 0 {
+		return nil, errors.WithDetail(ErrBadDescriptor, "program count must be positive")
+	}
+	if count > maxImportPrograms {
+		return nil, errors.WithDetailf(ErrBadDescriptor, "cannot import more than %d programs at once", maxImportPrograms)
+	}
+	if first > math.MaxInt64-count {
+		return nil, errors.WithDetailf(ErrBadDescriptor, "program index %d out of range", first)
+	}
+	tagsParam, err := tagsToNullString(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the signer, its programs, and the account
+	// together, so a failure leaves none of them behind.
+	var signer *signers.Signer
+	err = pg.RunInTx(ctx, m.db, func(db pg.DB) error {
+		signer, err = signers.Import(ctx, db, "account", d.XPubs, d.Quorum, d.KeyIndex, clientToken)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		if descriptorFor(signer).String() != d.String() {
+			return errors.WithDetail(ErrBadDescriptor, "client token was used for an account with a different descriptor")
+		}
+
+		const q = `INSERT INTO watch_only_accounts (account_id) VALUES ($1) ON CONFLICT DO NOTHING`
+		_, err = db.ExecContext(ctx, q, signer.ID)
+		if err != nil {
+			return errors.Wrap(err, "marking account watch-only")
+		}
+
+		var (
+			indexes  pq.Int64Array
+			programs pq.ByteaArray
+		)
+		for idx := first; idx < first+count; idx++ {
+			prog, err := deriveControlProgram(signer, idx)
+			if err != nil {
+				return errors.Wrapf(err, "deriving program %d", idx)
+			}
+			indexes = append(indexes, int64(idx))
+			programs = append(programs, prog)
+		}
+		const progQ = `
+			INSERT INTO account_control_programs (signer_id, key_index, control_program, change)
+			SELECT $1, unnest($2::bigint[]), unnest($3::bytea[]), false
+			ON CONFLICT DO NOTHING
+		`
+		_, err = db.ExecContext(ctx, progQ, signer.ID, indexes, programs)
+		if err != nil {
+			return errors.Wrap(err, "storing control programs")
+		}
+
+		// A program that was already stored must be this
+		// account's, from an earlier import; it can't
+		// belong to two accounts.
+		const conflictQ = `
+			SELECT signer_id FROM account_control_programs
+			WHERE control_program = ANY($1::bytea[]) AND signer_id <> $2
+			LIMIT 1
+		`
+		var other string
+		err = db.QueryRowContext(ctx, conflictQ, programs, signer.ID).Scan(&other)
+		if err == nil {
+			return errors.WithDetailf(ErrBadDescriptor, "a control program of the descriptor belongs to account %s", other)
+		}
+		if err != sql.ErrNoRows {
+			return errors.Wrap(err, "checking control programs")
+		}
+
+		return insertAccount(ctx, db, signer, alias, tagsParam)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.indexAccount(ctx, signer, alias, tags)
+}
+
+// checkWatchOnly returns ErrWatchOnly
+// if the account is watch-only.
+func (m *Manager) checkWatchOnly(ctx context.Context, accountID string) error {
+	const q = `SELECT EXISTS(SELECT 1 FROM watch_only_accounts WHERE account_id = $1)`
+	var watchOnly bool
+	err := m.db.QueryRowContext(ctx, q, accountID).Scan(&watchOnly)
+	if err != nil {
+		return errors.Wrap(err, "checking watch-only account")
+	}
+	if watchOnly {
+		return errors.WithDetailf(ErrWatchOnly, "account %s", accountID)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"chain/core/signers"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestDescriptorRoundTrip(t *testing.T) {
+	var xpubs []chainkd.XPub
+	for i := 0; i < 3; i++ {
+		_, xpub, err := chainkd.NewXKeys(nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		xpubs = append(xpubs, xpub)
+	}
+	sort.Slice(xpubs, func(i, j int) bool { return bytes.Compare(xpubs[i][:], xpubs[j][:]) < 0 })
+	d := Descriptor{XPubs: xpubs, Quorum: 2, KeyIndex: 300}
+
+	got, err := ParseDescriptor(d.String())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.String() != d.String() {
+		t.Errorf("ParseDescriptor(%q).String() = %q", d.String(), got.String())
+	}
+
+	signer := &signers.Signer{XPubs: xpubs, Quorum: 2, KeyIndex: 300}
+	for idx := uint64(1); idx < 4; idx++ {
+		want, err := deriveControlProgram(signer, idx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		prog, err := got.Program(idx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !bytes.Equal(prog, want) {
+			t.Errorf("Program(%d) = %x want %x", idx, prog, want)
+		}
+	}
+}
+
+func TestParseDescriptorErrors(t *testing.T) {
+	_, xpub1, _ := chainkd.NewXKeys(nil)
+	_, xpub2, _ := chainkd.NewXKeys(nil)
+	if bytes.Compare(xpub1[:], xpub2[:]) > 0 {
+		xpub1, xpub2 = xpub2, xpub1
+	}
+	good := Descriptor{XPubs: []chainkd.XPub{xpub1, xpub2}, Quorum: 1, KeyIndex: 1}.String()
+	unsorted := Descriptor{XPubs: []chainkd.XPub{xpub2, xpub1}, Quorum: 1, KeyIndex: 1}.String()
+	quorum := Descriptor{XPubs: []chainkd.XPub{xpub1}, Quorum: 2, KeyIndex: 1}.String()
+
+	cases := []string{
+		"",
+		strings.Split(good, "#")[0],
+		strings.Replace(good, "p2sp(1", "p2sp(2", 1),
+		unsorted,
+		quorum,
+	}
+	for _, c := range cases {
+		_, err := ParseDescriptor(c)
+		if errors.Root(err) != ErrBadDescriptor {
+			t.Errorf("ParseDescriptor(%q) error = %v want %v", c, err, ErrBadDescriptor)
+		}
+	}
+}
+
+func TestImportDescriptorCount(t *testing.T) {
+	_, xpub, _ := chainkd.NewXKeys(nil)
+	d := Descriptor{XPubs: []chainkd.XPub{xpub}, Quorum: 1, KeyIndex: 1}
+	cases := []struct {
+		first, count uint64
+	}{
+		{0, 0},
+		{0, maxImportPrograms + 1},
+		{math.MaxInt64, 1},
+	}
+	m := new(Manager)
+	for _, c := range cases {
+		_, err := m.ImportDescriptor(context.Background(), d, c.first, c.count, "", nil, "token")
+		if errors.Root(err) != ErrBadDescriptor {
+			t.Errorf("ImportDescriptor(first %d, count %d) error = %v, want %v", c.first, c.count, err, ErrBadDescriptor)
+		}
+	}
+}
+
+func TestImportDescriptorConflict(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), prottest.NewChain(t), nil)
+	_, xpub, _ := chainkd.NewXKeys(nil)
+	d := Descriptor{XPubs: []chainkd.XPub{xpub}, Quorum: 1, KeyIndex: 1}
+
+	_, err := m.ImportDescriptor(ctx, d, 0, 5, "first", nil, "token1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Importing more programs of the same account is fine.
+	_, err = m.ImportDescriptor(ctx, d, 3, 5, "first", nil, "token1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Another account can't take over its programs.
+	_, err = m.ImportDescriptor(ctx, d, 4, 2, "second", nil, "token2")
+	if errors.Root(err) != ErrBadDescriptor {
+		t.Errorf("ImportDescriptor(other account) error = %v, want %v", err, ErrBadDescriptor)
+	}
+}
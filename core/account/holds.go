@@ -0,0 +1,291 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// A hold sets aside part of an account's balance of an asset
+// until it expires, for an off-chain purpose such as a quote
+// awaiting settlement. Transactions built by the Core can't
+// spend held funds, except by settling the hold: a spend
+// action naming the hold may spend up to its amount. The hold
+// is released once the settling transaction is submitted to
+// the Core or lands in a block. If the transaction is built
+// again, or added to by a later build that doesn't name the
+// hold, the hold stays until it expires or is released.
+//
+// Holds are stored in the database, so every process of the
+// Core respects them. A hold is placed only if the account's
+// confirmed balance, less its other holds, covers it; funds
+// reserved for transactions being built are not counted.
+// Holds on the same account and asset are placed one at a
+// time, under a database lock.
+
+var (
+	// ErrHeld is returned when building a transaction
+	// that would spend funds held for another purpose.
+	ErrHeld = errors.New("funds are held")
+
+	// ErrBadHold is returned for a hold that can't be placed,
+	// or settled by the given spend.
+	ErrBadHold = errors.New("invalid account hold")
+)
+
+// Hold is a hold on part of an account's balance of an asset.
+type Hold struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"account_id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+	Amount    uint64     `json:"amount"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	Reference string     `json:"reference,omitempty"`
+}
+
+// PlaceHold holds amount units of the asset in the account until
+// expiresAt. It returns ErrHeld if the account's balance outside
+// vaults, less its other holds, is too small. A retried call with the same client
+// token returns the original hold.
+func (m *Manager) PlaceHold(ctx context.Context, accountID string, assetID bc.AssetID, amount uint64, expiresAt time.Time, reference, clientToken string) (*Hold, error) {
+	if amount == 0 {
+		return nil, errors.WithDetail(ErrBadHold, "amount must be positive")
+	}
+	_, err := bc.NewAmount(assetID, amount)
+	if err != nil {
+		return nil, err
+	}
+	now := m.utxoDB.clock.Now()
+	if !expiresAt.After(now) {
+		return nil, errors.WithDetail(ErrBadHold, "expiry must be in the future")
+	}
+	_, err = m.findByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientToken != "" {
+		h, err := m.holdByClientToken(ctx, clientToken)
+		if err != nil || h != nil {
+			return h, err
+		}
+	}
+
+	h := &Hold{
+		AccountID: accountID,
+		AssetID:   assetID,
+		Amount:    amount,
+		ExpiresAt: expiresAt,
+		Reference: reference,
+	}
+	const q = `
+		INSERT INTO account_holds (account_id, asset_id, amount, expires_at, reference, client_token)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE (SELECT COALESCE(SUM(amount), 0) FROM account_utxos WHERE account_id = $1 AND asset_id = $2 AND NOT vault)
+			- (SELECT COALESCE(SUM(amount), 0) FROM account_holds WHERE account_id = $1 AND asset_id = $2 AND expires_at > $7)
+			>= $3
+		ON CONFLICT (client_token) DO NOTHING
+		RETURNING id
+	`
+	err = pg.RunInTx(ctx, m.db, func(db pg.DB) error {
+		// Serialize holds on the account and asset, so that
+		// concurrent calls can't both count the same funds.
+		const lockQ = `SELECT pg_advisory_xact_lock(hashtext($1), hashtext(encode($2, 'hex')))`
+		_, err := db.ExecContext(ctx, lockQ, accountID, assetID)
+		if err != nil {
+			return errors.Wrap(err, "locking account holds")
+		}
+		return db.QueryRowContext(ctx, q, accountID, assetID, amount, expiresAt, reference,
+			sql.NullString{String: clientToken, Valid: clientToken != ""}, now).Scan(&h.ID)
+	})
+	if errors.Root(err) == sql.ErrNoRows {
+		// Either the balance is too small, or a concurrent
+		// call with the same client token placed the hold.
+		if clientToken != "" {
+			existing, err := m.holdByClientToken(ctx, clientToken)
+			if err != nil || existing != nil {
+				return existing, err
+			}
+		}
+		return nil, errors.WithDetailf(ErrHeld, "account %s has less than %d unheld units of asset %x", accountID, amount, assetID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting account hold")
+	}
+	return h, nil
+}
+
+func (m *Manager) holdByClientToken(ctx context.Context, clientToken string) (*Hold, error) {
+	const q = `
+		SELECT id, account_id, asset_id, amount, expires_at, reference
+		FROM account_holds WHERE client_token = $1
+	`
+	h := new(Hold)
+	err := m.db.QueryRowContext(ctx, q, clientToken).Scan(&h.ID, &h.AccountID, &h.AssetID, &h.Amount, &h.ExpiresAt, &h.Reference)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up account hold")
+	}
+	return h, nil
+}
+
+// ReleaseHold releases the hold with the given ID before it expires.
+func (m *Manager) ReleaseHold(ctx context.Context, holdID string) error {
+	const q = `DELETE FROM account_holds WHERE id = $1`
+	res, err := m.db.ExecContext(ctx, q, holdID)
+	if err != nil {
+		return errors.Wrap(err, "deleting account hold")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if n == 0 {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "hold %s", holdID)
+	}
+	return nil
+}
+
+// Holds returns the account's holds that haven't expired.
+func (m *Manager) Holds(ctx context.Context, accountID string) ([]*Hold, error) {
+	const q = `
+		SELECT id, asset_id, amount, expires_at, reference
+		FROM account_holds WHERE account_id = $1 AND expires_at > $2
+		ORDER BY expires_at
+	`
+	holds := []*Hold{}
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, m.utxoDB.clock.Now(), func(id string, assetID bc.AssetID, amount uint64, expiresAt time.Time, ref string) {
+		holds = append(holds, &Hold{
+			ID:        id,
+			AccountID: accountID,
+			AssetID:   assetID,
+			Amount:    amount,
+			ExpiresAt: expiresAt,
+			Reference: ref,
+		})
+	})
+	return holds, err
+}
+
+// deleteExpiredHolds deletes the holds that have expired.
+func (m *Manager) deleteExpiredHolds(ctx context.Context) error {
+	const q = `DELETE FROM account_holds WHERE expires_at <= $1`
+	_, err := m.db.ExecContext(ctx, q, m.utxoDB.clock.Now())
+	return errors.Wrap(err, "deleting expired account holds")
+}
+
+// setSettlingTx records that the transaction with ID txID
+// settles the hold with ID holdID.
+func (m *Manager) setSettlingTx(ctx context.Context, holdID string, txID bc.Hash) error {
+	const q = `UPDATE account_holds SET settling_tx_id = $2 WHERE id = $1`
+	_, err := m.db.ExecContext(ctx, q, holdID, txID)
+	return errors.Wrap(err, "recording account hold settlement")
+}
+
+// ReleaseSettledHolds releases the holds settled by the
+// transactions with the given IDs. The Core calls it when it
+// accepts a transaction for submission, and for each block.
+func (m *Manager) ReleaseSettledHolds(ctx context.Context, txIDs ...bc.Hash) error {
+	var ids pq.ByteaArray
+	for _, id := range txIDs {
+		ids = append(ids, id.Bytes())
+	}
+	const q = `DELETE FROM account_holds WHERE settling_tx_id = ANY($1::bytea[])`
+	_, err := m.db.ExecContext(ctx, q, ids)
+	return errors.Wrap(err, "releasing settled account holds")
+}
+
+// heldAmount returns the units of src's asset held in src's
+// account, other than by the hold with ID settling, if any. Units
+// of a hold that live reservations already set aside to settle it
+// aren't held any more, since the reservations keep them from
+// being spent otherwise.
+func (re *reserver) heldAmount(ctx context.Context, src source, settling string) (uint64, error) {
+	const q = `
+		SELECT id, amount FROM account_holds
+		WHERE account_id = $1 AND asset_id = $2 AND expires_at > $3 AND id <> $4
+	`
+	re.reservationsMu.Lock()
+	settled := make(map[string]uint64, len(re.settling))
+	for id, n := range re.settling {
+		settled[id] = n
+	}
+	re.reservationsMu.Unlock()
+
+	held := bc.ZeroAmount(src.AssetID)
+	err := pg.ForQueryRows(ctx, re.db, q, src.AccountID, src.AssetID, re.clock.Now(), settling, func(id string, amount uint64) error {
+		if settled[id] >= amount {
+			return nil
+		}
+		unsettled, err := bc.NewAmount(src.AssetID, amount-settled[id])
+		if err != nil {
+			return err
+		}
+		held, err = held.Add(unsettled)
+		return err
+	})
+	return held.Units(), errors.Wrap(err, "summing account holds")
+}
+
+// claimSettlement records that a reservation will spend amount
+// units to settle hold h. It returns ErrBadHold if the
+// reservations settling h would spend more than its amount.
+func (re *reserver) claimSettlement(h *Hold, amount uint64) error {
+	re.reservationsMu.Lock()
+	defer re.reservationsMu.Unlock()
+	claimed := re.settling[h.ID]
+	if amount > h.Amount-claimed {
+		return errors.WithDetailf(ErrBadHold, "hold %s has %d units left to settle, less than %d", h.ID, h.Amount-claimed, amount)
+	}
+	re.settling[h.ID] = claimed + amount
+	return nil
+}
+
+// unclaimSettlement undoes claimSettlement, for a reservation
+// that is canceled or expires. The caller must hold
+// re.reservationsMu.
+func (re *reserver) unclaimSettlement(holdID string, amount uint64) {
+	if holdID == "" {
+		return
+	}
+	re.settling[holdID] -= amount
+	if re.settling[holdID] == 0 {
+		delete(re.settling, holdID)
+	}
+}
+
+// checkSettlement checks that spending amount units of src's
+// asset from src's account may settle the hold with ID holdID,
+// and returns the hold.
+func (m *Manager) checkSettlement(ctx context.Context, src source, amount uint64, holdID string) (*Hold, error) {
+	_, err := bc.NewAmount(src.AssetID, amount)
+	if err != nil {
+		return nil, err
+	}
+	const q = `SELECT account_id, asset_id, amount, expires_at FROM account_holds WHERE id = $1`
+	h := &Hold{ID: holdID}
+	err = m.db.QueryRowContext(ctx, q, holdID).Scan(&h.AccountID, &h.AssetID, &h.Amount, &h.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(ErrBadHold, "hold %s not found", holdID)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up account hold")
+	}
+	switch {
+	case !h.ExpiresAt.After(m.utxoDB.clock.Now()):
+		return nil, errors.WithDetailf(ErrBadHold, "hold %s has expired", holdID)
+	case h.AccountID != src.AccountID || h.AssetID != src.AssetID:
+		return nil, errors.WithDetailf(ErrBadHold, "hold %s is on a different account or asset", holdID)
+	case amount > h.Amount:
+		return nil, errors.WithDetailf(ErrBadHold, "hold %s is for %d units, less than %d", holdID, h.Amount, amount)
+	}
+	return h, nil
+}
@@ -0,0 +1,308 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/math/checked"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	// ErrNotHot is returned when reading the hot balance
+	// of an account that isn't designated hot.
+	ErrNotHot = errors.New("account is not hot")
+
+	// ErrStaleHotBalance is returned when an account's hot
+	// balance is not yet current as of the requested height.
+	ErrStaleHotBalance = errors.New("hot balance is not current")
+)
+
+// seedRetryPeriod is how long ProcessHotBalances waits
+// before retrying a seed interrupted by the indexers.
+var seedRetryPeriod = time.Second
+
+// HotBalance is the balance of a hot account as of
+// the end of the block at BlockHeight.
+type HotBalance struct {
+	AccountID   string           `json:"account_id"`
+	BlockHeight uint64           `json:"block_height"`
+	Balances    []HotAssetAmount `json:"balances"`
+}
+
+// HotAssetAmount is the amount of one asset in a HotBalance.
+type HotAssetAmount struct {
+	AssetID bc.AssetID `json:"asset_id"`
+	Amount  uint64     `json:"amount"`
+}
+
+// SetHot designates the account a hot account, or removes
+// the designation if hot is false.
+//
+// The balances of hot accounts are maintained by ApplyHotBlock
+// as each block is committed, instead of by the account
+// indexer, so they are current as soon as the chain's height
+// advances. A newly designated account's balance is available
+// once ProcessHotBalances has seeded it.
+func (m *Manager) SetHot(ctx context.Context, accountID string, hot bool) error {
+	account, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if !hot {
+		const q = `
+			WITH removed AS (
+				DELETE FROM hot_accounts WHERE account_id = $1
+			)
+			DELETE FROM hot_account_balances WHERE account_id = $1
+		`
+		_, err = m.db.ExecContext(ctx, q, account.ID)
+		return errors.Wrap(err, "removing hot account")
+	}
+
+	// Height 0 marks an account whose balance
+	// ProcessHotBalances hasn't seeded yet.
+	const q = `
+		INSERT INTO hot_accounts (account_id, block_height) VALUES ($1, 0)
+		ON CONFLICT (account_id) DO NOTHING
+	`
+	_, err = m.db.ExecContext(ctx, q, account.ID)
+	return errors.Wrap(err, "adding hot account")
+}
+
+// HotBalance returns the balance of the hot account. If it is
+// not yet current as of minHeight, it returns ErrStaleHotBalance.
+func (m *Manager) HotBalance(ctx context.Context, accountID string, minHeight uint64) (*HotBalance, error) {
+	const q = `
+		SELECT h.block_height, b.asset_id, b.amount
+		FROM hot_accounts h LEFT JOIN hot_account_balances b ON b.account_id = h.account_id AND b.amount > 0
+		WHERE h.account_id = $1
+		ORDER BY b.asset_id
+	`
+	var (
+		found bool
+		res   = &HotBalance{AccountID: accountID, Balances: []HotAssetAmount{}}
+	)
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, func(height uint64, assetID []byte, amount sql.NullInt64) {
+		found = true
+		res.BlockHeight = height
+		if amount.Valid {
+			var b32 [32]byte
+			copy(b32[:], assetID)
+			res.Balances = append(res.Balances, HotAssetAmount{
+				AssetID: bc.NewAssetID(b32),
+				Amount:  uint64(amount.Int64),
+			})
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading hot balance")
+	}
+	if !found {
+		return nil, errors.WithDetailf(ErrNotHot, "account %s", accountID)
+	}
+	if res.BlockHeight == 0 || res.BlockHeight < minHeight {
+		return nil, errors.WithDetailf(ErrStaleHotBalance, "current as of height %d, want %d", res.BlockHeight, minHeight)
+	}
+	return res, nil
+}
+
+// ApplyHotBlock updates the balances of hot accounts that are
+// current as of the block before b, so they are current as of b.
+// It is idempotent, and it is safe to call concurrently for the
+// same block.
+//
+// It is meant to be called as each block is committed, before
+// the chain's height advances to it; see protocol.Chain.OnCommit.
+func (m *Manager) ApplyHotBlock(ctx context.Context, b *legacy.Block) error {
+	var (
+		progs    pq.ByteaArray
+		assetIDs pq.ByteaArray
+		amounts  pq.Int64Array
+	)
+	add := func(prog []byte, assetID bc.AssetID, amount int64) {
+		progs = append(progs, prog)
+		assetIDs = append(assetIDs, assetID.Bytes())
+		amounts = append(amounts, amount)
+	}
+	for _, tx := range b.Transactions {
+		for _, in := range tx.Inputs {
+			if in.IsIssuance() {
+				continue
+			}
+			add(in.ControlProgram(), in.AssetID(), -int64(in.Amount()))
+		}
+		for _, out := range tx.Outputs {
+			add(out.ControlProgram, *out.AssetId, int64(out.Amount))
+		}
+	}
+
+	// Claiming the accounts current as of the previous block and
+	// updating their balances happen in one statement, so each
+	// block is applied to each account exactly once.
+	const q = `
+		WITH claimed AS (
+			UPDATE hot_accounts SET block_height = $1
+			WHERE block_height = $1 - 1
+			RETURNING account_id
+		), deltas AS (
+			SELECT acp.signer_id AS account_id, d.asset_id, SUM(d.amount) AS amount
+			FROM unnest($2::bytea[], $3::bytea[], $4::bigint[]) AS d(control_program, asset_id, amount)
+			JOIN account_control_programs acp ON acp.control_program = d.control_program
+			WHERE acp.signer_id IN (SELECT account_id FROM claimed)
+			GROUP BY 1, 2
+		)
+		INSERT INTO hot_account_balances (account_id, asset_id, amount)
+		SELECT account_id, asset_id, amount FROM deltas
+		ON CONFLICT (account_id, asset_id) DO UPDATE
+		SET amount = hot_account_balances.amount + excluded.amount
+	`
+	_, err := m.db.ExecContext(ctx, q, b.Height, progs, assetIDs, amounts)
+	return errors.Wrapf(err, "applying block %d to hot balances", b.Height)
+}
+
+// ProcessHotBalances seeds the balances of newly designated hot
+// accounts and brings accounts that have fallen behind, for
+// instance because ApplyHotBlock failed, up to the chain's
+// height. It must run only on the leader. It returns when ctx
+// is canceled.
+func (m *Manager) ProcessHotBalances(ctx context.Context) {
+	for {
+		caughtUp, err := m.catchUpHotBalances(ctx)
+		if err != nil {
+			log.Error(ctx, err, "processing hot balances")
+		}
+		var wait <-chan struct{}
+		if caughtUp {
+			wait = m.chain.BlockWaiter(m.chain.Height() + 1)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-wait:
+		case <-time.After(seedRetryPeriod):
+		}
+	}
+}
+
+// catchUpHotBalances seeds or advances each hot account that
+// isn't current as of the chain's height, and reports whether
+// they all are afterward.
+func (m *Manager) catchUpHotBalances(ctx context.Context) (caughtUp bool, err error) {
+	const q = `SELECT account_id, block_height FROM hot_accounts WHERE block_height < $1`
+	height := m.chain.Height()
+	behind := make(map[string]uint64)
+	err = pg.ForQueryRows(ctx, m.db, q, height, func(accountID string, height uint64) {
+		behind[accountID] = height
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "listing hot accounts")
+	}
+	for accountID, h := range behind {
+		if h == 0 {
+			err = m.seedHotBalance(ctx, accountID)
+			if err != nil {
+				return false, errors.Wrapf(err, "seeding hot account %s", accountID)
+			}
+			continue
+		}
+		// Each block applies to every account current as of
+		// the one before it, including accounts not in behind.
+		for ; h < height; h++ {
+			b, err := m.chain.GetBlock(ctx, h+1)
+			if err != nil {
+				return false, errors.Wrap(err, "getting block")
+			}
+			err = m.ApplyHotBlock(ctx, b)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	const checkQ = `SELECT NOT EXISTS (SELECT 1 FROM hot_accounts WHERE block_height < $1)`
+	err = m.db.QueryRowContext(ctx, checkQ, height).Scan(&caughtUp)
+	return caughtUp, errors.Wrap(err, "checking hot accounts")
+}
+
+// seedHotBalance computes the account's balance as of the
+// leader's current state snapshot from the account's UTXOs,
+// and records it as the starting point for ApplyHotBlock.
+//
+// The UTXOs confirmed by the snapshot's block, and not yet
+// spent as of it, are those in the snapshot's state tree, once
+// the account indexer has reached that block and as long as
+// spent UTXOs from later blocks haven't been deleted. If they
+// have, the seed is abandoned and retried later. A balance that
+// overflows an int64 fails the seed.
+func (m *Manager) seedHotBalance(ctx context.Context, accountID string) error {
+	block, snapshot := m.chain.State()
+	if block == nil || m.pinStore == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.pinStore.PinWaiter(PinName, block.Height):
+	}
+
+	const q = `
+		SELECT output_id, asset_id, amount FROM account_utxos
+		WHERE account_id = $1 AND confirmed_in <= $2
+	`
+	balances := make(map[bc.AssetID]int64)
+	var seen int
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, block.Height, func(outputID bc.Hash, assetID bc.AssetID, amount int64) error {
+		unspent, err := snapshot.Tree.Contains(outputID.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "reading state tree")
+		}
+		if !unspent {
+			return nil
+		}
+		sum, ok := checked.AddInt64(balances[assetID], amount)
+		if !ok {
+			return errors.WithDetailf(checked.ErrOverflow, "balance of asset %x", assetID.Bytes())
+		}
+		balances[assetID] = sum
+		seen++
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "reading account utxos")
+	}
+	if m.pinStore.Height(DeleteSpentsPinName) > block.Height {
+		log.Printkv(ctx, "at", "retrying hot account seed", "account", accountID, "height", block.Height)
+		return nil
+	}
+
+	var (
+		assetIDs pq.ByteaArray
+		amounts  pq.Int64Array
+	)
+	for assetID, amount := range balances {
+		assetIDs = append(assetIDs, assetID.Bytes())
+		amounts = append(amounts, amount)
+	}
+	const insertQ = `
+		WITH seeded AS (
+			UPDATE hot_accounts SET block_height = $2
+			WHERE account_id = $1 AND block_height = 0
+			RETURNING account_id
+		)
+		INSERT INTO hot_account_balances (account_id, asset_id, amount)
+		SELECT account_id, unnest($3::bytea[]), unnest($4::bigint[]) FROM seeded
+	`
+	_, err = m.db.ExecContext(ctx, insertQ, accountID, block.Height, assetIDs, amounts)
+	if err != nil {
+		return errors.Wrap(err, "saving hot account seed")
+	}
+	log.Printkv(ctx, "at", "seeded hot account", "account", accountID, "height", block.Height, "utxos", seen)
+	return nil
+}
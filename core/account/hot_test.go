@@ -0,0 +1,102 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestApplyHotBlock(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	hot := m.createTestAccount(ctx, t, "", nil)
+	hotProg := m.createTestControlProgram(ctx, t, hot.ID).controlProgram
+	coldProg := m.createTestControlProgram(ctx, t, "").controlProgram
+	assetID := bc.AssetID{V0: 1}
+
+	_, err := m.HotBalance(ctx, hot.ID, 0)
+	if errors.Root(err) != ErrNotHot {
+		t.Fatalf("HotBalance before SetHot: err = %v, want %v", err, ErrNotHot)
+	}
+	err = m.SetHot(ctx, hot.ID, true)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = m.HotBalance(ctx, hot.ID, 0)
+	if errors.Root(err) != ErrStaleHotBalance {
+		t.Fatalf("HotBalance before seeding: err = %v, want %v", err, ErrStaleHotBalance)
+	}
+
+	// Pretend the account was seeded, empty, as of block 1.
+	_, err = db.ExecContext(ctx, `UPDATE hot_accounts SET block_height = 1`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	block2 := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 2},
+		Transactions: []*legacy.Tx{legacy.NewTx(legacy.TxData{
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 10, hotProg, nil),
+				legacy.NewTxOutput(assetID, 5, hotProg, nil),
+				legacy.NewTxOutput(assetID, 7, coldProg, nil),
+			},
+		})},
+	}
+	block3 := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 3},
+		Transactions: []*legacy.Tx{legacy.NewTx(legacy.TxData{
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.Hash{}, assetID, 10, 0, hotProg, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 4, coldProg, nil),
+				legacy.NewTxOutput(assetID, 6, hotProg, nil),
+			},
+		})},
+	}
+
+	// Blocks applied more than once, or out of
+	// order, have no further effect.
+	for _, b := range []*legacy.Block{block2, block2, block3, block2, block3} {
+		err = m.ApplyHotBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	got, err := m.HotBalance(ctx, hot.ID, 3)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := &HotBalance{
+		AccountID:   hot.ID,
+		BlockHeight: 3,
+		Balances:    []HotAssetAmount{{AssetID: assetID, Amount: 11}},
+	}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("HotBalance = %+v, want %+v", got, want)
+	}
+
+	_, err = m.HotBalance(ctx, hot.ID, 4)
+	if errors.Root(err) != ErrStaleHotBalance {
+		t.Errorf("HotBalance at height 4: err = %v, want %v", err, ErrStaleHotBalance)
+	}
+
+	err = m.SetHot(ctx, hot.ID, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = m.HotBalance(ctx, hot.ID, 0)
+	if errors.Root(err) != ErrNotHot {
+		t.Errorf("HotBalance after removal: err = %v, want %v", err, ErrNotHot)
+	}
+}
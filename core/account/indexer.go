@@ -96,6 +96,7 @@ type accountOutput struct {
 	AccountID string
 	keyIndex  uint64
 	change    bool
+	vault     bool
 }
 
 func (m *Manager) ProcessBlocks(ctx context.Context) {
@@ -134,36 +135,47 @@ func (m *Manager) deleteSpentOutputs(ctx context.Context, b *legacy.Block) error
 
 func (m *Manager) indexAccountUTXOs(ctx context.Context, b *legacy.Block) error {
 	// Upsert any UTXOs belonging to accounts managed by this Core.
-	outs := make([]*rawOutput, 0, len(b.Transactions))
-	blockPositions := make(map[bc.Hash]uint32, len(b.Transactions))
-	for i, tx := range b.Transactions {
-		blockPositions[tx.ID] = uint32(i)
-		for j, out := range tx.Outputs {
-			resOutID := tx.ResultIds[j]
-			resOut, ok := tx.Entries[*resOutID].(*bc.Output)
-			if !ok {
-				continue
-			}
-			out := &rawOutput{
-				OutputID:       *tx.OutputID(j),
-				AssetAmount:    out.AssetAmount,
-				ControlProgram: out.ControlProgram,
-				txHash:         tx.ID,
-				outputIndex:    uint32(j),
-				sourceID:       *resOut.Source.Ref,
-				sourcePos:      resOut.Source.Position,
-				refData:        *resOut.Data,
-			}
-			outs = append(outs, out)
-		}
-	}
+	outs, blockPositions := blockOutputs(b)
 	accOuts, err := m.loadAccountInfo(ctx, outs)
 	if err != nil {
 		return errors.Wrap(err, "loading account info from control programs")
 	}
 
 	err = m.upsertConfirmedAccountOutputs(ctx, accOuts, blockPositions, b)
-	return errors.Wrap(err, "upserting confirmed account utxos")
+	if err != nil {
+		return errors.Wrap(err, "upserting confirmed account utxos")
+	}
+
+	// Release holds settled by transactions in the block,
+	// which may have been submitted through another Core.
+	txIDs := make([]bc.Hash, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		txIDs = append(txIDs, tx.ID)
+	}
+	return m.ReleaseSettledHolds(ctx, txIDs...)
+}
+
+// blockOutputs returns the outputs created in b, and the
+// position of each of its transactions.
+func blockOutputs(b *legacy.Block) ([]*rawOutput, map[bc.Hash]uint32) {
+	outs := make([]*rawOutput, 0, len(b.Transactions))
+	blockPositions := make(map[bc.Hash]uint32, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		blockPositions[tx.ID] = uint32(i)
+	}
+	for _, out := range legacy.MapBlock(b).NewOutputs() {
+		outs = append(outs, &rawOutput{
+			OutputID:       out.OutputID,
+			AssetAmount:    out.AssetAmount,
+			ControlProgram: out.ControlProgram.Code,
+			txHash:         out.TxID,
+			outputIndex:    out.OutputIndex,
+			sourceID:       *out.Output.Source.Ref,
+			sourcePos:      out.Output.Source.Position,
+			refData:        *out.Output.Data,
+		})
+	}
+	return outs, blockPositions
 }
 
 func prevoutDBKeys(txs ...*legacy.Tx) (outputIDs pq.ByteaArray) {
@@ -182,8 +194,12 @@ func prevoutDBKeys(txs ...*legacy.Tx) (outputIDs pq.ByteaArray) {
 // annotated are excluded from the result.
 func (m *Manager) loadAccountInfo(ctx context.Context, outs []*rawOutput) ([]*accountOutput, error) {
 	outsByScript := make(map[string][]*rawOutput, len(outs))
+	vaults := make(map[string]bool)
 	for _, out := range outs {
-		scriptStr := string(out.ControlProgram)
+		scriptStr, vault := programKey(out.ControlProgram)
+		if vault {
+			vaults[scriptStr] = true
+		}
 		outsByScript[scriptStr] = append(outsByScript[scriptStr], out)
 	}
 
@@ -206,6 +222,7 @@ func (m *Manager) loadAccountInfo(ctx context.Context, outs []*rawOutput) ([]*ac
 				AccountID: accountID,
 				keyIndex:  keyIndex,
 				change:    change,
+				vault:     vaults[string(program)],
 			}
 			result = append(result, newOut)
 		}
@@ -232,6 +249,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos pq.Int64Array
 		refData   pq.ByteaArray
 		change    pq.BoolArray
+		vault     pq.BoolArray
 	)
 	for _, out := range outs {
 		outputID = append(outputID, out.OutputID.Bytes())
@@ -244,14 +262,16 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos = append(sourcePos, int64(out.sourcePos))
 		refData = append(refData, out.refData.Bytes())
 		change = append(change, out.change)
+		vault = append(vault, out.vault)
 	}
 
 	const q = `
 		INSERT INTO account_utxos (output_id, asset_id, amount, account_id, control_program_index,
-			control_program, confirmed_in, source_id, source_pos, ref_data_hash, change)
+			control_program, confirmed_in, source_id, source_pos, ref_data_hash, change, vault)
 		SELECT unnest($1::bytea[]), unnest($2::bytea[]),  unnest($3::bigint[]),
 			   unnest($4::text[]), unnest($5::bigint[]), unnest($6::bytea[]), $7,
-			   unnest($8::bytea[]), unnest($9::bigint[]), unnest($10::bytea[]), unnest($11::boolean[])
+			   unnest($8::bytea[]), unnest($9::bigint[]), unnest($10::bytea[]), unnest($11::boolean[]),
+			   unnest($12::boolean[])
 		ON CONFLICT (output_id) DO NOTHING
 	`
 	_, err := m.db.ExecContext(ctx, q,
@@ -266,6 +286,7 @@ func (m *Manager) upsertConfirmedAccountOutputs(ctx context.Context, outs []*acc
 		sourcePos,
 		refData,
 		change,
+		vault,
 	)
 	return errors.Wrap(err)
 }
@@ -0,0 +1,360 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	// ErrPolicyViolation is returned when a transaction sends an
+	// account's funds somewhere the account's policy forbids.
+	ErrPolicyViolation = errors.New("transaction violates account policy")
+
+	// ErrPolicyVersion is returned when an account's policy is
+	// not at the version the caller expected, because it has
+	// changed since the caller last read it.
+	ErrPolicyVersion = errors.New("account policy version mismatch")
+)
+
+// Policy restricts where an account's funds may be sent.
+//
+// An output is forbidden if its control program, the account that
+// control program belongs to, or its asset is denied. Otherwise,
+// if any programs or accounts are allowed, the output must pay to
+// one of them, and if any assets are allowed, its asset must be
+// one of them. Outputs paying back to the account itself, such as
+// change, are always permitted.
+//
+// Each change to an account's policy gets a new version. Version 0
+// is the empty policy every account starts with.
+type Policy struct {
+	Version       uint64               `json:"version"`
+	AllowPrograms []chainjson.HexBytes `json:"allow_control_programs,omitempty"`
+	DenyPrograms  []chainjson.HexBytes `json:"deny_control_programs,omitempty"`
+	AllowAccounts []string             `json:"allow_accounts,omitempty"`
+	DenyAccounts  []string             `json:"deny_accounts,omitempty"`
+	AllowAssets   []bc.AssetID         `json:"allow_assets,omitempty"`
+	DenyAssets    []bc.AssetID         `json:"deny_assets,omitempty"`
+}
+
+// permits reports whether p lets funds of the given asset
+// be sent to prog, which belongs to destAccountID, if that
+// is not empty.
+func (p *Policy) permits(prog []byte, destAccountID string, assetID bc.AssetID) bool {
+	for _, deny := range p.DenyPrograms {
+		if bytes.Equal(deny, prog) {
+			return false
+		}
+	}
+	if destAccountID != "" && containsString(p.DenyAccounts, destAccountID) {
+		return false
+	}
+	if containsAsset(p.DenyAssets, assetID) {
+		return false
+	}
+	if len(p.AllowAssets) > 0 && !containsAsset(p.AllowAssets, assetID) {
+		return false
+	}
+	if len(p.AllowPrograms) == 0 && len(p.AllowAccounts) == 0 {
+		return true
+	}
+	for _, allow := range p.AllowPrograms {
+		if bytes.Equal(allow, prog) {
+			return true
+		}
+	}
+	return destAccountID != "" && containsString(p.AllowAccounts, destAccountID)
+}
+
+func containsString(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAsset(l []bc.AssetID, a bc.AssetID) bool {
+	for _, x := range l {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy returns the account's current policy.
+func (m *Manager) Policy(ctx context.Context, accountID string) (*Policy, error) {
+	_, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := m.loadPolicies(ctx, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := policies[accountID]; ok {
+		return p, nil
+	}
+	return &Policy{}, nil
+}
+
+// SetPolicy replaces the account's policy with p, giving it the
+// next version. p.Version must be the version being replaced;
+// if it is not the current one, SetPolicy returns
+// ErrPolicyVersion and changes nothing.
+func (m *Manager) SetPolicy(ctx context.Context, accountID string, p *Policy) (*Policy, error) {
+	_, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *p
+	next.Version = p.Version + 1
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	const q = `
+		INSERT INTO account_policies (account_id, version, policy)
+		SELECT $1, $2, $3
+		WHERE (SELECT COALESCE(max(version), 0) FROM account_policies WHERE account_id = $1) = $4
+		ON CONFLICT (account_id, version) DO NOTHING
+	`
+	res, err := m.db.ExecContext(ctx, q, accountID, next.Version, data, p.Version)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting account policy")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	if n == 0 {
+		return nil, errors.WithDetailf(ErrPolicyVersion, "account %s policy is no longer at version %d", accountID, p.Version)
+	}
+	return &next, nil
+}
+
+// ApproveOverride lets the account's funds be sent to prog until
+// expiresAt, even if the account's policy forbids it. The approval
+// applies only while the policy remains at version; if the policy
+// is not at that version now, ApproveOverride returns
+// ErrPolicyVersion.
+func (m *Manager) ApproveOverride(ctx context.Context, accountID string, version uint64, prog []byte, expiresAt time.Time) error {
+	_, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	const q = `
+		INSERT INTO account_policy_overrides (account_id, policy_version, control_program, expires_at)
+		SELECT $1, $2, $3, $4
+		WHERE (SELECT COALESCE(max(version), 0) FROM account_policies WHERE account_id = $1) = $2
+		ON CONFLICT (account_id, policy_version, control_program)
+		DO UPDATE SET expires_at = excluded.expires_at
+	`
+	res, err := m.db.ExecContext(ctx, q, accountID, version, prog, expiresAt)
+	if err != nil {
+		return errors.Wrap(err, "inserting policy override")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if n == 0 {
+		return errors.WithDetailf(ErrPolicyVersion, "account %s policy is not at version %d", accountID, version)
+	}
+	return nil
+}
+
+// CheckPolicies checks tx against the policies of the accounts
+// whose UTXOs it spends, returning ErrPolicyViolation if it
+// sends their funds somewhere forbidden. It is for transactions
+// submitted to this Core, which may have been built elsewhere
+// or modified after building.
+func (m *Manager) CheckPolicies(ctx context.Context, tx *legacy.Tx) error {
+	spent := prevoutDBKeys(tx)
+	if len(spent) == 0 {
+		return nil
+	}
+	spends := make(map[string]map[bc.AssetID]bool)
+	const q = `
+		SELECT account_id, asset_id FROM account_utxos
+		WHERE output_id = ANY($1::bytea[])
+	`
+	err := pg.ForQueryRows(ctx, m.db, q, spent, func(accountID string, assetID bc.AssetID) {
+		if spends[accountID] == nil {
+			spends[accountID] = make(map[bc.AssetID]bool)
+		}
+		spends[accountID][assetID] = true
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading spent account utxos")
+	}
+	return m.checkPolicies(ctx, spends, tx.Outputs, nil)
+}
+
+// checkPolicyOnBuild arranges for the account's policy to be
+// checked against the outputs of the transaction being built,
+// once all of its actions are built. Only outputs of the given
+// assets carry the account's funds.
+func (m *Manager) checkPolicyOnBuild(ctx context.Context, b *txbuilder.TemplateBuilder, accountID string, assetIDs ...bc.AssetID) {
+	assets := make(map[bc.AssetID]bool, len(assetIDs))
+	for _, assetID := range assetIDs {
+		assets[assetID] = true
+	}
+	b.OnBuild(func() error {
+		// Control programs created by this build may not
+		// have been inserted yet.
+		m.delayedACPsMu.Lock()
+		pending := append([]*controlProgram(nil), m.delayedACPs[b]...)
+		m.delayedACPsMu.Unlock()
+
+		spends := map[string]map[bc.AssetID]bool{accountID: assets}
+		return m.checkPolicies(ctx, spends, b.Outputs(), pending)
+	})
+}
+
+// checkPolicies checks outputs against the policy of each
+// account in spends, for the outputs of the assets spent
+// from that account. Pending holds control programs that
+// are not yet in the database.
+func (m *Manager) checkPolicies(ctx context.Context, spends map[string]map[bc.AssetID]bool, outputs []*legacy.TxOutput, pending []*controlProgram) error {
+	accountIDs := make([]string, 0, len(spends))
+	for accountID := range spends {
+		accountIDs = append(accountIDs, accountID)
+	}
+	policies, err := m.loadPolicies(ctx, accountIDs)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+
+	owners, err := m.programOwners(ctx, outputs, pending)
+	if err != nil {
+		return errors.Wrap(err, "loading destination accounts")
+	}
+
+	for accountID, p := range policies {
+		var forbidden []*legacy.TxOutput
+		for _, out := range outputs {
+			if !spends[accountID][*out.AssetId] {
+				continue
+			}
+			owner := owners[string(out.ControlProgram)]
+			if owner == accountID || p.permits(out.ControlProgram, owner, *out.AssetId) {
+				continue
+			}
+			forbidden = append(forbidden, out)
+		}
+		if len(forbidden) == 0 {
+			continue
+		}
+
+		approved, err := m.approvedOverrides(ctx, accountID, p.Version, forbidden)
+		if err != nil {
+			return errors.Wrap(err, "loading policy overrides")
+		}
+		for _, out := range forbidden {
+			if !approved[string(out.ControlProgram)] {
+				return errors.WithDetailf(ErrPolicyViolation,
+					"policy version %d of account %s forbids sending asset %x to control program %x",
+					p.Version, accountID, out.AssetId.Bytes(), out.ControlProgram)
+			}
+		}
+	}
+	return nil
+}
+
+// loadPolicies returns the current policies of the given
+// accounts, keyed by account ID. Accounts that have never
+// had a policy are omitted.
+func (m *Manager) loadPolicies(ctx context.Context, accountIDs []string) (map[string]*Policy, error) {
+	policies := make(map[string]*Policy)
+	const q = `
+		SELECT DISTINCT ON (account_id) account_id, version, policy
+		FROM account_policies
+		WHERE account_id = ANY($1::text[])
+		ORDER BY account_id, version DESC
+	`
+	var decodeErr error
+	err := pg.ForQueryRows(ctx, m.db, q, pq.StringArray(accountIDs), func(accountID string, version uint64, data []byte) {
+		p := new(Policy)
+		err := json.Unmarshal(data, p)
+		if err != nil {
+			decodeErr = errors.Wrapf(err, "decoding policy of account %s", accountID)
+			return
+		}
+		p.Version = version
+		policies[accountID] = p
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "loading account policies")
+	}
+	return policies, decodeErr
+}
+
+// programOwners returns the accounts of this Core that the
+// control programs of outputs belong to, keyed by program.
+func (m *Manager) programOwners(ctx context.Context, outputs []*legacy.TxOutput, pending []*controlProgram) (map[string]string, error) {
+	progs := make([][]byte, 0, len(outputs))
+	for _, out := range outputs {
+		progs = append(progs, out.ControlProgram)
+	}
+	owners, err := m.ProgramAccounts(ctx, progs)
+	if err != nil {
+		return nil, err
+	}
+	for _, acp := range pending {
+		owners[string(acp.controlProgram)] = acp.accountID
+	}
+	return owners, nil
+}
+
+// ProgramAccounts returns the accounts of this Core that the
+// given control programs belong to, keyed by program. Programs
+// of other accounts or Cores are omitted.
+func (m *Manager) ProgramAccounts(ctx context.Context, progs [][]byte) (map[string]string, error) {
+	owners := make(map[string]string)
+	const q = `
+		SELECT control_program, signer_id FROM account_control_programs
+		WHERE control_program = ANY($1::bytea[])
+	`
+	err := pg.ForQueryRows(ctx, m.db, q, pq.ByteaArray(progs), func(prog []byte, accountID string) {
+		owners[string(prog)] = accountID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "loading control program accounts")
+	}
+	return owners, nil
+}
+
+// approvedOverrides returns the control programs of outs that
+// have an unexpired override for the account's policy version.
+func (m *Manager) approvedOverrides(ctx context.Context, accountID string, version uint64, outs []*legacy.TxOutput) (map[string]bool, error) {
+	var progs pq.ByteaArray
+	for _, out := range outs {
+		progs = append(progs, out.ControlProgram)
+	}
+	approved := make(map[string]bool)
+	const q = `
+		SELECT control_program FROM account_policy_overrides
+		WHERE account_id = $1 AND policy_version = $2
+			AND control_program = ANY($3::bytea[]) AND expires_at > now()
+	`
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, version, progs, func(prog []byte) {
+		approved[string(prog)] = true
+	})
+	return approved, err
+}
@@ -0,0 +1,42 @@
+package account
+
+import (
+	"testing"
+
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+)
+
+func TestPolicyPermits(t *testing.T) {
+	var (
+		asset1 = bc.AssetID(bc.NewHash([32]byte{1}))
+		asset2 = bc.AssetID(bc.NewHash([32]byte{2}))
+		prog1  = []byte{1}
+		prog2  = []byte{2}
+	)
+	cases := []struct {
+		name    string
+		policy  Policy
+		prog    []byte
+		account string
+		asset   bc.AssetID
+		want    bool
+	}{
+		{"empty policy", Policy{}, prog1, "", asset1, true},
+		{"denied program", Policy{DenyPrograms: []chainjson.HexBytes{prog1}}, prog1, "", asset1, false},
+		{"denied account", Policy{DenyAccounts: []string{"acc1"}}, prog1, "acc1", asset1, false},
+		{"denied asset", Policy{DenyAssets: []bc.AssetID{asset1}}, prog1, "", asset1, false},
+		{"other asset allowed", Policy{AllowAssets: []bc.AssetID{asset2}}, prog1, "", asset1, false},
+		{"asset allowed", Policy{AllowAssets: []bc.AssetID{asset1}}, prog1, "", asset1, true},
+		{"program allowed", Policy{AllowPrograms: []chainjson.HexBytes{prog1}}, prog1, "", asset1, true},
+		{"program not allowed", Policy{AllowPrograms: []chainjson.HexBytes{prog1}}, prog2, "", asset1, false},
+		{"account allowed", Policy{AllowPrograms: []chainjson.HexBytes{prog1}, AllowAccounts: []string{"acc2"}}, prog2, "acc2", asset1, true},
+		{"allowed but denied", Policy{AllowAccounts: []string{"acc1"}, DenyPrograms: []chainjson.HexBytes{prog2}}, prog2, "acc1", asset1, false},
+	}
+	for _, c := range cases {
+		got := c.policy.permits(c.prog, c.account, c.asset)
+		if got != c.want {
+			t.Errorf("%s: permits = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+
+	"chain/core/query"
+	"chain/core/signers"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrBadRescanRange is returned by RescanAccount when the
+// height range is empty or extends past the end of the chain.
+var ErrBadRescanRange = errors.New("invalid rescan height range")
+
+// RescanAccount re-indexes the outputs in blocks fromHeight
+// through toHeight, inclusive, that pay to the account. It
+// repairs the account's UTXOs and annotations when control
+// programs are recorded after the blocks that use them were
+// indexed, for instance after restoring them from a backup,
+// without requiring a full reindex.
+//
+// The account's control programs are re-derived from its keys
+// at each key index recorded for it. Unspent outputs paying to
+// them are added to the account's UTXOs, and annotated
+// transactions, inputs and outputs involving them that have no
+// account annotation are re-annotated. Other rows are left
+// alone, so a rescan is safe to repeat.
+//
+// If progress is not nil, it is called with the height of each
+// block once that block has been rescanned.
+func (m *Manager) RescanAccount(ctx context.Context, accountID string, fromHeight, toHeight uint64, progress func(height uint64)) error {
+	if fromHeight == 0 || fromHeight > toHeight || toHeight > m.chain.Height() {
+		return errors.WithDetailf(ErrBadRescanRange, "heights %d to %d, chain height %d", fromHeight, toHeight, m.chain.Height())
+	}
+
+	account, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	progs, err := m.deriveAccountPrograms(ctx, account)
+	if err != nil {
+		return errors.Wrap(err, "deriving account control programs")
+	}
+	ann, err := m.loadAccountAnnotation(ctx, account.ID)
+	if err != nil {
+		return errors.Wrap(err, "loading account annotation")
+	}
+
+	// Let the indexers get past the range first,
+	// so they don't race with the rescan.
+	if m.pinStore != nil {
+		for _, pin := range []string{PinName, query.TxPinName} {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-m.pinStore.PinWaiter(pin, toHeight):
+			}
+		}
+	}
+
+	for height := fromHeight; height <= toHeight; height++ {
+		b, err := m.chain.GetBlock(ctx, height)
+		if err != nil {
+			return errors.Wrapf(err, "getting block %d", height)
+		}
+		err = m.rescanBlock(ctx, b, progs, ann)
+		if err != nil {
+			return errors.Wrapf(err, "rescanning block %d", height)
+		}
+		if progress != nil {
+			progress(height)
+		}
+	}
+	return nil
+}
+
+// deriveAccountPrograms returns the account's control programs,
+// keyed by program, derived from its keys at each key index
+// recorded for it in its control programs or UTXOs.
+func (m *Manager) deriveAccountPrograms(ctx context.Context, account *signers.Signer) (map[string]*controlProgram, error) {
+	const q = `
+		SELECT key_index, change FROM account_control_programs WHERE signer_id = $1
+		UNION
+		SELECT control_program_index, change FROM account_utxos WHERE account_id = $1
+	`
+	progs := make(map[string]*controlProgram)
+	var deriveErr error
+	err := pg.ForQueryRows(ctx, m.db, q, account.ID, func(keyIndex uint64, change bool) {
+		if deriveErr != nil {
+			return
+		}
+		prog, err := deriveControlProgram(account, keyIndex)
+		if err != nil {
+			deriveErr = errors.Wrapf(err, "key index %d", keyIndex)
+			return
+		}
+		progs[string(prog)] = &controlProgram{
+			accountID:      account.ID,
+			keyIndex:       keyIndex,
+			controlProgram: prog,
+			change:         change,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if deriveErr != nil {
+		return nil, deriveErr
+	}
+
+	// A vault's program can't be derived from its key index
+	// alone, so the stored one is used.
+	const vaultQ = `SELECT key_index, control_program FROM account_control_programs WHERE signer_id = $1`
+	err = pg.ForQueryRows(ctx, m.db, vaultQ, account.ID, func(keyIndex uint64, prog []byte) {
+		_, vault := programKey(prog)
+		if vault {
+			progs[string(prog)] = &controlProgram{
+				accountID:      account.ID,
+				keyIndex:       keyIndex,
+				controlProgram: prog,
+				vault:          true,
+			}
+		}
+	})
+	return progs, err
+}
+
+// accountAnnotation holds the account fields
+// of annotated inputs and outputs.
+type accountAnnotation struct {
+	id    string
+	alias sql.NullString
+	tags  json.RawMessage
+}
+
+func (m *Manager) loadAccountAnnotation(ctx context.Context, accountID string) (*accountAnnotation, error) {
+	ann := &accountAnnotation{id: accountID}
+	var tags []byte
+	const q = `SELECT alias, tags FROM accounts WHERE account_id = $1`
+	err := m.db.QueryRowContext(ctx, q, accountID).Scan(&ann.alias, &tags)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	ann.tags = empty
+	if len(tags) > 0 {
+		ann.tags = tags
+	}
+	return ann, nil
+}
+
+// rescanBlock adds the unspent outputs of b paying to progs to
+// the account's UTXOs and re-annotates them.
+//
+// Whether an output is unspent is read from the chain's current
+// snapshot. The spent-output deleter must have caught up with
+// that snapshot before the UTXOs are inserted, or it could delete
+// them first and leave one that is spent in a later block. If the
+// deleter moves past the snapshot while inserting, the block is
+// checked again against the newer snapshot.
+func (m *Manager) rescanBlock(ctx context.Context, b *legacy.Block, progs map[string]*controlProgram, ann *accountAnnotation) error {
+	outs, blockPositions := blockOutputs(b)
+	for {
+		snapshotBlock, snapshot := m.chain.State()
+		if m.pinStore != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-m.pinStore.PinWaiter(DeleteSpentsPinName, snapshotBlock.Height):
+			}
+		}
+
+		var accOuts, unspent []*accountOutput
+		var spent pq.ByteaArray
+		for _, out := range outs {
+			key, _ := programKey(out.ControlProgram)
+			cp, ok := progs[key]
+			if !ok {
+				continue
+			}
+			accOut := &accountOutput{
+				rawOutput: *out,
+				AccountID: cp.accountID,
+				keyIndex:  cp.keyIndex,
+				change:    cp.change,
+				vault:     cp.vault,
+			}
+			accOuts = append(accOuts, accOut)
+			ok, err := snapshot.Tree.Contains(out.OutputID.Bytes())
+			if err != nil {
+				return errors.Wrap(err, "checking output")
+			}
+			if ok {
+				unspent = append(unspent, accOut)
+			} else {
+				spent = append(spent, out.OutputID.Bytes())
+			}
+		}
+		if len(accOuts) == 0 {
+			return nil
+		}
+
+		// Outputs inserted by an earlier pass may have
+		// been spent since.
+		const delQ = `DELETE FROM account_utxos WHERE output_id = ANY($1::bytea[])`
+		_, err := m.db.ExecContext(ctx, delQ, spent)
+		if err != nil {
+			return errors.Wrap(err, "deleting spent account utxos")
+		}
+		err = m.upsertConfirmedAccountOutputs(ctx, unspent, blockPositions, b)
+		if err != nil {
+			return errors.Wrap(err, "upserting confirmed account utxos")
+		}
+		if m.pinStore == nil || m.pinStore.Height(DeleteSpentsPinName) <= snapshotBlock.Height {
+			return errors.Wrap(m.reannotate(ctx, accOuts, ann), "re-annotating")
+		}
+	}
+}
+
+// reannotate adds the account annotation to the annotated
+// outputs in outs, the inputs that spend them, and the
+// transactions containing either, where it is missing.
+func (m *Manager) reannotate(ctx context.Context, outs []*accountOutput, ann *accountAnnotation) error {
+	var (
+		outputIDs pq.ByteaArray
+		change    pq.BoolArray
+		byID      = make(map[bc.Hash]*accountOutput, len(outs))
+		tags      = sql.NullString{String: string(ann.tags), Valid: true}
+	)
+	for _, out := range outs {
+		outputIDs = append(outputIDs, out.OutputID.Bytes())
+		change = append(change, out.change)
+		byID[out.OutputID] = out
+	}
+
+	const outputsQ = `
+		UPDATE annotated_outputs o
+		SET account_id = $3, account_alias = $4, account_tags = $5,
+			purpose = CASE WHEN t.change THEN 'change' ELSE 'receive' END
+		FROM unnest($1::bytea[], $2::boolean[]) AS t(output_id, change)
+		WHERE o.output_id = t.output_id AND o.account_id IS NULL
+	`
+	_, err := m.queryDB.ExecContext(ctx, outputsQ, outputIDs, change, ann.id, ann.alias, tags)
+	if err != nil {
+		return errors.Wrap(err, "updating annotated outputs")
+	}
+
+	const inputsQ = `
+		UPDATE annotated_inputs
+		SET account_id = $2, account_alias = $3, account_tags = $4
+		WHERE spent_output_id = ANY($1::bytea[]) AND account_id IS NULL
+	`
+	_, err = m.queryDB.ExecContext(ctx, inputsQ, outputIDs, ann.id, ann.alias, tags)
+	if err != nil {
+		return errors.Wrap(err, "updating annotated inputs")
+	}
+
+	type txRow struct {
+		height uint64
+		pos    uint32
+		data   []byte
+	}
+	var rows []txRow
+	const txsQ = `
+		SELECT block_height, tx_pos, data FROM annotated_txs
+		WHERE tx_hash IN (
+			SELECT tx_hash FROM annotated_outputs WHERE output_id = ANY($1::bytea[])
+			UNION
+			SELECT tx_hash FROM annotated_inputs WHERE spent_output_id = ANY($1::bytea[])
+		)
+	`
+	err = pg.ForQueryRows(ctx, m.queryDB, txsQ, outputIDs, func(height uint64, pos uint32, data []byte) {
+		rows = append(rows, txRow{height, pos, data})
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading annotated txs")
+	}
+
+	const updateTxQ = `UPDATE annotated_txs SET data = $3 WHERE block_height = $1 AND tx_pos = $2`
+	for _, row := range rows {
+		var tx query.AnnotatedTx
+		err = json.Unmarshal(row.data, &tx)
+		if err != nil {
+			return errors.Wrapf(err, "decoding annotated tx at %d:%d", row.height, row.pos)
+		}
+		if !applyAccountAnnotation(&tx, byID, ann) {
+			continue
+		}
+		data, err := json.Marshal(&tx)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		_, err = m.queryDB.ExecContext(ctx, updateTxQ, row.height, row.pos, data)
+		if err != nil {
+			return errors.Wrapf(err, "updating annotated tx at %d:%d", row.height, row.pos)
+		}
+	}
+	return nil
+}
+
+// applyAccountAnnotation annotates the inputs and outputs
+// of tx that spend or create one of outs and have no account
+// annotation. It reports whether it changed anything.
+func applyAccountAnnotation(tx *query.AnnotatedTx, outs map[bc.Hash]*accountOutput, ann *accountAnnotation) bool {
+	var changed bool
+	for _, in := range tx.Inputs {
+		if in.SpentOutputID == nil || in.AccountID != "" {
+			continue
+		}
+		if _, ok := outs[*in.SpentOutputID]; !ok {
+			continue
+		}
+		in.AccountID = ann.id
+		in.AccountAlias = ann.alias.String
+		tags := ann.tags
+		in.AccountTags = &tags
+		changed = true
+	}
+	for _, out := range tx.Outputs {
+		accOut, ok := outs[out.OutputID]
+		if !ok || out.AccountID != "" {
+			continue
+		}
+		out.AccountID = ann.id
+		out.AccountAlias = ann.alias.String
+		tags := ann.tags
+		out.AccountTags = &tags
+		out.Purpose = "receive"
+		if accOut.change {
+			out.Purpose = "change"
+		}
+		changed = true
+	}
+	return changed
+}
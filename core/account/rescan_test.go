@@ -0,0 +1,81 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"chain/core/query"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+)
+
+func TestRescanAccountRange(t *testing.T) {
+	c := prottest.NewChain(t)
+	prottest.MakeBlock(t, c, nil)
+	m := NewManager(nil, c, nil)
+	ctx := context.Background()
+
+	cases := []struct{ from, to uint64 }{
+		{0, 1},
+		{2, 1},
+		{1, c.Height() + 1},
+	}
+	for _, tc := range cases {
+		err := m.RescanAccount(ctx, "acc", tc.from, tc.to, nil)
+		if errors.Root(err) != ErrBadRescanRange {
+			t.Errorf("RescanAccount(%d, %d) = %v, want %s", tc.from, tc.to, err, ErrBadRescanRange)
+		}
+	}
+}
+
+func TestApplyAccountAnnotation(t *testing.T) {
+	var (
+		spent   = bc.NewHash([32]byte{1})
+		created = bc.NewHash([32]byte{2})
+		other   = bc.NewHash([32]byte{3})
+	)
+	ann := &accountAnnotation{
+		id:    "acc1",
+		alias: sql.NullString{String: "alice", Valid: true},
+		tags:  json.RawMessage(`{"x":1}`),
+	}
+	outs := map[bc.Hash]*accountOutput{
+		spent:   {AccountID: "acc1"},
+		created: {AccountID: "acc1", change: true},
+	}
+	tx := &query.AnnotatedTx{
+		Inputs: []*query.AnnotatedInput{
+			{SpentOutputID: &spent},
+			{SpentOutputID: &other},
+			{},
+		},
+		Outputs: []*query.AnnotatedOutput{
+			{OutputID: created},
+			{OutputID: other},
+		},
+	}
+
+	if !applyAccountAnnotation(tx, outs, ann) {
+		t.Fatal("expected annotation to change tx")
+	}
+	if in := tx.Inputs[0]; in.AccountID != "acc1" || in.AccountAlias != "alice" || string(*in.AccountTags) != `{"x":1}` {
+		t.Errorf("input 0 = %+v, want annotated with acc1", in)
+	}
+	if tx.Inputs[1].AccountID != "" || tx.Inputs[2].AccountID != "" {
+		t.Error("annotated input not spending an account output")
+	}
+	if out := tx.Outputs[0]; out.AccountID != "acc1" || out.Purpose != "change" {
+		t.Errorf("output 0 = %+v, want change for acc1", out)
+	}
+	if tx.Outputs[1].AccountID != "" {
+		t.Error("annotated output not paying to the account")
+	}
+
+	// Rows that are already annotated are left alone.
+	if applyAccountAnnotation(tx, outs, ann) {
+		t.Error("expected second annotation to change nothing")
+	}
+}
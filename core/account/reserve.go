@@ -8,9 +8,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"chain/clock"
 	"chain/core/pin"
 	"chain/database/pg"
 	"chain/errors"
+	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/sync/idempotency"
@@ -69,6 +71,11 @@ type reservation struct {
 	Change      uint64
 	Expiry      time.Time
 	ClientToken *string
+
+	// Settling is the ID of the hold the reservation may spend,
+	// if any, and SettlingAmount is how much of it.
+	Settling       string
+	SettlingAmount uint64
 }
 
 func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
@@ -76,7 +83,9 @@ func newReserver(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *reserver {
 		c:            c,
 		db:           db,
 		pinStore:     pinStore,
+		clock:        clock.Real,
 		reservations: make(map[uint64]*reservation),
+		settling:     make(map[string]uint64),
 		sources:      make(map[source]*sourceReserver),
 	}
 }
@@ -95,35 +104,69 @@ type reserver struct {
 	c                 *protocol.Chain
 	db                pg.DB
 	pinStore          *pin.Store
+	clock             clock.Clock
 	nextReservationID uint64
 	idempotency       idempotency.Group
 
 	reservationsMu sync.Mutex
 	reservations   map[uint64]*reservation
+	settling       map[string]uint64 // hold ID -> units reserved to settle it
 
 	sourcesMu sync.Mutex
 	sources   map[source]*sourceReserver
+
+	statsMu sync.Mutex
+	stats   ReaperStats
 }
 
 // Reserve selects and reserves UTXOs according to the criteria provided
-// in source. The resulting reservation expires at exp.
-func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (*reservation, error) {
+// in source. The resulting reservation expires at exp. It leaves
+// enough of the account's funds unreserved to cover its holds, other
+// than the hold settling, if any, which the reservation may spend.
+// The reservations settling a hold may spend at most its amount
+// between them.
+func (re *reserver) Reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time, settling *Hold) (*reservation, error) {
 	if clientToken == nil {
-		return re.reserve(ctx, src, amount, clientToken, exp)
+		return re.reserve(ctx, src, amount, clientToken, exp, settling)
 	}
 
 	untypedRes, err := re.idempotency.Once(*clientToken, func() (interface{}, error) {
-		return re.reserve(ctx, src, amount, clientToken, exp)
+		return re.reserve(ctx, src, amount, clientToken, exp, settling)
 	})
 	return untypedRes.(*reservation), err
 }
 
-func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time) (res *reservation, err error) {
+func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clientToken *string, exp time.Time, settling *Hold) (res *reservation, err error) {
+	_, err = bc.NewAmount(src.AssetID, amount)
+	if err != nil {
+		return nil, err
+	}
 	sourceReserver := re.source(src)
 
+	var settlingID string
+	if settling != nil {
+		settlingID = settling.ID
+		err = re.claimSettlement(settling, amount)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				re.reservationsMu.Lock()
+				re.unclaimSettlement(settlingID, amount)
+				re.reservationsMu.Unlock()
+			}
+		}()
+	}
+
+	held, err := re.heldAmount(ctx, src, settlingID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Try to reserve the right amount.
 	rid := atomic.AddUint64(&re.nextReservationID, 1)
-	reserved, total, err := sourceReserver.reserve(ctx, rid, amount)
+	reserved, total, err := sourceReserver.reserve(ctx, rid, amount, held)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +178,9 @@ func (re *reserver) reserve(ctx context.Context, src source, amount uint64, clie
 		Expiry:      exp,
 		ClientToken: clientToken,
 	}
+	if settling != nil {
+		res.Settling, res.SettlingAmount = settlingID, amount
+	}
 
 	// Save the successful reservation.
 	re.reservationsMu.Lock()
@@ -166,12 +212,44 @@ func (re *reserver) reserveUTXO(ctx context.Context, out bc.Hash, exp time.Time,
 	if err != nil {
 		return nil, err
 	}
-	if !re.checkUTXO(u) {
+	ok, err := re.checkUTXO(u)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, pg.ErrUserInputNotFound
+	}
+
+	held, err := re.heldAmount(ctx, u.source(), "")
+	if err != nil {
+		return nil, err
+	}
+	return re.reserveFound(ctx, u, held, exp, clientToken)
+}
+
+// ReserveVaultUTXO reserves the vault output out, so that no other
+// transaction is built from it before exp. Vault outputs are never
+// held, so holds on the account's asset are not checked.
+func (re *reserver) ReserveVaultUTXO(ctx context.Context, out bc.Hash, exp time.Time) (*reservation, error) {
+	u, err := findVaultUTXO(ctx, re.db, out)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := re.checkUTXO(u)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, pg.ErrUserInputNotFound
 	}
+	return re.reserveFound(ctx, u, 0, exp, nil)
+}
 
+// reserveFound reserves u, if that leaves enough
+// unreserved to cover held.
+func (re *reserver) reserveFound(ctx context.Context, u *utxo, held uint64, exp time.Time, clientToken *string) (*reservation, error) {
 	rid := atomic.AddUint64(&re.nextReservationID, 1)
-	err = re.source(u.source()).reserveUTXO(rid, u)
+	err := re.source(u.source()).reserveUTXO(ctx, rid, u, held)
 	if err != nil {
 		return nil, err
 	}
@@ -195,6 +273,9 @@ func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
 	re.reservationsMu.Lock()
 	res, ok := re.reservations[rid]
 	delete(re.reservations, rid)
+	if ok {
+		re.unclaimSettlement(res.Settling, res.SettlingAmount)
+	}
 	re.reservationsMu.Unlock()
 	if !ok {
 		return fmt.Errorf("couldn't find reservation %d", rid)
@@ -206,37 +287,93 @@ func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
 	return nil
 }
 
-// ExpireReservations cleans up all reservations that have expired,
-// making their UTXOs available for reservation again.
-func (re *reserver) ExpireReservations(ctx context.Context) error {
-	// Remove records of any reservations that have expired.
-	now := time.Now()
-	var canceled []*reservation
+// ReaperStats counts the reservations and cached UTXOs
+// the reservation reaper has released.
+type ReaperStats struct {
+	Runs                uint64    `json:"runs"`
+	ExpiredReservations uint64    `json:"expired_reservations"`
+	StaleReservations   uint64    `json:"stale_reservations"`
+	StaleUTXOs          uint64    `json:"stale_utxos"`
+	LastRun             time.Time `json:"last_run"`
+}
+
+// reap cancels all reservations that have expired, and all
+// reservations some of whose UTXOs have since been spent, making
+// their remaining UTXOs available for reservation again. A
+// reservation with a spent UTXO was either used in a transaction
+// that has landed, or can never be, since another transaction
+// spent the same output; either way, there's no point in holding
+// it until it expires. reap also drops spent UTXOs from the
+// source reservers' caches.
+func (re *reserver) reap(ctx context.Context) {
+	now := re.clock.Now()
+	_, snapshot := re.c.State()
+	var expired, stale []*reservation
 	re.reservationsMu.Lock()
 	for rid, res := range re.reservations {
 		if res.Expiry.Before(now) {
-			canceled = append(canceled, res)
+			expired = append(expired, res)
 			delete(re.reservations, rid)
+			re.unclaimSettlement(res.Settling, res.SettlingAmount)
+			continue
+		}
+		for _, u := range res.UTXOs {
+			ok, err := snapshot.Tree.Contains(u.OutputID.Bytes())
+			if err != nil {
+				// Keep the reservation; a later run will
+				// try again or it will expire.
+				log.Error(ctx, err, "checking reserved utxo")
+				break
+			}
+			if !ok {
+				stale = append(stale, res)
+				delete(re.reservations, rid)
+				re.unclaimSettlement(res.Settling, res.SettlingAmount)
+				break
+			}
 		}
 	}
 	re.reservationsMu.Unlock()
 
-	// If we removed any expired reservations, update the corresponding
-	// source reservers.
-	for _, res := range canceled {
+	// Update the source reservers of the reservations we removed.
+	for _, res := range append(expired, stale...) {
 		re.source(res.Source).cancel(res)
 		if res.ClientToken != nil {
 			re.idempotency.Forget(*res.ClientToken)
 		}
 	}
 
+	re.sourcesMu.Lock()
+	srcs := make([]*sourceReserver, 0, len(re.sources))
+	for _, sr := range re.sources {
+		srcs = append(srcs, sr)
+	}
+	re.sourcesMu.Unlock()
+	var staleUTXOs int
+	for _, sr := range srcs {
+		staleUTXOs += sr.dropSpent(ctx)
+	}
+
+	re.statsMu.Lock()
+	re.stats.Runs++
+	re.stats.ExpiredReservations += uint64(len(expired))
+	re.stats.StaleReservations += uint64(len(stale))
+	re.stats.StaleUTXOs += uint64(staleUTXOs)
+	re.stats.LastRun = now
+	re.statsMu.Unlock()
+
 	// TODO(jackson): Cleanup any source reservers that don't have
 	// anything reserved. It'll be a little tricky because of our
 	// locking scheme.
-	return nil
 }
 
-func (re *reserver) checkUTXO(u *utxo) bool {
+func (re *reserver) reaperStats() ReaperStats {
+	re.statsMu.Lock()
+	defer re.statsMu.Unlock()
+	return re.stats
+}
+
+func (re *reserver) checkUTXO(u *utxo) (bool, error) {
 	_, s := re.c.State()
 	return s.Tree.Contains(u.OutputID.Bytes())
 }
@@ -267,7 +404,7 @@ func (re *reserver) source(src source) *sourceReserver {
 type sourceReserver struct {
 	db       pg.DB
 	src      source
-	validFn  func(u *utxo) bool
+	validFn  func(u *utxo) (bool, error)
 	heightFn func() uint64
 
 	mu         sync.Mutex
@@ -276,8 +413,8 @@ type sourceReserver struct {
 	lastHeight uint64
 }
 
-func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64) ([]*utxo, uint64, error) {
-	reservedUTXOs, reservedAmount, err := sr.reserveFromCache(rid, amount)
+func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount, held uint64) ([]*utxo, uint64, error) {
+	reservedUTXOs, reservedAmount, err := sr.reserveFromCache(rid, amount, held)
 	if err == nil {
 		return reservedUTXOs, reservedAmount, nil
 	}
@@ -288,12 +425,16 @@ func (sr *sourceReserver) reserve(ctx context.Context, rid uint64, amount uint64
 		return nil, 0, err
 	}
 
-	return sr.reserveFromCache(rid, amount)
+	return sr.reserveFromCache(rid, amount, held)
 }
 
-func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo, uint64, error) {
+// reserveFromCache reserves cached UTXOs totaling at least amount,
+// leaving enough unreserved, counting the change the reservation
+// will make, to cover held.
+func (sr *sourceReserver) reserveFromCache(rid uint64, amount, held uint64) ([]*utxo, uint64, error) {
 	var (
 		reserved, unavailable uint64
+		spare                 uint64 // unreserved beyond what's needed
 		reservedUTXOs         []*utxo
 	)
 	sr.mu.Lock()
@@ -308,14 +449,24 @@ func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo,
 		// Cached utxos aren't guaranteed to still be valid; they may
 		// have been spent. Verify that that the outputs are still in
 		// the state tree.
-		if !sr.validFn(u) {
+		ok, err := sr.validFn(u)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
 			delete(sr.cached, o)
 			continue
 		}
 
+		if reserved >= amount {
+			spare += u.Amount
+			continue
+		}
 		reserved += u.Amount
 		reservedUTXOs = append(reservedUTXOs, u)
-		if reserved >= amount {
+		if reserved >= amount && held == 0 {
+			// Only count the spare funds
+			// if some of them are held.
 			break
 		}
 	}
@@ -324,7 +475,12 @@ func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo,
 		// enough to satisfy the request.
 		return nil, 0, ErrInsufficient
 	}
-	if reserved < amount {
+	if reserved+spare+unavailable < amount+held {
+		// The account has enough, but not without
+		// spending funds held for another purpose.
+		return nil, 0, errors.WithDetailf(ErrHeld, "%d units are held", held)
+	}
+	if reserved < amount || reserved+spare < amount+held {
 		// The account has enough for the request, but some is tied up in
 		// other reservations.
 		return nil, 0, ErrReserved
@@ -338,7 +494,16 @@ func (sr *sourceReserver) reserveFromCache(rid uint64, amount uint64) ([]*utxo,
 	return reservedUTXOs, reserved, nil
 }
 
-func (sr *sourceReserver) reserveUTXO(rid uint64, utxo *utxo) error {
+// reserveUTXO reserves utxo, if that leaves
+// enough unreserved to cover held.
+func (sr *sourceReserver) reserveUTXO(ctx context.Context, rid uint64, utxo *utxo, held uint64) error {
+	if held > 0 {
+		err := sr.refillCache(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
 
@@ -346,6 +511,25 @@ func (sr *sourceReserver) reserveUTXO(rid uint64, utxo *utxo) error {
 	if isReserved {
 		return ErrReserved
 	}
+	if held > 0 {
+		var spare uint64
+		for o, u := range sr.cached {
+			_, ok := sr.reserved[o]
+			if ok || o == utxo.OutputID {
+				continue
+			}
+			valid, err := sr.validFn(u)
+			if err != nil {
+				return err
+			}
+			if valid {
+				spare += u.Amount
+			}
+		}
+		if spare < held {
+			return errors.WithDetailf(ErrHeld, "%d units are held", held)
+		}
+	}
 
 	sr.reserved[utxo.OutputID] = rid
 	return nil
@@ -359,6 +543,31 @@ func (sr *sourceReserver) cancel(res *reservation) {
 	}
 }
 
+// dropSpent removes the spent UTXOs that are neither
+// reserved from the cache, returning how many it removed.
+// Reserved ones are released when their reservation is.
+// UTXOs that can't be checked stay cached.
+func (sr *sourceReserver) dropSpent(ctx context.Context) int {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	var n int
+	for o, u := range sr.cached {
+		if _, ok := sr.reserved[o]; ok {
+			continue
+		}
+		ok, err := sr.validFn(u)
+		if err != nil {
+			log.Error(ctx, err, "checking cached utxo")
+			continue
+		}
+		if !ok {
+			delete(sr.cached, o)
+			n++
+		}
+	}
+	return n
+}
+
 func (sr *sourceReserver) refillCache(ctx context.Context) error {
 	sr.mu.Lock()
 	lastHeight := sr.lastHeight
@@ -391,7 +600,7 @@ func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64)
 		SELECT output_id, amount, control_program_index, control_program,
 			source_id, source_pos, ref_data_hash
 		FROM account_utxos
-		WHERE account_id = $1 AND asset_id = $2 AND confirmed_in > $3
+		WHERE account_id = $1 AND asset_id = $2 AND confirmed_in > $3 AND NOT vault
 	`
 	var utxos []*utxo
 	err := pg.ForQueryRows(ctx, db, q, src.AccountID, src.AssetID, height,
@@ -419,7 +628,7 @@ func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error)
 		SELECT account_id, asset_id, amount, control_program_index, control_program,
 			source_id, source_pos, ref_data_hash
 		FROM account_utxos
-		WHERE output_id = $1
+		WHERE output_id = $1 AND NOT vault
 	`
 	u := new(utxo)
 	// TODO(oleg): maybe we need to scan txid:index too from here...
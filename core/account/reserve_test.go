@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/prottest"
 )
@@ -62,3 +63,122 @@ func TestCancelReservation(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReapReservations(t *testing.T) {
+	var (
+		live1  = bc.Hash{V0: 1}
+		live2  = bc.Hash{V0: 2}
+		live3  = bc.Hash{V0: 3}
+		spent  = bc.Hash{V0: 4}
+		cached = bc.Hash{V0: 5}
+		src    = source{AssetID: bc.AssetID{V0: 9}, AccountID: "acc1"}
+	)
+	c := prottest.NewChain(t, prottest.WithOutputIDs(live1, live2, live3))
+	utxoDB := newReserver(nil, c, nil)
+
+	now := time.Now()
+	add := func(rid uint64, exp time.Time, outs ...bc.Hash) {
+		res := &reservation{ID: rid, Source: src, Expiry: exp}
+		for _, out := range outs {
+			u := &utxo{OutputID: out, AssetID: src.AssetID, AccountID: src.AccountID}
+			res.UTXOs = append(res.UTXOs, u)
+			utxoDB.source(src).reserved[out] = rid
+		}
+		utxoDB.reservations[rid] = res
+	}
+	add(1, now.Add(time.Minute), live1)        // still usable
+	add(2, now.Add(-time.Minute), live2)       // expired
+	add(3, now.Add(time.Minute), live3, spent) // stale
+	utxoDB.source(src).cached[cached] = &utxo{OutputID: cached}
+
+	utxoDB.reap(context.Background())
+
+	if _, ok := utxoDB.reservations[1]; !ok || len(utxoDB.reservations) != 1 {
+		t.Errorf("reservations after reap = %v, want only 1", utxoDB.reservations)
+	}
+	if r := utxoDB.source(src).reserved; len(r) != 1 || r[live1] != 1 {
+		t.Errorf("reserved utxos after reap = %v, want only %x", r, live1.Bytes())
+	}
+	if _, ok := utxoDB.source(src).cached[cached]; ok {
+		t.Errorf("spent utxo still cached after reap")
+	}
+	got := utxoDB.reaperStats()
+	got.LastRun = time.Time{}
+	want := ReaperStats{Runs: 1, ExpiredReservations: 1, StaleReservations: 1, StaleUTXOs: 1}
+	if got != want {
+		t.Errorf("reaperStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReserveFromCacheHeld(t *testing.T) {
+	newSource := func() *sourceReserver {
+		sr := &sourceReserver{
+			validFn:  func(*utxo) (bool, error) { return true, nil },
+			cached:   make(map[bc.Hash]*utxo),
+			reserved: make(map[bc.Hash]uint64),
+		}
+		for i, amount := range []uint64{100, 200, 300} {
+			id := bc.NewHash([32]byte{byte(i + 1)})
+			sr.cached[id] = &utxo{OutputID: id, Amount: amount}
+		}
+		return sr
+	}
+
+	cases := []struct {
+		amount, held uint64
+		wantErr      error
+	}{
+		{amount: 600, held: 0},
+		{amount: 400, held: 200},
+		{amount: 400, held: 201, wantErr: ErrHeld},
+		{amount: 601, held: 0, wantErr: ErrInsufficient},
+	}
+	for _, c := range cases {
+		sr := newSource()
+		_, total, err := sr.reserveFromCache(1, c.amount, c.held)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("reserve %d with %d held: got error %v, want %v", c.amount, c.held, err, c.wantErr)
+			continue
+		}
+		if err == nil && total < c.amount {
+			t.Errorf("reserve %d with %d held: reserved %d", c.amount, c.held, total)
+		}
+	}
+
+	// Funds reserved by another reservation still
+	// count toward covering a hold, once they're free.
+	sr := newSource()
+	sr.reserved[bc.NewHash([32]byte{3})] = 2
+	_, _, err := sr.reserveFromCache(1, 100, 250)
+	if errors.Root(err) != ErrReserved {
+		t.Errorf("got error %v, want %v", err, ErrReserved)
+	}
+}
+
+func TestClaimSettlement(t *testing.T) {
+	re := newReserver(nil, nil, nil)
+	h := &Hold{ID: "hold1", Amount: 100}
+
+	err := re.claimSettlement(h, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = re.claimSettlement(h, 50)
+	if errors.Root(err) != ErrBadHold {
+		t.Errorf("claiming 110 of 100 units: got error %v, want %v", err, ErrBadHold)
+	}
+	err = re.claimSettlement(h, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re.unclaimSettlement(h.ID, 60)
+	if re.settling[h.ID] != 40 {
+		t.Errorf("settling %d units after unclaiming, want 40", re.settling[h.ID])
+	}
+	re.unclaimSettlement(h.ID, 40)
+	_, ok := re.settling[h.ID]
+	if ok {
+		t.Errorf("hold still settling after unclaiming all of it")
+	}
+}
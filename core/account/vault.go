@@ -0,0 +1,246 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"chain/core/signers"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
+)
+
+// CreateVaultProgram creates a rate-limited vault program (see
+// vmutil.VaultProgram) controlled by the account's keys, from
+// which at most limit units of an asset may be withdrawn in each
+// period, and stores it in the database. Periods are measured in
+// transaction mintimes, which may trail block time by a period,
+// so up to twice the limit can be withdrawn at once.
+//
+// Outputs to the vault belong to the account, and count toward
+// its balances, but ordinary spends don't use them. They can
+// only be spent with spend_from_vault and top_up_vault actions.
+func (m *Manager) CreateVaultProgram(ctx context.Context, accountID string, limit uint64, period time.Duration) ([]byte, error) {
+	account, err := m.findByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	err = m.checkWatchOnly(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := m.nextIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := signers.Path(account, signers.AccountKeySpace, idx)
+	derivedXPubs := chainkd.DeriveXPubs(account.XPubs, path)
+	prog, err := vmutil.VaultProgram(&vmutil.Vault{
+		PubKeys:  chainkd.XPubKeys(derivedXPubs),
+		Quorum:   account.Quorum,
+		Limit:    limit,
+		PeriodMS: bc.DurationMillis(period),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating vault program")
+	}
+
+	err = m.insertAccountControlProgram(ctx, &controlProgram{
+		accountID:      account.ID,
+		keyIndex:       idx,
+		controlProgram: prog,
+		vault:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prog, nil
+}
+
+// programKey returns the program under which outputs with control
+// program prog are stored in account_control_programs, and whether
+// it's a vault's. A vault's program changes with its state, so its
+// outputs are stored under the program of its zero state.
+func programKey(prog []byte) (string, bool) {
+	v, err := vmutil.ParseVaultProgram(prog)
+	if err != nil {
+		return string(prog), false
+	}
+	zero, err := vmutil.VaultProgram(v.Zero())
+	if err != nil {
+		return string(prog), false
+	}
+	return string(zero), true
+}
+
+func (m *Manager) DecodeSpendFromVaultAction(data []byte) (txbuilder.Action, error) {
+	a := &vaultAction{accounts: m, withdraw: true}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+func (m *Manager) DecodeTopUpVaultAction(data []byte) (txbuilder.Action, error) {
+	a := &vaultAction{accounts: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// vaultMinTimeMargin is how long before the current time the
+// mintime of a vault transaction is set, allowing for clock skew
+// between this Core and the generator. It is at most half the
+// vault's period, which bounds the transaction's time range.
+const vaultMinTimeMargin = 30 * time.Second
+
+// vaultAction spends a vault output and returns what's left of
+// its value to the vault, under the program of its next state.
+// A withdrawal leaves Amount for other actions to send elsewhere;
+// a top-up returns Amount more, which other actions must supply.
+//
+// The vault output is reserved until the transaction's maxtime,
+// as for spend_account_unspent_output, so that two transactions
+// aren't built from it.
+type vaultAction struct {
+	accounts *Manager
+	withdraw bool
+
+	OutputID      *bc.Hash      `json:"output_id"`
+	Amount        uint64        `json:"amount"`
+	ReferenceData chainjson.Map `json:"reference_data"`
+}
+
+func (a *vaultAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.OutputID == nil {
+		missing = append(missing, "output_id")
+	}
+	if a.Amount == 0 {
+		missing = append(missing, "amount")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	res, err := a.accounts.utxoDB.ReserveVaultUTXO(ctx, *a.OutputID, b.MaxTime())
+	if err != nil {
+		return errors.Wrap(err, "reserving vault output")
+	}
+	b.OnRollback(canceler(ctx, a.accounts, res.ID))
+	u := res.UTXOs[0]
+
+	v, err := vmutil.ParseVaultProgram(u.ControlProgram)
+	if err != nil {
+		return errors.Wrap(err, "parsing vault program")
+	}
+	acct, err := a.accounts.findByID(ctx, u.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "get account info")
+	}
+
+	var returned, withdrawn uint64
+	if a.withdraw {
+		if a.Amount > u.Amount {
+			return errors.WithDetailf(ErrInsufficient, "vault output holds %d", u.Amount)
+		}
+		returned, withdrawn = u.Amount-a.Amount, a.Amount
+	} else {
+		if a.Amount > math.MaxInt64-u.Amount {
+			return errors.WithDetail(txbuilder.ErrBadAmount, "vault top-up too large")
+		}
+		returned = u.Amount + a.Amount
+	}
+
+	// The vault takes the transaction's mintime as the current
+	// time. It's set a little before now, so that the transaction
+	// is valid even if the generator's clock is behind this Core's.
+	// Check the withdrawal against the limit now, to fail early,
+	// and again once the mintime is final.
+	margin := vaultMinTimeMargin
+	half := bc.MillisDuration(v.PeriodMS) / 2
+	if half < margin {
+		margin = half
+	}
+	b.RestrictMinTime(a.accounts.utxoDB.clock.Now().Add(-margin))
+	_, err = v.After(bc.Millis(b.MinTime()), withdrawn)
+	if err != nil {
+		return err
+	}
+
+	txInput, sigInst, err := utxoToInputs(ctx, acct, u, a.ReferenceData)
+	if err != nil {
+		return errors.Wrap(err, "creating inputs")
+	}
+	err = b.AddInput(txInput, sigInst)
+	if err != nil {
+		return errors.Wrap(err, "adding inputs")
+	}
+	out := legacy.NewTxOutput(u.AssetID, returned, u.ControlProgram, u.OutputID.Bytes())
+	err = b.AddOutput(out)
+	if err != nil {
+		return errors.Wrap(err, "adding vault output")
+	}
+
+	// The next state, the maxtime, and the output's index
+	// depend on actions that may not have been built yet.
+	b.OnBuild(func() error {
+		minTime := b.MinTime()
+		next, err := v.After(bc.Millis(minTime), withdrawn)
+		if err != nil {
+			return err
+		}
+		out.ControlProgram, err = vmutil.VaultProgram(next)
+		if err != nil {
+			return errors.Wrap(err, "creating vault program")
+		}
+		b.RestrictMaxTime(minTime.Add(bc.MillisDuration(v.PeriodMS)))
+
+		var index int
+		for i, o := range b.Outputs() {
+			if o == out {
+				index = i
+			}
+		}
+		sigInst.Arguments = []chainjson.HexBytes{
+			vm.Int64Bytes(int64(index)),
+			vm.Int64Bytes(int64(returned)),
+		}
+		return nil
+	})
+	return nil
+}
+
+func findVaultUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error) {
+	const q = `
+		SELECT account_id, asset_id, amount, control_program_index, control_program,
+			source_id, source_pos, ref_data_hash
+		FROM account_utxos
+		WHERE output_id = $1 AND vault
+	`
+	u := new(utxo)
+	err := db.QueryRowContext(ctx, q, out).Scan(
+		&u.AccountID,
+		&u.AssetID,
+		&u.Amount,
+		&u.ControlProgramIndex,
+		&u.ControlProgram,
+		&u.SourceID,
+		&u.SourcePos,
+		&u.RefDataHash,
+	)
+	if err == sql.ErrNoRows {
+		return nil, pg.ErrUserInputNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	u.OutputID = out
+	return u, nil
+}
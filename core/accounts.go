@@ -3,11 +3,16 @@ package core
 import (
 	"context"
 	"sync"
+	"time"
 
 	"chain/core/account"
+	"chain/core/query"
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
+	"chain/protocol/bc"
 )
 
 // POST /create-account
@@ -51,6 +56,51 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 	return responses
 }
 
+// POST /bulk-create-accounts
+//
+// bulkCreateAccounts is like createAccount, but inserts all of
+// the accounts in a handful of database statements rather than
+// one round trip per account. It accepts at most maxBulkItems
+// accounts per request.
+func (a *API) bulkCreateAccounts(ctx context.Context, ins []struct {
+	RootXPubs   []chainkd.XPub `json:"root_xpubs"`
+	Quorum      int
+	Alias       string
+	Tags        map[string]interface{}
+	ClientToken string `json:"client_token"`
+}) ([]interface{}, error) {
+	if len(ins) > maxBulkItems {
+		return nil, errors.WithDetailf(errTooManyItems, "%d accounts requested, limit is %d", len(ins), maxBulkItems)
+	}
+
+	reqs := make([]account.CreateRequest, len(ins))
+	for i, in := range ins {
+		reqs[i] = account.CreateRequest{
+			XPubs:       in.RootXPubs,
+			Quorum:      in.Quorum,
+			Alias:       in.Alias,
+			Tags:        in.Tags,
+			ClientToken: in.ClientToken,
+		}
+	}
+	accounts, errs := a.accounts.CreateBatch(ctx, reqs)
+
+	responses := make([]interface{}, len(ins))
+	for i, acc := range accounts {
+		if errs[i] != nil {
+			responses[i] = formatItemError(ctx, errs[i])
+			continue
+		}
+		aa, err := account.Annotated(acc)
+		if err != nil {
+			responses[i] = formatItemError(ctx, err)
+			continue
+		}
+		responses[i] = aa
+	}
+	return responses, nil
+}
+
 // POST /update-account-tags
 func (a *API) updateAccountTags(ctx context.Context, ins []struct {
 	ID    *string
@@ -79,3 +129,121 @@ func (a *API) updateAccountTags(ctx context.Context, ins []struct {
 	wg.Wait()
 	return responses
 }
+
+// POST /get-account-descriptor
+func (a *API) getAccountDescriptor(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+}) (interface{}, error) {
+	d, err := a.accounts.Descriptor(ctx, in.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"descriptor": d}, nil
+}
+
+// POST /import-account-descriptor
+//
+// The response is the watch-only account. Calling it again with
+// the same client_token imports more of the account's programs.
+func (a *API) importAccountDescriptor(ctx context.Context, in struct {
+	Descriptor        account.Descriptor     `json:"descriptor"`
+	Alias             string                 `json:"alias"`
+	Tags              map[string]interface{} `json:"tags"`
+	ClientToken       string                 `json:"client_token"`
+	FirstProgramIndex uint64                 `json:"first_program_index"`
+	ProgramCount      uint64                 `json:"program_count"`
+}) (*query.AnnotatedAccount, error) {
+	if in.ClientToken == "" {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "client_token is required")
+	}
+	acc, err := a.accounts.ImportDescriptor(ctx, in.Descriptor, in.FirstProgramIndex, in.ProgramCount, in.Alias, in.Tags, in.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	return account.Annotated(acc)
+}
+
+// POST /get-account-policy
+func (a *API) getAccountPolicy(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+}) (*account.Policy, error) {
+	return a.accounts.Policy(ctx, in.AccountID)
+}
+
+// POST /set-account-policy
+//
+// The policy's version must be the current version, which
+// the new policy replaces. The response has the new version.
+func (a *API) setAccountPolicy(ctx context.Context, in struct {
+	AccountID string         `json:"account_id"`
+	Policy    account.Policy `json:"policy"`
+}) (*account.Policy, error) {
+	return a.accounts.SetPolicy(ctx, in.AccountID, &in.Policy)
+}
+
+// POST /set-account-hot
+func (a *API) setAccountHot(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+	Hot       bool   `json:"hot"`
+}) error {
+	return a.accounts.SetHot(ctx, in.AccountID, in.Hot)
+}
+
+// POST /get-hot-account-balance
+//
+// The balance is current as of the end of the block at the
+// response's block_height, which is at least min_block_height.
+func (a *API) getHotAccountBalance(ctx context.Context, in struct {
+	AccountID      string `json:"account_id"`
+	MinBlockHeight uint64 `json:"min_block_height"`
+}) (*account.HotBalance, error) {
+	return a.accounts.HotBalance(ctx, in.AccountID, in.MinBlockHeight)
+}
+
+// POST /approve-account-policy-override
+//
+// Only the client-approver policy grants access, and only
+// internal callers can grant it (see createGrant), so the holder
+// of a client-readwrite token, who builds transactions, can't
+// approve exceptions to an account's policy too.
+func (a *API) approveAccountPolicyOverride(ctx context.Context, in struct {
+	AccountID      string             `json:"account_id"`
+	PolicyVersion  uint64             `json:"policy_version"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+}) error {
+	if len(in.ControlProgram) == 0 || in.ExpiresAt.IsZero() {
+		return errors.WithDetail(httpjson.ErrBadRequest, "control_program and expires_at are required")
+	}
+	return a.accounts.ApproveOverride(ctx, in.AccountID, in.PolicyVersion, in.ControlProgram, in.ExpiresAt)
+}
+
+// POST /create-account-hold
+//
+// The hold sets aside amount units of the asset in the account
+// until expires_at. A spend action with the hold's ID as hold_id
+// settles it.
+func (a *API) createAccountHold(ctx context.Context, in struct {
+	AccountID   string     `json:"account_id"`
+	AssetID     bc.AssetID `json:"asset_id"`
+	Amount      uint64     `json:"amount"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	Reference   string     `json:"reference"`
+	ClientToken string     `json:"client_token"`
+}) (*account.Hold, error) {
+	return a.accounts.PlaceHold(ctx, in.AccountID, in.AssetID, in.Amount, in.ExpiresAt, in.Reference, in.ClientToken)
+}
+
+// POST /release-account-hold
+func (a *API) releaseAccountHold(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) error {
+	return a.accounts.ReleaseHold(ctx, in.ID)
+}
+
+// POST /list-account-holds
+func (a *API) listAccountHolds(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+}) ([]*account.Hold, error) {
+	return a.accounts.Holds(ctx, in.AccountID)
+}
@@ -6,17 +6,21 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"strings"
 	"sync"
 	"time"
 
 	"chain/core/accesstoken"
 	"chain/core/account"
 	"chain/core/asset"
+	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/eventlog"
 	"chain/core/fetch"
 	"chain/core/generator"
 	"chain/core/leader"
@@ -26,9 +30,11 @@ import (
 	"chain/core/txbuilder"
 	"chain/core/txdb"
 	"chain/core/txfeed"
+	"chain/core/volume"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/encoding/json"
+	"chain/env"
 	"chain/errors"
 	"chain/generated/dashboard"
 	"chain/log"
@@ -37,6 +43,7 @@ import (
 	"chain/net/http/gzip"
 	"chain/net/http/httpjson"
 	"chain/net/http/limit"
+	"chain/net/http/reqid"
 	"chain/net/http/static"
 	"chain/protocol"
 	"chain/protocol/bc/legacy"
@@ -44,6 +51,10 @@ import (
 
 const (
 	defGenericPageSize = 100
+
+	// maxBulkItems limits the number of items in a
+	// single bulk-create request.
+	maxBulkItems = 1000
 )
 
 // TODO(kr): change this to "crosscore" or something.
@@ -53,6 +64,7 @@ var (
 	errNotFound         = errors.New("not found")
 	errRateLimited      = errors.New("request limit exceeded")
 	errNotAuthenticated = errors.New("not authenticated")
+	errTooManyItems     = errors.New("too many items in request")
 )
 
 // API serves the Chain HTTP API
@@ -68,8 +80,10 @@ type API struct {
 	grants          *authz.Store
 	config          *config.Config
 	options         *config.Options
+	fence           func() []string // the fence option's value
 	submitter       txbuilder.Submitter
 	db              pg.DB
+	queryDB         pg.DB // holds the annotated query tables
 	sdb             *sinkdb.DB
 	mux             *http.ServeMux
 	handler         http.Handler
@@ -78,11 +92,29 @@ type API struct {
 	signer          func(context.Context, *legacy.Block) ([]byte, error)
 	requestLimits   []requestLimit
 	generator       *generator.Generator
+	pendingStore    generator.BlockStore
 	replicator      *fetch.Replicator
+	notifier        *blockNotifier
+	approver        *approver
+	callbackURL     string
 	remoteGenerator *rpc.Client
 	indexTxs        bool
+	annotators      []query.Annotator
+	washWindow      uint64
+	reindexDelay    time.Duration
+	eventLog        *eventlog.Log
+	eventPublisher  eventlog.Publisher
+	finalityPins    []string
+	volume          *volume.Tracker
+	canary          *canary
+	shadow          *shadow
+	signerPolicy    *blocksigner.Policy
+	complianceCheck func(context.Context, *legacy.Tx) error
+	disabledChecks  map[string]bool
 	internalSubj    pkix.Name
 	httpClient      *http.Client
+	configFile      string
+	settings        []env.Value
 
 	downloadingSnapshotMu sync.Mutex
 	downloadingSnapshot   *fetch.SnapshotProgress
@@ -141,10 +173,27 @@ func (a *API) buildHandler() {
 
 	m.Handle("/create-account", needConfig(a.createAccount))
 	m.Handle("/create-asset", needConfig(a.createAsset))
+	m.Handle("/bulk-create-accounts", needConfig(a.bulkCreateAccounts))
+	m.Handle("/bulk-create-assets", needConfig(a.bulkCreateAssets))
+	m.Handle("/import-assets", needConfig(a.importAssets))
 	m.Handle("/update-account-tags", needConfig(a.updateAccountTags))
+	m.Handle("/get-account-descriptor", needConfig(a.getAccountDescriptor))
+	m.Handle("/import-account-descriptor", needConfig(a.importAccountDescriptor))
+	m.Handle("/get-account-policy", needConfig(a.getAccountPolicy))
+	m.Handle("/set-account-policy", needConfig(a.setAccountPolicy))
+	m.Handle("/approve-account-policy-override", needConfig(a.approveAccountPolicyOverride))
+	m.Handle("/set-account-hot", needConfig(a.setAccountHot))
+	m.Handle("/get-hot-account-balance", needConfig(a.getHotAccountBalance))
+	m.Handle("/create-account-hold", needConfig(a.createAccountHold))
+	m.Handle("/release-account-hold", needConfig(a.releaseAccountHold))
+	m.Handle("/list-account-holds", needConfig(a.listAccountHolds))
 	m.Handle("/update-asset-tags", needConfig(a.updateAssetTags))
 	m.Handle("/build-transaction", needConfig(a.build))
+	m.Handle("/build-issuance", needConfig(a.buildIssuance))
 	m.Handle("/submit-transaction", needConfig(a.submit))
+	m.Handle("/get-transaction-status", needConfig(a.getTxStatus))
+	m.Handle("/get-signing-payloads", needConfig(a.signingPayloads))
+	m.Handle("/review-transaction", needConfig(a.reviewTransactions))
 	m.Handle("/create-control-program", needConfig(a.createControlProgram)) // DEPRECATED
 	m.Handle("/create-account-receiver", needConfig(a.createAccountReceiver))
 	m.Handle("/create-transaction-feed", needConfig(a.createTxFeed))
@@ -156,17 +205,42 @@ func (a *API) buildHandler() {
 	m.Handle("/list-assets", needConfig(a.listAssets))
 	m.Handle("/list-transaction-feeds", needConfig(a.listTxFeeds))
 	m.Handle("/list-transactions", needConfig(a.listTransactions))
+	m.Handle("/reindex-transactions", needConfig(a.reindexTransactions))
 	m.Handle("/list-balances", needConfig(a.listBalances))
+	m.Handle("/list-distinct-values", needConfig(a.listDistinctValues))
+	m.Handle("/list-tag-history", needConfig(a.listTagHistory))
+	m.Handle("/list-annotated-changes", needConfig(a.listAnnotatedChanges))
+	m.Handle("/list-asset-velocities", needConfig(a.listAssetVelocities))
+	m.Handle("/list-holding-times", needConfig(a.listHoldingTimes))
 	m.Handle("/list-unspent-outputs", needConfig(a.listUnspentOutputs))
+	m.Handle("/get-output-provenance", needConfig(a.getOutputProvenance))
+	m.Handle("/get-consensus-program", needConfig(a.getConsensusProgram))
+	m.Handle("/list-block-headers", needConfig(a.listBlockHeaders))
+	m.Handle("/get-block-signers", needConfig(a.getBlockSigners))
+	m.Handle("/get-index-summary", needConfig(a.getIndexSummary))
+	m.Handle("/export-audit-bundle", needConfig(a.exportAuditBundle))
+	m.Handle("/export-blocks", needConfig(a.exportBlocks))
+	m.Handle("/import-blocks", needConfig(a.importBlocks))
+	m.Handle("/get-asset-volumes", needConfig(a.getAssetVolumes))
 	m.Handle("/reset", resetAllowed(needConfig(a.reset)))
+	m.Handle("/standby/fence", needConfig(a.fenceCore))
+	m.Handle("/standby/promote", needConfig(a.promote))
 
 	m.Handle(crosscoreRPCPrefix+"submit", needConfig(func(ctx context.Context, tx *legacy.Tx) error {
+		err := a.checkFence()
+		if err != nil {
+			return err
+		}
 		return a.submitter.Submit(ctx, tx)
 	}))
 	m.Handle(crosscoreRPCPrefix+"get-block", needConfig(a.getBlockRPC))
+	m.Handle(crosscoreRPCPrefix+"get-block-id", needConfig(a.getBlockIDRPC))
+	m.Handle(crosscoreRPCPrefix+"get-transaction-status", needConfig(a.getTxStatusRPC))
 	m.Handle(crosscoreRPCPrefix+"get-snapshot-info", needConfig(a.getSnapshotInfoRPC))
 	m.Handle(crosscoreRPCPrefix+"get-snapshot", http.HandlerFunc(a.getSnapshotRPC))
 	m.Handle(crosscoreRPCPrefix+"signer/sign-block", needConfig(a.leaderSignHandler(a.signer)))
+	m.Handle(crosscoreRPCPrefix+"register-block-callback", needConfig(a.registerBlockCallbackRPC))
+	m.Handle(crosscoreRPCPrefix+"notify-block", needConfig(a.notifyBlockRPC))
 	m.Handle(crosscoreRPCPrefix+"block-height", needConfig(func(ctx context.Context) map[string]uint64 {
 		h := a.chain.Height()
 		return map[string]uint64{
@@ -187,7 +261,9 @@ func (a *API) buildHandler() {
 	m.Handle("/configure", jsonHandler(a.configure))
 	m.Handle("/config", jsonHandler(a.retrieveConfig))
 	m.Handle("/info", jsonHandler(a.info))
+	m.Handle("/node-settings", jsonHandler(a.nodeSettings))
 
+	m.Handle("/metrics", a.metricsHandler())
 	m.Handle("/debug/vars", expvar.Handler())
 	m.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
 	m.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
@@ -213,6 +289,10 @@ func (a *API) buildHandler() {
 	if a.config != nil && a.config.BlockchainId != nil {
 		handler = blockchainIDHandler(handler, a.config.BlockchainId.String())
 	}
+	if a.chain != nil {
+		costsHash := a.chain.Costs.Hash()
+		handler = costTableHashHandler(handler, hex.EncodeToString(costsHash[:]))
+	}
 	handler = loggingHandler(handler)
 	a.handler = handler
 }
@@ -238,10 +318,26 @@ type requestQuery struct {
 	StartTimeMS uint64 `json:"start_time,omitempty"`
 	EndTimeMS   uint64 `json:"end_time,omitempty"`
 
+	// Field is the annotated field counted by /list-distinct-values
+	Field string `json:"field,omitempty"`
+
+	// These are used for block-range queries like /list-asset-velocities.
+	// An EndHeight of 0 means the end of the blockchain.
+	StartHeight uint64 `json:"start_block_height,omitempty"`
+	EndHeight   uint64 `json:"end_block_height,omitempty"`
+
+	// BucketBoundsMS gives the buckets of /list-holding-times
+	BucketBoundsMS []int64 `json:"bucket_bounds_ms,omitempty"`
+
 	// This is used for point-in-time queries like /list-balances
 	// TODO(bobg): Different request structs for endpoints with different needs
 	TimestampMS uint64 `json:"timestamp,omitempty"`
 
+	// These ask point-in-time queries for the state at a block
+	// height, or at a timestamp, like TimestampMS.
+	AsOfHeight      uint64 `json:"as_of_block_height,omitempty"`
+	AsOfTimestampMS uint64 `json:"as_of_timestamp,omitempty"`
+
 	// This is used for filtering results from /list-access-tokens
 	// Value must be "client" or "network"
 	Type string `json:"type"`
@@ -284,7 +380,7 @@ func AuthHandler(handler http.Handler, sdb *sinkdb.DB, accessTokens *accesstoken
 			return
 		}
 
-		err = authorizer.Authorize(req)
+		req, err = authorizer.Authorize(req)
 		if err != nil {
 			errorFormatter.Write(req.Context(), rw, err)
 			return
@@ -319,6 +415,22 @@ func blockchainIDHandler(handler http.Handler, blockchainID string) http.Handler
 	})
 }
 
+// costTableHashHandler adds the Cost-Table-Hash HTTP header
+// to all requests. It rejects cross-core RPCs from peers that
+// send a different hash.
+func costTableHashHandler(handler http.Handler, hash string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(rpc.HeaderCostTableHash, hash)
+		h := req.Header.Get(rpc.HeaderCostTableHash)
+		if h != "" && h != hash && strings.HasPrefix(req.URL.Path, crosscoreRPCPrefix) {
+			err := errors.WithDetail(rpc.ErrWrongNetwork, "peer uses a different opcode cost table")
+			errorFormatter.Write(req.Context(), w, err)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 // loggingHandler pulls out request data and adds it to the request's
 // logging context.
 func loggingHandler(handler http.Handler) http.Handler {
@@ -362,15 +474,39 @@ func (a *API) leaderSignHandler(f func(context.Context, *legacy.Block) ([]byte,
 		if f == nil {
 			return nil, errNotFound // TODO(kr): is this really the right error here?
 		}
+		err := a.checkGenerator(ctx)
+		if err != nil {
+			return nil, err
+		}
 		if a.leader.State() == leader.Leading {
 			return f(ctx, b)
 		}
 		var resp []byte
-		err := a.forwardToLeader(ctx, "/rpc/signer/sign-block", b, &resp)
+		err = a.forwardToLeader(ctx, "/rpc/signer/sign-block", b, &resp)
 		return resp, err
 	}
 }
 
+// checkGenerator checks that the signer policy, if any, allows
+// the caller to request a block signature. Requests forwarded
+// from another process in this Core's cluster were checked by
+// that process.
+func (a *API) checkGenerator(ctx context.Context) error {
+	if a.signerPolicy == nil {
+		return nil
+	}
+	certs := authn.X509Certs(ctx)
+	if len(certs) > 0 && a.internalSubj.String() != "" && certs[0].Subject.String() == a.internalSubj.String() {
+		return nil
+	}
+	err := a.signerPolicy.CheckGenerator(certs)
+	if err != nil {
+		log.Printkv(ctx, "at", "signer policy", "decision", "reject", "reason", errors.Detail(err))
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
 // forwardToLeader forwards the current request to the core's leader
 // process. It relies on a.httpClient's TLS configuration for authenticating
 // with the leader cored. The internal policy must be authorized for the
@@ -416,6 +552,15 @@ func alwaysError(err error) http.Handler {
 	return jsonHandler(func() error { return err })
 }
 
+// formatItemError logs err and converts it into an error response
+// for a single item of a batch request.
+func formatItemError(ctx context.Context, err error) interface{} {
+	errorFormatter.Log(ctx, err)
+	resp := errorFormatter.Format(err)
+	resp.RequestID = reqid.FromContext(ctx)
+	return resp
+}
+
 func batchRecover(ctx context.Context, v *interface{}) {
 	if r := recover(); r != nil {
 		var err error
@@ -434,7 +579,6 @@ func batchRecover(ctx context.Context, v *interface{}) {
 	// Convert errors into error responses (including errors
 	// from recovered panics above).
 	if err, ok := (*v).(error); ok {
-		errorFormatter.Log(ctx, err)
-		*v = errorFormatter.Format(err)
+		*v = formatItemError(ctx, err)
 	}
 }
@@ -216,8 +216,14 @@ func TestTransfer(t *testing.T) {
 	api.assets.IndexAssets(api.indexer)
 	api.accounts.IndexAccounts(api.indexer)
 	go api.accounts.ProcessBlocks(ctx)
-	api.indexer.RegisterAnnotator(api.accounts.AnnotateTxs)
-	api.indexer.RegisterAnnotator(api.assets.AnnotateTxs)
+	err := api.indexer.RegisterAnnotator(api.accounts.Annotator())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = api.indexer.RegisterAnnotator(api.assets.Annotator())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
 	api.leader = alwaysLeader{}
 
 	assetAlias := "some-asset"
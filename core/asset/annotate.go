@@ -12,6 +12,12 @@ import (
 	"chain/protocol/bc"
 )
 
+// Annotator returns a query.Annotator, named query.AssetAnnotator,
+// that adds asset data to transactions with AnnotateTxs.
+func (reg *Registry) Annotator() query.Annotator {
+	return query.NewAnnotator(query.AssetAnnotator, reg.AnnotateTxs)
+}
+
 func (reg *Registry) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx) error {
 	assetIDMap := make(map[bc.AssetID]bool)
 
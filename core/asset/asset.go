@@ -16,6 +16,7 @@ import (
 	"github.com/lib/pq"
 
 	"chain/core/pin"
+	"chain/core/query"
 	"chain/core/signers"
 	"chain/crypto/ed25519"
 	"chain/crypto/ed25519/chainkd"
@@ -28,9 +29,15 @@ import (
 
 const maxAssetCache = 1000
 
+// assetVersion is the asset version of the assets this Core
+// defines and imports. Their IDs are derived the same way
+// validation derives them, by bc.ComputeVersionedAssetID.
+const assetVersion = 1
+
 var (
 	ErrDuplicateAlias = errors.New("duplicate asset alias")
 	ErrBadIdentifier  = errors.New("either ID or alias must be specified, and not both")
+	ErrMismatchedID   = errors.New("asset ID does not match issuance program and definition")
 )
 
 func NewRegistry(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Registry {
@@ -103,11 +110,42 @@ func (asset *Asset) SetDefinition(def map[string]interface{}) error {
 
 // Define defines a new Asset.
 func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum int, definition map[string]interface{}, alias string, tags map[string]interface{}, clientToken string) (*Asset, error) {
+	err := query.ValidateTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	assetSigner, err := signers.Create(ctx, reg.db, "asset", xpubs, quorum, clientToken)
 	if err != nil {
 		return nil, err
 	}
 
+	asset, err := reg.newAsset(assetSigner, definition, alias, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err = reg.insertAsset(ctx, asset, clientToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting asset")
+	}
+
+	err = insertAssetTags(ctx, reg.db, asset.AssetID, tags)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting asset tags")
+	}
+
+	err = reg.indexAnnotatedAsset(ctx, asset)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing annotated asset")
+	}
+
+	return asset, nil
+}
+
+// newAsset constructs an Asset controlled by the given signer,
+// computing its issuance program and asset ID.
+func (reg *Registry) newAsset(assetSigner *signers.Signer, definition map[string]interface{}, alias string, tags map[string]interface{}) (*Asset, error) {
 	rawDefinition, err := serializeAssetDef(definition)
 	if err != nil {
 		return nil, errors.Wrap(err, "serializing asset definition")
@@ -122,24 +160,267 @@ func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum in
 	}
 
 	defhash := bc.NewHash(sha3.Sum256(rawDefinition))
+	assetID, err := bc.ComputeVersionedAssetID(issuanceProgram, &reg.initialBlockHash, vmver, assetVersion, &defhash)
+	if err != nil {
+		return nil, errors.Wrap(err, "computing asset ID")
+	}
 	asset := &Asset{
 		definition:       definition,
 		rawDefinition:    rawDefinition,
 		VMVersion:        vmver,
 		IssuanceProgram:  issuanceProgram,
 		InitialBlockHash: reg.initialBlockHash,
-		AssetID:          bc.ComputeAssetID(issuanceProgram, &reg.initialBlockHash, vmver, &defhash),
+		AssetID:          assetID,
 		Signer:           assetSigner,
 		Tags:             tags,
 	}
 	if alias != "" {
 		asset.Alias = &alias
 	}
+	return asset, nil
+}
+
+// DefineRequest holds the parameters for one asset
+// in a call to DefineBatch.
+type DefineRequest struct {
+	XPubs       []chainkd.XPub
+	Quorum      int
+	Definition  map[string]interface{}
+	Alias       string
+	Tags        map[string]interface{}
+	ClientToken string
+}
+
+// DefineBatch defines a new Asset for each element of reqs. Signers,
+// assets, and asset tags are each inserted with a single statement.
+// The returned slices correspond 1:1 with reqs; for each element
+// exactly one of the asset or the error is non-nil.
+func (reg *Registry) DefineBatch(ctx context.Context, reqs []DefineRequest) ([]*Asset, []error) {
+	params := make([]signers.CreateParams, len(reqs))
+	for i, req := range reqs {
+		params[i] = signers.CreateParams{
+			XPubs:       req.XPubs,
+			Quorum:      req.Quorum,
+			ClientToken: req.ClientToken,
+		}
+	}
+	assetSigners, errs := signers.CreateBatch(ctx, reg.db, "asset", params)
 
-	asset, err = reg.insertAsset(ctx, asset, clientToken)
+	var (
+		assets   = make([]*Asset, len(reqs))
+		ordinals []int
+
+		ids           pq.ByteaArray
+		aliases       []sql.NullString
+		signerIDs     pq.StringArray
+		initialBlocks pq.ByteaArray
+		vmVersions    pq.Int64Array
+		programs      pq.ByteaArray
+		definitions   pq.ByteaArray
+		tokens        []sql.NullString
+	)
+	for i, req := range reqs {
+		if errs[i] != nil {
+			continue
+		}
+		err := query.ValidateTags(req.Tags)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		asset, err := reg.newAsset(assetSigners[i], req.Definition, req.Alias, req.Tags)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		assets[i] = asset
+		ordinals = append(ordinals, i)
+
+		ids = append(ids, asset.AssetID.Bytes())
+		aliases = append(aliases, sql.NullString{String: req.Alias, Valid: req.Alias != ""})
+		signerIDs = append(signerIDs, asset.Signer.ID)
+		initialBlocks = append(initialBlocks, asset.InitialBlockHash.Bytes())
+		vmVersions = append(vmVersions, int64(asset.VMVersion))
+		programs = append(programs, asset.IssuanceProgram)
+		definitions = append(definitions, asset.rawDefinition)
+		tokens = append(tokens, sql.NullString{String: req.ClientToken, Valid: req.ClientToken != ""})
+	}
+	if len(ordinals) == 0 {
+		return assets, errs
+	}
+
+	fail := func(err error) ([]*Asset, []error) {
+		for _, i := range ordinals {
+			assets[i] = nil
+			errs[i] = err
+		}
+		return assets, errs
+	}
+
+	// Rows that conflict on the client token or the alias are
+	// skipped rather than aborting the whole batch.
+	const q = `
+		INSERT INTO assets
+			(id, alias, signer_id, initial_block_hash, vm_version, issuance_program, definition, client_token)
+		SELECT * FROM unnest($1::bytea[], $2::text[], $3::text[], $4::bytea[],
+			$5::bigint[], $6::bytea[], $7::bytea[], $8::text[])
+		ON CONFLICT DO NOTHING
+		RETURNING id, sort_id
+	`
+	sortIDs := make(map[bc.AssetID]string, len(ordinals))
+	err := pg.ForQueryRows(ctx, reg.db, q, ids, pq.Array(aliases), signerIDs, initialBlocks,
+		vmVersions, programs, definitions, pq.Array(tokens),
+		func(id bc.AssetID, sortID string) {
+			sortIDs[id] = sortID
+		})
+	if err != nil {
+		return fail(errors.Wrap(err, "batch inserting assets"))
+	}
+
+	var (
+		tagAssetIDs pq.ByteaArray
+		tagValues   []*sql.NullString
+		tagged      = make(map[bc.AssetID]bool, len(ordinals))
+	)
+	for _, i := range ordinals {
+		asset := assets[i]
+		if sortID, ok := sortIDs[asset.AssetID]; ok {
+			asset.sortID = sortID
+		} else if reqs[i].ClientToken != "" {
+			// There may already be an asset with the provided client
+			// token. Return the existing asset, as Define does. If
+			// there isn't, the row conflicted on its alias instead.
+			assets[i], err = assetByClientToken(ctx, reg.db, reqs[i].ClientToken)
+			if errors.Root(err) == pg.ErrUserInputNotFound {
+				errs[i] = errors.WithDetail(ErrDuplicateAlias, "an asset with the provided alias already exists")
+			} else if err != nil {
+				errs[i] = errors.Wrap(err, "retrieving existing asset")
+			}
+			continue
+		} else {
+			assets[i] = nil
+			errs[i] = errors.WithDetail(ErrDuplicateAlias, "an asset with the provided alias already exists")
+			continue
+		}
+
+		// Requests in the same batch that share a client token
+		// define the same asset. Its tags may only be written once
+		// per statement.
+		if tagged[asset.AssetID] {
+			continue
+		}
+		tagged[asset.AssetID] = true
+
+		tagsParam, err := mapToNullString(asset.Tags)
+		if err != nil {
+			assets[i] = nil
+			errs[i] = err
+			continue
+		}
+		tagAssetIDs = append(tagAssetIDs, asset.AssetID.Bytes())
+		tagValues = append(tagValues, tagsParam)
+	}
+
+	if len(tagAssetIDs) > 0 {
+		const tagsQ = `
+			INSERT INTO asset_tags (asset_id, tags)
+			SELECT * FROM unnest($1::bytea[], $2::jsonb[])
+			ON CONFLICT (asset_id) DO UPDATE SET tags = excluded.tags
+		`
+		_, err = reg.db.ExecContext(ctx, tagsQ, tagAssetIDs, pq.Array(tagValues))
+		if err != nil {
+			return fail(errors.Wrap(err, "batch inserting asset tags"))
+		}
+	}
+
+	for _, i := range ordinals {
+		if assets[i] == nil {
+			continue
+		}
+		err = reg.indexAnnotatedAsset(ctx, assets[i])
+		if err != nil {
+			assets[i] = nil
+			errs[i] = errors.Wrap(err, "indexing annotated asset")
+		}
+	}
+	return assets, errs
+}
+
+// Import registers an asset defined by another core on the same
+// blockchain. The asset ID is recomputed from the issuance program,
+// VM version, and definition, and must match id. An imported asset
+// has no local signer, so it can't be issued by this core, but it
+// can be annotated, queried, and spent by alias like any other asset.
+//
+// Importing an asset that has already been indexed from the
+// blockchain gives it the provided alias and tags. Importing an
+// asset that is already known by that alias is a no-op. Annotated
+// inputs and outputs of the asset indexed before the import get
+// its alias and definition from a backfill; see package query.
+func (reg *Registry) Import(ctx context.Context, id bc.AssetID, issuanceProgram []byte, vmVersion uint64, definition map[string]interface{}, alias string, tags map[string]interface{}) (*Asset, error) {
+	err := query.ValidateTags(tags)
 	if err != nil {
+		return nil, err
+	}
+
+	rawDefinition, err := serializeAssetDef(definition)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing asset definition")
+	}
+
+	defhash := bc.NewHash(sha3.Sum256(rawDefinition))
+	computed, err := bc.ComputeVersionedAssetID(issuanceProgram, &reg.initialBlockHash, vmVersion, assetVersion, &defhash)
+	if err != nil {
+		return nil, errors.Wrap(err, "computing asset ID")
+	}
+	if computed != id {
+		return nil, errors.WithDetailf(ErrMismatchedID, "computed asset ID is %s", computed.String())
+	}
+
+	asset := &Asset{
+		AssetID:          id,
+		VMVersion:        vmVersion,
+		IssuanceProgram:  issuanceProgram,
+		InitialBlockHash: reg.initialBlockHash,
+		Tags:             tags,
+		rawDefinition:    rawDefinition,
+		definition:       definition,
+	}
+	if alias != "" {
+		asset.Alias = &alias
+	}
+
+	// Non-local assets may already be present if they've been issued
+	// in a block this core has processed. In that case, keep the
+	// existing row and only fill in a missing alias.
+	const q = `
+		INSERT INTO assets
+			(id, alias, initial_block_hash, vm_version, issuance_program, definition)
+		VALUES($1::bytea, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET alias = COALESCE(assets.alias, excluded.alias)
+			WHERE assets.signer_id IS NULL
+		RETURNING alias, sort_id
+	`
+	var storedAlias sql.NullString
+	err = reg.db.QueryRowContext(
+		ctx, q,
+		asset.AssetID, asset.Alias, asset.InitialBlockHash,
+		asset.VMVersion, asset.IssuanceProgram, asset.rawDefinition,
+	).Scan(&storedAlias, &asset.sortID)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "an asset with the provided alias already exists")
+	} else if err == sql.ErrNoRows {
+		// The asset is local to this core.
+		return reg.findByID(ctx, id)
+	} else if err != nil {
 		return nil, errors.Wrap(err, "inserting asset")
 	}
+	if storedAlias.Valid {
+		if alias != "" && storedAlias.String != alias {
+			return nil, errors.WithDetailf(ErrDuplicateAlias, "asset already has alias %q", storedAlias.String)
+		}
+		asset.Alias = &storedAlias.String
+	}
 
 	err = insertAssetTags(ctx, reg.db, asset.AssetID, tags)
 	if err != nil {
@@ -151,6 +432,13 @@ func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum in
 		return nil, errors.Wrap(err, "indexing annotated asset")
 	}
 
+	reg.cacheMu.Lock()
+	reg.cache.Add(asset.AssetID, asset)
+	if asset.Alias != nil {
+		reg.aliasCache.Add(*asset.Alias, asset.AssetID)
+	}
+	reg.cacheMu.Unlock()
+
 	return asset, nil
 }
 
@@ -161,12 +449,14 @@ func (reg *Registry) UpdateTags(ctx context.Context, id, alias *string, tags map
 		return errors.Wrap(ErrBadIdentifier)
 	}
 
+	err := query.ValidateTags(tags)
+	if err != nil {
+		return err
+	}
+
 	// Fetch the existing asset
 
-	var (
-		asset *Asset
-		err   error
-	)
+	var asset *Asset
 
 	if id != nil {
 		var aid bc.AssetID
@@ -190,11 +480,26 @@ func (reg *Registry) UpdateTags(ctx context.Context, id, alias *string, tags map
 
 	asset.Tags = tags
 
-	// Perform persistent updates
+	// Perform persistent updates, recording the tags being
+	// replaced so that earlier tags remain available as history.
 
-	err = insertAssetTags(ctx, reg.db, asset.AssetID, asset.Tags)
+	err = pg.RunInTx(ctx, reg.db, func(db pg.DB) error {
+		err := query.LockTagHistory(ctx, db)
+		if err != nil {
+			return err
+		}
+		const historyQ = `
+			INSERT INTO tag_history (object_type, object_id, tags)
+			SELECT 'asset', encode(asset_id, 'hex'), tags FROM asset_tags WHERE asset_id = $1
+		`
+		_, err = db.ExecContext(ctx, historyQ, asset.AssetID)
+		if err != nil {
+			return errors.Wrap(err, "recording tag history")
+		}
+		return errors.Wrap(insertAssetTags(ctx, db, asset.AssetID, asset.Tags), "inserting asset tags")
+	})
 	if err != nil {
-		return errors.Wrap(err, "inserting asset tags")
+		return err
 	}
 
 	err = reg.indexAnnotatedAsset(ctx, asset)
@@ -5,9 +5,13 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"golang.org/x/crypto/sha3"
 
+	"chain/core/query"
+	"chain/core/signers"
 	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/prottest"
 	"chain/testutil"
@@ -58,6 +62,162 @@ func TestDefineAssetIdempotency(t *testing.T) {
 	}
 }
 
+func TestDefineBatch(t *testing.T) {
+	r := NewRegistry(pgtest.NewTx(t), prottest.NewChain(t), nil)
+	ctx := context.Background()
+	keys := []chainkd.XPub{testutil.TestXPub}
+
+	existing, err := r.Define(ctx, keys, 1, nil, "existing", nil, "existing-token")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		req     DefineRequest
+		wantErr error
+	}{
+		{req: DefineRequest{XPubs: keys, Quorum: 1, Alias: "a", Tags: map[string]interface{}{"x": "y"}}},
+		{req: DefineRequest{XPubs: keys, Quorum: 2}, wantErr: signers.ErrBadQuorum},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, Tags: map[string]interface{}{"n": nil}}, wantErr: query.ErrBadTags},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, Alias: "existing"}, wantErr: ErrDuplicateAlias},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, Alias: "existing", ClientToken: "existing-token"}},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, ClientToken: "dup-token"}},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, ClientToken: "dup-token"}},
+		{req: DefineRequest{XPubs: keys, Quorum: 1, Alias: "existing", ClientToken: "new-token"}, wantErr: ErrDuplicateAlias},
+	}
+	reqs := make([]DefineRequest, 0, len(cases))
+	for _, c := range cases {
+		reqs = append(reqs, c.req)
+	}
+	assets, errs := r.DefineBatch(ctx, reqs)
+	if len(assets) != len(cases) || len(errs) != len(cases) {
+		t.Fatalf("DefineBatch returned %d assets and %d errors, want %d of each", len(assets), len(errs), len(cases))
+	}
+	for i, c := range cases {
+		if errors.Root(errs[i]) != c.wantErr {
+			t.Errorf("case %d: err = %v want %v", i, errs[i], c.wantErr)
+			continue
+		}
+		if c.wantErr != nil {
+			if assets[i] != nil {
+				t.Errorf("case %d: got asset %x along with error", i, assets[i].AssetID.Bytes())
+			}
+			continue
+		}
+		_, err := r.findByID(ctx, assets[i].AssetID)
+		if err != nil {
+			t.Errorf("case %d: cannot find asset %x: %v", i, assets[i].AssetID.Bytes(), err)
+		}
+	}
+
+	if assets[0] != nil {
+		found, err := r.findByID(ctx, assets[0].AssetID)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !testutil.DeepEqual(found.Tags, reqs[0].Tags) {
+			t.Errorf("tags = %v want %v", found.Tags, reqs[0].Tags)
+		}
+	}
+
+	// A retried client token returns the asset it defined before.
+	if assets[4] != nil && assets[4].AssetID != existing.AssetID {
+		t.Errorf("retried token defined %x, want %x", assets[4].AssetID.Bytes(), existing.AssetID.Bytes())
+	}
+	// Requests in one batch with the same client token
+	// define a single asset.
+	if assets[5] != nil && assets[6] != nil && assets[5].AssetID != assets[6].AssetID {
+		t.Errorf("same token defined %x and %x", assets[5].AssetID.Bytes(), assets[6].AssetID.Bytes())
+	}
+}
+
+func TestImportAsset(t *testing.T) {
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	r := NewRegistry(db, c, nil)
+	ctx := context.Background()
+
+	prog := []byte{0x51}
+	def := map[string]interface{}{"currency": "USD"}
+	rawDef, err := serializeAssetDef(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defhash := bc.NewHash(sha3.Sum256(rawDef))
+	id := bc.ComputeAssetID(prog, &c.InitialBlockHash, 1, &defhash)
+	tags := map[string]interface{}{"issuer": "elsewhere"}
+
+	_, err = r.Import(ctx, bc.AssetID{}, prog, 1, def, "usd", tags)
+	if errors.Root(err) != ErrMismatchedID {
+		t.Errorf("Import(wrong id) error = %v want %v", err, ErrMismatchedID)
+	}
+	_, err = r.Import(ctx, id, prog, 1, def, "usd", map[string]interface{}{"n": []interface{}{1}})
+	if errors.Root(err) != query.ErrBadTags {
+		t.Errorf("Import(bad tags) error = %v want %v", err, query.ErrBadTags)
+	}
+
+	imported, err := r.Import(ctx, id, prog, 1, def, "usd", tags)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if imported.Signer != nil {
+		t.Errorf("imported asset has signer %s", imported.Signer.ID)
+	}
+
+	// Look the asset up with a fresh registry,
+	// so it's read from the database.
+	found, err := NewRegistry(db, c, nil).FindByAlias(ctx, "usd")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if found.AssetID != id || found.Signer != nil {
+		t.Errorf("FindByAlias(usd) = %x with signer %v, want %x with none", found.AssetID.Bytes(), found.Signer, id.Bytes())
+	}
+	if !testutil.DeepEqual(found.Tags, tags) {
+		t.Errorf("tags = %v want %v", found.Tags, tags)
+	}
+	gotDef, err := found.Definition()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !testutil.DeepEqual(gotDef, def) {
+		t.Errorf("definition = %v want %v", gotDef, def)
+	}
+
+	// Importing again under the same alias is a no-op,
+	// but a different alias is refused.
+	again, err := r.Import(ctx, id, prog, 1, def, "usd", tags)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if again.sortID != imported.sortID {
+		t.Errorf("reimport sortID = %s want %s", again.sortID, imported.sortID)
+	}
+	_, err = r.Import(ctx, id, prog, 1, def, "dollars", tags)
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("Import(new alias) error = %v want %v", err, ErrDuplicateAlias)
+	}
+
+	// Another asset can't take the imported asset's alias.
+	_, err = r.Define(ctx, []chainkd.XPub{testutil.TestXPub}, 1, nil, "usd", nil, "")
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("Define(usd) error = %v want %v", err, ErrDuplicateAlias)
+	}
+
+	// Importing a local asset returns it unchanged.
+	local, err := r.Define(ctx, []chainkd.XPub{testutil.TestXPub}, 1, nil, "local", nil, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := r.Import(ctx, local.AssetID, local.IssuanceProgram, local.VMVersion, nil, "other", nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Signer == nil || got.Signer.ID != local.Signer.ID || *got.Alias != "local" {
+		t.Errorf("Import(local) = %+v want %+v", got, local)
+	}
+}
+
 func TestFindAssetByID(t *testing.T) {
 	r := NewRegistry(pgtest.NewTx(t), prottest.NewChain(t), nil)
 	ctx := context.Background()
@@ -6,8 +6,11 @@ import (
 
 	"chain/core/asset"
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
+	"chain/protocol/bc"
 )
 
 // POST /create-asset
@@ -60,6 +63,106 @@ func (a *API) createAsset(ctx context.Context, ins []struct {
 	return responses, nil
 }
 
+// POST /bulk-create-assets
+//
+// bulkCreateAssets is like createAsset, but inserts all of
+// the assets in a handful of database statements rather than
+// several round trips per asset. It accepts at most maxBulkItems
+// assets per request.
+func (a *API) bulkCreateAssets(ctx context.Context, ins []struct {
+	Alias       string
+	RootXPubs   []chainkd.XPub `json:"root_xpubs"`
+	Quorum      int
+	Definition  map[string]interface{}
+	Tags        map[string]interface{}
+	ClientToken string `json:"client_token"`
+}) ([]interface{}, error) {
+	if len(ins) > maxBulkItems {
+		return nil, errors.WithDetailf(errTooManyItems, "%d assets requested, limit is %d", len(ins), maxBulkItems)
+	}
+
+	reqs := make([]asset.DefineRequest, len(ins))
+	for i, in := range ins {
+		reqs[i] = asset.DefineRequest{
+			XPubs:       in.RootXPubs,
+			Quorum:      in.Quorum,
+			Definition:  in.Definition,
+			Alias:       in.Alias,
+			Tags:        in.Tags,
+			ClientToken: in.ClientToken,
+		}
+	}
+	assets, errs := a.assets.DefineBatch(ctx, reqs)
+
+	responses := make([]interface{}, len(ins))
+	for i, as := range assets {
+		if errs[i] != nil {
+			responses[i] = formatItemError(ctx, errs[i])
+			continue
+		}
+		aa, err := asset.Annotated(as)
+		if err != nil {
+			responses[i] = formatItemError(ctx, err)
+			continue
+		}
+		responses[i] = aa
+	}
+	return responses, nil
+}
+
+// POST /import-assets
+//
+// importAssets registers assets created by other cores on the
+// same blockchain, so they can be annotated and referred to by
+// alias locally.
+func (a *API) importAssets(ctx context.Context, ins []struct {
+	ID              bc.AssetID
+	Alias           string
+	IssuanceProgram chainjson.HexBytes `json:"issuance_program"`
+	VMVersion       *uint64            `json:"vm_version"`
+	Definition      map[string]interface{}
+	Tags            map[string]interface{}
+}) ([]interface{}, error) {
+	responses := make([]interface{}, len(ins))
+	var wg sync.WaitGroup
+	wg.Add(len(responses))
+
+	for i := range responses {
+		go func(i int) {
+			subctx := reqid.NewSubContext(ctx, reqid.New())
+			defer wg.Done()
+			defer batchRecover(subctx, &responses[i])
+
+			vmver := uint64(1)
+			if ins[i].VMVersion != nil {
+				vmver = *ins[i].VMVersion
+			}
+			a, err := a.assets.Import(
+				subctx,
+				ins[i].ID,
+				ins[i].IssuanceProgram,
+				vmver,
+				ins[i].Definition,
+				ins[i].Alias,
+				ins[i].Tags,
+			)
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			aa, err := asset.Annotated(a)
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			responses[i] = aa
+		}(i)
+	}
+
+	wg.Wait()
+	return responses, nil
+}
+
 // POST /update-asset-tags
 func (a *API) updateAssetTags(ctx context.Context, ins []struct {
 	ID    *string
@@ -6,11 +6,16 @@ import (
 	"reflect"
 	"testing"
 
+	"golang.org/x/crypto/sha3"
+
 	"chain/core/asset"
 	"chain/core/coretest"
 	"chain/core/pin"
 	"chain/core/query"
 	"chain/database/pg/pgtest"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
 	"chain/protocol/prottest"
 	"chain/testutil"
 )
@@ -122,3 +127,66 @@ func TestUpdateAssetTags(t *testing.T) {
 		t.Fatalf("id:\ngot:  %v\nwant: %v", items[0].ID.String(), id)
 	}
 }
+
+func TestImportAssets(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	pinStore := pin.NewStore(db)
+	indexer := query.NewIndexer(db, c, pinStore)
+	assets := asset.NewRegistry(db, c, pinStore)
+	assets.IndexAssets(indexer)
+	api := &API{db: db, chain: c, assets: assets, indexer: indexer}
+
+	prog := []byte{0x51}
+	defhash := bc.NewHash(sha3.Sum256(nil))
+	id := bc.ComputeAssetID(prog, &c.InitialBlockHash, 1, &defhash)
+	vm2 := uint64(2)
+
+	responses, err := api.importAssets(ctx, []struct {
+		ID              bc.AssetID
+		Alias           string
+		IssuanceProgram chainjson.HexBytes `json:"issuance_program"`
+		VMVersion       *uint64            `json:"vm_version"`
+		Definition      map[string]interface{}
+		Tags            map[string]interface{}
+	}{
+		{ID: id, Alias: "imported", IssuanceProgram: prog, Tags: map[string]interface{}{"a": "b"}},
+		{ID: bc.AssetID{}, Alias: "wrong-id", IssuanceProgram: prog},
+		{ID: id, Alias: "imported", IssuanceProgram: prog, VMVersion: &vm2},
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(responses))
+	}
+
+	aa, ok := responses[0].(*query.AnnotatedAsset)
+	if !ok {
+		t.Fatalf("response 0 = %v, want annotated asset", responses[0])
+	}
+	if aa.ID != id || aa.Alias != "imported" || bool(aa.IsLocal) {
+		t.Errorf("response 0 = %+v, want non-local asset %x aliased imported", aa, id.Bytes())
+	}
+	// The second request has the wrong ID, and the third
+	// computes a different ID from its VM version.
+	for i := 1; i < 3; i++ {
+		err, ok := responses[i].(error)
+		if !ok || errors.Root(err) != asset.ErrMismatchedID {
+			t.Errorf("response %d = %v, want %v", i, responses[i], asset.ErrMismatchedID)
+		}
+	}
+
+	page, err := api.listAssets(ctx, requestQuery{
+		Filter:       "alias=$1",
+		FilterParams: []interface{}{"imported"},
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	items := page.Items.([]*query.AnnotatedAsset)
+	if len(items) != 1 || items[0].ID != id {
+		t.Fatalf("list-assets(alias=imported) = %v, want asset %x", items, id.Bytes())
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/audit"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// maxAuditBlocks is the largest range of blocks
+// exported in a single audit bundle.
+const maxAuditBlocks = 10000
+
+// POST /export-audit-bundle
+//
+// exportAuditBundle returns an audit bundle for the blocks from
+// start_height through end_height, with inclusion proofs for the
+// given transactions. The bundle can be checked offline with
+// package chain/protocol/audit.
+func (a *API) exportAuditBundle(ctx context.Context, in struct {
+	StartHeight    uint64    `json:"start_height"`
+	EndHeight      uint64    `json:"end_height"`
+	TransactionIDs []bc.Hash `json:"transaction_ids"`
+}) (*audit.Bundle, error) {
+	if in.EndHeight == 0 {
+		in.EndHeight = a.chain.Height()
+	}
+	if in.StartHeight == 0 {
+		in.StartHeight = 1
+	}
+	if in.StartHeight > in.EndHeight || in.EndHeight > a.chain.Height() {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "invalid block range %d-%d, current height is %d", in.StartHeight, in.EndHeight, a.chain.Height())
+	}
+	if in.EndHeight-in.StartHeight >= maxAuditBlocks {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "%d blocks requested, limit is %d", in.EndHeight-in.StartHeight+1, maxAuditBlocks)
+	}
+
+	var blocks []*legacy.Block
+	for height := in.StartHeight; height <= in.EndHeight; height++ {
+		b, err := a.chain.GetBlock(ctx, height)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting block %d", height)
+		}
+		blocks = append(blocks, b)
+	}
+	return audit.NewBundle(blocks, in.TransactionIDs)
+}
@@ -5,6 +5,7 @@ const GrantPrefix = "/core/grant/"
 var Policies = []string{
 	"client-readwrite",
 	"client-readonly",
+	"client-approver",
 	"crosscore",
 	"crosscore-signblock",
 	"monitoring",
@@ -13,40 +14,87 @@ var Policies = []string{
 }
 
 var policyByRoute = map[string][]string{
-	"/create-account":           {"client-readwrite"},
-	"/create-asset":             {"client-readwrite"},
-	"/update-account-tags":      {"client-readwrite"},
-	"/update-asset-tags":        {"client-readwrite"},
-	"/build-transaction":        {"client-readwrite", "internal"},
-	"/submit-transaction":       {"client-readwrite", "internal"},
-	"/create-control-program":   {"client-readwrite"},
-	"/create-account-receiver":  {"client-readwrite"},
-	"/create-transaction-feed":  {"client-readwrite"},
-	"/get-transaction-feed":     {"client-readwrite", "client-readonly"},
-	"/update-transaction-feed":  {"client-readwrite"},
-	"/delete-transaction-feed":  {"client-readwrite"},
-	"/mockhsm":                  {"client-readwrite"},
-	"/mockhsm/create-block-key": {"internal"},
-	"/mockhsm/create-key":       {"client-readwrite"},
-	"/mockhsm/list-keys":        {"client-readwrite", "client-readonly"},
-	"/mockhsm/delkey":           {"client-readwrite"},
-	"/mockhsm/sign-transaction": {"client-readwrite"},
+	"/create-account":                  {"client-readwrite"},
+	"/create-asset":                    {"client-readwrite"},
+	"/bulk-create-accounts":            {"client-readwrite"},
+	"/bulk-create-assets":              {"client-readwrite"},
+	"/import-assets":                   {"client-readwrite"},
+	"/update-account-tags":             {"client-readwrite"},
+	"/get-account-descriptor":          {"client-readwrite", "client-readonly"},
+	"/import-account-descriptor":       {"client-readwrite"},
+	"/get-account-policy":              {"client-readwrite", "client-readonly"},
+	"/set-account-policy":              {"client-readwrite"},
+	"/approve-account-policy-override": {"client-approver"},
+	"/set-account-hot":                 {"client-readwrite"},
+	"/get-hot-account-balance":         {"client-readwrite", "client-readonly"},
+	"/create-account-hold":             {"client-readwrite"},
+	"/release-account-hold":            {"client-readwrite"},
+	"/list-account-holds":              {"client-readwrite", "client-readonly"},
+	"/update-asset-tags":               {"client-readwrite"},
+	"/build-transaction":               {"client-readwrite", "internal"},
+	"/build-issuance":                  {"client-readwrite"},
+	"/submit-transaction":              {"client-readwrite", "internal"},
+	"/get-transaction-status":          {"client-readwrite", "client-readonly", "internal"},
+	"/get-signing-payloads":            {"client-readwrite", "client-readonly"},
+	"/review-transaction":              {"client-approver"},
+	"/create-control-program":          {"client-readwrite"},
+	"/create-account-receiver":         {"client-readwrite"},
+	"/create-transaction-feed":         {"client-readwrite"},
+	"/get-transaction-feed":            {"client-readwrite", "client-readonly"},
+	"/update-transaction-feed":         {"client-readwrite"},
+	"/delete-transaction-feed":         {"client-readwrite"},
+	"/mockhsm":                         {"client-readwrite"},
+	"/mockhsm/create-block-key":        {"internal"},
+	"/mockhsm/create-key":              {"client-readwrite"},
+	"/mockhsm/list-keys":               {"client-readwrite", "client-readonly"},
+	"/mockhsm/delkey":                  {"client-readwrite"},
+	"/mockhsm/sign-transaction":        {"client-readwrite"},
+	"/mockhsm/export-key":              {"internal"},
+	"/mockhsm/import-key":              {"internal"},
+	"/mockhsm/rotate-keystore":         {"internal"},
 
 	"/list-accounts":          {"client-readwrite", "client-readonly"},
 	"/list-assets":            {"client-readwrite", "client-readonly"},
 	"/list-transaction-feeds": {"client-readwrite", "client-readonly"},
 	"/list-transactions":      {"client-readwrite", "client-readonly"},
 	"/list-balances":          {"client-readwrite", "client-readonly"},
+	"/list-distinct-values":   {"client-readwrite", "client-readonly"},
+	"/list-tag-history":       {"client-readwrite", "client-readonly"},
+	"/list-annotated-changes": {"client-readwrite", "client-readonly"},
+	"/list-asset-velocities":  {"client-readwrite", "client-readonly"},
+	"/list-holding-times":     {"client-readwrite", "client-readonly"},
 	"/list-unspent-outputs":   {"client-readwrite", "client-readonly"},
+	"/get-output-provenance":  {"client-readwrite", "client-readonly"},
+	"/get-consensus-program":  {"client-readwrite", "client-readonly", "monitoring"},
+	"/list-block-headers":     {"client-readwrite", "client-readonly", "monitoring"},
+	"/get-block-signers":      {"client-readwrite", "client-readonly", "monitoring"},
+	"/get-index-summary":      {"client-readwrite", "client-readonly", "monitoring"},
+	"/export-audit-bundle":    {"client-readwrite", "client-readonly"},
+	"/export-blocks":          {"client-readwrite", "client-readonly"},
+	"/import-blocks":          {"client-readwrite"},
+	"/reindex-transactions":   {"client-readwrite", "internal"},
+	"/get-asset-volumes":      {"client-readwrite", "client-readonly", "monitoring"},
+	"/metrics":                {"client-readwrite", "client-readonly", "monitoring"},
 	"/reset":                  {"client-readwrite", "internal"},
+	"/standby/fence":          {"internal"},
+	"/standby/promote":        {"internal"},
 
-	crosscoreRPCPrefix + "submit":            {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-block":         {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot-info": {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot":      {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "signer/sign-block": {"internal", "crosscore-signblock"},
-	crosscoreRPCPrefix + "block-height":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "submit":                 {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-block":              {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-block-id":           {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-transaction-status": {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot-info":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot":           {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "signer/sign-block":      {"internal", "crosscore-signblock"},
+	crosscoreRPCPrefix + "block-height":           {"crosscore", "crosscore-signblock"},
 
+	// The generator notifies only block signers, with the access
+	// tokens it signs blocks with; notifications also carry a
+	// secret from the registration.
+	crosscoreRPCPrefix + "register-block-callback": {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "notify-block":            {"crosscore-signblock", "internal"},
+
+	// Only internal callers may grant client-approver; see createGrant.
 	"/list-authorization-grants":  {"client-readwrite", "client-readonly", "internal"},
 	"/create-authorization-grant": {"client-readwrite", "internal"},
 	"/delete-authorization-grant": {"client-readwrite", "internal"},
@@ -60,6 +108,7 @@ var policyByRoute = map[string][]string{
 	"/configure":                  {"client-readwrite", "internal"},
 	"/config":                     {"client-readwrite", "client-readonly", "monitoring", "internal"},
 	"/info":                       {"client-readwrite", "client-readonly", "crosscore", "crosscore-signblock", "monitoring", "internal"},
+	"/node-settings":              {"client-readwrite", "internal"},
 
 	"/debug/": {"client-readwrite", "client-readonly", "monitoring"},
 
@@ -41,12 +41,15 @@ func TestAuthz(t *testing.T) {
 	testPolicies := []string{
 		"client-readwrite",
 		"client-readonly",
+		"client-approver",
 		"crosscore",
 		"crosscore-signblock",
 		"monitoring",
 		"internal",
 		"public",
 	}
+	// Only an internal caller can grant client-approver.
+	internalCtx := authz.NewContext(ctx, []string{"internal"})
 	tokens := make(map[string]*accesstoken.Token)
 	for i := 0; i < len(testPolicies); i++ {
 		token, err := accessTokens.Create(ctx, fmt.Sprintf("token%d", i), "")
@@ -54,7 +57,7 @@ func TestAuthz(t *testing.T) {
 			t.Fatal(err)
 		}
 		tokens[Policies[i]] = token
-		_, err = api.createGrant(ctx, apiGrant{
+		_, err = api.createGrant(internalCtx, apiGrant{
 			GuardType: "access_token",
 			GuardData: map[string]interface{}{"id": token.ID},
 			Policy:    Policies[i],
@@ -83,6 +86,26 @@ func TestAuthz(t *testing.T) {
 			"internal":            false,
 			"public":              false,
 		},
+		"/review-transaction": map[string]bool{
+			"client-readwrite":    false,
+			"client-readonly":     false,
+			"client-approver":     true,
+			"crosscore":           false,
+			"crosscore-signblock": false,
+			"monitoring":          false,
+			"internal":            false,
+			"public":              false,
+		},
+		"/approve-account-policy-override": map[string]bool{
+			"client-readwrite":    false,
+			"client-readonly":     false,
+			"client-approver":     true,
+			"crosscore":           false,
+			"crosscore-signblock": false,
+			"monitoring":          false,
+			"internal":            false,
+			"public":              false,
+		},
 		"/reset": map[string]bool{
 			"client-readwrite":    true,
 			"client-readonly":     false,
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/fetch"
+	"chain/core/leader"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc/legacy"
+)
+
+// maxExportBlocks is the largest number of
+// blocks returned by a single /export-blocks.
+const maxExportBlocks = 1000
+
+var errImportGenerator = errors.New("generator cannot import blocks")
+
+// POST /export-blocks
+//
+// exportBlocks returns the raw blocks from start_height through
+// end_height, or as many of them as fit in a single response,
+// for writing to a block file. See package fetch. Blocks above
+// the current height are left out, so the response is empty
+// once the caller has every block.
+func (a *API) exportBlocks(ctx context.Context, in struct {
+	StartHeight uint64 `json:"start_height"`
+	EndHeight   uint64 `json:"end_height"`
+}) (x struct {
+	Blocks []chainjson.HexBytes `json:"blocks"`
+}, err error) {
+	if in.StartHeight == 0 {
+		in.StartHeight = 1
+	}
+	if in.EndHeight != 0 && in.StartHeight > in.EndHeight {
+		return x, errors.WithDetailf(httpjson.ErrBadRequest, "invalid block range %d-%d", in.StartHeight, in.EndHeight)
+	}
+	height := a.chain.Height()
+	if in.EndHeight == 0 || in.EndHeight > height {
+		in.EndHeight = height
+	}
+	if in.EndHeight >= in.StartHeight+maxExportBlocks {
+		in.EndHeight = in.StartHeight + maxExportBlocks - 1
+	}
+
+	x.Blocks = []chainjson.HexBytes{}
+	for h := in.StartHeight; h <= in.EndHeight; h++ {
+		raw, err := a.store.GetRawBlock(ctx, h)
+		if err != nil {
+			return x, errors.Wrapf(err, "getting block %d", h)
+		}
+		x.Blocks = append(x.Blocks, raw)
+	}
+	return x, nil
+}
+
+// POST /import-blocks
+//
+// importBlocks validates blocks read from a block file and
+// applies them to the blockchain, for a Core that can't reach
+// the generator. It returns the resulting block height.
+func (a *API) importBlocks(ctx context.Context, in struct {
+	Blocks []*legacy.Block `json:"blocks"`
+}) (x struct {
+	BlockHeight uint64 `json:"block_height"`
+}, err error) {
+	if a.config.IsGenerator {
+		return x, errors.Wrap(errImportGenerator)
+	}
+	if a.leader.State() != leader.Leading {
+		err = a.forwardToLeader(ctx, "/import-blocks", in, &x)
+		return x, err
+	}
+
+	err = fetch.ImportBlocks(ctx, a.chain, in.Blocks)
+	x.BlockHeight = a.chain.Height()
+	return x, err
+}
@@ -0,0 +1,89 @@
+package blocksigner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	"chain/errors"
+)
+
+// AWSKMS is a KMSBackend that signs with an
+// ECC_NIST_EDWARDS25519 key in AWS KMS.
+type AWSKMS struct {
+	Region string
+	KeyID  string // key ID, ARN, or alias
+
+	Credentials *credentials.Credentials
+
+	// Endpoint, if set, overrides the
+	// regional endpoint, for testing.
+	Endpoint string
+
+	Client *http.Client // if nil, uses http.DefaultClient
+}
+
+// Name returns "aws." followed by the key's region.
+func (k *AWSKMS) Name() string { return "aws." + k.Region }
+
+// Sign calls the KMS Sign action, as described in
+// https://docs.aws.amazon.com/kms/latest/APIReference/API_Sign.html.
+func (k *AWSKMS) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"KeyId":            k.KeyID,
+		"Message":          msg, // base64-encoded
+		"MessageType":      "RAW",
+		"SigningAlgorithm": "ED25519_SHA_512",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = "https://kms." + k.Region + ".amazonaws.com/"
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Sign")
+	_, err = v4.NewSigner(k.Credentials).Sign(req, bytes.NewReader(body), "kms", k.Region, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "signing KMS request")
+	}
+
+	var resp struct {
+		Signature []byte // base64-encoded
+	}
+	err = doKMSRequest(k.Client, req, &resp)
+	return resp.Signature, errors.Wrap(err, "calling AWS KMS")
+}
+
+// doKMSRequest sends req with client, or http.DefaultClient
+// if client is nil, and decodes the JSON response into v.
+func doKMSRequest(client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return errors.WithDetailf(errors.New(resp.Status), "response body: %s", b)
+	}
+	return errors.Wrap(json.Unmarshal(b, v), "decoding response")
+}
@@ -32,6 +32,11 @@ type Signer interface {
 // BlockSigner validates and signs blocks.
 type BlockSigner struct {
 	Pub ed25519.PublicKey
+
+	// Policy, if set, is checked by ValidateAndSignBlock
+	// after validating a block, and before signing it.
+	Policy *Policy
+
 	hsm Signer
 	db  pg.DB
 	c   *protocol.Chain
@@ -99,6 +104,13 @@ func (s *BlockSigner) ValidateAndSignBlock(ctx context.Context, b *legacy.Block)
 	if err != nil {
 		return nil, errors.Wrap(err, "validating block for signature")
 	}
+	if s.Policy != nil {
+		err = s.Policy.Check(prev, b)
+		logDecision(ctx, b, err)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+	}
 
 	err = lockBlockHeight(ctx, s.db, b)
 	if err != nil {
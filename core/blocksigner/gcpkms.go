@@ -0,0 +1,115 @@
+package blocksigner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"chain/errors"
+)
+
+// gcpMetadataTokenURL is where a GCE instance or GKE pod gets
+// an access token for its service account.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPKMS is a KMSBackend that signs with an
+// EC_SIGN_ED25519 key version in Google Cloud KMS.
+type GCPKMS struct {
+	// KeyVersion is the resource name of the key version, such as
+	// projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1.
+	KeyVersion string
+
+	// Token, if set, returns an OAuth 2.0 access token for
+	// calling Cloud KMS. Otherwise, Sign gets one from the
+	// metadata server for the instance's service account.
+	Token func(context.Context) (string, error)
+
+	// Endpoint, if set, overrides the
+	// Cloud KMS endpoint, for testing.
+	Endpoint string
+
+	Client *http.Client // if nil, uses http.DefaultClient
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// Name returns "gcp." followed by the key's location.
+func (k *GCPKMS) Name() string {
+	parts := strings.Split(k.KeyVersion, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "locations" {
+			return "gcp." + parts[i+1]
+		}
+	}
+	return "gcp"
+}
+
+// Sign calls the asymmetricSign method, as described in
+// https://cloud.google.com/kms/docs/reference/rest/v1/projects.locations.keyRings.cryptoKeys.cryptoKeyVersions/asymmetricSign.
+func (k *GCPKMS) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	token, err := k.accessToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting access token")
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"data": msg, // base64-encoded
+	})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = "https://cloudkms.googleapis.com"
+	}
+	req, err := http.NewRequest("POST", endpoint+"/v1/"+k.KeyVersion+":asymmetricSign", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var resp struct {
+		Signature []byte `json:"signature"` // base64-encoded
+	}
+	err = doKMSRequest(k.Client, req, &resp)
+	return resp.Signature, errors.Wrap(err, "calling Cloud KMS")
+}
+
+// accessToken returns k.Token's token, or else a token from the
+// metadata server, reusing it until shortly before it expires.
+func (k *GCPKMS) accessToken(ctx context.Context) (string, error) {
+	if k.Token != nil {
+		return k.Token(ctx)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if time.Now().Before(k.tokenExpiry) {
+		return k.token, nil
+	}
+
+	req, err := http.NewRequest("GET", gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"` // seconds
+	}
+	err = doKMSRequest(k.Client, req, &resp)
+	if err != nil {
+		return "", errors.Wrap(err, "calling metadata server")
+	}
+	k.token = resp.AccessToken
+	k.tokenExpiry = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return k.token, nil
+}
@@ -0,0 +1,144 @@
+package blocksigner
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/log"
+	"chain/metrics"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// kmsTimeout bounds each request to a KMS backend,
+// so a slow one doesn't hold up its fallbacks.
+const kmsTimeout = 5 * time.Second
+
+var kmsFailures = expvar.NewMap("blocksigner.kms.failures")
+
+var errKMSSignature = errors.New("KMS signature doesn't verify")
+
+// KMSBackend signs messages with an ed25519 key held in a
+// cloud key management service, such as AWSKMS or GCPKMS.
+type KMSBackend interface {
+	// Name identifies the backend in logs and metrics.
+	Name() string
+
+	// Sign returns the ed25519 signature of msg.
+	Sign(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+// KMSSigner implements the Signer interface by calling cloud KMS
+// backends to sign blocks, so that no block signing key is ever
+// held by the Core or an enclave.
+//
+// Each backend must hold the same key, as with an AWS multi-region
+// key or its replicas. Sign tries the backends in order, falling
+// back to the next when one fails or times out, and checks every
+// signature against the public key before returning it.
+// Concurrent calls to sign the same block share one request.
+type KMSSigner struct {
+	Backends []KMSBackend
+
+	mu       sync.Mutex
+	inflight map[bc.Hash]*kmsCall
+
+	latencyOnce sync.Once
+	latency     []*metrics.RotatingLatency // by backend position
+}
+
+type kmsCall struct {
+	done chan struct{}
+	sig  []byte
+	err  error
+}
+
+// Sign signs bh with the key pub held by s's backends.
+func (s *KMSSigner) Sign(ctx context.Context, pub ed25519.PublicKey, bh *legacy.BlockHeader) ([]byte, error) {
+	hash := bh.Hash()
+
+	s.mu.Lock()
+	if s.inflight == nil {
+		s.inflight = make(map[bc.Hash]*kmsCall)
+	}
+	call, ok := s.inflight[hash]
+	if !ok {
+		call = &kmsCall{done: make(chan struct{})}
+		s.inflight[hash] = call
+		go func() {
+			// Detached from ctx, since
+			// other callers may be waiting.
+			call.sig, call.err = s.sign(context.Background(), pub, hash, bh.Height)
+			s.mu.Lock()
+			delete(s.inflight, hash)
+			s.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.sig, call.err
+	}
+}
+
+func (s *KMSSigner) sign(ctx context.Context, pub ed25519.PublicKey, hash bc.Hash, height uint64) ([]byte, error) {
+	if len(s.Backends) == 0 {
+		return nil, errors.New("no KMS backends")
+	}
+	s.latencyOnce.Do(s.publishLatency)
+
+	var err error
+	for i, b := range s.Backends {
+		var sig []byte
+		sig, err = s.signWith(ctx, i, pub, hash)
+		if err == nil {
+			return sig, nil
+		}
+		kmsFailures.Add(backendKey(i, b), 1)
+		log.Error(ctx, err, fmt.Sprintf("Unable to sign block at height %d with KMS backend %s", height, b.Name()))
+	}
+	return nil, err
+}
+
+func (s *KMSSigner) signWith(ctx context.Context, i int, pub ed25519.PublicKey, hash bc.Hash) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, kmsTimeout)
+	defer cancel()
+
+	defer s.latency[i].RecordSince(time.Now())
+	sig, err := s.Backends[i].Sign(ctx, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, hash.Bytes(), sig) {
+		return nil, errors.Wrapf(errKMSSignature, "with public key %x", []byte(pub))
+	}
+	return sig, nil
+}
+
+// publishLatency publishes a latency histogram for each backend,
+// under the expvar "latency" map, as "blocksigner.kms.<key>",
+// where the key is the one backendKey returns.
+func (s *KMSSigner) publishLatency() {
+	s.latency = make([]*metrics.RotatingLatency, len(s.Backends))
+	for i, b := range s.Backends {
+		l := metrics.NewRotatingLatency(5, kmsTimeout)
+		s.latency[i] = l
+		metrics.PublishLatency("blocksigner.kms."+backendKey(i, b), l)
+	}
+}
+
+// backendKey identifies the backend at position i in metrics.
+// Names alone may collide, as for replicas of one key in regions
+// reached through the same service, so it includes the position.
+func backendKey(i int, b KMSBackend) string {
+	return fmt.Sprintf("%d.%s", i, b.Name())
+}
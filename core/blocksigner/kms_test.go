@@ -0,0 +1,180 @@
+package blocksigner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+type fakeKMS struct {
+	name  string
+	prv   ed25519.PrivateKey
+	err   error
+	calls int32
+}
+
+func (k *fakeKMS) Name() string { return k.name }
+
+func (k *fakeKMS) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	atomic.AddInt32(&k.calls, 1)
+	if k.err != nil {
+		return nil, k.err
+	}
+	return ed25519.Sign(k.prv, msg), nil
+}
+
+func TestKMSSignerFallback(t *testing.T) {
+	pub, prv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	down := &fakeKMS{name: "down", err: errors.New("unavailable")}
+	wrongKey := &fakeKMS{name: "wrong", prv: otherPrv}
+	up := &fakeKMS{name: "up", prv: prv}
+	s := &KMSSigner{Backends: []KMSBackend{down, wrongKey, up}}
+
+	bh := &legacy.BlockHeader{Height: 2}
+	sig, err := s.Sign(context.Background(), pub, bh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := bh.Hash()
+	if !ed25519.Verify(pub, hash.Bytes(), sig) {
+		t.Error("bad signature")
+	}
+	if down.calls != 1 || wrongKey.calls != 1 || up.calls != 1 {
+		t.Errorf("calls = %d, %d, %d, want 1 each", down.calls, wrongKey.calls, up.calls)
+	}
+
+	s = &KMSSigner{Backends: []KMSBackend{down, wrongKey}}
+	_, err = s.Sign(context.Background(), pub, bh)
+	if errors.Root(err) != errKMSSignature {
+		t.Errorf("err = %v, want %v", err, errKMSSignature)
+	}
+}
+
+func TestKMSSignerSameNames(t *testing.T) {
+	pub, prv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Backends with the same name get metrics of their own.
+	down := &fakeKMS{name: "replica", err: errors.New("unavailable")}
+	up := &fakeKMS{name: "replica", prv: prv}
+	s := &KMSSigner{Backends: []KMSBackend{down, up}}
+	_, err = s.Sign(context.Background(), pub, &legacy.BlockHeader{Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.latency) != 2 || s.latency[0] == s.latency[1] {
+		t.Errorf("got latency histograms %v, want 2 distinct ones", s.latency)
+	}
+	if kmsFailures.Get("0.replica") == nil || kmsFailures.Get("1.replica") != nil {
+		t.Errorf("failures = %s, want one for the first backend only", kmsFailures)
+	}
+}
+
+func TestAWSKMS(t *testing.T) {
+	pub, prv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Amz-Target") != "TrentService.Sign" {
+			t.Errorf("X-Amz-Target = %q", req.Header.Get("X-Amz-Target"))
+		}
+		if !strings.Contains(req.Header.Get("Authorization"), "/us-west-2/kms/aws4_request") {
+			t.Errorf("Authorization = %q, want a SigV4 signature for kms in us-west-2", req.Header.Get("Authorization"))
+		}
+		var body struct {
+			KeyID            string `json:"KeyId"`
+			Message          []byte
+			SigningAlgorithm string
+		}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body.KeyID != "alias/block-key" || body.SigningAlgorithm != "ED25519_SHA_512" {
+			t.Errorf("got key %q, algorithm %q", body.KeyID, body.SigningAlgorithm)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Signature": ed25519.Sign(prv, body.Message)})
+	}))
+	defer server.Close()
+
+	k := &AWSKMS{
+		Region:      "us-west-2",
+		KeyID:       "alias/block-key",
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Endpoint:    server.URL,
+	}
+	sig, err := k.Sign(context.Background(), []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(pub, []byte("message"), sig) {
+		t.Error("bad signature")
+	}
+}
+
+func TestGCPKMS(t *testing.T) {
+	pub, prv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const keyVersion = "projects/p/locations/us-east1/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/"+keyVersion+":asymmetricSign" {
+			t.Errorf("path = %q", req.URL.Path)
+		}
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q", req.Header.Get("Authorization"))
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Data []byte `json:"data"`
+		}
+		err = json.Unmarshal(b, &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"signature": ed25519.Sign(prv, body.Data)})
+	}))
+	defer server.Close()
+
+	k := &GCPKMS{
+		KeyVersion: keyVersion,
+		Token:      func(context.Context) (string, error) { return "token", nil },
+		Endpoint:   server.URL,
+	}
+	if k.Name() != "gcp.us-east1" {
+		t.Errorf("Name() = %q, want gcp.us-east1", k.Name())
+	}
+	sig, err := k.Sign(context.Background(), []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(pub, []byte("message"), sig) {
+		t.Error("bad signature")
+	}
+}
@@ -0,0 +1,147 @@
+package blocksigner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrPolicy is returned from ValidateAndSignBlock, and from
+// CheckGenerator, when the signer's policy rejects a block.
+var ErrPolicy = errors.New("block rejected by signer policy")
+
+// Policy holds rules a signer applies to a block, beyond the
+// protocol's validation rules, before signing it. The zero value
+// of each field imposes no rule.
+//
+// A policy file is a JSON object, such as
+//
+//	{
+//		"max_transactions": 1000,
+//		"forbidden_issuances": ["<asset id>"],
+//		"min_block_interval_ms": 500,
+//		"generator_keys": ["<key fingerprint>"]
+//	}
+type Policy struct {
+	// MaxTransactions is the largest number
+	// of transactions a block may have.
+	MaxTransactions int `json:"max_transactions"`
+
+	// ForbiddenIssuances lists assets a
+	// block may not issue any units of.
+	ForbiddenIssuances []bc.AssetID `json:"forbidden_issuances"`
+
+	// MinBlockIntervalMS is the least time, in milliseconds,
+	// between a block's timestamp and that of the block before it.
+	MinBlockIntervalMS uint64 `json:"min_block_interval_ms"`
+
+	// GeneratorKeys lists the fingerprints of the keys whose
+	// TLS client certificates may request signatures. A key's
+	// fingerprint is the hex-encoded SHA-256 hash of its
+	// certificate's DER-encoded SubjectPublicKeyInfo.
+	// See CheckGenerator.
+	GeneratorKeys []string `json:"generator_keys"`
+}
+
+// LoadPolicy reads and parses the policy file at path.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading signer policy")
+	}
+	p, err := ParsePolicy(b)
+	return p, errors.Wrapf(err, "parsing signer policy %s", path)
+}
+
+// ParsePolicy parses the JSON policy in b.
+// Unknown fields are an error, so that a misspelled
+// rule isn't silently ignored.
+func ParsePolicy(b []byte) (*Policy, error) {
+	p := new(Policy)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(p)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, k := range p.GeneratorKeys {
+		fp, err := hex.DecodeString(k)
+		if err != nil || len(fp) != sha256.Size {
+			return nil, errors.New("invalid generator key fingerprint " + k)
+		}
+	}
+	if p.MaxTransactions < 0 {
+		return nil, errors.New("negative max_transactions")
+	}
+	return p, nil
+}
+
+// KeyFingerprint returns the fingerprint of cert's public
+// key, as listed in a policy's generator keys.
+func KeyFingerprint(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(h[:])
+}
+
+// Check reports whether the policy allows signing b,
+// which follows prev, returning an error if not.
+func (p *Policy) Check(prev, b *legacy.Block) error {
+	if p.MaxTransactions > 0 && len(b.Transactions) > p.MaxTransactions {
+		return errors.WithDetailf(ErrPolicy, "block has %d transactions, more than the maximum of %d", len(b.Transactions), p.MaxTransactions)
+	}
+	if p.MinBlockIntervalMS > 0 && b.TimestampMS < prev.TimestampMS+p.MinBlockIntervalMS {
+		return errors.WithDetailf(ErrPolicy, "block is %dms after the previous block, less than the minimum of %dms", b.TimestampMS-prev.TimestampMS, p.MinBlockIntervalMS)
+	}
+	for _, tx := range b.Transactions {
+		for _, in := range tx.Inputs {
+			if !in.IsIssuance() {
+				continue
+			}
+			assetID := in.AssetID()
+			for _, forbidden := range p.ForbiddenIssuances {
+				if assetID == forbidden {
+					return errors.WithDetailf(ErrPolicy, "transaction %x issues forbidden asset %x", tx.ID.Bytes(), assetID.Bytes())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckGenerator reports whether the policy allows the holder of
+// certs, the TLS client certificate chain of a request for a
+// signature, to request one, returning an error if not. If the
+// policy lists no generator keys, any caller may request one.
+func (p *Policy) CheckGenerator(certs []*x509.Certificate) error {
+	if len(p.GeneratorKeys) == 0 {
+		return nil
+	}
+	if len(certs) == 0 {
+		return errors.WithDetail(ErrPolicy, "signature requested without a client certificate")
+	}
+	fp := KeyFingerprint(certs[0])
+	for _, k := range p.GeneratorKeys {
+		if k == fp {
+			return nil
+		}
+	}
+	return errors.WithDetailf(ErrPolicy, "signature requested by unknown generator key %s", fp)
+}
+
+// logDecision logs the policy's decision on b.
+func logDecision(ctx context.Context, b *legacy.Block, err error) {
+	if err != nil {
+		log.Printkv(ctx, "at", "signer policy", "height", b.Height, "decision", "reject", "reason", errors.Detail(err))
+		return
+	}
+	log.Printkv(ctx, "at", "signer policy", "height", b.Height, "decision", "approve")
+}
@@ -0,0 +1,156 @@
+package blocksigner
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestParsePolicy(t *testing.T) {
+	cases := []struct {
+		policy string
+		ok     bool
+	}{
+		{`{}`, true},
+		{`{"max_transactions": 10, "min_block_interval_ms": 500}`, true},
+		{`{"forbidden_issuances": ["` + strings.Repeat("ab", 32) + `"]}`, true},
+		{`{"forbidden_issuances": ["ab"]}`, false},
+		{`{"generator_keys": ["` + strings.Repeat("ab", 32) + `"]}`, true},
+		{`{"generator_keys": ["not hex"]}`, false},
+		{`{"max_transactions": -1}`, false},
+		{`{"max_transaction": 10}`, false}, // misspelled
+		{`not json`, false},
+	}
+	for _, c := range cases {
+		_, err := ParsePolicy([]byte(c.policy))
+		if (err == nil) != c.ok {
+			t.Errorf("ParsePolicy(%s) error = %v, want ok %t", c.policy, err, c.ok)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	issuance := legacy.NewIssuanceInput([]byte{1}, 1, nil, bc.Hash{}, []byte{0x51}, nil, nil)
+	issued := issuance.AssetID()
+	tx := legacy.NewTx(legacy.TxData{Version: 1, Inputs: []*legacy.TxInput{issuance}})
+
+	prev := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 1, TimestampMS: 1000}}
+	block := func(timestampMS uint64, txs ...*legacy.Tx) *legacy.Block {
+		return &legacy.Block{
+			BlockHeader:  legacy.BlockHeader{Height: 2, TimestampMS: timestampMS},
+			Transactions: txs,
+		}
+	}
+
+	cases := []struct {
+		policy string
+		block  *legacy.Block
+		ok     bool
+	}{
+		{`{}`, block(1001, tx, tx), true},
+		{`{"max_transactions": 2}`, block(1001, tx, tx), true},
+		{`{"max_transactions": 1}`, block(1001, tx, tx), false},
+		{`{"min_block_interval_ms": 500}`, block(1500), true},
+		{`{"min_block_interval_ms": 500}`, block(1499), false},
+		{`{"forbidden_issuances": ["` + strings.Repeat("00", 32) + `"]}`, block(1001, tx), true},
+		{`{"forbidden_issuances": ["` + issued.String() + `"]}`, block(1001), true},
+		{`{"forbidden_issuances": ["` + issued.String() + `"]}`, block(1001, tx), false},
+	}
+	for _, c := range cases {
+		p, err := ParsePolicy([]byte(c.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = p.Check(prev, c.block)
+		if c.ok && err != nil {
+			t.Errorf("policy %s: unexpected error %v", c.policy, err)
+		} else if !c.ok && errors.Root(err) != ErrPolicy {
+			t.Errorf("policy %s: error = %v, want ErrPolicy", c.policy, err)
+		}
+	}
+}
+
+func TestPolicyCheckGenerator(t *testing.T) {
+	cert := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("generator key")}
+	other := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("other key")}
+
+	p, err := ParsePolicy([]byte(`{"generator_keys": ["` + KeyFingerprint(cert) + `"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.CheckGenerator([]*x509.Certificate{cert})
+	if err != nil {
+		t.Errorf("generator cert: unexpected error %v", err)
+	}
+	for _, certs := range [][]*x509.Certificate{nil, {other}} {
+		err = p.CheckGenerator(certs)
+		if errors.Root(err) != ErrPolicy {
+			t.Errorf("CheckGenerator(%v) = %v, want ErrPolicy", certs, err)
+		}
+	}
+
+	var open Policy
+	err = open.CheckGenerator(nil)
+	if err != nil {
+		t.Errorf("no generator keys: unexpected error %v", err)
+	}
+}
+
+func TestPolicyRunTests(t *testing.T) {
+	issuance := legacy.NewIssuanceInput([]byte{1}, 1, nil, bc.Hash{}, []byte{0x51}, nil, nil)
+	tx := legacy.NewTx(legacy.TxData{Version: 1, Inputs: []*legacy.TxInput{issuance}})
+	prev := &legacy.BlockHeader{Height: 1, TimestampMS: 1000}
+	full := &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 2, TimestampMS: 2000},
+		Transactions: []*legacy.Tx{tx, tx},
+	}
+
+	tests := []*PolicyTest{
+		{Name: "one tx", Prev: prev, TimestampMS: 2000, Transactions: []*legacy.Tx{tx}, Allow: true},
+		{Name: "too soon", Prev: prev, TimestampMS: 1001, Allow: false},
+		{Name: "full block", Prev: prev, Block: full, Allow: false},
+		{Name: "wrong expectation", Prev: prev, Block: full, Allow: true},
+	}
+
+	// The tests are read from a file, with the
+	// blocks and transactions hex-encoded.
+	b, err := json.Marshal(tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "policytests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests, err = LoadPolicyTests(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ParsePolicy([]byte(`{"max_transactions": 1, "min_block_interval_ms": 500}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := p.RunTests(tests)
+	for i, err := range errs[:3] {
+		if err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+		}
+	}
+	if errs[3] == nil {
+		t.Error("test 3: passed, want failure")
+	}
+}
@@ -0,0 +1,90 @@
+package blocksigner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+// A PolicyTest is a fixture for checking that a policy file
+// decides as intended. It gives the previous block's header
+// and either a block or the transactions of one, all
+// hex-encoded as in the API, and whether the policy should
+// allow signing the block.
+//
+// A file of policy tests is a JSON array, such as
+//
+//	[
+//		{
+//			"name": "too many transactions",
+//			"prev": "<block header>",
+//			"timestamp_ms": 1500,
+//			"transactions": ["<tx>", "<tx>"],
+//			"allow": false
+//		}
+//	]
+type PolicyTest struct {
+	Name string              `json:"name"`
+	Prev *legacy.BlockHeader `json:"prev"`
+
+	// Block is the block to check. If it is nil, the block
+	// checked follows Prev, with timestamp TimestampMS and
+	// the given transactions.
+	Block        *legacy.Block `json:"block"`
+	TimestampMS  uint64        `json:"timestamp_ms"`
+	Transactions []*legacy.Tx  `json:"transactions"`
+
+	Allow bool `json:"allow"`
+}
+
+// LoadPolicyTests reads and parses the policy tests at path.
+func LoadPolicyTests(path string) ([]*PolicyTest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading policy tests")
+	}
+	var tests []*PolicyTest
+	err = json.Unmarshal(b, &tests)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing policy tests %s", path)
+	}
+	for i, t := range tests {
+		if t.Prev == nil {
+			return nil, fmt.Errorf("policy test %d (%s): no previous block header", i, t.Name)
+		}
+	}
+	return tests, nil
+}
+
+// RunTests checks each of tests against the policy. It returns
+// an error for each test the policy decides differently than
+// the test expects, saying why, or nil if it passes.
+func (p *Policy) RunTests(tests []*PolicyTest) []error {
+	errs := make([]error, len(tests))
+	for i, t := range tests {
+		b := t.Block
+		if b == nil {
+			b = &legacy.Block{
+				BlockHeader: legacy.BlockHeader{
+					Height:            t.Prev.Height + 1,
+					PreviousBlockHash: t.Prev.Hash(),
+					TimestampMS:       t.TimestampMS,
+				},
+				Transactions: t.Transactions,
+			}
+		}
+		err := p.Check(&legacy.Block{BlockHeader: *t.Prev}, b)
+		switch {
+		case err != nil && errors.Root(err) != ErrPolicy:
+			errs[i] = errors.Wrapf(err, "policy test %d (%s)", i, t.Name)
+		case t.Allow && err != nil:
+			errs[i] = fmt.Errorf("policy test %d (%s): rejected a block it should allow: %s", i, t.Name, errors.Detail(err))
+		case !t.Allow && err == nil:
+			errs[i] = fmt.Errorf("policy test %d (%s): allowed a block it should reject", i, t.Name)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+// canaryTimeout bounds each canary transaction,
+// from building it to its being indexed.
+const canaryTimeout = time.Minute
+
+// canaryStages are the stages of a canary transaction,
+// in order, each of whose latency the canary reports.
+var canaryStages = []string{"build", "sign", "submit", "confirm", "index"}
+
+// canary periodically issues and retires one unit of a test
+// asset, taking it through the same stages as a client's
+// transaction: building, signing, submitting, confirmation
+// in a block, and processing by the Core's block processors,
+// including the query indexer. Its success and latency are an
+// end-to-end health signal that no individual component's
+// health can give.
+//
+// The test asset is defined by the Core itself, with a key kept
+// in the database, so it shows up in asset queries, tagged
+// "chain_canary".
+type canary struct {
+	period time.Duration
+
+	// key and assetID are set by the first successful
+	// call to canaryAsset, and used only by runCanary.
+	key     *chainkd.XPrv
+	assetID bc.AssetID
+
+	mu    sync.Mutex
+	stats canaryStats
+}
+
+type canaryStats struct {
+	Runs     uint64 `json:"runs"`
+	Failures uint64 `json:"failures"`
+
+	// LastTxID is the ID of the last canary transaction to
+	// succeed, and LatencyMS the time taken by each of its
+	// stages, in milliseconds.
+	LastTxID  *bc.Hash          `json:"last_transaction_id,omitempty"`
+	LatencyMS map[string]uint64 `json:"latency_ms,omitempty"`
+
+	// LastError is the error from the last
+	// run, if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Canary configures the Core to run a canary transaction every
+// period while it is the leader, issuing and retiring one unit
+// of a test asset to measure the latency of each stage of a
+// transaction's life. The results are reported by /health,
+// under the "canary" key, and /metrics.
+func Canary(period time.Duration) RunOption {
+	return func(a *API) { a.canary = &canary{period: period} }
+}
+
+// runCanary runs canary transactions until ctx is canceled.
+// It must run only on the leader.
+func (a *API) runCanary(ctx context.Context) {
+	setHealth := a.healthSetter("canary")
+	ticker := time.NewTicker(a.canary.period)
+	defer ticker.Stop()
+	for {
+		txID, latency, err := a.canaryTx(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error(ctx, err, "canary transaction")
+		}
+		setHealth(err)
+		a.canary.record(txID, latency, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *canary) record(txID bc.Hash, latency map[string]time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Runs++
+	if err != nil {
+		c.stats.Failures++
+		c.stats.LastError = err.Error()
+		return
+	}
+	c.stats.LastError = ""
+	c.stats.LastTxID = &txID
+	c.stats.LatencyMS = make(map[string]uint64, len(latency))
+	for stage, d := range latency {
+		c.stats.LatencyMS[stage] = uint64(d / time.Millisecond)
+	}
+}
+
+// snapshot returns a copy of the canary's statistics.
+func (c *canary) snapshot() canaryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.LatencyMS = make(map[string]uint64, len(c.stats.LatencyMS))
+	for stage, ms := range c.stats.LatencyMS {
+		stats.LatencyMS[stage] = ms
+	}
+	return stats
+}
+
+// collectors returns the canary's Prometheus metrics.
+func (c *canary) collectors() []prometheus.Collector {
+	stat := func(f func(canaryStats) float64) func() float64 {
+		return func() float64 { return f(c.snapshot()) }
+	}
+	collectors := []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_canary_runs_total",
+			Help: "Canary transactions attempted.",
+		}, stat(func(s canaryStats) float64 { return float64(s.Runs) })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_canary_failures_total",
+			Help: "Canary transactions that failed or timed out.",
+		}, stat(func(s canaryStats) float64 { return float64(s.Failures) })),
+	}
+	for _, stage := range canaryStages {
+		stage := stage
+		collectors = append(collectors, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "chain_canary_latency_seconds",
+			Help:        "Latency of each stage of the last successful canary transaction.",
+			ConstLabels: prometheus.Labels{"stage": stage},
+		}, stat(func(s canaryStats) float64 { return float64(s.LatencyMS[stage]) / 1000 })))
+	}
+	return collectors
+}
+
+// canaryTx issues and retires one unit of the canary asset,
+// returning the transaction's ID and the latency of each stage.
+func (a *API) canaryTx(ctx context.Context) (bc.Hash, map[string]time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, canaryTimeout)
+	defer cancel()
+
+	xprv, assetID, err := a.canaryAsset(ctx)
+	if err != nil {
+		return bc.Hash{}, nil, err
+	}
+
+	latency := make(map[string]time.Duration)
+	start := time.Now()
+	stage := func(name string) {
+		now := time.Now()
+		latency[name] = now.Sub(start)
+		start = now
+	}
+
+	amount := bc.AssetAmount{AssetId: &assetID, Amount: 1}
+	retire, err := json.Marshal(amount)
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrap(err)
+	}
+	retireAction, err := txbuilder.DecodeRetireAction(retire)
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrap(err)
+	}
+	tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{
+		a.assets.NewIssueAction(amount, nil),
+		retireAction,
+	}, time.Now().Add(canaryTimeout))
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrap(err, "building canary transaction")
+	}
+	initialBlockID := a.chain.InitialBlockHash
+	tpl.InitialBlockID = &initialBlockID
+	stage("build")
+
+	err = txbuilder.Sign(ctx, tpl, []chainkd.XPub{xprv.XPub()}, func(_ context.Context, _ chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+		return xprv.Derive(path).Sign(data[:]), nil
+	})
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrap(err, "signing canary transaction")
+	}
+	stage("sign")
+
+	tx := tpl.Transaction
+	height := a.chain.Height()
+	err = txbuilder.FinalizeTx(ctx, a.chain, a.submitter, tx)
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrapf(generatorErr(err), "submitting canary transaction %x", tx.ID.Bytes())
+	}
+	stage("submit")
+
+	height, err = a.waitForTxInBlock(ctx, tx, height)
+	if err != nil {
+		return bc.Hash{}, nil, errors.Wrapf(err, "waiting for canary transaction %x", tx.ID.Bytes())
+	}
+	stage("confirm")
+
+	select {
+	case <-ctx.Done():
+		return bc.Hash{}, nil, errors.Wrapf(ctx.Err(), "waiting for block %d to be processed", height)
+	case <-a.pinStore.AllWaiter(height):
+	}
+	stage("index")
+
+	return tx.ID, latency, nil
+}
+
+// canaryAsset returns the canary's key, creating it if need
+// be, and the ID of the canary asset, defining it if need be.
+func (a *API) canaryAsset(ctx context.Context) (chainkd.XPrv, bc.AssetID, error) {
+	if a.canary.key != nil {
+		return *a.canary.key, a.canary.assetID, nil
+	}
+
+	var xprv chainkd.XPrv
+	b, err := loadCanaryKey(ctx, a.db)
+	if err != nil {
+		return xprv, bc.AssetID{}, err
+	}
+	copy(xprv[:], b)
+
+	def := map[string]interface{}{"name": "Chain Core canary"}
+	tags := map[string]interface{}{"chain_canary": true}
+	asset, err := a.assets.Define(ctx, []chainkd.XPub{xprv.XPub()}, 1, def, "", tags, "chain-core-canary")
+	if err != nil {
+		return xprv, bc.AssetID{}, errors.Wrap(err, "defining canary asset")
+	}
+	a.canary.key, a.canary.assetID = &xprv, asset.AssetID
+	return xprv, asset.AssetID, nil
+}
+
+// loadCanaryKey returns the canary's private key,
+// generating and storing one if there is none.
+func loadCanaryKey(ctx context.Context, db pg.DB) ([]byte, error) {
+	xprv, err := chainkd.NewXPrv(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating canary key")
+	}
+	const insertQ = `INSERT INTO canary_key (xprv) VALUES ($1) ON CONFLICT (singleton) DO NOTHING`
+	_, err = db.ExecContext(ctx, insertQ, xprv.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "saving canary key")
+	}
+
+	var b []byte
+	err = db.QueryRowContext(ctx, `SELECT xprv FROM canary_key`).Scan(&b)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading canary key")
+	}
+	if len(b) != len(xprv) {
+		return nil, errors.New("invalid canary key")
+	}
+	return b, nil
+}
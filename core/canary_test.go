@@ -0,0 +1,43 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"chain/protocol/bc"
+)
+
+func TestCanaryRecord(t *testing.T) {
+	c := new(canary)
+	txID := bc.NewHash([32]byte{1})
+	c.record(txID, map[string]time.Duration{"build": 5 * time.Millisecond, "confirm": time.Second}, nil)
+	c.record(bc.Hash{}, nil, errors.New("timed out"))
+
+	got := c.snapshot()
+	if got.Runs != 2 || got.Failures != 1 {
+		t.Errorf("runs, failures = %d, %d, want 2, 1", got.Runs, got.Failures)
+	}
+	if got.LastError != "timed out" {
+		t.Errorf("last error = %q, want %q", got.LastError, "timed out")
+	}
+
+	// A failure leaves the last successful
+	// transaction's latencies in place.
+	if got.LastTxID == nil || *got.LastTxID != txID {
+		t.Errorf("last tx ID = %v, want %x", got.LastTxID, txID.Bytes())
+	}
+	if got.LatencyMS["build"] != 5 || got.LatencyMS["confirm"] != 1000 {
+		t.Errorf("latencies = %v, want build 5, confirm 1000", got.LatencyMS)
+	}
+
+	got.LatencyMS["build"] = 0
+	if c.snapshot().LatencyMS["build"] != 5 {
+		t.Error("snapshot shares latencies with the canary")
+	}
+
+	c.record(txID, map[string]time.Duration{"build": time.Millisecond}, nil)
+	if got := c.snapshot(); got.LastError != "" {
+		t.Errorf("last error after success = %q, want none", got.LastError)
+	}
+}
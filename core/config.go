@@ -43,6 +43,10 @@ func Config(ctx context.Context, db pg.DB, sdb *sinkdb.DB) (*config.Options, err
 	// the URL, not the access token.
 	opts.DefineSet("enclave", 2, cleanEnclaveTuple, equalFirst)
 
+	// fence holds the time this Core was fenced and the reason.
+	// See fenceOption.
+	opts.DefineSingle(fenceOption, 2, cleanFenceTuple)
+
 	// migrate any old-style existing configuration options
 	monolith, err := config.Load(ctx, db, sdb)
 	if errors.Root(err) == raft.ErrUninitialized {
@@ -6,6 +6,7 @@ package config
 //go:generate protoc -I. -I$CHAIN/.. --go_out=. config.proto
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"database/sql"
@@ -27,7 +28,9 @@ import (
 	"chain/net/http/authz"
 	"chain/protocol"
 	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 )
 
 const (
@@ -43,6 +46,8 @@ var (
 	ErrNoBlockPub      = errors.New("blockpub cannot be empty in mockhsm disabled build")
 	ErrNoBlockHSMURL   = errors.New("block hsm URL cannot be empty in mockhsm disabled build")
 	ErrStaleRaftConfig = errors.New("raft core ID doesn't match Postgres core ID")
+	ErrBadCostTable    = errors.New("invalid opcode cost table")
+	ErrBadInitialBlock = errors.New("initial block doesn't match the block signers and quorum")
 
 	Version, BuildCommit, BuildDate string
 
@@ -203,11 +208,25 @@ func CheckConfigExists(ctx context.Context, sdb *sinkdb.DB) (*Config, error) {
 // for signing blocks, and assigns it to c.BlockPub.
 //
 // If c.IsGenerator is true, Configure creates an initial block,
-// saves it, and assigns its hash to c.BlockchainId
+// saves it, and assigns its hash to c.BlockchainId. If initialBlock
+// is not nil, for instance one agreed on in a genesis ceremony, it
+// must be the initial block Configure would create at the same
+// timestamp from the block signing keys and c.Quorum.
 // Otherwise, c.IsGenerator is false, and Configure makes a test request
 // to GeneratorUrl to detect simple configuration mistakes.
-func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.Client, c *Config) error {
-	var err error
+//
+// Configure records the hash of the opcode cost table described by
+// c.CostTableVersion and c.OpcodeCosts in c.CostTableHash. Every core
+// on a blockchain must be configured with the same cost table, so
+// the test request fails if the generator reports a different hash.
+func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.Client, c *Config, initialBlock *legacy.Block) error {
+	costs, err := costTable(c)
+	if err != nil {
+		return err
+	}
+	costsHash := costs.Hash()
+	c.CostTableHash = costsHash[:]
+
 	if !c.IsGenerator {
 		blockchainID, err := c.BlockchainId.MarshalText()
 		err = tryGenerator(
@@ -215,6 +234,7 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 			c.GeneratorUrl,
 			c.GeneratorAccessToken,
 			string(blockchainID),
+			hex.EncodeToString(c.CostTableHash),
 			httpClient,
 		)
 		if err != nil {
@@ -263,10 +283,19 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 			return errors.Wrap(ErrBadQuorum)
 		}
 
-		block, err := protocol.NewInitialBlock(signingKeys, int(c.Quorum), time.Now())
+		ts := time.Now()
+		if initialBlock != nil {
+			// The supplied block must be exactly the one we would
+			// build from the signers and quorum at its timestamp.
+			ts = time.Unix(0, int64(initialBlock.TimestampMS)*int64(time.Millisecond))
+		}
+		block, err := protocol.NewInitialBlock(signingKeys, int(c.Quorum), ts)
 		if err != nil {
 			return err
 		}
+		if initialBlock != nil && initialBlock.Hash() != block.Hash() {
+			return errors.Wrap(ErrBadInitialBlock)
+		}
 
 		initialBlockHash := block.Hash()
 
@@ -283,6 +312,7 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 
 		c.BlockchainId = &initialBlockHash
 		chain.MaxIssuanceWindow = bc.MillisDuration(c.MaxIssuanceWindowMs)
+		chain.Costs = costs
 	}
 
 	b := make([]byte, 10)
@@ -307,12 +337,92 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 	)
 }
 
-func tryGenerator(ctx context.Context, url, accessToken, blockchainID string, httpClient *http.Client) error {
+// Promote reconfigures a Core that replicates blocks from a
+// generator to generate blocks itself, using the given block
+// signers and quorum. The caller is responsible for checking
+// that they match the blockchain's consensus program, and for
+// restarting the Core to load the new configuration.
+func Promote(ctx context.Context, sdb *sinkdb.DB, signers []*BlockSigner, quorum uint32, maxIssuanceWindowMs uint64) error {
+	for _, signer := range signers {
+		_, err := url.Parse(signer.Url)
+		if err != nil {
+			return errors.Sub(ErrBadSignerURL, err)
+		}
+	}
+
+	c := new(Config)
+	ver, err := sdb.Get(ctx, "/core/config", c)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if !ver.Exists() || c.IsGenerator {
+		return errors.Wrap(sinkdb.ErrConflict)
+	}
+
+	c.IsGenerator = true
+	c.GeneratorUrl = ""
+	c.GeneratorAccessToken = ""
+	c.Signers = signers
+	c.Quorum = quorum
+	c.MaxIssuanceWindowMs = maxIssuanceWindowMs
+	return sdb.Exec(ctx,
+		sinkdb.IfNotModified(ver),
+		sinkdb.Set("/core/config", c),
+	)
+}
+
+// CostTable returns the opcode cost table in c, checking
+// that it matches the hash recorded when c was configured.
+// Cores configured before cost tables existed use the
+// default table.
+func CostTable(c *Config) (*vm.CostTable, error) {
+	costs, err := costTable(c)
+	if err != nil {
+		return nil, err
+	}
+	if costs == nil && len(c.CostTableHash) == 0 {
+		return nil, nil
+	}
+	h := costs.Hash()
+	if !bytes.Equal(h[:], c.CostTableHash) {
+		return nil, errors.WithDetail(ErrBadCostTable, "cost table does not match the hash recorded at configuration")
+	}
+	return costs, nil
+}
+
+// costTable builds an opcode cost table from c. It returns
+// nil if c uses the default table.
+func costTable(c *Config) (*vm.CostTable, error) {
+	if c.CostTableVersion == 0 && len(c.OpcodeCosts) == 0 {
+		return nil, nil
+	}
+	if c.CostTableVersion == 0 {
+		return nil, errors.WithDetail(ErrBadCostTable, "cost table version is required when opcode costs are given")
+	}
+	costs := &vm.CostTable{
+		Version: c.CostTableVersion,
+		Costs:   make(map[vm.Op]int64, len(c.OpcodeCosts)),
+	}
+	for op, cost := range c.OpcodeCosts {
+		if op > 0xff {
+			return nil, errors.WithDetailf(ErrBadCostTable, "invalid opcode %d", op)
+		}
+		costs.Costs[vm.Op(op)] = cost
+	}
+	err := costs.Validate()
+	if err != nil {
+		return nil, errors.Sub(ErrBadCostTable, err)
+	}
+	return costs, nil
+}
+
+func tryGenerator(ctx context.Context, url, accessToken, blockchainID, costTableHash string, httpClient *http.Client) error {
 	client := &rpc.Client{
-		BaseURL:      url,
-		AccessToken:  accessToken,
-		BlockchainID: blockchainID,
-		Client:       httpClient,
+		BaseURL:       url,
+		AccessToken:   accessToken,
+		BlockchainID:  blockchainID,
+		CostTableHash: costTableHash,
+		Client:        httpClient,
 	}
 	var x struct {
 		BlockHeight uint64 `json:"block_height"`
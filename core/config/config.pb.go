@@ -31,19 +31,22 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Config struct {
-	Id                   string         `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	IsSigner             bool           `protobuf:"varint,2,opt,name=is_signer,json=isSigner" json:"is_signer,omitempty"`
-	IsGenerator          bool           `protobuf:"varint,3,opt,name=is_generator,json=isGenerator" json:"is_generator,omitempty"`
-	BlockchainId         *bc.Hash       `protobuf:"bytes,4,opt,name=blockchain_id,json=blockchainId" json:"blockchain_id,omitempty"`
-	GeneratorUrl         string         `protobuf:"bytes,5,opt,name=generator_url,json=generatorUrl" json:"generator_url,omitempty"`
-	GeneratorAccessToken string         `protobuf:"bytes,6,opt,name=generator_access_token,json=generatorAccessToken" json:"generator_access_token,omitempty"`
-	BlockHsmUrl          string         `protobuf:"bytes,7,opt,name=block_hsm_url,json=blockHsmUrl" json:"block_hsm_url,omitempty"`
-	BlockHsmAccessToken  string         `protobuf:"bytes,8,opt,name=block_hsm_access_token,json=blockHsmAccessToken" json:"block_hsm_access_token,omitempty"`
-	ConfiguredAt         uint64         `protobuf:"varint,9,opt,name=configured_at,json=configuredAt" json:"configured_at,omitempty"`
-	BlockPub             []byte         `protobuf:"bytes,10,opt,name=block_pub,json=blockPub,proto3" json:"block_pub,omitempty"`
-	Signers              []*BlockSigner `protobuf:"bytes,11,rep,name=signers" json:"signers,omitempty"`
-	Quorum               uint32         `protobuf:"varint,12,opt,name=quorum" json:"quorum,omitempty"`
-	MaxIssuanceWindowMs  uint64         `protobuf:"varint,13,opt,name=max_issuance_window_ms,json=maxIssuanceWindowMs" json:"max_issuance_window_ms,omitempty"`
+	Id                   string           `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	IsSigner             bool             `protobuf:"varint,2,opt,name=is_signer,json=isSigner" json:"is_signer,omitempty"`
+	IsGenerator          bool             `protobuf:"varint,3,opt,name=is_generator,json=isGenerator" json:"is_generator,omitempty"`
+	BlockchainId         *bc.Hash         `protobuf:"bytes,4,opt,name=blockchain_id,json=blockchainId" json:"blockchain_id,omitempty"`
+	GeneratorUrl         string           `protobuf:"bytes,5,opt,name=generator_url,json=generatorUrl" json:"generator_url,omitempty"`
+	GeneratorAccessToken string           `protobuf:"bytes,6,opt,name=generator_access_token,json=generatorAccessToken" json:"generator_access_token,omitempty"`
+	BlockHsmUrl          string           `protobuf:"bytes,7,opt,name=block_hsm_url,json=blockHsmUrl" json:"block_hsm_url,omitempty"`
+	BlockHsmAccessToken  string           `protobuf:"bytes,8,opt,name=block_hsm_access_token,json=blockHsmAccessToken" json:"block_hsm_access_token,omitempty"`
+	ConfiguredAt         uint64           `protobuf:"varint,9,opt,name=configured_at,json=configuredAt" json:"configured_at,omitempty"`
+	BlockPub             []byte           `protobuf:"bytes,10,opt,name=block_pub,json=blockPub,proto3" json:"block_pub,omitempty"`
+	Signers              []*BlockSigner   `protobuf:"bytes,11,rep,name=signers" json:"signers,omitempty"`
+	Quorum               uint32           `protobuf:"varint,12,opt,name=quorum" json:"quorum,omitempty"`
+	MaxIssuanceWindowMs  uint64           `protobuf:"varint,13,opt,name=max_issuance_window_ms,json=maxIssuanceWindowMs" json:"max_issuance_window_ms,omitempty"`
+	CostTableVersion     uint64           `protobuf:"varint,14,opt,name=cost_table_version,json=costTableVersion" json:"cost_table_version,omitempty"`
+	OpcodeCosts          map[uint32]int64 `protobuf:"bytes,15,rep,name=opcode_costs,json=opcodeCosts" json:"opcode_costs,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	CostTableHash        []byte           `protobuf:"bytes,16,opt,name=cost_table_hash,json=costTableHash,proto3" json:"cost_table_hash,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -142,6 +145,27 @@ func (m *Config) GetMaxIssuanceWindowMs() uint64 {
 	return 0
 }
 
+func (m *Config) GetCostTableVersion() uint64 {
+	if m != nil {
+		return m.CostTableVersion
+	}
+	return 0
+}
+
+func (m *Config) GetOpcodeCosts() map[uint32]int64 {
+	if m != nil {
+		return m.OpcodeCosts
+	}
+	return nil
+}
+
+func (m *Config) GetCostTableHash() []byte {
+	if m != nil {
+		return m.CostTableHash
+	}
+	return nil
+}
+
 type BlockSigner struct {
 	AccessToken string `protobuf:"bytes,1,opt,name=access_token,json=accessToken" json:"access_token,omitempty"`
 	Pubkey      []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
@@ -182,31 +206,38 @@ func init() {
 func init() { proto.RegisterFile("config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 414 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x52, 0x41, 0x6b, 0xdb, 0x30,
-	0x14, 0xc6, 0x49, 0xe6, 0x3a, 0xcf, 0xf6, 0x18, 0xca, 0x30, 0xa2, 0xbb, 0x78, 0x2d, 0x0c, 0x5f,
-	0x9a, 0x40, 0x3b, 0xd8, 0xb9, 0xdd, 0x61, 0xed, 0x61, 0x30, 0xb4, 0x8d, 0xc1, 0x2e, 0x42, 0x96,
-	0xbd, 0x44, 0x24, 0xb6, 0x32, 0xc9, 0xa2, 0xdd, 0x71, 0xff, 0xbc, 0xe8, 0xd9, 0x89, 0xd3, 0x9b,
-	0xdf, 0xf7, 0x7d, 0xef, 0x7b, 0xcf, 0x9f, 0x1e, 0x24, 0x52, 0xb7, 0x7f, 0xd4, 0x7a, 0xb9, 0x37,
-	0xba, 0xd3, 0x24, 0xec, 0xab, 0xf3, 0x73, 0xb9, 0x11, 0xaa, 0x5d, 0x21, 0x28, 0xf5, 0x6e, 0x55,
-	0xca, 0x55, 0x29, 0x7b, 0xcd, 0xc5, 0xff, 0x19, 0x84, 0x9f, 0x51, 0x46, 0x5e, 0xc3, 0x44, 0x55,
-	0x34, 0xc8, 0x83, 0x62, 0xce, 0x26, 0xaa, 0x22, 0xef, 0x60, 0xae, 0x2c, 0xb7, 0x6a, 0xdd, 0xd6,
-	0x86, 0x4e, 0xf2, 0xa0, 0x88, 0x58, 0xa4, 0xec, 0x77, 0xac, 0xc9, 0x7b, 0x48, 0x94, 0xe5, 0xeb,
-	0xba, 0xad, 0x8d, 0xe8, 0xb4, 0xa1, 0x53, 0xe4, 0x63, 0x65, 0xbf, 0x1c, 0x20, 0x72, 0x05, 0x69,
-	0xb9, 0xd3, 0x72, 0x8b, 0xd3, 0xb9, 0xaa, 0xe8, 0x2c, 0x0f, 0x8a, 0xf8, 0x3a, 0x5a, 0x96, 0x72,
-	0x79, 0x2f, 0xec, 0x86, 0x25, 0x23, 0xfd, 0x50, 0x91, 0x4b, 0x48, 0x8f, 0x76, 0xdc, 0x99, 0x1d,
-	0x7d, 0x85, 0x9b, 0x24, 0x47, 0xf0, 0xa7, 0xd9, 0x91, 0x8f, 0x90, 0x8d, 0x22, 0x21, 0x65, 0x6d,
-	0x2d, 0xef, 0xf4, 0xb6, 0x6e, 0x69, 0x88, 0xea, 0xb7, 0x47, 0xf6, 0x16, 0xc9, 0x1f, 0x9e, 0x23,
-	0x1f, 0x86, 0x4d, 0xf8, 0xc6, 0x36, 0x68, 0x7d, 0xe6, 0xc5, 0x77, 0x13, 0x1a, 0xb0, 0x18, 0x89,
-	0x7b, 0xdb, 0x78, 0xf7, 0x4f, 0x90, 0x8d, 0xba, 0x17, 0xee, 0xd1, 0xb1, 0x61, 0x71, 0x68, 0x38,
-	0x1d, 0x70, 0x09, 0x69, 0x9f, 0xb5, 0x33, 0x75, 0xc5, 0x45, 0x47, 0xe7, 0x79, 0x50, 0xcc, 0x58,
-	0x32, 0x82, 0xb7, 0x9d, 0xcf, 0xb3, 0x77, 0xdf, 0xbb, 0x92, 0x42, 0x1e, 0x14, 0x09, 0x8b, 0x10,
-	0xf8, 0xe6, 0x4a, 0x72, 0x05, 0x67, 0x7d, 0xd2, 0x96, 0xc6, 0xf9, 0xb4, 0x88, 0xaf, 0x17, 0xcb,
-	0xe1, 0x2d, 0xef, 0xbc, 0xa4, 0x4f, 0x9d, 0x1d, 0x34, 0x24, 0x83, 0xf0, 0xaf, 0xd3, 0xc6, 0x35,
-	0x34, 0xc9, 0x83, 0x22, 0x65, 0x43, 0x45, 0x6e, 0x20, 0x6b, 0xc4, 0x13, 0x57, 0xd6, 0x3a, 0xd1,
-	0xca, 0x9a, 0x3f, 0xaa, 0xb6, 0xd2, 0x8f, 0xbc, 0xb1, 0x34, 0xc5, 0x8d, 0x16, 0x8d, 0x78, 0x7a,
-	0x18, 0xc8, 0x5f, 0xc8, 0x7d, 0xb5, 0x17, 0xbf, 0x21, 0x3e, 0x19, 0xe2, 0x9f, 0xf6, 0xc5, 0xbf,
-	0xf7, 0x17, 0x11, 0x8b, 0x93, 0xff, 0xcd, 0x20, 0xdc, 0xbb, 0x72, 0x5b, 0xff, 0xc3, 0xbb, 0x48,
-	0xd8, 0x50, 0x91, 0x37, 0x30, 0xf5, 0xf1, 0x4e, 0xb1, 0xc3, 0x7f, 0x96, 0x21, 0x9e, 0xd9, 0xcd,
-	0x73, 0x00, 0x00, 0x00, 0xff, 0xff, 0x25, 0xf4, 0xd5, 0xaf, 0x9a, 0x02, 0x00, 0x00,
+	// 520 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x53, 0x5d, 0x6b, 0xdb, 0x30,
+	0x14, 0xc5, 0x4e, 0xeb, 0x26, 0xd7, 0x76, 0x1b, 0x94, 0x12, 0x44, 0xf6, 0x30, 0xaf, 0x85, 0xe2,
+	0x87, 0x35, 0x81, 0x76, 0xb0, 0xb1, 0x87, 0x41, 0x53, 0xc6, 0xda, 0x87, 0xb1, 0xe1, 0x75, 0x1b,
+	0xec, 0x45, 0xc8, 0xb2, 0x96, 0x88, 0xd8, 0x56, 0x66, 0xd9, 0xfd, 0xf8, 0xad, 0xfb, 0x33, 0x43,
+	0xd7, 0xf9, 0xea, 0xde, 0x7c, 0xcf, 0x39, 0xf7, 0x83, 0xa3, 0x63, 0x08, 0x84, 0x2e, 0x7f, 0xab,
+	0xd9, 0x78, 0x59, 0xe9, 0x5a, 0x13, 0xaf, 0xad, 0x46, 0x23, 0x31, 0xe7, 0xaa, 0x9c, 0x20, 0x28,
+	0x74, 0x3e, 0x49, 0xc5, 0x24, 0x15, 0xad, 0xe6, 0xe4, 0xef, 0x3e, 0x78, 0xd7, 0x28, 0x23, 0x87,
+	0xe0, 0xaa, 0x8c, 0x3a, 0x91, 0x13, 0xf7, 0x12, 0x57, 0x65, 0xe4, 0x05, 0xf4, 0x94, 0x61, 0x46,
+	0xcd, 0x4a, 0x59, 0x51, 0x37, 0x72, 0xe2, 0x6e, 0xd2, 0x55, 0xe6, 0x1b, 0xd6, 0xe4, 0x15, 0x04,
+	0xca, 0xb0, 0x99, 0x2c, 0x65, 0xc5, 0x6b, 0x5d, 0xd1, 0x0e, 0xf2, 0xbe, 0x32, 0x9f, 0xd6, 0x10,
+	0x39, 0x87, 0x30, 0xcd, 0xb5, 0x58, 0xe0, 0x76, 0xa6, 0x32, 0xba, 0x17, 0x39, 0xb1, 0x7f, 0xd1,
+	0x1d, 0xa7, 0x62, 0x7c, 0xc3, 0xcd, 0x3c, 0x09, 0xb6, 0xf4, 0x6d, 0x46, 0x4e, 0x21, 0xdc, 0x8c,
+	0x63, 0x4d, 0x95, 0xd3, 0x7d, 0xbc, 0x24, 0xd8, 0x80, 0xdf, 0xab, 0x9c, 0xbc, 0x81, 0xe1, 0x56,
+	0xc4, 0x85, 0x90, 0xc6, 0xb0, 0x5a, 0x2f, 0x64, 0x49, 0x3d, 0x54, 0x1f, 0x6f, 0xd8, 0x2b, 0x24,
+	0xef, 0x2c, 0x47, 0xce, 0x56, 0x97, 0xb0, 0xb9, 0x29, 0x70, 0xf4, 0x81, 0x15, 0x4f, 0x5d, 0xea,
+	0x24, 0x3e, 0x12, 0x37, 0xa6, 0xb0, 0xd3, 0xdf, 0xc2, 0x70, 0xab, 0x7b, 0x36, 0xbd, 0xbb, 0x69,
+	0x18, 0xac, 0x1b, 0x76, 0x17, 0x9c, 0x42, 0xd8, 0x7a, 0xdd, 0x54, 0x32, 0x63, 0xbc, 0xa6, 0xbd,
+	0xc8, 0x89, 0xf7, 0x92, 0x60, 0x0b, 0x5e, 0xd5, 0xd6, 0xcf, 0x76, 0xfa, 0xb2, 0x49, 0x29, 0x44,
+	0x4e, 0x1c, 0x24, 0x5d, 0x04, 0xbe, 0x36, 0x29, 0x39, 0x87, 0x83, 0xd6, 0x69, 0x43, 0xfd, 0xa8,
+	0x13, 0xfb, 0x17, 0x83, 0xf1, 0xea, 0x2d, 0xa7, 0x56, 0xd2, 0xba, 0x9e, 0xac, 0x35, 0x64, 0x08,
+	0xde, 0x9f, 0x46, 0x57, 0x4d, 0x41, 0x83, 0xc8, 0x89, 0xc3, 0x64, 0x55, 0x91, 0x4b, 0x18, 0x16,
+	0xfc, 0x91, 0x29, 0x63, 0x1a, 0x5e, 0x0a, 0xc9, 0x1e, 0x54, 0x99, 0xe9, 0x07, 0x56, 0x18, 0x1a,
+	0xe2, 0x45, 0x83, 0x82, 0x3f, 0xde, 0xae, 0xc8, 0x9f, 0xc8, 0x7d, 0x36, 0xe4, 0x35, 0x10, 0xa1,
+	0x4d, 0xcd, 0x6a, 0x9e, 0xe6, 0x92, 0xdd, 0xcb, 0xca, 0x28, 0x5d, 0xd2, 0x43, 0x6c, 0xe8, 0x5b,
+	0xe6, 0xce, 0x12, 0x3f, 0x5a, 0x9c, 0x4c, 0x21, 0xd0, 0x4b, 0xa1, 0x33, 0xc9, 0x2c, 0x65, 0xe8,
+	0x11, 0x9e, 0xfb, 0x72, 0x7d, 0x6e, 0x1b, 0xa6, 0xf1, 0x17, 0x94, 0x5c, 0x5b, 0xc5, 0xc7, 0xb2,
+	0xae, 0x9e, 0x12, 0x5f, 0x6f, 0x11, 0x72, 0x06, 0x47, 0x3b, 0x1b, 0xe7, 0xdc, 0xcc, 0x69, 0x1f,
+	0x0d, 0x09, 0x37, 0xeb, 0x6c, 0x42, 0x46, 0x1f, 0xa0, 0xff, 0xff, 0x20, 0xd2, 0x87, 0xce, 0x42,
+	0x3e, 0x61, 0x4e, 0xc3, 0xc4, 0x7e, 0x92, 0x63, 0xd8, 0xbf, 0xe7, 0x79, 0x23, 0x31, 0xa4, 0x9d,
+	0xa4, 0x2d, 0xde, 0xbb, 0xef, 0x9c, 0x93, 0x5f, 0xe0, 0xef, 0xd8, 0x67, 0x43, 0xfb, 0xec, 0x55,
+	0xdb, 0xac, 0xfb, 0x7c, 0xe7, 0x25, 0x87, 0xe0, 0x2d, 0x9b, 0xd4, 0x2e, 0x70, 0xf1, 0xa0, 0x55,
+	0x65, 0xb7, 0xda, 0xe0, 0x74, 0xb0, 0xc3, 0x7e, 0xa6, 0x1e, 0xfe, 0x40, 0x97, 0xff, 0x06, 0x00,
+	0x24, 0x2f, 0x03, 0xf6, 0x74, 0x03, 0x00, 0x00,
 }
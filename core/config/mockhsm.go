@@ -11,8 +11,16 @@ import (
 	"chain/log"
 )
 
+// DevHSM is the HSM that holds the block-signing key of a
+// development Core. If it is nil, the key is kept in the
+// Core's database.
+var DevHSM *mockhsm.HSM
+
 func getOrCreateDevKey(ctx context.Context, db pg.DB, c *Config) (blockPub ed25519.PublicKey, err error) {
-	hsm := mockhsm.New(db)
+	hsm := DevHSM
+	if hsm == nil {
+		hsm = mockhsm.New(db)
+	}
 	corePub, created, err := hsm.GetOrCreate(ctx, autoBlockKeyAlias)
 	if err != nil {
 		return nil, err
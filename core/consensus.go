@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"context"
+
+	"chain/crypto/ed25519"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/vm/vmutil"
+)
+
+// defaultSignerScanDepth is the number of recent blocks searched
+// for each key's most recent signature, if the request doesn't
+// say. A request may search at most maxSignerScanDepth blocks.
+const (
+	defaultSignerScanDepth = 10
+	maxSignerScanDepth     = 100
+)
+
+type consensusKey struct {
+	Pubkey json.HexBytes `json:"pubkey"`
+
+	// SignerURL is the URL of the configured block signer
+	// holding this key, if there is one.
+	SignerURL string `json:"signer_url,omitempty"`
+
+	// LastSignedHeight is the height of the most recent block,
+	// among those searched, signed by this key.
+	// It is zero if no such block was found.
+	LastSignedHeight uint64 `json:"last_signed_height"`
+}
+
+// getConsensusProgram decodes the consensus program of the latest
+// block, the program that must be satisfied by the next block,
+// into its public keys and quorum, for monitoring the federation.
+// It searches the latest scan_depth blocks for each key's most
+// recent signature.
+func (a *API) getConsensusProgram(ctx context.Context, in struct {
+	ScanDepth uint64 `json:"scan_depth"`
+}) (x struct {
+	Program     json.HexBytes  `json:"program"`
+	Quorum      int            `json:"quorum"`
+	Keys        []consensusKey `json:"keys"`
+	BlockHeight uint64         `json:"block_height"`
+	ScanDepth   uint64         `json:"scan_depth"`
+}, err error) {
+	depth := in.ScanDepth
+	if depth == 0 {
+		depth = defaultSignerScanDepth
+	}
+	if depth > maxSignerScanDepth {
+		return x, errors.WithDetailf(httpjson.ErrBadRequest, "scan_depth may be at most %d", maxSignerScanDepth)
+	}
+
+	latest, _ := a.chain.State()
+	if latest == nil {
+		return x, errors.Wrap(errUnconfigured, "no blocks")
+	}
+
+	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(latest.ConsensusProgram)
+	if err != nil {
+		return x, errors.Wrap(err, "parsing consensus program")
+	}
+
+	x.Program = latest.ConsensusProgram
+	x.Quorum = quorum
+	x.BlockHeight = latest.Height
+	x.ScanDepth = depth
+	x.Keys = make([]consensusKey, len(pubkeys))
+	for i, pub := range pubkeys {
+		x.Keys[i].Pubkey = json.HexBytes(pub)
+		for _, signer := range a.config.Signers {
+			if bytes.Equal(signer.Pubkey, pub) {
+				x.Keys[i].SignerURL = signer.Url
+				break
+			}
+		}
+	}
+
+	// Search back through recent blocks for the most recent
+	// signature made by each key. The initial block is unsigned.
+	remaining := len(pubkeys)
+	for height := latest.Height; height > 1 && latest.Height-height < depth && remaining > 0; height-- {
+		b := latest
+		if height != latest.Height {
+			b, err = a.chain.GetBlock(ctx, height)
+			if err != nil {
+				return x, errors.Wrapf(err, "getting block %d", height)
+			}
+		}
+		hash := b.Hash()
+		for _, sig := range b.Witness {
+			for i, pub := range pubkeys {
+				if x.Keys[i].LastSignedHeight == 0 && ed25519.Verify(pub, hash.Bytes(), sig) {
+					x.Keys[i].LastSignedHeight = height
+					remaining--
+					break
+				}
+			}
+		}
+	}
+	return x, nil
+}
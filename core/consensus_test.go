@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/config"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestGetConsensusProgram(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t, prottest.WithBlockSigners(2, 3))
+	pubs, privs := prottest.BlockKeyPairs(c)
+
+	// Key 0 signs blocks 2 and 3, key 1 signs
+	// blocks 2 through 5, and key 2 signs none.
+	makeSignedBlock(t, c, privs[0], privs[1])
+	makeSignedBlock(t, c, privs[0], privs[1])
+	makeSignedBlock(t, c, privs[1])
+	makeSignedBlock(t, c, privs[1])
+
+	a := &API{chain: c, config: &config.Config{
+		Signers: []*config.BlockSigner{{Pubkey: pubs[1], Url: "https://signer.example.com"}},
+	}}
+
+	cases := []struct {
+		depth     uint64
+		wantDepth uint64
+		want      []uint64 // last signed height of each key
+	}{
+		{0, defaultSignerScanDepth, []uint64{3, 5, 0}},
+		{3, 3, []uint64{3, 5, 0}},
+		{2, 2, []uint64{0, 5, 0}},
+		{maxSignerScanDepth, maxSignerScanDepth, []uint64{3, 5, 0}},
+	}
+	for _, test := range cases {
+		got, err := a.getConsensusProgram(ctx, struct {
+			ScanDepth uint64 `json:"scan_depth"`
+		}{test.depth})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Quorum != 2 || got.BlockHeight != 5 || got.ScanDepth != test.wantDepth || len(got.Keys) != 3 {
+			t.Errorf("depth %d: got quorum %d, height %d, depth %d, %d keys, want 2, 5, %d, 3",
+				test.depth, got.Quorum, got.BlockHeight, got.ScanDepth, len(got.Keys), test.wantDepth)
+			continue
+		}
+		for i, want := range test.want {
+			if got.Keys[i].LastSignedHeight != want {
+				t.Errorf("depth %d: key %d last signed height = %d, want %d", test.depth, i, got.Keys[i].LastSignedHeight, want)
+			}
+		}
+		if got.Keys[1].SignerURL != "https://signer.example.com" || got.Keys[0].SignerURL != "" {
+			t.Errorf("depth %d: signer URLs = %q, %q, want only key 1's", test.depth, got.Keys[0].SignerURL, got.Keys[1].SignerURL)
+		}
+	}
+
+	_, err := a.getConsensusProgram(ctx, struct {
+		ScanDepth uint64 `json:"scan_depth"`
+	}{maxSignerScanDepth + 1})
+	if errors.Root(err) != httpjson.ErrBadRequest {
+		t.Errorf("depth %d: err = %v, want %v", maxSignerScanDepth+1, err, httpjson.ErrBadRequest)
+	}
+}
+
+// makeSignedBlock makes an empty block on c,
+// signs it with privs, and commits it.
+func makeSignedBlock(t testing.TB, c *protocol.Chain, privs ...ed25519.PrivateKey) *legacy.Block {
+	ctx := context.Background()
+	prev, snapshot := c.State()
+	b, s, err := c.GenerateBlock(ctx, prev, snapshot, time.Now(), nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	hash := b.Hash()
+	for _, priv := range privs {
+		b.Witness = append(b.Witness, ed25519.Sign(priv, hash.Bytes()))
+	}
+	err = c.CommitAppliedBlock(ctx, b, s)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return b
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	stdjson "encoding/json"
+	"math"
 	"sync"
 	"time"
 
@@ -35,6 +36,8 @@ func (a *API) createControlProgram(ctx context.Context, ins []struct {
 			switch ins[i].Type {
 			case "account":
 				prog, err = a.createAccountControlProgram(subctx, ins[i].Params)
+			case "vault":
+				prog, err = a.createVaultControlProgram(subctx, ins[i].Params)
 			default:
 				err = errors.WithDetailf(httpjson.ErrBadRequest, "unknown control program type %q", ins[i].Type)
 			}
@@ -79,3 +82,38 @@ func (a *API) createAccountControlProgram(ctx context.Context, input []byte) (in
 	}
 	return ret, nil
 }
+
+func (a *API) createVaultControlProgram(ctx context.Context, input []byte) (interface{}, error) {
+	var parsed struct {
+		AccountAlias string        `json:"account_alias"`
+		AccountID    string        `json:"account_id"`
+		Limit        uint64        `json:"limit"`
+		Period       json.Duration `json:"period"`
+	}
+	err := stdjson.Unmarshal(input, &parsed)
+	if err != nil {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "bad parameters for vault control program")
+	}
+	if parsed.Period.Duration < time.Millisecond || parsed.Limit > math.MaxInt64 {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "vault limit or period out of range")
+	}
+
+	accountID := parsed.AccountID
+	if accountID == "" {
+		acc, err := a.accounts.FindByAlias(ctx, parsed.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		accountID = acc.ID
+	}
+
+	controlProgram, err := a.accounts.CreateVaultProgram(ctx, accountID, parsed.Limit, parsed.Period.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]interface{}{
+		"control_program": json.HexBytes(controlProgram),
+	}
+	return ret, nil
+}
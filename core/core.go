@@ -18,6 +18,7 @@ import (
 	"chain/net/http/httpjson"
 	"chain/net/raft"
 	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
 )
 
 var (
@@ -46,6 +47,15 @@ func (a *API) reset(ctx context.Context, req struct {
 	panic("unreached")
 }
 
+// nodeSettings reports the settings this process
+// started with. Secrets in them are redacted.
+func (a *API) nodeSettings(ctx context.Context) map[string]interface{} {
+	return map[string]interface{}{
+		"config_file": a.configFile,
+		"settings":    a.settings,
+	}
+}
+
 func (a *API) info(ctx context.Context) (map[string]interface{}, error) {
 	if a.config == nil {
 		// never configured
@@ -112,6 +122,7 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 		"generator_access_token":            obfuscateTokenSecret(a.config.GeneratorAccessToken),
 		"blockchain_id":                     a.config.BlockchainId,
 		"block_height":                      localHeight,
+		"final_block_height":                a.finalHeight(),
 		"generator_block_height":            generatorHeight,
 		"generator_block_height_fetched_at": generatorFetched,
 		"network_rpc_version":               crosscoreRPCVersion, // "Network" is legacy terminology for "Cross-core"
@@ -122,8 +133,17 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 		"build_date":                        config.BuildDate,
 		"build_config":                      config.BuildConfig,
 		"health":                            a.health(),
+		"fenced":                            a.fenced(),
 	}
 
+	// Shadow Cores check this to make sure
+	// they don't share our database.
+	dbID, err := databaseID(ctx, a.db)
+	if err != nil {
+		return nil, err
+	}
+	m["database_id"] = dbID
+
 	// Add in snapshot information if we're downloading a snapshot.
 	if snapshot != nil {
 		downloadedBytes, totalBytes := snapshot.Progress()
@@ -146,6 +166,11 @@ type configureRequest struct {
 
 	// Updates contains incremental updates to configuration options.
 	Updates []configUpdate `json:"updates"`
+
+	// InitialBlock, if present, is the initial block a new
+	// generator uses, for instance one agreed on in a genesis
+	// ceremony, instead of creating its own.
+	InitialBlock *legacy.Block `json:"initial_block,omitempty"`
 }
 
 type configUpdate struct {
@@ -208,7 +233,7 @@ func (a *API) configure(ctx context.Context, req configureRequest) error {
 	if req.Config.IsGenerator && req.Config.MaxIssuanceWindowMs == 0 {
 		req.Config.MaxIssuanceWindowMs = bc.DurationMillis(24 * time.Hour)
 	}
-	err = config.Configure(ctx, a.db, a.sdb, a.httpClient, &req.Config)
+	err = config.Configure(ctx, a.db, a.sdb, a.httpClient, &req.Config, req.InitialBlock)
 	if err != nil {
 		return err
 	}
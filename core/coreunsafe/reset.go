@@ -73,6 +73,12 @@ func ResetEverything(ctx context.Context, db pg.DB, sdb *sinkdb.DB) error {
 	return errors.Wrap(err, "could not delete grants sinkdb")
 }
 
+// ResetQueryDB deletes all data from a separate query database
+// (see core.QueryDB). Its tables are rebuilt by indexing.
+func ResetQueryDB(ctx context.Context, db pg.DB) error {
+	return truncateDB(ctx, db, neverReset)
+}
+
 func truncateDB(ctx context.Context, db pg.DB, skipTbls []string) error {
 	const tableQ = `
 		SELECT table_name
@@ -8,6 +8,8 @@ import (
 	"chain/core/asset"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/fetch"
+	"chain/core/generator"
 	"chain/core/leader"
 	"chain/core/query"
 	"chain/core/query/filter"
@@ -23,6 +25,8 @@ import (
 	"chain/net/http/httpjson"
 	"chain/net/raft"
 	"chain/protocol"
+	"chain/protocol/audit"
+	"chain/protocol/vm/vmutil"
 )
 
 func isTemporary(info httperror.Info, err error) bool {
@@ -66,11 +70,13 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrMissingFields: {400, "CH010", "One or more fields are missing"},
 		authz.ErrNotAuthorized:     {403, "CH011", "Request is unauthorized"},
 		sinkdb.ErrConflict:         {409, "CH012", "Conflict processing request"},
+		errTooManyItems:            {400, "CH013", "Too many items in request"},
 		asset.ErrDuplicateAlias:    {400, "CH050", "Alias already exists"},
 		account.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
 		txfeed.ErrDuplicateAlias:   {400, "CH050", "Alias already exists"},
 		account.ErrBadIdentifier:   {400, "CH051", "Either an ID or alias must be provided, but not both"},
 		asset.ErrBadIdentifier:     {400, "CH051", "Either an ID or alias must be provided, but not both"},
+		asset.ErrMismatchedID:      {400, "CH052", "Asset ID does not match the issuance program and definition"},
 
 		// Core error namespace
 		errUnconfigured:                {400, "CH100", "This core still needs to be configured"},
@@ -86,8 +92,23 @@ var errorFormatter = httperror.Formatter{
 		errNoMockHSM:                   {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		errNoReset:                     {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		config.ErrNoBlockHSMURL:        {400, "CH111", "Block HSM URL cannot be empty when configuring a non mockhsm signer"},
+		config.ErrBadCostTable:         {400, "CH112", "Invalid opcode cost table"},
+		audit.ErrBadBundle:             {400, "CH113", "Cannot build audit bundle for the requested blocks"},
+		fetch.ErrBadCallbackSecret:     {401, "CH114", "Block notification has the wrong secret"},
+		errFenced:                      {400, "CH115", "This core is fenced and no longer generates blocks or accepts transactions"},
+		errAlreadyGenerator:            {400, "CH116", "This core is already the block generator"},
+		errConsensusMismatch:           {400, "CH117", "Block signers and quorum don't match the blockchain's consensus program"},
+		config.ErrBadInitialBlock:      {400, "CH118", "Initial block doesn't match the block signers and quorum"},
+		fetch.ErrBlockGap:              {400, "CH119", "Imported blocks don't follow this core's block height"},
 		errNoClientTokens:              {400, "CH120", "Cannot enable client authentication with no client tokens"},
+		protocol.ErrBadBlock:           {400, "CH121", "Block is invalid"},
+		errImportGenerator:             {400, "CH122", "The generator cannot import blocks"},
+		errShadow:                      {400, "CH123", "This core is a shadow and doesn't accept transactions"},
+		errNotGenerator:                {400, "CH124", "This core is not the block generator"},
+		errPendingBlock:                {400, "CH125", "Pending block from the fenced generator is invalid"},
+		errTooManyCallbacks:            {400, "CH126", "The generator has too many block callbacks"},
 		blocksigner.ErrConsensusChange: {400, "CH150", "Refuse to sign block with consensus change"},
+		blocksigner.ErrPolicy:          {400, "CH151", "Refuse to sign block rejected by signer policy"},
 		errMissingAddr:                 {400, "CH160", "Address is missing"},
 		errInvalidAddr:                 {400, "CH161", "Address is invalid"},
 		raft.ErrAddressNotAllowed:      {400, "CH162", "Address is not allowed"},
@@ -112,21 +133,27 @@ var errorFormatter = httperror.Formatter{
 		errCurrentToken:            {400, "CH310", "The access token used to authenticate this request cannot be deleted"},
 		errProtectedGrant:          {400, "CH320", "Protected grants cannot be manually deleted"},
 		errCreateProtectedGrant:    {400, "CH321", "Protected grants cannot be manually created"},
+		errApproverGrant:           {403, "CH322", "Only an internal caller can grant the client-approver policy"},
 
 		// Query error namespace (6xx)
 		query.ErrBadAfter:               {400, "CH600", "Malformed pagination parameter `after`"},
 		query.ErrParameterCountMismatch: {400, "CH601", "Incorrect number of parameters to filter"},
 		filter.ErrBadFilter:             {400, "CH602", "Malformed query filter"},
+		query.ErrBadTags:                {400, "CH603", "Tags must be strings, numbers or booleans"},
+		query.ErrBadBuckets:             {400, "CH604", "Invalid holding time buckets"},
+		query.ErrBadReindexHeight:       {400, "CH605", "Cannot reindex from a height the indexer hasn't reached"},
+		query.ErrReindexing:             {400, "CH606", "A reindex is already in progress"},
 
 		// Transaction error namespace (7xx)
 		// Build error namespace (70x)
-		txbuilder.ErrBadRefData: {400, "CH700", "Reference data does not match previous transaction's reference data"},
-		errBadActionType:        {400, "CH701", "Invalid action type"},
-		errBadAlias:             {400, "CH702", "Invalid alias on action"},
-		errBadAction:            {400, "CH703", "Invalid action object"},
-		txbuilder.ErrBadAmount:  {400, "CH704", "Invalid asset amount"},
-		txbuilder.ErrBlankCheck: {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
-		txbuilder.ErrAction:     {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrBadRefData:   {400, "CH700", "Reference data does not match previous transaction's reference data"},
+		errBadActionType:          {400, "CH701", "Invalid action type"},
+		errBadAlias:               {400, "CH702", "Invalid alias on action"},
+		errBadAction:              {400, "CH703", "Invalid action object"},
+		txbuilder.ErrBadAmount:    {400, "CH704", "Invalid asset amount"},
+		txbuilder.ErrBlankCheck:   {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
+		txbuilder.ErrAction:       {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrUnknownInput: {400, "CH707", "Transaction has an input of a type this Core doesn't understand"},
 
 		// Submit error namespace (73x)
 		txbuilder.ErrMissingRawTx:          {400, "CH730", "Missing raw transaction"},
@@ -137,10 +164,28 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrNoTxSighashCommitment: {400, "CH736", "Transaction is not final, additional actions still allowed"},
 		txbuilder.ErrTxSignatureFailure:    {400, "CH737", "Transaction signature missing, client may be missing signature key"},
 		txbuilder.ErrNoTxSighashAttempt:    {400, "CH738", "Transaction signature was not attempted"},
+		generator.ErrExpired:               {400, "CH739", "Transaction expired from the pending pool; rebuild it"},
+		generator.ErrTooLarge:              {400, "CH740", "Transaction exceeds the generator's maximum weight"},
+		errApprovalRequired:                {400, "CH741", "Transaction requires an approval token from /review-transaction"},
+		errBadApproval:                     {400, "CH742", "Approval token is invalid or expired"},
+		txbuilder.ErrNoInitialBlockID:      {400, "CH743", "Template has no initial block ID"},
+		errBadSerialization:                {400, "CH744", "Transaction serialization is invalid"},
+		errCompliance:                      {400, "CH745", "Transaction rejected by compliance check"},
 
 		// account action error namespace (76x)
-		account.ErrInsufficient: {400, "CH760", "Insufficient funds for tx"},
-		account.ErrReserved:     {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrInsufficient:    {400, "CH760", "Insufficient funds for tx"},
+		account.ErrReserved:        {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrPolicyViolation: {400, "CH762", "Transaction sends funds somewhere the account's policy forbids"},
+		account.ErrPolicyVersion:   {400, "CH763", "Account policy has changed; reload it and try again"},
+		account.ErrSigningKeys:     {400, "CH764", "Signing keys cannot authorize spending an output"},
+		account.ErrNotHot:          {400, "CH765", "Account is not a hot account"},
+		account.ErrStaleHotBalance: {400, "CH766", "Hot account balance is not yet current; try again"},
+		account.ErrBadDescriptor:   {400, "CH767", "Invalid account descriptor"},
+		account.ErrWatchOnly:       {400, "CH768", "Account is watch-only; its control programs are derived by an external wallet"},
+		account.ErrHeld:            {400, "CH769", "Funds are held for another purpose; release or settle the hold"},
+		account.ErrBadHold:         {400, "CH770", "Invalid account hold"},
+		vmutil.ErrVaultLimit:       {400, "CH771", "Withdrawal exceeds the vault's spending limit for the period"},
+		account.ErrBadDestination:  {400, "CH772", "Transfer destination must have exactly one of an account and a control program"},
 
 		// Mock HSM error namespace (80x)
 	},
@@ -21,6 +21,7 @@ func TestErrorMapping(t *testing.T) {
 		{errors.Wrap(pg.ErrUserInputNotFound, "foo"), `{"code":"CH002","message":"Not found","temporary":false}`, 400},
 		{errors.WithDetail(pg.ErrUserInputNotFound, "foo"), `{"code":"CH002","message":"Not found","detail":"foo","temporary":false}`, 400},
 		{context.DeadlineExceeded, `{"code":"CH001","message":"Request timed out","temporary":true}`, 408},
+		{errors.Wrap(errNotGenerator), `{"code":"CH124","message":"This core is not the block generator","temporary":false}`, 400},
 	}
 
 	for _, test := range cases {
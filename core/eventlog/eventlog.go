@@ -0,0 +1,201 @@
+// Package eventlog publishes a log of blockchain activity seen
+// by Chain Core to a message broker, so that downstream systems
+// can follow it without polling the HTTP API.
+//
+// For each block, the log holds a block event, an event for
+// each annotated transaction in the block, and a balance event
+// for each input or output that debits or credits a local
+// account. Every event has a key derived from its block height
+// and position, so a consumer can discard events it has already
+// seen. Blocks are published in order, but a block may be
+// published more than once if the Core restarts or changes
+// leader while publishing it.
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// PinName is used to identify the pin associated
+// with the event log block processor.
+const PinName = "eventlog"
+
+// Event types.
+const (
+	TypeBlock       = "block"
+	TypeTransaction = "transaction"
+	TypeBalance     = "balance"
+)
+
+// Event is a single entry in the event log.
+type Event struct {
+	// Key identifies the event. It is the block height for a
+	// block event, the block height and transaction position
+	// for a transaction event, such as "12:3", and those plus
+	// the input or output position for a balance event, such
+	// as "12:3:output:0".
+	Key  string      `json:"key"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Block is the data of a block event.
+type Block struct {
+	Height           uint64    `json:"height"`
+	ID               bc.Hash   `json:"id"`
+	Timestamp        time.Time `json:"timestamp"`
+	TransactionCount int       `json:"transaction_count"`
+}
+
+// BalanceChange is the data of a balance event.
+// Direction is "credit" for an output that pays
+// a local account and "debit" for an input that
+// spends from one.
+type BalanceChange struct {
+	TransactionID bc.Hash    `json:"transaction_id"`
+	Direction     string     `json:"direction"`
+	AccountID     string     `json:"account_id"`
+	AccountAlias  string     `json:"account_alias,omitempty"`
+	AssetID       bc.AssetID `json:"asset_id"`
+	AssetAlias    string     `json:"asset_alias,omitempty"`
+	Amount        uint64     `json:"amount"`
+}
+
+// Publisher sends events to a message broker. Publish is called
+// once per block with all of that block's events, in order. It
+// must not return until the events are stored durably; if it
+// returns an error, the block's events will be published again.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Log publishes the event log.
+type Log struct {
+	db       pg.DB
+	c        *protocol.Chain
+	pinStore *pin.Store
+	pub      Publisher
+}
+
+// New returns a Log that publishes to pub. It reads annotated
+// transactions from the query indexer, so transaction indexing
+// must be enabled.
+func New(db pg.DB, c *protocol.Chain, pinStore *pin.Store, pub Publisher) *Log {
+	return &Log{
+		db:       db,
+		c:        c,
+		pinStore: pinStore,
+		pub:      pub,
+	}
+}
+
+// ProcessBlocks publishes the events of each new block
+// until ctx is canceled.
+func (l *Log) ProcessBlocks(ctx context.Context) {
+	if l.pinStore == nil {
+		return
+	}
+	l.pinStore.ProcessBlocks(ctx, l.c, PinName, l.publishBlock)
+}
+
+func (l *Log) publishBlock(ctx context.Context, b *legacy.Block) error {
+	<-l.pinStore.PinWaiter(query.TxPinName, b.Height)
+	<-l.pinStore.PinWaiter(PinName, b.Height-1)
+
+	txs, err := l.annotatedTxs(ctx, b.Height)
+	if err != nil {
+		return err
+	}
+	err = l.pub.Publish(ctx, blockEvents(b, txs))
+	return errors.Wrapf(err, "publishing events for block %d", b.Height)
+}
+
+func (l *Log) annotatedTxs(ctx context.Context, height uint64) ([]*query.AnnotatedTx, error) {
+	const q = `
+		SELECT data FROM annotated_txs
+		WHERE block_height = $1
+		ORDER BY tx_pos
+	`
+	var txs []*query.AnnotatedTx
+	err := pg.ForQueryRows(ctx, l.db, q, height, func(data []byte) error {
+		tx := new(query.AnnotatedTx)
+		err := json.Unmarshal(data, tx)
+		if err != nil {
+			return errors.Wrap(err, "decoding annotated transaction")
+		}
+		txs = append(txs, tx)
+		return nil
+	})
+	return txs, errors.Wrapf(err, "loading annotated transactions for block %d", height)
+}
+
+// blockEvents returns the events of block b,
+// whose annotated transactions are txs.
+func blockEvents(b *legacy.Block, txs []*query.AnnotatedTx) []Event {
+	events := []Event{{
+		Key:  fmt.Sprint(b.Height),
+		Type: TypeBlock,
+		Data: Block{
+			Height:           b.Height,
+			ID:               b.Hash(),
+			Timestamp:        b.Time(),
+			TransactionCount: len(b.Transactions),
+		},
+	}}
+	for _, tx := range txs {
+		txKey := fmt.Sprintf("%d:%d", tx.BlockHeight, tx.Position)
+		events = append(events, Event{
+			Key:  txKey,
+			Type: TypeTransaction,
+			Data: tx,
+		})
+		for i, in := range tx.Inputs {
+			if in.AccountID == "" {
+				continue
+			}
+			events = append(events, Event{
+				Key:  fmt.Sprintf("%s:input:%d", txKey, i),
+				Type: TypeBalance,
+				Data: BalanceChange{
+					TransactionID: tx.ID,
+					Direction:     "debit",
+					AccountID:     in.AccountID,
+					AccountAlias:  in.AccountAlias,
+					AssetID:       in.AssetID,
+					AssetAlias:    in.AssetAlias,
+					Amount:        in.Amount,
+				},
+			})
+		}
+		for _, out := range tx.Outputs {
+			if out.AccountID == "" {
+				continue
+			}
+			events = append(events, Event{
+				Key:  fmt.Sprintf("%s:output:%d", txKey, out.Position),
+				Type: TypeBalance,
+				Data: BalanceChange{
+					TransactionID: tx.ID,
+					Direction:     "credit",
+					AccountID:     out.AccountID,
+					AccountAlias:  out.AccountAlias,
+					AssetID:       out.AssetID,
+					AssetAlias:    out.AssetAlias,
+					Amount:        out.Amount,
+				},
+			})
+		}
+	}
+	return events
+}
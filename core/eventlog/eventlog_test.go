@@ -0,0 +1,74 @@
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"chain/core/query"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestBlockEvents(t *testing.T) {
+	b := &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 7, TimestampMS: 1000},
+		Transactions: []*legacy.Tx{legacy.NewTx(legacy.TxData{Version: 1})},
+	}
+	txs := []*query.AnnotatedTx{{
+		ID:          bc.NewHash([32]byte{1}),
+		BlockHeight: 7,
+		Position:    0,
+		Inputs: []*query.AnnotatedInput{
+			{AccountID: "acc1", Amount: 5},
+			{Amount: 3},
+		},
+		Outputs: []*query.AnnotatedOutput{
+			{Position: 0, Amount: 6},
+			{Position: 1, AccountID: "acc2", AccountAlias: "bob", Amount: 2},
+		},
+	}}
+
+	events := blockEvents(b, txs)
+	var keys []string
+	for _, e := range events {
+		keys = append(keys, e.Type+" "+e.Key)
+	}
+	want := []string{
+		"block 7",
+		"transaction 7:0",
+		"balance 7:0:input:0",
+		"balance 7:0:output:1",
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got events %q, want %q", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, keys[i], want[i])
+		}
+	}
+
+	credit := events[3].Data.(BalanceChange)
+	if credit.Direction != "credit" || credit.AccountAlias != "bob" || credit.Amount != 2 {
+		t.Errorf("got balance change %+v, want credit of 2 to bob", credit)
+	}
+
+	var buf bytes.Buffer
+	err := NewWriterPublisher(&buf).Publish(context.Background(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i := range events {
+		var got struct{ Key, Type string }
+		err := dec.Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Key != events[i].Key || got.Type != events[i].Type {
+			t.Errorf("line %d = %+v, want key %s type %s", i, got, events[i].Key, events[i].Type)
+		}
+	}
+}
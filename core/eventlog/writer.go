@@ -0,0 +1,44 @@
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"chain/errors"
+)
+
+// NewWriterPublisher returns a Publisher that writes each event
+// to w as a line of JSON. If w has a Sync method, such as an
+// *os.File, it is called after each block's events are written.
+//
+// A log written this way can be fed to a broker by any tool that
+// tails a file, such as a Kafka Connect file source.
+func NewWriterPublisher(w io.Writer) Publisher {
+	return &writerPublisher{w: w}
+}
+
+type writerPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (p *writerPublisher) Publish(ctx context.Context, events []Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	enc := json.NewEncoder(p.w)
+	for _, e := range events {
+		err := enc.Encode(e)
+		if err != nil {
+			return errors.Wrapf(err, "writing event %s", e.Key)
+		}
+	}
+	if s, ok := p.w.(interface {
+		Sync() error
+	}); ok {
+		return errors.Wrap(s.Sync(), "syncing event log")
+	}
+	return nil
+}
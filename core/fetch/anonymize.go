@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"chain/crypto/sha3pool"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+)
+
+// An Anonymizer replaces the business data in the blocks of a
+// block file with salted hashes, so an operator can share a file
+// reproducing a problem without revealing it. It replaces the
+// reference data of each transaction, input, and output, and the
+// definition of each issued asset, with the SHA3-256 hash of the
+// salt followed by the data. Detached reference data hashes are
+// hashed the same way. Empty data is left empty.
+//
+// Replacing that data changes the IDs of the transactions, their
+// outputs, and the assets they issue, so the Anonymizer rewrites
+// what depends on them, in the blocks it has already seen: the
+// spends of rewritten outputs, the asset IDs of rewritten assets,
+// and each block's transactions merkle root and previous block
+// hash. If the first block it anonymizes is the initial block, it
+// also replays the anonymized state, to recompute each block's
+// assets merkle root. So an anonymized file exported from height
+// 1 has the structure of a valid chain. Amounts, programs, and
+// timestamps are left as they were. Signatures are too, and they
+// no longer verify, so a Core still can't import the file.
+//
+// A file must be anonymized by a single Anonymizer, in order of
+// height, so equal data hashes equally throughout.
+type Anonymizer struct {
+	salt []byte
+
+	prevHash *bc.Hash                  // of the last block anonymized
+	outputs  map[bc.Hash]anonSource    // keyed by original output ID
+	assets   map[bc.AssetID]bc.AssetID // original to anonymized
+	snapshot *state.Snapshot           // nil if not anonymizing from height 1
+}
+
+// anonSource is where an anonymized output
+// comes from, for rewriting its spends.
+type anonSource struct {
+	sourceID    bc.Hash
+	refDataHash bc.Hash
+}
+
+// NewAnonymizer returns an Anonymizer hashing data with salt,
+// which should be secret.
+func NewAnonymizer(salt []byte) *Anonymizer {
+	return &Anonymizer{
+		salt:    salt,
+		outputs: make(map[bc.Hash]anonSource),
+		assets:  make(map[bc.AssetID]bc.AssetID),
+	}
+}
+
+// AnonymizeBlock anonymizes b in place. It must follow
+// the last block a anonymized.
+func (a *Anonymizer) AnonymizeBlock(b *legacy.Block) error {
+	if a.prevHash == nil && b.Height == 1 {
+		a.snapshot = state.Empty()
+	}
+	if a.prevHash != nil {
+		b.PreviousBlockHash = *a.prevHash
+	}
+
+	var txEntries []*bc.Tx
+	for i, tx := range b.Transactions {
+		anonTx, err := a.anonymizeTx(tx)
+		if err != nil {
+			return errors.Wrapf(err, "anonymizing transaction %d", i)
+		}
+		b.Transactions[i] = anonTx
+		txEntries = append(txEntries, anonTx.Tx)
+	}
+
+	var err error
+	b.TransactionsMerkleRoot, err = bc.MerkleRoot(txEntries)
+	if err != nil {
+		return errors.Wrap(err, "computing transactions merkle root")
+	}
+	if a.snapshot != nil {
+		err = a.snapshot.ApplyBlock(legacy.MapBlock(b))
+		if err != nil {
+			return errors.Wrap(err, "applying anonymized block")
+		}
+		b.AssetsMerkleRoot = a.snapshot.Tree.RootHash()
+	}
+
+	h := b.Hash()
+	a.prevHash = &h
+	return nil
+}
+
+func (a *Anonymizer) anonymizeTx(tx *legacy.Tx) (*legacy.Tx, error) {
+	data := tx.TxData
+	anonymizeRefData(&data.ReferenceData, data.DetachedRefDataHash, a.salt)
+	for i, in := range data.Inputs {
+		anonymizeRefData(&in.ReferenceData, in.DetachedRefDataHash, a.salt)
+		switch typed := in.TypedInput.(type) {
+		case *legacy.IssuanceInput:
+			if len(typed.AssetDefinition) == 0 {
+				continue
+			}
+			orig := typed.AssetID()
+			typed.AssetDefinition = saltedHash(a.salt, typed.AssetDefinition)
+			a.assets[orig] = typed.AssetID()
+		case *legacy.SpendInput:
+			a.remapAsset(&typed.AssetAmount)
+			sp, err := tx.Spend(tx.InputIDs[i])
+			if err != nil {
+				return nil, errors.Wrapf(err, "input %d", i)
+			}
+			src, ok := a.outputs[*sp.SpentOutputId]
+			if ok {
+				typed.SourceID = src.sourceID
+				typed.RefDataHash = src.refDataHash
+				delete(a.outputs, *sp.SpentOutputId)
+			}
+		}
+	}
+	for _, out := range data.Outputs {
+		a.remapAsset(&out.AssetAmount)
+		anonymizeRefData(&out.ReferenceData, out.DetachedRefDataHash, a.salt)
+	}
+
+	anonTx := legacy.NewTx(data)
+	for i, id := range anonTx.ResultIds {
+		o, err := anonTx.Output(*id)
+		if err != nil {
+			continue // a retirement
+		}
+		a.outputs[*tx.ResultIds[i]] = anonSource{
+			sourceID:    *o.Source.Ref,
+			refDataHash: *o.Data,
+		}
+	}
+	return anonTx, nil
+}
+
+func (a *Anonymizer) remapAsset(amt *bc.AssetAmount) {
+	id, ok := a.assets[*amt.AssetId]
+	if ok {
+		amt.AssetId = &id
+	}
+}
+
+func anonymizeRefData(data *[]byte, detached *bc.Hash, salt []byte) {
+	if detached != nil {
+		var sum [32]byte
+		copy(sum[:], saltedHash(salt, detached.Bytes()))
+		*detached = bc.NewHash(sum)
+		return
+	}
+	if len(*data) > 0 {
+		*data = saltedHash(salt, *data)
+	}
+}
+
+func saltedHash(salt, data []byte) []byte {
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write(salt)
+	h.Write(data)
+	sum := make([]byte, 32)
+	h.Read(sum)
+	return sum
+}
@@ -0,0 +1,123 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/prottest/memstore"
+	"chain/protocol/state"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestAnonymizeBlocks(t *testing.T) {
+	ctx := context.Background()
+	src := prottest.NewChain(t)
+	trueProg := []byte{byte(vm.OP_TRUE)}
+	now := time.Now()
+
+	iss := legacy.NewIssuanceInput([]byte{1}, 5, []byte("issue ref"), prottest.Initial(t, src).Hash(), trueProg, nil, []byte(`{"name":"secret"}`))
+	assetID := iss.AssetID()
+	issueTx := legacy.NewTx(legacy.TxData{
+		Version:       1,
+		Inputs:        []*legacy.TxInput{iss},
+		Outputs:       []*legacy.TxOutput{legacy.NewTxOutput(assetID, 5, trueProg, []byte("invoice 123"))},
+		MinTime:       bc.Millis(now),
+		MaxTime:       bc.Millis(now.Add(time.Hour)),
+		ReferenceData: []byte("payroll"),
+	})
+	out, err := issueTx.Output(*issueTx.ResultIds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	spendTx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, *out.Source.Ref, assetID, 5, 0, trueProg, *out.Data, []byte("spend ref")),
+		},
+		Outputs: []*legacy.TxOutput{legacy.NewTxOutput(assetID, 5, trueProg, []byte("receipt 456"))},
+	})
+	for _, tx := range []*legacy.Tx{issueTx, spendTx} {
+		// Block timestamps must increase.
+		time.Sleep(time.Millisecond)
+		b := prottest.MakeBlock(t, src, []*legacy.Tx{tx})
+		if len(b.Transactions) != 1 {
+			t.Fatalf("block %d has %d transactions, want 1", b.Height, len(b.Transactions))
+		}
+	}
+
+	anonymize := func(salt string) []*legacy.Block {
+		a := NewAnonymizer([]byte(salt))
+		var blocks []*legacy.Block
+		for h := uint64(1); h <= src.Height(); h++ {
+			orig, err := src.GetBlock(ctx, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Anonymize a copy, leaving the chain's own block alone.
+			raw, err := orig.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := new(legacy.Block)
+			err = b.Decode(raw.([]byte))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = a.AnonymizeBlock(b)
+			if err != nil {
+				testutil.FatalErr(t, err)
+			}
+			blocks = append(blocks, b)
+		}
+		return blocks
+	}
+	blocks := anonymize("salt")
+
+	for _, b := range blocks {
+		var buf bytes.Buffer
+		_, err = b.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"issue ref", "secret", "invoice 123", "payroll", "spend ref", "receipt 456"} {
+			if bytes.Contains(buf.Bytes(), []byte(secret)) {
+				t.Errorf("anonymized block %d contains %q", b.Height, secret)
+			}
+		}
+	}
+	if blocks[1].Transactions[0].Outputs[0].AssetAmount == issueTx.Outputs[0].AssetAmount {
+		t.Error("anonymized issuance has the original asset ID")
+	}
+
+	// The anonymized blocks form a valid chain.
+	dst, err := protocol.NewChain(ctx, blocks[0].Hash(), memstore.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.MaxIssuanceWindow = src.MaxIssuanceWindow
+	err = dst.CommitAppliedBlock(ctx, blocks[0], state.Empty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ImportBlocks(ctx, dst, blocks[1:])
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The same data with the same salt gives the same blocks.
+	again := anonymize("salt")
+	if again[2].Hash() != blocks[2].Hash() {
+		t.Error("anonymizing with the same salt gave a different block")
+	}
+	other := anonymize("other salt")
+	if other[2].Hash() == blocks[2].Hash() {
+		t.Error("anonymizing with a different salt gave the same block")
+	}
+}
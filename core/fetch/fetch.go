@@ -14,6 +14,7 @@ import (
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
 	"chain/protocol/state"
 )
 
@@ -25,16 +26,24 @@ const heightPollingPeriod = 3 * time.Second
 // cancelled. To begin replicating blocks, the caller must call
 // Fetch.
 func New(peer *rpc.Client) *Replicator {
-	return &Replicator{peer: peer}
+	return &Replicator{
+		peer:           peer,
+		callbackSecret: newCallbackSecret(),
+		wake:           make(chan struct{}, 1),
+	}
 }
 
 // Replicator implements block replication.
 type Replicator struct {
 	peer *rpc.Client // peer to replicate
 
+	callbackSecret string        // see RegisterBlockCallback
+	wake           chan struct{} // signaled by Notify
+
 	mu              sync.Mutex
 	peerHeight      uint64
 	heightFetchedAt time.Time
+	badBlockErr     error
 }
 
 // PeerHeight returns the height of the peer Chain Core and the
@@ -47,15 +56,30 @@ func (rep *Replicator) PeerHeight() (uint64, time.Time) {
 	return h, t
 }
 
+// BadBlockErr returns the reason the last block fetched failed
+// validation, if it did, and replication halted; otherwise nil.
+func (rep *Replicator) BadBlockErr() error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return rep.badBlockErr
+}
+
 // Fetch runs in a loop, fetching blocks from the configured
 // peer (e.g. the generator) and applying them to the local
 // Chain.
 //
+// Fetch doesn't trust the peer: it validates each block in full
+// before applying it, including its transactions, its consensus
+// signatures and the state tree it commits to. If a block is
+// invalid, Fetch logs a critical alert, reports the error to
+// health, and stops applying blocks, which also halts indexing,
+// until the process restarts.
+//
 // It returns when its context is canceled.
 // After each attempt to fetch and apply a block, it calls health
 // to report either an error or nil to indicate success.
 func (rep *Replicator) Fetch(ctx context.Context, c *protocol.Chain, health func(error)) {
-	blockch, errch := DownloadBlocks(ctx, rep.peer, c.Height()+1)
+	blockch, errch := downloadBlocks(ctx, rep.peer, c.Height()+1, rep.wake)
 
 	var err error
 	var nfailures uint
@@ -71,8 +95,9 @@ func (rep *Replicator) Fetch(ctx context.Context, c *protocol.Chain, health func
 			prevBlock, prevSnapshot := c.State()
 			for {
 				err = applyBlock(ctx, c, prevSnapshot, prevBlock, b)
-				if err == protocol.ErrBadBlock {
-					log.Fatalkv(ctx, log.KeyError, err)
+				if errors.Root(err) == protocol.ErrBadBlock {
+					rep.halt(ctx, b, err, health)
+					return
 				} else if err != nil {
 					// This is a serious I/O error.
 					health(err)
@@ -91,6 +116,24 @@ func (rep *Replicator) Fetch(ctx context.Context, c *protocol.Chain, health func
 	}
 }
 
+// halt records that block b is invalid, raises an alert,
+// and waits for ctx to be canceled.
+func (rep *Replicator) halt(ctx context.Context, b *legacy.Block, err error, health func(error)) {
+	err = errors.Wrapf(err, "block %d (%x) from generator", b.Height, b.Hash().Bytes())
+	rep.mu.Lock()
+	rep.badBlockErr = err
+	rep.mu.Unlock()
+
+	log.Printkv(ctx,
+		"alert", "critical",
+		log.KeyMessage, "fetched block failed validation; halting replication and indexing",
+		"height", b.Height,
+		log.KeyError, err,
+	)
+	health(err)
+	<-ctx.Done()
+}
+
 // PollRemoteHeight periodically polls the configured peer for
 // its blockchain height. It blocks until the ctx is canceled.
 func (rep *Replicator) PollRemoteHeight(ctx context.Context) {
@@ -130,6 +173,12 @@ func (rep *Replicator) updateGeneratorHeight(ctx context.Context) {
 // reading from both. DownloadBlocks will continue even if it encounters errors,
 // until its context is done.
 func DownloadBlocks(ctx context.Context, peer *rpc.Client, height uint64) (chan *legacy.Block, chan error) {
+	return downloadBlocks(ctx, peer, height, nil)
+}
+
+// downloadBlocks is like DownloadBlocks, but a receive on wake
+// cuts short the wait before retrying a failed download.
+func downloadBlocks(ctx context.Context, peer *rpc.Client, height uint64, wake <-chan struct{}) (chan *legacy.Block, chan error) {
 	blockch := make(chan *legacy.Block)
 	errch := make(chan error)
 	go func() {
@@ -146,7 +195,10 @@ func DownloadBlocks(ctx context.Context, peer *rpc.Client, height uint64) (chan
 				if err != nil {
 					errch <- err
 					nfailures++
-					time.Sleep(backoffDur(nfailures))
+					select {
+					case <-time.After(backoffDur(nfailures)):
+					case <-wake:
+					}
 					continue
 				}
 				if block == nil {
@@ -157,7 +209,11 @@ func DownloadBlocks(ctx context.Context, peer *rpc.Client, height uint64) (chan
 					continue
 				}
 
-				blockch <- block
+				select {
+				case blockch <- block:
+				case <-ctx.Done():
+					continue
+				}
 				ntimeouts, nfailures = 0, 0
 				height++
 			}
@@ -166,12 +222,29 @@ func DownloadBlocks(ctx context.Context, peer *rpc.Client, height uint64) (chan
 	return blockch, errch
 }
 
+// applyBlock validates block and applies it to c. If block is
+// invalid, the root of the error it returns is
+// protocol.ErrBadBlock.
 func applyBlock(ctx context.Context, c *protocol.Chain, prevSnap *state.Snapshot, prev *legacy.Block, block *legacy.Block) error {
 	err := c.ValidateBlock(block, prev)
 	if err != nil {
 		return errors.Wrap(err, "validating fetched block")
 	}
-	err = c.CommitBlock(ctx, block)
+
+	snapshot := state.Copy(prevSnap)
+	err = snapshot.ApplyBlock(legacy.MapBlock(block))
+	if errors.Root(err) == patricia.ErrLoad {
+		// An I/O error reading the state tree, not a bad block.
+		return errors.Wrap(err, "applying fetched block")
+	}
+	if err != nil {
+		return errors.Sub(protocol.ErrBadBlock, errors.Wrap(err, "applying fetched block"))
+	}
+	if block.AssetsMerkleRoot != snapshot.Tree.RootHash() {
+		return errors.Sub(protocol.ErrBadBlock, protocol.ErrBadStateRoot)
+	}
+
+	err = c.CommitAppliedBlock(ctx, block, snapshot)
 	return errors.Wrap(err, "committing block")
 }
 
@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+)
+
+func TestApplyBlock(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	prev, snap := c.State()
+	// Time the block after the initial block, which may
+	// have been made in the same millisecond.
+	ts := time.Unix(0, 0).Add(bc.MillisDuration(prev.TimestampMS + 1))
+	b, _, err := c.GenerateBlock(ctx, prev, snap, ts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A block committing to the wrong state tree is
+	// invalid, even if everything else about it is fine.
+	bad := *b
+	bad.AssetsMerkleRoot = bc.NewHash([32]byte{1})
+	err = applyBlock(ctx, c, snap, prev, &bad)
+	if errors.Root(err) != protocol.ErrBadBlock {
+		t.Errorf("applyBlock(bad state root) = %v, want %s", err, protocol.ErrBadBlock)
+	}
+	if c.Height() != 1 {
+		t.Fatalf("height = %d after bad block, want 1", c.Height())
+	}
+
+	err = applyBlock(ctx, c, snap, prev, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Height() != 2 {
+		t.Errorf("height = %d, want 2", c.Height())
+	}
+}
+
+func TestNotify(t *testing.T) {
+	rep := New(nil)
+	n := &BlockNotification{
+		Secret: "wrong",
+		Header: &legacy.BlockHeader{Height: 5},
+	}
+	err := rep.Notify(n)
+	if errors.Root(err) != ErrBadCallbackSecret {
+		t.Errorf("Notify(wrong secret) = %v, want %s", err, ErrBadCallbackSecret)
+	}
+	if h, _ := rep.PeerHeight(); h != 0 {
+		t.Errorf("peer height after bad notification = %d, want 0", h)
+	}
+
+	n.Secret = rep.callbackSecret
+	err = rep.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := rep.PeerHeight(); h != 5 {
+		t.Errorf("peer height = %d, want 5", h)
+	}
+	select {
+	case <-rep.wake:
+	default:
+		t.Error("Notify did not wake Fetch")
+	}
+}
@@ -0,0 +1,130 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+)
+
+// Block files move blocks to a Core that can't reach the
+// generator, such as an air-gapped validator. A block file holds
+// a contiguous range of blocks: blockFileMagic, followed by each
+// block, serialized as for storage, prefixed with its length as
+// a uvarint. Blocks are only ever appended, so an interrupted
+// export can resume where it left off.
+
+const blockFileMagic = "chain block file v1\n"
+
+// maxFileBlockSize bounds the length prefix of
+// a block in a block file, to catch corruption
+// before allocating a huge buffer.
+const maxFileBlockSize = 1 << 30
+
+var (
+	// ErrBadBlockFile is returned for a block file that is
+	// malformed, or whose blocks don't form a hash chain.
+	ErrBadBlockFile = errors.New("invalid block file")
+
+	// ErrBlockGap is returned by ImportBlocks when the blocks
+	// start above the height following the chain's height.
+	ErrBlockGap = errors.New("blocks don't follow the current height")
+)
+
+// WriteBlockFileHeader writes the header of a new block file to w.
+func WriteBlockFileHeader(w io.Writer) error {
+	_, err := io.WriteString(w, blockFileMagic)
+	return errors.Wrap(err, "writing block file header")
+}
+
+// WriteFileBlock appends raw, a serialized block, to the block
+// file in w. The caller must append blocks in order of height.
+func WriteFileBlock(w io.Writer, raw []byte) error {
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(raw)))
+	_, err := w.Write(prefix[:n])
+	if err != nil {
+		return errors.Wrap(err, "writing block length")
+	}
+	_, err = w.Write(raw)
+	return errors.Wrap(err, "writing block")
+}
+
+// ReadBlockFile reads the block file in r, calling f with each
+// block in turn, after checking that it follows the one before it.
+// It returns the first error from f, if any.
+func ReadBlockFile(r io.Reader, f func(*legacy.Block) error) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(blockFileMagic))
+	_, err := io.ReadFull(br, magic)
+	if err != nil || string(magic) != blockFileMagic {
+		return errors.WithDetail(ErrBadBlockFile, "missing block file header")
+	}
+
+	var prev *legacy.Block
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Sub(ErrBadBlockFile, err)
+		}
+		if n > maxFileBlockSize {
+			return errors.WithDetailf(ErrBadBlockFile, "block of %d bytes", n)
+		}
+		raw := make([]byte, n)
+		_, err = io.ReadFull(br, raw)
+		if err != nil {
+			return errors.Sub(ErrBadBlockFile, errors.Wrap(err, "reading block"))
+		}
+
+		b := new(legacy.Block)
+		err = b.Decode(raw)
+		if err != nil {
+			return errors.Sub(ErrBadBlockFile, errors.Wrap(err, "decoding block"))
+		}
+		if prev != nil && (b.Height != prev.Height+1 || b.PreviousBlockHash != prev.Hash()) {
+			return errors.WithDetailf(ErrBadBlockFile, "block %d doesn't follow block %d", b.Height, prev.Height)
+		}
+		err = f(b)
+		if err != nil {
+			return err
+		}
+		prev = b
+	}
+}
+
+// ImportBlocks validates blocks and applies them to c, as Fetch
+// does with blocks from the generator. The blocks must be in
+// order of height. Those at or below c's height are checked
+// against the blocks c already has, and skipped, so an import
+// can be retried or resumed from the start of the same range.
+func ImportBlocks(ctx context.Context, c *protocol.Chain, blocks []*legacy.Block) error {
+	for _, b := range blocks {
+		height := c.Height()
+		if b.Height <= height {
+			existing, err := c.GetBlock(ctx, b.Height)
+			if err != nil {
+				return errors.Wrapf(err, "getting block %d", b.Height)
+			}
+			if existing.Hash() != b.Hash() {
+				return errors.WithDetailf(protocol.ErrBadBlock, "block %d differs from the one this core has", b.Height)
+			}
+			continue
+		}
+		if b.Height > height+1 {
+			return errors.WithDetailf(ErrBlockGap, "block %d follows height %d", b.Height, height)
+		}
+
+		prev, snapshot := c.State()
+		err := applyBlock(ctx, c, snapshot, prev, b)
+		if err != nil {
+			return errors.Wrapf(err, "importing block %d", b.Height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/prottest/memstore"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestBlockFile(t *testing.T) {
+	ctx := context.Background()
+	src := prottest.NewChain(t)
+	for i := 0; i < 3; i++ {
+		// Block timestamps must increase.
+		time.Sleep(time.Millisecond)
+		prottest.MakeBlock(t, src, nil)
+	}
+
+	var buf bytes.Buffer
+	err := WriteBlockFileHeader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for h := uint64(1); h <= src.Height(); h++ {
+		b, err := src.GetBlock(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := b.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = WriteFileBlock(&buf, raw.([]byte))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var blocks []*legacy.Block
+	err = ReadBlockFile(bytes.NewReader(buf.Bytes()), func(b *legacy.Block) error {
+		blocks = append(blocks, b)
+		return nil
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("read %d blocks, want 4", len(blocks))
+	}
+
+	// A core with only the initial block imports the rest,
+	// and importing the same blocks again changes nothing.
+	dst, err := protocol.NewChain(ctx, blocks[0].Hash(), memstore.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dst.CommitAppliedBlock(ctx, blocks[0], state.Empty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ImportBlocks(ctx, dst, blocks[2:])
+	if errors.Root(err) != ErrBlockGap {
+		t.Errorf("ImportBlocks(from height 3) = %v, want %s", err, ErrBlockGap)
+	}
+	for i := 0; i < 2; i++ {
+		err = ImportBlocks(ctx, dst, blocks)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if dst.Height() != 4 {
+			t.Fatalf("height = %d after import, want 4", dst.Height())
+		}
+	}
+
+	// A block file whose blocks don't form a hash chain is invalid.
+	var bad bytes.Buffer
+	WriteBlockFileHeader(&bad)
+	for _, b := range []*legacy.Block{blocks[0], blocks[2]} {
+		raw, _ := b.Value()
+		WriteFileBlock(&bad, raw.([]byte))
+	}
+	err = ReadBlockFile(&bad, func(*legacy.Block) error { return nil })
+	if errors.Root(err) != ErrBadBlockFile {
+		t.Errorf("ReadBlockFile(gap) = %v, want %s", err, ErrBadBlockFile)
+	}
+}
@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+// callbackRenewPeriod is how often a participant renews its block
+// callback with the generator. It must be well within the period
+// after which the generator forgets a callback.
+const callbackRenewPeriod = 30 * time.Second
+
+// ErrBadCallbackSecret is returned by Notify for a notification
+// that doesn't carry the secret this Replicator registered.
+var ErrBadCallbackSecret = errors.New("bad block callback secret")
+
+// BlockCallback is the request a participant sends to the
+// generator to register for block notifications.
+type BlockCallback struct {
+	// URL is the base URL of the participant, as the generator
+	// can reach it. The generator posts new block headers to
+	// its /rpc/notify-block endpoint.
+	URL string `json:"url"`
+
+	// Secret is sent back by the generator with each notification,
+	// so the participant can tell them from forgeries.
+	Secret string `json:"secret"`
+}
+
+// BlockNotification is the request the generator sends to a
+// participant's /rpc/notify-block endpoint for a new block.
+type BlockNotification struct {
+	Secret string              `json:"secret"`
+	Header *legacy.BlockHeader `json:"header"`
+}
+
+func newCallbackSecret() string {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// RegisterBlockCallback asks the peer to push the header of each
+// new block to the Chain Core at url, so that replication needn't
+// wait for the next poll. It renews the registration periodically,
+// and returns when ctx is canceled.
+//
+// Notifications are only hints: Fetch still downloads and validates
+// each block, and falls back to polling if they stop arriving.
+func (rep *Replicator) RegisterBlockCallback(ctx context.Context, url string) {
+	ticker := time.NewTicker(callbackRenewPeriod)
+	defer ticker.Stop()
+	for {
+		cb := BlockCallback{URL: url, Secret: rep.callbackSecret}
+		err := rep.peer.Call(ctx, "/rpc/register-block-callback", cb, nil)
+		if err != nil {
+			logNetworkError(ctx, errors.Wrap(err, "registering block callback"))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Notify handles a block notification pushed by the peer.
+// It records the peer's new height and wakes Fetch, if it's
+// waiting to retry a download.
+func (rep *Replicator) Notify(n *BlockNotification) error {
+	if subtle.ConstantTimeCompare([]byte(n.Secret), []byte(rep.callbackSecret)) != 1 {
+		return errors.Wrap(ErrBadCallbackSecret)
+	}
+	if n.Header == nil {
+		return nil
+	}
+
+	rep.mu.Lock()
+	if n.Header.Height > rep.peerHeight {
+		rep.peerHeight = n.Header.Height
+		rep.heightFetchedAt = time.Now()
+	}
+	rep.mu.Unlock()
+
+	select {
+	case rep.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
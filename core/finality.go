@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/eventlog"
+	"chain/core/query"
+)
+
+// checkFinalityPins returns an error if any of the finality
+// pins is not one whose block processor this Core runs.
+// Waiting on such a pin would never end.
+func (a *API) checkFinalityPins() error {
+	running := map[string]bool{
+		account.PinName:             true,
+		account.ExpirePinName:       true,
+		account.DeleteSpentsPinName: true,
+		asset.PinName:               true,
+		query.TxPinName:             a.indexTxs,
+		eventlog.PinName:            a.eventLog != nil,
+	}
+	for _, name := range a.finalityPins {
+		if !running[name] {
+			return fmt.Errorf("finality pin %q is not processed by this core", name)
+		}
+	}
+	return nil
+}
+
+// finalHeight returns the height of the latest block that is
+// final: one that every finality pin has processed. Without
+// finality pins, every block is final as soon as it is committed.
+func (a *API) finalHeight() uint64 {
+	height := a.chain.Height()
+	for _, name := range a.finalityPins {
+		h, ok := a.pinStore.PinHeight(name)
+		if !ok {
+			// Not created yet; nothing is final.
+			return 0
+		}
+		if h < height {
+			height = h
+		}
+	}
+	return height
+}
+
+// waitFinal waits until the block at height is final.
+func (a *API) waitFinal(ctx context.Context, height uint64) error {
+	for _, name := range a.finalityPins {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.pinStore.PinWaiter(name, height):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package core
+
+import "testing"
+
+func TestCheckFinalityPins(t *testing.T) {
+	cases := []struct {
+		pins     []string
+		indexTxs bool
+		ok       bool
+	}{
+		{nil, false, true},
+		{[]string{"account", "asset"}, false, true},
+		{[]string{"account", "asset", "tx"}, true, true},
+		{[]string{"account", "tx"}, false, false},
+		{[]string{"eventlog"}, true, false},
+		{[]string{"nonesuch"}, true, false},
+	}
+	for _, c := range cases {
+		a := &API{finalityPins: c.pins, indexTxs: c.indexTxs}
+		err := a.checkFinalityPins()
+		if (err == nil) != c.ok {
+			t.Errorf("checkFinalityPins(%q, indexTxs %v) = %v, want ok %v", c.pins, c.indexTxs, err, c.ok)
+		}
+	}
+}
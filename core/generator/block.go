@@ -12,6 +12,7 @@ import (
 	"chain/errors"
 	"chain/log"
 	"chain/metrics"
+	"chain/net/http/reqid"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
@@ -53,7 +54,7 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 	// Check to see if we already have a pending, generated block.
 	// This can happen if the leader process exits between generating
 	// the block and committing the signed block to the blockchain.
-	b, err = getPendingBlock(ctx, g.db)
+	b, err = g.PendingStore.GetPendingBlock(ctx)
 	if err != nil {
 		return errors.Wrap(err, "retrieving the pending block")
 	}
@@ -64,20 +65,33 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 			log.Fatalkv(ctx, log.KeyError, err)
 		}
 	} else {
+		now := g.clock.Now()
+
 		g.mu.Lock()
-		txs := g.pool
+		var txs []*legacy.Tx
+		for _, tx := range g.pool {
+			st := g.checkExpiry(tx, latestBlock.Height+1, bc.Millis(now))
+			if st != nil {
+				log.Printkv(txContext(ctx, g.reqIDs, tx), "at", "transaction expired from pending pool", "tx", tx.ID, "reason", st.Reason)
+				continue
+			}
+			txs = append(txs, tx)
+		}
+		reqIDs := g.reqIDs
 		g.pool = nil
 		g.poolHashes = make(map[bc.Hash]bool)
+		g.reqIDs = make(map[bc.Hash]string)
 		g.mu.Unlock()
 
-		b, s, err = g.chain.GenerateBlock(ctx, latestBlock, latestSnapshot, time.Now(), txs)
+		b, s, err = g.chain.GenerateBlock(ctx, latestBlock, latestSnapshot, now, txs)
 		if err != nil {
 			return errors.Wrap(err, "generate")
 		}
+		logDroppedTxs(ctx, reqIDs, txs, b)
 		if len(b.Transactions) == 0 {
 			return nil // don't bother making an empty block
 		}
-		err = savePendingBlock(ctx, g.db, b)
+		err = g.PendingStore.SavePendingBlock(ctx, b)
 		if err != nil {
 			return errors.Wrap(err, "saving pending block")
 		}
@@ -85,6 +99,30 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 	return g.commitBlock(ctx, b, s, latestBlock)
 }
 
+// logDroppedTxs logs each pending transaction in txs that
+// GenerateBlock left out of b, because it was invalid.
+func logDroppedTxs(ctx context.Context, reqIDs map[bc.Hash]string, txs []*legacy.Tx, b *legacy.Block) {
+	included := make(map[bc.Hash]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		included[tx.ID] = true
+	}
+	for _, tx := range txs {
+		if !included[tx.ID] {
+			log.Printkv(txContext(ctx, reqIDs, tx), "at", "invalid transaction dropped from pending pool", "tx", tx.ID)
+		}
+	}
+}
+
+// txContext returns ctx with the ID of the
+// request that submitted tx, if there was one.
+func txContext(ctx context.Context, reqIDs map[bc.Hash]string, tx *legacy.Tx) context.Context {
+	id, ok := reqIDs[tx.ID]
+	if !ok {
+		return ctx
+	}
+	return reqid.NewContext(ctx, id)
+}
+
 func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.Snapshot, prevBlock *legacy.Block) error {
 	err := g.getAndAddBlockSignatures(ctx, b, prevBlock)
 	if err != nil {
@@ -95,6 +133,7 @@ func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.S
 	if err != nil {
 		return errors.Wrap(err, "commit")
 	}
+	g.forget(b)
 	return nil
 }
 
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrExpired is returned by Submit for a transaction that has
+// expired from the pending pool. It will never be included in
+// a block; the application should build a new transaction.
+var ErrExpired = errors.New("transaction expired from pending pool")
+
+// statusRetentionBlocks is the number of blocks for which the
+// generator remembers a transaction after it expires.
+const statusRetentionBlocks = 1000
+
+// defaultRebuildTTL is the length of the suggested rebuild
+// window for a transaction that had no time range of its own.
+const defaultRebuildTTL = 5 * time.Minute
+
+// TxStatus describes what the generator knows about a transaction.
+type TxStatus struct {
+	// Status is "pending" for a transaction in the pending pool,
+	// "expired" for one that expired from it, and "unknown"
+	// otherwise. An unknown transaction may already be in a
+	// block, or may have been rejected as invalid.
+	Status string `json:"status"`
+
+	// The remaining fields are set only for expired transactions.
+	Reason        string `json:"reason,omitempty"`
+	ExpiredHeight uint64 `json:"expired_height,omitempty"`
+
+	// RebuildMinTimeMS and RebuildMaxTimeMS suggest a time
+	// range for a replacement transaction.
+	RebuildMinTimeMS uint64 `json:"rebuild_min_time_ms,omitempty"`
+	RebuildMaxTimeMS uint64 `json:"rebuild_max_time_ms,omitempty"`
+}
+
+// GetTxStatus returns the status of the transaction with the given ID.
+func (g *Generator) GetTxStatus(id bc.Hash) TxStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if st, ok := g.expired[id]; ok {
+		return *st
+	}
+	if g.poolHashes[id] {
+		return TxStatus{Status: "pending"}
+	}
+	return TxStatus{Status: "unknown"}
+}
+
+// expiredErr returns ErrExpired with the details of st.
+func expiredErr(st *TxStatus) error {
+	err := errors.WithDetail(ErrExpired, st.Reason)
+	return errors.WithData(err,
+		"rebuild_min_time_ms", st.RebuildMinTimeMS,
+		"rebuild_max_time_ms", st.RebuildMaxTimeMS,
+	)
+}
+
+// checkExpiry expires tx if its max time is before nowMS or if it
+// was first submitted more than g.MaxPendingBlocks blocks before
+// height. It returns the new status, or nil if tx has not expired.
+// The caller must hold g.mu.
+func (g *Generator) checkExpiry(tx *legacy.Tx, height, nowMS uint64) *TxStatus {
+	var reason string
+	if tx.MaxTime > 0 && tx.MaxTime < nowMS {
+		reason = "transaction max time has passed"
+	} else if first, ok := g.firstSeen[tx.ID]; ok && g.MaxPendingBlocks > 0 && height > first+g.MaxPendingBlocks {
+		reason = "transaction waited too many blocks"
+	}
+	if reason == "" {
+		return nil
+	}
+
+	ttl := uint64(defaultRebuildTTL / time.Millisecond)
+	if tx.MinTime > 0 && tx.MaxTime > tx.MinTime {
+		ttl = tx.MaxTime - tx.MinTime
+	}
+	st := &TxStatus{
+		Status:           "expired",
+		Reason:           reason,
+		ExpiredHeight:    height,
+		RebuildMinTimeMS: nowMS,
+		RebuildMaxTimeMS: nowMS + ttl,
+	}
+	g.expired[tx.ID] = st
+	delete(g.firstSeen, tx.ID)
+	return st
+}
+
+// forget drops the generator's record of the transactions in b
+// and of any transactions it has tracked for too long.
+func (g *Generator) forget(b *legacy.Block) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, tx := range b.Transactions {
+		delete(g.firstSeen, tx.ID)
+	}
+	if b.Height <= statusRetentionBlocks {
+		return
+	}
+	cutoff := b.Height - statusRetentionBlocks
+	for id, st := range g.expired {
+		if st.ExpiredHeight < cutoff {
+			delete(g.expired, id)
+		}
+	}
+	for id, first := range g.firstSeen {
+		if first+g.MaxPendingBlocks < cutoff {
+			delete(g.firstSeen, id)
+		}
+	}
+}
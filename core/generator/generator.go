@@ -9,13 +9,20 @@ import (
 	"sync"
 	"time"
 
+	"chain/clock"
 	"chain/database/pg"
+	"chain/errors"
 	"chain/log"
+	"chain/net/http/reqid"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 )
 
+// ErrTooLarge is returned by Submit for a transaction
+// whose weight exceeds the generator's limit.
+var ErrTooLarge = errors.New("transaction too large")
+
 // A BlockSigner signs blocks.
 type BlockSigner interface {
 	// SignBlock returns an ed25519 signature over the block's sighash.
@@ -28,13 +35,40 @@ type BlockSigner interface {
 // an interval.
 type Generator struct {
 	// config
-	db      pg.DB
 	chain   *protocol.Chain
 	signers []BlockSigner
 
+	// MaxPendingBlocks, if nonzero, is the number of blocks a
+	// transaction may wait, counted from its first submission,
+	// before it expires from the pending pool.
+	MaxPendingBlocks uint64
+
+	// MaxTxWeight, if nonzero, is the largest fee weight
+	// (see legacy.TxData.Weight) of a transaction that
+	// Submit accepts.
+	MaxTxWeight int64
+
+	// PendingStore persists the block being signed, so that
+	// the generator recovers it after a crash. New sets it to
+	// a store in the database passed to New.
+	PendingStore BlockStore
+
+	clock clock.Clock // times blocks and transaction expiry
+
 	mu         sync.Mutex
 	pool       []*legacy.Tx // in topological order
 	poolHashes map[bc.Hash]bool
+	firstSeen  map[bc.Hash]uint64 // height at first submission
+	expired    map[bc.Hash]*TxStatus
+
+	// reqIDs holds the ID of the request that submitted each
+	// pending transaction, so that what later happens to it
+	// is logged with that request's ID.
+	reqIDs map[bc.Hash]string
+
+	// makeMu is held while a block is made; see Stop.
+	makeMu  sync.Mutex
+	stopped bool
 }
 
 // New creates and initializes a new Generator.
@@ -44,13 +78,24 @@ func New(
 	db pg.DB,
 ) *Generator {
 	return &Generator{
-		db:         db,
-		chain:      c,
-		signers:    s,
-		poolHashes: make(map[bc.Hash]bool),
+		chain:        c,
+		signers:      s,
+		clock:        clock.Real,
+		PendingStore: NewDBBlockStore(db),
+		poolHashes:   make(map[bc.Hash]bool),
+		firstSeen:    make(map[bc.Hash]uint64),
+		expired:      make(map[bc.Hash]*TxStatus),
+		reqIDs:       make(map[bc.Hash]string),
 	}
 }
 
+// SetClock sets the clock that times blocks and transaction
+// expiry, in place of the real one, for testing. It must be
+// called before the generator is used.
+func (g *Generator) SetClock(c clock.Clock) {
+	g.clock = c
+}
+
 // PendingTxs returns all of the pendings txs that will be
 // included in the generator's next block.
 func (g *Generator) PendingTxs() []*legacy.Tx {
@@ -63,22 +108,48 @@ func (g *Generator) PendingTxs() []*legacy.Tx {
 }
 
 // Submit adds a new pending tx to the pending tx pool.
+// It returns ErrExpired if tx has expired; see GetTxStatus.
+// It returns ErrTooLarge if tx weighs more than g.MaxTxWeight.
 func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
+	if g.MaxTxWeight > 0 {
+		w, err := tx.Weight()
+		if err != nil {
+			return errors.Wrap(err, "weighing transaction")
+		}
+		if w > g.MaxTxWeight {
+			return errors.WithDetailf(ErrTooLarge, "transaction weight %d exceeds the maximum of %d", w, g.MaxTxWeight)
+		}
+	}
+
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	if st, ok := g.expired[tx.ID]; ok {
+		return expiredErr(st)
+	}
 	if g.poolHashes[tx.ID] {
 		return nil
 	}
 
+	height := g.chain.Height() + 1
+	if st := g.checkExpiry(tx, height, bc.Millis(g.clock.Now())); st != nil {
+		return expiredErr(st)
+	}
+	if _, ok := g.firstSeen[tx.ID]; !ok {
+		g.firstSeen[tx.ID] = height
+	}
+
 	g.poolHashes[tx.ID] = true
 	g.pool = append(g.pool, tx)
+	if id := reqid.FromContext(ctx); id != "" {
+		g.reqIDs[tx.ID] = id
+	}
 	return nil
 }
 
 // Generate runs in a loop, making one new block
 // every block period. It returns when its context
-// is canceled.
+// is canceled or Stop is called.
 // After each attempt to make a block, it calls health
 // to report either an error or nil to indicate success.
 func (g *Generator) Generate(
@@ -86,14 +157,22 @@ func (g *Generator) Generate(
 	period time.Duration,
 	health func(error),
 ) {
-	ticks := time.Tick(period)
+	ticker := g.clock.NewTicker(period)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf(ctx, "Deposed, Generate exiting")
 			return
-		case <-ticks:
+		case <-ticker.C:
+			g.makeMu.Lock()
+			if g.stopped {
+				g.makeMu.Unlock()
+				log.Printf(ctx, "Stopped, Generate exiting")
+				return
+			}
 			err := g.makeBlock(ctx)
+			g.makeMu.Unlock()
 			health(err)
 			if err != nil {
 				log.Error(ctx, err)
@@ -101,3 +180,13 @@ func (g *Generator) Generate(
 		}
 	}
 }
+
+// Stop stops Generate from making any more blocks.
+// It waits for the block being made, if any, to be
+// committed or abandoned, so once it returns, the
+// chain's height no longer changes.
+func (g *Generator) Stop() {
+	g.makeMu.Lock()
+	defer g.makeMu.Unlock()
+	g.stopped = true
+}
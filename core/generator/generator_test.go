@@ -3,13 +3,20 @@ package generator
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"chain/clock"
 	"chain/crypto/ed25519"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -164,6 +171,96 @@ func TestGetAndAddBlockSignaturesInitialBlock(t *testing.T) {
 	}
 }
 
+// TestGeneratorFailoverPendingBlock tests a failover after one
+// signer has signed the primary's pending block, but the primary
+// failed to get a quorum and commit it.
+func TestGeneratorFailoverPendingBlock(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t, prottest.WithBlockSigners(2, 2))
+	pubkeys, privkeys := prottest.BlockKeyPairs(c)
+	dir, err := ioutil.TempDir("", "generator")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	defer os.RemoveAll(dir)
+
+	signed := &heightLockSigner{testSigner: testSigner{nil, pubkeys[0], privkeys[0]}}
+	down := testSigner{func() error { return errors.New("down") }, pubkeys[1], privkeys[1]}
+	up := testSigner{nil, pubkeys[1], privkeys[1]}
+	initial := prottest.Initial(t, c).Hash()
+	height := c.Height()
+
+	primary := New(c, []BlockSigner{signed, down}, nil)
+	primary.PendingStore = NewFileBlockStore(filepath.Join(dir, "primary"))
+	primary.pool = append(primary.pool, bctest.NewIssuanceTx(t, initial))
+	err = primary.makeBlock(ctx)
+	if err == nil {
+		t.Fatal("primary committed a block without a quorum")
+	}
+	pending, err := primary.PendingStore.GetPendingBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// A standby that doesn't take over the pending block
+	// generates a different one, which the first signer
+	// refuses to sign at the same height.
+	standby := New(c, []BlockSigner{signed, up}, nil)
+	standby.PendingStore = NewFileBlockStore(filepath.Join(dir, "standby"))
+	standby.pool = append(standby.pool, bctest.NewIssuanceTx(t, initial))
+	err = standby.makeBlock(ctx)
+	if err == nil || c.Height() != height {
+		t.Fatalf("standby without pending block: err = %v, height %d, want error and height %d", err, c.Height(), height)
+	}
+
+	// A standby that takes over the pending block commits it.
+	standby = New(c, []BlockSigner{signed, up}, nil)
+	standby.PendingStore = NewFileBlockStore(filepath.Join(dir, "adopted"))
+	err = standby.PendingStore.SavePendingBlock(ctx, pending)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = standby.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	b, err := c.GetBlock(ctx, height+1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if b.Hash() != pending.Hash() {
+		t.Errorf("committed block %x, want pending block %x", b.Hash().Bytes(), pending.Hash().Bytes())
+	}
+}
+
+// heightLockSigner signs at most one block at each height,
+// as a blocksigner does.
+type heightLockSigner struct {
+	testSigner
+
+	mu     sync.Mutex
+	signed map[uint64]bc.Hash
+}
+
+func (s *heightLockSigner) SignBlock(ctx context.Context, marshalledBlock []byte) ([]byte, error) {
+	var b legacy.Block
+	err := b.UnmarshalText(marshalledBlock)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.signed[b.Height]
+	if ok && h != b.Hash() {
+		return nil, fmt.Errorf("already signed a different block at height %d", b.Height)
+	}
+	if s.signed == nil {
+		s.signed = make(map[uint64]bc.Hash)
+	}
+	s.signed[b.Height] = b.Hash()
+	return s.testSigner.SignBlock(ctx, marshalledBlock)
+}
+
 type testSigner struct {
 	before  func() error
 	pubKey  ed25519.PublicKey
@@ -188,3 +285,97 @@ func (s testSigner) SignBlock(ctx context.Context, marshalledBlock []byte) ([]by
 func (s testSigner) String() string {
 	return "test-signer"
 }
+
+func TestSubmitExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+	g.MaxPendingBlocks = 2
+
+	// A transaction whose max time has passed expires on submission.
+	tx := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: 1})
+	err := g.Submit(ctx, tx)
+	if errors.Root(err) != ErrExpired {
+		t.Errorf("Submit(expired tx) = %v, want %v", err, ErrExpired)
+	}
+	st := g.GetTxStatus(tx.ID)
+	if st.Status != "expired" || st.RebuildMaxTimeMS <= st.RebuildMinTimeMS {
+		t.Errorf("GetTxStatus(expired tx) = %+v, want expired with a rebuild window", st)
+	}
+
+	// A transaction that waits too many blocks expires
+	// when it is resubmitted.
+	tx = legacy.NewTx(legacy.TxData{Version: 1, ReferenceData: []byte("wait")})
+	err = g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if st := g.GetTxStatus(tx.ID); st.Status != "pending" {
+		t.Errorf("GetTxStatus(pending tx) = %+v, want pending", st)
+	}
+	for i := 0; i < 3; i++ {
+		prottest.MakeBlock(t, c, nil)
+	}
+	g.mu.Lock()
+	g.pool = nil
+	g.poolHashes = make(map[bc.Hash]bool)
+	g.mu.Unlock()
+
+	err = g.Submit(ctx, tx)
+	if errors.Root(err) != ErrExpired {
+		t.Errorf("Submit(stale tx) = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestSubmitExpiryVirtualTime(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+	clk := clock.NewFake(time.Unix(1000, 0))
+	g.SetClock(clk)
+
+	maxTime := bc.Millis(clk.Now().Add(time.Minute))
+	tx := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: maxTime})
+	err := g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	clk.Advance(2 * time.Minute)
+	g.mu.Lock()
+	g.pool = nil
+	g.poolHashes = make(map[bc.Hash]bool)
+	g.mu.Unlock()
+
+	err = g.Submit(ctx, tx)
+	if errors.Root(err) != ErrExpired {
+		t.Errorf("Submit(tx past max time) = %v, want %v", err, ErrExpired)
+	}
+	st := g.GetTxStatus(tx.ID)
+	if st.RebuildMinTimeMS != bc.Millis(clk.Now()) {
+		t.Errorf("rebuild min time = %d, want the virtual time %d", st.RebuildMinTimeMS, bc.Millis(clk.Now()))
+	}
+}
+
+func TestSubmitTooLarge(t *testing.T) {
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+	tx := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+
+	weight, err := tx.Weight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.MaxTxWeight = weight - 1
+	err = g.Submit(context.Background(), tx)
+	if errors.Root(err) != ErrTooLarge {
+		t.Errorf("got error %v, want %s", err, ErrTooLarge)
+	}
+
+	g.MaxTxWeight = weight
+	err = g.Submit(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+// A BlockStore persists the block the generator has generated
+// but not yet committed. A generator that restarts before
+// committing a block commits the same block, rather than
+// generating a different one at the same height.
+type BlockStore interface {
+	// GetPendingBlock returns the pending
+	// block, or nil if there is none.
+	GetPendingBlock(ctx context.Context) (*legacy.Block, error)
+
+	// SavePendingBlock durably saves b as the pending block,
+	// before the generator asks signers to sign it. If the
+	// saved block's height is b's or higher, it returns
+	// errDuplicateBlock and keeps the saved block.
+	SavePendingBlock(ctx context.Context, b *legacy.Block) error
+}
+
+// NewDBBlockStore returns the BlockStore New uses,
+// which keeps the pending block in db.
+func NewDBBlockStore(db pg.DB) BlockStore {
+	return pgBlockStore{db}
+}
+
+// pgBlockStore keeps the pending block in Postgres.
+type pgBlockStore struct {
+	db pg.DB
+}
+
+func (s pgBlockStore) GetPendingBlock(ctx context.Context) (*legacy.Block, error) {
+	return getPendingBlock(ctx, s.db)
+}
+
+func (s pgBlockStore) SavePendingBlock(ctx context.Context, b *legacy.Block) error {
+	return savePendingBlock(ctx, s.db, b)
+}
+
+// FileBlockStore is a BlockStore that keeps the pending block
+// in a file, for a generator whose blocks aren't in Postgres.
+// It replaces the file atomically, so a crash leaves either the
+// old block or the new one. Only one process may use the file,
+// and no other process may take over as generator: it wouldn't
+// see the pending block, and would generate a different one at
+// the same height.
+// A generator switched between stores loses a block pending in
+// the old one, so switch only while no block is pending.
+type FileBlockStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileBlockStore returns a FileBlockStore
+// that keeps the pending block in the file at path.
+func NewFileBlockStore(path string) *FileBlockStore {
+	return &FileBlockStore{path: path}
+}
+
+// GetPendingBlock implements BlockStore.
+func (s *FileBlockStore) GetPendingBlock(ctx context.Context) (*legacy.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *FileBlockStore) read() (*legacy.Block, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading pending block file")
+	}
+	b := new(legacy.Block)
+	err = b.Scan(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding pending block file")
+	}
+	return b, nil
+}
+
+// SavePendingBlock implements BlockStore.
+func (s *FileBlockStore) SavePendingBlock(ctx context.Context, b *legacy.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved, err := s.read()
+	if err != nil {
+		return err
+	}
+	if saved != nil && saved.Height >= b.Height {
+		return errDuplicateBlock
+	}
+	data, err := b.Value()
+	if err != nil {
+		return errors.Wrap(err, "encoding pending block")
+	}
+	return errors.Wrap(writeFileSync(s.path, data.([]byte)), "writing pending block file")
+}
+
+// writeFileSync replaces the file at path with data, atomically
+// and durably: it writes and syncs a temp file, renames it over
+// path, then syncs the directory so the rename survives a crash.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chain/testutil"
+)
+
+func TestFileBlockStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "pending")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewFileBlockStore(filepath.Join(dir, "pending-block"))
+
+	b, err := s.GetPendingBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if b != nil {
+		t.Fatalf("pending block before save = %v, want nil", b)
+	}
+
+	err = s.SavePendingBlock(ctx, fakeBlock(100))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Saving another block at the same height or lower should error.
+	err = s.SavePendingBlock(ctx, fakeBlock(20))
+	if err != errDuplicateBlock {
+		t.Errorf("got %s, want %s", err, errDuplicateBlock)
+	}
+	err = s.SavePendingBlock(ctx, fakeBlock(100))
+	if err != errDuplicateBlock {
+		t.Errorf("got %s, want %s", err, errDuplicateBlock)
+	}
+
+	// Saving a higher block should succeed, and a new
+	// store on the same file should recover it.
+	err = s.SavePendingBlock(ctx, fakeBlock(101))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	b, err = NewFileBlockStore(filepath.Join(dir, "pending-block")).GetPendingBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if b == nil || b.Height != 101 {
+		t.Errorf("recovered pending block %v, want height 101", b)
+	}
+}
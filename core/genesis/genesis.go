@@ -0,0 +1,224 @@
+// Package genesis implements a ceremony in which the block
+// signers of a new blockchain agree on its initial block.
+//
+// One participant proposes the initial block, built from every
+// signer's block-signing public key, the quorum, and a timestamp.
+// Each signer checks the proposal offline, by rebuilding the
+// initial block from its parameters, and signs it with the key
+// it contributed. Once every signer has signed, the proposal and
+// signatures form a Transcript, which anyone can verify later to
+// confirm that the blockchain's initial block is the one its
+// signers agreed on.
+package genesis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	// ErrBadProposal is returned when a proposal's initial
+	// block doesn't match its parameters, or its parameters
+	// are invalid.
+	ErrBadProposal = errors.New("invalid genesis proposal")
+
+	// ErrBadSignature is returned for a signature that isn't
+	// a valid signature of the proposal by one of its keys.
+	ErrBadSignature = errors.New("invalid genesis signature")
+
+	// ErrMissingSignature is returned when a key in a
+	// proposal has not signed it.
+	ErrMissingSignature = errors.New("missing genesis signature")
+)
+
+// messagePrefix separates proposal signatures
+// from signatures made for other purposes.
+const messagePrefix = "chain genesis ceremony v1\x00"
+
+// Proposal is a proposed initial block and
+// the parameters it was built from.
+type Proposal struct {
+	// Pubkeys are the block-signing keys of the blockchain's
+	// signers, in the order they appear in the consensus
+	// program.
+	Pubkeys             []chainjson.HexBytes `json:"pubkeys"`
+	Quorum              int                  `json:"quorum"`
+	TimestampMS         uint64               `json:"timestamp_ms"`
+	MaxIssuanceWindowMS uint64               `json:"max_issuance_window_ms"`
+	InitialBlock        *legacy.Block        `json:"initial_block"`
+}
+
+// Signature is a signer's signature of a proposal.
+type Signature struct {
+	Pubkey    chainjson.HexBytes `json:"pubkey"`
+	Signature chainjson.HexBytes `json:"signature"`
+}
+
+// Transcript is a proposal signed by all of its keys.
+type Transcript struct {
+	Proposal   *Proposal    `json:"proposal"`
+	Signatures []*Signature `json:"signatures"`
+}
+
+// NewProposal builds a proposal for an initial block
+// requiring quorum signatures from pubkeys.
+func NewProposal(pubkeys []ed25519.PublicKey, quorum int, timestamp time.Time, maxIssuanceWindow time.Duration) (*Proposal, error) {
+	p := &Proposal{
+		Quorum:              quorum,
+		TimestampMS:         bc.Millis(timestamp),
+		MaxIssuanceWindowMS: bc.DurationMillis(maxIssuanceWindow),
+	}
+	for _, pub := range pubkeys {
+		p.Pubkeys = append(p.Pubkeys, chainjson.HexBytes(pub))
+	}
+	err := p.checkParams()
+	if err != nil {
+		return nil, err
+	}
+	p.InitialBlock, err = p.buildBlock()
+	if err != nil {
+		return nil, errors.Sub(ErrBadProposal, err)
+	}
+	return p, nil
+}
+
+// Verify checks that p's initial block is
+// the one built from its parameters.
+func (p *Proposal) Verify() error {
+	err := p.checkParams()
+	if err != nil {
+		return err
+	}
+	if p.InitialBlock == nil {
+		return errors.WithDetail(ErrBadProposal, "no initial block")
+	}
+	want, err := p.buildBlock()
+	if err != nil {
+		return errors.Sub(ErrBadProposal, err)
+	}
+	if p.InitialBlock.Hash() != want.Hash() {
+		return errors.WithDetail(ErrBadProposal, "initial block doesn't match the proposal's parameters")
+	}
+	return nil
+}
+
+func (p *Proposal) checkParams() error {
+	if len(p.Pubkeys) == 0 {
+		return errors.WithDetail(ErrBadProposal, "no public keys")
+	}
+	if p.Quorum < 1 || p.Quorum > len(p.Pubkeys) {
+		return errors.WithDetailf(ErrBadProposal, "quorum %d out of range for %d keys", p.Quorum, len(p.Pubkeys))
+	}
+	for i, pub := range p.Pubkeys {
+		if len(pub) != ed25519.PublicKeySize {
+			return errors.WithDetailf(ErrBadProposal, "public key %d has length %d", i, len(pub))
+		}
+		for _, other := range p.Pubkeys[:i] {
+			if bytes.Equal(pub, other) {
+				return errors.WithDetailf(ErrBadProposal, "duplicate public key %x", []byte(pub))
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Proposal) buildBlock() (*legacy.Block, error) {
+	var pubkeys []ed25519.PublicKey
+	for _, pub := range p.Pubkeys {
+		pubkeys = append(pubkeys, ed25519.PublicKey(pub))
+	}
+	// NewInitialBlock takes a time.Time, but only
+	// its milliseconds end up in the block.
+	ts := time.Unix(0, int64(p.TimestampMS)*int64(time.Millisecond))
+	return protocol.NewInitialBlock(pubkeys, p.Quorum, ts)
+}
+
+// Message returns the message each signer signs: a hash
+// of the initial block's ID and the maximum issuance window,
+// the chain configuration the block doesn't commit to.
+func (p *Proposal) Message() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(messagePrefix)
+	id := p.InitialBlock.Hash()
+	buf.Write(id.Bytes())
+	var window [8]byte
+	binary.BigEndian.PutUint64(window[:], p.MaxIssuanceWindowMS)
+	buf.Write(window[:])
+
+	msg := make([]byte, 32)
+	sha3pool.Sum256(msg, buf.Bytes())
+	return msg
+}
+
+// Sign verifies p and signs it with priv,
+// whose public key must be one of p's keys.
+func Sign(p *Proposal, priv ed25519.PrivateKey) (*Signature, error) {
+	err := p.Verify()
+	if err != nil {
+		return nil, err
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	if p.keyIndex(pub) < 0 {
+		return nil, errors.WithDetailf(ErrBadSignature, "key %x is not in the proposal", []byte(pub))
+	}
+	return &Signature{
+		Pubkey:    chainjson.HexBytes(pub),
+		Signature: ed25519.Sign(priv, p.Message()),
+	}, nil
+}
+
+func (p *Proposal) keyIndex(pub []byte) int {
+	for i, k := range p.Pubkeys {
+		if bytes.Equal(k, pub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Finalize verifies p and sigs, which must include a signature
+// by each of p's keys, and returns the resulting transcript,
+// with its signatures in the order of p's keys.
+func Finalize(p *Proposal, sigs []*Signature) (*Transcript, error) {
+	err := p.Verify()
+	if err != nil {
+		return nil, err
+	}
+	ordered := make([]*Signature, len(p.Pubkeys))
+	msg := p.Message()
+	for _, sig := range sigs {
+		i := p.keyIndex(sig.Pubkey)
+		if i < 0 {
+			return nil, errors.WithDetailf(ErrBadSignature, "key %x is not in the proposal", []byte(sig.Pubkey))
+		}
+		if !ed25519.Verify(ed25519.PublicKey(sig.Pubkey), msg, sig.Signature) {
+			return nil, errors.WithDetailf(ErrBadSignature, "bad signature by key %x", []byte(sig.Pubkey))
+		}
+		ordered[i] = sig
+	}
+	for i, sig := range ordered {
+		if sig == nil {
+			return nil, errors.WithDetailf(ErrMissingSignature, "no signature by key %x", []byte(p.Pubkeys[i]))
+		}
+	}
+	return &Transcript{Proposal: p, Signatures: ordered}, nil
+}
+
+// Verify checks that t's proposal is valid and
+// signed by each of its keys.
+func (t *Transcript) Verify() error {
+	if t.Proposal == nil {
+		return errors.WithDetail(ErrBadProposal, "no proposal")
+	}
+	_, err := Finalize(t.Proposal, t.Signatures)
+	return err
+}
@@ -0,0 +1,135 @@
+package genesis
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestCeremony(t *testing.T) {
+	var (
+		pubs  []ed25519.PublicKey
+		privs []ed25519.PrivateKey
+	)
+	for i := 0; i < 3; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, pub)
+		privs = append(privs, priv)
+	}
+	p, err := NewProposal(pubs, 2, time.Now(), time.Hour)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Each signer receives the proposal
+	// as JSON, checks it, and signs it.
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sigs []*Signature
+	for i := len(privs) - 1; i >= 0; i-- {
+		var received Proposal
+		err = json.Unmarshal(b, &received)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := Sign(&received, privs[i])
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	_, err = Finalize(p, sigs[:2])
+	if errors.Root(err) != ErrMissingSignature {
+		t.Errorf("Finalize with 2 of 3 signatures: err = %v, want %v", err, ErrMissingSignature)
+	}
+	tr, err := Finalize(p, sigs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for i, sig := range tr.Signatures {
+		if !testutil.DeepEqual([]byte(sig.Pubkey), []byte(pubs[i])) {
+			t.Errorf("signature %d is by %x, want %x", i, []byte(sig.Pubkey), []byte(pubs[i]))
+		}
+	}
+
+	b, err = json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Transcript
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = got.Verify()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Changing the proposal's parameters
+	// invalidates the transcript.
+	got.Proposal.MaxIssuanceWindowMS++
+	err = got.Verify()
+	if errors.Root(err) != ErrBadSignature {
+		t.Errorf("Verify with changed issuance window: err = %v, want %v", err, ErrBadSignature)
+	}
+	got.Proposal.MaxIssuanceWindowMS--
+	got.Proposal.Quorum = 1
+	err = got.Verify()
+	if errors.Root(err) != ErrBadProposal {
+		t.Errorf("Verify with changed quorum: err = %v, want %v", err, ErrBadProposal)
+	}
+}
+
+func TestProposalVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewProposal([]ed25519.PublicKey{pub, pub}, 1, time.Now(), time.Hour)
+	if errors.Root(err) != ErrBadProposal {
+		t.Errorf("duplicate keys: err = %v, want %v", err, ErrBadProposal)
+	}
+	_, err = NewProposal([]ed25519.PublicKey{pub}, 2, time.Now(), time.Hour)
+	if errors.Root(err) != ErrBadProposal {
+		t.Errorf("quorum above key count: err = %v, want %v", err, ErrBadProposal)
+	}
+
+	p, err := NewProposal([]ed25519.PublicKey{pub}, 1, time.Now(), time.Hour)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	p.InitialBlock.TimestampMS++
+	_, err = Sign(p, priv)
+	if errors.Root(err) != ErrBadProposal {
+		t.Errorf("Sign with altered block: err = %v, want %v", err, ErrBadProposal)
+	}
+
+	p.InitialBlock.TimestampMS--
+	other, otherPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Sign(p, otherPriv)
+	if errors.Root(err) != ErrBadSignature {
+		t.Errorf("Sign with key %x not in proposal: err = %v, want %v", []byte(other), err, ErrBadSignature)
+	}
+	_, err = Finalize(p, []*Signature{{Pubkey: []byte(pub), Signature: make([]byte, 64)}})
+	if errors.Root(err) != ErrBadSignature {
+		t.Errorf("Finalize with bad signature: err = %v, want %v", err, ErrBadSignature)
+	}
+	if p.InitialBlock.Hash() == (bc.Hash{}) {
+		t.Error("proposal has no initial block")
+	}
+}
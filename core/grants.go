@@ -32,6 +32,10 @@ var (
 	// errCreateProtectedGrant is returned when a createGrant request is called with
 	// a protected grant.
 	errCreateProtectedGrant = errors.New("cannot manually create a protected grant")
+
+	// errApproverGrant is returned when a createGrant request for the
+	// client-approver policy doesn't come from an internal caller.
+	errApproverGrant = errors.New("only an internal caller can grant the client-approver policy")
 )
 
 // extraGrantLoader is an authz.Loader that wraps loader with extra grants.
@@ -97,6 +101,13 @@ func (a *API) createGrant(ctx context.Context, x apiGrant) (*apiGrant, error) {
 		return nil, errors.WithDetail(httpjson.ErrBadRequest, "invalid policy: "+x.Policy)
 	}
 
+	// Approvers check the transactions and policy overrides of
+	// client-readwrite callers, so those callers must not be
+	// able to make themselves approvers.
+	if x.Policy == "client-approver" && !authz.HasPolicy(ctx, "internal") {
+		return nil, errApproverGrant
+	}
+
 	if x.GuardType == "access_token" {
 		if id, _ := x.GuardData["id"].(string); !a.accessTokens.Exists(ctx, id) {
 			return nil, errMissingTokenID
@@ -8,6 +8,7 @@ import (
 	"chain/core/accesstoken"
 	"chain/database/pg/pgtest"
 	"chain/database/sinkdb/sinkdbtest"
+	"chain/errors"
 	"chain/net/http/authz"
 )
 
@@ -125,6 +126,45 @@ func TestCreatGrantValidation(t *testing.T) {
 	}
 }
 
+func TestCreateApproverGrant(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+
+	accessTokens := &accesstoken.CredentialStore{DB: db}
+	_, err := accessTokens.Create(ctx, "test-token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sdb := sinkdbtest.NewDB(t)
+	api := &API{
+		mux:          http.NewServeMux(),
+		sdb:          sdb,
+		accessTokens: accessTokens,
+		grants:       authz.NewStore(sdb, GrantPrefix),
+	}
+
+	grant := apiGrant{
+		GuardType: "access_token",
+		GuardData: map[string]interface{}{"id": "test-token"},
+		Policy:    "client-approver",
+	}
+	cases := []struct {
+		policies []string
+		want     error
+	}{
+		{nil, errApproverGrant},
+		{[]string{"client-readwrite"}, errApproverGrant},
+		{[]string{"client-readwrite", "internal"}, nil},
+	}
+	for _, c := range cases {
+		_, err := api.createGrant(authz.NewContext(ctx, c.policies), grant)
+		if errors.Root(err) != c.want {
+			t.Errorf("createGrant(client-approver) by %v = %v, want %v", c.policies, err, c.want)
+		}
+	}
+}
+
 func TestDeleteGrants(t *testing.T) {
 	ctx := context.Background()
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+)
+
+// blockHeader is a block header with the signatures in its
+// witness matched to the keys of the consensus program they
+// satisfy, the previous block's.
+type blockHeader struct {
+	Height           uint64        `json:"height"`
+	ID               bc.Hash       `json:"id"`
+	PreviousBlockID  bc.Hash       `json:"previous_block_id"`
+	Timestamp        time.Time     `json:"timestamp"`
+	ConsensusProgram json.HexBytes `json:"consensus_program"`
+
+	// Quorum and Signers describe the previous block's
+	// consensus program. They are empty for the initial
+	// block, which is unsigned.
+	Quorum  int           `json:"quorum"`
+	Signers []blockSigner `json:"signers"`
+
+	// SignatureCount is the number of signatures
+	// in the block's witness.
+	SignatureCount int `json:"signature_count"`
+}
+
+type blockSigner struct {
+	Pubkey json.HexBytes `json:"pubkey"`
+
+	// SignerURL is the URL of the configured block signer
+	// holding this key, if there is one.
+	SignerURL string `json:"signer_url,omitempty"`
+
+	Signed    bool          `json:"signed"`
+	Signature json.HexBytes `json:"signature,omitempty"`
+}
+
+// listBlockHeaders is an http handler for listing block headers
+// in ascending order of height, with details of who signed each
+// block, so monitoring systems can notice a federation member
+// that stops signing.
+//
+// Without an `after` cursor, the list starts at
+// start_block_height, or at the latest block. With
+// ascending_with_long_poll, it waits up to timeout for
+// the next block when there are no more.
+//
+// POST /list-block-headers
+func (a *API) listBlockHeaders(ctx context.Context, in requestQuery) (result page, err error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+
+	var after uint64
+	switch {
+	case in.After != "":
+		after, err = strconv.ParseUint(in.After, 10, 64)
+		if err != nil {
+			return result, errors.WithDetail(httpjson.ErrBadRequest, "invalid `after` cursor")
+		}
+	case in.StartHeight > 0:
+		after = in.StartHeight - 1
+	case a.chain.Height() > 0:
+		after = a.chain.Height() - 1
+	}
+
+	if in.AscLongPoll && a.chain.Height() <= after {
+		timeout := in.Timeout.Duration
+		if timeout == 0 {
+			timeout = time.Minute
+		}
+		select {
+		case <-a.chain.BlockWaiter(after + 1):
+		case <-time.After(timeout):
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+
+	var (
+		headers = make([]*blockHeader, 0, limit)
+		prev    *legacy.Block
+		height  = a.chain.Height()
+	)
+	if after > 0 && after < height {
+		prev, err = a.chain.GetBlock(ctx, after)
+		if err != nil {
+			return result, errors.Wrapf(err, "getting block %d", after)
+		}
+	}
+	for h := after + 1; h <= height && len(headers) < limit; h++ {
+		b, err := a.chain.GetBlock(ctx, h)
+		if err != nil {
+			return result, errors.Wrapf(err, "getting block %d", h)
+		}
+		header, err := a.decodeBlockHeader(prev, b)
+		if err != nil {
+			return result, errors.Wrapf(err, "decoding block %d", h)
+		}
+		headers = append(headers, header)
+		prev = b
+	}
+
+	out := in
+	if len(headers) > 0 {
+		out.After = strconv.FormatUint(headers[len(headers)-1].Height, 10)
+	} else {
+		out.After = strconv.FormatUint(after, 10)
+	}
+	return page{
+		Items:    httpjson.Array(headers),
+		LastPage: len(headers) < limit,
+		Next:     out,
+	}, nil
+}
+
+// decodeBlockHeader matches the signatures in b's witness to
+// the keys of prev's consensus program. Prev is nil only if
+// b is the initial block.
+func (a *API) decodeBlockHeader(prev, b *legacy.Block) (*blockHeader, error) {
+	header := &blockHeader{
+		Height:           b.Height,
+		ID:               b.Hash(),
+		PreviousBlockID:  b.PreviousBlockHash,
+		Timestamp:        b.Time(),
+		ConsensusProgram: b.ConsensusProgram,
+		Signers:          []blockSigner{},
+		SignatureCount:   len(b.Witness),
+	}
+	if prev == nil {
+		return header, nil
+	}
+
+	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prev.ConsensusProgram)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing consensus program")
+	}
+	header.Quorum = quorum
+	for _, pub := range pubkeys {
+		s := blockSigner{Pubkey: json.HexBytes(pub)}
+		for _, signer := range a.config.Signers {
+			if bytes.Equal(signer.Pubkey, pub) {
+				s.SignerURL = signer.Url
+				break
+			}
+		}
+		header.Signers = append(header.Signers, s)
+	}
+	for _, sig := range b.Witness {
+		for i, pub := range pubkeys {
+			if !header.Signers[i].Signed && ed25519.Verify(pub, header.ID.Bytes(), sig) {
+				header.Signers[i].Signed = true
+				header.Signers[i].Signature = sig
+				break
+			}
+		}
+	}
+	return header, nil
+}
+
+// getBlockSigners is an http handler reporting, for a block at any
+// height, the consensus program the block had to satisfy, its keys
+// and quorum, and which of the keys signed the block, for audits
+// of the federation's behavior over time. It is computed from the
+// stored blocks. Without block_height, it reports on the latest block.
+//
+// POST /get-block-signers
+func (a *API) getBlockSigners(ctx context.Context, in struct {
+	BlockHeight uint64 `json:"block_height"`
+}) (x struct {
+	BlockHeight uint64  `json:"block_height"`
+	BlockID     bc.Hash `json:"block_id"`
+
+	// ConsensusProgram is the previous block's consensus
+	// program, the one in force for this block. It is empty
+	// for the initial block, which is unsigned.
+	ConsensusProgram json.HexBytes `json:"consensus_program"`
+
+	Quorum  int           `json:"quorum"`
+	Signers []blockSigner `json:"signers"`
+}, err error) {
+	height := a.chain.Height()
+	if height == 0 {
+		return x, errors.Wrap(errUnconfigured, "no blocks")
+	}
+	if in.BlockHeight > height {
+		return x, errors.WithDetailf(httpjson.ErrBadRequest, "block height %d is beyond this core's height %d", in.BlockHeight, height)
+	}
+	if in.BlockHeight > 0 {
+		height = in.BlockHeight
+	}
+
+	b, err := a.chain.GetBlock(ctx, height)
+	if err != nil {
+		return x, errors.Wrapf(err, "getting block %d", height)
+	}
+	var prev *legacy.Block
+	if height > 1 {
+		prev, err = a.chain.GetBlock(ctx, height-1)
+		if err != nil {
+			return x, errors.Wrapf(err, "getting block %d", height-1)
+		}
+		x.ConsensusProgram = prev.ConsensusProgram
+	}
+	header, err := a.decodeBlockHeader(prev, b)
+	if err != nil {
+		return x, errors.Wrapf(err, "decoding block %d", height)
+	}
+
+	x.BlockHeight = header.Height
+	x.BlockID = header.ID
+	x.Quorum = header.Quorum
+	x.Signers = header.Signers
+	return x, nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"chain/core/config"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+func TestDecodeBlockHeader(t *testing.T) {
+	var (
+		pubs  []ed25519.PublicKey
+		privs []ed25519.PrivateKey
+	)
+	for i := 0; i < 3; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, pub)
+		privs = append(privs, priv)
+	}
+	prog, err := vmutil.BlockMultiSigProgram(pubs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 1}}
+	prev.ConsensusProgram = prog
+	b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, PreviousBlockHash: prev.Hash()}}
+	b.ConsensusProgram = prog
+	hash := b.Hash()
+	b.Witness = [][]byte{
+		ed25519.Sign(privs[0], hash.Bytes()),
+		ed25519.Sign(privs[2], hash.Bytes()),
+	}
+
+	a := &API{config: &config.Config{Signers: []*config.BlockSigner{{Pubkey: pubs[2], Url: "https://signer.example.com"}}}}
+	got, err := a.decodeBlockHeader(prev, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Quorum != 2 || got.SignatureCount != 2 || len(got.Signers) != 3 {
+		t.Fatalf("decodeBlockHeader = %+v, want quorum 2, 2 signatures, 3 signers", got)
+	}
+	for i, want := range []bool{true, false, true} {
+		if got.Signers[i].Signed != want {
+			t.Errorf("signer %d signed = %v, want %v", i, got.Signers[i].Signed, want)
+		}
+	}
+	if got.Signers[2].SignerURL != "https://signer.example.com" {
+		t.Errorf("signer 2 URL = %q, want configured signer's", got.Signers[2].SignerURL)
+	}
+
+	got, err = a.decodeBlockHeader(nil, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Quorum != 0 || len(got.Signers) != 0 {
+		t.Errorf("initial block header = %+v, want no signers", got)
+	}
+}
+
+func TestGetBlockSigners(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t, prottest.WithBlockSigners(2, 3))
+	_, privs := prottest.BlockKeyPairs(c)
+	initial := prottest.Initial(t, c)
+	b2 := makeSignedBlock(t, c, privs[0], privs[2])
+	b3 := makeSignedBlock(t, c, privs[1])
+
+	a := &API{chain: c, config: &config.Config{}}
+	cases := []struct {
+		height     uint64
+		wantHeight uint64
+		wantID     bc.Hash
+		wantProg   []byte
+		wantQuorum int
+		wantSigned []bool
+	}{
+		{1, 1, initial.Hash(), nil, 0, nil},
+		{2, 2, b2.Hash(), initial.ConsensusProgram, 2, []bool{true, false, true}},
+		{0, 3, b3.Hash(), b2.ConsensusProgram, 2, []bool{false, true, false}},
+	}
+	for _, test := range cases {
+		got, err := a.getBlockSigners(ctx, struct {
+			BlockHeight uint64 `json:"block_height"`
+		}{test.height})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.BlockHeight != test.wantHeight || got.BlockID != test.wantID {
+			t.Errorf("height %d: got block %d %x, want %d %x", test.height, got.BlockHeight, got.BlockID.Bytes(), test.wantHeight, test.wantID.Bytes())
+		}
+		if !bytes.Equal(got.ConsensusProgram, test.wantProg) || got.Quorum != test.wantQuorum {
+			t.Errorf("height %d: got program %x quorum %d, want %x quorum %d", test.height, got.ConsensusProgram, got.Quorum, test.wantProg, test.wantQuorum)
+		}
+		if len(got.Signers) != len(test.wantSigned) {
+			t.Errorf("height %d: got %d signers, want %d", test.height, len(got.Signers), len(test.wantSigned))
+			continue
+		}
+		for i, want := range test.wantSigned {
+			if got.Signers[i].Signed != want {
+				t.Errorf("height %d: signer %d signed = %v, want %v", test.height, i, got.Signers[i].Signed, want)
+			}
+		}
+	}
+
+	_, err := a.getBlockSigners(ctx, struct {
+		BlockHeight uint64 `json:"block_height"`
+	}{4})
+	if errors.Root(err) != httpjson.ErrBadRequest {
+		t.Errorf("height 4: err = %v, want %v", err, httpjson.ErrBadRequest)
+	}
+}
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"chain/core/account"
+	"chain/core/query"
+)
+
 // healthSetter returns a function that, when called,
 // sets the named health status in the map returned by "/health".
 // The returned function is safe to call concurrently with ServeHTTP.
@@ -22,8 +27,40 @@ func (a *API) setHealth(name string, err error) {
 
 func (a *API) health() (x struct {
 	Errors map[string]string `json:"errors"`
+
+	// Reaper reports the work of the UTXO reservation reaper.
+	Reaper *account.ReaperStats `json:"reservation_reaper,omitempty"`
+
+	// Canary reports the canary transactions, if enabled.
+	Canary *canaryStats `json:"canary,omitempty"`
+
+	// Shadow reports the comparisons of a shadow
+	// Core with the production Core, if enabled.
+	Shadow *shadowStats `json:"shadow,omitempty"`
+
+	// Reindex reports the latest reindex of
+	// annotated transactions, if there was one.
+	Reindex *query.ReindexStats `json:"reindex,omitempty"`
 }) {
 	x.Errors = make(map[string]string)
+	if a.accounts != nil {
+		stats := a.accounts.ReaperStats()
+		x.Reaper = &stats
+	}
+	if a.canary != nil {
+		stats := a.canary.snapshot()
+		x.Canary = &stats
+	}
+	if a.shadow != nil {
+		stats := a.shadow.snapshot()
+		x.Shadow = &stats
+	}
+	if a.indexer != nil {
+		stats := a.indexer.ReindexStats()
+		if !stats.StartedAt.IsZero() {
+			x.Reindex = &stats
+		}
+	}
 
 	if err := a.sdb.RaftService().Err(); err != nil {
 		x.Errors["raft"] = err.Error()
@@ -4,10 +4,13 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 
 	"chain/core/mockhsm"
 	"chain/core/txbuilder"
 	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/net/http/httperror"
 	"chain/net/http/httpjson"
 )
@@ -16,13 +19,19 @@ func init() {
 	errorFormatter.Errors[mockhsm.ErrDuplicateKeyAlias] = httperror.Info{400, "CH050", "Alias already exists"}
 	errorFormatter.Errors[mockhsm.ErrInvalidAfter] = httperror.Info{400, "CH801", "Invalid `after` in query"}
 	errorFormatter.Errors[mockhsm.ErrTooManyAliasesToList] = httperror.Info{400, "CH802", "Too many aliases to list"}
+	errorFormatter.Errors[mockhsm.ErrBadPassphrase] = httperror.Info{400, "CH803", "Wrong passphrase or corrupt key data"}
+	errorFormatter.Errors[mockhsm.ErrInvalidKeyType] = httperror.Info{400, "CH804", "Invalid key type"}
+	errorFormatter.Errors[mockhsm.ErrInvalidKeySize] = httperror.Info{400, "CH805", "Invalid key size"}
+	errorFormatter.Errors[mockhsm.ErrNotRotatable] = httperror.Info{400, "CH806", "Key store is not encrypted"}
+	errorFormatter.Errors[mockhsm.ErrNoKey] = httperror.Info{404, "CH807", "Key not found"}
+	errorFormatter.Errors[mockhsm.ErrKeyMismatch] = httperror.Info{400, "CH808", "Public key doesn't match private key"}
 }
 
 // MockHSM configures the Core to expose the MockHSM endpoints. It
 // is only included in non-production builds.
 func MockHSM(hsm *mockhsm.HSM) RunOption {
 	return func(a *API) {
-		h := &mockHSMHandler{MockHSM: hsm}
+		h := &mockHSMHandler{MockHSM: hsm, api: a}
 
 		needConfig := a.needConfig()
 		a.mux.Handle("/mockhsm/create-block-key", jsonHandler(h.mockhsmCreateBlockKey))
@@ -30,11 +39,15 @@ func MockHSM(hsm *mockhsm.HSM) RunOption {
 		a.mux.Handle("/mockhsm/list-keys", needConfig(h.mockhsmListKeys))
 		a.mux.Handle("/mockhsm/delkey", needConfig(h.mockhsmDelKey))
 		a.mux.Handle("/mockhsm/sign-transaction", needConfig(h.mockhsmSignTemplates))
+		a.mux.Handle("/mockhsm/export-key", jsonHandler(h.mockhsmExportKey))
+		a.mux.Handle("/mockhsm/import-key", jsonHandler(h.mockhsmImportKey))
+		a.mux.Handle("/mockhsm/rotate-keystore", jsonHandler(h.mockhsmRotateKeyStore))
 	}
 }
 
 type mockHSMHandler struct {
 	MockHSM *mockhsm.HSM
+	api     *API
 }
 
 func (h *mockHSMHandler) mockhsmCreateBlockKey(ctx context.Context) (result *mockhsm.Pub, err error) {
@@ -74,13 +87,73 @@ func (h *mockHSMHandler) mockhsmDelKey(ctx context.Context, xpub chainkd.XPub) e
 	return h.MockHSM.DeleteChainKDKey(ctx, xpub)
 }
 
+type mockhsmKey struct {
+	Type  string             `json:"type"`
+	Pub   chainjson.HexBytes `json:"pub"`
+	Alias string             `json:"alias,omitempty"`
+}
+
+type exportKeyRequest struct {
+	Type       string             `json:"type"` // chain_kd or ed25519
+	Pub        chainjson.HexBytes `json:"pub"`
+	Passphrase string             `json:"passphrase"`
+}
+
+type sealedKey struct {
+	SealedKey  json.RawMessage `json:"sealed_key"`
+	Passphrase string          `json:"passphrase,omitempty"`
+}
+
+// POST /mockhsm/export-key
+//
+// mockhsmExportKey returns a key, private part included,
+// encrypted under the given passphrase, for import into
+// another Core with /mockhsm/import-key.
+func (h *mockHSMHandler) mockhsmExportKey(ctx context.Context, in exportKeyRequest) (*sealedKey, error) {
+	if in.Passphrase == "" {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "passphrase must not be empty")
+	}
+	data, err := h.MockHSM.ExportKey(ctx, in.Type, in.Pub, in.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &sealedKey{SealedKey: data}, nil
+}
+
+// POST /mockhsm/import-key
+func (h *mockHSMHandler) mockhsmImportKey(ctx context.Context, in sealedKey) (*mockhsmKey, error) {
+	k, err := h.MockHSM.ImportKey(ctx, in.SealedKey, in.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &mockhsmKey{Type: k.Type, Pub: k.Pub, Alias: k.Alias}, nil
+}
+
+// POST /mockhsm/rotate-keystore
+//
+// mockhsmRotateKeyStore re-encrypts the key store file
+// under a new passphrase. The Core must be started with
+// the new passphrase from then on.
+func (h *mockHSMHandler) mockhsmRotateKeyStore(in struct{ Passphrase string }) error {
+	if in.Passphrase == "" {
+		return errors.WithDetail(httpjson.ErrBadRequest, "passphrase must not be empty")
+	}
+	return h.MockHSM.RotateKeyStore(in.Passphrase)
+}
+
 func (h *mockHSMHandler) mockhsmSignTemplates(ctx context.Context, x struct {
 	Txs   []*txbuilder.Template `json:"transactions"`
 	XPubs []chainkd.XPub        `json:"xpubs"`
 }) []interface{} {
 	resp := make([]interface{}, 0, len(x.Txs))
 	for _, tx := range x.Txs {
-		err := txbuilder.Sign(ctx, tx, x.XPubs, h.mockhsmSignTemplate)
+		var err error
+		if h.api != nil {
+			err = h.api.checkApproval(tx)
+		}
+		if err == nil {
+			err = txbuilder.Sign(ctx, tx, x.XPubs, h.mockhsmSignTemplate)
+		}
 		if err != nil {
 			info := errorFormatter.Format(err)
 			resp = append(resp, info)
@@ -1,12 +1,22 @@
 package core
 
 import (
+	"context"
 	"expvar"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"chain/core/account"
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/core/volume"
+	"chain/crypto/sha3pool"
 	"chain/metrics"
+	"chain/protocol"
 )
 
 var (
@@ -69,3 +79,189 @@ func countCore(id string) {
 	}
 	coresSeen[id] = true
 }
+
+// POST /get-asset-volumes
+func (a *API) getAssetVolumes(ctx context.Context) []volume.Volume {
+	return a.volume.Volumes()
+}
+
+// metricsHandler serves the per-asset volume counters, the
+// reservation reaper's totals, the progress of the block
+// processors and the transaction indexer, and the canary's
+// results, if enabled, in the Prometheus text format.
+func (a *API) metricsHandler() http.Handler {
+	reg := prometheus.NewRegistry()
+	if a.volume != nil {
+		reg.MustRegister(a.volume)
+	}
+	if a.pinStore != nil && a.chain != nil {
+		reg.MustRegister(&pinCollector{pins: a.pinStore, chain: a.chain})
+	}
+	if a.indexer != nil {
+		reg.MustRegister(&indexCollector{indexer: a.indexer})
+	}
+	if a.accounts != nil {
+		reaped := func(f func(account.ReaperStats) uint64) func() float64 {
+			return func() float64 { return float64(f(a.accounts.ReaperStats())) }
+		}
+		reg.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "chain_reservations_expired_total",
+				Help: "UTXO reservations released because they expired.",
+			}, reaped(func(s account.ReaperStats) uint64 { return s.ExpiredReservations })),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "chain_reservations_stale_total",
+				Help: "UTXO reservations released because some of their UTXOs were spent.",
+			}, reaped(func(s account.ReaperStats) uint64 { return s.StaleReservations })),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "chain_reservation_cache_stale_utxos_total",
+				Help: "Spent UTXOs dropped from the reservation caches.",
+			}, reaped(func(s account.ReaperStats) uint64 { return s.StaleUTXOs })),
+		)
+	}
+	if a.canary != nil {
+		reg.MustRegister(a.canary.collectors()...)
+	}
+	sha3 := func(f func(sha3pool.Stats) uint64) func() float64 {
+		return func() float64 { return float64(f(sha3pool.ReadStats())) }
+	}
+	reg.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_sha3pool_gets_total",
+			Help: "SHA3 hashes taken from the hash pool.",
+		}, sha3(func(s sha3pool.Stats) uint64 { return s.Gets })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_sha3pool_puts_total",
+			Help: "SHA3 hashes returned to the hash pool.",
+		}, sha3(func(s sha3pool.Stats) uint64 { return s.Puts })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_sha3pool_news_total",
+			Help: "SHA3 hashes allocated because the hash pool was empty.",
+		}, sha3(func(s sha3pool.Stats) uint64 { return s.News })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "chain_sha3pool_discards_total",
+			Help: "SHA3 hashes dropped because the hash pool was full.",
+		}, sha3(func(s sha3pool.Stats) uint64 { return s.Discards })),
+	)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+var (
+	pinHeightDesc = prometheus.NewDesc(
+		"chain_pin_height",
+		"Height of the last block the block processor has processed.",
+		[]string{"pin"}, nil,
+	)
+	pinLagBlocksDesc = prometheus.NewDesc(
+		"chain_pin_lag_blocks",
+		"Blocks the block processor is behind the blockchain.",
+		[]string{"pin"}, nil,
+	)
+	pinLagSecondsDesc = prometheus.NewDesc(
+		"chain_pin_lag_seconds",
+		"Time between the latest block and the last block the block processor has processed.",
+		[]string{"pin"}, nil,
+	)
+	pinProcessedDesc = prometheus.NewDesc(
+		"chain_pin_blocks_processed_total",
+		"Blocks the block processor has processed in this process.",
+		[]string{"pin"}, nil,
+	)
+	pinBlockSecondsDesc = prometheus.NewDesc(
+		"chain_pin_block_seconds",
+		"Moving average of the time the block processor takes to process a block.",
+		[]string{"pin"}, nil,
+	)
+)
+
+// pinCollector reports how far each block
+// processor is behind the blockchain.
+type pinCollector struct {
+	pins  *pin.Store
+	chain *protocol.Chain
+}
+
+// Describe implements prometheus.Collector.
+func (c *pinCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pinHeightDesc
+	ch <- pinLagBlocksDesc
+	ch <- pinLagSecondsDesc
+	ch <- pinProcessedDesc
+	ch <- pinBlockSecondsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *pinCollector) Collect(ch chan<- prometheus.Metric) {
+	height := c.chain.Height()
+	tipMS := c.chain.TimestampMS()
+	for _, s := range c.pins.Stats() {
+		var lag uint64
+		if height > s.Height {
+			lag = height - s.Height
+		}
+		ch <- prometheus.MustNewConstMetric(pinHeightDesc, prometheus.GaugeValue, float64(s.Height), s.Name)
+		ch <- prometheus.MustNewConstMetric(pinLagBlocksDesc, prometheus.GaugeValue, float64(lag), s.Name)
+		ch <- prometheus.MustNewConstMetric(pinProcessedDesc, prometheus.CounterValue, float64(s.Processed), s.Name)
+		ch <- prometheus.MustNewConstMetric(pinBlockSecondsDesc, prometheus.GaugeValue, s.Latency.Seconds(), s.Name)
+
+		// Only a process that processes a pin's blocks
+		// knows the timestamp of its last block.
+		if !s.Time.IsZero() {
+			var lagSeconds float64
+			if lag > 0 {
+				tip := time.Unix(0, int64(tipMS)*int64(time.Millisecond))
+				lagSeconds = tip.Sub(s.Time).Seconds()
+			}
+			ch <- prometheus.MustNewConstMetric(pinLagSecondsDesc, prometheus.GaugeValue, lagSeconds, s.Name)
+		}
+	}
+}
+
+var (
+	indexBlocksDesc = prometheus.NewDesc(
+		"chain_query_blocks_indexed_total",
+		"Blocks the transaction indexer has indexed or reindexed in this process.",
+		nil, nil,
+	)
+	indexRowsDesc = prometheus.NewDesc(
+		"chain_query_rows_written_total",
+		"Annotated rows the transaction indexer has written, by table.",
+		[]string{"table"}, nil,
+	)
+	indexSecondsDesc = prometheus.NewDesc(
+		"chain_query_index_seconds_total",
+		"Time the transaction indexer has spent indexing blocks.",
+		nil, nil,
+	)
+	annotateSecondsDesc = prometheus.NewDesc(
+		"chain_query_annotate_seconds_total",
+		"Time the transaction indexer has spent in each annotator.",
+		[]string{"annotator"}, nil,
+	)
+)
+
+// indexCollector reports the work of the transaction indexer.
+type indexCollector struct {
+	indexer *query.Indexer
+}
+
+// Describe implements prometheus.Collector.
+func (c *indexCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- indexBlocksDesc
+	ch <- indexRowsDesc
+	ch <- indexSecondsDesc
+	ch <- annotateSecondsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *indexCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.indexer.IndexStats()
+	ch <- prometheus.MustNewConstMetric(indexBlocksDesc, prometheus.CounterValue, float64(s.Blocks))
+	ch <- prometheus.MustNewConstMetric(indexRowsDesc, prometheus.CounterValue, float64(s.Txs), "annotated_txs")
+	ch <- prometheus.MustNewConstMetric(indexRowsDesc, prometheus.CounterValue, float64(s.Inputs), "annotated_inputs")
+	ch <- prometheus.MustNewConstMetric(indexRowsDesc, prometheus.CounterValue, float64(s.Outputs), "annotated_outputs")
+	ch <- prometheus.MustNewConstMetric(indexSecondsDesc, prometheus.CounterValue, s.IndexTime.Seconds())
+	for name, d := range s.AnnotateTime {
+		ch <- prometheus.MustNewConstMetric(annotateSecondsDesc, prometheus.CounterValue, d.Seconds(), name)
+	}
+}
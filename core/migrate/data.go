@@ -81,4 +81,138 @@ var migrations = []migration{
 		ALTER TABLE ONLY core_id
 			ADD CONSTRAINT core_id_pkey PRIMARY KEY (singleton);
 	`},
+	{Name: `2017-07-01.0.query.output-created-index.sql`, SQL: `
+		CREATE INDEX annotated_outputs_created_idx ON annotated_outputs USING btree (lower(timespan));
+	`},
+	{Name: `2017-07-02.0.core.tag-history.sql`, SQL: `
+		CREATE TABLE tag_history (
+			object_type text NOT NULL,
+			object_id text NOT NULL,
+			tags jsonb,
+			replaced_at timestamp without time zone DEFAULT now() NOT NULL,
+			seq bigserial NOT NULL
+		);
+		CREATE UNIQUE INDEX tag_history_seq_idx ON tag_history USING btree (seq);
+		CREATE INDEX tag_history_object_seq_idx ON tag_history USING btree (object_type, object_id, seq);
+	`},
+	{Name: `2017-07-03.0.query.output-id-pkey.sql`, SQL: `
+		ALTER TABLE annotated_outputs DROP CONSTRAINT annotated_outputs_output_id_key;
+		ALTER TABLE annotated_outputs DROP CONSTRAINT annotated_outputs_pkey;
+		ALTER TABLE ONLY annotated_outputs
+			ADD CONSTRAINT annotated_outputs_pkey PRIMARY KEY (output_id);
+		CREATE UNIQUE INDEX annotated_outputs_position_idx ON annotated_outputs USING btree (block_height, tx_pos, output_index);
+	`},
+	{Name: `2017-07-04.0.account.policies.sql`, SQL: `
+		CREATE TABLE account_policies (
+			account_id text NOT NULL,
+			version bigint NOT NULL,
+			policy jsonb NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY account_policies
+			ADD CONSTRAINT account_policies_pkey PRIMARY KEY (account_id, version);
+		CREATE TABLE account_policy_overrides (
+			account_id text NOT NULL,
+			policy_version bigint NOT NULL,
+			control_program bytea NOT NULL,
+			expires_at timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY account_policy_overrides
+			ADD CONSTRAINT account_policy_overrides_pkey PRIMARY KEY (account_id, policy_version, control_program);
+	`},
+	{Name: `2017-07-06.0.query.output-lifetime.sql`, SQL: `
+		ALTER TABLE annotated_outputs
+			ADD COLUMN spent_block_height bigint,
+			ADD COLUMN lifetime_ms bigint;
+		UPDATE annotated_outputs AS out
+			SET spent_block_height = txs.block_height, lifetime_ms = upper(out.timespan) - lower(out.timespan)
+			FROM annotated_inputs AS inp, annotated_txs AS txs
+			WHERE inp.spent_output_id = out.output_id AND txs.tx_hash = inp.tx_hash;
+		CREATE INDEX annotated_outputs_spent_block_height_idx ON annotated_outputs USING btree (spent_block_height);
+	`},
+	{Name: `2017-07-07.0.account.hot-accounts.sql`, SQL: `
+		CREATE TABLE hot_accounts (
+			account_id text NOT NULL,
+			block_height bigint NOT NULL
+		);
+		ALTER TABLE ONLY hot_accounts
+			ADD CONSTRAINT hot_accounts_pkey PRIMARY KEY (account_id);
+		CREATE INDEX hot_accounts_block_height_idx ON hot_accounts USING btree (block_height);
+		CREATE TABLE hot_account_balances (
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL
+		);
+		ALTER TABLE ONLY hot_account_balances
+			ADD CONSTRAINT hot_account_balances_pkey PRIMARY KEY (account_id, asset_id);
+	`},
+	{Name: `2017-07-08.0.query.alias-columns.sql`, SQL: `
+		ALTER TABLE annotated_inputs
+			ADD COLUMN asset_alias_at_tx text,
+			ADD COLUMN account_alias_at_tx text;
+		UPDATE annotated_inputs SET asset_alias_at_tx = asset_alias, account_alias_at_tx = account_alias;
+		ALTER TABLE annotated_inputs ALTER COLUMN asset_alias_at_tx SET NOT NULL;
+		ALTER TABLE annotated_outputs
+			ADD COLUMN asset_alias_at_tx text,
+			ADD COLUMN account_alias_at_tx text;
+		UPDATE annotated_outputs SET asset_alias_at_tx = asset_alias, account_alias_at_tx = account_alias;
+		ALTER TABLE annotated_outputs ALTER COLUMN asset_alias_at_tx SET NOT NULL;
+		CREATE INDEX annotated_inputs_asset_alias_idx ON annotated_inputs USING btree (asset_alias);
+		CREATE INDEX annotated_inputs_account_alias_idx ON annotated_inputs USING btree (account_alias);
+		CREATE INDEX annotated_outputs_asset_alias_idx ON annotated_outputs USING btree (asset_alias);
+		CREATE INDEX annotated_outputs_account_alias_idx ON annotated_outputs USING btree (account_alias);
+		CREATE TABLE alias_backfills (
+			object_type text NOT NULL,
+			object_id text NOT NULL,
+			alias text NOT NULL,
+			block_height bigint NOT NULL
+		);
+		ALTER TABLE ONLY alias_backfills
+			ADD CONSTRAINT alias_backfills_pkey PRIMARY KEY (object_type, object_id);
+	`},
+	{Name: `2017-07-09.0.core.canary-key.sql`, SQL: `
+		CREATE TABLE canary_key (
+			singleton boolean DEFAULT true NOT NULL,
+			xprv bytea NOT NULL,
+			CONSTRAINT canary_key_singleton CHECK (singleton)
+		);
+		ALTER TABLE ONLY canary_key
+			ADD CONSTRAINT canary_key_pkey PRIMARY KEY (singleton);
+	`},
+	{Name: `2017-07-10.0.account.watch-only-accounts.sql`, SQL: `
+		CREATE TABLE watch_only_accounts (
+			account_id text NOT NULL
+		);
+		ALTER TABLE ONLY watch_only_accounts
+			ADD CONSTRAINT watch_only_accounts_pkey PRIMARY KEY (account_id);
+	`},
+	{Name: `2017-07-11.0.account.holds.sql`, SQL: `
+		CREATE TABLE account_holds (
+			id text DEFAULT next_chain_id('hold'::text) NOT NULL,
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			expires_at timestamp with time zone NOT NULL,
+			reference text DEFAULT '' NOT NULL,
+			client_token text
+		);
+		ALTER TABLE ONLY account_holds
+			ADD CONSTRAINT account_holds_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY account_holds
+			ADD CONSTRAINT account_holds_client_token_key UNIQUE (client_token);
+		CREATE INDEX account_holds_account_id_asset_id_idx ON account_holds USING btree (account_id, asset_id);
+	`},
+	{Name: `2017-07-13.0.query.wash-score.sql`, SQL: `
+		ALTER TABLE annotated_txs ADD COLUMN wash_score integer DEFAULT 0 NOT NULL;
+	`},
+	{Name: `2017-07-14.0.account.vault-utxos.sql`, SQL: `
+		ALTER TABLE account_utxos ADD COLUMN vault boolean DEFAULT false NOT NULL;
+	`},
+	{Name: `2017-07-15.0.account.hold-settlements.sql`, SQL: `
+		ALTER TABLE account_holds ADD COLUMN settling_tx_id bytea;
+		CREATE INDEX account_holds_settling_tx_id_idx ON account_holds USING btree (settling_tx_id);
+	`},
+	{Name: `2017-07-16.0.query.annotated-inputs-spent-output.sql`, SQL: `
+		CREATE INDEX annotated_inputs_spent_output_id_idx ON annotated_inputs USING btree (spent_output_id);
+	`},
 }
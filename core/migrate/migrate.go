@@ -13,7 +13,7 @@ import (
 
 // Run runs all built-in migrations.
 func Run(db pg.DB) error {
-	ctx := context.Background()
+	ctx := pg.WithQueryClass(context.Background(), pg.Migration)
 
 	// Create the migrations table if not yet created.
 	_, err := db.ExecContext(ctx, createMigrationTableSQL)
@@ -21,12 +21,12 @@ func Run(db pg.DB) error {
 		return errors.Wrap(err, "creating migration table")
 	}
 
-	err = convertOldStatus(db)
+	err = convertOldStatus(ctx, db)
 	if err != nil {
 		return err
 	}
 
-	err = loadStatus(db, migrations)
+	err = loadStatus(ctx, db, migrations)
 	if err != nil {
 		return err
 	}
@@ -44,7 +44,7 @@ func Run(db pg.DB) error {
 		// The migration and the insertion cannot be grouped in a single
 		// transaction, because some migrations contain SQL that cannot be
 		// run within a transaction.
-		err = insertAppliedMigration(db, m)
+		err = insertAppliedMigration(ctx, db, m)
 		if err != nil {
 			return err
 		}
@@ -56,7 +56,7 @@ func Run(db pg.DB) error {
 
 // PrintStatus prints the status of each built-in migration.
 func PrintStatus(db pg.DB) error {
-	err := loadStatus(db, migrations)
+	err := loadStatus(context.Background(), db, migrations)
 	if err != nil {
 		return err
 	}
@@ -89,8 +89,7 @@ func (m migration) String() string {
 // in table "migrations" in db.
 // It is an error for the stored hash to be different
 // from the migration's computed hash.
-func loadStatus(db pg.DB, ms []migration) error {
-	ctx := context.Background()
+func loadStatus(ctx context.Context, db pg.DB, ms []migration) error {
 	const q = `
 		SELECT count(*) FROM pg_tables
 		WHERE schemaname='public' AND tablename='migrations'
@@ -136,8 +135,7 @@ func loadStatus(db pg.DB, ms []migration) error {
 // format before attempting to run any new migrations.
 // If they are running a schema older than the last migration
 // just before the squash, we cannot help them here.
-func convertOldStatus(db pg.DB) error {
-	ctx := context.Background()
+func convertOldStatus(ctx context.Context, db pg.DB) error {
 
 	// last migration in the old regime
 	const q = `
@@ -178,8 +176,7 @@ func find(name string, ms []migration) *migration {
 	return nil
 }
 
-func insertAppliedMigration(db pg.DB, m migration) error {
-	ctx := context.Background()
+func insertAppliedMigration(ctx context.Context, db pg.DB, m migration) error {
 
 	const q = `
 		INSERT INTO migrations (filename, hash, applied_at)
@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"testing"
@@ -84,7 +85,7 @@ func TestLoadStatus(t *testing.T) {
 		got := make([]migration, len(test.migs))
 		copy(got, test.migs)
 
-		err := loadStatus(db, got)
+		err := loadStatus(context.Background(), db, got)
 		if err != nil {
 			t.Error(err)
 			continue
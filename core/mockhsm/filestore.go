@@ -0,0 +1,299 @@
+package mockhsm
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	chainjson "chain/encoding/json"
+	"chain/errors"
+)
+
+// ErrBadPassphrase is returned when sealed key data
+// can't be decrypted with the given passphrase.
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupt key data")
+
+// sealIterations is the PBKDF2 iteration count
+// for keys derived from new passphrases.
+const sealIterations = 200000
+
+// FileStore is a KeyStore that keeps keys in a file,
+// encrypted with a key derived from a passphrase.
+// It rewrites the whole file on each change, so it
+// suits the few keys of a single Core.
+type FileStore struct {
+	path string
+
+	mu     sync.Mutex
+	sealer *sealer
+	keys   []*fileKey // in order of insertion
+	nextID int64
+}
+
+type fileKey struct {
+	Type   string             `json:"type"`
+	Pub    chainjson.HexBytes `json:"pub"`
+	Prv    chainjson.HexBytes `json:"prv"`
+	Alias  string             `json:"alias,omitempty"`
+	SortID int64              `json:"sort_id,omitempty"`
+}
+
+func (k *fileKey) key() *Key {
+	return &Key{Type: k.Type, Pub: k.Pub, Prv: k.Prv, Alias: k.Alias}
+}
+
+// OpenFileStore opens the key store in the file at path,
+// decrypting it with passphrase. If the file doesn't
+// exist, OpenFileStore creates it, empty.
+func OpenFileStore(path, passphrase string) (*FileStore, error) {
+	s := &FileStore{path: path, nextID: 1}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.sealer, err = newSealer(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return s, s.write()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading key store")
+	}
+
+	plain, sl, err := unseal(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(plain, &s.keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding key store")
+	}
+	s.sealer = sl
+	for _, k := range s.keys {
+		if k.SortID >= s.nextID {
+			s.nextID = k.SortID + 1
+		}
+	}
+	return s, nil
+}
+
+// Rotate re-encrypts the key store under passphrase.
+// The file must be opened with passphrase from then on.
+func (s *FileStore) Rotate(passphrase string) error {
+	sl, err := newSealer(passphrase)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.sealer
+	s.sealer = sl
+	err = s.write()
+	if err != nil {
+		s.sealer = old
+	}
+	return err
+}
+
+// write replaces the file with the current keys.
+// The caller must hold s.mu, or have sole access to s.
+func (s *FileStore) write() error {
+	plain, err := json.Marshal(s.keys)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	data, err := s.sealer.seal(plain)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "writing key store")
+	}
+	return errors.Wrap(os.Rename(tmp, s.path), "writing key store")
+}
+
+func (s *FileStore) Insert(ctx context.Context, k *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fk := range s.keys {
+		if k.Alias != "" && fk.Alias == k.Alias {
+			return errors.WithDetailf(ErrDuplicateKeyAlias, "value: %q", k.Alias)
+		}
+		if bytes.Equal(fk.Pub, k.Pub) {
+			return errors.New("key already exists")
+		}
+	}
+	s.keys = append(s.keys, &fileKey{Type: k.Type, Pub: k.Pub, Prv: k.Prv, Alias: k.Alias, SortID: s.nextID})
+	err := s.write()
+	if err != nil {
+		s.keys = s.keys[:len(s.keys)-1]
+		return err
+	}
+	s.nextID++
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, keyType string, pub []byte) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fk := range s.keys {
+		if fk.Type == keyType && bytes.Equal(fk.Pub, pub) {
+			return fk.key(), nil
+		}
+	}
+	return nil, ErrNoKey
+}
+
+func (s *FileStore) GetByAlias(ctx context.Context, alias string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fk := range s.keys {
+		if fk.Alias == alias {
+			return fk.key(), nil
+		}
+	}
+	return nil, ErrNoKey
+}
+
+func (s *FileStore) List(ctx context.Context, keyType string, aliases []string, after int64, limit int) ([]*Key, int64, error) {
+	want := make(map[string]bool, len(aliases))
+	for _, a := range aliases {
+		want[a] = true
+	}
+
+	s.mu.Lock()
+	var matches []*fileKey
+	for _, fk := range s.keys {
+		if fk.Type != keyType || (after != 0 && fk.SortID >= after) {
+			continue
+		}
+		if len(want) > 0 && !want[fk.Alias] {
+			continue
+		}
+		matches = append(matches, fk)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].SortID > matches[j].SortID })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	keys := make([]*Key, 0, len(matches))
+	for _, fk := range matches {
+		k := fk.key()
+		k.Prv = nil
+		keys = append(keys, k)
+		after = fk.SortID
+	}
+	return keys, after, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, keyType string, pub []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, fk := range s.keys {
+		if fk.Type == keyType && bytes.Equal(fk.Pub, pub) {
+			keys := append(s.keys[:i:i], s.keys[i+1:]...)
+			old := s.keys
+			s.keys = keys
+			err := s.write()
+			if err != nil {
+				s.keys = old
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// sealed is the encoding of data encrypted under a passphrase,
+// used for key store files and exported keys.
+type sealed struct {
+	Version    int                `json:"version"`
+	Salt       chainjson.HexBytes `json:"salt"`
+	Iterations int                `json:"iterations"`
+	Nonce      chainjson.HexBytes `json:"nonce"`
+	Ciphertext chainjson.HexBytes `json:"ciphertext"`
+}
+
+// sealer encrypts data with AES-256-GCM under a key
+// derived from a passphrase with PBKDF2-SHA256.
+// Deriving the key is slow by design, so a sealer
+// keeps it for repeated use.
+type sealer struct {
+	salt       []byte
+	iterations int
+	aead       cipher.AEAD
+}
+
+func newSealer(passphrase string) (*sealer, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return deriveSealer(passphrase, salt, sealIterations)
+}
+
+func deriveSealer(passphrase string, salt []byte, iterations int) (*sealer, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return &sealer{salt: salt, iterations: iterations, aead: aead}, nil
+}
+
+func (sl *sealer) seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, sl.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return json.Marshal(sealed{
+		Version:    1,
+		Salt:       sl.salt,
+		Iterations: sl.iterations,
+		Nonce:      nonce,
+		Ciphertext: sl.aead.Seal(nil, nonce, plain, nil),
+	})
+}
+
+// unseal decrypts data sealed under passphrase. It also
+// returns a sealer for resealing under the same passphrase.
+func unseal(data []byte, passphrase string) ([]byte, *sealer, error) {
+	var sd sealed
+	err := json.Unmarshal(data, &sd)
+	if err != nil || sd.Version != 1 || sd.Iterations <= 0 {
+		return nil, nil, errors.Wrap(ErrBadPassphrase)
+	}
+	sl, err := deriveSealer(passphrase, sd.Salt, sd.Iterations)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sd.Nonce) != sl.aead.NonceSize() {
+		return nil, nil, errors.Wrap(ErrBadPassphrase)
+	}
+	plain, err := sl.aead.Open(nil, sd.Nonce, sd.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrBadPassphrase)
+	}
+	return plain, sl, nil
+}
@@ -0,0 +1,138 @@
+package mockhsm
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chain/crypto/ed25519"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockhsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys")
+	ctx := context.Background()
+
+	store, err := OpenFileStore(path, "pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsm := NewWithStore(store)
+	xpub, err := hsm.XCreate(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = hsm.XCreate(ctx, "alice")
+	if errors.Root(err) != ErrDuplicateKeyAlias {
+		t.Errorf("duplicate alias err = %v, want %v", err, ErrDuplicateKeyAlias)
+	}
+	pub, created, err := hsm.GetOrCreate(ctx, "block_key")
+	if err != nil || !created {
+		t.Fatalf("GetOrCreate = %v, %v", created, err)
+	}
+
+	_, err = OpenFileStore(path, "wrong")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Errorf("open with wrong passphrase err = %v, want %v", err, ErrBadPassphrase)
+	}
+
+	err = hsm.RotateKeyStore("pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenFileStore(path, "pass1")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Errorf("open with old passphrase err = %v, want %v", err, ErrBadPassphrase)
+	}
+	store, err = OpenFileStore(path, "pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsm = NewWithStore(store)
+	pub2, created, err := hsm.GetOrCreate(ctx, "block_key")
+	if err != nil || created || string(pub2.Pub) != string(pub.Pub) {
+		t.Errorf("GetOrCreate after reopen = %x, %v, %v; want %x, false, nil", pub2.Pub, created, err, pub.Pub)
+	}
+	xpubs, _, err := hsm.ListKeys(ctx, nil, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xpubs) != 1 || xpubs[0].XPub != xpub.XPub {
+		t.Errorf("ListKeys = %+v, want [%x]", xpubs, xpub.XPub)
+	}
+
+	msg := []byte("message")
+	sig, err := hsm.XSign(ctx, xpub.XPub, nil, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := hsm.ExportKey(ctx, ChainKDKey, xpub.XPub.Bytes(), "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewWithStore(&FileStore{path: filepath.Join(dir, "other"), nextID: 1, sealer: store.sealer})
+	_, err = other.ImportKey(ctx, exported, "wrong")
+	if errors.Root(err) != ErrBadPassphrase {
+		t.Errorf("import with wrong passphrase err = %v, want %v", err, ErrBadPassphrase)
+	}
+	k, err := other.ImportKey(ctx, exported, "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Alias != "alice" || k.Prv != nil {
+		t.Errorf("imported key = %+v, want alias alice and no prv", k)
+	}
+	sig2, err := other.XSign(ctx, xpub.XPub, nil, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sig2) != string(sig) {
+		t.Error("imported key signs differently")
+	}
+}
+
+func TestImportKeyMismatch(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "mockhsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, prv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := json.Marshal(&fileKey{Type: Ed25519Key, Pub: chainjson.HexBytes(pub), Prv: chainjson.HexBytes(prv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl, err := newSealer("export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sl.seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hsm := NewWithStore(&FileStore{path: filepath.Join(dir, "keys"), nextID: 1, sealer: sl})
+	_, err = hsm.ImportKey(ctx, sealed, "export")
+	if errors.Root(err) != ErrKeyMismatch {
+		t.Errorf("import of mismatched key err = %v, want %v", err, ErrKeyMismatch)
+	}
+}
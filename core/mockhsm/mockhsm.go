@@ -3,14 +3,12 @@
 package mockhsm
 
 import (
+	"bytes"
 	"context"
-	"database/sql"
-	"fmt"
+	"encoding/json"
 	"strconv"
 	"sync"
 
-	"github.com/lib/pq"
-
 	"chain/crypto/ed25519"
 	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
@@ -27,10 +25,13 @@ var (
 	ErrNoKey                = errors.New("key not found")
 	ErrInvalidKeySize       = errors.New("key invalid size")
 	ErrTooManyAliasesToList = errors.New("requested aliases exceeds limit")
+	ErrInvalidKeyType       = errors.New("invalid key type")
+	ErrNotRotatable         = errors.New("key store is not encrypted")
+	ErrKeyMismatch          = errors.New("public key doesn't match private key")
 )
 
 type HSM struct {
-	db pg.DB
+	store KeyStore
 
 	cacheMu sync.Mutex
 	kdCache map[chainkd.XPub]chainkd.XPrv
@@ -47,15 +48,21 @@ type Pub struct {
 	Pub   ed25519.PublicKey `json:"pub"`
 }
 
+// New returns an HSM that keeps its keys in db.
 func New(db pg.DB) *HSM {
+	return NewWithStore(NewDBStore(db))
+}
+
+// NewWithStore returns an HSM that keeps its keys in store.
+func NewWithStore(store KeyStore) *HSM {
 	return &HSM{
-		db:      db,
+		store:   store,
 		kdCache: make(map[chainkd.XPub]chainkd.XPrv),
 		edCache: make(map[string]ed25519.PrivateKey),
 	}
 }
 
-// XCreate produces a new random xprv and stores it in the db.
+// XCreate produces a new random xprv and stores it in the key store.
 func (h *HSM) XCreate(ctx context.Context, alias string) (*XPub, error) {
 	xpub, _, err := h.createChainKDKey(ctx, alias, false)
 	return xpub, err
@@ -66,34 +73,27 @@ func (h *HSM) createChainKDKey(ctx context.Context, alias string, get bool) (*XP
 	if err != nil {
 		return nil, false, err
 	}
-	sqlAlias := sql.NullString{String: alias, Valid: alias != ""}
 	var ptrAlias *string
 	if alias != "" {
 		ptrAlias = &alias
 	}
-	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type) VALUES ($1, $2, $3, 'chain_kd')`
-	_, err = h.db.ExecContext(ctx, q, xpub.Bytes(), xprv.Bytes(), sqlAlias)
-	if err != nil {
-		if pg.IsUniqueViolation(err) {
-			if !get {
-				return nil, false, errors.WithDetailf(ErrDuplicateKeyAlias, "value: %q", alias)
-			}
-
-			var xpubBytes []byte
-			err = h.db.QueryRowContext(ctx, `SELECT pub FROM mockhsm WHERE alias = $1`, alias).Scan(&xpubBytes)
-			if err != nil {
-				return nil, false, errors.Wrapf(err, "reading existing xpub with alias %s", alias)
-			}
-			var existingXPub chainkd.XPub
-			copy(existingXPub[:], xpubBytes)
-			return &XPub{XPub: existingXPub, Alias: ptrAlias}, false, nil
+	err = h.store.Insert(ctx, &Key{Type: ChainKDKey, Pub: xpub.Bytes(), Prv: xprv.Bytes(), Alias: alias})
+	if errors.Root(err) == ErrDuplicateKeyAlias && get {
+		k, err := h.store.GetByAlias(ctx, alias)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "reading existing xpub with alias %s", alias)
 		}
-		return nil, false, errors.Wrap(err, "storing new xpub")
+		var existingXPub chainkd.XPub
+		copy(existingXPub[:], k.Pub)
+		return &XPub{XPub: existingXPub, Alias: ptrAlias}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
 	}
 	return &XPub{XPub: xpub, Alias: ptrAlias}, true, nil
 }
 
-// Create produces a new random prv and stores it in the db.
+// Create produces a new random prv and stores it in the key store.
 func (h *HSM) Create(ctx context.Context, alias string) (*Pub, error) {
 	pub, _, err := h.createEd25519Key(ctx, alias, false)
 	return pub, err
@@ -111,32 +111,25 @@ func (h *HSM) createEd25519Key(ctx context.Context, alias string, get bool) (*Pu
 		return nil, false, err
 	}
 
-	sqlAlias := sql.NullString{String: alias, Valid: alias != ""}
 	var ptrAlias *string
 	if alias != "" {
 		ptrAlias = &alias
 	}
-	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type) VALUES ($1, $2, $3, 'ed25519')`
-	_, err = h.db.ExecContext(ctx, q, []byte(pub), []byte(prv), sqlAlias)
-	if err != nil {
-		if pg.IsUniqueViolation(err) {
-			if !get {
-				return nil, false, errors.WithDetailf(ErrDuplicateKeyAlias, "value: %q", alias)
-			}
-
-			var pubBytes []byte
-			err = h.db.QueryRowContext(ctx, `SELECT pub FROM mockhsm WHERE alias = $1`, alias).Scan(&pubBytes)
-			if err != nil {
-				return nil, false, errors.Wrapf(err, "reading existing pub with alias %s", alias)
-			}
-			return &Pub{Pub: ed25519.PublicKey(pubBytes), Alias: ptrAlias}, false, nil
+	err = h.store.Insert(ctx, &Key{Type: Ed25519Key, Pub: pub, Prv: prv, Alias: alias})
+	if errors.Root(err) == ErrDuplicateKeyAlias && get {
+		k, err := h.store.GetByAlias(ctx, alias)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "reading existing pub with alias %s", alias)
 		}
-		return nil, false, errors.Wrap(err, "storing new pub")
+		return &Pub{Pub: ed25519.PublicKey(k.Pub), Alias: ptrAlias}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
 	}
 	return &Pub{Pub: pub, Alias: ptrAlias}, true, nil
 }
 
-// ListKeys returns a list of all xpubs from the db.
+// ListKeys returns a list of all xpubs from the key store.
 func (h *HSM) ListKeys(ctx context.Context, aliases []string, after string, limit int) ([]*XPub, string, error) {
 	if len(aliases) > listKeyMaxAliases {
 		return nil, "", errors.WithDetailf(ErrTooManyAliasesToList, "max: %d", listKeyMaxAliases)
@@ -154,44 +147,21 @@ func (h *HSM) ListKeys(ctx context.Context, aliases []string, after string, limi
 		}
 	}
 
-	var (
-		xpubs  []*XPub
-		params []interface{}
-	)
-	q := `
-		SELECT pub, alias, sort_id FROM mockhsm
-		WHERE key_type = 'chain_kd'
-	`
-
-	if len(aliases) > 0 {
-		params = append(params, pq.StringArray(aliases))
-		q += fmt.Sprintf(" AND alias = ANY($%d)", len(params))
-	}
-
-	if zafter != 0 {
-		params = append(params, zafter)
-		q += fmt.Sprintf(" AND sort_id < $%d", len(params))
+	keys, zafter, err := h.store.List(ctx, ChainKDKey, aliases, zafter, limit)
+	if err != nil {
+		return nil, "", err
 	}
 
-	q += fmt.Sprintf(" ORDER BY sort_id DESC LIMIT %d", limit)
-
-	consumeRow := func(b []byte, alias sql.NullString, sortID int64) {
-		var hdxpub chainkd.XPub
-		copy(hdxpub[:], b)
-		xpub := &XPub{XPub: hdxpub}
-		if alias.Valid {
-			xpub.Alias = &alias.String
+	xpubs := make([]*XPub, 0, len(keys))
+	for _, k := range keys {
+		xpub := new(XPub)
+		copy(xpub.XPub[:], k.Pub)
+		if k.Alias != "" {
+			alias := k.Alias
+			xpub.Alias = &alias
 		}
 		xpubs = append(xpubs, xpub)
-		zafter = sortID
-	}
-	params = append(params, consumeRow)
-
-	err = pg.ForQueryRows(ctx, h.db, q, params...)
-	if err != nil {
-		return nil, "", err
 	}
-
 	return xpubs, strconv.FormatInt(zafter, 10), nil
 }
 
@@ -203,15 +173,11 @@ func (h *HSM) loadChainKDKey(ctx context.Context, xpub chainkd.XPub) (xprv chain
 		return xprv, nil
 	}
 
-	var b []byte
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes()).Scan(&b)
-	if err == sql.ErrNoRows {
-		return xprv, ErrNoKey
-	}
+	k, err := h.store.Get(ctx, ChainKDKey, xpub.Bytes())
 	if err != nil {
 		return xprv, err
 	}
-	copy(xprv[:], b)
+	copy(xprv[:], k.Prv)
 	h.kdCache[xpub] = xprv
 	return xprv, nil
 }
@@ -234,8 +200,7 @@ func (h *HSM) DeleteChainKDKey(ctx context.Context, xpub chainkd.XPub) error {
 	h.cacheMu.Lock()
 	delete(h.kdCache, xpub)
 	h.cacheMu.Unlock()
-	_, err := h.db.ExecContext(ctx, "DELETE FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes())
-	return err
+	return h.store.Delete(ctx, ChainKDKey, xpub.Bytes())
 }
 
 func (h *HSM) loadEd25519Key(ctx context.Context, pub ed25519.PublicKey) (prv ed25519.PrivateKey, err error) {
@@ -248,13 +213,11 @@ func (h *HSM) loadEd25519Key(ctx context.Context, pub ed25519.PublicKey) (prv ed
 		return prv, nil
 	}
 
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='ed25519'", []byte(pub)).Scan(&prv)
-	if err == sql.ErrNoRows {
-		return prv, ErrNoKey
-	}
+	k, err := h.store.Get(ctx, Ed25519Key, pub)
 	if err != nil {
 		return prv, err
 	}
+	prv = ed25519.PrivateKey(k.Prv)
 	h.edCache[pubStr] = prv
 	return prv, nil
 }
@@ -273,3 +236,77 @@ func (h *HSM) Sign(ctx context.Context, pub ed25519.PublicKey, bh *legacy.BlockH
 	msg := bh.Hash()
 	return ed25519.Sign(prv, msg.Bytes()), nil
 }
+
+// ExportKey returns the key of the given type with the given
+// public key, private key included, sealed under passphrase.
+// ImportKey reverses it.
+func (h *HSM) ExportKey(ctx context.Context, keyType string, pub []byte, passphrase string) ([]byte, error) {
+	k, err := h.store.Get(ctx, keyType, pub)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(&fileKey{Type: k.Type, Pub: k.Pub, Prv: k.Prv, Alias: k.Alias})
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	sl, err := newSealer(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return sl.seal(plain)
+}
+
+// ImportKey unseals a key exported by ExportKey
+// and adds it to the key store. It returns the
+// key without its private part. It fails if the
+// key's public part doesn't match its private part.
+func (h *HSM) ImportKey(ctx context.Context, data []byte, passphrase string) (*Key, error) {
+	plain, _, err := unseal(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var fk fileKey
+	err = json.Unmarshal(plain, &fk)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding exported key")
+	}
+	switch fk.Type {
+	case ChainKDKey:
+		if len(fk.Pub) != len(chainkd.XPub{}) || len(fk.Prv) != len(chainkd.XPrv{}) {
+			return nil, errors.Wrap(ErrInvalidKeySize)
+		}
+		var xprv chainkd.XPrv
+		copy(xprv[:], fk.Prv)
+		xpub := xprv.XPub()
+		if !bytes.Equal(xpub[:], fk.Pub) {
+			return nil, errors.Wrap(ErrKeyMismatch)
+		}
+	case Ed25519Key:
+		if len(fk.Pub) != ed25519.PublicKeySize || len(fk.Prv) != ed25519.PrivateKeySize {
+			return nil, errors.Wrap(ErrInvalidKeySize)
+		}
+		pub := ed25519.PrivateKey(fk.Prv).Public().(ed25519.PublicKey)
+		if !bytes.Equal(pub, fk.Pub) {
+			return nil, errors.Wrap(ErrKeyMismatch)
+		}
+	default:
+		return nil, errors.WithDetailf(ErrInvalidKeyType, "type: %q", fk.Type)
+	}
+	k := fk.key()
+	err = h.store.Insert(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	k.Prv = nil
+	return k, nil
+}
+
+// RotateKeyStore re-encrypts the HSM's key store under
+// passphrase. Only a FileStore can be rotated.
+func (h *HSM) RotateKeyStore(passphrase string) error {
+	fs, ok := h.store.(*FileStore)
+	if !ok {
+		return errors.Wrap(ErrNotRotatable)
+	}
+	return fs.Rotate(passphrase)
+}
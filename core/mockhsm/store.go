@@ -0,0 +1,143 @@
+package mockhsm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// Key types.
+const (
+	ChainKDKey = "chain_kd"
+	Ed25519Key = "ed25519"
+)
+
+// Key is a private key held by a KeyStore.
+type Key struct {
+	Type  string // ChainKDKey or Ed25519Key
+	Pub   []byte
+	Prv   []byte
+	Alias string // optional
+}
+
+// A KeyStore holds the private keys of an HSM.
+type KeyStore interface {
+	// Insert stores a new key. It returns ErrDuplicateKeyAlias
+	// if k has an alias and another key already has it.
+	Insert(ctx context.Context, k *Key) error
+
+	// Get returns the key of the given type with the given
+	// public key, or ErrNoKey.
+	Get(ctx context.Context, keyType string, pub []byte) (*Key, error)
+
+	// GetByAlias returns the key with the given alias, or ErrNoKey.
+	GetByAlias(ctx context.Context, alias string) (*Key, error)
+
+	// List returns up to limit keys of the given type, most
+	// recently inserted first, starting after the position
+	// after (or at the beginning, if after is 0). If aliases
+	// is nonempty, it returns only keys with those aliases.
+	// It also returns the position of the last key listed,
+	// for use as after in the next call. The keys needn't
+	// have their Prv set.
+	List(ctx context.Context, keyType string, aliases []string, after int64, limit int) ([]*Key, int64, error)
+
+	// Delete removes the key of the given type with
+	// the given public key, if there is one.
+	Delete(ctx context.Context, keyType string, pub []byte) error
+}
+
+// dbStore is a KeyStore that keeps keys unencrypted
+// in the mockhsm table of a Chain Core database.
+type dbStore struct {
+	db pg.DB
+}
+
+// NewDBStore returns a KeyStore that keeps keys in db.
+// The keys are not encrypted.
+func NewDBStore(db pg.DB) KeyStore {
+	return &dbStore{db: db}
+}
+
+func (s *dbStore) Insert(ctx context.Context, k *Key) error {
+	sqlAlias := sql.NullString{String: k.Alias, Valid: k.Alias != ""}
+	const q = `INSERT INTO mockhsm (pub, prv, alias, key_type) VALUES ($1, $2, $3, $4)`
+	_, err := s.db.ExecContext(ctx, q, k.Pub, k.Prv, sqlAlias, k.Type)
+	if pg.IsUniqueViolation(err) {
+		return errors.WithDetailf(ErrDuplicateKeyAlias, "value: %q", k.Alias)
+	}
+	return errors.Wrap(err, "storing new key")
+}
+
+func (s *dbStore) Get(ctx context.Context, keyType string, pub []byte) (*Key, error) {
+	k := &Key{Type: keyType, Pub: pub}
+	var alias sql.NullString
+	const q = `SELECT prv, alias FROM mockhsm WHERE pub = $1 AND key_type = $2`
+	err := s.db.QueryRowContext(ctx, q, pub, keyType).Scan(&k.Prv, &alias)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.Alias = alias.String
+	return k, nil
+}
+
+func (s *dbStore) GetByAlias(ctx context.Context, alias string) (*Key, error) {
+	k := &Key{Alias: alias}
+	const q = `SELECT key_type, pub, prv FROM mockhsm WHERE alias = $1`
+	err := s.db.QueryRowContext(ctx, q, alias).Scan(&k.Type, &k.Pub, &k.Prv)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoKey
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading key with alias %s", alias)
+	}
+	return k, nil
+}
+
+func (s *dbStore) List(ctx context.Context, keyType string, aliases []string, after int64, limit int) ([]*Key, int64, error) {
+	var (
+		keys   []*Key
+		params = []interface{}{keyType}
+	)
+	q := `
+		SELECT pub, alias, sort_id FROM mockhsm
+		WHERE key_type = $1
+	`
+
+	if len(aliases) > 0 {
+		params = append(params, pq.StringArray(aliases))
+		q += fmt.Sprintf(" AND alias = ANY($%d)", len(params))
+	}
+
+	if after != 0 {
+		params = append(params, after)
+		q += fmt.Sprintf(" AND sort_id < $%d", len(params))
+	}
+
+	q += fmt.Sprintf(" ORDER BY sort_id DESC LIMIT %d", limit)
+
+	consumeRow := func(pub []byte, alias sql.NullString, sortID int64) {
+		keys = append(keys, &Key{Type: keyType, Pub: pub, Alias: alias.String})
+		after = sortID
+	}
+	params = append(params, consumeRow)
+
+	err := pg.ForQueryRows(ctx, s.db, q, params...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return keys, after, nil
+}
+
+func (s *dbStore) Delete(ctx context.Context, keyType string, pub []byte) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM mockhsm WHERE pub = $1 AND key_type = $2", pub, keyType)
+	return err
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/core/config"
+	"chain/core/fetch"
+	"chain/core/leader"
+	"chain/core/rpc"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc/legacy"
+)
+
+const (
+	// blockCallbackTTL is how long the generator keeps a block
+	// callback that its participant hasn't renewed.
+	blockCallbackTTL = 2 * time.Minute
+
+	// maxBlockCallbacks is the most block callbacks
+	// the generator keeps at once.
+	maxBlockCallbacks = 100
+
+	notifyTimeout = 5 * time.Second
+)
+
+// errTooManyCallbacks is returned when registering a block
+// callback with a generator that has maxBlockCallbacks.
+var errTooManyCallbacks = errors.New("too many block callbacks")
+
+// blockNotifier pushes the header of each new block to the
+// block signers that registered a callback with the generator.
+// Only the signers in the generator's config may register, at
+// the URLs configured for them, and notifications carry the
+// access tokens the generator uses to reach them.
+type blockNotifier struct {
+	client rpc.Client        // template for requests to participants
+	tokens map[string]string // access tokens of the signers, by URL
+
+	mu        sync.Mutex
+	callbacks map[string]blockCallback // by URL
+}
+
+type blockCallback struct {
+	secret  string
+	expires time.Time
+}
+
+func newBlockNotifier(client rpc.Client, signers []*config.BlockSigner) *blockNotifier {
+	n := &blockNotifier{client: client, tokens: make(map[string]string)}
+	for _, s := range signers {
+		if s.Url != "" {
+			n.tokens[s.Url] = s.AccessToken
+		}
+	}
+	return n
+}
+
+func (n *blockNotifier) register(cb fetch.BlockCallback, now time.Time) error {
+	_, ok := n.tokens[cb.URL]
+	if !ok {
+		return errors.WithDetailf(httpjson.ErrBadRequest, "%s is not the URL of a block signer", cb.URL)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.callbacks == nil {
+		n.callbacks = make(map[string]blockCallback)
+	}
+	n.forgetExpired(now)
+	_, ok = n.callbacks[cb.URL]
+	if !ok && len(n.callbacks) >= maxBlockCallbacks {
+		return errors.WithDetailf(errTooManyCallbacks, "at most %d", maxBlockCallbacks)
+	}
+	n.callbacks[cb.URL] = blockCallback{
+		secret:  cb.Secret,
+		expires: now.Add(blockCallbackTTL),
+	}
+	return nil
+}
+
+// forgetExpired forgets the callbacks that expired
+// before now. The caller must hold n.mu.
+func (n *blockNotifier) forgetExpired(now time.Time) {
+	for url, cb := range n.callbacks {
+		if now.After(cb.expires) {
+			delete(n.callbacks, url)
+		}
+	}
+}
+
+// live returns the unexpired callbacks,
+// forgetting the rest.
+func (n *blockNotifier) live(now time.Time) map[string]string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.forgetExpired(now)
+	secrets := make(map[string]string, len(n.callbacks))
+	for url, cb := range n.callbacks {
+		secrets[url] = cb.secret
+	}
+	return secrets
+}
+
+// notify sends header to each participant concurrently. The
+// generator calls it as it commits each block; see
+// protocol.Chain.OnCommit. Failures are only logged;
+// participants fall back to polling.
+func (n *blockNotifier) notify(ctx context.Context, header *legacy.BlockHeader) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for url, secret := range n.live(time.Now()) {
+		client := n.client
+		client.BaseURL = url
+		client.AccessToken = n.tokens[url]
+		req := fetch.BlockNotification{Secret: secret, Header: header}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := client.Call(ctx, crosscoreRPCPrefix+"notify-block", req, nil)
+			if err != nil {
+				log.Printkv(ctx, log.KeyError, err, "at", "notifying participant of new block", "url", client.BaseURL)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// registerBlockCallbackRPC registers a participant to be
+// notified of new blocks. It is served by the generator.
+func (a *API) registerBlockCallbackRPC(ctx context.Context, cb fetch.BlockCallback) error {
+	if a.generator == nil {
+		return errors.Wrap(errNotGenerator)
+	}
+	if cb.URL == "" || cb.Secret == "" {
+		return errors.WithDetail(httpjson.ErrBadRequest, "url and secret are required")
+	}
+	if a.leader.State() != leader.Leading {
+		return a.forwardToLeader(ctx, crosscoreRPCPrefix+"register-block-callback", cb, nil)
+	}
+	return a.notifier.register(cb, time.Now())
+}
+
+// notifyBlockRPC receives a new block notification
+// from the generator. It is served by participants.
+func (a *API) notifyBlockRPC(ctx context.Context, n *fetch.BlockNotification) error {
+	if a.replicator == nil {
+		return errors.WithDetail(httpjson.ErrBadRequest, "core is the generator")
+	}
+	if a.leader.State() != leader.Leading {
+		return a.forwardToLeader(ctx, crosscoreRPCPrefix+"notify-block", n, nil)
+	}
+	return a.replicator.Notify(n)
+}
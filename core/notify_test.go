@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"chain/core/config"
+	"chain/core/fetch"
+	"chain/core/rpc"
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+func TestBlockNotifierExpiry(t *testing.T) {
+	n := newBlockNotifier(rpc.Client{}, []*config.BlockSigner{
+		{Url: "https://a.example"},
+		{Url: "https://b.example"},
+	})
+	now := time.Now()
+	n.register(fetch.BlockCallback{URL: "https://a.example", Secret: "s1"}, now)
+	n.register(fetch.BlockCallback{URL: "https://b.example", Secret: "s2"}, now)
+
+	got := n.live(now)
+	if len(got) != 2 || got["https://a.example"] != "s1" || got["https://b.example"] != "s2" {
+		t.Errorf("live callbacks = %v, want both", got)
+	}
+
+	got = n.live(now.Add(blockCallbackTTL + time.Second))
+	if len(got) != 0 {
+		t.Errorf("live callbacks after TTL = %v, want none", got)
+	}
+	if len(n.callbacks) != 0 {
+		t.Errorf("expired callbacks were not forgotten: %v", n.callbacks)
+	}
+}
+
+func TestBlockNotifierRegister(t *testing.T) {
+	var signers []*config.BlockSigner
+	for i := 0; i <= maxBlockCallbacks; i++ {
+		signers = append(signers, &config.BlockSigner{Url: fmt.Sprintf("https://%d.example", i)})
+	}
+	n := newBlockNotifier(rpc.Client{}, signers)
+	now := time.Now()
+
+	err := n.register(fetch.BlockCallback{URL: "https://other.example", Secret: "s"}, now)
+	if errors.Root(err) != httpjson.ErrBadRequest {
+		t.Errorf("register(not a signer) = %v, want %v", err, httpjson.ErrBadRequest)
+	}
+
+	for i := 0; i < maxBlockCallbacks; i++ {
+		err = n.register(fetch.BlockCallback{URL: signers[i].Url, Secret: "s"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := fetch.BlockCallback{URL: signers[maxBlockCallbacks].Url, Secret: "s"}
+	err = n.register(last, now)
+	if errors.Root(err) != errTooManyCallbacks {
+		t.Errorf("register(one too many) = %v, want %v", err, errTooManyCallbacks)
+	}
+	// Renewing a registration doesn't count against the cap.
+	err = n.register(fetch.BlockCallback{URL: signers[0].Url, Secret: "s"}, now)
+	if err != nil {
+		t.Errorf("renewing: %v", err)
+	}
+	// Expired registrations make room for new ones.
+	err = n.register(last, now.Add(blockCallbackTTL+time.Second))
+	if err != nil {
+		t.Errorf("registering after the others expired: %v", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
+	"chain/clock"
 	"chain/database/pg"
 	"chain/errors"
 	"chain/log"
@@ -13,10 +15,35 @@ import (
 	"chain/protocol/bc/legacy"
 )
 
-const processorWorkers = 10
+const (
+	// processorWorkers is the most blocks a pin
+	// processes concurrently.
+	processorWorkers = 10
+
+	// slowCallback is the callback latency above which a pin
+	// halves its concurrency, to ease load on the database.
+	slowCallback = time.Second
+
+	// maxSaveBatch is the most blocks a pin completes before
+	// saving its height to the database, when it is far enough
+	// behind the chain to be catching up. Near the tip of the
+	// chain, it saves after every block. maxSaveDelay bounds
+	// the time between saves.
+	maxSaveBatch = 100
+	maxSaveDelay = 5 * time.Second
+
+	// retryDelay is how long a pin waits to try a
+	// block again after failing to process it. Without
+	// it, a callback that keeps failing, say while the
+	// database is down, would retry in a tight loop,
+	// spinning a CPU and flooding the log. It is short
+	// so that a transient failure barely delays the pin.
+	retryDelay = 500 * time.Millisecond
+)
 
 type Store struct {
-	db pg.DB
+	db    pg.DB
+	clock clock.Clock // times callback retries and pin saves
 
 	mu   sync.Mutex
 	cond sync.Cond
@@ -25,30 +52,53 @@ type Store struct {
 
 func NewStore(db pg.DB) *Store {
 	s := &Store{
-		db:   db,
-		pins: make(map[string]*pin),
+		db:    db,
+		clock: clock.Real,
+		pins:  make(map[string]*pin),
 	}
 	s.cond.L = &s.mu
 	return s
 }
 
+// SetClock sets the clock that times callback retries and
+// pin saves, in place of the real one, for testing. It
+// affects only pins created or loaded after it is called.
+func (s *Store) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// ProcessBlocks calls cb for each block after the pin's height,
+// in order of height but concurrently, marking each block complete
+// once cb succeeds. Near the tip of the chain it processes one
+// block at a time; while catching up, it processes up to
+// processorWorkers blocks at once, fewer if cb is slow.
 func (s *Store) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinName string, cb func(context.Context, *legacy.Block) error) {
 	p := <-s.pin(pinName)
 	height := p.getHeight()
+	done := make(chan struct{}, processorWorkers)
+	var inFlight int
 	for {
 		select {
 		case <-ctx.Done(): // leader deposed
 			log.Error(ctx, ctx.Err())
 			return
 		case <-c.BlockWaiter(height + 1):
-			select {
-			case <-ctx.Done():
-				log.Error(ctx, ctx.Err())
-				return
-			case p.sem <- true:
-				go p.processBlock(ctx, c, height+1, cb)
-				height++
+			tip := c.Height()
+			p.setTip(tip)
+			for inFlight >= p.workers(tip-height) {
+				select {
+				case <-ctx.Done():
+					log.Error(ctx, ctx.Err())
+					return
+				case <-done:
+					inFlight--
+				}
 			}
+			inFlight++
+			go p.processBlock(ctx, c, height+1, cb, done)
+			height++
 		}
 	}
 }
@@ -67,7 +117,7 @@ func (s *Store) CreatePin(ctx context.Context, name string, height uint64) error
 	if err != nil {
 		return errors.Wrap(err)
 	}
-	s.pins[name] = newPin(s.db, name, height)
+	s.pins[name] = newPin(s.db, s.clock, name, height)
 	s.cond.Broadcast()
 	return nil
 }
@@ -77,12 +127,72 @@ func (s *Store) Height(name string) uint64 {
 	return p.getHeight()
 }
 
+// PinHeight returns the height of the named pin without waiting
+// for it to be created. It returns false if there is no such pin.
+func (s *Store) PinHeight(name string) (height uint64, ok bool) {
+	s.mu.Lock()
+	p := s.pins[name]
+	s.mu.Unlock()
+	if p == nil {
+		return 0, false
+	}
+	return p.getHeight(), true
+}
+
+// Stats reports a pin's progress processing blocks.
+type Stats struct {
+	Name string
+
+	// Height is the height up to which the pin has
+	// processed blocks. It can be ahead of the height
+	// saved to the database.
+	Height uint64
+
+	// Time is the timestamp of the block at Height,
+	// if this process processed it, or the zero time.
+	Time time.Time
+
+	// Processed is the number of blocks this
+	// process has processed for the pin.
+	Processed uint64
+
+	// Latency is a moving average of the
+	// time the pin takes to process a block.
+	Latency time.Duration
+}
+
+// Stats returns the progress of each pin
+// the store has loaded, sorted by name.
+func (s *Store) Stats() []Stats {
+	s.mu.Lock()
+	pins := make([]*pin, 0, len(s.pins))
+	for _, p := range s.pins {
+		pins = append(pins, p)
+	}
+	s.mu.Unlock()
+
+	stats := make([]Stats, 0, len(pins))
+	for _, p := range pins {
+		p.mu.Lock()
+		stats = append(stats, Stats{
+			Name:      p.name,
+			Height:    p.done,
+			Time:      p.heightTime,
+			Processed: p.processed,
+			Latency:   p.latency,
+		})
+		p.mu.Unlock()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
 func (s *Store) LoadAll(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	const q = `SELECT name, height FROM block_processors;`
 	err := pg.ForQueryRows(ctx, s.db, q, func(name string, height uint64) {
-		s.pins[name] = newPin(s.db, name, height)
+		s.pins[name] = newPin(s.db, s.clock, name, height)
 	})
 	s.cond.Broadcast()
 	return err
@@ -160,7 +270,7 @@ func (s *Store) Listen(ctx context.Context, pinName, dbURL string) {
 					var ok bool
 					p, ok = s.pins[pinName]
 					if !ok {
-						p = newPin(s.db, pinName, height)
+						p = newPin(s.db, s.clock, pinName, height)
 						s.pins[pinName] = p
 						s.cond.Broadcast()
 					}
@@ -172,6 +282,10 @@ func (s *Store) Listen(ctx context.Context, pinName, dbURL string) {
 					p.height = height
 					p.cond.Broadcast()
 				}
+				if p.done < height {
+					p.done = height
+					p.heightTime = time.Time{} // processed elsewhere
+				}
 				p.mu.Unlock()
 			}
 		}
@@ -181,41 +295,131 @@ func (s *Store) Listen(ctx context.Context, pinName, dbURL string) {
 }
 
 type pin struct {
-	mu        sync.Mutex
-	cond      sync.Cond
-	height    uint64
+	mu   sync.Mutex
+	cond sync.Cond
+
+	// height is the height last saved to the database, and
+	// savedAt is when. Waiters see only this height, so that
+	// nothing depends on a block the pin would process again
+	// after a restart.
+	height  uint64
+	savedAt time.Time
+
+	// done is the height up to which blocks have been
+	// processed, and completed holds the heights of
+	// blocks processed beyond it.
+	done      uint64
 	completed []uint64
 
-	db   pg.DB
-	name string
-	sem  chan bool
+	// tip is the chain height last seen by ProcessBlocks.
+	tip uint64
+
+	// latency is a moving average of the callback duration.
+	latency time.Duration
+
+	// heightTime is the timestamp of the block at done, if
+	// processBlock processed it. times holds the timestamps of
+	// blocks processed but not yet reached by done.
+	heightTime time.Time
+	times      map[uint64]time.Time
+
+	// processed counts the blocks completed.
+	processed uint64
+
+	db    pg.DB
+	clock clock.Clock
+	name  string
 }
 
-func newPin(db pg.DB, name string, height uint64) *pin {
-	p := &pin{db: db, name: name, height: height, sem: make(chan bool, processorWorkers)}
+func newPin(db pg.DB, clk clock.Clock, name string, height uint64) *pin {
+	p := &pin{db: db, clock: clk, name: name, height: height, savedAt: clk.Now(), done: height, times: make(map[uint64]time.Time)}
 	p.cond.L = &p.mu
 	return p
 }
 
+func (p *pin) setTip(tip uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tip = tip
+}
+
+// workers returns the number of blocks to process concurrently
+// when the next block to start is lag blocks behind the tip.
+func (p *pin) workers(lag uint64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := processorWorkers
+	if lag < uint64(n) {
+		n = int(lag)
+	}
+	if p.latency > slowCallback {
+		n /= 2
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// recordTime notes the timestamp of the block at height,
+// which has been processed, for Stats.
+func (p *pin) recordTime(height uint64, t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if height > p.done {
+		p.times[height] = t
+	}
+}
+
+// recordLatency adds d to the moving average of callback latency.
+func (p *pin) recordLatency(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = (p.latency*7 + d) / 8
+}
+
+// shouldSave reports whether a pin that has processed blocks
+// up to height should save it to the database now.
+// The caller must hold p.mu.
+func (p *pin) shouldSave(height uint64, now time.Time) bool {
+	if height <= p.height {
+		return false
+	}
+	if height+processorWorkers >= p.tip {
+		return true // near the tip
+	}
+	return height-p.height >= maxSaveBatch || now.Sub(p.savedAt) >= maxSaveDelay
+}
+
 func (p *pin) getHeight() uint64 {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	return p.height
 }
 
-func (p *pin) processBlock(ctx context.Context, c *protocol.Chain, height uint64, cb func(context.Context, *legacy.Block) error) {
-	defer func() { <-p.sem }()
+func (p *pin) processBlock(ctx context.Context, c *protocol.Chain, height uint64, cb func(context.Context, *legacy.Block) error, done chan<- struct{}) {
+	defer func() { done <- struct{}{} }()
 	for {
 		block, err := c.GetBlock(ctx, height)
 		if err != nil {
 			log.Error(ctx, err)
+			if !p.waitRetry(ctx) {
+				return
+			}
 			continue
 		}
+		start := time.Now()
 		err = cb(ctx, block)
+		p.recordLatency(time.Since(start))
 		if err != nil {
 			log.Error(ctx, errors.Wrapf(err, "pin %q callback", p.name))
+			if !p.waitRetry(ctx) {
+				return
+			}
 			continue
 		}
+		p.recordTime(block.Height, block.Time())
 		err = p.complete(ctx, block.Height)
 		if err != nil {
 			log.Error(ctx, err)
@@ -224,15 +428,31 @@ func (p *pin) processBlock(ctx context.Context, c *protocol.Chain, height uint64
 	}
 }
 
+// waitRetry waits retryDelay before processBlock tries a block
+// again. It returns false if ctx is canceled first.
+func (p *pin) waitRetry(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-p.clock.After(retryDelay):
+		return true
+	}
+}
+
+// complete marks the block at height processed. Once the blocks
+// up to a new height are all processed, and it's time to save,
+// complete saves that height to the database, then wakes the
+// pin's waiters.
 func (p *pin) complete(ctx context.Context, height uint64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.processed++
 	p.completed = append(p.completed, height)
 	sort.Sort(uint64s(p.completed))
 
 	var (
-		max = p.height
+		max = p.done
 		i   int
 	)
 	for i = 0; i < len(p.completed); i++ {
@@ -243,27 +463,41 @@ func (p *pin) complete(ctx context.Context, height uint64) error {
 		}
 		max = p.completed[i]
 	}
+	p.completed = p.completed[i:]
 
-	if max == p.height {
+	if max > p.done {
+		p.done = max
+		t, ok := p.times[max]
+		if ok {
+			p.heightTime = t
+		}
+		for h := range p.times {
+			if h <= max {
+				delete(p.times, h)
+			}
+		}
+	}
+
+	now := p.clock.Now()
+	if !p.shouldSave(p.done, now) {
 		return nil
 	}
 
 	const q = `UPDATE block_processors SET height=$1 WHERE height<$1 AND name=$2`
-	_, err := p.db.ExecContext(ctx, q, max, p.name)
+	_, err := p.db.ExecContext(ctx, q, p.done, p.name)
 	if err != nil {
 		return err
 	}
 
 	const notifyQ = `SELECT pg_notify($1, $2)`
-	_, err = p.db.ExecContext(ctx, notifyQ, "pin-"+p.name, max)
+	_, err = p.db.ExecContext(ctx, notifyQ, "pin-"+p.name, p.done)
 	if err != nil {
 		return err
 	}
 
-	p.completed = p.completed[i:]
-	p.height = max
+	p.height = p.done
+	p.savedAt = now
 	p.cond.Broadcast()
-
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/clock"
 	"chain/database/pg/pgtest"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -67,7 +68,7 @@ func TestWaitForPin(t *testing.T) {
 	dbtx := pgtest.NewTx(t)
 	ctx := context.Background()
 
-	p := newPin(dbtx, "test", 0)
+	p := newPin(dbtx, clock.Real, "test", 0)
 	s := &Store{pins: map[string]*pin{"test": p}}
 
 	sctx, cancel := context.WithTimeout(ctx, time.Second)
@@ -127,3 +128,75 @@ func TestProcessBlocks(t *testing.T) {
 		t.Errorf("processed block heights, got %#v want %#v", blockHeights, want)
 	}
 }
+
+func TestAdaptivePin(t *testing.T) {
+	p := newPin(nil, clock.Real, "test", 0)
+	if n := p.workers(1); n != 1 {
+		t.Errorf("workers near tip = %d, want 1", n)
+	}
+	if n := p.workers(1000); n != processorWorkers {
+		t.Errorf("workers catching up = %d, want %d", n, processorWorkers)
+	}
+	for i := 0; i < 20; i++ {
+		p.recordLatency(10 * slowCallback)
+	}
+	if n := p.workers(1000); n != processorWorkers/2 {
+		t.Errorf("workers with slow callback = %d, want %d", n, processorWorkers/2)
+	}
+
+	now := time.Now()
+	p.setTip(1000)
+	if p.shouldSave(1, now) {
+		t.Error("shouldSave(1) while catching up = true, want false")
+	}
+	if !p.shouldSave(maxSaveBatch, now) {
+		t.Errorf("shouldSave(%d) = false, want true", maxSaveBatch)
+	}
+	if !p.shouldSave(1, now.Add(maxSaveDelay)) {
+		t.Error("shouldSave after delay = false, want true")
+	}
+	if !p.shouldSave(995, now) {
+		t.Error("shouldSave near tip = false, want true")
+	}
+}
+
+func TestPinStats(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(nil)
+	p := newPin(nil, clock.Real, "test", 0)
+	p.setTip(1000) // catching up, so complete doesn't save
+	s.pins["test"] = p
+
+	t1 := time.Unix(1, 0)
+	t2 := time.Unix(2, 0)
+	p.recordTime(2, t2)
+	err := p.complete(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	p.recordTime(1, t1)
+	err = p.complete(ctx, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	got := s.Stats()
+	want := []Stats{{Name: "test", Height: 2, Time: t2, Processed: 2}}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v want %+v", got, want)
+	}
+	if len(p.times) != 0 {
+		t.Errorf("pin kept %d block times, want 0", len(p.times))
+	}
+
+	// Waiters see only saved heights.
+	h := p.getHeight()
+	if h != 0 {
+		t.Errorf("getHeight() = %d before saving, want 0", h)
+	}
+	select {
+	case <-s.PinWaiter("test", 1):
+		t.Error("PinWaiter(1) fired before the height was saved")
+	default:
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"math"
 
+	"chain/core/leader"
 	"chain/core/query"
 	"chain/core/query/filter"
 	"chain/errors"
 	"chain/net/http/httpjson"
+	"chain/protocol/bc"
 )
 
 // listAccounts is an http handler for listing accounts matching
@@ -81,25 +83,218 @@ func (a *API) listBalances(ctx context.Context, in requestQuery) (result page, e
 		sumBy = append(sumBy, f)
 	}
 
-	timestampMS := in.TimestampMS
+	timestampMS, height, err := a.asOf(in)
+	if err != nil {
+		return result, err
+	}
+
+	// TODO(jackson): paginate this endpoint.
+	balances, err := a.indexer.Balances(ctx, in.Filter, in.FilterParams, sumBy, timestampMS, height)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = httpjson.Array(balances)
+	result.LastPage = true
+	result.Next = in
+	return result, nil
+}
+
+// asOf returns the point in time a point-in-time query asks for:
+// a timestamp, which defaults to now, or a block height, which
+// must have been indexed. A height of 0 means none was given.
+func (a *API) asOf(in requestQuery) (timestampMS, height uint64, err error) {
+	timestampMS = in.TimestampMS
+	if in.AsOfTimestampMS != 0 {
+		if timestampMS != 0 && timestampMS != in.AsOfTimestampMS {
+			return 0, 0, errors.WithDetail(httpjson.ErrBadRequest, "timestamp and as_of_timestamp differ")
+		}
+		timestampMS = in.AsOfTimestampMS
+	}
+	if in.AsOfHeight != 0 {
+		if timestampMS != 0 {
+			return 0, 0, errors.WithDetail(httpjson.ErrBadRequest, "as_of_block_height can't be given with a timestamp")
+		}
+		indexed, _ := a.pinStore.PinHeight(query.TxPinName)
+		if in.AsOfHeight > indexed {
+			return 0, 0, errors.WithDetailf(httpjson.ErrBadRequest, "as_of_block_height is after the last block indexed, %d", indexed)
+		}
+		return 0, in.AsOfHeight, nil
+	}
 	if timestampMS == 0 {
 		timestampMS = math.MaxInt64
 	} else if timestampMS > math.MaxInt64 {
-		return result, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
+		return 0, 0, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
 	}
+	return timestampMS, 0, nil
+}
 
-	// TODO(jackson): paginate this endpoint.
-	balances, err := a.indexer.Balances(ctx, in.Filter, in.FilterParams, sumBy, timestampMS)
+// listDistinctValues is an http handler for counting annotated
+// outputs by each distinct value of a field, for outputs created
+// in a time range and matching an ad-hoc filter.
+//
+// POST /list-distinct-values
+func (a *API) listDistinctValues(ctx context.Context, in requestQuery) (result page, err error) {
+	if in.Field == "" {
+		return result, errors.WithDetail(httpjson.ErrBadRequest, "field is required")
+	}
+	field, err := filter.ParseField(in.Field)
 	if err != nil {
 		return result, err
 	}
 
-	result.Items = httpjson.Array(balances)
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+
+	endTimeMS := in.EndTimeMS
+	if endTimeMS == 0 {
+		endTimeMS = math.MaxInt64
+	} else if endTimeMS > math.MaxInt64 {
+		return result, errors.WithDetail(httpjson.ErrBadRequest, "end timestamp is too large")
+	}
+
+	values, err := a.indexer.DistinctValues(ctx, in.Filter, in.FilterParams, field, in.StartTimeMS, endTimeMS, limit)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = httpjson.Array(values)
+	result.LastPage = true
+	result.Next = in
+	return result, nil
+}
+
+// listAssetVelocities is an http handler for measuring how much of
+// each asset changed hands in a range of blocks, among the annotated
+// outputs matching an ad-hoc filter.
+//
+// POST /list-asset-velocities
+func (a *API) listAssetVelocities(ctx context.Context, in requestQuery) (result page, err error) {
+	start, end, err := a.heightRange(in)
+	if err != nil {
+		return result, err
+	}
+	velocities, err := a.indexer.AssetVelocity(ctx, in.Filter, in.FilterParams, start, end)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = httpjson.Array(velocities)
+	result.LastPage = true
+	result.Next = in
+	return result, nil
+}
+
+// listHoldingTimes is an http handler for the distribution of
+// how long the annotated outputs matching an ad-hoc filter and
+// spent in a range of blocks had been unspent, for each asset.
+//
+// POST /list-holding-times
+func (a *API) listHoldingTimes(ctx context.Context, in requestQuery) (result page, err error) {
+	start, end, err := a.heightRange(in)
+	if err != nil {
+		return result, err
+	}
+	times, err := a.indexer.HoldingTimes(ctx, in.Filter, in.FilterParams, start, end, in.BucketBoundsMS)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = httpjson.Array(times)
 	result.LastPage = true
 	result.Next = in
 	return result, nil
 }
 
+func (a *API) heightRange(in requestQuery) (start, end uint64, err error) {
+	end = in.EndHeight
+	if end == 0 {
+		end = a.chain.Height() + 1
+	}
+	if end > math.MaxInt64 {
+		return 0, 0, errors.WithDetail(httpjson.ErrBadRequest, "end block height is too large")
+	}
+	if in.StartHeight > end {
+		return 0, 0, errors.WithDetail(httpjson.ErrBadRequest, "start block height is after end block height")
+	}
+	return in.StartHeight, end, nil
+}
+
+// listTagHistory is an http handler for listing the earlier
+// tags of an account or asset, newest first.
+//
+// POST /list-tag-history
+func (a *API) listTagHistory(ctx context.Context, in struct {
+	AccountID string `json:"account_id"`
+	AssetID   string `json:"asset_id"`
+}) (interface{}, error) {
+	var objectType, id string
+	switch {
+	case in.AccountID != "" && in.AssetID == "":
+		objectType, id = "account", in.AccountID
+	case in.AssetID != "" && in.AccountID == "":
+		objectType, id = "asset", in.AssetID
+	default:
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "exactly one of account_id or asset_id is required")
+	}
+
+	changes, err := a.indexer.TagHistory(ctx, objectType, id)
+	if err != nil {
+		return nil, err
+	}
+	return httpjson.Array(changes), nil
+}
+
+// listAnnotatedChanges is an http handler for listing the changes
+// to the annotated data after a cursor, so that a downstream copy
+// can be kept in sync without querying everything again. The last
+// page is the first one without changes; with a timeout, a request
+// that would return none waits up to that long for a new block.
+//
+// POST /list-annotated-changes
+func (a *API) listAnnotatedChanges(ctx context.Context, in requestQuery) (result page, err error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+
+	var after query.ChangesAfter
+	if in.After != "" {
+		after, err = query.DecodeChangesAfter(in.After)
+		if err != nil {
+			return result, errors.Wrap(err, "decoding `after`")
+		}
+	}
+
+	changes, next, err := a.indexer.Changes(ctx, after, limit)
+	if err != nil {
+		return result, err
+	}
+	timeout := in.Timeout.Duration
+	if len(changes) == 0 && timeout != 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		select {
+		case <-ctx.Done():
+		case <-a.pinStore.PinWaiter(query.TxPinName, next.Height+1):
+			changes, next, err = a.indexer.Changes(ctx, next, limit)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	out := in
+	out.After = next.String()
+	return page{
+		Items:    httpjson.Array(changes),
+		LastPage: len(changes) == 0,
+		Next:     out,
+	}, nil
+}
+
 // listTransactions is an http handler for listing transactions matching
 // an index or an ad-hoc filter.
 //
@@ -136,6 +331,15 @@ func (a *API) listTransactions(ctx context.Context, in requestQuery) (result pag
 		if err != nil {
 			return result, err
 		}
+		// Pin the list to the blocks indexed so far, so that
+		// paging through it doesn't skip or repeat transactions
+		// as new blocks land. Long polls wait for new blocks,
+		// so the indexer limits each of their queries to the
+		// blocks indexed at the time instead.
+		indexed, ok := a.pinStore.PinHeight(query.TxPinName)
+		if ok && !in.AscLongPoll {
+			after = after.Snapshot(indexed)
+		}
 	}
 
 	txns, nextAfter, err := a.indexer.Transactions(ctx, in.Filter, in.FilterParams, after, limit, in.AscLongPoll)
@@ -192,13 +396,11 @@ func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result p
 		}
 	}
 
-	timestampMS := in.TimestampMS
-	if timestampMS == 0 {
-		timestampMS = math.MaxInt64
-	} else if timestampMS > math.MaxInt64 {
-		return result, errors.WithDetail(httpjson.ErrBadRequest, "timestamp is too large")
+	timestampMS, height, err := a.asOf(in)
+	if err != nil {
+		return result, err
 	}
-	outputs, nextAfter, err := a.indexer.Outputs(ctx, in.Filter, in.FilterParams, timestampMS, after, limit)
+	outputs, nextAfter, err := a.indexer.Outputs(ctx, in.Filter, in.FilterParams, timestampMS, height, after, limit)
 	if err != nil {
 		return result, errors.Wrap(err, "querying outputs")
 	}
@@ -211,3 +413,51 @@ func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result p
 		Next:     outQuery,
 	}, nil
 }
+
+const (
+	defProvenanceHops = 3
+	maxProvenanceHops = 20
+)
+
+// getOutputProvenance is an http handler for the graph of
+// transactions an output came from, going back up to hops
+// transactions through the outputs they spent.
+//
+// POST /get-output-provenance
+func (a *API) getOutputProvenance(ctx context.Context, in struct {
+	OutputID bc.Hash `json:"output_id"`
+	Hops     int     `json:"hops"`
+}) (*query.Provenance, error) {
+	hops := in.Hops
+	if hops == 0 {
+		hops = defProvenanceHops
+	}
+	if hops < 0 || hops > maxProvenanceHops {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "hops must be between 1 and %d", maxProvenanceHops)
+	}
+	return a.indexer.Provenance(ctx, in.OutputID, hops)
+}
+
+// reindexTransactions is an http handler that starts rebuilding
+// the annotated transactions, inputs and outputs of the blocks
+// from from_block_height through the height the indexer has
+// reached. It returns once the reindex has started. Its progress
+// is reported by /health, under the "reindex" key.
+//
+// POST /reindex-transactions
+func (a *API) reindexTransactions(ctx context.Context, in struct {
+	FromBlockHeight uint64 `json:"from_block_height"`
+}) (x query.ReindexStats, err error) {
+	if !a.indexTxs {
+		return x, errors.WithDetail(query.ErrBadReindexHeight, "transactions are not indexed")
+	}
+	if a.leader.State() != leader.Leading {
+		err = a.forwardToLeader(ctx, "/reindex-transactions", in, &x)
+		return x, err
+	}
+	err = a.indexer.RequestReindex(ctx, in.FromBlockHeight)
+	if err != nil {
+		return x, err
+	}
+	return a.indexer.ReindexStats(), nil
+}
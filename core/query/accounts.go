@@ -18,13 +18,26 @@ func (ind *Indexer) SaveAnnotatedAccount(ctx context.Context, account *Annotated
 		return errors.Wrap(err)
 	}
 
+	// If the account's alias changes, queue a backfill
+	// of the aliases on its inputs and outputs.
 	const q = `
-		INSERT INTO annotated_accounts (id, alias, keys, quorum, tags)
-		VALUES($1, $2, $3::jsonb, $4, $5::jsonb)
-		ON CONFLICT (id) DO UPDATE SET tags = $5::jsonb
+		WITH old AS (
+			SELECT alias FROM annotated_accounts WHERE id = $1
+		), saved AS (
+			INSERT INTO annotated_accounts (id, alias, keys, quorum, tags)
+			VALUES($1, $2, $3::jsonb, $4, $5::jsonb)
+			ON CONFLICT (id) DO UPDATE SET tags = $5::jsonb,
+				alias = COALESCE(NULLIF($2, ''), annotated_accounts.alias)
+			RETURNING alias
+		)
+		INSERT INTO alias_backfills (object_type, object_id, alias, block_height)
+			SELECT 'account', $1, saved.alias, $6
+			FROM old, saved WHERE old.alias <> saved.alias
+		ON CONFLICT (object_type, object_id)
+			DO UPDATE SET alias = excluded.alias, block_height = excluded.block_height
 	`
 	_, err = ind.db.ExecContext(ctx, q, account.ID, account.Alias, keysJSON,
-		account.Quorum, string(*account.Tags))
+		account.Quorum, string(*account.Tags), ind.c.Height())
 	return errors.Wrap(err, "saving annotated account")
 }
 
@@ -0,0 +1,167 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+)
+
+// Annotated inputs and outputs store two copies of their asset and
+// account aliases. The asset_alias and account_alias columns hold
+// the current alias, so that filters and exports by alias don't
+// need to join against annotated_assets or annotated_accounts.
+// The *_alias_at_tx columns hold the alias as of the transaction,
+// and never change.
+//
+// When an alias changes, SaveAnnotatedAsset or SaveAnnotatedAccount
+// queues a backfill in the alias_backfills table, and
+// ProcessAliasBackfills rewrites the current-alias columns.
+//
+// An asset backfill also fills in the asset definition of inputs
+// and outputs indexed before the asset was known, which have an
+// empty one. An asset's definition never changes, so it needs no
+// other backfill.
+
+var (
+	// aliasBackfillBatch is the number of annotated inputs or
+	// outputs each statement of a backfill updates.
+	aliasBackfillBatch = 1000
+
+	// aliasBackfillPeriod is how often ProcessAliasBackfills
+	// checks for queued backfills.
+	aliasBackfillPeriod = 5 * time.Second
+)
+
+// ProcessAliasBackfills updates the current aliases of annotated
+// inputs and outputs whose asset or account alias has changed.
+// It must run only on the leader. It returns when ctx is canceled.
+func (ind *Indexer) ProcessAliasBackfills(ctx context.Context) {
+	if ind.pinStore == nil {
+		return
+	}
+	for {
+		err := ind.backfillAliases(ctx)
+		if err != nil {
+			log.Error(ctx, err, "backfilling aliases")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(aliasBackfillPeriod):
+		}
+	}
+}
+
+type aliasBackfill struct {
+	objectType  string
+	objectID    string
+	alias       string
+	blockHeight uint64
+}
+
+// backfillAliases runs each queued backfill. A backfill stays
+// queued until the transaction indexer has passed the height the
+// chain was at when the alias changed, since blocks annotated
+// before the change may still be indexed with the old alias.
+func (ind *Indexer) backfillAliases(ctx context.Context) error {
+	const q = `SELECT object_type, object_id, alias, block_height FROM alias_backfills`
+	var backfills []aliasBackfill
+	err := pg.ForQueryRows(ctx, ind.db, q, func(objectType, objectID, alias string, height uint64) {
+		backfills = append(backfills, aliasBackfill{objectType, objectID, alias, height})
+	})
+	if err != nil {
+		return errors.Wrap(err, "listing alias backfills")
+	}
+	for _, b := range backfills {
+		indexed := ind.pinStore.Height(TxPinName)
+		err = ind.backfillAlias(ctx, b)
+		if err != nil {
+			return errors.Wrapf(err, "backfilling %s %s", b.objectType, b.objectID)
+		}
+		if indexed <= b.blockHeight {
+			continue
+		}
+
+		// Only remove the backfill if its alias
+		// hasn't changed again in the meantime.
+		const delQ = `
+			DELETE FROM alias_backfills
+			WHERE object_type = $1 AND object_id = $2 AND alias = $3
+		`
+		_, err = ind.db.ExecContext(ctx, delQ, b.objectType, b.objectID, b.alias)
+		if err != nil {
+			return errors.Wrap(err, "deleting alias backfill")
+		}
+	}
+	return nil
+}
+
+// backfillAlias sets the current alias of every annotated
+// input and output of b's asset or account to b's alias,
+// aliasBackfillBatch rows at a time. For an asset, it also sets
+// missing asset definitions to the annotated asset's.
+func (ind *Indexer) backfillAlias(ctx context.Context, b aliasBackfill) error {
+	var queries []string
+	switch b.objectType {
+	case "asset":
+		queries = []string{`
+			WITH a AS (SELECT definition FROM annotated_assets WHERE id = decode($1, 'hex'))
+			UPDATE annotated_outputs SET asset_alias = $2, asset_definition = a.definition
+			FROM a
+			WHERE output_id IN (
+				SELECT output_id FROM annotated_outputs, a
+				WHERE asset_id = decode($1, 'hex') AND (asset_alias <> $2
+					OR asset_definition = '{}'::jsonb AND a.definition <> '{}'::jsonb)
+				LIMIT $3
+			)
+		`, `
+			WITH a AS (SELECT definition FROM annotated_assets WHERE id = decode($1, 'hex'))
+			UPDATE annotated_inputs SET asset_alias = $2, asset_definition = a.definition
+			FROM a
+			WHERE (tx_hash, index) IN (
+				SELECT tx_hash, index FROM annotated_inputs, a
+				WHERE asset_id = decode($1, 'hex') AND (asset_alias <> $2
+					OR asset_definition = '{}'::jsonb AND a.definition <> '{}'::jsonb)
+				LIMIT $3
+			)
+		`}
+	case "account":
+		queries = []string{`
+			UPDATE annotated_outputs SET account_alias = $2
+			WHERE output_id IN (
+				SELECT output_id FROM annotated_outputs
+				WHERE account_id = $1 AND account_alias IS DISTINCT FROM $2
+				LIMIT $3
+			)
+		`, `
+			UPDATE annotated_inputs SET account_alias = $2
+			WHERE (tx_hash, index) IN (
+				SELECT tx_hash, index FROM annotated_inputs
+				WHERE account_id = $1 AND account_alias IS DISTINCT FROM $2
+				LIMIT $3
+			)
+		`}
+	default:
+		return errors.New("unknown alias backfill type " + b.objectType)
+	}
+
+	for _, q := range queries {
+		for {
+			res, err := ind.db.ExecContext(ctx, q, b.objectID, b.alias, aliasBackfillBatch)
+			if err != nil {
+				return errors.Wrap(err, "updating aliases")
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return errors.Wrap(err)
+			}
+			if n < int64(aliasBackfillBatch) {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/pin"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestBackfillAliases(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	pinStore := pin.NewStore(db)
+	err := pinStore.CreatePin(ctx, TxPinName, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	indexer := NewIndexer(db, prottest.NewChain(t), pinStore)
+
+	// An asset imported without an alias, with an output and
+	// an input indexed before it got one.
+	asset := &AnnotatedAsset{
+		ID:              bc.NewAssetID([32]byte{1}),
+		IssuanceProgram: []byte{0xde, 0xad, 0xbe, 0xef},
+		Definition:      raw(`{}`),
+		Tags:            raw(`{}`),
+	}
+	err = indexer.SaveAnnotatedAsset(ctx, asset, "asset1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
+			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount,
+			control_program, reference_data, local, asset_alias_at_tx)
+		VALUES (1, 0, 0, 'ab', 'o1', int8range(1, NULL), 'control', 'receive', $1, '', '{}'::jsonb,
+			false, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, false, '')
+	`, asset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_inputs (tx_hash, index, type, asset_id, asset_alias, asset_definition,
+			asset_tags, asset_local, amount, issuance_program, reference_data, local, spent_output_id,
+			asset_alias_at_tx)
+		VALUES ('cd', 0, 'spend', $1, '', '{}'::jsonb, '{}'::jsonb, false, 10, '', '{}'::jsonb, false, 'o1', '')
+	`, asset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asset.Alias = "gold"
+	err = indexer.SaveAnnotatedAsset(ctx, asset, "asset1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = indexer.backfillAliases(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	for _, table := range []string{"annotated_outputs", "annotated_inputs"} {
+		var alias, aliasAtTx string
+		err = db.QueryRowContext(ctx, `SELECT asset_alias, asset_alias_at_tx FROM `+table).Scan(&alias, &aliasAtTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alias != "gold" || aliasAtTx != "" {
+			t.Errorf("%s aliases = %q, %q, want %q, %q", table, alias, aliasAtTx, "gold", "")
+		}
+	}
+
+	// The backfill stays queued until the transaction
+	// indexer passes the height at which the alias changed.
+	var n int
+	err = db.QueryRowContext(ctx, `SELECT count(*) FROM alias_backfills`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d queued backfills, want 1", n)
+	}
+}
+
+func TestBackfillAssetDefinitions(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	pinStore := pin.NewStore(db)
+	err := pinStore.CreatePin(ctx, TxPinName, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	indexer := NewIndexer(db, prottest.NewChain(t), pinStore)
+
+	// An output and an input indexed before their asset was
+	// known, so without its definition.
+	assetID := bc.NewAssetID([32]byte{2})
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
+			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount,
+			control_program, reference_data, local, asset_alias_at_tx)
+		VALUES (1, 0, 0, 'ab', 'o1', int8range(1, NULL), 'control', 'receive', $1, '', '{}'::jsonb,
+			false, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, false, '')
+	`, assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_inputs (tx_hash, index, type, asset_id, asset_alias, asset_definition,
+			asset_tags, asset_local, amount, issuance_program, reference_data, local, spent_output_id,
+			asset_alias_at_tx)
+		VALUES ('cd', 0, 'spend', $1, '', '{}'::jsonb, '{}'::jsonb, false, 10, '', '{}'::jsonb, false, 'o1', '')
+	`, assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The asset is imported without an alias.
+	asset := &AnnotatedAsset{
+		ID:              assetID,
+		IssuanceProgram: []byte{0xde, 0xad, 0xbe, 0xef},
+		Definition:      raw(`{"name":"gold"}`),
+		Tags:            raw(`{}`),
+	}
+	err = indexer.SaveAnnotatedAsset(ctx, asset, "asset1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = indexer.backfillAliases(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	for _, table := range []string{"annotated_outputs", "annotated_inputs"} {
+		var def string
+		err = db.QueryRowContext(ctx, `SELECT asset_definition::text FROM `+table).Scan(&def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if def != `{"name": "gold"}` {
+			t.Errorf("%s asset_definition = %s, want %s", table, def, `{"name": "gold"}`)
+		}
+	}
+}
@@ -25,6 +25,7 @@ type AnnotatedTx struct {
 	IsLocal                Bool               `json:"is_local"`
 	Inputs                 []*AnnotatedInput  `json:"inputs"`
 	Outputs                []*AnnotatedOutput `json:"outputs"`
+	WashScore              int                `json:"wash_score,omitempty"`
 }
 
 type AnnotatedInput struct {
@@ -63,6 +64,40 @@ type AnnotatedOutput struct {
 	ControlProgram  chainjson.HexBytes `json:"control_program"`
 	ReferenceData   *json.RawMessage   `json:"reference_data"`
 	IsLocal         Bool               `json:"is_local"`
+	Vault           *AnnotatedVault    `json:"vault,omitempty"`
+
+	// AssetAliasAtTx and AccountAliasAtTx are the aliases as of
+	// the output's transaction. AssetAlias and AccountAlias are
+	// the current ones. They are set only by Outputs.
+	AssetAliasAtTx   string `json:"asset_alias_at_transaction,omitempty"`
+	AccountAliasAtTx string `json:"account_alias_at_transaction,omitempty"`
+}
+
+// AnnotatedVault is the state of a rate-limited vault, for
+// outputs controlled by a vault program. WindowStart is nil
+// until the vault's first withdrawal.
+type AnnotatedVault struct {
+	Limit       uint64             `json:"limit"`
+	Period      chainjson.Duration `json:"period"`
+	WindowStart *time.Time         `json:"window_start"`
+	Spent       uint64             `json:"spent"`
+}
+
+func annotatedVault(prog []byte) *AnnotatedVault {
+	v, err := vmutil.ParseVaultProgram(prog)
+	if err != nil {
+		return nil
+	}
+	av := &AnnotatedVault{
+		Limit:  v.Limit,
+		Period: chainjson.Duration{Duration: bc.MillisDuration(v.PeriodMS)},
+		Spent:  v.Spent,
+	}
+	if v.WindowStartMS > 0 {
+		t := time.Unix(0, int64(bc.MillisDuration(v.WindowStartMS))).UTC()
+		av.WindowStart = &t
+	}
+	return av
 }
 
 type AnnotatedAccount struct {
@@ -164,6 +199,8 @@ func buildAnnotatedInput(tx *legacy.Tx, i uint32) *AnnotatedInput {
 	case *bc.Issuance:
 		in.Type = "issue"
 		in.IssuanceProgram = orig.IssuanceProgram()
+	case *bc.UnknownInput:
+		in.Type = "unknown"
 	}
 
 	return in
@@ -181,6 +218,7 @@ func buildAnnotatedOutput(tx *legacy.Tx, idx int) *AnnotatedOutput {
 		Amount:          orig.Amount,
 		ControlProgram:  orig.ControlProgram,
 		ReferenceData:   &emptyJSONObject,
+		Vault:           annotatedVault(orig.ControlProgram),
 	}
 	if pg.IsValidJSONB(orig.ReferenceData) {
 		referenceData := json.RawMessage(orig.ReferenceData)
@@ -194,9 +232,10 @@ func buildAnnotatedOutput(tx *legacy.Tx, idx int) *AnnotatedOutput {
 	return out
 }
 
-// localAnnotator depends on the asset and account annotators and
-// must be run after them.
-func localAnnotator(ctx context.Context, txs []*AnnotatedTx) {
+// localAnnotator marks the transactions, inputs and outputs
+// involving the Core's accounts and assets local. It uses
+// the annotations of the asset and account annotators.
+func localAnnotator(ctx context.Context, txs []*AnnotatedTx) error {
 	for _, tx := range txs {
 		for _, in := range tx.Inputs {
 			if in.AccountID != "" {
@@ -213,4 +252,5 @@ func localAnnotator(ctx context.Context, txs []*AnnotatedTx) {
 			}
 		}
 	}
+	return nil
 }
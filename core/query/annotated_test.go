@@ -0,0 +1,59 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	chainjson "chain/encoding/json"
+	"chain/protocol/vm/vmutil"
+)
+
+func TestAnnotatedVault(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &vmutil.Vault{
+		PubKeys:       []ed25519.PublicKey{pub},
+		Quorum:        1,
+		Limit:         100,
+		PeriodMS:      3600000,
+		WindowStartMS: 1500000000000,
+		Spent:         30,
+	}
+	prog, err := vmutil.VaultProgram(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windowStart := time.Unix(1500000000, 0).UTC()
+	want := &AnnotatedVault{
+		Limit:       100,
+		Period:      chainjson.Duration{Duration: time.Hour},
+		WindowStart: &windowStart,
+		Spent:       30,
+	}
+	got := annotatedVault(prog)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotatedVault = %+v want %+v", got, want)
+	}
+
+	zero, err := vmutil.VaultProgram(v.Zero())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = annotatedVault(zero)
+	if got == nil || got.WindowStart != nil || got.Spent != 0 {
+		t.Errorf("annotatedVault(zero state) = %+v want no window start and nothing spent", got)
+	}
+
+	p2sp, err := vmutil.P2SPMultiSigProgram([]ed25519.PublicKey{pub}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = annotatedVault(p2sp)
+	if got != nil {
+		t.Errorf("annotatedVault(P2SP program) = %+v want nil", got)
+	}
+}
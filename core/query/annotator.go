@@ -0,0 +1,133 @@
+package query
+
+import (
+	"context"
+	"strings"
+
+	"chain/errors"
+)
+
+// Annotator names of the annotators
+// the Core registers itself.
+const (
+	AssetAnnotator   = "asset"
+	AccountAnnotator = "account"
+	LocalAnnotator   = "local"
+)
+
+// ErrBadAnnotator is returned by RegisterAnnotator for an
+// annotator whose name is taken, or whose ordering
+// constraints can't be satisfied.
+var ErrBadAnnotator = errors.New("invalid annotator")
+
+// An Annotator adds annotations to transactions, inputs and
+// outputs as they are indexed. Annotators run in an order that
+// satisfies each one's After constraints.
+type Annotator interface {
+	// Name identifies the annotator, so
+	// that others can be ordered after it.
+	Name() string
+
+	// After returns the names of the annotators
+	// whose annotations this one uses, which
+	// must run before it, if they are registered.
+	After() []string
+
+	// Annotate annotates the transactions of a block.
+	Annotate(ctx context.Context, txs []*AnnotatedTx) error
+}
+
+// NewAnnotator returns an Annotator named name, which
+// annotates with f, after the annotators named in after.
+func NewAnnotator(name string, f func(context.Context, []*AnnotatedTx) error, after ...string) Annotator {
+	return &funcAnnotator{name: name, f: f, after: after}
+}
+
+type funcAnnotator struct {
+	name  string
+	f     func(context.Context, []*AnnotatedTx) error
+	after []string
+}
+
+func (a *funcAnnotator) Name() string    { return a.name }
+func (a *funcAnnotator) After() []string { return a.after }
+
+func (a *funcAnnotator) Annotate(ctx context.Context, txs []*AnnotatedTx) error {
+	return a.f(ctx, txs)
+}
+
+// RegisterAnnotator adds an annotator capable of mutating the
+// annotated transaction objects. Registered annotators run in
+// registration order, except where that breaks an annotator's
+// After constraint. It returns ErrBadAnnotator if an annotator
+// of the same name is registered, or if the constraints form a
+// cycle. It must be called before the indexer starts.
+func (ind *Indexer) RegisterAnnotator(a Annotator) error {
+	for _, reg := range ind.annotators {
+		if reg.Name() == a.Name() {
+			return errors.WithDetailf(ErrBadAnnotator, "annotator %q is already registered", a.Name())
+		}
+	}
+	sorted, err := sortAnnotators(append(ind.annotators, a))
+	if err != nil {
+		return err
+	}
+	ind.annotators = sorted
+	return nil
+}
+
+// sortAnnotators orders as topologically by their After
+// constraints, keeping the given order where it can.
+// Constraints naming unregistered annotators are ignored.
+func sortAnnotators(as []Annotator) ([]Annotator, error) {
+	index := make(map[string]int, len(as))
+	for i, a := range as {
+		index[a.Name()] = i
+	}
+	before := make([][]int, len(as)) // before[i] must run before as[i]
+	for i, a := range as {
+		for _, name := range a.After() {
+			j, ok := index[name]
+			if ok {
+				before[i] = append(before[i], j)
+			}
+		}
+	}
+
+	var (
+		sorted []Annotator
+		done   = make([]bool, len(as))
+	)
+	for len(sorted) < len(as) {
+		// Take the first annotator not yet
+		// run whose constraints are met.
+		next := -1
+		for i := range as {
+			if !done[i] && allDone(before[i], done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var names []string
+			for i, a := range as {
+				if !done[i] {
+					names = append(names, a.Name())
+				}
+			}
+			return nil, errors.WithDetailf(ErrBadAnnotator, "annotators %s must each run after another", strings.Join(names, ", "))
+		}
+		done[next] = true
+		sorted = append(sorted, as[next])
+	}
+	return sorted, nil
+}
+
+func allDone(is []int, done []bool) bool {
+	for _, i := range is {
+		if !done[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"chain/errors"
+	"chain/testutil"
+)
+
+func nopAnnotator(name string, after ...string) Annotator {
+	return NewAnnotator(name, func(context.Context, []*AnnotatedTx) error { return nil }, after...)
+}
+
+func annotatorNames(as []Annotator) []string {
+	var names []string
+	for _, a := range as {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+func TestRegisterAnnotatorOrder(t *testing.T) {
+	ind := NewIndexer(nil, nil, nil)
+	for _, a := range []Annotator{
+		nopAnnotator("risk", LocalAnnotator, "missing"),
+		nopAnnotator(AssetAnnotator),
+		nopAnnotator(AccountAnnotator),
+		nopAnnotator("memo"),
+	} {
+		err := ind.RegisterAnnotator(a)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	got := annotatorNames(ind.annotators)
+	want := []string{AssetAnnotator, AccountAnnotator, LocalAnnotator, "risk", "memo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotators = %v want %v", got, want)
+	}
+}
+
+func TestRegisterAnnotatorErrors(t *testing.T) {
+	ind := NewIndexer(nil, nil, nil)
+	err := ind.RegisterAnnotator(nopAnnotator(AssetAnnotator))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = ind.RegisterAnnotator(nopAnnotator(AssetAnnotator))
+	if errors.Root(err) != ErrBadAnnotator {
+		t.Errorf("duplicate annotator error = %v want %v", err, ErrBadAnnotator)
+	}
+
+	err = ind.RegisterAnnotator(nopAnnotator("a", "b"))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = ind.RegisterAnnotator(nopAnnotator("b", "a"))
+	if errors.Root(err) != ErrBadAnnotator {
+		t.Errorf("cyclic annotator error = %v want %v", err, ErrBadAnnotator)
+	}
+
+	// A rejected annotator leaves the order unchanged.
+	got := annotatorNames(ind.annotators)
+	want := []string{AssetAnnotator, LocalAnnotator, "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotators = %v want %v", got, want)
+	}
+}
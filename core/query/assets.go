@@ -18,14 +18,32 @@ func (ind *Indexer) SaveAnnotatedAsset(ctx context.Context, asset *AnnotatedAsse
 		return errors.Wrap(err)
 	}
 
+	// An asset's alias can be set after it is first saved, when
+	// an asset without one is imported. If it changes, queue a
+	// backfill of the aliases on its inputs and outputs. Queue
+	// one too when the asset is first saved, since its inputs
+	// and outputs may have been indexed before it was known,
+	// without its definition, as when it is imported.
 	const q = `
-		INSERT INTO annotated_assets
-			(id, sort_id, alias, issuance_program, keys, quorum, definition, tags, local)
-		VALUES($1, $2, $3, $4, $5, $6, $7::jsonb, $8::jsonb, $9)
-		ON CONFLICT (id) DO UPDATE SET sort_id = $2, tags = $8::jsonb
+		WITH old AS (
+			SELECT alias FROM annotated_assets WHERE id = $1::bytea
+		), saved AS (
+			INSERT INTO annotated_assets
+				(id, sort_id, alias, issuance_program, keys, quorum, definition, tags, local)
+			VALUES($1, $2, $3, $4, $5, $6, $7::jsonb, $8::jsonb, $9)
+			ON CONFLICT (id) DO UPDATE SET sort_id = $2, tags = $8::jsonb,
+				alias = COALESCE(NULLIF($3, ''), annotated_assets.alias)
+			RETURNING alias
+		)
+		INSERT INTO alias_backfills (object_type, object_id, alias, block_height)
+			SELECT 'asset', encode($1::bytea, 'hex'), saved.alias, $10
+			FROM saved LEFT JOIN old ON true WHERE old.alias IS DISTINCT FROM saved.alias
+		ON CONFLICT (object_type, object_id)
+			DO UPDATE SET alias = excluded.alias, block_height = excluded.block_height
 	`
 	_, err = ind.db.ExecContext(ctx, q, asset.ID, sortID, asset.Alias, []byte(asset.IssuanceProgram),
-		keysJSON, asset.Quorum, string(*asset.Definition), string(*asset.Tags), bool(asset.IsLocal))
+		keysJSON, asset.Quorum, string(*asset.Definition), string(*asset.Tags), bool(asset.IsLocal),
+		ind.c.Height())
 	return errors.Wrap(err, "saving annotated asset")
 }
 
@@ -3,7 +3,7 @@ package query
 import (
 	"bytes"
 	"context"
-	"fmt"
+	"encoding/json"
 	"strconv"
 
 	"github.com/lib/pq"
@@ -12,8 +12,12 @@ import (
 	"chain/errors"
 )
 
-// Balances performs a balances query against the annotated_outputs.
-func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{}, sumBy []filter.Field, timestampMS uint64) ([]interface{}, error) {
+// Balances performs a balances query against the annotated_outputs,
+// summing the outputs unspent at timestampMS or, if height is
+// nonzero, at the end of the block at height. Each sum is
+// reported exactly, as a JSON number, even if it exceeds the
+// largest valid amount of an asset.
+func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{}, sumBy []filter.Field, timestampMS, height uint64) ([]interface{}, error) {
 	p, err := filter.Parse(filt, outputsTable, vals)
 	if err != nil {
 		return nil, err
@@ -25,7 +29,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 	if err != nil {
 		return nil, err
 	}
-	queryStr, queryArgs, err := constructBalancesQuery(expr, vals, sumBy, timestampMS)
+	queryStr, queryArgs, err := constructBalancesQuery(expr, vals, sumBy, timestampMS, height)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +42,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 	var balances []interface{}
 	for rows.Next() {
 		// balance and groupings will hold the output of the row scan
-		var balance uint64
+		var balance json.Number
 		scanArguments := make([]interface{}, 0, len(sumBy)+1)
 		scanArguments = append(scanArguments, &balance)
 		for range sumBy {
@@ -57,7 +61,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 		// This struct enforces JSON field ordering in API output.
 		item := struct {
 			SumBy  map[string]interface{} `json:"sum_by,omitempty"`
-			Amount uint64                 `json:"amount"`
+			Amount json.Number            `json:"amount"`
 		}{
 			Amount: balance,
 		}
@@ -69,7 +73,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 	return balances, errors.Wrap(rows.Err())
 }
 
-func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Field, timestampMS uint64) (string, []interface{}, error) {
+func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Field, timestampMS, height uint64) (string, []interface{}, error) {
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT COALESCE(SUM(amount), 0)")
@@ -91,9 +95,7 @@ func constructBalancesQuery(expr string, vals []interface{}, sumBy []filter.Fiel
 		buf.WriteString(") AND ")
 	}
 
-	vals = append(vals, timestampMS)
-	timestampValIndex := len(vals)
-	buf.WriteString(fmt.Sprintf("timespan @> $%d::int8", timestampValIndex))
+	vals = unspentAsOf(&buf, vals, timestampMS, height)
 
 	if len(sumBy) > 0 {
 		buf.WriteString(" GROUP BY ")
@@ -0,0 +1,260 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// Kinds of Change.
+const (
+	TxChange    = "transaction"
+	SpentChange = "spent_output"
+	TagsChange  = "tags"
+)
+
+// A Change is a change to the annotated data, for keeping a
+// downstream copy of it in sync. Which fields are set depends on
+// its Kind: a transaction change carries the annotated transaction;
+// a spent-output change, the ID of an annotated output spent at
+// BlockHeight; a tags change, the current tags of an account or
+// asset whose tags were updated.
+type Change struct {
+	Kind        string           `json:"kind"`
+	BlockHeight uint64           `json:"block_height,omitempty"`
+	Transaction *json.RawMessage `json:"transaction,omitempty"`
+	OutputID    *bc.Hash         `json:"output_id,omitempty"`
+	ObjectType  string           `json:"object_type,omitempty"`
+	ObjectID    string           `json:"object_id,omitempty"`
+	Tags        *json.RawMessage `json:"tags,omitempty"`
+}
+
+// ChangesAfter is the position in the changes
+// after the last one returned by a call to Changes.
+type ChangesAfter struct {
+	// Height is the height of the last block whose
+	// transactions and spent outputs were returned.
+	Height uint64
+
+	// TagSeq is the sequence number of
+	// the last tag update returned.
+	TagSeq uint64
+}
+
+func (after ChangesAfter) String() string {
+	return fmt.Sprintf("%d:%d", after.Height, after.TagSeq)
+}
+
+// DecodeChangesAfter decodes a ChangesAfter
+// formatted with its String method. It also accepts
+// the older "height:txid:seq" format, ignoring the
+// transaction ID.
+func DecodeChangesAfter(str string) (c ChangesAfter, err error) {
+	parts := strings.Split(str, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return c, errors.WithDetailf(ErrBadAfter, "want 2 or 3 fields, got %d", len(parts))
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return c, errors.Sub(ErrBadAfter, err)
+	}
+	seq, err := strconv.ParseUint(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		return c, errors.Sub(ErrBadAfter, err)
+	}
+	if height > math.MaxInt64 || seq > math.MaxInt64 {
+		return c, errors.Wrap(ErrBadAfter)
+	}
+	return ChangesAfter{Height: height, TagSeq: seq}, nil
+}
+
+// Changes returns the changes to the annotated data after the
+// position after, and the position after the last one returned.
+//
+// It returns the transactions and spent outputs of whole blocks,
+// oldest first, up to the last block indexed, stopping once it
+// has returned limit transactions. (A single block with more
+// transactions than limit is still returned whole.) It also
+// returns up to limit tag updates, oldest first. Applying the
+// changes in order brings a copy of the annotated transactions
+// and outputs, and of account and asset tags, up to date.
+func (ind *Indexer) Changes(ctx context.Context, after ChangesAfter, limit int) ([]Change, ChangesAfter, error) {
+	indexed := ind.pinStore.Height(TxPinName)
+	changes, next, err := ind.blockChanges(ctx, after, indexed, limit)
+	if err != nil {
+		return nil, after, err
+	}
+	tagChanges, next, err := ind.tagChanges(ctx, next, limit)
+	if err != nil {
+		return nil, after, err
+	}
+	return append(changes, tagChanges...), next, nil
+}
+
+func (ind *Indexer) blockChanges(ctx context.Context, after ChangesAfter, indexed uint64, limit int) ([]Change, ChangesAfter, error) {
+	if after.Height >= indexed {
+		return nil, after, nil
+	}
+
+	const txQ = `
+		SELECT block_height, data FROM annotated_txs
+		WHERE block_height > $1 AND block_height <= $2
+		ORDER BY block_height, tx_pos
+		LIMIT $3
+	`
+	var txs []Change
+	rows, err := ind.db.QueryContext(ctx, txQ, after.Height, indexed, limit)
+	if err != nil {
+		return nil, after, errors.Wrap(err, "querying annotated transactions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c    = Change{Kind: TxChange}
+			data json.RawMessage
+		)
+		err := rows.Scan(&c.BlockHeight, &data)
+		if err != nil {
+			return nil, after, errors.Wrap(err, "scanning annotated transaction")
+		}
+		c.Transaction = &data
+		txs = append(txs, c)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, after, errors.Wrap(err)
+	}
+
+	// Return only whole blocks. If the limit cut the last block
+	// short, drop it, unless it's the only block, in which case
+	// return all of it.
+	end := indexed
+	if len(txs) == limit && limit > 0 {
+		end = txs[len(txs)-1].BlockHeight
+		if txs[0].BlockHeight == end {
+			txs, err = ind.blockTxChanges(ctx, end)
+			if err != nil {
+				return nil, after, err
+			}
+		} else {
+			end--
+			for len(txs) > 0 && txs[len(txs)-1].BlockHeight > end {
+				txs = txs[:len(txs)-1]
+			}
+		}
+	}
+
+	spent, err := ind.spentChanges(ctx, after.Height, end)
+	if err != nil {
+		return nil, after, err
+	}
+
+	// Interleave the spent outputs with the transactions,
+	// after those of the block that spent them.
+	changes := append(txs, spent...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].BlockHeight < changes[j].BlockHeight
+	})
+	after.Height = end
+	return changes, after, nil
+}
+
+// blockTxChanges returns the transaction changes
+// for all the transactions in the block at height.
+func (ind *Indexer) blockTxChanges(ctx context.Context, height uint64) ([]Change, error) {
+	const q = `
+		SELECT data FROM annotated_txs
+		WHERE block_height = $1
+		ORDER BY tx_pos
+	`
+	var txs []Change
+	rows, err := ind.db.QueryContext(ctx, q, height)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying annotated transactions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data json.RawMessage
+		err := rows.Scan(&data)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning annotated transaction")
+		}
+		txs = append(txs, Change{Kind: TxChange, BlockHeight: height, Transaction: &data})
+	}
+	return txs, errors.Wrap(rows.Err())
+}
+
+// spentChanges returns the spent-output changes for
+// the blocks after height from and up to height to.
+func (ind *Indexer) spentChanges(ctx context.Context, from, to uint64) ([]Change, error) {
+	const q = `
+		SELECT output_id, spent_block_height FROM annotated_outputs
+		WHERE spent_block_height > $1 AND spent_block_height <= $2
+		ORDER BY spent_block_height, block_height, tx_pos, output_index
+	`
+	var spent []Change
+	rows, err := ind.db.QueryContext(ctx, q, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying spent outputs")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c  = Change{Kind: SpentChange}
+			id bc.Hash
+		)
+		err := rows.Scan(&id, &c.BlockHeight)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning spent output")
+		}
+		c.OutputID = &id
+		spent = append(spent, c)
+	}
+	return spent, errors.Wrap(rows.Err())
+}
+
+// tagChanges returns up to limit tag updates after after.TagSeq,
+// each with the current tags of the account or asset updated.
+//
+// Tag updates are recorded in the main database, whatever the
+// database of the query tables, and take a lock that makes them
+// commit in the order of their sequence numbers (see
+// LockTagHistory), so no update can appear behind the position
+// of one already returned.
+func (ind *Indexer) tagChanges(ctx context.Context, after ChangesAfter, limit int) ([]Change, ChangesAfter, error) {
+	const q = `
+		SELECT h.seq, h.object_type, h.object_id, COALESCE(acc.tags, ast.tags, '{}'::jsonb)
+		FROM tag_history h
+		LEFT JOIN accounts acc ON h.object_type = 'account' AND acc.account_id = h.object_id
+		LEFT JOIN asset_tags ast ON h.object_type = 'asset' AND ast.asset_id = decode(h.object_id, 'hex')
+		WHERE h.seq > $1
+		ORDER BY h.seq
+		LIMIT $2
+	`
+	var changes []Change
+	rows, err := ind.mainDB.QueryContext(ctx, q, after.TagSeq, limit)
+	if err != nil {
+		return nil, after, errors.Wrap(err, "querying tag history")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c    = Change{Kind: TagsChange}
+			tags json.RawMessage
+		)
+		err := rows.Scan(&after.TagSeq, &c.ObjectType, &c.ObjectID, &tags)
+		if err != nil {
+			return nil, after, errors.Wrap(err, "scanning tag history row")
+		}
+		c.Tags = &tags
+		changes = append(changes, c)
+	}
+	return changes, after, errors.Wrap(rows.Err())
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"chain/core/pin"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestChanges(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	pinStore := pin.NewStore(db)
+	err := pinStore.CreatePin(ctx, TxPinName, 3)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	indexer := NewIndexer(db, prottest.NewChain(t), pinStore)
+
+	// Two transactions in block 1, creating an output spent
+	// in block 2, and one in each of blocks 2, 3 and 4, where
+	// block 4 isn't indexed yet.
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_txs (block_height, tx_pos, tx_hash, data, timestamp, block_id, local, reference_data)
+		VALUES
+			(1, 0, 'a', '{"id": "a"}', now(), 'b1', false, '{}'),
+			(1, 1, 'b', '{"id": "b"}', now(), 'b1', false, '{}'),
+			(2, 0, 'c', '{"id": "c"}', now(), 'b2', false, '{}'),
+			(3, 0, 'd', '{"id": "d"}', now(), 'b3', false, '{}'),
+			(4, 0, 'e', '{"id": "e"}', now(), 'b4', false, '{}');
+		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
+			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount,
+			control_program, reference_data, local, asset_alias_at_tx, spent_block_height)
+		VALUES (1, 0, 0, 'a', 'o1', int8range(1, 2), 'control', 'receive', 'x', '', '{}'::jsonb,
+			false, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, false, '', 2);
+		INSERT INTO accounts (account_id, alias, tags)
+		VALUES ('acc1', 'alice', '{"tier": 3}');
+		INSERT INTO tag_history (object_type, object_id, tags)
+		VALUES ('account', 'acc1', '{"tier": 1}');
+		INSERT INTO tag_history (object_type, object_id, tags)
+		VALUES ('account', 'acc1', '{"tier": 2}');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A page size of one still returns all of block 1,
+	// and only the first tag update, with the account's
+	// current tags.
+	changes, next, err := indexer.Changes(ctx, ChangesAfter{}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got := describeChanges(changes)
+	want := []string{"transaction 1", "transaction 1", "tags account acc1 {\"tier\": 3}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("first page = %v want %v", got, want)
+	}
+	if next.Height != 1 || next.TagSeq == 0 {
+		t.Errorf("first page next = %v want height 1 and a tag seq", next)
+	}
+
+	changes, next, err = indexer.Changes(ctx, next, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got = describeChanges(changes)
+	want = []string{"transaction 2", "spent_output 2", "transaction 3", "tags account acc1 {\"tier\": 3}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %v want %v", got, want)
+	}
+	if next.Height != 3 {
+		t.Errorf("second page next height = %d want 3", next.Height)
+	}
+
+	after, err := DecodeChangesAfter(next.String())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if after != next {
+		t.Errorf("DecodeChangesAfter(%q) = %v want %v", next.String(), after, next)
+	}
+	changes, _, err = indexer.Changes(ctx, after, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("last page = %v want none", describeChanges(changes))
+	}
+}
+
+func TestDecodeChangesAfter(t *testing.T) {
+	cases := []struct {
+		str  string
+		want ChangesAfter
+	}{
+		{"3:7", ChangesAfter{Height: 3, TagSeq: 7}},
+		{"3:1234:7", ChangesAfter{Height: 3, TagSeq: 7}}, // older format, with a transaction ID
+	}
+	for _, c := range cases {
+		got, err := DecodeChangesAfter(c.str)
+		if err != nil {
+			t.Errorf("DecodeChangesAfter(%q) error = %v", c.str, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("DecodeChangesAfter(%q) = %v want %v", c.str, got, c.want)
+		}
+	}
+	for _, str := range []string{"3", "3:7x", "3:7:", "3:1234:7:8", "x:7", ""} {
+		_, err := DecodeChangesAfter(str)
+		if errors.Root(err) != ErrBadAfter {
+			t.Errorf("DecodeChangesAfter(%q) = %v want %v", str, err, ErrBadAfter)
+		}
+	}
+}
+
+func describeChanges(changes []Change) []string {
+	var a []string
+	for _, c := range changes {
+		switch c.Kind {
+		case TagsChange:
+			a = append(a, c.Kind+" "+c.ObjectType+" "+c.ObjectID+" "+string(*c.Tags))
+		default:
+			a = append(a, c.Kind+" "+strconv.FormatUint(c.BlockHeight, 10))
+		}
+	}
+	return a
+}
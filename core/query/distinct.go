@@ -0,0 +1,85 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chain/core/query/filter"
+	"chain/errors"
+)
+
+// DistinctValues counts the annotated outputs matching the filter
+// for each distinct value of field, considering only outputs created
+// in the time range [startMS, endMS). The values with the most
+// matching outputs are returned first, up to limit values.
+func (ind *Indexer) DistinctValues(ctx context.Context, filt string, vals []interface{}, field filter.Field, startMS, endMS uint64, limit int) ([]interface{}, error) {
+	p, err := filter.Parse(filt, outputsTable, vals)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != p.Parameters {
+		return nil, ErrParameterCountMismatch
+	}
+	expr, err := filter.AsSQL(p, outputsTable, vals)
+	if err != nil {
+		return nil, err
+	}
+	queryStr, queryArgs, err := constructDistinctValuesQuery(expr, vals, field, startMS, endMS, limit)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []interface{}
+	for rows.Next() {
+		// This struct enforces JSON field ordering in API output.
+		var item struct {
+			Value *string `json:"value"`
+			Count uint64  `json:"count"`
+		}
+		var value sql.NullString
+		err := rows.Scan(&value, &item.Count)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning distinct value row")
+		}
+		if value.Valid {
+			item.Value = &value.String
+		}
+		items = append(items, item)
+	}
+	return items, errors.Wrap(rows.Err())
+}
+
+func constructDistinctValuesQuery(expr string, vals []interface{}, field filter.Field, startMS, endMS uint64, limit int) (string, []interface{}, error) {
+	fieldSQL, err := filter.FieldAsSQL(outputsTable, field)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("SELECT ")
+	buf.WriteString(fieldSQL)
+	buf.WriteString(", COUNT(*) FROM ")
+	buf.WriteString(pq.QuoteIdentifier("annotated_outputs"))
+	buf.WriteString(" AS out WHERE ")
+	if len(expr) > 0 {
+		buf.WriteString("(")
+		buf.WriteString(expr)
+		buf.WriteString(") AND ")
+	}
+
+	// The lower bound of an output's timespan is the time
+	// it was created. It's indexed for this query.
+	vals = append(vals, startMS, endMS, limit)
+	buf.WriteString(fmt.Sprintf("lower(out.timespan) >= $%d::int8 AND lower(out.timespan) < $%d::int8", len(vals)-2, len(vals)-1))
+	buf.WriteString(fmt.Sprintf(" GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $%d", len(vals)))
+	return buf.String(), vals, nil
+}
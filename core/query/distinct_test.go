@@ -0,0 +1,64 @@
+package query
+
+import (
+	"testing"
+
+	"chain/core/query/filter"
+	"chain/testutil"
+)
+
+func TestConstructDistinctValuesQuery(t *testing.T) {
+	testCases := []struct {
+		predicate  string
+		field      string
+		values     []interface{}
+		wantQuery  string
+		wantValues []interface{}
+	}{
+		{
+			field:      "asset_alias",
+			wantQuery:  `SELECT out."asset_alias", COUNT(*) FROM "annotated_outputs" AS out WHERE lower(out.timespan) >= $1::int8 AND lower(out.timespan) < $2::int8 GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $3`,
+			wantValues: []interface{}{uint64(10), uint64(20), 100},
+		},
+		{
+			predicate:  "account_alias = $1",
+			field:      "asset_alias",
+			values:     []interface{}{"alice"},
+			wantQuery:  `SELECT out."asset_alias", COUNT(*) FROM "annotated_outputs" AS out WHERE (out."account_alias" = $1) AND lower(out.timespan) >= $2::int8 AND lower(out.timespan) < $3::int8 GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $4`,
+			wantValues: []interface{}{`alice`, uint64(10), uint64(20), 100},
+		},
+		{
+			predicate:  "asset_id = $1",
+			field:      "account_tags.region",
+			values:     []interface{}{"foo"},
+			wantQuery:  `SELECT out."account_tags"->>'region', COUNT(*) FROM "annotated_outputs" AS out WHERE (encode(out."asset_id", 'hex') = $1) AND lower(out.timespan) >= $2::int8 AND lower(out.timespan) < $3::int8 GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $4`,
+			wantValues: []interface{}{`foo`, uint64(10), uint64(20), 100},
+		},
+	}
+
+	for i, tc := range testCases {
+		p, err := filter.Parse(tc.predicate, outputsTable, tc.values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expr, err := filter.AsSQL(p, outputsTable, tc.values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		field, err := filter.ParseField(tc.field)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, values, err := constructDistinctValuesQuery(expr, tc.values, field, 10, 20, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != tc.wantQuery {
+			t.Errorf("case %d: got\n%s\nwant\n%s", i, query, tc.wantQuery)
+		}
+		if !testutil.DeepEqual(values, tc.wantValues) {
+			t.Errorf("case %d: got %#v, want %#v", i, values, tc.wantValues)
+		}
+	}
+}
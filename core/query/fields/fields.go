@@ -0,0 +1,194 @@
+// Package fields declares the fields of annotated objects that
+// query filters can refer to, and which of them are indexed.
+//
+// It also provides helpers for building filters in Go, checking
+// the types of their values at compile time:
+//
+//	f := fields.AssetID.Eq(assetID).And(fields.Amount.Gt(5))
+//	outputs, err := indexer.Outputs(ctx, f.String(), f.Params(), ...)
+package fields
+
+import "chain/core/query/filter"
+
+// A Field is a field of one or more kinds of annotated object,
+// named as in filter expressions.
+type Field struct {
+	Name string
+	Type filter.Type
+}
+
+// A Column describes where a field of one kind of annotated
+// object is stored.
+type Column struct {
+	Field   Field
+	Name    string
+	SQLType filter.SQLType
+
+	// Indexable is true if the column leads a database index,
+	// so that filtering on it for equality is fast. The tests
+	// check it against the indexes in core/schema.sql.
+	Indexable bool
+}
+
+// StringField is a field with string values. Bytes are
+// hex-encoded, and booleans are "yes" or "no".
+type StringField struct{ Field }
+
+// IntField is a field with integer values.
+type IntField struct{ Field }
+
+// ObjectField is a field with JSON object values.
+// Use Key to refer to the values in it.
+type ObjectField struct{ Field }
+
+// AnyField is a value in an object field,
+// which may be of any type.
+type AnyField struct{ Field }
+
+// Key returns the field for the value under key in f.
+func (f ObjectField) Key(key string) ObjectField {
+	return ObjectField{Field{Name: f.Name + "." + key, Type: filter.Object}}
+}
+
+// Value returns the field for the scalar value under key in f.
+func (f ObjectField) Value(key string) AnyField {
+	return AnyField{Field{Name: f.Name + "." + key, Type: filter.Any}}
+}
+
+var (
+	ID                     = StringField{Field{"id", filter.String}}
+	Alias                  = StringField{Field{"alias", filter.String}}
+	Type                   = StringField{Field{"type", filter.String}}
+	Purpose                = StringField{Field{"purpose", filter.String}}
+	IsLocal                = StringField{Field{"is_local", filter.String}}
+	Quorum                 = IntField{Field{"quorum", filter.Integer}}
+	Tags                   = ObjectField{Field{"tags", filter.Object}}
+	Definition             = ObjectField{Field{"definition", filter.Object}}
+	ReferenceData          = ObjectField{Field{"reference_data", filter.Object}}
+	TransactionID          = StringField{Field{"transaction_id", filter.String}}
+	Position               = IntField{Field{"position", filter.Integer}}
+	AssetID                = StringField{Field{"asset_id", filter.String}}
+	AssetAlias             = StringField{Field{"asset_alias", filter.String}}
+	AssetDefinition        = ObjectField{Field{"asset_definition", filter.Object}}
+	AssetTags              = ObjectField{Field{"asset_tags", filter.Object}}
+	AssetIsLocal           = StringField{Field{"asset_is_local", filter.String}}
+	Amount                 = IntField{Field{"amount", filter.Integer}}
+	AccountID              = StringField{Field{"account_id", filter.String}}
+	AccountAlias           = StringField{Field{"account_alias", filter.String}}
+	AccountTags            = ObjectField{Field{"account_tags", filter.Object}}
+	AssetAliasAtTx         = StringField{Field{"asset_alias_at_transaction", filter.String}}
+	AccountAliasAtTx       = StringField{Field{"account_alias_at_transaction", filter.String}}
+	ControlProgram         = StringField{Field{"control_program", filter.String}}
+	IssuanceProgram        = StringField{Field{"issuance_program", filter.String}}
+	SpentOutputID          = StringField{Field{"spent_output_id", filter.String}}
+	SpentOutput            = ObjectField{Field{"spent_output", filter.Object}}
+	Timestamp              = StringField{Field{"timestamp", filter.String}}
+	BlockID                = StringField{Field{"block_id", filter.String}}
+	BlockHeight            = IntField{Field{"block_height", filter.Integer}}
+	BlockTransactionsCount = IntField{Field{"block_transactions_count", filter.Integer}}
+	SpentBlockHeight       = IntField{Field{"spent_block_height", filter.Integer}}
+	LifetimeMS             = IntField{Field{"lifetime_ms", filter.Integer}}
+	WashScore              = IntField{Field{"wash_score", filter.Integer}}
+)
+
+// The columns of each kind of annotated object.
+var (
+	Assets = []Column{
+		{ID.Field, "id", filter.SQLBytea, true},
+		{Alias.Field, "alias", filter.SQLText, false},
+		{IssuanceProgram.Field, "issuance_program", filter.SQLBytea, false},
+		{Quorum.Field, "quorum", filter.SQLInteger, false},
+		{Tags.Field, "tags", filter.SQLJSONB, false},
+		{Definition.Field, "definition", filter.SQLJSONB, false},
+		{IsLocal.Field, "local", filter.SQLBool, false},
+	}
+	Accounts = []Column{
+		{ID.Field, "id", filter.SQLText, true},
+		{Alias.Field, "alias", filter.SQLText, false},
+		{Quorum.Field, "quorum", filter.SQLInteger, false},
+		{Tags.Field, "tags", filter.SQLJSONB, false},
+	}
+	Outputs = []Column{
+		{ID.Field, "output_id", filter.SQLBytea, true},
+		{Type.Field, "type", filter.SQLText, false},
+		{Purpose.Field, "purpose", filter.SQLText, false},
+		{TransactionID.Field, "tx_hash", filter.SQLBytea, false},
+		{Position.Field, "output_index", filter.SQLInteger, false},
+		{AssetID.Field, "asset_id", filter.SQLBytea, false},
+		{AssetAlias.Field, "asset_alias", filter.SQLText, true},
+		{AssetDefinition.Field, "asset_definition", filter.SQLJSONB, false},
+		{AssetTags.Field, "asset_tags", filter.SQLJSONB, false},
+		{AssetIsLocal.Field, "asset_local", filter.SQLBool, false},
+		{Amount.Field, "amount", filter.SQLBigint, false},
+		{AccountID.Field, "account_id", filter.SQLText, false},
+		{AccountAlias.Field, "account_alias", filter.SQLText, true},
+		{AccountTags.Field, "account_tags", filter.SQLJSONB, false},
+
+		// The asset_alias and account_alias columns hold the
+		// current aliases, kept up to date when they change.
+		// These hold the aliases as of the transaction.
+		{AssetAliasAtTx.Field, "asset_alias_at_tx", filter.SQLText, false},
+		{AccountAliasAtTx.Field, "account_alias_at_tx", filter.SQLText, false},
+
+		{ControlProgram.Field, "control_program", filter.SQLBytea, false},
+		{ReferenceData.Field, "reference_data", filter.SQLJSONB, false},
+		{IsLocal.Field, "local", filter.SQLBool, false},
+
+		// The block height at which the output was created and spent,
+		// and the time between, in milliseconds. The last two are
+		// null until the output is spent, and for retirements.
+		{BlockHeight.Field, "block_height", filter.SQLBigint, true},
+		{SpentBlockHeight.Field, "spent_block_height", filter.SQLBigint, true},
+		{LifetimeMS.Field, "lifetime_ms", filter.SQLBigint, false},
+	}
+	Inputs = []Column{
+		{Type.Field, "type", filter.SQLText, false},
+		{AssetID.Field, "asset_id", filter.SQLBytea, false},
+		{AssetAlias.Field, "asset_alias", filter.SQLText, true},
+		{AssetDefinition.Field, "asset_definition", filter.SQLJSONB, false},
+		{AssetTags.Field, "asset_tags", filter.SQLJSONB, false},
+		{AssetIsLocal.Field, "asset_local", filter.SQLBool, false},
+		{Amount.Field, "amount", filter.SQLBigint, false},
+		{AccountID.Field, "account_id", filter.SQLText, false},
+		{AccountAlias.Field, "account_alias", filter.SQLText, true},
+		{AccountTags.Field, "account_tags", filter.SQLJSONB, false},
+
+		// The aliases as of the transaction, as for outputs.
+		{AssetAliasAtTx.Field, "asset_alias_at_tx", filter.SQLText, false},
+		{AccountAliasAtTx.Field, "account_alias_at_tx", filter.SQLText, false},
+
+		{IssuanceProgram.Field, "issuance_program", filter.SQLBytea, false},
+		{ReferenceData.Field, "reference_data", filter.SQLJSONB, false},
+		{IsLocal.Field, "local", filter.SQLBool, false},
+		{SpentOutputID.Field, "spent_output_id", filter.SQLBytea, true},
+		{SpentOutput.Field, "spent_output", filter.SQLJSONB, false},
+	}
+	Transactions = []Column{
+		{ID.Field, "tx_hash", filter.SQLBytea, false},
+		{Timestamp.Field, "timestamp", filter.SQLTimestamp, false},
+		{BlockID.Field, "block_id", filter.SQLBytea, false},
+		{BlockHeight.Field, "block_height", filter.SQLBigint, true},
+		{Position.Field, "tx_pos", filter.SQLInteger, false},
+		{BlockTransactionsCount.Field, "block_tx_count", filter.SQLInteger, false},
+		{ReferenceData.Field, "reference_data", filter.SQLJSONB, false},
+		{IsLocal.Field, "local", filter.SQLBool, false},
+
+		// The number of outputs returning an asset to an
+		// account it recently left, set by the wash annotator.
+		{WashScore.Field, "wash_score", filter.SQLInteger, false},
+	}
+)
+
+// SQLTable returns a table for compiling filters on
+// annotated objects with the given columns.
+func SQLTable(name, alias string, cols []Column) *filter.SQLTable {
+	tbl := &filter.SQLTable{
+		Name:    name,
+		Alias:   alias,
+		Columns: make(map[string]*filter.SQLColumn, len(cols)),
+	}
+	for _, c := range cols {
+		tbl.Columns[c.Field.Name] = &filter.SQLColumn{Name: c.Name, Type: c.Field.Type, SQLType: c.SQLType}
+	}
+	return tbl
+}
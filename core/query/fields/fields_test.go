@@ -0,0 +1,129 @@
+package fields
+
+import (
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"chain/core/query/filter"
+)
+
+func TestFilter(t *testing.T) {
+	cases := []struct {
+		f          Filter
+		cols       []Column
+		wantString string
+		wantParams []interface{}
+	}{
+		{
+			f:          AssetID.Eq("abcd").And(Amount.Gt(5)),
+			cols:       Outputs,
+			wantString: "asset_id = $1 AND amount > $2",
+			wantParams: []interface{}{"abcd", int64(5)},
+		},
+		{
+			f:          AccountAlias.Eq("alice").Or(AccountAlias.Eq("bob")).And(AssetTags.Value("class").Eq("bond")),
+			cols:       Outputs,
+			wantString: "(account_alias = $1 OR account_alias = $2) AND asset_tags.class = $3",
+			wantParams: []interface{}{"alice", "bob", "bond"},
+		},
+		{
+			f:          AnyInput(AccountID.Eq("acc1")).Or(AnyOutput(ReferenceData.Key("a").Value("b").EqInt(1))),
+			cols:       Transactions,
+			wantString: "inputs(account_id = $1) OR outputs(reference_data.a.b = $2)",
+			wantParams: []interface{}{"acc1", int64(1)},
+		},
+		{
+			f:          BlockHeight.Ge(2).And(BlockHeight.Lt(10).And(IsLocal.Eq("yes"))),
+			cols:       Transactions,
+			wantString: "block_height >= $1 AND block_height < $2 AND is_local = $3",
+			wantParams: []interface{}{int64(2), int64(10), "yes"},
+		},
+		{
+			f:          Filter{},
+			cols:       Transactions,
+			wantString: "",
+		},
+		{
+			f:          Filter{}.And(AnyInput(Filter{})).And(AssetID.Eq("abcd")),
+			cols:       Outputs,
+			wantString: "asset_id = $1",
+			wantParams: []interface{}{"abcd"},
+		},
+		{
+			f:          AnyOutput(Amount.Gt(5)).Or(Filter{}),
+			cols:       Transactions,
+			wantString: "",
+		},
+	}
+
+	inputs := SQLTable("annotated_inputs", "inp", Inputs)
+	outputs := SQLTable("annotated_outputs", "out", Outputs)
+	for i, c := range cases {
+		if got := c.f.String(); got != c.wantString {
+			t.Errorf("case %d: String() = %q, want %q", i, got, c.wantString)
+		}
+		if got := c.f.Params(); !reflect.DeepEqual(got, c.wantParams) {
+			t.Errorf("case %d: Params() = %v, want %v", i, got, c.wantParams)
+		}
+
+		tbl := SQLTable("t", "t", c.cols)
+		tbl.ForeignKeys = map[string]*filter.SQLForeignKey{
+			"inputs":  {Table: inputs, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
+			"outputs": {Table: outputs, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
+		}
+		_, err := filter.Parse(c.f.String(), tbl, c.f.Params())
+		if err != nil {
+			t.Errorf("case %d: parsing %q: %s", i, c.f.String(), err)
+		}
+	}
+}
+
+func TestColumnsConsistent(t *testing.T) {
+	for _, cols := range [][]Column{Assets, Accounts, Outputs, Inputs, Transactions} {
+		seen := make(map[string]bool)
+		for _, c := range cols {
+			if seen[c.Field.Name] {
+				t.Errorf("duplicate column for field %s", c.Field.Name)
+			}
+			seen[c.Field.Name] = true
+			if (c.SQLType == filter.SQLJSONB) != (c.Field.Type == filter.Object) {
+				t.Errorf("field %s has type %s but column %s is not a matching SQL type", c.Field.Name, c.Field.Type, c.Name)
+			}
+		}
+	}
+}
+
+func TestIndexableColumns(t *testing.T) {
+	schema, err := ioutil.ReadFile("../../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Find the columns leading an index or primary key of each table.
+	indexed := make(map[string]bool)
+	indexRE := regexp.MustCompile(`(?m)^CREATE (?:UNIQUE )?INDEX \w+ ON (\w+) USING btree \((\w+)[,)]`)
+	for _, m := range indexRE.FindAllStringSubmatch(string(schema), -1) {
+		indexed[m[1]+"."+m[2]] = true
+	}
+	pkeyRE := regexp.MustCompile(`(?m)^ALTER TABLE ONLY (\w+)\s+ADD CONSTRAINT \w+ PRIMARY KEY \((\w+)[,)]`)
+	for _, m := range pkeyRE.FindAllStringSubmatch(string(schema), -1) {
+		indexed[m[1]+"."+m[2]] = true
+	}
+
+	tables := map[string][]Column{
+		"annotated_assets":   Assets,
+		"annotated_accounts": Accounts,
+		"annotated_outputs":  Outputs,
+		"annotated_inputs":   Inputs,
+		"annotated_txs":      Transactions,
+	}
+	for table, cols := range tables {
+		for _, c := range cols {
+			if got := indexed[table+"."+c.Name]; got != c.Indexable {
+				t.Errorf("%s.%s: indexed = %v, but Indexable = %v", table, c.Name, got, c.Indexable)
+			}
+		}
+	}
+}
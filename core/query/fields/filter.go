@@ -0,0 +1,169 @@
+package fields
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// A Filter is a filter expression built from fields.
+// Its values are kept as parameters, not written into
+// the expression. The zero Filter matches everything.
+type Filter struct {
+	op string // "AND", "OR", a comparison, or "" for a quantifier or the zero Filter
+
+	// Comparisons
+	name  string
+	value interface{}
+
+	// AND and OR
+	l, r *Filter
+
+	// Quantifiers
+	list  string
+	inner *Filter
+}
+
+// Eq returns a filter requiring f to equal s.
+func (f StringField) Eq(s string) Filter { return compare(f.Field, "=", s) }
+
+// Eq returns a filter requiring f to equal n.
+func (f IntField) Eq(n int64) Filter { return compare(f.Field, "=", n) }
+
+// Lt returns a filter requiring f to be less than n.
+func (f IntField) Lt(n int64) Filter { return compare(f.Field, "<", n) }
+
+// Le returns a filter requiring f to be at most n.
+func (f IntField) Le(n int64) Filter { return compare(f.Field, "<=", n) }
+
+// Gt returns a filter requiring f to be greater than n.
+func (f IntField) Gt(n int64) Filter { return compare(f.Field, ">", n) }
+
+// Ge returns a filter requiring f to be at least n.
+func (f IntField) Ge(n int64) Filter { return compare(f.Field, ">=", n) }
+
+// Eq returns a filter requiring f to equal s.
+func (f AnyField) Eq(s string) Filter { return compare(f.Field, "=", s) }
+
+// EqInt returns a filter requiring f to equal n.
+func (f AnyField) EqInt(n int64) Filter { return compare(f.Field, "=", n) }
+
+// Lt returns a filter requiring f to be less than n.
+func (f AnyField) Lt(n int64) Filter { return compare(f.Field, "<", n) }
+
+// Le returns a filter requiring f to be at most n.
+func (f AnyField) Le(n int64) Filter { return compare(f.Field, "<=", n) }
+
+// Gt returns a filter requiring f to be greater than n.
+func (f AnyField) Gt(n int64) Filter { return compare(f.Field, ">", n) }
+
+// Ge returns a filter requiring f to be at least n.
+func (f AnyField) Ge(n int64) Filter { return compare(f.Field, ">=", n) }
+
+func compare(f Field, op string, v interface{}) Filter {
+	return Filter{op: op, name: f.Name, value: v}
+}
+
+// And returns a filter requiring both a and b.
+func (a Filter) And(b Filter) Filter {
+	if a.isZero() {
+		return b
+	}
+	if b.isZero() {
+		return a
+	}
+	return Filter{op: "AND", l: &a, r: &b}
+}
+
+// Or returns a filter requiring a or b.
+// If either is the zero Filter, so is the result.
+func (a Filter) Or(b Filter) Filter {
+	if a.isZero() || b.isZero() {
+		return Filter{}
+	}
+	return Filter{op: "OR", l: &a, r: &b}
+}
+
+func (a Filter) isZero() bool {
+	return a.op == "" && a.inner == nil
+}
+
+// AnyInput returns a filter, for transactions,
+// requiring some input to satisfy f.
+// If f is the zero Filter, so is the result.
+func AnyInput(f Filter) Filter {
+	if f.isZero() {
+		return f
+	}
+	return Filter{list: "inputs", inner: &f}
+}
+
+// AnyOutput returns a filter, for transactions,
+// requiring some output to satisfy f.
+// If f is the zero Filter, so is the result.
+func AnyOutput(f Filter) Filter {
+	if f.isZero() {
+		return f
+	}
+	return Filter{list: "outputs", inner: &f}
+}
+
+// String returns the filter expression, with placeholders
+// $1, $2, etc for the values returned by Params. It is
+// empty for the zero Filter.
+func (a Filter) String() string {
+	var buf bytes.Buffer
+	var n int
+	a.write(&buf, &n)
+	return buf.String()
+}
+
+// Params returns the values of the filter's placeholders.
+func (a Filter) Params() []interface{} {
+	var params []interface{}
+	a.params(&params)
+	return params
+}
+
+func (a *Filter) write(buf *bytes.Buffer, n *int) {
+	switch a.op {
+	case "":
+		if a.isZero() {
+			return
+		}
+		buf.WriteString(a.list)
+		buf.WriteByte('(')
+		a.inner.write(buf, n)
+		buf.WriteByte(')')
+	case "AND", "OR":
+		for i, sub := range []*Filter{a.l, a.r} {
+			if i > 0 {
+				buf.WriteString(" " + a.op + " ")
+			}
+			paren := (sub.op == "AND" || sub.op == "OR") && sub.op != a.op
+			if paren {
+				buf.WriteByte('(')
+			}
+			sub.write(buf, n)
+			if paren {
+				buf.WriteByte(')')
+			}
+		}
+	default:
+		*n++
+		buf.WriteString(a.name + " " + a.op + " $" + strconv.Itoa(*n))
+	}
+}
+
+func (a *Filter) params(params *[]interface{}) {
+	switch a.op {
+	case "":
+		if !a.isZero() {
+			a.inner.params(params)
+		}
+	case "AND", "OR":
+		a.l.params(params)
+		a.r.params(params)
+	default:
+		*params = append(*params, a.value)
+	}
+}
@@ -14,6 +14,10 @@ Expressions in a filter expression have the following forms:
   expr1 "AND" expr2        bool     bool, bool
   ident "(" expr ")"       bool     list, bool
   expr1 "=" expr2          bool     any (must match)
+  expr1 "<" expr2          bool     int, int
+  expr1 "<=" expr2         bool     int, int
+  expr1 ">" expr2          bool     int, int
+  expr1 ">=" expr2         bool     int, int
   expr "." ident           any      object
   "(" expr ")"             any      any
   ident                    any      n/a
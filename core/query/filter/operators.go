@@ -10,4 +10,8 @@ var binaryOps = map[string]*binaryOp{
 	"OR":  {1, "OR", "OR"},
 	"AND": {2, "AND", "AND"},
 	"=":   {3, "=", "="},
+	"<":   {3, "<", "<"},
+	"<=":  {3, "<=", "<="},
+	">":   {3, ">", ">"},
+	">=":  {3, ">=", ">="},
 }
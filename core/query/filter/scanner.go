@@ -205,6 +205,11 @@ func (s *scanner) Scan() (pos int, tok token, lit string) {
 			s.scanString()
 		case '.', '(', ')', '=':
 			tok = tokPunct
+		case '<', '>':
+			if s.ch == '=' {
+				s.next()
+			}
+			tok = tokPunct
 		case '$':
 			s.scanMantissa(10)
 			if s.offset-pos <= 1 {
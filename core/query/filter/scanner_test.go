@@ -54,6 +54,21 @@ func TestScannerValid(t *testing.T) {
 				{pos: 25, lit: "", tok: tokEOF},
 			},
 		},
+		{
+			input: []byte("a<b <= c>=d >e"),
+			toks: []scannedTok{
+				{pos: 0, lit: "a", tok: tokIdent},
+				{pos: 1, lit: "<", tok: tokPunct},
+				{pos: 2, lit: "b", tok: tokIdent},
+				{pos: 4, lit: "<=", tok: tokPunct},
+				{pos: 7, lit: "c", tok: tokIdent},
+				{pos: 8, lit: ">=", tok: tokPunct},
+				{pos: 10, lit: "d", tok: tokIdent},
+				{pos: 12, lit: ">", tok: tokPunct},
+				{pos: 13, lit: "e", tok: tokIdent},
+				{pos: 14, lit: "", tok: tokEOF},
+			},
+		},
 		{
 			input: []byte(`comme ci comme ça`),
 			toks: []scannedTok{
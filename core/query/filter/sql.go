@@ -87,6 +87,21 @@ func jsonbPath(f expr) []string {
 	}
 }
 
+// writeJSONBPath writes the jsonb operators indexing into
+// path, using last to select the final element.
+func writeJSONBPath(buf *bytes.Buffer, path []string, last string) {
+	for i, p := range path {
+		if i == len(path)-1 {
+			buf.WriteString(last)
+		} else {
+			buf.WriteString(`->`)
+		}
+		buf.WriteRune('\'')
+		buf.WriteString(p)
+		buf.WriteRune('\'')
+	}
+}
+
 type SQLType int
 
 const (
@@ -195,28 +210,28 @@ func asSQL(c *sqlContext, filterExpr expr) error {
 			return errors.WithDetailf(ErrBadFilter, "cannot index on non-object attribute: %s", base)
 		}
 
-		c.buf.WriteRune('(')
-		c.writeCol(base)
-		for i, p := range path {
-			if i == len(path)-1 {
-				c.buf.WriteString(`->>`)
-			} else {
-				c.buf.WriteString(`->`)
-			}
-			c.buf.WriteRune('\'')
-			c.buf.WriteString(p)
-			c.buf.WriteRune('\'')
-		}
-		c.buf.WriteRune(')')
-
 		// Use the type inferred by the typechecker to cast the expression
 		// o the right type. If uncasted, the ->> operator will result in a
-		// text PostgreSQL value.
+		// text PostgreSQL value. Only JSON numbers are cast to numbers,
+		// so other values, and decimals, don't make the query fail.
 		typ, ok := c.selectorTypes[selectorPath]
+		if ok && typ == Integer {
+			c.buf.WriteString(`CASE WHEN jsonb_typeof(`)
+			c.writeCol(base)
+			writeJSONBPath(&c.buf, path, `->`)
+			c.buf.WriteString(`) = 'number' THEN (`)
+			c.writeCol(base)
+			writeJSONBPath(&c.buf, path, `->>`)
+			c.buf.WriteString(`)::numeric END`)
+			break
+		}
+
+		c.buf.WriteRune('(')
+		c.writeCol(base)
+		writeJSONBPath(&c.buf, path, `->>`)
+		c.buf.WriteRune(')')
 		if ok {
 			switch typ {
-			case Integer:
-				c.buf.WriteString("::bigint")
 			case Bool:
 				c.buf.WriteString("::boolean")
 			case Object:
@@ -110,7 +110,14 @@ func TestAsSQL(t *testing.T) {
 		{ // indexing into arbitrary json as an integer
 			q:   `ref.buyer.address.street_number = 200`,
 			tbl: transactionsSQLTable,
-			sql: `(txs."ref"->'buyer'->'address'->>'street_number')::bigint = 200::bigint`,
+			sql: `CASE WHEN jsonb_typeof(txs."ref"->'buyer'->'address'->'street_number') = 'number' THEN (txs."ref"->'buyer'->'address'->>'street_number')::numeric END = 200::bigint`,
+		},
+		{ // ordered comparison of arbitrary json as an integer
+			q:   `inputs(account_tags.region = 'eu' AND account_tags.tier > 2)`,
+			tbl: transactionsSQLTable,
+			sql: `
+EXISTS(SELECT 1 FROM annotated_inputs AS inp WHERE inp."tx_hash" = txs."tx_hash" AND ((inp."account_tags"->>'region') = 'eu' AND CASE WHEN jsonb_typeof(inp."account_tags"->'tier') = 'number' THEN (inp."account_tags"->>'tier')::numeric END > 2::bigint))
+`,
 		},
 		{ // indexing into arbitrary json as a boolean
 			q:   `ref.buyer.is_high_priority`,
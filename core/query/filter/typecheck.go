@@ -118,6 +118,24 @@ func typeCheckExpr(expr expr, tbl *SQLTable, valTypes []Type, selectorTypes map[
 				return typ, fmt.Errorf("%s expects operands of matching types", e.op.name)
 			}
 			return Bool, nil
+		case "<", "<=", ">", ">=":
+			// Ordered comparisons are only defined on integers.
+			ok, err := assertType(e.l, leftTyp, Integer, selectorTypes)
+			if err != nil {
+				return typ, err
+			}
+			if !ok {
+				return typ, fmt.Errorf("%s expects integer operands", e.op.name)
+			}
+
+			ok, err = assertType(e.r, rightTyp, Integer, selectorTypes)
+			if err != nil {
+				return typ, err
+			}
+			if !ok {
+				return typ, fmt.Errorf("%s expects integer operands", e.op.name)
+			}
+			return Bool, nil
 		default:
 			panic(fmt.Errorf("unsupported operator: %s", e.op.name))
 		}
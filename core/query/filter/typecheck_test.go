@@ -26,6 +26,8 @@ func TestTypeCheckInvalid(t *testing.T) {
 		{p: `position.huh`, err: errors.New("selector `.` can only be used on objects")},
 		{p: `ref.something = 'abc' OR ref.something = 123`, err: errors.New("\"ref.something\" used as both string and integer")},
 		{p: `ref.buyer.id = 'abc' OR ref.buyer = 'hello'`, err: errors.New("\"ref.buyer\" used as both object and string")},
+		{p: `'a' < 'b'`, err: errors.New("< expects integer operands")},
+		{p: `ref.tier >= 2 AND ref.tier = 'gold'`, err: errors.New("\"ref.tier\" used as both integer and string")},
 	}
 
 	for _, tc := range testCases {
@@ -62,6 +64,8 @@ func TestTypeCheckValid(t *testing.T) {
 		{p: `ref.a_boolean_field AND ref.another_boolean_field`, typ: Bool},
 		{p: `$1`, valTypes: []Type{String}, typ: String},
 		{p: `$1 = $2`, valTypes: []Type{String, String}, typ: Bool},
+		{p: `position <= 2`, typ: Bool},
+		{p: `ref.tier > $1`, valTypes: []Type{Integer}, typ: Bool},
 	}
 
 	for _, tc := range testCases {
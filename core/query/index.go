@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/lib/pq"
 
@@ -24,28 +26,37 @@ const (
 func NewIndexer(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *Indexer {
 	indexer := &Indexer{
 		db:       db,
+		mainDB:   db,
 		c:        c,
 		pinStore: pinStore,
 	}
+	indexer.reindex.requests = make(chan reindexRequest, 1)
+	indexer.annotators = []Annotator{
+		NewAnnotator(LocalAnnotator, localAnnotator, AssetAnnotator, AccountAnnotator),
+	}
 	return indexer
 }
 
 // Indexer creates, updates and queries against indexes.
 type Indexer struct {
 	db         pg.DB
+	mainDB     pg.DB // holds the tag history
 	c          *protocol.Chain
 	pinStore   *pin.Store
 	annotators []Annotator
-}
 
-// Annotator describes a function capable of adding annotations
-// to transactions, inputs and outputs.
-type Annotator func(ctx context.Context, txs []*AnnotatedTx) error
+	// indexMu serializes indexing new blocks
+	// with rebuilding indexed ones.
+	indexMu sync.Mutex
+	reindex reindexer
 
-// RegisterAnnotator adds an additional annotator capable of mutating
-// the annotated transaction object.
-func (ind *Indexer) RegisterAnnotator(annotator Annotator) {
-	ind.annotators = append(ind.annotators, annotator)
+	stats indexStats
+}
+
+// SetMainDB sets the Core's main database, where tag updates
+// are recorded, if the query tables are in a different one.
+func (ind *Indexer) SetMainDB(db pg.DB) {
+	ind.mainDB = db
 }
 
 func (ind *Indexer) ProcessBlocks(ctx context.Context) {
@@ -62,32 +73,45 @@ func (ind *Indexer) IndexTransactions(ctx context.Context, b *legacy.Block) erro
 	<-ind.pinStore.PinWaiter("account", b.Height)
 	<-ind.pinStore.PinWaiter(TxPinName, b.Height-1)
 
-	err := ind.insertBlock(ctx, b)
+	ind.indexMu.Lock()
+	defer ind.indexMu.Unlock()
+	return ind.indexBlock(ctx, ind.db, b)
+}
+
+// indexBlock saves the annotated transactions, inputs
+// and outputs of b to db, and marks the outputs b spends.
+func (ind *Indexer) indexBlock(ctx context.Context, db pg.DB, b *legacy.Block) error {
+	start := time.Now()
+	err := ind.insertBlock(ctx, db, b)
+	if err != nil {
+		return err
+	}
+	txs, err := ind.insertAnnotatedTxs(ctx, db, b)
 	if err != nil {
 		return err
 	}
-	txs, err := ind.insertAnnotatedTxs(ctx, b)
+	err = ind.insertAnnotatedOutputs(ctx, db, b, txs)
 	if err != nil {
 		return err
 	}
-	err = ind.insertAnnotatedOutputs(ctx, b, txs)
+	err = ind.insertAnnotatedInputs(ctx, db, b, txs)
 	if err != nil {
 		return err
 	}
-	err = ind.insertAnnotatedInputs(ctx, b, txs)
-	return err
+	ind.stats.recordBlock(txs, time.Since(start))
+	return nil
 }
 
-func (ind *Indexer) insertBlock(ctx context.Context, b *legacy.Block) error {
+func (ind *Indexer) insertBlock(ctx context.Context, db pg.DB, b *legacy.Block) error {
 	const q = `
 		INSERT INTO query_blocks (height, timestamp) VALUES($1, $2)
 		ON CONFLICT (height) DO NOTHING
 	`
-	_, err := ind.db.ExecContext(ctx, q, b.Height, b.TimestampMS)
+	_, err := db.ExecContext(ctx, q, b.Height, b.TimestampMS)
 	return errors.Wrap(err, "inserting block timestamp")
 }
 
-func (ind *Indexer) insertAnnotatedTxs(ctx context.Context, b *legacy.Block) ([]*AnnotatedTx, error) {
+func (ind *Indexer) insertAnnotatedTxs(ctx context.Context, db pg.DB, b *legacy.Block) ([]*AnnotatedTx, error) {
 	var (
 		hashes           = pq.ByteaArray(make([][]byte, 0, len(b.Transactions)))
 		positions        = make([]uint32, 0, len(b.Transactions))
@@ -95,6 +119,7 @@ func (ind *Indexer) insertAnnotatedTxs(ctx context.Context, b *legacy.Block) ([]
 		annotatedTxs     = make([]*AnnotatedTx, 0, len(b.Transactions))
 		locals           = pq.BoolArray(make([]bool, 0, len(b.Transactions)))
 		referenceDatas   = pq.StringArray(make([]string, 0, len(b.Transactions)))
+		washScores       = make([]int, 0, len(b.Transactions))
 	)
 
 	// Build the fully annotated transactions.
@@ -102,12 +127,13 @@ func (ind *Indexer) insertAnnotatedTxs(ctx context.Context, b *legacy.Block) ([]
 		annotatedTxs = append(annotatedTxs, buildAnnotatedTransaction(tx, b, uint32(pos)))
 	}
 	for _, annotator := range ind.annotators {
-		err := annotator(ctx, annotatedTxs)
+		start := time.Now()
+		err := annotator.Annotate(ctx, annotatedTxs)
+		ind.stats.recordAnnotate(annotator.Name(), time.Since(start))
 		if err != nil {
-			return nil, errors.Wrap(err, "adding external annotations")
+			return nil, errors.Wrapf(err, "adding %s annotations", annotator.Name())
 		}
 	}
-	localAnnotator(ctx, annotatedTxs)
 
 	// Collect the fields we need to commit to the DB.
 	for pos, tx := range annotatedTxs {
@@ -120,26 +146,28 @@ func (ind *Indexer) insertAnnotatedTxs(ctx context.Context, b *legacy.Block) ([]
 		positions = append(positions, uint32(pos))
 		locals = append(locals, bool(tx.IsLocal))
 		referenceDatas = append(referenceDatas, string(*tx.ReferenceData))
+		washScores = append(washScores, tx.WashScore)
 	}
 
 	// Save the annotated txs to the database.
 	const insertQ = `
 		INSERT INTO annotated_txs(block_height, block_id, timestamp,
-			tx_pos, tx_hash, data, local, reference_data, block_tx_count)
+			tx_pos, tx_hash, data, local, reference_data, block_tx_count, wash_score)
 		SELECT $1, $2, $3, unnest($4::integer[]), unnest($5::bytea[]),
-			unnest($6::jsonb[]), unnest($7::boolean[]), unnest($8::jsonb[]), $9
+			unnest($6::jsonb[]), unnest($7::boolean[]), unnest($8::jsonb[]), $9,
+			unnest($10::integer[])
 		ON CONFLICT (block_height, tx_pos) DO NOTHING;
 	`
-	_, err := ind.db.ExecContext(ctx, insertQ, b.Height, b.Hash(), b.Time(),
+	_, err := db.ExecContext(ctx, insertQ, b.Height, b.Hash(), b.Time(),
 		pq.Array(positions), hashes, annotatedTxBlobs, locals,
-		referenceDatas, len(b.Transactions))
+		referenceDatas, len(b.Transactions), pq.Array(washScores))
 	if err != nil {
 		return nil, errors.Wrap(err, "inserting annotated_txs to db")
 	}
 	return annotatedTxs, nil
 }
 
-func (ind *Indexer) insertAnnotatedInputs(ctx context.Context, b *legacy.Block, annotatedTxs []*AnnotatedTx) error {
+func (ind *Indexer) insertAnnotatedInputs(ctx context.Context, db pg.DB, b *legacy.Block, annotatedTxs []*AnnotatedTx) error {
 	var (
 		inputTxHashes         pq.ByteaArray
 		inputIndexes          pq.Int64Array
@@ -191,21 +219,22 @@ func (ind *Indexer) insertAnnotatedInputs(ctx context.Context, b *legacy.Block,
 		INSERT INTO annotated_inputs (tx_hash, index, type,
 			asset_id, asset_alias, asset_definition, asset_tags, asset_local,
 			amount, account_id, account_alias, account_tags, issuance_program,
-			reference_data, local, spent_output_id)
+			reference_data, local, spent_output_id, asset_alias_at_tx, account_alias_at_tx)
 		SELECT unnest($1::bytea[]), unnest($2::integer[]), unnest($3::text[]), unnest($4::bytea[]),
 		unnest($5::text[]), unnest($6::jsonb[]), unnest($7::jsonb[]), unnest($8::boolean[]),
 		unnest($9::bigint[]), unnest($10::text[]), unnest($11::text[]), unnest($12::jsonb[]),
-		unnest($13::bytea[]), unnest($14::jsonb[]), unnest($15::boolean[]), unnest($16::bytea[])
+		unnest($13::bytea[]), unnest($14::jsonb[]), unnest($15::boolean[]), unnest($16::bytea[]),
+		unnest($5::text[]), unnest($11::text[])
 		ON CONFLICT (tx_hash, index) DO NOTHING;
 	`
-	_, err := ind.db.ExecContext(ctx, insertQ, inputTxHashes, inputIndexes, inputTypes, inputAssetIDs,
+	_, err := db.ExecContext(ctx, insertQ, inputTxHashes, inputIndexes, inputTypes, inputAssetIDs,
 		inputAssetAliases, inputAssetDefinitions, pq.Array(inputAssetTags), inputAssetLocals,
 		inputAmounts, pq.Array(inputAccountIDs), pq.Array(inputAccountAliases), pq.Array(inputAccountTags),
 		inputIssuancePrograms, inputReferenceDatas, inputLocals, inputSpentOutputIDs)
 	return errors.Wrap(err, "batch inserting annotated inputs")
 }
 
-func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block, annotatedTxs []*AnnotatedTx) error {
+func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, db pg.DB, b *legacy.Block, annotatedTxs []*AnnotatedTx) error {
 	var (
 		outputIDs              pq.ByteaArray
 		outputTxPositions      []uint32
@@ -273,16 +302,16 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash,
 			timespan, output_id, type, purpose, asset_id, asset_alias, asset_definition,
 			asset_tags, asset_local, amount, account_id, account_alias, account_tags,
-			control_program, reference_data, local)
+			control_program, reference_data, local, asset_alias_at_tx, account_alias_at_tx)
 		SELECT $1, tx_pos, output_index, tx_hash,
 		CASE WHEN type='retire' THEN int8range($5, $5) ELSE int8range($5, NULL) END,
 		output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags,
 		asset_local, amount, account_id, account_alias, account_tags, control_program,
-		reference_data, local
+		reference_data, local, asset_alias, account_alias
 		FROM utxos
-		ON CONFLICT (block_height, tx_pos, output_index) DO NOTHING;
+		ON CONFLICT (output_id) DO NOTHING;
 	`
-	_, err := ind.db.ExecContext(ctx, insertQ, b.Height, pq.Array(outputTxPositions),
+	_, err := db.ExecContext(ctx, insertQ, b.Height, pq.Array(outputTxPositions),
 		pq.Array(outputIndexes), outputTxHashes, b.TimestampMS, outputIDs, outputTypes,
 		outputPurposes, outputAssetIDs, outputAssetAliases,
 		outputAssetDefinitions, outputAssetTags, outputAssetLocals,
@@ -294,9 +323,10 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 	}
 
 	const updateQ = `
-		UPDATE annotated_outputs SET timespan = INT8RANGE(LOWER(timespan), $1)
+		UPDATE annotated_outputs SET timespan = INT8RANGE(LOWER(timespan), $1),
+			spent_block_height = $3, lifetime_ms = $1 - LOWER(timespan)
 		WHERE (output_id) IN (SELECT unnest($2::bytea[]))
 	`
-	_, err = ind.db.ExecContext(ctx, updateQ, b.TimestampMS, prevoutIDs)
+	_, err = db.ExecContext(ctx, updateQ, b.TimestampMS, prevoutIDs, b.Height)
 	return errors.Wrap(err, "updating spent annotated outputs")
 }
@@ -23,7 +23,7 @@ func TestAnnotatedTxs(t *testing.T) {
 			bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash()),
 		},
 	}
-	txs, err := indexer.insertAnnotatedTxs(ctx, b)
+	txs, err := indexer.insertAnnotatedTxs(ctx, indexer.db, b)
 	if err != nil {
 		t.Error(err)
 	}
@@ -64,7 +64,7 @@ func TestAnnotatedTxsReferenceData(t *testing.T) {
 					bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash(), setRefData),
 				},
 			}
-			txs, err := indexer.insertAnnotatedTxs(ctx, b)
+			txs, err := indexer.insertAnnotatedTxs(ctx, indexer.db, b)
 			if err != nil {
 				t.Error(err)
 			}
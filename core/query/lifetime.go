@@ -0,0 +1,287 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chain/core/query/filter"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrBadBuckets is returned by HoldingTimes when the
+// bucket bounds aren't positive and increasing.
+var ErrBadBuckets = errors.New("invalid holding time buckets")
+
+// AssetVelocity describes how much of an asset changed hands
+// in a range of blocks, among the outputs matching a filter.
+// Its amounts are exact sums, which can exceed the largest
+// valid amount of the asset.
+type AssetVelocity struct {
+	AssetID    bc.AssetID `json:"asset_id"`
+	AssetAlias string     `json:"asset_alias,omitempty"`
+
+	// SpentAmount and SpentCount are the total amount and
+	// number of the outputs spent in the range.
+	SpentAmount json.Number `json:"spent_amount"`
+	SpentCount  uint64      `json:"spent_count"`
+
+	// StartAmount and EndAmount are the amounts in unspent
+	// outputs at the start and end of the range.
+	StartAmount json.Number `json:"start_amount"`
+	EndAmount   json.Number `json:"end_amount"`
+
+	// Velocity is SpentAmount divided by the mean of
+	// StartAmount and EndAmount, or 0 if both are 0.
+	Velocity float64 `json:"velocity"`
+}
+
+// AssetVelocity computes the velocity of each asset among the
+// annotated outputs matching the filter, over the blocks with
+// heights in [startHeight, endHeight). Retirements are not
+// counted.
+func (ind *Indexer) AssetVelocity(ctx context.Context, filt string, vals []interface{}, startHeight, endHeight uint64) ([]*AssetVelocity, error) {
+	expr, err := outputsFilterSQL(filt, vals)
+	if err != nil {
+		return nil, err
+	}
+	queryStr, queryArgs := constructAssetVelocityQuery(expr, vals, startHeight, endHeight)
+	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*AssetVelocity
+	for rows.Next() {
+		v := new(AssetVelocity)
+		err := rows.Scan(&v.AssetID, &v.AssetAlias, &v.SpentAmount, &v.SpentCount, &v.StartAmount, &v.EndAmount)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning asset velocity row")
+		}
+		v.Velocity, err = velocity(v.SpentAmount, v.StartAmount, v.EndAmount)
+		if err != nil {
+			return nil, errors.Wrap(err, "computing asset velocity")
+		}
+		items = append(items, v)
+	}
+	return items, errors.Wrap(rows.Err())
+}
+
+// velocity returns spent divided by the mean of start and
+// end, or 0 if both are 0.
+func velocity(spent, start, end json.Number) (float64, error) {
+	var f [3]float64
+	for i, n := range []json.Number{spent, start, end} {
+		var err error
+		f[i], err = n.Float64()
+		if err != nil {
+			return 0, err
+		}
+	}
+	mean := (f[1] + f[2]) / 2
+	if mean == 0 {
+		return 0, nil
+	}
+	return f[0] / mean, nil
+}
+
+func constructAssetVelocityQuery(expr string, vals []interface{}, startHeight, endHeight uint64) (string, []interface{}) {
+	vals = append(vals, startHeight, endHeight)
+	start := fmt.Sprintf("$%d::int8", len(vals)-1)
+	end := fmt.Sprintf("$%d::int8", len(vals))
+	unspentAt := func(h string) string {
+		return fmt.Sprintf("block_height < %s AND (spent_block_height IS NULL OR spent_block_height >= %s)", h, h)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("SELECT asset_id, MAX(asset_alias)")
+	spent := fmt.Sprintf("spent_block_height >= %s AND spent_block_height < %s", start, end)
+	buf.WriteString(fmt.Sprintf(", COALESCE(SUM(amount) FILTER (WHERE %s), 0)", spent))
+	buf.WriteString(fmt.Sprintf(", COUNT(*) FILTER (WHERE %s)", spent))
+	buf.WriteString(fmt.Sprintf(", COALESCE(SUM(amount) FILTER (WHERE %s), 0)", unspentAt(start)))
+	buf.WriteString(fmt.Sprintf(", COALESCE(SUM(amount) FILTER (WHERE spent_block_height IS NULL OR spent_block_height >= %s), 0)", end))
+	buf.WriteString(" FROM ")
+	buf.WriteString(pq.QuoteIdentifier("annotated_outputs"))
+	buf.WriteString(" AS out WHERE ")
+	if len(expr) > 0 {
+		buf.WriteString("(")
+		buf.WriteString(expr)
+		buf.WriteString(") AND ")
+	}
+
+	// Consider only the outputs that exist at some
+	// point in the range. That rules out retirements,
+	// which have an empty timespan.
+	buf.WriteString(fmt.Sprintf("type <> 'retire' AND block_height < %s AND (spent_block_height IS NULL OR spent_block_height >= %s)", end, start))
+	buf.WriteString(" GROUP BY 1 ORDER BY 3 DESC, 1")
+	return buf.String(), vals
+}
+
+// HoldingTimes describes how long the outputs of an asset
+// that were spent in a range of blocks had been unspent.
+// Amount, like each bucket's, is an exact sum.
+type HoldingTimes struct {
+	AssetID    bc.AssetID  `json:"asset_id"`
+	AssetAlias string      `json:"asset_alias,omitempty"`
+	Count      uint64      `json:"count"`
+	Amount     json.Number `json:"amount"`
+
+	MeanMS   float64 `json:"mean_ms"`
+	MedianMS int64   `json:"median_ms"`
+	P90MS    int64   `json:"p90_ms"`
+	P99MS    int64   `json:"p99_ms"`
+
+	// MeanBlocks is the mean number of blocks
+	// between an output's creation and its spending.
+	MeanBlocks float64 `json:"mean_blocks"`
+
+	Buckets []*HoldingTimeBucket `json:"buckets,omitempty"`
+}
+
+// HoldingTimeBucket counts the spent outputs with lifetimes
+// in [MinMS, MaxMS). MaxMS is nil for the last bucket.
+type HoldingTimeBucket struct {
+	MinMS  int64       `json:"min_ms"`
+	MaxMS  *int64      `json:"max_ms,omitempty"`
+	Count  uint64      `json:"count"`
+	Amount json.Number `json:"amount"`
+}
+
+// HoldingTimes returns the distribution of the lifetimes of the
+// annotated outputs matching the filter that were spent in blocks
+// with heights in [startHeight, endHeight), for each asset.
+// If bounds is nonempty, each result also counts the outputs in
+// the buckets with those lower bounds, which must be positive and
+// increasing, after a first bucket starting at 0.
+func (ind *Indexer) HoldingTimes(ctx context.Context, filt string, vals []interface{}, startHeight, endHeight uint64, bounds []int64) ([]*HoldingTimes, error) {
+	for i, b := range bounds {
+		if b <= 0 || i > 0 && b <= bounds[i-1] {
+			return nil, errors.WithDetail(ErrBadBuckets, "bucket bounds must be positive and increasing")
+		}
+	}
+	expr, err := outputsFilterSQL(filt, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	queryStr, queryArgs := constructHoldingTimesQuery(expr, vals, startHeight, endHeight)
+	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*HoldingTimes
+	byAsset := make(map[bc.AssetID]*HoldingTimes)
+	for rows.Next() {
+		h := new(HoldingTimes)
+		var pcts pq.Int64Array
+		err := rows.Scan(&h.AssetID, &h.AssetAlias, &h.Count, &h.Amount, &h.MeanMS, &pcts, &h.MeanBlocks)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning holding times row")
+		}
+		if len(pcts) == 3 {
+			h.MedianMS, h.P90MS, h.P99MS = pcts[0], pcts[1], pcts[2]
+		}
+		if len(bounds) > 0 {
+			h.Buckets = newHoldingTimeBuckets(bounds)
+		}
+		items = append(items, h)
+		byAsset[h.AssetID] = h
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	if len(bounds) == 0 || len(items) == 0 {
+		return items, nil
+	}
+
+	queryStr, queryArgs = constructHoldingTimeBucketsQuery(expr, vals, startHeight, endHeight, bounds)
+	rows, err = ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			assetID bc.AssetID
+			bucket  int
+			count   uint64
+			amount  json.Number
+		)
+		err := rows.Scan(&assetID, &bucket, &count, &amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning holding time bucket row")
+		}
+		h := byAsset[assetID]
+		if h == nil || bucket < 1 || bucket > len(h.Buckets) {
+			continue
+		}
+		h.Buckets[bucket-1].Count = count
+		h.Buckets[bucket-1].Amount = amount
+	}
+	return items, errors.Wrap(rows.Err())
+}
+
+func newHoldingTimeBuckets(bounds []int64) []*HoldingTimeBucket {
+	buckets := make([]*HoldingTimeBucket, 0, len(bounds)+1)
+	var min int64
+	for _, b := range bounds {
+		max := b
+		buckets = append(buckets, &HoldingTimeBucket{MinMS: min, MaxMS: &max})
+		min = b
+	}
+	return append(buckets, &HoldingTimeBucket{MinMS: min})
+}
+
+// spentInRangeSQL writes the FROM and WHERE clauses selecting
+// the outputs matching expr spent in the range of block heights
+// given by the last two of the query's nvals parameters.
+func spentInRangeSQL(buf *bytes.Buffer, expr string, nvals int) {
+	buf.WriteString(" FROM ")
+	buf.WriteString(pq.QuoteIdentifier("annotated_outputs"))
+	buf.WriteString(" AS out WHERE ")
+	if len(expr) > 0 {
+		buf.WriteString("(")
+		buf.WriteString(expr)
+		buf.WriteString(") AND ")
+	}
+	buf.WriteString(fmt.Sprintf("spent_block_height >= $%d::int8 AND spent_block_height < $%d::int8", nvals-1, nvals))
+}
+
+func constructHoldingTimesQuery(expr string, vals []interface{}, startHeight, endHeight uint64) (string, []interface{}) {
+	vals = append(vals, startHeight, endHeight)
+	var buf bytes.Buffer
+	buf.WriteString("SELECT asset_id, MAX(asset_alias), COUNT(*), SUM(amount), AVG(lifetime_ms)::float8")
+	buf.WriteString(", percentile_disc(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY lifetime_ms)")
+	buf.WriteString(", AVG(spent_block_height - block_height)::float8")
+	spentInRangeSQL(&buf, expr, len(vals))
+	buf.WriteString(" GROUP BY 1 ORDER BY 3 DESC, 1")
+	return buf.String(), vals
+}
+
+func constructHoldingTimeBucketsQuery(expr string, vals []interface{}, startHeight, endHeight uint64, bounds []int64) (string, []interface{}) {
+	vals = append(vals, pq.Int64Array(append([]int64{0}, bounds...)), startHeight, endHeight)
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("SELECT asset_id, width_bucket(lifetime_ms, $%d::int8[]), COUNT(*), SUM(amount)", len(vals)-2))
+	spentInRangeSQL(&buf, expr, len(vals))
+	buf.WriteString(" GROUP BY 1, 2")
+	return buf.String(), vals
+}
+
+func outputsFilterSQL(filt string, vals []interface{}) (string, error) {
+	p, err := filter.Parse(filt, outputsTable, vals)
+	if err != nil {
+		return "", err
+	}
+	if len(vals) != p.Parameters {
+		return "", ErrParameterCountMismatch
+	}
+	return filter.AsSQL(p, outputsTable, vals)
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"chain/testutil"
+)
+
+func TestConstructAssetVelocityQuery(t *testing.T) {
+	expr, err := outputsFilterSQL("account_alias = $1", []interface{}{"alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, values := constructAssetVelocityQuery(expr, []interface{}{"alice"}, 10, 20)
+
+	const wantQuery = `SELECT asset_id, MAX(asset_alias)` +
+		`, COALESCE(SUM(amount) FILTER (WHERE spent_block_height >= $2::int8 AND spent_block_height < $3::int8), 0)` +
+		`, COUNT(*) FILTER (WHERE spent_block_height >= $2::int8 AND spent_block_height < $3::int8)` +
+		`, COALESCE(SUM(amount) FILTER (WHERE block_height < $2::int8 AND (spent_block_height IS NULL OR spent_block_height >= $2::int8)), 0)` +
+		`, COALESCE(SUM(amount) FILTER (WHERE spent_block_height IS NULL OR spent_block_height >= $3::int8), 0)` +
+		` FROM "annotated_outputs" AS out WHERE (out."account_alias" = $1) AND type <> 'retire'` +
+		` AND block_height < $3::int8 AND (spent_block_height IS NULL OR spent_block_height >= $2::int8)` +
+		` GROUP BY 1 ORDER BY 3 DESC, 1`
+	if query != wantQuery {
+		t.Errorf("got\n%s\nwant\n%s", query, wantQuery)
+	}
+	wantValues := []interface{}{"alice", uint64(10), uint64(20)}
+	if !testutil.DeepEqual(values, wantValues) {
+		t.Errorf("got values %#v, want %#v", values, wantValues)
+	}
+}
+
+func TestConstructHoldingTimesQueries(t *testing.T) {
+	query, values := constructHoldingTimesQuery("", nil, 10, 20)
+	const wantQuery = `SELECT asset_id, MAX(asset_alias), COUNT(*), SUM(amount), AVG(lifetime_ms)::float8` +
+		`, percentile_disc(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY lifetime_ms)` +
+		`, AVG(spent_block_height - block_height)::float8` +
+		` FROM "annotated_outputs" AS out WHERE spent_block_height >= $1::int8 AND spent_block_height < $2::int8` +
+		` GROUP BY 1 ORDER BY 3 DESC, 1`
+	if query != wantQuery {
+		t.Errorf("got\n%s\nwant\n%s", query, wantQuery)
+	}
+	if want := []interface{}{uint64(10), uint64(20)}; !testutil.DeepEqual(values, want) {
+		t.Errorf("got values %#v, want %#v", values, want)
+	}
+
+	expr, err := outputsFilterSQL("asset_alias = $1", []interface{}{"gold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, values = constructHoldingTimeBucketsQuery(expr, []interface{}{"gold"}, 10, 20, []int64{1000, 60000})
+	const wantBucketsQuery = `SELECT asset_id, width_bucket(lifetime_ms, $2::int8[]), COUNT(*), SUM(amount)` +
+		` FROM "annotated_outputs" AS out WHERE (out."asset_alias" = $1) AND spent_block_height >= $3::int8 AND spent_block_height < $4::int8` +
+		` GROUP BY 1, 2`
+	if query != wantBucketsQuery {
+		t.Errorf("got\n%s\nwant\n%s", query, wantBucketsQuery)
+	}
+	wantValues := []interface{}{"gold", pq.Int64Array{0, 1000, 60000}, uint64(10), uint64(20)}
+	if !testutil.DeepEqual(values, wantValues) {
+		t.Errorf("got values %#v, want %#v", values, wantValues)
+	}
+}
+
+func TestNewHoldingTimeBuckets(t *testing.T) {
+	buckets := newHoldingTimeBuckets([]int64{1000, 60000})
+	if len(buckets) != 3 {
+		t.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+	for i, want := range [][2]int64{{0, 1000}, {1000, 60000}} {
+		b := buckets[i]
+		if b.MinMS != want[0] || b.MaxMS == nil || *b.MaxMS != want[1] {
+			t.Errorf("bucket %d = [%d, %v), want [%d, %d)", i, b.MinMS, b.MaxMS, want[0], want[1])
+		}
+	}
+	if b := buckets[2]; b.MinMS != 60000 || b.MaxMS != nil {
+		t.Errorf("last bucket = [%d, %v), want [60000, nil)", b.MinMS, b.MaxMS)
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	cases := []struct {
+		spent, start, end json.Number
+		want              float64
+	}{
+		{"0", "0", "0", 0},
+		{"30", "10", "50", 1},
+		{"18446744073709551616", "18446744073709551616", "18446744073709551616", 1},
+	}
+	for _, c := range cases {
+		got, err := velocity(c.spent, c.start, c.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("velocity(%s, %s, %s) = %v, want %v", c.spent, c.start, c.end, got, c.want)
+		}
+	}
+}
@@ -47,7 +47,10 @@ func DecodeOutputsAfter(str string) (c *OutputsAfter, err error) {
 	}, nil
 }
 
-func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) ([]*AnnotatedOutput, *OutputsAfter, error) {
+// Outputs returns the annotated outputs matching filt that were
+// unspent at timestampMS or, if height is nonzero, at the end of
+// the block at height, newest first.
+func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}, timestampMS, height uint64, after *OutputsAfter, limit int) ([]*AnnotatedOutput, *OutputsAfter, error) {
 	p, err := filter.Parse(filt, outputsTable, vals)
 	if err != nil {
 		return nil, nil, err
@@ -59,7 +62,7 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 	if err != nil {
 		return nil, nil, err
 	}
-	queryStr, queryArgs := constructOutputsQuery(expr, vals, timestampMS, after, limit)
+	queryStr, queryArgs := constructOutputsQuery(expr, vals, timestampMS, height, after, limit)
 	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, nil, err
@@ -79,6 +82,7 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 			txID         = new(bc.Hash)
 			accountID    *string
 			accountAlias *string
+			aliasAtTx    *string
 			out          = new(AnnotatedOutput)
 		)
 		err = rows.Scan(
@@ -101,12 +105,15 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 			&out.ControlProgram,
 			&out.ReferenceData,
 			&out.IsLocal,
+			&out.AssetAliasAtTx,
+			&aliasAtTx,
 		)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "scanning annotated output")
 		}
 
 		out.TransactionID = txID
+		out.Vault = annotatedVault(out.ControlProgram)
 
 		// Set nullable fields.
 		if accountID != nil {
@@ -115,6 +122,9 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 		if accountAlias != nil {
 			out.AccountAlias = *accountAlias
 		}
+		if aliasAtTx != nil {
+			out.AccountAliasAtTx = *aliasAtTx
+		}
 
 		outputs = append(outputs, out)
 
@@ -130,14 +140,14 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 	return outputs, &newAfter, nil
 }
 
-func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) (string, []interface{}) {
+func constructOutputsQuery(where string, vals []interface{}, timestampMS, height uint64, after *OutputsAfter, limit int) (string, []interface{}) {
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT ")
 	buf.WriteString("block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, ")
 	buf.WriteString("asset_id, asset_alias, asset_definition, asset_tags, asset_local, ")
 	buf.WriteString("amount, account_id, account_alias, account_tags, control_program, ")
-	buf.WriteString("reference_data, local")
+	buf.WriteString("reference_data, local, asset_alias_at_tx, account_alias_at_tx")
 	buf.WriteString(" FROM ")
 	buf.WriteString(pq.QuoteIdentifier("annotated_outputs"))
 	buf.WriteString(" AS out WHERE ")
@@ -148,9 +158,7 @@ func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64,
 		buf.WriteString(") AND ")
 	}
 
-	vals = append(vals, timestampMS)
-	timestampValIndex := len(vals)
-	buf.WriteString(fmt.Sprintf("timespan @> $%d::int8", timestampValIndex))
+	vals = unspentAsOf(&buf, vals, timestampMS, height)
 
 	if after != nil {
 		vals = append(vals, after.lastBlockHeight)
@@ -169,3 +177,21 @@ func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64,
 
 	return buf.String(), vals
 }
+
+// unspentAsOf writes to buf a condition selecting the outputs
+// unspent at timestampMS or, if height is nonzero, at the end of
+// the block at height, and returns vals with its parameter added.
+func unspentAsOf(buf *bytes.Buffer, vals []interface{}, timestampMS, height uint64) []interface{} {
+	if height == 0 {
+		vals = append(vals, timestampMS)
+		buf.WriteString(fmt.Sprintf("timespan @> $%d::int8", len(vals)))
+		return vals
+	}
+
+	// Retirements are never unspent. They have an empty
+	// timespan, but no spent_block_height.
+	vals = append(vals, height)
+	h := fmt.Sprintf("$%d::int8", len(vals))
+	buf.WriteString(fmt.Sprintf("type <> 'retire' AND block_height <= %s AND (spent_block_height IS NULL OR spent_block_height > %s)", h, h))
+	return vals
+}
@@ -44,12 +44,12 @@ func TestOutputsAfter(t *testing.T) {
 	ctx := context.Background()
 	_, err := db.ExecContext(ctx, `
 		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
-			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount, control_program, reference_data, local)
+			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount, control_program, reference_data, local, asset_alias_at_tx)
 		VALUES
-		(1, 0, 0, 'ab', 'o1', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true),
-		(1, 1, 0, 'cd', 'o2', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true),
-		(1, 1, 1, 'cd', 'o3', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true),
-		(2, 0, 0, 'ef', 'o4', int8range(10, 50), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true);
+		(1, 0, 0, 'ab', 'o1', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true, 'a'),
+		(1, 1, 0, 'cd', 'o2', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true, 'a'),
+		(1, 1, 1, 'cd', 'o3', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true, 'a'),
+		(2, 0, 0, 'ef', 'o4', int8range(10, 50), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, true, 'a');
 	`)
 	if err != nil {
 		t.Fatal(err)
@@ -57,7 +57,7 @@ func TestOutputsAfter(t *testing.T) {
 
 	const q = `asset_id = 'deadbeef'`
 	indexer := NewIndexer(db, &protocol.Chain{}, nil)
-	results, after, err := indexer.Outputs(ctx, q, nil, 25, nil, 2)
+	results, after, err := indexer.Outputs(ctx, q, nil, 25, 0, nil, 2)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,7 +68,7 @@ func TestOutputsAfter(t *testing.T) {
 		t.Errorf("got after=%q want 1:1:1", after.String())
 	}
 
-	results, after, err = indexer.Outputs(ctx, q, nil, 25, after, 2)
+	results, after, err = indexer.Outputs(ctx, q, nil, 25, 0, after, 2)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -88,18 +88,19 @@ func TestConstructOutputsQuery(t *testing.T) {
 		filter     string
 		values     []interface{}
 		after      *OutputsAfter
+		height     uint64
 		wantQuery  string
 		wantValues []interface{}
 	}{
 		{
 			// empty filter
-			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local FROM "annotated_outputs" AS out WHERE timespan @> $1::int8 ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
+			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local, asset_alias_at_tx, account_alias_at_tx FROM "annotated_outputs" AS out WHERE timespan @> $1::int8 ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
 			wantValues: []interface{}{nowMillis},
 		},
 		{
 			filter:     "asset_id = $1 AND account_id = 'abc'",
 			values:     []interface{}{"foo"},
-			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local FROM "annotated_outputs" AS out WHERE (encode(out."asset_id", 'hex') = $1 AND out."account_id" = 'abc') AND timespan @> $2::int8 ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
+			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local, asset_alias_at_tx, account_alias_at_tx FROM "annotated_outputs" AS out WHERE (encode(out."asset_id", 'hex') = $1 AND out."account_id" = 'abc') AND timespan @> $2::int8 ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
 			wantValues: []interface{}{`foo`, nowMillis},
 		},
 		{
@@ -110,9 +111,15 @@ func TestConstructOutputsQuery(t *testing.T) {
 				lastTxPos:       17,
 				lastIndex:       19,
 			},
-			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local FROM "annotated_outputs" AS out WHERE (encode(out."asset_id", 'hex') = $1 AND out."account_id" = 'abc') AND timespan @> $2::int8 AND (block_height, tx_pos, output_index) < ($3, $4, $5) ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
+			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local, asset_alias_at_tx, account_alias_at_tx FROM "annotated_outputs" AS out WHERE (encode(out."asset_id", 'hex') = $1 AND out."account_id" = 'abc') AND timespan @> $2::int8 AND (block_height, tx_pos, output_index) < ($3, $4, $5) ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
 			wantValues: []interface{}{`foo`, nowMillis, uint64(15), uint32(17), 19},
 		},
+		{
+			// as of a block height
+			height:     7,
+			wantQuery:  `SELECT block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount, account_id, account_alias, account_tags, control_program, reference_data, local, asset_alias_at_tx, account_alias_at_tx FROM "annotated_outputs" AS out WHERE type <> 'retire' AND block_height <= $1::int8 AND (spent_block_height IS NULL OR spent_block_height > $1::int8) ORDER BY block_height DESC, tx_pos DESC, output_index DESC LIMIT 10`,
+			wantValues: []interface{}{uint64(7)},
+		},
 	}
 
 	for i, tc := range testCases {
@@ -124,7 +131,7 @@ func TestConstructOutputsQuery(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		query, values := constructOutputsQuery(expr, tc.values, nowMillis, tc.after, 10)
+		query, values := constructOutputsQuery(expr, tc.values, nowMillis, tc.height, tc.after, 10)
 		if query != tc.wantQuery {
 			t.Errorf("case %d: got %s want %s", i, query, tc.wantQuery)
 		}
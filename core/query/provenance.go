@@ -0,0 +1,147 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// maxProvenanceTxs bounds the transactions in a provenance
+// graph, since the graph can grow exponentially with its depth.
+const maxProvenanceTxs = 1000
+
+// A Provenance is the graph of transactions an output came from,
+// traced backward through the outputs they spent. The graph is a
+// DAG: a transaction appears once however many paths lead to it.
+type Provenance struct {
+	OutputID bc.Hash `json:"output_id"`
+
+	// Transactions are the annotated transactions in the graph,
+	// starting with the one that created the output, in the order
+	// they were reached: nearest first.
+	Transactions []*AnnotatedTx `json:"transactions"`
+
+	// Edges link each transaction in the graph, but the first,
+	// to a transaction that spent one of its outputs.
+	Edges []ProvenanceEdge `json:"edges"`
+
+	// Truncated is set if the graph was cut short after
+	// maxProvenanceTxs transactions, before it was hops deep.
+	Truncated bool `json:"truncated"`
+}
+
+// A ProvenanceEdge is an output created
+// by one transaction and spent by another.
+type ProvenanceEdge struct {
+	OutputID  bc.Hash `json:"output_id"`
+	CreatedBy bc.Hash `json:"created_by"`
+	SpentBy   bc.Hash `json:"spent_by"`
+}
+
+// Provenance returns the graph of transactions the output
+// with the given ID came from, going back up to hops
+// transactions: with one hop, it's the transaction that
+// created the output; with two, that one and those that
+// created the outputs it spent; and so on. The graph stops
+// at issuances, and at outputs not in the annotated data.
+func (ind *Indexer) Provenance(ctx context.Context, outputID bc.Hash, hops int) (*Provenance, error) {
+	p := &Provenance{OutputID: outputID}
+	seen := make(map[bc.Hash]bool)
+
+	// spentBy maps each output in the frontier
+	// to the transaction that spent it.
+	frontier := []bc.Hash{outputID}
+	spentBy := make(map[bc.Hash]bc.Hash)
+	for hop := 0; hop < hops && len(frontier) > 0; hop++ {
+		created, err := ind.creatingTxs(ctx, frontier)
+		if err != nil {
+			return nil, err
+		}
+		if hop == 0 && len(created) == 0 {
+			return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "could not find output with id=%x", outputID.Bytes())
+		}
+
+		var next []bc.Hash
+		for _, out := range frontier {
+			tx, ok := created[out]
+			if !ok {
+				continue
+			}
+			if !seen[tx.ID] && len(p.Transactions) == maxProvenanceTxs {
+				p.Truncated = true
+				return p, nil
+			}
+			if hop > 0 {
+				p.Edges = append(p.Edges, ProvenanceEdge{OutputID: out, CreatedBy: tx.ID, SpentBy: spentBy[out]})
+			}
+			if seen[tx.ID] {
+				continue
+			}
+			seen[tx.ID] = true
+			p.Transactions = append(p.Transactions, tx)
+			for _, in := range tx.Inputs {
+				if in.SpentOutputID == nil {
+					continue // an issuance
+				}
+				_, ok := spentBy[*in.SpentOutputID]
+				if !ok {
+					spentBy[*in.SpentOutputID] = tx.ID
+					next = append(next, *in.SpentOutputID)
+				}
+			}
+		}
+		frontier = next
+	}
+	return p, nil
+}
+
+// creatingTxs returns the annotated transactions
+// that created the outputs with the given IDs,
+// keyed by output ID.
+func (ind *Indexer) creatingTxs(ctx context.Context, outputIDs []bc.Hash) (map[bc.Hash]*AnnotatedTx, error) {
+	var ids pq.ByteaArray
+	for _, id := range outputIDs {
+		ids = append(ids, id.Bytes())
+	}
+	const q = `
+		SELECT o.output_id, t.data FROM annotated_outputs o
+		JOIN annotated_txs t ON t.block_height = o.block_height AND t.tx_pos = o.tx_pos
+		WHERE o.output_id = ANY($1::bytea[])
+	`
+	rows, err := ind.db.QueryContext(ctx, q, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying creating transactions")
+	}
+	defer rows.Close()
+
+	// Outputs created by the same transaction
+	// share its decoded annotated transaction.
+	byData := make(map[string]*AnnotatedTx)
+	created := make(map[bc.Hash]*AnnotatedTx)
+	for rows.Next() {
+		var (
+			id   bc.Hash
+			data []byte
+		)
+		err := rows.Scan(&id, &data)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning creating transaction")
+		}
+		tx, ok := byData[string(data)]
+		if !ok {
+			tx = new(AnnotatedTx)
+			err = json.Unmarshal(data, tx)
+			if err != nil {
+				return nil, errors.Wrap(err, "unmarshaling annotated transaction")
+			}
+			byData[string(data)] = tx
+		}
+		created[id] = tx
+	}
+	return created, errors.Wrap(rows.Err())
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestProvenance(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	indexer := NewIndexer(db, nil, nil)
+
+	// An issuance in block 1 creates o1, which a
+	// transfer in block 2 spends to create o2.
+	var (
+		issuanceID = bc.NewHash([32]byte{1})
+		transferID = bc.NewHash([32]byte{2})
+		o1         = bc.NewHash([32]byte{0xa1})
+		o2         = bc.NewHash([32]byte{0xa2})
+	)
+	txs := []*AnnotatedTx{
+		{ID: issuanceID, BlockHeight: 1, Inputs: []*AnnotatedInput{{Type: "issue"}}},
+		{ID: transferID, BlockHeight: 2, Inputs: []*AnnotatedInput{{Type: "spend", SpentOutputID: &o1}}},
+	}
+	for i, tx := range txs {
+		data, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO annotated_txs (block_height, tx_pos, tx_hash, data, timestamp, block_id, local, reference_data)
+			VALUES ($1, 0, $2, $3, now(), '', false, '{}')
+		`, tx.BlockHeight, tx.ID, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
+				type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount,
+				control_program, reference_data, local, asset_alias_at_tx)
+			VALUES ($1, 0, 0, $2, $3, int8range(1, NULL), 'control', 'receive', 'x', '', '{}'::jsonb,
+				false, '{}'::jsonb, 10, E'\\xDEADBEEF', '{}'::jsonb, false, '')
+		`, tx.BlockHeight, tx.ID, []bc.Hash{o1, o2}[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := indexer.Provenance(ctx, o2, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(p.Transactions) != 1 || p.Transactions[0].ID != transferID || len(p.Edges) != 0 {
+		t.Errorf("one hop = %+v want only the transfer", p)
+	}
+
+	p, err = indexer.Provenance(ctx, o2, 3)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(p.Transactions) != 2 || p.Transactions[1].ID != issuanceID {
+		t.Errorf("three hops = %+v want the transfer and the issuance", p)
+	}
+	wantEdge := ProvenanceEdge{OutputID: o1, CreatedBy: issuanceID, SpentBy: transferID}
+	if len(p.Edges) != 1 || p.Edges[0] != wantEdge {
+		t.Errorf("three hops edges = %+v want [%+v]", p.Edges, wantEdge)
+	}
+
+	_, err = indexer.Provenance(ctx, bc.NewHash([32]byte{0xff}), 1)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("unknown output error = %v want %v", err, pg.ErrUserInputNotFound)
+	}
+}
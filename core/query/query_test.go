@@ -33,8 +33,14 @@ func setupQueryTest(t *testing.T) (context.Context, *query.Indexer, time.Time, t
 	accounts := account.NewManager(db, c, pinStore)
 	assets := asset.NewRegistry(db, c, pinStore)
 	assets.IndexAssets(indexer)
-	indexer.RegisterAnnotator(accounts.AnnotateTxs)
-	indexer.RegisterAnnotator(assets.AnnotateTxs)
+	err := indexer.RegisterAnnotator(accounts.Annotator())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = indexer.RegisterAnnotator(assets.Annotator())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
 	go assets.ProcessBlocks(ctx)
 	go accounts.ProcessBlocks(ctx)
 	go indexer.ProcessBlocks(ctx)
@@ -176,7 +182,7 @@ func TestQueryOutputs(t *testing.T) {
 	}
 
 	for i, tc := range cases {
-		outputs, _, err := indexer.Outputs(ctx, tc.filter, tc.values, bc.Millis(tc.when), nil, 1000)
+		outputs, _, err := indexer.Outputs(ctx, tc.filter, tc.values, bc.Millis(tc.when), 0, nil, 1000)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -314,7 +320,7 @@ func TestQueryBalances(t *testing.T) {
 			fields = append(fields, f)
 		}
 
-		balances, err := indexer.Balances(ctx, tc.predicate, tc.values, fields, bc.Millis(tc.when))
+		balances, err := indexer.Balances(ctx, tc.predicate, tc.values, fields, bc.Millis(tc.when), 0)
 		if err != nil {
 			t.Fatal(err)
 		}
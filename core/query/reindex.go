@@ -0,0 +1,318 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/reqid"
+	"chain/protocol/bc/legacy"
+)
+
+// Reindexing rebuilds the annotated transactions, inputs and
+// outputs of blocks the indexer has already processed, from the
+// blocks stored by the Core. It recovers from corrupted or lost
+// annotated rows, and applies annotators registered since the
+// blocks were first indexed, without resetting the database.
+//
+// Each block is rebuilt in turn: its annotated rows are deleted,
+// then annotated and inserted again as IndexTransactions would,
+// and finally the outputs spent by later blocks are marked spent
+// again. While a block is being rebuilt, queries may not see its
+// transactions. A reindex that stops partway, because of an
+// error or a change of leader, leaves earlier blocks rebuilt and
+// later ones as they were; it is safe to run it again.
+
+var (
+	// ErrBadReindexHeight is returned when a reindex is requested
+	// from a height the transaction indexer hasn't reached.
+	ErrBadReindexHeight = errors.New("invalid reindex height")
+
+	// ErrReindexing is returned when a reindex is requested
+	// while another is pending or running.
+	ErrReindexing = errors.New("reindex already in progress")
+)
+
+// reindexLogInterval is how many blocks Reindex
+// rebuilds between progress log lines.
+const reindexLogInterval = 1000
+
+// ReindexStats reports the progress of the latest reindex.
+type ReindexStats struct {
+	Running    bool   `json:"running"`
+	FromHeight uint64 `json:"from_block_height"`
+	ToHeight   uint64 `json:"to_block_height"`
+
+	// Height is the height of the last
+	// block rebuilt, or 0 if none has been.
+	Height uint64 `json:"block_height"`
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// LastError is the error that stopped
+	// the reindex, if one did.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// reindexRequest is a reindex requested with RequestReindex.
+type reindexRequest struct {
+	fromHeight uint64
+
+	// reqID is the ID of the request that asked
+	// for the reindex, so it's logged with it.
+	reqID string
+}
+
+// reindexer holds the state of the Indexer's reindexes.
+type reindexer struct {
+	// delay is the pause after each block, so a reindex
+	// doesn't starve the live indexer and queries.
+	delay time.Duration
+
+	requests chan reindexRequest
+
+	mu    sync.Mutex
+	stats ReindexStats
+}
+
+// SetReindexDelay sets the pause Reindex takes
+// after rebuilding each block. It defaults to 0.
+func (ind *Indexer) SetReindexDelay(d time.Duration) {
+	ind.reindex.delay = d
+}
+
+// ReindexStats returns the progress of the latest reindex.
+func (ind *Indexer) ReindexStats() ReindexStats {
+	ind.reindex.mu.Lock()
+	defer ind.reindex.mu.Unlock()
+	return ind.reindex.stats
+}
+
+// RequestReindex asks ProcessReindexes to reindex the blocks from
+// fromHeight through the height the indexer has reached. It
+// returns without waiting for the reindex to run; its progress
+// is reported by ReindexStats.
+func (ind *Indexer) RequestReindex(ctx context.Context, fromHeight uint64) error {
+	toHeight, err := ind.checkReindexHeight(fromHeight)
+	if err != nil {
+		return err
+	}
+	err = ind.startReindex(fromHeight, toHeight)
+	if err != nil {
+		return err
+	}
+	ind.reindex.requests <- reindexRequest{fromHeight, reqid.FromContext(ctx)}
+	return nil
+}
+
+// ProcessReindexes runs each reindex requested with
+// RequestReindex. It must run only on the leader.
+// It returns when ctx is canceled.
+func (ind *Indexer) ProcessReindexes(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-ind.reindex.requests:
+			reqCtx := ctx
+			if req.reqID != "" {
+				reqCtx = reqid.NewContext(ctx, req.reqID)
+			}
+			toHeight := ind.ReindexStats().ToHeight
+			err := ind.runReindex(reqCtx, req.fromHeight, toHeight)
+			if err != nil {
+				log.Error(reqCtx, err, "reindexing transactions")
+			}
+		}
+	}
+}
+
+// Reindex rebuilds the annotated transactions, inputs and outputs
+// of the blocks from fromHeight through the height the indexer has
+// reached, with the annotators currently registered. Later blocks
+// are indexed by ProcessBlocks as usual. Reindex must run only on
+// the leader, while ProcessBlocks is running.
+func (ind *Indexer) Reindex(ctx context.Context, fromHeight uint64) error {
+	toHeight, err := ind.checkReindexHeight(fromHeight)
+	if err != nil {
+		return err
+	}
+	err = ind.startReindex(fromHeight, toHeight)
+	if err != nil {
+		return err
+	}
+	return ind.runReindex(ctx, fromHeight, toHeight)
+}
+
+func (ind *Indexer) checkReindexHeight(fromHeight uint64) (uint64, error) {
+	if ind.pinStore == nil {
+		return 0, errors.WithDetail(ErrBadReindexHeight, "transactions are not indexed")
+	}
+	toHeight := ind.pinStore.Height(TxPinName)
+	if fromHeight == 0 || fromHeight > toHeight {
+		return 0, errors.WithDetailf(ErrBadReindexHeight, "height %d, indexed height %d", fromHeight, toHeight)
+	}
+	return toHeight, nil
+}
+
+func (ind *Indexer) startReindex(fromHeight, toHeight uint64) error {
+	ind.reindex.mu.Lock()
+	defer ind.reindex.mu.Unlock()
+	if ind.reindex.stats.Running {
+		s := ind.reindex.stats
+		return errors.WithDetailf(ErrReindexing, "heights %d to %d", s.FromHeight, s.ToHeight)
+	}
+	ind.reindex.stats = ReindexStats{
+		Running:    true,
+		FromHeight: fromHeight,
+		ToHeight:   toHeight,
+		StartedAt:  time.Now(),
+	}
+	return nil
+}
+
+func (ind *Indexer) runReindex(ctx context.Context, fromHeight, toHeight uint64) (err error) {
+	defer func() {
+		now := time.Now()
+		ind.reindex.mu.Lock()
+		defer ind.reindex.mu.Unlock()
+		ind.reindex.stats.Running = false
+		ind.reindex.stats.FinishedAt = &now
+		if err != nil {
+			ind.reindex.stats.LastError = err.Error()
+		}
+	}()
+
+	log.Printkv(ctx, "at", "reindex starting", "from", fromHeight, "to", toHeight)
+	for height := fromHeight; height <= toHeight; height++ {
+		b, err := ind.c.GetBlock(ctx, height)
+		if err != nil {
+			return errors.Wrapf(err, "getting block %d", height)
+		}
+		err = ind.reindexBlock(ctx, b)
+		if err != nil {
+			return errors.Wrapf(err, "reindexing block %d", height)
+		}
+
+		ind.reindex.mu.Lock()
+		ind.reindex.stats.Height = height
+		ind.reindex.mu.Unlock()
+		if (height-fromHeight+1)%reindexLogInterval == 0 {
+			log.Printkv(ctx, "at", "reindex progress", "height", height, "to", toHeight)
+		}
+
+		if ind.reindex.delay > 0 && height < toHeight {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(ind.reindex.delay):
+			}
+		}
+	}
+	log.Printkv(ctx, "at", "reindex finished", "from", fromHeight, "to", toHeight)
+	return nil
+}
+
+// reindexBlock deletes and rebuilds the annotated rows of b,
+// in one database transaction, so queries never see b half-indexed.
+// The rebuilt rows keep the aliases their inputs and outputs had
+// when first indexed, rather than taking the current ones.
+func (ind *Indexer) reindexBlock(ctx context.Context, b *legacy.Block) error {
+	// Don't let the live indexer mark outputs of b
+	// spent while they're being rebuilt.
+	ind.indexMu.Lock()
+	defer ind.indexMu.Unlock()
+
+	var hashes pq.ByteaArray
+	for _, tx := range b.Transactions {
+		hashes = append(hashes, tx.ID.Bytes())
+	}
+	return pg.RunInTx(ctx, ind.db, func(db pg.DB) error {
+		var inputs, outputs aliasesAtTx
+		const inputsQ = `
+			DELETE FROM annotated_inputs WHERE tx_hash = ANY($1::bytea[])
+			RETURNING tx_hash, index, asset_alias_at_tx, account_alias_at_tx
+		`
+		err := pg.ForQueryRows(ctx, db, inputsQ, hashes, inputs.add)
+		if err != nil {
+			return errors.Wrap(err, "deleting annotated inputs")
+		}
+		const outputsQ = `
+			DELETE FROM annotated_outputs WHERE block_height = $1
+			RETURNING output_id, 0, asset_alias_at_tx, account_alias_at_tx
+		`
+		err = pg.ForQueryRows(ctx, db, outputsQ, b.Height, outputs.add)
+		if err != nil {
+			return errors.Wrap(err, "deleting annotated outputs")
+		}
+		const txsQ = `DELETE FROM annotated_txs WHERE block_height = $1`
+		_, err = db.ExecContext(ctx, txsQ, b.Height)
+		if err != nil {
+			return errors.Wrap(err, "deleting annotated txs")
+		}
+
+		err = ind.indexBlock(ctx, db, b)
+		if err != nil {
+			return err
+		}
+
+		const restoreInputsQ = `
+			UPDATE annotated_inputs i SET asset_alias_at_tx = t.asset_alias, account_alias_at_tx = t.account_alias
+			FROM unnest($1::bytea[], $2::integer[], $3::text[], $4::text[]) AS t(tx_hash, index, asset_alias, account_alias)
+			WHERE i.tx_hash = t.tx_hash AND i.index = t.index
+		`
+		_, err = db.ExecContext(ctx, restoreInputsQ, inputs.ids, inputs.indexes,
+			inputs.assetAliases, pq.Array(inputs.accountAliases))
+		if err != nil {
+			return errors.Wrap(err, "restoring annotated input aliases")
+		}
+		const restoreOutputsQ = `
+			UPDATE annotated_outputs o SET asset_alias_at_tx = t.asset_alias, account_alias_at_tx = t.account_alias
+			FROM unnest($1::bytea[], $2::text[], $3::text[]) AS t(output_id, asset_alias, account_alias)
+			WHERE o.output_id = t.output_id
+		`
+		_, err = db.ExecContext(ctx, restoreOutputsQ, outputs.ids,
+			outputs.assetAliases, pq.Array(outputs.accountAliases))
+		if err != nil {
+			return errors.Wrap(err, "restoring annotated output aliases")
+		}
+
+		// Mark spent the outputs of b that later blocks spend,
+		// as their indexing did.
+		const spentQ = `
+			UPDATE annotated_outputs o SET timespan = INT8RANGE(LOWER(o.timespan), qb.timestamp),
+				spent_block_height = t.block_height, lifetime_ms = qb.timestamp - LOWER(o.timespan)
+			FROM annotated_inputs i
+			JOIN annotated_txs t ON t.tx_hash = i.tx_hash
+			JOIN query_blocks qb ON qb.height = t.block_height
+			WHERE o.block_height = $1 AND i.spent_output_id = o.output_id
+		`
+		_, err = db.ExecContext(ctx, spentQ, b.Height)
+		return errors.Wrap(err, "updating spent annotated outputs")
+	})
+}
+
+// aliasesAtTx holds the aliases at the time of the transaction
+// of annotated inputs or outputs deleted by reindexBlock. An
+// input is identified by its transaction hash and index; an
+// output, by its output ID alone.
+type aliasesAtTx struct {
+	ids            pq.ByteaArray
+	indexes        pq.Int64Array
+	assetAliases   pq.StringArray
+	accountAliases []sql.NullString
+}
+
+func (a *aliasesAtTx) add(id []byte, index int64, assetAlias string, accountAlias sql.NullString) {
+	a.ids = append(a.ids, id)
+	a.indexes = append(a.indexes, index)
+	a.assetAliases = append(a.assetAliases, assetAlias)
+	a.accountAliases = append(a.accountAliases, accountAlias)
+}
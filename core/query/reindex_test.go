@@ -0,0 +1,79 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestReindexBlock(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 1000},
+		Transactions: []*legacy.Tx{
+			bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash()),
+		},
+	}
+	err := indexer.indexBlock(ctx, indexer.db, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Lose the block's annotated outputs, and
+	// corrupt its annotated transaction.
+	_, err = db.ExecContext(ctx, `DELETE FROM annotated_outputs WHERE block_height = 2`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = db.ExecContext(ctx, `UPDATE annotated_txs SET data = '{}' WHERE block_height = 2`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The input's aliases at the time of the transaction
+	// differ from the current ones, which are empty.
+	_, err = db.ExecContext(ctx, `UPDATE annotated_inputs SET asset_alias_at_tx = 'gold', account_alias_at_tx = 'alice'`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = indexer.reindexBlock(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var txs, outputs, inputs int
+	const q = `
+		SELECT
+			(SELECT COUNT(*) FROM annotated_txs WHERE block_height = 2 AND data <> '{}'),
+			(SELECT COUNT(*) FROM annotated_outputs WHERE block_height = 2),
+			(SELECT COUNT(*) FROM annotated_inputs WHERE tx_hash = $1)
+	`
+	err = db.QueryRowContext(ctx, q, b.Transactions[0].ID.Bytes()).Scan(&txs, &outputs, &inputs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := len(b.Transactions[0].Outputs)
+	if txs != 1 || outputs != want || inputs != 1 {
+		t.Errorf("after reindex got %d txs, %d outputs, %d inputs, want 1, %d, 1", txs, outputs, inputs, want)
+	}
+
+	var assetAlias, accountAlias string
+	const aliasQ = `SELECT asset_alias_at_tx, account_alias_at_tx FROM annotated_inputs WHERE tx_hash = $1`
+	err = db.QueryRowContext(ctx, aliasQ, b.Transactions[0].ID.Bytes()).Scan(&assetAlias, &accountAlias)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if assetAlias != "gold" || accountAlias != "alice" {
+		t.Errorf("after reindex input aliases at tx = %q, %q, want %q, %q", assetAlias, accountAlias, "gold", "alice")
+	}
+}
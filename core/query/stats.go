@@ -0,0 +1,68 @@
+package query
+
+import (
+	"sync"
+	"time"
+)
+
+// IndexStats reports the work the indexer has done in this
+// process, including reindexing, since it started.
+type IndexStats struct {
+	Blocks uint64
+
+	// Txs, Inputs and Outputs count the annotated
+	// rows written, so that divided by Blocks
+	// they give the rows written per block.
+	Txs     uint64
+	Inputs  uint64
+	Outputs uint64
+
+	// IndexTime is the total time spent indexing blocks.
+	IndexTime time.Duration
+
+	// AnnotateTime is the total time spent
+	// in each annotator, by annotator name.
+	AnnotateTime map[string]time.Duration
+}
+
+// indexStats accumulates IndexStats.
+type indexStats struct {
+	mu    sync.Mutex
+	stats IndexStats
+}
+
+// IndexStats returns the work the indexer has done.
+func (ind *Indexer) IndexStats() IndexStats {
+	ind.stats.mu.Lock()
+	defer ind.stats.mu.Unlock()
+	s := ind.stats.stats
+	s.AnnotateTime = make(map[string]time.Duration, len(ind.stats.stats.AnnotateTime))
+	for name, d := range ind.stats.stats.AnnotateTime {
+		s.AnnotateTime[name] = d
+	}
+	return s
+}
+
+// recordBlock adds an indexed block, with its annotated
+// transactions, to the stats.
+func (s *indexStats) recordBlock(txs []*AnnotatedTx, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Blocks++
+	s.stats.Txs += uint64(len(txs))
+	for _, tx := range txs {
+		s.stats.Inputs += uint64(len(tx.Inputs))
+		s.stats.Outputs += uint64(len(tx.Outputs))
+	}
+	s.stats.IndexTime += d
+}
+
+// recordAnnotate adds time spent in the named annotator.
+func (s *indexStats) recordAnnotate(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats.AnnotateTime == nil {
+		s.stats.AnnotateTime = make(map[string]time.Duration)
+	}
+	s.stats.AnnotateTime[name] += d
+}
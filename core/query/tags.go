@@ -0,0 +1,86 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// ErrBadTags is returned when account or asset
+// tags have a value that is not a string, number or boolean.
+var ErrBadTags = errors.New("invalid tags")
+
+// ValidateTags checks that every value in tags is a string, a
+// number or a boolean, so that filters can compare tags with
+// simple values, as in tags.tier>2. Nested objects, lists and
+// nulls are rejected. Numbers need not be integers, as tags
+// written before they were checked may hold decimals.
+func ValidateTags(tags map[string]interface{}) error {
+	for k, v := range tags {
+		switch v.(type) {
+		case string, bool, json.Number, float64:
+		case nil:
+			return errors.WithDetailf(ErrBadTags, "tag %q must not be null", k)
+		default:
+			return errors.WithDetailf(ErrBadTags, "tag %q must be a string, number or boolean", k)
+		}
+	}
+	return nil
+}
+
+// TagChange is a set of tags that was replaced by an update.
+type TagChange struct {
+	Tags       map[string]interface{} `json:"tags"`
+	ReplacedAt time.Time              `json:"replaced_at"`
+}
+
+// LockTagHistory takes a lock, held until the database
+// transaction db commits, that serializes tag updates. Each
+// tag update must take it before recording the tags it
+// replaces in tag_history, so that the updates commit in the
+// order of their sequence numbers.
+func LockTagHistory(ctx context.Context, db pg.DB) error {
+	const q = `SELECT pg_advisory_xact_lock(hashtext('tag_history'))`
+	_, err := db.ExecContext(ctx, q)
+	return errors.Wrap(err, "locking tag history")
+}
+
+// TagHistory returns the earlier tags of the account or asset with
+// the given ID, newest first. objectType is "account" or "asset".
+// Tag updates are recorded in the main database, even if the
+// query tables are in another one.
+func (ind *Indexer) TagHistory(ctx context.Context, objectType, id string) ([]TagChange, error) {
+	const q = `
+		SELECT tags, replaced_at FROM tag_history
+		WHERE object_type = $1 AND object_id = $2
+		ORDER BY seq DESC
+	`
+	rows, err := ind.mainDB.QueryContext(ctx, q, objectType, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tag history")
+	}
+	defer rows.Close()
+
+	var changes []TagChange
+	for rows.Next() {
+		var (
+			c    TagChange
+			tags []byte
+		)
+		err := rows.Scan(&tags, &c.ReplacedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning tag history row")
+		}
+		if len(tags) > 0 {
+			err = json.Unmarshal(tags, &c.Tags)
+			if err != nil {
+				return nil, errors.Wrap(err, "decoding tags")
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, errors.Wrap(rows.Err())
+}
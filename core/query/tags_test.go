@@ -0,0 +1,34 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestValidateTags(t *testing.T) {
+	cases := []struct {
+		tags map[string]interface{}
+		ok   bool
+	}{
+		{nil, true},
+		{map[string]interface{}{"region": "eu", "tier": json.Number("3"), "vip": true}, true},
+		{map[string]interface{}{"tier": 3.0}, true},
+		{map[string]interface{}{"tier": json.Number("2.5")}, true},
+		{map[string]interface{}{"tier": 2.5}, true},
+		{map[string]interface{}{"tier": json.Number("99999999999999999999")}, true},
+		{map[string]interface{}{"address": map[string]interface{}{"city": "Paris"}}, false},
+		{map[string]interface{}{"regions": []interface{}{"eu"}}, false},
+		{map[string]interface{}{"region": nil}, false},
+	}
+	for _, c := range cases {
+		err := ValidateTags(c.tags)
+		if c.ok && err != nil {
+			t.Errorf("ValidateTags(%v) = %v, want nil", c.tags, err)
+		}
+		if !c.ok && errors.Root(err) != ErrBadTags {
+			t.Errorf("ValidateTags(%v) = %v, want %v", c.tags, err, ErrBadTags)
+		}
+	}
+}
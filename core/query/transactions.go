@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 
 	"chain/core/query/filter"
 	"chain/errors"
@@ -30,24 +31,53 @@ type TxAfter struct {
 	// list. It is used when list-transactions is called with a time range instead
 	// of an `after`.
 	StopBlockHeight uint64 // inclusive
+
+	// SnapshotHeight, if nonzero, is the height of the last
+	// block whose transactions are included in the list, fixed
+	// when the list starts. Blocks that land while the list is
+	// paged through are left out, so each page continues exactly
+	// where the previous one stopped. See Snapshot.
+	SnapshotHeight uint64 // inclusive
 }
 
 func (after TxAfter) String() string {
-	return fmt.Sprintf("%d:%d-%d", after.FromBlockHeight, after.FromPosition, after.StopBlockHeight)
+	s := fmt.Sprintf("%d:%d-%d", after.FromBlockHeight, after.FromPosition, after.StopBlockHeight)
+	if after.SnapshotHeight > 0 {
+		s += fmt.Sprintf("@%d", after.SnapshotHeight)
+	}
+	return s
+}
+
+// Snapshot returns after limited to the transactions in blocks
+// up to height, which should be fully indexed. A transaction
+// list started with it pages through the same transactions,
+// however many blocks land in the meantime.
+func (after TxAfter) Snapshot(height uint64) TxAfter {
+	if after.FromBlockHeight > height {
+		after.FromBlockHeight = height
+		after.FromPosition = math.MaxInt32
+	}
+	after.SnapshotHeight = height
+	return after
 }
 
 func DecodeTxAfter(str string) (c TxAfter, err error) {
-	var from, pos, stop uint64
-	_, err = fmt.Sscanf(str, "%d:%d-%d", &from, &pos, &stop)
+	var from, pos, stop, snapshot uint64
+	if strings.Contains(str, "@") {
+		_, err = fmt.Sscanf(str, "%d:%d-%d@%d", &from, &pos, &stop, &snapshot)
+	} else {
+		_, err = fmt.Sscanf(str, "%d:%d-%d", &from, &pos, &stop)
+	}
 	if err != nil {
 		return c, errors.Sub(ErrBadAfter, err)
 	}
 	if from > math.MaxInt64 ||
 		pos > math.MaxUint32 ||
-		stop > math.MaxInt64 {
+		stop > math.MaxInt64 ||
+		snapshot > math.MaxInt64 {
 		return c, errors.Wrap(ErrBadAfter)
 	}
-	return TxAfter{FromBlockHeight: from, FromPosition: uint32(pos), StopBlockHeight: stop}, nil
+	return TxAfter{FromBlockHeight: from, FromPosition: uint32(pos), StopBlockHeight: stop, SnapshotHeight: snapshot}, nil
 }
 
 func ValidateTransactionFilter(filt string) error {
@@ -89,11 +119,10 @@ func (ind *Indexer) Transactions(ctx context.Context, filt string, vals []interf
 		return nil, nil, errors.Wrap(err, "converting to SQL")
 	}
 
-	queryStr, queryArgs := constructTransactionsQuery(expr, vals, after, asc, limit)
-
 	if asc {
-		return ind.waitForAndFetchTransactions(ctx, queryStr, queryArgs, after, limit)
+		return ind.waitForAndFetchTransactions(ctx, expr, vals, after, limit)
 	}
+	queryStr, queryArgs := constructTransactionsQuery(expr, vals, after, asc, limit)
 	return ind.fetchTransactions(ctx, queryStr, queryArgs, after, limit)
 }
 
@@ -112,6 +141,11 @@ func constructTransactionsQuery(expr string, vals []interface{}, after TxAfter,
 		buf.WriteString(" AND ")
 	}
 
+	if after.SnapshotHeight > 0 {
+		buf.WriteString(fmt.Sprintf("txs.block_height <= $%d AND ", len(vals)+1))
+		vals = append(vals, after.SnapshotHeight)
+	}
+
 	if asc {
 		// add time range & after conditions
 		buf.WriteString(fmt.Sprintf("(txs.block_height, txs.tx_pos) > ($%d, $%d) AND ", len(vals)+1, len(vals)+2))
@@ -166,7 +200,13 @@ type fetchResp struct {
 	err   error
 }
 
-func (ind *Indexer) waitForAndFetchTransactions(ctx context.Context, queryStr string, queryArgs []interface{}, after TxAfter, limit int) ([]*AnnotatedTx, *TxAfter, error) {
+// waitForAndFetchTransactions returns the transactions after
+// after, in ascending order, waiting for blocks to be indexed
+// until there is at least one. Each query is limited to the
+// blocks indexed when it runs, so a block's transactions are
+// never returned before all of them are indexed. The snapshot
+// height of after is ignored, and the returned one is zero.
+func (ind *Indexer) waitForAndFetchTransactions(ctx context.Context, expr string, vals []interface{}, after TxAfter, limit int) ([]*AnnotatedTx, *TxAfter, error) {
 	resp := make(chan fetchResp, 1)
 	go func() {
 		var (
@@ -182,13 +222,17 @@ func (ind *Indexer) waitForAndFetchTransactions(ctx context.Context, queryStr st
 				return
 			}
 
-			txs, aft, err = ind.fetchTransactions(ctx, queryStr, queryArgs, after, limit)
+			snapshot := after
+			snapshot.SnapshotHeight = h
+			queryStr, queryArgs := constructTransactionsQuery(expr, vals, snapshot, true, limit)
+			txs, aft, err = ind.fetchTransactions(ctx, queryStr, queryArgs, snapshot, limit)
 			if err != nil {
 				resp <- fetchResp{nil, nil, err}
 				return
 			}
 
 			if len(txs) > 0 {
+				aft.SnapshotHeight = 0
 				resp <- fetchResp{txs, aft, nil}
 				return
 			}
@@ -27,11 +27,26 @@ func TestDecodeTxAfter(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"9:3-2@10",
+			TxAfter{
+				FromBlockHeight: 9,
+				FromPosition:    3,
+				StopBlockHeight: 2,
+				SnapshotHeight:  10,
+			},
+			nil,
+		},
 		{
 			"hello",
 			TxAfter{},
 			ErrBadAfter,
 		},
+		{
+			"9:3-2@",
+			TxAfter{},
+			ErrBadAfter,
+		},
 	}
 
 	for _, c := range testCases {
@@ -43,6 +58,25 @@ func TestDecodeTxAfter(t *testing.T) {
 		if got != c.want {
 			t.Fatalf("want DecodeTxAfter(%q)=%#v, got %#v", c.str, c.want, got)
 		}
+		if err == nil && got.String() != c.str {
+			t.Errorf("DecodeTxAfter(%q).String() = %q", c.str, got.String())
+		}
+	}
+}
+
+func TestTxAfterSnapshot(t *testing.T) {
+	after := TxAfter{FromBlockHeight: 12, FromPosition: math.MaxInt32, StopBlockHeight: 1}
+	got := after.Snapshot(10)
+	want := TxAfter{FromBlockHeight: 10, FromPosition: math.MaxInt32, StopBlockHeight: 1, SnapshotHeight: 10}
+	if got != want {
+		t.Errorf("Snapshot(10) = %#v, want %#v", got, want)
+	}
+
+	after = TxAfter{FromBlockHeight: 8, FromPosition: 2, StopBlockHeight: 1}
+	got = after.Snapshot(10)
+	want = TxAfter{FromBlockHeight: 8, FromPosition: 2, StopBlockHeight: 1, SnapshotHeight: 10}
+	if got != want {
+		t.Errorf("Snapshot(10) = %#v, want %#v", got, want)
 	}
 }
 
@@ -110,6 +144,18 @@ EXISTS(SELECT 1 FROM annotated_outputs AS out WHERE out."tx_hash" = txs."tx_hash
 				`acc123`, `corp`, uint64(2), uint32(20), uint64(1),
 			},
 		},
+		{
+			filter: `outputs(account_id = $1)`,
+			values: []interface{}{"acc123"},
+			after:  TxAfter{FromBlockHeight: 8, FromPosition: 2, StopBlockHeight: 1, SnapshotHeight: 10},
+			asc:    false,
+			wantQuery: `SELECT block_height, tx_pos, data FROM annotated_txs AS txs WHERE 
+EXISTS(SELECT 1 FROM annotated_outputs AS out WHERE out."tx_hash" = txs."tx_hash" AND (out."account_id" = $1))
+ AND txs.block_height <= $2 AND (txs.block_height, txs.tx_pos) < ($3, $4) AND txs.block_height >= $5 ORDER BY txs.block_height DESC, txs.tx_pos DESC LIMIT 100`,
+			wantValues: []interface{}{
+				`acc123`, uint64(10), uint64(8), uint32(2), uint64(1),
+			},
+		},
 	}
 
 	for _, tc := range testCases {
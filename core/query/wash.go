@@ -0,0 +1,235 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// WashAnnotator is the name of the annotator
+// returned by NewWashAnnotator.
+const WashAnnotator = "wash"
+
+// maxWashTrace bounds the outputs traced back from each
+// output of a transaction when scoring it for wash transfers.
+const maxWashTrace = 1000
+
+// maxWashHops bounds the number of transfers traced
+// back from each output of a transaction.
+const maxWashHops = 10
+
+// washTruncated counts the traces the wash annotator
+// gave up on before reaching the end of the window,
+// because of maxWashTrace or maxWashHops.
+var washTruncated = expvar.NewInt("query.wash.truncated")
+
+func NewWashAnnotator(db pg.DB, window uint64) Annotator {
+	w := &washAnnotator{db: db, window: window}
+	return NewAnnotator(WashAnnotator, w.annotate, AccountAnnotator)
+}
+
+type washAnnotator struct {
+	db     pg.DB
+	window uint64
+}
+
+// washInput is an input of a transaction that
+// created an output traced by the wash annotator.
+type washInput struct {
+	account     string
+	assetID     bc.AssetID
+	spentOutput *bc.Hash
+}
+
+// washTrace follows the transfers of an asset back from
+// the outputs of a transaction paying to an account,
+// looking for one from the account itself.
+type washTrace struct {
+	account   string
+	assetID   bc.AssetID
+	frontier  []bc.Hash
+	visited   map[bc.Hash]bool
+	found     bool
+	truncated bool // an output was left out because of maxWashTrace
+}
+
+func (t *washTrace) add(id bc.Hash) {
+	if t.visited[id] {
+		return
+	}
+	if len(t.visited) >= maxWashTrace {
+		t.truncated = true
+		return
+	}
+	t.visited[id] = true
+	t.frontier = append(t.frontier, id)
+}
+
+func (w *washAnnotator) annotate(ctx context.Context, txs []*AnnotatedTx) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	var minHeight uint64
+	height := txs[0].BlockHeight
+	if height > w.window {
+		minHeight = height - w.window
+	}
+
+	// The outputs created in the block, so that round trips
+	// within it are found without querying for them.
+	inBlock := make(map[bc.Hash]*AnnotatedTx)
+	for _, tx := range txs {
+		for _, out := range tx.Outputs {
+			inBlock[out.OutputID] = tx
+		}
+	}
+
+	// Start a trace for each account and asset
+	// that each transaction pays to.
+	type target struct {
+		tx      *AnnotatedTx
+		account string
+		assetID bc.AssetID
+	}
+	traces := make(map[target]*washTrace)
+	var active []*washTrace
+	for _, tx := range txs {
+		for _, out := range tx.Outputs {
+			k := target{tx, out.AccountID, out.AssetID}
+			if out.AccountID == "" || traces[k] != nil {
+				continue
+			}
+			t := &washTrace{account: out.AccountID, assetID: out.AssetID, visited: make(map[bc.Hash]bool)}
+			for _, in := range tx.Inputs {
+				if in.SpentOutputID != nil && in.AssetID == t.assetID && in.AccountID != t.account {
+					t.add(*in.SpentOutputID)
+				}
+			}
+			traces[k] = t
+			if len(t.frontier) > 0 {
+				active = append(active, t)
+			}
+		}
+	}
+
+	// Step all the traces back one transfer at a time.
+	for hop := 0; hop < maxWashHops && len(active) > 0; hop++ {
+		var ids []bc.Hash
+		for _, t := range active {
+			ids = append(ids, t.frontier...)
+		}
+		creators, err := w.creatorInputs(ctx, ids, minHeight, inBlock)
+		if err != nil {
+			return err
+		}
+
+		next := active[:0]
+		for _, t := range active {
+			frontier := t.frontier
+			t.frontier = nil
+			for _, id := range frontier {
+				for _, in := range creators[id] {
+					if in.assetID != t.assetID {
+						continue
+					}
+					if in.account == t.account {
+						t.found = true
+					}
+					if in.spentOutput != nil {
+						t.add(*in.spentOutput)
+					}
+				}
+			}
+			if !t.found && len(t.frontier) > 0 {
+				next = append(next, t)
+			}
+		}
+		active = next
+	}
+	for _, t := range traces {
+		if !t.found && (t.truncated || len(t.frontier) > 0) {
+			washTruncated.Add(1)
+		}
+	}
+
+	for _, tx := range txs {
+		score := 0
+		for _, out := range tx.Outputs {
+			t := traces[target{tx, out.AccountID, out.AssetID}]
+			if t != nil && t.found {
+				score++
+			}
+		}
+		tx.WashScore = score
+	}
+	return nil
+}
+
+// creatorInputs returns the inputs of the transactions that created
+// the outputs with the given IDs, keyed by output ID, if they're in
+// blocks at or above minHeight.
+func (w *washAnnotator) creatorInputs(ctx context.Context, outputIDs []bc.Hash, minHeight uint64, inBlock map[bc.Hash]*AnnotatedTx) (map[bc.Hash][]washInput, error) {
+	var (
+		inputs = make(map[bc.Hash][]washInput)
+		ids    pq.ByteaArray
+	)
+	for _, id := range outputIDs {
+		if _, ok := inputs[id]; ok {
+			continue
+		}
+		tx, ok := inBlock[id]
+		if !ok {
+			ids = append(ids, id.Bytes())
+			continue
+		}
+		inputs[id] = nil
+		for _, in := range tx.Inputs {
+			inputs[id] = append(inputs[id], washInput{in.AccountID, in.AssetID, in.SpentOutputID})
+		}
+	}
+	if len(ids) == 0 {
+		return inputs, nil
+	}
+
+	const q = `
+		SELECT DISTINCT o.output_id, i.index, i.account_id, i.asset_id, i.spent_output_id
+		FROM annotated_outputs o
+		JOIN annotated_inputs i ON i.tx_hash = o.tx_hash
+		WHERE o.output_id = ANY($1::bytea[]) AND o.block_height >= $2
+	`
+	rows, err := w.db.QueryContext(ctx, q, ids, minHeight)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying creating transactions' inputs")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			outputID bc.Hash
+			index    int
+			account  sql.NullString
+			in       washInput
+			spent    []byte
+		)
+		err := rows.Scan(&outputID, &index, &account, &in.assetID, &spent)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning input")
+		}
+		in.account = account.String
+		if len(spent) > 0 {
+			var h bc.Hash
+			err = h.Scan(spent)
+			if err != nil {
+				return nil, errors.Wrap(err, "scanning spent output id")
+			}
+			in.spentOutput = &h
+		}
+		inputs[outputID] = append(inputs[outputID], in)
+	}
+	return inputs, errors.Wrap(rows.Err())
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestWashAnnotator(t *testing.T) {
+	var (
+		gold   = bc.NewAssetID([32]byte{1})
+		silver = bc.NewAssetID([32]byte{2})
+		o1     = bc.NewHash([32]byte{0xa1})
+		o2     = bc.NewHash([32]byte{0xa2})
+		o3     = bc.NewHash([32]byte{0xa3})
+		o4     = bc.NewHash([32]byte{0xa4})
+		o5     = bc.NewHash([32]byte{0xa5})
+	)
+
+	// In one block, alice issues gold to herself, sends it to bob,
+	// who sends it to carol, who sends it back to alice along
+	// with silver from carol. Bob's change goes back to bob.
+	issue := &AnnotatedTx{
+		BlockHeight: 10,
+		Inputs:      []*AnnotatedInput{{Type: "issue", AssetID: gold}},
+		Outputs:     []*AnnotatedOutput{{OutputID: o1, AssetID: gold, AccountID: "alice"}},
+	}
+	toBob := &AnnotatedTx{
+		BlockHeight: 10,
+		Inputs:      []*AnnotatedInput{{AssetID: gold, AccountID: "alice", SpentOutputID: &o1}},
+		Outputs:     []*AnnotatedOutput{{OutputID: o2, AssetID: gold, AccountID: "bob"}},
+	}
+	toCarol := &AnnotatedTx{
+		BlockHeight: 10,
+		Inputs:      []*AnnotatedInput{{AssetID: gold, AccountID: "bob", SpentOutputID: &o2}},
+		Outputs: []*AnnotatedOutput{
+			{OutputID: o3, AssetID: gold, AccountID: "carol"},
+			{OutputID: o4, AssetID: gold, AccountID: "bob"},
+		},
+	}
+	toAlice := &AnnotatedTx{
+		BlockHeight: 10,
+		Inputs:      []*AnnotatedInput{{AssetID: gold, AccountID: "carol", SpentOutputID: &o3}},
+		Outputs: []*AnnotatedOutput{
+			{OutputID: o5, AssetID: gold, AccountID: "alice"},
+			{AssetID: silver, AccountID: "alice"},
+		},
+	}
+	txs := []*AnnotatedTx{issue, toBob, toCarol, toAlice}
+
+	err := NewWashAnnotator(nil, 5).Annotate(context.Background(), txs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for i, want := range []int{0, 0, 0, 1} {
+		if txs[i].WashScore != want {
+			t.Errorf("tx %d wash score = %d want %d", i, txs[i].WashScore, want)
+		}
+	}
+}
+
+func TestWashAnnotatorMaxHops(t *testing.T) {
+	gold := bc.NewAssetID([32]byte{1})
+
+	// Alice's gold passes through more accounts than
+	// the annotator traces before coming back to her.
+	var (
+		txs  []*AnnotatedTx
+		prev *bc.Hash
+	)
+	for i := 0; i <= maxWashHops+2; i++ {
+		account := fmt.Sprintf("acc%d", i)
+		if i == 0 || i == maxWashHops+2 {
+			account = "alice"
+		}
+		tx := &AnnotatedTx{BlockHeight: 10}
+		if prev == nil {
+			tx.Inputs = []*AnnotatedInput{{Type: "issue", AssetID: gold}}
+		} else {
+			tx.Inputs = []*AnnotatedInput{{AssetID: gold, AccountID: txs[i-1].Outputs[0].AccountID, SpentOutputID: prev}}
+		}
+		out := bc.NewHash([32]byte{byte(i + 1)})
+		tx.Outputs = []*AnnotatedOutput{{OutputID: out, AssetID: gold, AccountID: account}}
+		txs = append(txs, tx)
+		prev = &out
+	}
+
+	before := washTruncated.Value()
+	err := NewWashAnnotator(nil, 5).Annotate(context.Background(), txs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got := txs[len(txs)-1].WashScore; got != 0 {
+		t.Errorf("wash score = %d, want 0 past %d hops", got, maxWashHops)
+	}
+	// The traces from the last two transactions
+	// are cut short.
+	if got := washTruncated.Value() - before; got != 2 {
+		t.Errorf("truncated traces = %d, want 2", got)
+	}
+}
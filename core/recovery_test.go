@@ -55,10 +55,15 @@ func TestRecovery(t *testing.T) {
 	go indexer.ProcessBlocks(ctx)
 
 	// Setup the transaction query indexer to index every transaction.
-	indexer.RegisterAnnotator(accounts.AnnotateTxs)
-	indexer.RegisterAnnotator(assets.AnnotateTxs)
+	err := indexer.RegisterAnnotator(accounts.Annotator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = indexer.RegisterAnnotator(assets.Annotator())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	var err error
 	pinHeight := c.Height()
 	if pinHeight > 0 {
 		pinHeight = pinHeight - 1
@@ -241,8 +246,14 @@ func generateBlock(ctx context.Context, t testing.TB, db pg.DB, timestamp time.T
 	// Setup the transaction query indexer to index every transaction.
 	assets.IndexAssets(indexer)
 	accounts.IndexAccounts(indexer)
-	indexer.RegisterAnnotator(assets.AnnotateTxs)
-	indexer.RegisterAnnotator(accounts.AnnotateTxs)
+	err = indexer.RegisterAnnotator(assets.Annotator())
+	if err != nil {
+		return err
+	}
+	err = indexer.RegisterAnnotator(accounts.Annotator())
+	if err != nil {
+		return err
+	}
 	err = pinStore.LoadAll(ctx)
 	if err != nil {
 		return err
@@ -41,6 +41,35 @@ func (a *API) filterAliases(ctx context.Context, br *buildRequest) error {
 			}
 			m["account_id"] = acc.ID
 		}
+
+		// Transfer actions carry their own list of destinations,
+		// each of which may name its asset and account by alias.
+		dests, _ := m["destinations"].([]interface{})
+		for j, d := range dests {
+			dm, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ = dm["asset_id"].(string)
+			alias, _ = dm["asset_alias"].(string)
+			if id == "" && alias != "" {
+				asset, err := a.assets.FindByAlias(ctx, alias)
+				if err != nil {
+					return errors.WithDetailf(err, "invalid asset alias %s on destination %d of action %d", alias, j, i)
+				}
+				dm["asset_id"] = asset.AssetID
+			}
+
+			id, _ = dm["account_id"].(string)
+			alias, _ = dm["account_alias"].(string)
+			if id == "" && alias != "" {
+				acc, err := a.accounts.FindByAlias(ctx, alias)
+				if err != nil {
+					return errors.WithDetailf(err, "invalid account alias %s on destination %d of action %d", alias, j, i)
+				}
+				dm["account_id"] = acc.ID
+			}
+		}
 	}
 	return nil
 }
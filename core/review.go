@@ -0,0 +1,304 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"chain/core/txbuilder"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+)
+
+// defaultApprovalTTL is how long an approval token
+// from /review-transaction stays valid.
+const defaultApprovalTTL = 10 * time.Minute
+
+var (
+	errApprovalRequired = errors.New("transaction requires approval")
+	errBadApproval      = errors.New("invalid or expired approval token")
+)
+
+// txReview summarizes a transaction template for a person to
+// check before its inputs are signed.
+type txReview struct {
+	ID      bc.Hash       `json:"id"`
+	Inputs  []reviewEntry `json:"inputs"`
+	Outputs []reviewEntry `json:"outputs"`
+
+	// AccountChanges is the net change, per asset, to each
+	// account of this Core that the transaction touches.
+	AccountChanges []accountChange `json:"account_changes"`
+
+	MinTime *time.Time `json:"min_time,omitempty"`
+	MaxTime *time.Time `json:"max_time,omitempty"`
+
+	// ApprovalRequired reports whether signing the template
+	// needs the approval token. ApprovalToken and
+	// ApprovalExpiresAt are set only if it does.
+	ApprovalRequired  bool       `json:"approval_required"`
+	ApprovalToken     string     `json:"approval_token,omitempty"`
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`
+}
+
+type reviewEntry struct {
+	Type           string             `json:"type"` // issue, spend, control, or retire
+	AssetID        bc.AssetID         `json:"asset_id"`
+	Amount         uint64             `json:"amount"`
+	ControlProgram chainjson.HexBytes `json:"control_program,omitempty"`
+	AccountID      string             `json:"account_id,omitempty"`
+}
+
+type accountChange struct {
+	AccountID string     `json:"account_id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+	Amount    int64      `json:"amount"` // negative for a decrease
+}
+
+// POST /review-transaction
+//
+// reviewTransactions returns a txReview for each template, so
+// that a second person can check what a transaction does before
+// it is signed. Where the Core requires approval to sign a
+// template, the review includes a token to set as the
+// template's approval_token, as that approval.
+//
+// Only the client-approver policy grants access to reviews,
+// and only internal callers can grant it, so the holder of a
+// client-readwrite token, who builds and signs transactions,
+// can't approve them too.
+func (a *API) reviewTransactions(ctx context.Context, tpls []*txbuilder.Template) []interface{} {
+	responses := make([]interface{}, len(tpls))
+	for i, tpl := range tpls {
+		review, err := a.review(ctx, tpl)
+		if err != nil {
+			responses[i] = formatItemError(ctx, err)
+		} else {
+			responses[i] = review
+		}
+	}
+	return responses
+}
+
+func (a *API) review(ctx context.Context, tpl *txbuilder.Template) (*txReview, error) {
+	tx := tpl.Transaction
+	if tx == nil {
+		return nil, errors.Wrap(txbuilder.ErrMissingRawTx)
+	}
+	if tx.HasUnknownInput() {
+		return nil, errors.Wrap(txbuilder.ErrUnknownInput)
+	}
+
+	var progs [][]byte
+	for _, in := range tx.Inputs {
+		if !in.IsIssuance() {
+			progs = append(progs, in.ControlProgram())
+		}
+	}
+	for _, out := range tx.Outputs {
+		progs = append(progs, out.ControlProgram)
+	}
+	owners, err := a.accounts.ProgramAccounts(ctx, progs)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &txReview{ID: tx.ID}
+	flows := make(map[accountChange]*accountFlow) // by account and asset; Amount unset
+	addFlow := func(e reviewEntry, spent bool) error {
+		amount, err := bc.NewAmount(e.AssetID, e.Amount)
+		if err != nil || e.AccountID == "" {
+			return err
+		}
+		key := accountChange{AccountID: e.AccountID, AssetID: e.AssetID}
+		f := flows[key]
+		if f == nil {
+			f = &accountFlow{spent: bc.ZeroAmount(e.AssetID), received: bc.ZeroAmount(e.AssetID)}
+			flows[key] = f
+		}
+		if spent {
+			f.spent, err = f.spent.Add(amount)
+		} else {
+			f.received, err = f.received.Add(amount)
+		}
+		return err
+	}
+	for _, in := range tx.Inputs {
+		e := reviewEntry{Type: "issue", AssetID: in.AssetID(), Amount: in.Amount()}
+		if !in.IsIssuance() {
+			e.Type = "spend"
+			e.ControlProgram = in.ControlProgram()
+			e.AccountID = owners[string(e.ControlProgram)]
+		}
+		err = addFlow(e, true)
+		if err != nil {
+			return nil, err
+		}
+		review.Inputs = append(review.Inputs, e)
+	}
+	for _, out := range tx.Outputs {
+		e := reviewEntry{Type: "control", AssetID: *out.AssetId, Amount: out.Amount}
+		if vmutil.IsUnspendable(out.ControlProgram) {
+			e.Type = "retire"
+		} else {
+			e.ControlProgram = out.ControlProgram
+			e.AccountID = owners[string(out.ControlProgram)]
+		}
+		err = addFlow(e, false)
+		if err != nil {
+			return nil, err
+		}
+		review.Outputs = append(review.Outputs, e)
+	}
+	for c, f := range flows {
+		c.Amount = f.net()
+		if c.Amount != 0 {
+			review.AccountChanges = append(review.AccountChanges, c)
+		}
+	}
+	sort.Slice(review.AccountChanges, func(i, j int) bool {
+		ci, cj := review.AccountChanges[i], review.AccountChanges[j]
+		if ci.AccountID != cj.AccountID {
+			return ci.AccountID < cj.AccountID
+		}
+		return bytes.Compare(ci.AssetID.Bytes(), cj.AssetID.Bytes()) < 0
+	})
+
+	if tx.MinTime > 0 {
+		t := millisTime(tx.MinTime)
+		review.MinTime = &t
+	}
+	if tx.MaxTime > 0 {
+		t := millisTime(tx.MaxTime)
+		review.MaxTime = &t
+	}
+
+	if a.approver != nil && a.approver.required(tx) {
+		exp := time.Now().Add(a.approver.ttl)
+		review.ApprovalRequired = true
+		review.ApprovalToken = a.approver.token(tx.ID, exp)
+		review.ApprovalExpiresAt = &exp
+	}
+	return review, nil
+}
+
+// accountFlow is the amount of an asset an account
+// spends and receives in a transaction.
+type accountFlow struct {
+	spent, received bc.Amount
+}
+
+// net returns the amount received less the amount spent.
+// Both are at most 2^63-1, so the difference fits.
+func (f *accountFlow) net() int64 {
+	d, err := f.received.Sub(f.spent)
+	if err == nil {
+		return int64(d.Units())
+	}
+	d, _ = f.spent.Sub(f.received)
+	return -int64(d.Units())
+}
+
+func millisTime(ms uint64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+}
+
+// approver issues and checks the approval tokens that
+// the sign endpoint requires for large transactions.
+//
+// A token is the expiration time followed by an HMAC,
+// under key, of the transaction ID and that time. It
+// approves only the transaction with that ID, so any
+// change to the transaction needs a new review.
+type approver struct {
+	key       []byte
+	threshold uint64
+	ttl       time.Duration
+}
+
+// required reports whether tx needs approval to be signed:
+// whether its inputs carry more than the threshold amount
+// of any one asset, or more than the protocol allows, or
+// whether it has an input whose amount can't be known.
+func (ap *approver) required(tx *legacy.Tx) bool {
+	if tx.HasUnknownInput() {
+		return true
+	}
+	totals := make(map[bc.AssetID]bc.Amount)
+	for _, in := range tx.Inputs {
+		assetID := in.AssetID()
+		amount, err := bc.NewAmount(assetID, in.Amount())
+		if err != nil {
+			return true
+		}
+		total, ok := totals[assetID]
+		if !ok {
+			total = bc.ZeroAmount(assetID)
+		}
+		total, err = total.Add(amount)
+		if err != nil || total.Units() > ap.threshold {
+			return true
+		}
+		totals[assetID] = total
+	}
+	return false
+}
+
+func (ap *approver) token(txID bc.Hash, exp time.Time) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(exp.Unix()))
+	return hex.EncodeToString(append(b[:], ap.mac(txID, b[:])...))
+}
+
+func (ap *approver) mac(txID bc.Hash, exp []byte) []byte {
+	h := hmac.New(sha256.New, ap.key)
+	h.Write(txID.Bytes())
+	h.Write(exp)
+	return h.Sum(nil)
+}
+
+// checkApproval returns an error if the Core requires approval
+// to sign tpl and tpl has no valid approval token. Every path by
+// which the Core signs a template, or hands out what to sign for
+// it, checks it first.
+func (a *API) checkApproval(tpl *txbuilder.Template) error {
+	if a.approver == nil {
+		return nil
+	}
+	if tpl.Transaction == nil {
+		return errors.Wrap(txbuilder.ErrMissingRawTx)
+	}
+	return a.approver.check(tpl.Transaction, tpl.ApprovalToken, time.Now())
+}
+
+// check returns errApprovalRequired if tx needs approval
+// and token is empty, and errBadApproval if token is not
+// an unexpired approval of tx.
+func (ap *approver) check(tx *legacy.Tx, token string, now time.Time) error {
+	if !ap.required(tx) {
+		return nil
+	}
+	if token == "" {
+		return errors.WithDetailf(errApprovalRequired, "transaction %x needs an approval token from /review-transaction", tx.ID.Bytes())
+	}
+	b, err := hex.DecodeString(token)
+	if err != nil || len(b) != 8+sha256.Size {
+		return errors.Wrap(errBadApproval)
+	}
+	if !hmac.Equal(b[8:], ap.mac(tx.ID, b[:8])) {
+		return errors.Wrap(errBadApproval)
+	}
+	exp := time.Unix(int64(binary.BigEndian.Uint64(b[:8])), 0)
+	if now.After(exp) {
+		return errors.WithDetailf(errBadApproval, "approval expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/net/http/httperror"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestApprover(t *testing.T) {
+	ap := &approver{key: []byte("secret"), threshold: 100, ttl: time.Minute}
+	assetID := bc.AssetID{V0: 1}
+	spend := func(amount uint64) *legacy.TxInput {
+		return legacy.NewSpendInput(nil, bc.Hash{V0: amount}, assetID, amount, 0, []byte{0x51}, bc.Hash{}, nil)
+	}
+	tx := func(amounts ...uint64) *legacy.Tx {
+		data := legacy.TxData{Version: 1}
+		var sum uint64
+		for _, amount := range amounts {
+			data.Inputs = append(data.Inputs, spend(amount))
+			sum += amount
+		}
+		data.Outputs = []*legacy.TxOutput{legacy.NewTxOutput(assetID, sum, []byte{0x52}, nil)}
+		return legacy.NewTx(data)
+	}
+	small := tx(60)
+	large := tx(60, 50)
+	other := tx(70, 50)
+
+	now := time.Now()
+	token := ap.token(large.ID, now.Add(time.Minute))
+	cases := []struct {
+		tx      *legacy.Tx
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{small, "", now, nil},
+		{large, "", now, errApprovalRequired},
+		{large, token, now, nil},
+		{large, token, now.Add(2 * time.Minute), errBadApproval},
+		{large, "zz", now, errBadApproval},
+		{other, token, now, errBadApproval},
+	}
+	for i, c := range cases {
+		err := ap.check(c.tx, c.token, c.now)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: check err = %v, want %v", i, err, c.wantErr)
+		}
+	}
+
+	// Each signing path checks the template's token.
+	api := &API{approver: ap}
+	tpl := &txbuilder.Template{Transaction: large}
+	err := api.checkApproval(tpl)
+	if errors.Root(err) != errApprovalRequired {
+		t.Errorf("checkApproval without token: err = %v, want %v", err, errApprovalRequired)
+	}
+	resp := api.signingPayloads(context.Background(), []*txbuilder.Template{tpl})[0]
+	if info, ok := resp.(httperror.Response); !ok || info.ChainCode != "CH741" {
+		t.Errorf("signingPayloads without token = %+v, want error CH741", resp)
+	}
+	tpl.ApprovalToken = ap.token(large.ID, time.Now().Add(time.Minute))
+	err = api.checkApproval(tpl)
+	if err != nil {
+		t.Errorf("checkApproval with token: err = %v", err)
+	}
+	resp = api.signingPayloads(context.Background(), []*txbuilder.Template{tpl})[0]
+	if _, ok := resp.(signingPayloadsResp); !ok {
+		t.Errorf("signingPayloads with token = %+v, want payloads", resp)
+	}
+}
+
+func TestAccountFlowNet(t *testing.T) {
+	assetID := bc.AssetID{V0: 1}
+	amount := func(units uint64) bc.Amount {
+		a, err := bc.NewAmount(assetID, units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	cases := []struct {
+		spent, received uint64
+		want            int64
+	}{
+		{10, 3, -7},
+		{3, 10, 7},
+		{5, 5, 0},
+		{math.MaxInt64, 0, -math.MaxInt64},
+	}
+	for _, c := range cases {
+		f := &accountFlow{spent: amount(c.spent), received: amount(c.received)}
+		got := f.net()
+		if got != c.want {
+			t.Errorf("net(spent %d, received %d) = %d, want %d", c.spent, c.received, got, c.want)
+		}
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"chain/core/generator"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/net/http/httpjson"
 	"chain/protocol/bc"
 )
 
+var errNotGenerator = errors.New("core is not the generator")
+
 // getBlockRPC returns the block at the requested height.
 // If successful, it always returns at least one block,
 // waiting if necessary until one is created.
@@ -29,6 +32,16 @@ func (a *API) getBlockRPC(ctx context.Context, height uint64) (chainjson.HexByte
 	return rawBlock, nil
 }
 
+// getTxStatusRPC reports the status of a transaction
+// in this core's generator.
+func (a *API) getTxStatusRPC(ctx context.Context, id bc.Hash) (*generator.TxStatus, error) {
+	if a.generator == nil {
+		return nil, errors.Wrap(errNotGenerator)
+	}
+	st := a.generator.GetTxStatus(id)
+	return &st, nil
+}
+
 type snapshotInfoResp struct {
 	Height       uint64  `json:"height"`
 	Size         uint64  `json:"size"`
@@ -4,6 +4,7 @@ package rpc
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,19 +16,29 @@ import (
 	"chain/errors"
 	"chain/net/http/httperror"
 	"chain/net/http/reqid"
+	"chain/protocol/vm"
 )
 
 // Chain-specific header fields
 const (
-	HeaderBlockchainID = "Blockchain-ID"
-	HeaderCoreID       = "Chain-Core-ID"
-	HeaderTimeout      = "RPC-Timeout"
+	HeaderBlockchainID  = "Blockchain-ID"
+	HeaderCostTableHash = "Cost-Table-Hash"
+	HeaderCoreID        = "Chain-Core-ID"
+	HeaderTimeout       = "RPC-Timeout"
 )
 
-// ErrWrongNetwork is returned when a peer's blockchain ID differs from
-// the RPC client's blockchain ID.
+// ErrWrongNetwork is returned when a peer's blockchain ID or opcode
+// cost table hash differs from the RPC client's.
 var ErrWrongNetwork = errors.New("connected to a peer on a different network")
 
+// DefaultCostTableHash is the hex-encoded hash of
+// vm.DefaultCostTable. A peer that sends no Cost-Table-Hash
+// header is taken to use the default table.
+var DefaultCostTableHash = func() string {
+	h := vm.DefaultCostTable.Hash()
+	return hex.EncodeToString(h[:])
+}()
+
 // A Client is a Chain RPC client. It performs RPCs over HTTP using JSON
 // request and responses. A Client must be configured with a secret token
 // to authenticate with other Cores on the network.
@@ -39,6 +50,11 @@ type Client struct {
 	BlockchainID string
 	CoreID       string
 
+	// CostTableHash is the hex-encoded hash of the opcode cost
+	// table the client's Core validates with. All Cores on a
+	// blockchain must use the same table.
+	CostTableHash string
+
 	// If set, Client is used for outgoing requests.
 	// TODO(kr): make this required (crash on nil)
 	Client *http.Client
@@ -111,11 +127,16 @@ func (c *Client) CallRaw(ctx context.Context, path string, request interface{})
 	}
 
 	// Propagate our request ID so that we can trace a request across nodes.
-	req.Header.Add("Request-ID", reqid.FromContext(ctx))
+	if id := reqid.FromContext(ctx); id != "" {
+		req.Header.Set(reqid.Header, id)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.userAgent())
 	req.Header.Set(HeaderBlockchainID, c.BlockchainID)
 	req.Header.Set(HeaderCoreID, c.CoreID)
+	if c.CostTableHash != "" {
+		req.Header.Set(HeaderCostTableHash, c.CostTableHash)
+	}
 
 	// Propagate our deadline if we have one.
 	deadline, ok := ctx.Deadline()
@@ -138,6 +159,16 @@ func (c *Client) CallRaw(ctx context.Context, path string, request interface{})
 		resp.Body.Close()
 		return nil, errors.Wrap(ErrWrongNetwork)
 	}
+	if c.CostTableHash != "" {
+		h := resp.Header.Get(HeaderCostTableHash)
+		if h == "" {
+			h = DefaultCostTableHash
+		}
+		if c.CostTableHash != h {
+			resp.Body.Close()
+			return nil, errors.WithDetail(ErrWrongNetwork, "peer uses a different opcode cost table")
+		}
+	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		defer resp.Body.Close()
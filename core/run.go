@@ -12,7 +12,9 @@ import (
 	"chain/core/accesstoken"
 	"chain/core/account"
 	"chain/core/asset"
+	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/eventlog"
 	"chain/core/fetch"
 	"chain/core/generator"
 	"chain/core/leader"
@@ -22,8 +24,10 @@ import (
 	"chain/core/txbuilder"
 	"chain/core/txdb"
 	"chain/core/txfeed"
+	"chain/core/volume"
 	"chain/database/pg"
 	"chain/database/sinkdb"
+	"chain/env"
 	"chain/log"
 	"chain/net/http/authz"
 	"chain/protocol"
@@ -31,8 +35,8 @@ import (
 )
 
 const (
-	blockPeriod              = time.Second
-	expireReservationsPeriod = time.Second
+	blockPeriod            = time.Second
+	reapReservationsPeriod = time.Second
 )
 
 // RunOption describes a runtime configuration option.
@@ -75,6 +79,35 @@ func BlockSigner(signFn func(context.Context, *legacy.Block) ([]byte, error)) Ru
 	return func(a *API) { a.signer = signFn }
 }
 
+// BlockSignerPolicy configures the Core to accept block-signing
+// requests only from the generator keys listed in p, if any.
+// The block signer itself checks p's other rules.
+func BlockSignerPolicy(p *blocksigner.Policy) RunOption {
+	return func(a *API) { a.signerPolicy = p }
+}
+
+// ComplianceCheck configures the Core to call f with each
+// transaction submitted to it, after checking account policies
+// and before validating it. If f returns an error, the
+// transaction is rejected, with f's error as the detail.
+func ComplianceCheck(f func(context.Context, *legacy.Tx) error) RunOption {
+	return func(a *API) { a.complianceCheck = f }
+}
+
+// DisableSubmitChecks configures the Core to skip the named checks
+// of the submit pipeline. See SubmitChecks. It ignores the names
+// in RequiredSubmitChecks.
+func DisableSubmitChecks(names ...string) RunOption {
+	return func(a *API) {
+		a.disabledChecks = make(map[string]bool)
+		for _, name := range names {
+			if !RequiredSubmitChecks[name] {
+				a.disabledChecks[name] = true
+			}
+		}
+	}
+}
+
 // GeneratorLocal configures the launched Core to run as a Generator.
 func GeneratorLocal(gen *generator.Generator) RunOption {
 	return func(a *API) {
@@ -99,12 +132,113 @@ func GeneratorRemote(client *rpc.Client) RunOption {
 	}
 }
 
+// PendingBlockStore configures the Core to keep the generator's
+// pending block in s, the store its generator uses once it's
+// promoted. A standby Core saves there the block its fenced
+// primary was getting signed, so it commits that block first.
+// The default is the local generator's PendingStore, if any,
+// or else a store in the Core's database.
+func PendingBlockStore(s generator.BlockStore) RunOption {
+	return func(a *API) { a.pendingStore = s }
+}
+
+// BlockCallback configures a participant Core to ask the
+// generator to push each new block header to url, the base URL
+// at which the generator can reach this Core. Only block
+// signers may ask, and url must be the URL the generator's
+// config has for the signer. Without it, the Core learns of new
+// blocks only by polling.
+func BlockCallback(url string) RunOption {
+	return func(a *API) { a.callbackURL = url }
+}
+
+// QueryDB configures the Core to keep its annotated query tables
+// in db, which may be on a different Postgres cluster than the
+// blocks and state, to isolate query load from them. db must
+// have the Core's schema. Tag history stays in the main database,
+// with the accounts and assets whose tags it records. Indexed
+// heights are still tracked by the pin store in the main
+// database, and advance only once a block's annotations are
+// stored in db. Indexing a block is idempotent, so if the two
+// diverge after a crash, the block is indexed again.
+func QueryDB(db pg.DB) RunOption {
+	return func(a *API) { a.queryDB = db }
+}
+
+// SigningApproval configures the Core to refuse to sign a
+// transaction whose inputs carry more than threshold units of
+// any one asset, unless its template includes the approval
+// token from a review of that transaction. The Core refuses
+// both to sign it with the MockHSM and to return its signing
+// payloads for other signers. Tokens are authenticated with
+// key, which every process of the Core must share, and expire
+// after ten minutes.
+//
+// This supports maker-checker flows, where one person builds
+// a transaction and another reviews and approves it.
+func SigningApproval(key []byte, threshold uint64) RunOption {
+	return func(a *API) {
+		a.approver = &approver{key: key, threshold: threshold, ttl: defaultApprovalTTL}
+	}
+}
+
 // IndexTransactions configures whether or not transactions should be
 // annotated and indexed for the query engine.
 func IndexTransactions(b bool) RunOption {
 	return func(a *API) { a.indexTxs = b }
 }
 
+// Annotators configures the Core to annotate indexed transactions
+// with as, in addition to the asset, account and local annotations.
+// Each runs after the annotators named by its After method. It has
+// no effect unless transactions are indexed.
+func Annotators(as ...query.Annotator) RunOption {
+	return func(a *API) { a.annotators = append(a.annotators, as...) }
+}
+
+// WashDetection configures the Core to annotate indexed
+// transactions with a wash_score, counting the outputs that
+// return an asset to an account it left, through other accounts,
+// within the last window blocks. See query.NewWashAnnotator.
+// It has no effect unless transactions are indexed.
+func WashDetection(window uint64) RunOption {
+	return func(a *API) { a.washWindow = window }
+}
+
+// ReindexDelay configures the pause a reindex of annotated
+// transactions takes after each block, to limit its load on
+// the query database. See /reindex-transactions.
+func ReindexDelay(d time.Duration) RunOption {
+	return func(a *API) { a.reindexDelay = d }
+}
+
+// PublishEvents configures the Core to publish a log of blockchain
+// activity to pub. It has no effect unless transactions are indexed.
+func PublishEvents(pub eventlog.Publisher) RunOption {
+	return func(a *API) { a.eventPublisher = pub }
+}
+
+// FinalityPins configures the Core to report a block as final
+// to clients only once each of the named block processors has
+// processed it. A transaction submitted with wait_until
+// "confirmed" is then reported only once it can be queried,
+// and /info reports the latest final block as final_block_height.
+// Each pin must be one the Core runs: "account", "asset",
+// and, if transactions are indexed, "tx".
+func FinalityPins(pins ...string) RunOption {
+	return func(a *API) { a.finalityPins = pins }
+}
+
+// NodeSettings configures the settings the Core reports at
+// /node-settings: the config file the process read, if any, and
+// the values of its env vars, with secrets already redacted.
+func NodeSettings(configFile string, settings []env.Value) RunOption {
+	return func(a *API) {
+		a.configFile = configFile
+		a.settings = settings
+	}
+}
+
 // RateLimit adds a rate-limiting restriction, using keyFn to extract the
 // key to rate limit on. It will allow up to burst requests in the bucket
 // and will refill the bucket at perSecond tokens per second.
@@ -161,6 +295,10 @@ func Run(
 	routableAddress string,
 	opts ...RunOption,
 ) (*API, error) {
+	// Queries made by the block processors started below, and
+	// by this core when it is leader, run as indexer queries.
+	ctx = pg.WithQueryClass(ctx, pg.Indexer)
+
 	// Set up the pin store for block processing
 	pinStore := pin.NewStore(db)
 	err := pinStore.LoadAll(ctx)
@@ -175,7 +313,6 @@ func Run(
 
 	assets := asset.NewRegistry(db, c, pinStore)
 	accounts := account.NewManager(db, c, pinStore)
-	indexer := query.NewIndexer(db, c, pinStore)
 
 	a := &API{
 		chain:        c,
@@ -184,7 +321,6 @@ func Run(
 		assets:       assets,
 		accounts:     accounts,
 		txFeeds:      &txfeed.Tracker{DB: db},
-		indexer:      indexer,
 		accessTokens: &accesstoken.CredentialStore{DB: db},
 		grants:       authz.NewStore(sdb, GrantPrefix),
 		config:       conf,
@@ -200,21 +336,87 @@ func Run(
 	if a.remoteGenerator == nil && a.generator == nil {
 		return nil, errors.New("no generator configured")
 	}
+	if a.shadow != nil && (conf.IsGenerator || conf.IsSigner || a.canary != nil) {
+		return nil, errors.New("shadow core cannot generate or sign blocks, or run a canary")
+	}
+	if a.shadow != nil && !a.indexTxs {
+		return nil, errors.New("shadow core must index transactions")
+	}
+	if a.shadow != nil && a.shadow.prodCoreID == conf.Id {
+		return nil, errors.New("shadow core has the production core's ID")
+	}
+	if a.queryDB == nil {
+		a.queryDB = db
+	}
+	if a.pendingStore == nil && a.generator != nil {
+		a.pendingStore = a.generator.PendingStore
+	} else if a.pendingStore == nil {
+		a.pendingStore = generator.NewDBBlockStore(db)
+	}
+	if confOpts != nil {
+		a.fence = confOpts.GetFunc(fenceOption)
+	}
+	a.indexer = query.NewIndexer(a.queryDB, c, pinStore)
+	a.indexer.SetMainDB(db)
+	a.indexer.SetReindexDelay(a.reindexDelay)
+	a.accounts.SetQueryDB(a.queryDB)
 
 	if a.replicator != nil {
 		go a.replicator.PollRemoteHeight(ctx)
 	}
+	if a.generator != nil {
+		a.notifier = newBlockNotifier(rpc.Client{
+			CoreID:       conf.Id,
+			BlockchainID: conf.BlockchainId.String(),
+			Client:       a.httpClient,
+		}, conf.Signers)
+	}
 
 	if a.indexTxs {
+		annotators := append([]query.Annotator{a.assets.Annotator(), a.accounts.Annotator()}, a.annotators...)
+		if a.washWindow > 0 {
+			annotators = append(annotators, query.NewWashAnnotator(a.queryDB, a.washWindow))
+		}
+		for _, annotator := range annotators {
+			err = a.indexer.RegisterAnnotator(annotator)
+			if err != nil {
+				return nil, err
+			}
+		}
 		go pinStore.Listen(ctx, query.TxPinName, dbURL)
-		a.indexer.RegisterAnnotator(a.assets.AnnotateTxs)
-		a.indexer.RegisterAnnotator(a.accounts.AnnotateTxs)
 		a.assets.IndexAssets(a.indexer)
 		a.accounts.IndexAccounts(a.indexer)
+		if a.eventPublisher != nil {
+			go pinStore.Listen(ctx, eventlog.PinName, dbURL)
+			a.eventLog = eventlog.New(a.queryDB, c, pinStore, a.eventPublisher)
+		}
+	}
+	err = a.checkFinalityPins()
+	if err != nil {
+		return nil, err
 	}
 
-	// Clean up expired UTXO reservations periodically.
-	go accounts.ExpireReservations(ctx, expireReservationsPeriod)
+	// Keep the balances of hot accounts current as each block
+	// is committed, rather than waiting for the account indexer.
+	// A generator also pushes the block's header to the
+	// participants that asked for it, without holding up the
+	// commit.
+	c.OnCommit = func(ctx context.Context, b *legacy.Block) {
+		err := accounts.ApplyHotBlock(ctx, b)
+		if err != nil {
+			log.Error(ctx, err)
+		}
+		if a.notifier != nil {
+			go a.notifier.notify(ctx, &b.BlockHeader)
+		}
+	}
+
+	// Count transaction volume per asset as blocks land.
+	a.volume = volume.NewTracker()
+	go a.volume.ProcessBlocks(ctx, c)
+
+	// Release expired and stale UTXO reservations periodically.
+	go accounts.ReapReservations(ctx, reapReservationsPeriod)
 
 	// GC old submitted txs periodically.
 	go cleanUpSubmittedTxs(ctx, a.db)
@@ -261,6 +463,9 @@ func (a *API) lead(ctx context.Context) {
 		pinHeight = pinHeight - 1
 	}
 	pins := []string{account.PinName, account.ExpirePinName, account.DeleteSpentsPinName, asset.PinName, query.TxPinName}
+	if a.eventLog != nil {
+		pins = append(pins, eventlog.PinName)
+	}
 	for _, p := range pins {
 		err = a.pinStore.CreatePin(ctx, p, pinHeight)
 		if err != nil {
@@ -268,7 +473,11 @@ func (a *API) lead(ctx context.Context) {
 		}
 	}
 
-	if a.config.IsGenerator {
+	if a.config.IsGenerator && a.fenced() {
+		// A fenced generator has been replaced by another Core,
+		// so it must not generate any more blocks.
+		a.setHealth("generator", a.checkFence())
+	} else if a.config.IsGenerator {
 		go a.generator.Generate(ctx, blockPeriod, a.healthSetter("generator"))
 	} else {
 		// Remove the downloading snapshot if there was one. The core
@@ -278,10 +487,25 @@ func (a *API) lead(ctx context.Context) {
 		a.downloadingSnapshotMu.Unlock()
 
 		go a.replicator.Fetch(ctx, a.chain, a.healthSetter("fetch"))
+		if a.callbackURL != "" {
+			go a.replicator.RegisterBlockCallback(ctx, a.callbackURL)
+		}
 	}
 	go a.accounts.ProcessBlocks(ctx)
+	go a.accounts.ProcessHotBalances(ctx)
 	go a.assets.ProcessBlocks(ctx)
 	if a.indexTxs {
 		go a.indexer.ProcessBlocks(ctx)
+		go a.indexer.ProcessAliasBackfills(ctx)
+		go a.indexer.ProcessReindexes(ctx)
+	}
+	if a.canary != nil {
+		go a.runCanary(ctx)
+	}
+	if a.shadow != nil {
+		go a.runShadow(ctx)
+	}
+	if a.eventLog != nil {
+		go a.eventLog.ProcessBlocks(ctx)
 	}
 }
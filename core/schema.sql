@@ -156,6 +156,37 @@ CREATE TABLE account_control_programs (
 
 
 
+CREATE TABLE account_holds (
+    id text DEFAULT next_chain_id('hold'::text) NOT NULL,
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    reference text DEFAULT ''::text NOT NULL,
+    client_token text,
+    settling_tx_id bytea
+);
+
+
+
+CREATE TABLE account_policies (
+    account_id text NOT NULL,
+    version bigint NOT NULL,
+    policy jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE account_policy_overrides (
+    account_id text NOT NULL,
+    policy_version bigint NOT NULL,
+    control_program bytea NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+
+
 CREATE TABLE account_utxos (
     asset_id bytea NOT NULL,
     amount bigint NOT NULL,
@@ -167,7 +198,8 @@ CREATE TABLE account_utxos (
     source_id bytea NOT NULL,
     source_pos bigint NOT NULL,
     ref_data_hash bytea NOT NULL,
-    change boolean NOT NULL
+    change boolean NOT NULL,
+    vault boolean DEFAULT false NOT NULL
 );
 
 
@@ -180,6 +212,15 @@ CREATE TABLE accounts (
 
 
 
+CREATE TABLE alias_backfills (
+    object_type text NOT NULL,
+    object_id text NOT NULL,
+    alias text NOT NULL,
+    block_height bigint NOT NULL
+);
+
+
+
 CREATE TABLE annotated_accounts (
     id text NOT NULL,
     alias text NOT NULL,
@@ -220,7 +261,9 @@ CREATE TABLE annotated_inputs (
     issuance_program bytea NOT NULL,
     reference_data jsonb NOT NULL,
     local boolean NOT NULL,
-    spent_output_id bytea NOT NULL
+    spent_output_id bytea NOT NULL,
+    asset_alias_at_tx text NOT NULL,
+    account_alias_at_tx text
 );
 
 
@@ -245,7 +288,11 @@ CREATE TABLE annotated_outputs (
     account_tags jsonb,
     control_program bytea NOT NULL,
     reference_data jsonb NOT NULL,
-    local boolean NOT NULL
+    local boolean NOT NULL,
+    spent_block_height bigint,
+    lifetime_ms bigint,
+    asset_alias_at_tx text NOT NULL,
+    account_alias_at_tx text
 );
 
 
@@ -259,7 +306,8 @@ CREATE TABLE annotated_txs (
     block_id bytea NOT NULL,
     local boolean NOT NULL,
     reference_data jsonb NOT NULL,
-    block_tx_count integer
+    block_tx_count integer,
+    wash_score integer DEFAULT 0 NOT NULL
 );
 
 
@@ -321,6 +369,14 @@ CREATE SEQUENCE chain_id_seq
 
 
 
+CREATE TABLE canary_key (
+    singleton boolean DEFAULT true NOT NULL,
+    xprv bytea NOT NULL,
+    CONSTRAINT canary_key_singleton CHECK (singleton)
+);
+
+
+
 CREATE TABLE config (
     singleton boolean DEFAULT true NOT NULL,
     is_signer boolean,
@@ -357,6 +413,21 @@ CREATE TABLE generator_pending_block (
 
 
 
+CREATE TABLE hot_account_balances (
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL
+);
+
+
+
+CREATE TABLE hot_accounts (
+    account_id text NOT NULL,
+    block_height bigint NOT NULL
+);
+
+
+
 CREATE TABLE leader (
     singleton boolean DEFAULT true NOT NULL,
     leader_key text NOT NULL,
@@ -448,6 +519,29 @@ CREATE TABLE submitted_txs (
 
 
 
+CREATE TABLE tag_history (
+    object_type text NOT NULL,
+    object_id text NOT NULL,
+    tags jsonb,
+    replaced_at timestamp without time zone DEFAULT now() NOT NULL,
+    seq bigint NOT NULL
+);
+
+
+
+CREATE SEQUENCE tag_history_seq_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+
+ALTER SEQUENCE tag_history_seq_seq OWNED BY tag_history.seq;
+
+
+
 CREATE TABLE txfeeds (
     id text DEFAULT next_chain_id('cur'::text) NOT NULL,
     alias text,
@@ -458,10 +552,20 @@ CREATE TABLE txfeeds (
 
 
 
+CREATE TABLE watch_only_accounts (
+    account_id text NOT NULL
+);
+
+
+
 ALTER TABLE ONLY signers ALTER COLUMN key_index SET DEFAULT nextval('signers_key_index_seq'::regclass);
 
 
 
+ALTER TABLE ONLY tag_history ALTER COLUMN seq SET DEFAULT nextval('tag_history_seq_seq'::regclass);
+
+
+
 ALTER TABLE ONLY access_tokens
     ADD CONSTRAINT access_tokens_pkey PRIMARY KEY (id);
 
@@ -472,6 +576,26 @@ ALTER TABLE ONLY account_control_programs
 
 
 
+ALTER TABLE ONLY account_holds
+    ADD CONSTRAINT account_holds_client_token_key UNIQUE (client_token);
+
+
+
+ALTER TABLE ONLY account_holds
+    ADD CONSTRAINT account_holds_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY account_policies
+    ADD CONSTRAINT account_policies_pkey PRIMARY KEY (account_id, version);
+
+
+
+ALTER TABLE ONLY account_policy_overrides
+    ADD CONSTRAINT account_policy_overrides_pkey PRIMARY KEY (account_id, policy_version, control_program);
+
+
+
 ALTER TABLE ONLY accounts
     ADD CONSTRAINT account_tags_pkey PRIMARY KEY (account_id);
 
@@ -487,6 +611,11 @@ ALTER TABLE ONLY accounts
 
 
 
+ALTER TABLE ONLY alias_backfills
+    ADD CONSTRAINT alias_backfills_pkey PRIMARY KEY (object_type, object_id);
+
+
+
 ALTER TABLE ONLY annotated_accounts
     ADD CONSTRAINT annotated_accounts_pkey PRIMARY KEY (id);
 
@@ -503,12 +632,7 @@ ALTER TABLE ONLY annotated_inputs
 
 
 ALTER TABLE ONLY annotated_outputs
-    ADD CONSTRAINT annotated_outputs_output_id_key UNIQUE (output_id);
-
-
-
-ALTER TABLE ONLY annotated_outputs
-    ADD CONSTRAINT annotated_outputs_pkey PRIMARY KEY (block_height, tx_pos, output_index);
+    ADD CONSTRAINT annotated_outputs_pkey PRIMARY KEY (output_id);
 
 
 
@@ -552,6 +676,11 @@ ALTER TABLE ONLY blocks
 
 
 
+ALTER TABLE ONLY canary_key
+    ADD CONSTRAINT canary_key_pkey PRIMARY KEY (singleton);
+
+
+
 ALTER TABLE ONLY config
     ADD CONSTRAINT config_pkey PRIMARY KEY (singleton);
 
@@ -567,6 +696,16 @@ ALTER TABLE ONLY generator_pending_block
 
 
 
+ALTER TABLE ONLY hot_account_balances
+    ADD CONSTRAINT hot_account_balances_pkey PRIMARY KEY (account_id, asset_id);
+
+
+
+ALTER TABLE ONLY hot_accounts
+    ADD CONSTRAINT hot_accounts_pkey PRIMARY KEY (account_id);
+
+
+
 ALTER TABLE ONLY leader
     ADD CONSTRAINT leader_singleton_key UNIQUE (singleton);
 
@@ -632,6 +771,19 @@ ALTER TABLE ONLY txfeeds
 
 
 
+ALTER TABLE ONLY watch_only_accounts
+    ADD CONSTRAINT watch_only_accounts_pkey PRIMARY KEY (account_id);
+
+
+
+CREATE INDEX account_holds_account_id_asset_id_idx ON account_holds USING btree (account_id, asset_id);
+
+
+
+CREATE INDEX account_holds_settling_tx_id_idx ON account_holds USING btree (settling_tx_id);
+
+
+
 CREATE INDEX account_utxos_asset_id_account_id_confirmed_in_idx ON account_utxos USING btree (asset_id, account_id, confirmed_in);
 
 
@@ -640,6 +792,38 @@ CREATE INDEX annotated_assets_sort_id ON annotated_assets USING btree (sort_id);
 
 
 
+CREATE INDEX annotated_inputs_account_alias_idx ON annotated_inputs USING btree (account_alias);
+
+
+
+CREATE INDEX annotated_inputs_asset_alias_idx ON annotated_inputs USING btree (asset_alias);
+
+
+
+CREATE INDEX annotated_inputs_spent_output_id_idx ON annotated_inputs USING btree (spent_output_id);
+
+
+
+CREATE INDEX annotated_outputs_account_alias_idx ON annotated_outputs USING btree (account_alias);
+
+
+
+CREATE INDEX annotated_outputs_asset_alias_idx ON annotated_outputs USING btree (asset_alias);
+
+
+
+CREATE INDEX annotated_outputs_created_idx ON annotated_outputs USING btree (lower(timespan));
+
+
+
+CREATE UNIQUE INDEX annotated_outputs_position_idx ON annotated_outputs USING btree (block_height, tx_pos, output_index);
+
+
+
+CREATE INDEX annotated_outputs_spent_block_height_idx ON annotated_outputs USING btree (spent_block_height);
+
+
+
 CREATE INDEX annotated_outputs_timespan_idx ON annotated_outputs USING gist (timespan);
 
 
@@ -648,6 +832,10 @@ CREATE INDEX annotated_txs_data_idx ON annotated_txs USING gin (data jsonb_path_
 
 
 
+CREATE INDEX hot_accounts_block_height_idx ON hot_accounts USING btree (block_height);
+
+
+
 CREATE INDEX query_blocks_timestamp_idx ON query_blocks USING btree ("timestamp");
 
 
@@ -656,6 +844,14 @@ CREATE UNIQUE INDEX signed_blocks_block_height_idx ON signed_blocks USING btree
 
 
 
+CREATE INDEX tag_history_object_seq_idx ON tag_history USING btree (object_type, object_id, seq);
+
+
+
+CREATE UNIQUE INDEX tag_history_seq_idx ON tag_history USING btree (seq);
+
+
+
 
 insert into migrations (filename, hash) values ('2017-02-03.0.core.schema-snapshot.sql', '1d55668affe0be9f3c19ead9d67bc75cfd37ec430651434d0f2af2706d9f08cd');
 insert into migrations (filename, hash) values ('2017-02-07.0.query.non-null-alias.sql', '17028a0bdbc95911e299dc65fe641184e54c87a0d07b3c576d62d023b9a8defc');
@@ -668,3 +864,17 @@ insert into migrations (filename, hash) values ('2017-04-17.0.core.null-token-ty
 insert into migrations (filename, hash) values ('2017-04-27.0.generator.pending-block-height.sql', 'bfe4fe5eec143e4367a91fd952cb5e3879f1c311f649ec13bfe95b202e94d4ec');
 insert into migrations (filename, hash) values ('2017-05-08.0.core.drop-redundant-indexes.sql', '5140e53b287b058c57ddf361d61cff3d3d1cbc3259a9de413b11574a71d09bec');
 insert into migrations (filename, hash) values ('2017-06-28.0.core.coreid.sql', 'a147b93ba1bf404265efedde066532c937070a87e15123b1d9277daba431ee01');
+insert into migrations (filename, hash) values ('2017-07-01.0.query.output-created-index.sql', '4ad70651d32a3e282d2b8c1b112f0bef449a46b1f85d0d8ff6c21a1fd76c9337');
+insert into migrations (filename, hash) values ('2017-07-02.0.core.tag-history.sql', '4a48bf3c446940094f4c443a696e50171d6bf7dbee0ddfd6698b5c41747badda');
+insert into migrations (filename, hash) values ('2017-07-03.0.query.output-id-pkey.sql', 'f5245aee2be0b473241a7633e848d51ff304eca2767ac05e4ca5cf1e9e4442cb');
+insert into migrations (filename, hash) values ('2017-07-04.0.account.policies.sql', '00d4f19ee9e86d0921da69c881817804d9497a56612bfb8dda231e59f028e9cd');
+insert into migrations (filename, hash) values ('2017-07-06.0.query.output-lifetime.sql', '8b01c6a81a0871009a30a7eaa24681951364751a2473551e16462fc56072b8a9');
+insert into migrations (filename, hash) values ('2017-07-07.0.account.hot-accounts.sql', '47485a4da98fc5a6b57d499b963a32a25e6fac5519f89870891e6d0f48758101');
+insert into migrations (filename, hash) values ('2017-07-08.0.query.alias-columns.sql', '2f644cb2bb2d8cbb2feb2247da96dbef8a70ac6e70a2464f8f3b306d8e2b8857');
+insert into migrations (filename, hash) values ('2017-07-09.0.core.canary-key.sql', 'e1b249d0fbeafb90f218a7d03a021fa101fa82c11735a06a60d7436e07864407');
+insert into migrations (filename, hash) values ('2017-07-10.0.account.watch-only-accounts.sql', 'e75ba6c1df406534d4b8962d0968174ad07130a942b4742f87cebd13124e4f6c');
+insert into migrations (filename, hash) values ('2017-07-11.0.account.holds.sql', 'e66f1439cc6c2ca4cb6fb66718a6cf5ed6632be76bc988b9fc3dd7a2fae8f107');
+insert into migrations (filename, hash) values ('2017-07-13.0.query.wash-score.sql', 'e04d38d40ca3e5de1a2a613fa73a83d697e34a801299f5cab3510e1464154ea2');
+insert into migrations (filename, hash) values ('2017-07-14.0.account.vault-utxos.sql', '0bbc6ad25fad3681f5dcfe3b417324d5de8469856fa3cbcad672a564b8d9fde1');
+insert into migrations (filename, hash) values ('2017-07-15.0.account.hold-settlements.sql', '2a2f35654695af7a5de63c6c8e0e1946b8748b85fb1528c63d0a66ed0a0837a4');
+insert into migrations (filename, hash) values ('2017-07-16.0.query.annotated-inputs-spent-output.sql', 'f8b0556b415b30e07d55d93baa55a1bd44abb74b6823e01b3a789a467ab710d9');
@@ -0,0 +1,340 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"chain/core/query"
+	"chain/core/rpc"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+)
+
+var (
+	errShadow         = errors.New("shadow core")
+	errShadowMismatch = errors.New("shadow core disagrees with production core")
+)
+
+// shadow runs a Core as a shadow of a production Core, to try
+// out a new version of cored before upgrading to it. A shadow
+// Core replicates blocks from the same generator as the
+// production Core, validating and indexing them into its own
+// database, but it signs no blocks and accepts no transactions.
+// Periodically, it compares the results of the blocks since its
+// last comparison with the production Core's: the number of
+// annotated transactions and outputs in each block, and a digest
+// of the parts of them that don't depend on the Core's own
+// accounts and assets. (Block IDs would always agree, since both
+// Cores get their blocks from the same generator.) It compares
+// at most maxIndexSummaryBlocks blocks at a time, starting with
+// the latest ones when it starts. Disagreements are reported by
+// /health, under the "shadow" key.
+type shadow struct {
+	prod       *rpc.Client
+	prodCoreID string
+	period     time.Duration
+
+	mu    sync.Mutex
+	stats shadowStats
+}
+
+type shadowStats struct {
+	Comparisons uint64 `json:"comparisons"`
+	Mismatches  uint64 `json:"mismatches"`
+
+	// LastHeight is the height of the last block whose
+	// results the shadow compared with the production Core.
+	LastHeight uint64 `json:"last_block_height"`
+
+	// LastError is the error from the last
+	// comparison, if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Shadow configures the Core to run as a shadow of the
+// production Core reached by prod, comparing results with it
+// every period while it is the leader. The shadow must be
+// configured to replicate blocks from the production Core's
+// generator, and must have its own database; prodCoreID is
+// the production Core's ID, as returned by CheckShadowOf.
+func Shadow(prod *rpc.Client, prodCoreID string, period time.Duration) RunOption {
+	return func(a *API) { a.shadow = &shadow{prod: prod, prodCoreID: prodCoreID, period: period} }
+}
+
+// CheckShadowOf checks that the production Core reached by prod
+// doesn't use db, the database of a shadow of it. It must be
+// called before migrating db, since the production Core may run
+// an older version of cored. It returns the production Core's ID.
+func CheckShadowOf(ctx context.Context, prod *rpc.Client, db pg.DB) (string, error) {
+	var info struct {
+		CoreID     string `json:"core_id"`
+		DatabaseID string `json:"database_id"`
+	}
+	err := prod.Call(ctx, "/info", nil, &info)
+	if err != nil {
+		return "", errors.Wrap(err, "getting production core's info")
+	}
+	if info.DatabaseID == "" {
+		return "", errors.New("production core does not report its database ID")
+	}
+	id, err := databaseID(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if id == info.DatabaseID {
+		return "", errors.New("shadow core uses the production core's database")
+	}
+	return info.CoreID, nil
+}
+
+// databaseID identifies the database and schema used by db, for
+// telling whether two Cores share them. It doesn't need
+// pg_control_system (Postgres 9.6): two Cores connected to the
+// same server see the same postmaster start time, and two
+// servers are all but certain to have been started at different
+// microseconds.
+func databaseID(ctx context.Context, db pg.DB) (string, error) {
+	const q = `
+		SELECT md5(concat_ws('/', pg_postmaster_start_time(), oid, current_schema()))
+		FROM pg_database WHERE datname = current_database()
+	`
+	var id string
+	err := db.QueryRowContext(ctx, q).Scan(&id)
+	return id, errors.Wrap(err, "getting database ID")
+}
+
+func (s *shadow) snapshot() shadowStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// maxIndexSummaryBlocks is the most blocks
+// an index summary may cover.
+const maxIndexSummaryBlocks = 100
+
+// indexSummary describes the results of validating and
+// indexing a range of blocks, for comparing the results
+// of two Cores.
+type indexSummary struct {
+	FromHeight  uint64 `json:"from_height"`
+	BlockHeight uint64 `json:"block_height"`
+
+	// Indexed reports whether the Core's query indexer has
+	// processed the blocks. If so, AnnotatedTxs and
+	// AnnotatedOutputs count the annotated rows of each
+	// block, from FromHeight to BlockHeight, and Digests
+	// hashes their contents.
+	Indexed          bool     `json:"indexed"`
+	AnnotatedTxs     []uint64 `json:"annotated_transactions"`
+	AnnotatedOutputs []uint64 `json:"annotated_outputs"`
+	Digests          []string `json:"digests"`
+}
+
+// getIndexSummary is an http handler summarizing the results of
+// validating and indexing the blocks from from_height to
+// block_height, for comparison with a shadow Core.
+//
+// POST /get-index-summary
+func (a *API) getIndexSummary(ctx context.Context, in struct {
+	FromHeight  uint64 `json:"from_height"`
+	BlockHeight uint64 `json:"block_height"`
+}) (indexSummary, error) {
+	if in.FromHeight == 0 || in.FromHeight > in.BlockHeight || in.BlockHeight > a.chain.Height() {
+		return indexSummary{}, errors.WithDetailf(httpjson.ErrBadRequest, "blocks %d to %d are not in this core's blockchain", in.FromHeight, in.BlockHeight)
+	}
+	if in.BlockHeight-in.FromHeight >= maxIndexSummaryBlocks {
+		return indexSummary{}, errors.WithDetailf(httpjson.ErrBadRequest, "at most %d blocks may be summarized at once", maxIndexSummaryBlocks)
+	}
+	return a.indexSummary(ctx, in.FromHeight, in.BlockHeight)
+}
+
+func (a *API) indexSummary(ctx context.Context, from, height uint64) (x indexSummary, err error) {
+	x.FromHeight = from
+	x.BlockHeight = height
+
+	if !a.indexTxs {
+		return x, nil
+	}
+	indexed, ok := a.pinStore.PinHeight(query.TxPinName)
+	if !ok || indexed < height {
+		return x, nil
+	}
+	// The digests leave out annotations, like account IDs and
+	// aliases, that come from the Core's own accounts and assets,
+	// and spent_block_height, which changes with later blocks.
+	const q = `
+		SELECT
+			(SELECT COUNT(*) FROM annotated_txs WHERE block_height = h),
+			(SELECT COUNT(*) FROM annotated_outputs WHERE block_height = h),
+			md5(concat_ws('|',
+				(SELECT string_agg(concat_ws(',', tx_pos, encode(tx_hash, 'hex'), reference_data), ';' ORDER BY tx_pos)
+					FROM annotated_txs WHERE block_height = h),
+				(SELECT string_agg(concat_ws(',', t.tx_pos, i.index, i.type, encode(i.asset_id, 'hex'), i.amount,
+						encode(i.spent_output_id, 'hex'), encode(i.issuance_program, 'hex'), i.reference_data), ';' ORDER BY t.tx_pos, i.index)
+					FROM annotated_inputs i JOIN annotated_txs t ON t.tx_hash = i.tx_hash WHERE t.block_height = h),
+				(SELECT string_agg(concat_ws(',', tx_pos, output_index, encode(output_id, 'hex'), type, encode(asset_id, 'hex'), amount,
+						encode(control_program, 'hex'), reference_data), ';' ORDER BY tx_pos, output_index)
+					FROM annotated_outputs WHERE block_height = h)
+			))
+		FROM generate_series($1::bigint, $2::bigint) h
+		ORDER BY h
+	`
+	rows, err := a.queryDB.QueryContext(ctx, q, from, height)
+	if err != nil {
+		return x, errors.Wrap(err, "counting annotated rows")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			txs, outs uint64
+			digest    string
+		)
+		err = rows.Scan(&txs, &outs, &digest)
+		if err != nil {
+			return x, errors.Wrap(err, "scanning annotated row counts")
+		}
+		x.AnnotatedTxs = append(x.AnnotatedTxs, txs)
+		x.AnnotatedOutputs = append(x.AnnotatedOutputs, outs)
+		x.Digests = append(x.Digests, digest)
+	}
+	err = rows.Err()
+	if err != nil {
+		return x, errors.Wrap(err, "counting annotated rows")
+	}
+	x.Indexed = true
+	return x, nil
+}
+
+// runShadow compares this Core's results with the production
+// Core's every period, until ctx is canceled. It must run only
+// on the leader.
+func (a *API) runShadow(ctx context.Context) {
+	setHealth := a.healthSetter("shadow")
+	ticker := time.NewTicker(a.shadow.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.shadow.mu.Lock()
+		last := a.shadow.stats.LastHeight
+		a.shadow.mu.Unlock()
+
+		height, err := a.compareWithProduction(ctx, last)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error(ctx, err)
+		}
+		setHealth(err)
+
+		a.shadow.mu.Lock()
+		if height > 0 {
+			a.shadow.stats.Comparisons++
+			a.shadow.stats.LastHeight = height
+		}
+		if errors.Root(err) == errShadowMismatch {
+			a.shadow.stats.Mismatches++
+		}
+		a.shadow.stats.LastError = ""
+		if err != nil {
+			a.shadow.stats.LastError = err.Error()
+		}
+		a.shadow.mu.Unlock()
+	}
+}
+
+// compareWithProduction compares the summaries of this Core's
+// and the production Core's results for the blocks after height
+// last, up to the last block both have indexed, or at most
+// maxIndexSummaryBlocks of them. It returns the last height
+// compared, or 0 if there was nothing to compare yet.
+func (a *API) compareWithProduction(ctx context.Context, last uint64) (uint64, error) {
+	height, _ := a.pinStore.PinHeight(query.TxPinName)
+	var info struct {
+		BlockHeight uint64 `json:"block_height"`
+	}
+	err := a.shadow.prod.Call(ctx, "/info", nil, &info)
+	if err != nil {
+		return 0, errors.Wrap(err, "getting production core's info")
+	}
+	if info.BlockHeight < height {
+		height = info.BlockHeight
+	}
+	if height <= last {
+		return 0, nil
+	}
+	from := last + 1
+	if last == 0 && height > maxIndexSummaryBlocks {
+		// Start with the latest blocks, rather
+		// than working through the whole chain.
+		from = height - maxIndexSummaryBlocks + 1
+	}
+	if height-from >= maxIndexSummaryBlocks {
+		height = from + maxIndexSummaryBlocks - 1
+	}
+
+	var prod indexSummary
+	req := map[string]uint64{"from_height": from, "block_height": height}
+	err = a.shadow.prod.Call(ctx, "/get-index-summary", req, &prod)
+	if err != nil {
+		return 0, errors.Wrap(err, "getting production core's summary")
+	}
+	local, err := a.indexSummary(ctx, from, height)
+	if err != nil {
+		return 0, err
+	}
+	if !local.Indexed || !prod.Indexed {
+		// Try again once both Cores catch up.
+		return 0, nil
+	}
+
+	var diffs []string
+	diffs = append(diffs, diffCounts("annotated transactions", from, local.AnnotatedTxs, prod.AnnotatedTxs)...)
+	diffs = append(diffs, diffCounts("annotated outputs", from, local.AnnotatedOutputs, prod.AnnotatedOutputs)...)
+	diffs = append(diffs, diffDigests(from, local.Digests, prod.Digests)...)
+	if len(diffs) > 0 {
+		return height, errors.WithDetailf(errShadowMismatch, "in blocks %d to %d: %s", from, height, strings.Join(diffs, "; "))
+	}
+	return height, nil
+}
+
+// diffCounts describes the differences between the per-block
+// counts local and prod of the blocks from height from.
+func diffCounts(what string, from uint64, local, prod []uint64) []string {
+	if len(local) != len(prod) {
+		return []string{fmt.Sprintf("%s of %d blocks, production has %d", what, len(local), len(prod))}
+	}
+	var diffs []string
+	for i := range local {
+		if local[i] != prod[i] {
+			diffs = append(diffs, fmt.Sprintf("%d %s in block %d, production has %d", local[i], what, from+uint64(i), prod[i]))
+		}
+	}
+	return diffs
+}
+
+// diffDigests describes the blocks, from height from,
+// whose digests differ between local and prod.
+func diffDigests(from uint64, local, prod []string) []string {
+	if len(local) != len(prod) {
+		return []string{fmt.Sprintf("digests of %d blocks, production has %d", len(local), len(prod))}
+	}
+	var diffs []string
+	for i := range local {
+		if local[i] != prod[i] {
+			diffs = append(diffs, fmt.Sprintf("annotated results of block %d differ from production", from+uint64(i)))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/core/rpc"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+)
+
+func TestCompareWithProduction(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	c := prottest.NewChain(t)
+	prottest.MakeBlock(t, c, nil)
+
+	pinStore := pin.NewStore(db)
+	err := pinStore.CreatePin(ctx, query.TxPinName, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer := query.NewIndexer(db, c, pinStore)
+	block := &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 2, TimestampMS: bc.Millis(time.Now())},
+		Transactions: []*legacy.Tx{legacy.NewTx(legacy.TxData{ReferenceData: []byte(`{"a": 1}`)})},
+	}
+	err = indexer.IndexTransactions(ctx, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &API{chain: c, pinStore: pinStore, indexer: indexer, queryDB: db, indexTxs: true}
+
+	prod, err := a.indexSummary(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prod.Indexed || len(prod.Digests) != 2 || prod.Digests[0] == prod.Digests[1] {
+		t.Fatalf("indexSummary(1, 2) = %+v, want 2 distinct digests", prod)
+	}
+
+	var gotReq map[string]uint64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/info":
+			json.NewEncoder(w).Encode(map[string]uint64{"block_height": 5})
+		case "/get-index-summary":
+			json.NewDecoder(req.Body).Decode(&gotReq)
+			json.NewEncoder(w).Encode(prod)
+		}
+	}))
+	defer server.Close()
+	Shadow(&rpc.Client{BaseURL: server.URL}, "prod", 0)(a)
+
+	height, err := a.compareWithProduction(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 2 {
+		t.Errorf("compared height %d, want 2", height)
+	}
+	if gotReq["from_height"] != 1 || gotReq["block_height"] != 2 {
+		t.Errorf("requested summary of %v, want blocks 1 to 2", gotReq)
+	}
+
+	// Nothing is compared again until there are new blocks.
+	height, err = a.compareWithProduction(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 0 {
+		t.Errorf("compared height %d after height 2, want 0", height)
+	}
+
+	prod.Digests[1] = prod.Digests[0]
+	_, err = a.compareWithProduction(ctx, 0)
+	if errors.Root(err) != errShadowMismatch {
+		t.Errorf("compareWithProduction() error = %v, want %v", err, errShadowMismatch)
+	}
+
+	// Nothing is compared until production has indexed the blocks.
+	prod = indexSummary{FromHeight: 1, BlockHeight: 2}
+	height, err = a.compareWithProduction(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 0 {
+		t.Errorf("compared height %d with unindexed production, want 0", height)
+	}
+}
+
+func TestCheckShadowOf(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	id, err := databaseID(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := map[string]string{"core_id": "prod"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(info)
+	}))
+	defer server.Close()
+	prod := &rpc.Client{BaseURL: server.URL}
+
+	cases := []struct {
+		databaseID string
+		wantErr    bool
+	}{
+		{"", true},
+		{id, true},
+		{"other", false},
+	}
+	for _, c := range cases {
+		info["database_id"] = c.databaseID
+		got, err := CheckShadowOf(ctx, prod, db)
+		if (err != nil) != c.wantErr {
+			t.Errorf("CheckShadowOf(database %q) error = %v, want error %v", c.databaseID, err, c.wantErr)
+		}
+		if err == nil && got != "prod" {
+			t.Errorf("CheckShadowOf(database %q) = %q, want prod", c.databaseID, got)
+		}
+	}
+}
+
+func TestDiffCounts(t *testing.T) {
+	cases := []struct {
+		local, prod []uint64
+		want        int
+	}{
+		{[]uint64{1, 2}, []uint64{1, 2}, 0},
+		{[]uint64{1, 2}, []uint64{1, 3}, 1},
+		{[]uint64{1, 2}, []uint64{0, 3}, 2},
+		{[]uint64{1, 2}, []uint64{1}, 1},
+	}
+	for _, c := range cases {
+		got := diffCounts("outputs", 5, c.local, c.prod)
+		if len(got) != c.want {
+			t.Errorf("diffCounts(%v, %v) = %q, want %d differences", c.local, c.prod, got, c.want)
+		}
+	}
+}
+
+func TestDiffDigests(t *testing.T) {
+	cases := []struct {
+		local, prod []string
+		want        int
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, 0},
+		{[]string{"a", "b"}, []string{"a", "c"}, 1},
+		{[]string{"a", "b"}, []string{"a"}, 1},
+	}
+	for _, c := range cases {
+		got := diffDigests(5, c.local, c.prod)
+		if len(got) != c.want {
+			t.Errorf("diffDigests(%v, %v) = %q, want %d differences", c.local, c.prod, got, c.want)
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/binary"
+	"encoding/hex"
 	"sort"
+	"strings"
 
 	"github.com/lib/pq"
 
@@ -77,19 +79,9 @@ func Path(s *Signer, ks keySpace, itemIndexes ...uint64) [][]byte {
 
 // Create creates and stores a Signer in the database
 func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quorum int, clientToken string) (*Signer, error) {
-	if len(xpubs) == 0 {
-		return nil, errors.Wrap(ErrNoXPubs)
-	}
-
-	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
-	for i := 1; i < len(xpubs); i++ {
-		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
-			return nil, errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
-		}
-	}
-
-	if quorum == 0 || quorum > len(xpubs) {
-		return nil, errors.Wrap(ErrBadQuorum)
+	err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
 	}
 
 	var xpubBytes [][]byte
@@ -113,7 +105,7 @@ func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quo
 		id       string
 		keyIndex uint64
 	)
-	err := db.QueryRowContext(ctx, q, typeIDMap[typ], typ, pq.ByteaArray(xpubBytes), quorum, nullToken).
+	err = db.QueryRowContext(ctx, q, typeIDMap[typ], typ, pq.ByteaArray(xpubBytes), quorum, nullToken).
 		Scan(&id, &keyIndex)
 	if err == sql.ErrNoRows && clientToken != "" {
 		return findByClientToken(ctx, db, clientToken)
@@ -131,6 +123,167 @@ func Create(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quo
 	}, nil
 }
 
+// Import stores a Signer with the given key index in the
+// database, instead of the next one, so that it derives the
+// same keys as a signer created elsewhere. As with Create, a
+// signer whose client token was already used is looked up
+// and returned.
+func Import(ctx context.Context, db pg.DB, typ string, xpubs []chainkd.XPub, quorum int, keyIndex uint64, clientToken string) (*Signer, error) {
+	err := checkKeys(xpubs, quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	var xpubBytes [][]byte
+	for _, key := range xpubs {
+		key := key
+		xpubBytes = append(xpubBytes, key[:])
+	}
+
+	nullToken := sql.NullString{
+		String: clientToken,
+		Valid:  clientToken != "",
+	}
+
+	const q = `
+		INSERT INTO signers (id, type, xpubs, quorum, key_index, client_token)
+		VALUES (next_chain_id($1::text), $2, $3, $4, $5, $6)
+		ON CONFLICT (client_token) DO NOTHING
+		RETURNING id
+	`
+	var id string
+	err = db.QueryRowContext(ctx, q, typeIDMap[typ], typ, pq.ByteaArray(xpubBytes), quorum, keyIndex, nullToken).Scan(&id)
+	if err == sql.ErrNoRows && clientToken != "" {
+		return findByClientToken(ctx, db, clientToken)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return &Signer{
+		ID:       id,
+		Type:     typ,
+		XPubs:    xpubs,
+		Quorum:   quorum,
+		KeyIndex: keyIndex,
+	}, nil
+}
+
+// CreateParams holds the parameters for one signer
+// in a call to CreateBatch.
+type CreateParams struct {
+	XPubs       []chainkd.XPub
+	Quorum      int
+	ClientToken string
+}
+
+// CreateBatch creates and stores a Signer in the database for each
+// element of params, using a single insert statement for all of them.
+// The returned slices correspond 1:1 with params; for each element
+// exactly one of the signer or the error is non-nil. Signers whose
+// client token was already used are looked up and returned, as in
+// Create.
+func CreateBatch(ctx context.Context, db pg.DB, typ string, params []CreateParams) ([]*Signer, []error) {
+	var (
+		signers = make([]*Signer, len(params))
+		errs    = make([]error, len(params))
+
+		ordinals pq.Int64Array
+		xpubs    pq.StringArray
+		quorums  pq.Int64Array
+		tokens   []sql.NullString
+	)
+	for i, p := range params {
+		err := checkKeys(p.XPubs, p.Quorum)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		hexKeys := make([]string, 0, len(p.XPubs))
+		for _, key := range p.XPubs {
+			hexKeys = append(hexKeys, hex.EncodeToString(key[:]))
+		}
+		ordinals = append(ordinals, int64(i))
+		xpubs = append(xpubs, strings.Join(hexKeys, ","))
+		quorums = append(quorums, int64(p.Quorum))
+		tokens = append(tokens, sql.NullString{String: p.ClientToken, Valid: p.ClientToken != ""})
+	}
+	if len(ordinals) == 0 {
+		return signers, errs
+	}
+
+	// The xpubs of each signer are passed as a comma-separated
+	// list of hex strings, since Postgres arrays of arrays must
+	// be rectangular.
+	const q = `
+		WITH new_signers AS (
+			SELECT t.ord, next_chain_id($1::text) AS id, t.xpubs, t.quorum, t.client_token
+			FROM unnest($3::bigint[], $4::text[], $5::bigint[], $6::text[])
+				AS t(ord, xpubs, quorum, client_token)
+		), inserted AS (
+			INSERT INTO signers (id, type, xpubs, quorum, client_token)
+			SELECT id, $2,
+				ARRAY(SELECT decode(x, 'hex') FROM unnest(string_to_array(xpubs, ',')) AS x),
+				quorum, client_token
+			FROM new_signers
+			ON CONFLICT (client_token) DO NOTHING
+			RETURNING id, key_index
+		)
+		SELECT n.ord, i.id, i.key_index FROM inserted i JOIN new_signers n ON n.id = i.id
+	`
+	err := pg.ForQueryRows(ctx, db, q, typeIDMap[typ], typ, ordinals, xpubs, quorums, pq.Array(tokens),
+		func(ord int, id string, keyIndex uint64) {
+			signers[ord] = &Signer{
+				ID:       id,
+				Type:     typ,
+				XPubs:    params[ord].XPubs,
+				Quorum:   params[ord].Quorum,
+				KeyIndex: keyIndex,
+			}
+		})
+	if err != nil {
+		err = errors.Wrap(err, "batch inserting signers")
+		for _, ord := range ordinals {
+			errs[ord] = err
+		}
+		return signers, errs
+	}
+
+	// Any valid signer that wasn't inserted must have
+	// collided with an existing client token.
+	for _, ord := range ordinals {
+		if signers[ord] != nil {
+			continue
+		}
+		if params[ord].ClientToken == "" {
+			errs[ord] = errors.Wrap(sql.ErrNoRows, "signer not inserted")
+			continue
+		}
+		signers[ord], errs[ord] = findByClientToken(ctx, db, params[ord].ClientToken)
+	}
+	return signers, errs
+}
+
+// checkKeys validates the keys and quorum for a new signer.
+// It sorts xpubs in place.
+func checkKeys(xpubs []chainkd.XPub, quorum int) error {
+	if len(xpubs) == 0 {
+		return errors.Wrap(ErrNoXPubs)
+	}
+
+	sort.Sort(sortKeys(xpubs)) // this transforms the input slice
+	for i := 1; i < len(xpubs); i++ {
+		if bytes.Equal(xpubs[i][:], xpubs[i-1][:]) {
+			return errors.WithDetailf(ErrDupeXPub, "duplicated key=%x", xpubs[i])
+		}
+	}
+
+	if quorum == 0 || quorum > len(xpubs) {
+		return errors.Wrap(ErrBadQuorum)
+	}
+	return nil
+}
+
 func New(id, typ string, xpubs [][]byte, quorum int, keyIndex uint64) (*Signer, error) {
 	keys, err := ConvertKeys(xpubs)
 	if err != nil {
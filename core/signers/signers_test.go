@@ -1,6 +1,7 @@
 package signers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
@@ -153,6 +154,117 @@ func TestCreateIdempotency(t *testing.T) {
 	}
 }
 
+func TestCreateBatch(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+
+	existing, err := Create(ctx, db, "account", []chainkd.XPub{testutil.TestXPub}, 1, "existing")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		params  CreateParams
+		wantErr error
+		wantID  string // if set, the signer must have this ID
+	}{{
+		params: CreateParams{XPubs: []chainkd.XPub{testutil.TestXPub}, Quorum: 1},
+	}, {
+		params:  CreateParams{XPubs: []chainkd.XPub{}, Quorum: 1},
+		wantErr: ErrNoXPubs,
+	}, {
+		params:  CreateParams{XPubs: []chainkd.XPub{testutil.TestXPub, testutil.TestXPub}, Quorum: 1},
+		wantErr: ErrDupeXPub,
+	}, {
+		params:  CreateParams{XPubs: []chainkd.XPub{testutil.TestXPub}, Quorum: 2},
+		wantErr: ErrBadQuorum,
+	}, {
+		params: CreateParams{XPubs: []chainkd.XPub{dummyXPub, testutil.TestXPub}, Quorum: 2, ClientToken: "new"},
+	}, {
+		params: CreateParams{XPubs: []chainkd.XPub{testutil.TestXPub}, Quorum: 1, ClientToken: "existing"},
+		wantID: existing.ID,
+	}}
+
+	params := make([]CreateParams, 0, len(cases))
+	for _, c := range cases {
+		params = append(params, c.params)
+	}
+	got, errs := CreateBatch(ctx, db, "account", params)
+	if len(got) != len(cases) || len(errs) != len(cases) {
+		t.Fatalf("CreateBatch returned %d signers and %d errors, want %d of each", len(got), len(errs), len(cases))
+	}
+
+	ids := make(map[string]bool)
+	for i, c := range cases {
+		if errors.Root(errs[i]) != c.wantErr {
+			t.Errorf("case %d: err = %v want %v", i, errs[i], c.wantErr)
+			continue
+		}
+		if c.wantErr != nil {
+			if got[i] != nil {
+				t.Errorf("case %d: got signer %s along with error", i, got[i].ID)
+			}
+			continue
+		}
+		if c.wantID != "" && got[i].ID != c.wantID {
+			t.Errorf("case %d: ID = %s want %s", i, got[i].ID, c.wantID)
+		}
+		if c.wantID == "" && (ids[got[i].ID] || got[i].ID == existing.ID) {
+			t.Errorf("case %d: ID %s reused", i, got[i].ID)
+		}
+		ids[got[i].ID] = true
+
+		found, err := Find(ctx, db, "account", got[i].ID)
+		if err != nil {
+			t.Errorf("case %d: cannot Find new signer %s: %v", i, got[i].ID, err)
+			continue
+		}
+		if !testutil.DeepEqual(found, got[i]) {
+			t.Errorf("case %d: Find = %+v want %+v", i, found, got[i])
+		}
+	}
+
+	// A batch with no valid signers doesn't touch the database.
+	got, errs = CreateBatch(ctx, db, "account", []CreateParams{{Quorum: 1}})
+	if got[0] != nil || errors.Root(errs[0]) != ErrNoXPubs {
+		t.Errorf("CreateBatch(no xpubs) = %v, %v want nil, %v", got[0], errs[0], ErrNoXPubs)
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	cases := []struct {
+		xpubs  []chainkd.XPub
+		quorum int
+		want   error
+	}{
+		{nil, 1, ErrNoXPubs},
+		{[]chainkd.XPub{testutil.TestXPub}, 0, ErrBadQuorum},
+		{[]chainkd.XPub{testutil.TestXPub}, 1, nil},
+		{[]chainkd.XPub{testutil.TestXPub}, 2, ErrBadQuorum},
+		{[]chainkd.XPub{testutil.TestXPub, testutil.TestXPub}, 1, ErrDupeXPub},
+		{[]chainkd.XPub{testutil.TestXPub, dummyXPub, testutil.TestXPub}, 1, ErrDupeXPub},
+		{[]chainkd.XPub{testutil.TestXPub, dummyXPub}, 2, nil},
+		{[]chainkd.XPub{testutil.TestXPub, dummyXPub}, 3, ErrBadQuorum},
+	}
+	for i, c := range cases {
+		got := checkKeys(c.xpubs, c.quorum)
+		if errors.Root(got) != c.want {
+			t.Errorf("case %d: checkKeys(%d xpubs, %d) = %v want %v", i, len(c.xpubs), c.quorum, got, c.want)
+		}
+	}
+
+	// checkKeys sorts its input, so signers with the
+	// same keys in any order get the same key set.
+	xpubs := []chainkd.XPub{testutil.TestXPub, dummyXPub}
+	err := checkKeys(xpubs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(xpubs[0][:], xpubs[1][:]) >= 0 {
+		t.Errorf("checkKeys left xpubs unsorted: %x, %x", xpubs[0][:], xpubs[1][:])
+	}
+}
+
 func TestFind(t *testing.T) {
 	ctx := context.Background()
 	db := pgtest.NewTx(t)
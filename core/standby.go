@@ -0,0 +1,245 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"chain/core/config"
+	"chain/core/leader"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+	"chain/protocol/vm/vmutil"
+)
+
+// fenceOption is the config option that fences a Core. Its
+// value is the time the Core was fenced and the reason.
+// A fenced Core neither generates blocks nor accepts
+// transactions, across restarts, until the option is removed.
+const fenceOption = "fence"
+
+var (
+	errFenced            = errors.New("core is fenced")
+	errAlreadyGenerator  = errors.New("core is already the generator")
+	errConsensusMismatch = errors.New("block signers don't match the consensus program")
+	errPendingBlock      = errors.New("invalid pending block")
+)
+
+// cleanFenceTuple checks that tup is a fenced-at
+// time in RFC 3339 format followed by a reason.
+func cleanFenceTuple(tup []string) error {
+	t, err := time.Parse(time.RFC3339, tup[0])
+	if err != nil {
+		return errors.WithDetail(err, "fenced-at time must be in RFC 3339 format")
+	}
+	tup[0] = t.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// fenced reports whether this Core is fenced.
+func (a *API) fenced() bool {
+	return a.fence != nil && len(a.fence()) > 0
+}
+
+func (a *API) checkFence() error {
+	if !a.fenced() {
+		return nil
+	}
+	tup := a.fence()
+	return errors.WithDetailf(errFenced, "fenced at %s: %s", tup[0], tup[1])
+}
+
+type blockIDResp struct {
+	Height  uint64  `json:"height"`
+	BlockID bc.Hash `json:"block_id"`
+}
+
+// getBlockIDRPC returns the ID of the block at the requested
+// height. Unlike get-block, it doesn't wait for the block: it
+// fails with protocol.ErrTheDistantFuture if this Core doesn't
+// have the block yet.
+func (a *API) getBlockIDRPC(ctx context.Context, height uint64) (*blockIDResp, error) {
+	if height == 0 || height > a.chain.Height() {
+		return nil, errors.WithDetailf(protocol.ErrTheDistantFuture, "current height is %d", a.chain.Height())
+	}
+	b, err := a.chain.GetBlock(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return &blockIDResp{Height: height, BlockID: b.Hash()}, nil
+}
+
+// POST /standby/fence
+//
+// fence stops this Core from generating blocks and accepting
+// transactions, so that a standby Core can safely take over as
+// generator. The fence is stored in the Core's configuration, so
+// it holds across restarts. If this Core is the generator, fence
+// waits for the block being made, if any, and stops generating.
+// It responds with the last block this Core committed, which the
+// standby must have before it's promoted, and the block it was
+// getting signed at the next height, if any. Signers may already
+// have signed that block, and refuse to sign a different one at
+// its height, so the standby must commit it first.
+func (a *API) fenceCore(ctx context.Context, req struct {
+	Reason string `json:"reason"`
+}) (*fenceResp, error) {
+	if a.leader.State() == leader.Following {
+		resp := new(fenceResp)
+		err := a.forwardToLeader(ctx, "/standby/fence", req, resp)
+		return resp, err
+	}
+	if !a.fenced() {
+		tup := []string{time.Now().UTC().Format(time.RFC3339), req.Reason}
+		err := a.sdb.Exec(ctx, a.options.Set(fenceOption, tup))
+		if err != nil {
+			return nil, err
+		}
+		log.Printkv(ctx, "at", "fenced", "reason", req.Reason)
+	}
+	if a.config.IsGenerator && a.generator != nil {
+		a.generator.Stop()
+		a.setHealth("generator", a.checkFence())
+	}
+
+	resp := new(fenceResp)
+	height := a.chain.Height()
+	if height > 0 {
+		last, err := a.getBlockIDRPC(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		resp.blockIDResp = *last
+	}
+	if a.config.IsGenerator && a.generator != nil {
+		b, err := a.generator.PendingStore.GetPendingBlock(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "retrieving the pending block")
+		}
+		if b != nil && b.Height == height+1 {
+			resp.PendingBlock = b
+		}
+	}
+	return resp, nil
+}
+
+type fenceResp struct {
+	blockIDResp
+	PendingBlock *legacy.Block `json:"pending_block,omitempty"`
+}
+
+type promoteRequest struct {
+	Signers             []*config.BlockSigner `json:"block_signers"`
+	Quorum              uint32                `json:"quorum"`
+	MaxIssuanceWindowMS uint64                `json:"max_issuance_window_ms"`
+	PendingBlock        *legacy.Block         `json:"pending_block"`
+}
+
+// POST /standby/promote
+//
+// promote reconfigures this Core, which must be replicating
+// blocks from a generator, to generate blocks itself, then
+// restarts it. The block signers and quorum must produce the
+// consensus program of the latest block, so the blockchain
+// continues unchanged.
+//
+// The caller must fence the old generator first, and pass the
+// pending block the fence returned, if any. This Core commits
+// that block before generating any of its own. Other cored
+// processes of this Core pick up the new configuration when
+// they restart.
+func (a *API) promote(ctx context.Context, req promoteRequest) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/standby/promote", req, nil)
+	}
+	if a.config.IsGenerator {
+		return errors.Wrap(errAlreadyGenerator)
+	}
+	err := a.checkFence()
+	if err != nil {
+		return err
+	}
+	err = a.checkPromotionSigners(ctx, req)
+	if err != nil {
+		return err
+	}
+	if req.PendingBlock != nil {
+		err = a.adoptPendingBlock(ctx, req.PendingBlock)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = config.Promote(ctx, a.sdb, req.Signers, req.Quorum, req.MaxIssuanceWindowMS)
+	if err != nil {
+		return err
+	}
+	log.Printkv(ctx, "at", "promoted", "height", a.chain.Height())
+
+	closeConnOK(httpjson.ResponseWriter(ctx), httpjson.Request(ctx))
+	execSelf("")
+	panic("unreached")
+}
+
+// checkPromotionSigners checks that the block signers in req,
+// along with this Core's own block key if it's a signer, match
+// the consensus program of the latest block.
+func (a *API) checkPromotionSigners(ctx context.Context, req promoteRequest) error {
+	var pubkeys []ed25519.PublicKey
+	if a.config.IsSigner {
+		pubkeys = append(pubkeys, ed25519.PublicKey(a.config.BlockPub))
+	}
+	for _, s := range req.Signers {
+		if len(s.Pubkey) != ed25519.PublicKeySize {
+			return errors.Wrap(config.ErrBadSignerPubkey)
+		}
+		pubkeys = append(pubkeys, ed25519.PublicKey(s.Pubkey))
+	}
+	if req.Quorum == 0 && len(pubkeys) > 0 {
+		return errors.Wrap(config.ErrBadQuorum)
+	}
+	prog, err := vmutil.BlockMultiSigProgram(pubkeys, int(req.Quorum))
+	if err != nil {
+		return errors.Sub(errConsensusMismatch, err)
+	}
+
+	height := a.chain.Height()
+	if height == 0 {
+		return errors.WithDetail(errConsensusMismatch, "no blocks to check against")
+	}
+	b, err := a.chain.GetBlock(ctx, height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(prog, b.ConsensusProgram) {
+		return errors.WithDetailf(errConsensusMismatch, "block %d has a different consensus program", height)
+	}
+	return nil
+}
+
+// adoptPendingBlock checks that b, the block the fenced
+// generator was getting signed, follows this Core's latest
+// block, and saves it as the pending block, so that this
+// Core's generator asks the signers to sign it again
+// and commits it.
+func (a *API) adoptPendingBlock(ctx context.Context, b *legacy.Block) error {
+	prev, snapshot := a.chain.State()
+	if prev == nil || b.Height != prev.Height+1 {
+		return errors.WithDetailf(errPendingBlock, "pending block height %d, current height %d", b.Height, a.chain.Height())
+	}
+	err := a.chain.ValidateBlockForSig(ctx, b)
+	if err != nil {
+		return errors.Sub(errPendingBlock, err)
+	}
+	err = state.Copy(snapshot).ApplyBlock(legacy.MapBlock(b))
+	if err != nil {
+		return errors.Sub(errPendingBlock, err)
+	}
+	return errors.Wrap(a.pendingStore.SavePendingBlock(ctx, b), "saving pending block")
+}
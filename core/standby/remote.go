@@ -0,0 +1,61 @@
+package standby
+
+import (
+	"context"
+
+	"chain/core/rpc"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// RemoteNode is a Node that manages a Core through its API.
+// Its client's access token needs the internal and crosscore
+// policies.
+type RemoteNode struct {
+	Client *rpc.Client
+}
+
+func (n *RemoteNode) Status(ctx context.Context) (*NodeStatus, error) {
+	var info struct {
+		IsConfigured bool     `json:"is_configured"`
+		BlockchainID *bc.Hash `json:"blockchain_id"`
+		Height       uint64   `json:"block_height"`
+		IsGenerator  bool     `json:"is_generator"`
+		Fenced       bool     `json:"fenced"`
+	}
+	err := n.Client.Call(ctx, "/info", nil, &info)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsConfigured || info.BlockchainID == nil {
+		return nil, errors.New("core is not configured")
+	}
+	return &NodeStatus{
+		BlockchainID: *info.BlockchainID,
+		Height:       info.Height,
+		IsGenerator:  info.IsGenerator,
+		Fenced:       info.Fenced,
+	}, nil
+}
+
+func (n *RemoteNode) BlockID(ctx context.Context, height uint64) (bc.Hash, error) {
+	var resp struct {
+		BlockID bc.Hash `json:"block_id"`
+	}
+	err := n.Client.Call(ctx, "/rpc/get-block-id", height, &resp)
+	return resp.BlockID, err
+}
+
+func (n *RemoteNode) Fence(ctx context.Context, reason string) (*Fence, error) {
+	req := map[string]string{"reason": reason}
+	f := new(Fence)
+	err := n.Client.Call(ctx, "/standby/fence", req, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (n *RemoteNode) Promote(ctx context.Context, p *Promotion) error {
+	return n.Client.Call(ctx, "/standby/promote", p, nil)
+}
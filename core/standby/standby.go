@@ -0,0 +1,422 @@
+// Package standby coordinates a primary Chain Core that generates
+// blocks with a standby Core, usually in another region, that
+// replicates them. A Coordinator continually checks that the
+// standby's blocks match the primary's, and promotes the standby
+// to generator if the primary fails, after fencing the primary
+// so that the two never generate blocks at the same time.
+package standby
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"chain/core/config"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	// ErrNotFenced is returned by Promote when
+	// the primary can't be fenced.
+	ErrNotFenced = errors.New("primary could not be fenced")
+
+	// ErrDiverged is returned by Promote when the standby's
+	// blocks don't match the primary's.
+	ErrDiverged = errors.New("standby has diverged from primary")
+
+	// ErrBehind is returned by Promote when the standby
+	// doesn't reach the fenced primary's last block in time.
+	ErrBehind = errors.New("standby is behind primary")
+
+	// ErrPromoted is returned by Promote when
+	// the standby has already been promoted.
+	ErrPromoted = errors.New("standby already promoted")
+)
+
+// catchUpPoll is how often Promote checks
+// whether the standby has caught up.
+var catchUpPoll = 250 * time.Millisecond
+
+// A Fence is the last block a fenced primary committed.
+type Fence struct {
+	Height  uint64  `json:"height"`
+	BlockID bc.Hash `json:"block_id"`
+
+	// PendingBlock is the block the primary was getting signed
+	// at the next height, if any. Signers may have signed it
+	// already, and won't sign a different block at its height,
+	// so the standby must commit it when promoted.
+	PendingBlock *legacy.Block `json:"pending_block,omitempty"`
+}
+
+// NodeStatus describes the state of a Core.
+type NodeStatus struct {
+	BlockchainID bc.Hash
+	Height       uint64
+	IsGenerator  bool
+	Fenced       bool
+}
+
+// Node is a Core that a Coordinator manages.
+type Node interface {
+	// Status returns the Core's current status.
+	Status(ctx context.Context) (*NodeStatus, error)
+
+	// BlockID returns the ID of the block at the given height.
+	// It fails if the Core doesn't have that block yet.
+	BlockID(ctx context.Context, height uint64) (bc.Hash, error)
+
+	// Fence stops the Core from generating blocks
+	// and accepting transactions, and returns the last
+	// block it committed and the block it was getting
+	// signed, if any. The Core commits no blocks
+	// after the last one.
+	Fence(ctx context.Context, reason string) (*Fence, error)
+
+	// Promote makes the Core, which must be replicating
+	// blocks, generate blocks itself.
+	Promote(ctx context.Context, p *Promotion) error
+}
+
+// Promotion holds the block signer configuration a standby
+// needs to take over as generator. It must produce the same
+// consensus program as the primary's configuration.
+type Promotion struct {
+	Signers             []*config.BlockSigner `json:"block_signers"`
+	Quorum              uint32                `json:"quorum"`
+	MaxIssuanceWindowMS uint64                `json:"max_issuance_window_ms"`
+
+	// PendingBlock is the fenced primary's pending block,
+	// which the standby commits before generating any of its
+	// own. Coordinator.Promote sets it from the Fence.
+	PendingBlock *legacy.Block `json:"pending_block,omitempty"`
+}
+
+// Status is the result of a Coordinator's latest check.
+type Status struct {
+	CheckedAt time.Time `json:"checked_at"`
+
+	// PrimaryHeight is the primary's height as of the last
+	// successful check of the primary, which may be older
+	// than CheckedAt.
+	PrimaryHeight   uint64 `json:"primary_height"`
+	StandbyHeight   uint64 `json:"standby_height"`
+	Lag             uint64 `json:"lag"`
+	PrimaryFailures int    `json:"primary_failures"`
+
+	// VerifiedHeight is the greatest height at which the
+	// primary and standby have been found to have the same
+	// block.
+	VerifiedHeight uint64 `json:"verified_height"`
+	Diverged       bool   `json:"diverged"`
+
+	Promoted   bool       `json:"promoted"`
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+
+	// Problems describes anything wrong found by the check.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Healthy reports whether the check found no problems.
+func (s Status) Healthy() bool {
+	return len(s.Problems) == 0
+}
+
+// Coordinator manages a primary Core and its standby.
+type Coordinator struct {
+	Primary, Standby Node
+
+	// Promotion configures the standby when it's promoted.
+	Promotion Promotion
+
+	// Interval is the time between checks. The default is 5s.
+	Interval time.Duration
+
+	// FailureThreshold is the number of consecutive checks in
+	// which the primary is unreachable that trigger promotion of
+	// the standby. If it is 0, the standby is promoted only by
+	// calling Promote.
+	FailureThreshold int
+
+	// MaxLag is the most blocks the standby can be behind the
+	// primary's last known height when the primary is down, and
+	// still be promoted automatically.
+	MaxLag uint64
+
+	// CatchUpTimeout bounds how long Promote waits, after
+	// fencing, for the standby to reach the primary's last
+	// block. The default is 1m.
+	CatchUpTimeout time.Duration
+
+	// Fencer, if set, fences the primary by other means,
+	// such as cutting it off from the network, when fencing
+	// it through its API fails. It returns the last block
+	// the primary committed, as Node.Fence does.
+	Fencer func(ctx context.Context) (*Fence, error)
+
+	// OnPromote, if set, is called after the standby is
+	// promoted, for example to point a DNS name at it.
+	OnPromote func(ctx context.Context, st Status) error
+
+	// OnAlert, if set, is called with the result of
+	// each check that finds a problem.
+	OnAlert func(ctx context.Context, st Status)
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status returns the result of the latest check.
+func (c *Coordinator) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Run checks the primary and standby every Interval, promoting
+// the standby if the primary fails, until ctx is canceled or the
+// standby is promoted.
+func (c *Coordinator) Run(ctx context.Context) {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		st := c.Check(ctx)
+		if st.Promoted {
+			return
+		}
+		if c.shouldPromote(st) {
+			reason := fmt.Sprintf("primary unreachable for %d checks", st.PrimaryFailures)
+			err := c.Promote(ctx, reason)
+			if err != nil {
+				log.Error(ctx, err, "automatic promotion")
+			} else {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// shouldPromote reports whether st calls for
+// automatic promotion of the standby.
+func (c *Coordinator) shouldPromote(st Status) bool {
+	return c.FailureThreshold > 0 &&
+		st.PrimaryFailures >= c.FailureThreshold &&
+		!st.Diverged &&
+		st.VerifiedHeight > 0 &&
+		st.Lag <= c.MaxLag
+}
+
+// Check checks the primary and standby once, verifying that
+// the standby has the same block as the primary at the greatest
+// height they both have, and returns the result.
+func (c *Coordinator) Check(ctx context.Context) Status {
+	c.mu.Lock()
+	st := c.status
+	c.mu.Unlock()
+	if st.Promoted {
+		return st
+	}
+	st.CheckedAt = time.Now()
+	st.Problems = nil
+
+	ps, err := c.Primary.Status(ctx)
+	if err != nil {
+		st.PrimaryFailures++
+		st.Problems = append(st.Problems, fmt.Sprintf("primary: %s", err))
+	} else {
+		st.PrimaryFailures = 0
+		st.PrimaryHeight = ps.Height
+		if !ps.IsGenerator {
+			st.Problems = append(st.Problems, "primary is not the generator")
+		}
+		if ps.Fenced {
+			st.Problems = append(st.Problems, "primary is fenced")
+		}
+	}
+
+	ss, err := c.Standby.Status(ctx)
+	if err != nil {
+		st.Problems = append(st.Problems, fmt.Sprintf("standby: %s", err))
+	} else {
+		st.StandbyHeight = ss.Height
+		if ss.IsGenerator {
+			st.Problems = append(st.Problems, "standby is a generator")
+		}
+	}
+
+	st.Lag = 0
+	if st.PrimaryHeight > st.StandbyHeight {
+		st.Lag = st.PrimaryHeight - st.StandbyHeight
+	}
+	if c.MaxLag > 0 && st.Lag > c.MaxLag {
+		st.Problems = append(st.Problems, fmt.Sprintf("standby is %d blocks behind", st.Lag))
+	}
+
+	if ps != nil && ss != nil && ps.BlockchainID != ss.BlockchainID {
+		st.Diverged = true
+	} else if ps != nil && ss != nil {
+		height := ps.Height
+		if ss.Height < height {
+			height = ss.Height
+		}
+		err = c.verify(ctx, height)
+		if errors.Root(err) == ErrDiverged {
+			st.Diverged = true
+		} else if err != nil {
+			st.Problems = append(st.Problems, err.Error())
+		} else if height > 0 {
+			st.VerifiedHeight = height
+		}
+	}
+	if st.Diverged {
+		st.Problems = append(st.Problems, ErrDiverged.Error())
+	}
+
+	c.mu.Lock()
+	if !c.status.Promoted {
+		c.status = st
+	}
+	c.mu.Unlock()
+	if !st.Healthy() && c.OnAlert != nil {
+		c.OnAlert(ctx, st)
+	}
+	return st
+}
+
+// verify checks that the primary and standby
+// have the same block at the given height.
+func (c *Coordinator) verify(ctx context.Context, height uint64) error {
+	if height == 0 {
+		return nil
+	}
+	want, err := c.Primary.BlockID(ctx, height)
+	if err != nil {
+		return errors.Wrapf(err, "getting primary block %d", height)
+	}
+	got, err := c.Standby.BlockID(ctx, height)
+	if err != nil {
+		return errors.Wrapf(err, "getting standby block %d", height)
+	}
+	if got != want {
+		return errors.WithDetailf(ErrDiverged, "block %d is %x on primary, %x on standby", height, want.Bytes(), got.Bytes())
+	}
+	return nil
+}
+
+// Promote fences the primary and promotes the standby to
+// generator, then calls OnPromote. It doesn't promote the
+// standby unless fencing succeeds, either through the
+// primary's API or through Fencer, and the standby has
+// the last block the primary committed. Promote waits
+// up to CatchUpTimeout for the standby to get that block.
+// The standby takes over the block the primary was getting
+// signed, if the fence returned one.
+func (c *Coordinator) Promote(ctx context.Context, reason string) error {
+	c.mu.Lock()
+	st := c.status
+	c.mu.Unlock()
+	if st.Promoted {
+		return errors.Wrap(ErrPromoted)
+	}
+	if st.Diverged {
+		return errors.Wrap(ErrDiverged)
+	}
+
+	f, err := c.Primary.Fence(ctx, reason)
+	if err != nil && c.Fencer != nil {
+		log.Error(ctx, err, "fencing primary through its API")
+		f, err = c.Fencer(ctx)
+	}
+	if err != nil {
+		return errors.Sub(ErrNotFenced, err)
+	}
+	log.Printkv(ctx, "at", "primary fenced", "reason", reason, "height", f.Height)
+
+	err = c.catchUp(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	p := c.Promotion
+	if f.PendingBlock != nil {
+		b := f.PendingBlock
+		if b.Height != f.Height+1 || b.PreviousBlockHash != f.BlockID {
+			return errors.WithDetailf(ErrDiverged, "pending block %d doesn't follow block %d, %x", b.Height, f.Height, f.BlockID.Bytes())
+		}
+		p.PendingBlock = b
+	}
+	err = c.Standby.Promote(ctx, &p)
+	if err != nil {
+		return errors.Wrap(err, "promoting standby")
+	}
+	now := time.Now()
+	c.mu.Lock()
+	c.status.Promoted = true
+	c.status.PromotedAt = &now
+	st = c.status
+	c.mu.Unlock()
+	log.Printkv(ctx, "at", "standby promoted")
+
+	if c.OnPromote != nil {
+		err = c.OnPromote(ctx, st)
+		if err != nil {
+			return errors.Wrap(err, "promote hook")
+		}
+	}
+	return nil
+}
+
+// catchUp waits for the standby to reach the fenced
+// primary's last block, then checks that the standby's
+// latest block is that block.
+func (c *Coordinator) catchUp(ctx context.Context, f *Fence) error {
+	timeout := c.CatchUpTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ss, err := c.Standby.Status(ctx)
+		if err == nil && ss.Height > f.Height {
+			return errors.WithDetailf(ErrDiverged, "standby height %d, fenced primary height %d", ss.Height, f.Height)
+		}
+		if err == nil && ss.Height == f.Height {
+			if f.Height == 0 {
+				return nil
+			}
+			id, err := c.Standby.BlockID(ctx, f.Height)
+			if err != nil {
+				return errors.Wrapf(err, "getting standby block %d", f.Height)
+			}
+			if id != f.BlockID {
+				return errors.WithDetailf(ErrDiverged, "block %d is %x on primary, %x on standby", f.Height, f.BlockID.Bytes(), id.Bytes())
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return errors.Wrap(err, "getting standby status")
+			}
+			return errors.WithDetailf(ErrBehind, "standby height %d, fenced primary height %d", ss.Height, f.Height)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(catchUpPoll):
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package standby
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+type fakeNode struct {
+	mu        sync.Mutex
+	down      bool
+	fenceErr  error
+	blocks    []bc.Hash // block i+1 has ID blocks[i]
+	generator bool
+	fenced    bool
+	pending   *legacy.Block
+	promoted  *Promotion
+}
+
+var errDown = errors.New("node is down")
+
+func (n *fakeNode) Status(ctx context.Context) (*NodeStatus, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return nil, errDown
+	}
+	return &NodeStatus{
+		BlockchainID: n.blocks[0],
+		Height:       uint64(len(n.blocks)),
+		IsGenerator:  n.generator,
+		Fenced:       n.fenced,
+	}, nil
+}
+
+func (n *fakeNode) BlockID(ctx context.Context, height uint64) (bc.Hash, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return bc.Hash{}, errDown
+	}
+	if height == 0 || height > uint64(len(n.blocks)) {
+		return bc.Hash{}, errors.New("no such block")
+	}
+	return n.blocks[height-1], nil
+}
+
+func (n *fakeNode) Fence(ctx context.Context, reason string) (*Fence, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return nil, errDown
+	}
+	if n.fenceErr != nil {
+		return nil, n.fenceErr
+	}
+	n.fenced = true
+	f := n.lastBlock()
+	f.PendingBlock = n.pending
+	return f, nil
+}
+
+func (n *fakeNode) lastBlock() *Fence {
+	return &Fence{Height: uint64(len(n.blocks)), BlockID: n.blocks[len(n.blocks)-1]}
+}
+
+func (n *fakeNode) Promote(ctx context.Context, p *Promotion) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.down {
+		return errDown
+	}
+	n.generator = true
+	n.promoted = p
+	return nil
+}
+
+func (n *fakeNode) setDown(down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.down = down
+}
+
+func (n *fakeNode) setBlocks(blocks []bc.Hash) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocks = blocks
+}
+
+func chain(ids ...uint64) []bc.Hash {
+	var blocks []bc.Hash
+	for _, id := range ids {
+		blocks = append(blocks, bc.Hash{V0: id})
+	}
+	return blocks
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	primary := &fakeNode{blocks: chain(1, 2, 3, 4), generator: true}
+	standby := &fakeNode{blocks: chain(1, 2, 3)}
+	var alerts int
+	c := &Coordinator{
+		Primary: primary,
+		Standby: standby,
+		MaxLag:  5,
+		OnAlert: func(context.Context, Status) { alerts++ },
+	}
+
+	st := c.Check(ctx)
+	if !st.Healthy() || st.VerifiedHeight != 3 || st.Lag != 1 || st.Diverged {
+		t.Errorf("Check() = %+v, want healthy, verified at 3, lag 1", st)
+	}
+
+	// The standby's block 3 differs from the primary's.
+	standby.blocks = chain(1, 2, 5)
+	st = c.Check(ctx)
+	if st.Healthy() || !st.Diverged || st.VerifiedHeight != 3 {
+		t.Errorf("Check() = %+v, want diverged with no new verified height", st)
+	}
+	if alerts != 1 {
+		t.Errorf("got %d alerts, want 1", alerts)
+	}
+
+	// Divergence stays reported until an operator intervenes.
+	standby.blocks = chain(1, 2, 3, 4)
+	st = c.Check(ctx)
+	if !st.Diverged {
+		t.Errorf("Check() = %+v, want diverged", st)
+	}
+}
+
+func TestAutomaticPromotion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	primary := &fakeNode{blocks: chain(1, 2, 3), generator: true}
+	standby := &fakeNode{blocks: chain(1, 2, 3)}
+	var fencedExternally, hooked bool
+	c := &Coordinator{
+		Primary:          primary,
+		Standby:          standby,
+		Promotion:        Promotion{Quorum: 1},
+		Interval:         time.Millisecond,
+		FailureThreshold: 3,
+		Fencer: func(context.Context) (*Fence, error) {
+			fencedExternally = true
+			return primary.lastBlock(), nil
+		},
+		OnPromote: func(ctx context.Context, st Status) error {
+			hooked = st.Promoted
+			return nil
+		},
+	}
+	c.Check(ctx)
+	primary.setDown(true)
+	c.Run(ctx)
+
+	st := c.Status()
+	if !st.Promoted || st.PrimaryFailures != 3 {
+		t.Errorf("Status() = %+v, want promoted after 3 failures", st)
+	}
+	if !fencedExternally {
+		t.Error("primary wasn't fenced by Fencer")
+	}
+	if standby.promoted == nil || standby.promoted.Quorum != 1 {
+		t.Errorf("standby promoted with %+v, want quorum 1", standby.promoted)
+	}
+	if !hooked {
+		t.Error("OnPromote wasn't called after promotion")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	ctx := context.Background()
+	catchUpPoll = time.Millisecond
+
+	cases := []struct {
+		primary *fakeNode
+		standby *fakeNode
+		fencer  func(context.Context) (*Fence, error)
+		maxLag  uint64
+		wantErr error
+	}{{
+		// The primary fences itself and the standby is caught up.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true},
+		standby: &fakeNode{blocks: chain(1, 2)},
+	}, {
+		// The primary can't be fenced.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true, fenceErr: errDown},
+		standby: &fakeNode{blocks: chain(1, 2)},
+		wantErr: ErrNotFenced,
+	}, {
+		// Fencing falls back to the Fencer, which fails.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true, fenceErr: errDown},
+		standby: &fakeNode{blocks: chain(1, 2)},
+		fencer:  func(context.Context) (*Fence, error) { return nil, errDown },
+		wantErr: ErrNotFenced,
+	}, {
+		// The Fencer reports a last block the standby doesn't have.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true, fenceErr: errDown},
+		standby: &fakeNode{blocks: chain(1, 2)},
+		fencer: func(context.Context) (*Fence, error) {
+			return &Fence{Height: 3, BlockID: bc.Hash{V0: 3}}, nil
+		},
+		wantErr: ErrBehind,
+	}, {
+		// The Fencer reports a last block that differs from the standby's.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true, fenceErr: errDown},
+		standby: &fakeNode{blocks: chain(1, 2)},
+		fencer: func(context.Context) (*Fence, error) {
+			return &Fence{Height: 2, BlockID: bc.Hash{V0: 5}}, nil
+		},
+		wantErr: ErrDiverged,
+	}, {
+		// The standby has blocks beyond the primary's last block.
+		primary: &fakeNode{blocks: chain(1, 2), generator: true},
+		standby: &fakeNode{blocks: chain(1, 2, 3)},
+		wantErr: ErrDiverged,
+	}, {
+		// The primary is reachable, and the standby doesn't catch up.
+		primary: &fakeNode{blocks: chain(1, 2, 3), generator: true},
+		standby: &fakeNode{blocks: chain(1, 2)},
+		maxLag:  5,
+		wantErr: ErrBehind,
+	}, {
+		// The primary's last block differs from the standby's.
+		primary: &fakeNode{blocks: chain(1, 2, 3), generator: true},
+		standby: &fakeNode{blocks: chain(1, 2, 4)},
+		wantErr: ErrDiverged,
+	}}
+	for i, c := range cases {
+		coord := &Coordinator{
+			Primary:        c.primary,
+			Standby:        c.standby,
+			Fencer:         c.fencer,
+			MaxLag:         c.maxLag,
+			CatchUpTimeout: 10 * time.Millisecond,
+		}
+		err := coord.Promote(ctx, "test")
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: Promote() = %v, want %v", i, err, c.wantErr)
+		}
+		promoted := c.standby.promoted != nil
+		if promoted != (c.wantErr == nil) {
+			t.Errorf("case %d: standby promoted = %v, want %v", i, promoted, c.wantErr == nil)
+		}
+		if promoted && !c.primary.fenced && c.fencer == nil {
+			t.Errorf("case %d: standby promoted without fencing primary", i)
+		}
+	}
+}
+
+func TestPromoteUnreachablePrimary(t *testing.T) {
+	ctx := context.Background()
+	catchUpPoll = time.Millisecond
+	primary := &fakeNode{blocks: chain(1, 2, 3, 4), generator: true}
+	standby := &fakeNode{blocks: chain(1, 2)}
+	c := &Coordinator{
+		Primary:        primary,
+		Standby:        standby,
+		MaxLag:         5,
+		CatchUpTimeout: 10 * time.Millisecond,
+		Fencer: func(context.Context) (*Fence, error) {
+			return &Fence{Height: 4, BlockID: bc.Hash{V0: 4}}, nil
+		},
+	}
+	c.Check(ctx)
+	primary.setDown(true)
+
+	// The standby doesn't have the primary's last block,
+	// even though it's within MaxLag.
+	err := c.Promote(ctx, "test")
+	if errors.Root(err) != ErrBehind {
+		t.Errorf("Promote() = %v, want %v", err, ErrBehind)
+	}
+
+	standby.setBlocks(chain(1, 2, 3, 4))
+	err = c.Promote(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Promote(ctx, "test")
+	if errors.Root(err) != ErrPromoted {
+		t.Errorf("second Promote() = %v, want %v", err, ErrPromoted)
+	}
+}
+
+func TestPromotePendingBlock(t *testing.T) {
+	ctx := context.Background()
+	catchUpPoll = time.Millisecond
+
+	// The primary fenced itself after a signer signed block 3.
+	pending := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 3, PreviousBlockHash: bc.Hash{V0: 2}}}
+	primary := &fakeNode{blocks: chain(1, 2), generator: true, pending: pending}
+	standby := &fakeNode{blocks: chain(1, 2)}
+	c := &Coordinator{Primary: primary, Standby: standby, Promotion: Promotion{Quorum: 1}}
+	err := c.Promote(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standby.promoted == nil || standby.promoted.PendingBlock != pending || standby.promoted.Quorum != 1 {
+		t.Errorf("standby promoted with %+v, want the primary's pending block and quorum 1", standby.promoted)
+	}
+	if c.Promotion.PendingBlock != nil {
+		t.Error("Promote changed the coordinator's Promotion")
+	}
+
+	// The pending block doesn't follow the primary's last block.
+	pending = &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 3, PreviousBlockHash: bc.Hash{V0: 5}}}
+	primary = &fakeNode{blocks: chain(1, 2), generator: true, pending: pending}
+	standby = &fakeNode{blocks: chain(1, 2)}
+	c = &Coordinator{Primary: primary, Standby: standby}
+	err = c.Promote(ctx, "test")
+	if errors.Root(err) != ErrDiverged {
+		t.Errorf("Promote() = %v, want %v", err, ErrDiverged)
+	}
+	if standby.promoted != nil {
+		t.Error("standby promoted with a pending block that doesn't follow the primary's")
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chain/core/generator"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestAdoptPendingBlock(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t, prottest.WithBlockSigners(1, 1))
+	_, privs := prottest.BlockKeyPairs(c)
+	makeSignedBlock(t, c, privs[0])
+	dir, err := ioutil.TempDir("", "core")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	defer os.RemoveAll(dir)
+	store := generator.NewFileBlockStore(filepath.Join(dir, "pending-block"))
+	a := &API{chain: c, pendingStore: store}
+
+	prev, snapshot := c.State()
+	pending, _, err := c.GenerateBlock(ctx, prev, snapshot, time.Now().Add(time.Minute), nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// A block that doesn't follow the latest block.
+	bad := *pending
+	bad.PreviousBlockHash = bc.Hash{V0: 1}
+	err = a.adoptPendingBlock(ctx, &bad)
+	if errors.Root(err) != errPendingBlock {
+		t.Errorf("adoptPendingBlock(wrong previous block) = %v, want %v", err, errPendingBlock)
+	}
+
+	err = a.adoptPendingBlock(ctx, pending)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := store.GetPendingBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got == nil || got.Hash() != pending.Hash() {
+		t.Errorf("saved pending block %v, want %x", got, pending.Hash().Bytes())
+	}
+
+	// Once another block is committed at its height,
+	// the pending block is stale.
+	makeSignedBlock(t, c, privs[0])
+	err = a.adoptPendingBlock(ctx, pending)
+	if errors.Root(err) != errPendingBlock {
+		t.Errorf("adoptPendingBlock(stale block) = %v, want %v", err, errPendingBlock)
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"time"
+
+	"chain/core/rpc"
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/metrics"
+	"chain/protocol/bc/legacy"
+)
+
+// Each submitted transaction passes through an ordered pipeline
+// of checks, the last of which admits it to the generator's
+// pending pool:
+//
+//	serialization  its serialization round-trips to the same transaction
+//	policy         it follows the policies of the accounts it spends from
+//	compliance     the compliance hook, if any, accepts it
+//	validation     it commits to its sighash, is valid, and hasn't expired
+//	admission      the generator accepts it into its pending pool
+//
+// Serialization and compliance can be disabled with
+// DisableSubmitChecks. The other checks can't be: without them,
+// a transaction could bypass account policies or validation.
+// Each check's latency is published in the expvar "latency" map,
+// as "submit.<name>".
+
+// SubmitChecks lists the names of the
+// submit pipeline's checks, in order.
+var SubmitChecks = []string{"serialization", "policy", "compliance", "validation", "admission"}
+
+// RequiredSubmitChecks holds the names of the
+// checks DisableSubmitChecks can't disable.
+var RequiredSubmitChecks = map[string]bool{"policy": true, "validation": true, "admission": true}
+
+var (
+	errBadSerialization = errors.New("transaction serialization doesn't round-trip")
+	errCompliance       = errors.New("transaction rejected by compliance check")
+
+	submitLatency = make(map[string]*metrics.RotatingLatency)
+)
+
+func init() {
+	for _, name := range SubmitChecks {
+		l := metrics.NewRotatingLatency(5, time.Second)
+		submitLatency[name] = l
+		metrics.PublishLatency("submit."+name, l)
+	}
+}
+
+// submission is a transaction going through the submit pipeline.
+type submission struct {
+	tx *legacy.Tx
+
+	// height is the height after which the transaction may
+	// appear in a block, set by the admission check.
+	height uint64
+}
+
+// submitCheckResult reports the outcome of one check.
+type submitCheckResult struct {
+	Check      string  `json:"check"`
+	Status     string  `json:"status"` // "passed", "failed", or "skipped"
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// runSubmitChecks runs s through each check of the submit
+// pipeline in turn, stopping at the first to fail, and returns
+// the result of each check run. If a check fails, its error is
+// returned, with the check's name and the results as data.
+func (a *API) runSubmitChecks(ctx context.Context, s *submission) ([]submitCheckResult, error) {
+	checks := map[string]func(context.Context, *submission) error{
+		"serialization": a.checkSerialization,
+		"policy":        a.checkPolicy,
+		"compliance":    a.checkCompliance,
+		"validation":    a.checkValidation,
+		"admission":     a.admitTx,
+	}
+	var results []submitCheckResult
+	for _, name := range SubmitChecks {
+		if a.disabledChecks[name] || (name == "compliance" && a.complianceCheck == nil) {
+			results = append(results, submitCheckResult{Check: name, Status: "skipped"})
+			continue
+		}
+		start := time.Now()
+		err := checks[name](ctx, s)
+		d := time.Since(start)
+		submitLatency[name].Record(d)
+
+		res := submitCheckResult{Check: name, Status: "passed", DurationMS: float64(d) / float64(time.Millisecond)}
+		if err != nil {
+			res.Status = "failed"
+			results = append(results, res)
+			return results, errors.WithData(err, "check", name, "checks", results)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (a *API) checkSerialization(ctx context.Context, s *submission) error {
+	b, err := s.tx.MarshalText()
+	if err != nil {
+		return errors.Sub(errBadSerialization, err)
+	}
+	var decoded legacy.Tx
+	err = decoded.UnmarshalText(b)
+	if err != nil {
+		return errors.Sub(errBadSerialization, err)
+	}
+	b2, err := decoded.MarshalText()
+	if err != nil {
+		return errors.Sub(errBadSerialization, err)
+	}
+	if decoded.ID != s.tx.ID || !bytes.Equal(b, b2) {
+		return errors.WithDetailf(errBadSerialization, "transaction %x decodes as %x", s.tx.ID.Bytes(), decoded.ID.Bytes())
+	}
+	return nil
+}
+
+// checkPolicy checks that the transaction follows the policies
+// of the accounts it spends from, however it was built.
+func (a *API) checkPolicy(ctx context.Context, s *submission) error {
+	return a.accounts.CheckPolicies(ctx, s.tx)
+}
+
+func (a *API) checkCompliance(ctx context.Context, s *submission) error {
+	err := a.complianceCheck(ctx, s.tx)
+	if err != nil {
+		return errors.WithDetail(errCompliance, err.Error())
+	}
+	return nil
+}
+
+// RemoteComplianceCheck returns a compliance check, for
+// ComplianceCheck, that asks the service at client's URL
+// about each transaction. It posts
+//
+//	{"transaction": "<hex-encoded transaction>"}
+//
+// and the service responds with {"approved": true}, or with
+// {"approved": false, "reason": "..."} to reject it. If the
+// service can't be reached, the transaction is rejected.
+func RemoteComplianceCheck(client *rpc.Client) func(context.Context, *legacy.Tx) error {
+	// Calls go to the path of client's URL,
+	// not to a path under it.
+	var path string
+	u, err := url.Parse(client.BaseURL)
+	if err == nil {
+		path = u.Path
+	}
+	return func(ctx context.Context, tx *legacy.Tx) error {
+		req := struct {
+			Transaction *legacy.Tx `json:"transaction"`
+		}{tx}
+		var resp struct {
+			Approved bool   `json:"approved"`
+			Reason   string `json:"reason"`
+		}
+		err := client.Call(ctx, path, req, &resp)
+		if err != nil {
+			return errors.Wrap(err, "calling compliance service")
+		}
+		if !resp.Approved && resp.Reason == "" {
+			return errors.New("no reason given")
+		}
+		if !resp.Approved {
+			return errors.New(resp.Reason)
+		}
+		return nil
+	}
+}
+
+func (a *API) checkValidation(ctx context.Context, s *submission) error {
+	return txbuilder.CheckTx(ctx, a.chain, s.tx)
+}
+
+// admitTx records the height the transaction is submitted at,
+// in case the submit call is retried, and sends it to the
+// generator.
+func (a *API) admitTx(ctx context.Context, s *submission) error {
+	// Use the current generator height as the lower bound of the block height
+	// that the transaction may appear in.
+	var generatorHeight uint64
+	if a.replicator != nil {
+		generatorHeight, _ = a.replicator.PeerHeight()
+	}
+	localHeight := a.chain.Height()
+	if localHeight > generatorHeight {
+		generatorHeight = localHeight
+	}
+
+	// Remember this height in case we retry this submit call.
+	height, err := recordSubmittedTx(ctx, a.db, s.tx.ID, generatorHeight)
+	if err != nil {
+		return errors.Wrap(err, "saving tx submitted height")
+	}
+	s.height = height
+
+	err = a.submitter.Submit(ctx, s.tx)
+	return generatorErr(errors.Wrap(err))
+}
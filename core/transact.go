@@ -6,13 +6,16 @@ import (
 	"sync"
 	"time"
 
+	"chain/core/generator"
 	"chain/core/leader"
+	"chain/core/rpc"
 	"chain/core/txbuilder"
 	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/log"
 	"chain/net/http/httperror"
+	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
@@ -37,6 +40,14 @@ func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, erro
 		decoder = a.accounts.DecodeSpendAction
 	case "spend_account_unspent_output":
 		decoder = a.accounts.DecodeSpendUTXOAction
+	case "spend_account_unspent_outputs":
+		decoder = a.accounts.DecodeSpendUTXOsAction
+	case "spend_from_vault":
+		decoder = a.accounts.DecodeSpendFromVaultAction
+	case "top_up_vault":
+		decoder = a.accounts.DecodeTopUpVaultAction
+	case "transfer":
+		decoder = a.accounts.DecodeTransferAction
 	case "set_transaction_reference_data":
 		decoder = txbuilder.DecodeSetTxRefDataAction
 	default:
@@ -93,6 +104,9 @@ func (a *API) buildSingle(ctx context.Context, req *buildRequest) (*txbuilder.Te
 		return nil, err
 	}
 
+	initialBlockID := a.chain.InitialBlockHash
+	tpl.InitialBlockID = &initialBlockID
+
 	// ensure null is never returned for signing instructions
 	if tpl.SigningInstructions == nil {
 		tpl.SigningInstructions = []*txbuilder.SigningInstruction{}
@@ -134,17 +148,104 @@ func (a *API) build(ctx context.Context, buildReqs []*buildRequest) (interface{}
 	return responses, nil
 }
 
+// issuanceRequest is an issuance of an asset to an account
+// or a control program, in a request to /build-issuance.
+type issuanceRequest struct {
+	AssetID        string             `json:"asset_id"`
+	AssetAlias     string             `json:"asset_alias"`
+	Amount         uint64             `json:"amount"`
+	ReferenceData  chainjson.Map      `json:"reference_data"`
+	AccountID      string             `json:"account_id"`
+	AccountAlias   string             `json:"account_alias"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+}
+
+// buildIssuance is an http handler for building a transaction
+// that issues several assets at once, each to an account or a
+// control program, so that they're issued atomically. Each
+// issuance gets its own nonce, and signing instructions for its
+// asset's keys.
+//
+// POST /build-issuance
+func (a *API) buildIssuance(ctx context.Context, in struct {
+	Issuances     []issuanceRequest  `json:"issuances"`
+	ReferenceData chainjson.Map      `json:"reference_data"`
+	TTL           chainjson.Duration `json:"ttl"`
+}) (*txbuilder.Template, error) {
+	if len(in.Issuances) == 0 {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "at least one issuance is required")
+	}
+
+	req := &buildRequest{TTL: in.TTL}
+	for i, iss := range in.Issuances {
+		asset := map[string]interface{}{"amount": iss.Amount}
+		switch {
+		case iss.AssetID != "":
+			asset["asset_id"] = iss.AssetID
+		case iss.AssetAlias != "":
+			asset["asset_alias"] = iss.AssetAlias
+		default:
+			return nil, errors.WithDetailf(httpjson.ErrBadRequest, "issuance %d needs an asset_id or asset_alias", i)
+		}
+
+		issue := map[string]interface{}{"type": "issue"}
+		control := map[string]interface{}{}
+		for k, v := range asset {
+			issue[k] = v
+			control[k] = v
+		}
+		if len(iss.ReferenceData) > 0 {
+			issue["reference_data"] = iss.ReferenceData
+		}
+		switch {
+		case len(iss.ControlProgram) > 0:
+			control["type"] = "control_program"
+			control["control_program"] = iss.ControlProgram
+		case iss.AccountID != "":
+			control["type"] = "control_account"
+			control["account_id"] = iss.AccountID
+		case iss.AccountAlias != "":
+			control["type"] = "control_account"
+			control["account_alias"] = iss.AccountAlias
+		default:
+			return nil, errors.WithDetailf(httpjson.ErrBadRequest, "issuance %d needs an account_id, account_alias or control_program", i)
+		}
+		req.Actions = append(req.Actions, issue, control)
+	}
+	if len(in.ReferenceData) > 0 {
+		req.Actions = append(req.Actions, map[string]interface{}{
+			"type":           "set_transaction_reference_data",
+			"reference_data": in.ReferenceData,
+		})
+	}
+	return a.buildSingle(ctx, req)
+}
+
 func (a *API) submitSingle(ctx context.Context, tpl *txbuilder.Template, waitUntil string) (interface{}, error) {
 	if tpl.Transaction == nil {
 		return nil, errors.Wrap(txbuilder.ErrMissingRawTx)
 	}
 
-	err := a.finalizeTxWait(ctx, tpl, waitUntil)
+	s := &submission{tx: tpl.Transaction}
+	checks, err := a.runSubmitChecks(ctx, s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+	}
+
+	err = a.accounts.ReleaseSettledHolds(ctx, tpl.Transaction.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+	}
+
+	err = a.waitForTx(ctx, tpl.Transaction, s.height, waitUntil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
 	}
 
-	return map[string]string{"id": tpl.Transaction.ID.String()}, nil
+	return map[string]interface{}{
+		"id":     tpl.Transaction.ID.String(),
+		"checks": checks,
+	}, nil
 }
 
 // recordSubmittedTx records a lower bound height at which the tx
@@ -205,43 +306,24 @@ func cleanUpSubmittedTxs(ctx context.Context, db pg.DB) {
 	}
 }
 
-// finalizeTxWait calls FinalizeTx and then waits for confirmation of
-// the transaction.  A nil error return means the transaction is
-// confirmed on the blockchain.  ErrRejected means a conflicting tx is
-// on the blockchain.  context.DeadlineExceeded means ctx is an
-// expiring context that timed out.
-func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template, waitUntil string) error {
-	// Use the current generator height as the lower bound of the block height
-	// that the transaction may appear in.
-	var generatorHeight uint64
-	if a.replicator != nil {
-		generatorHeight, _ = a.replicator.PeerHeight()
-	}
-	localHeight := a.chain.Height()
-	if localHeight > generatorHeight {
-		generatorHeight = localHeight
-	}
-
-	// Remember this height in case we retry this submit call.
-	height, err := recordSubmittedTx(ctx, a.db, txTemplate.Transaction.ID, generatorHeight)
-	if err != nil {
-		return errors.Wrap(err, "saving tx submitted height")
-	}
-
-	err = txbuilder.FinalizeTx(ctx, a.chain, a.submitter, txTemplate.Transaction)
-	if err != nil {
-		return err
-	}
+// waitForTx waits for confirmation of a transaction that
+// has passed the submit pipeline, whose checks recorded that
+// it may appear in a block after the given height. A nil error
+// return means the transaction is confirmed on the blockchain.
+// ErrRejected means a conflicting tx is on the blockchain.
+// context.DeadlineExceeded means ctx is an expiring context
+// that timed out.
+func (a *API) waitForTx(ctx context.Context, tx *legacy.Tx, height uint64, waitUntil string) error {
 	if waitUntil == "none" {
 		return nil
 	}
 
-	height, err = a.waitForTxInBlock(ctx, txTemplate.Transaction, height)
+	height, err := a.waitForTxInBlock(ctx, tx, height)
 	if err != nil {
 		return err
 	}
 	if waitUntil == "confirmed" {
-		return nil
+		return a.waitFinal(ctx, height)
 	}
 
 	select {
@@ -282,7 +364,7 @@ func (a *API) waitForTxInBlock(ctx context.Context, tx *legacy.Tx, height uint64
 			// Re-insert into the pool in case it was dropped.
 			err = txbuilder.FinalizeTx(ctx, a.chain, a.submitter, tx)
 			if err != nil {
-				return 0, err
+				return 0, generatorErr(err)
 			}
 
 			// TODO(jackson): Do simple rejection checks like checking if
@@ -291,6 +373,50 @@ func (a *API) waitForTxInBlock(ctx context.Context, tx *legacy.Tx, height uint64
 	}
 }
 
+// generatorErr translates an error relayed from a remote generator
+// back into the error it represents, if the application needs to
+// tell that error apart from others.
+func generatorErr(err error) error {
+	e, ok := errors.Root(err).(rpc.ErrStatusCode)
+	if !ok || e.ErrorData == nil {
+		return err
+	}
+	for _, genErr := range []error{generator.ErrExpired, generator.ErrTooLarge} {
+		if e.ErrorData.ChainCode != errorFormatter.Errors[genErr].ChainCode {
+			continue
+		}
+		var keyvals []interface{}
+		for k, v := range e.ErrorData.Data {
+			keyvals = append(keyvals, k, v)
+		}
+		return errors.WithData(errors.WithDetail(genErr, e.ErrorData.Detail), keyvals...)
+	}
+	return err
+}
+
+// POST /get-transaction-status
+//
+// getTxStatus reports whether a submitted transaction is pending
+// in the generator, has expired from its pending pool, or neither.
+// An expired transaction should be rebuilt within the suggested
+// time range rather than resubmitted.
+func (a *API) getTxStatus(ctx context.Context, in struct {
+	ID bc.Hash `json:"id"`
+}) (*generator.TxStatus, error) {
+	if a.leader.State() != leader.Leading {
+		var resp generator.TxStatus
+		err := a.forwardToLeader(ctx, "/get-transaction-status", in, &resp)
+		return &resp, err
+	}
+	if a.generator != nil {
+		st := a.generator.GetTxStatus(in.ID)
+		return &st, nil
+	}
+	var resp generator.TxStatus
+	err := a.remoteGenerator.Call(ctx, crosscoreRPCPrefix+"get-transaction-status", in.ID, &resp)
+	return &resp, errors.Wrap(err, "generator transaction status")
+}
+
 type submitArg struct {
 	Transactions []txbuilder.Template
 	wait         chainjson.Duration
@@ -304,6 +430,13 @@ func (a *API) submit(ctx context.Context, x submitArg) (interface{}, error) {
 		err := a.forwardToLeader(ctx, "/submit-transaction", x, &resp)
 		return resp, err
 	}
+	err := a.checkFence()
+	if err != nil {
+		return nil, err
+	}
+	if a.shadow != nil {
+		return nil, errors.WithDetail(errShadow, "a shadow core does not accept transactions")
+	}
 
 	// Setup a timeout for the provided wait duration.
 	timeout := x.wait.Duration
@@ -334,3 +467,32 @@ func (a *API) submit(ctx context.Context, x submitArg) (interface{}, error) {
 	wg.Wait()
 	return responses, nil
 }
+
+type signingPayloadsResp struct {
+	Template *txbuilder.Template         `json:"template"`
+	Payloads []*txbuilder.SigningPayload `json:"payloads"`
+}
+
+// POST /get-signing-payloads
+//
+// signingPayloads returns, for each template, the payloads its
+// keys must still sign, so that signers outside of Chain Core,
+// such as HSMs, can sign it. See txbuilder.SigningPayload.
+// Like the MockHSM, it refuses a template that needs approval
+// and lacks it; see SigningApproval.
+func (a *API) signingPayloads(ctx context.Context, tpls []*txbuilder.Template) []interface{} {
+	responses := make([]interface{}, len(tpls))
+	for i, tpl := range tpls {
+		var payloads []*txbuilder.SigningPayload
+		err := a.checkApproval(tpl)
+		if err == nil {
+			payloads, err = txbuilder.SigningPayloads(tpl)
+		}
+		if err != nil {
+			responses[i] = formatItemError(ctx, err)
+		} else {
+			responses[i] = signingPayloadsResp{Template: tpl, Payloads: payloads}
+		}
+	}
+	return responses
+}
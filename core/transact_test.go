@@ -2,6 +2,10 @@ package core
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -12,8 +16,10 @@ import (
 	"chain/core/generator"
 	"chain/core/pin"
 	"chain/core/query"
+	"chain/core/rpc"
 	"chain/core/txbuilder"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
@@ -194,3 +200,88 @@ func TestWaitForTxInBlockResubmits(t *testing.T) {
 		return
 	}
 }
+
+func TestRemoteComplianceCheck(t *testing.T) {
+	ctx := context.Background()
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(bc.AssetID{}, 123, []byte{10, 11, 12}, nil),
+		},
+	})
+	sanctioned := legacy.NewTx(legacy.TxData{Version: 1, ReferenceData: []byte("sanctioned")})
+
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+		var body struct {
+			Transaction *legacy.Tx `json:"transaction"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body.Transaction.ID == sanctioned.ID {
+			w.Write([]byte(`{"approved": false, "reason": "sanctioned"}`))
+			return
+		}
+		w.Write([]byte(`{"approved": true}`))
+	}))
+	defer srv.Close()
+
+	check := RemoteComplianceCheck(&rpc.Client{BaseURL: srv.URL + "/check"})
+	err := check(ctx, tx)
+	if err != nil {
+		t.Errorf("approved tx: unexpected error %v", err)
+	}
+	if gotPath != "/check" {
+		t.Errorf("request path = %q, want /check", gotPath)
+	}
+	err = check(ctx, sanctioned)
+	if err == nil || err.Error() != "sanctioned" {
+		t.Errorf("rejected tx: error = %v, want sanctioned", err)
+	}
+
+	srv.Close()
+	err = check(ctx, tx)
+	if err == nil {
+		t.Error("unreachable service: got no error")
+	}
+}
+
+func TestSubmitChecksStopAtFailure(t *testing.T) {
+	ctx := context.Background()
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(bc.AssetID{}, 123, []byte{10, 11, 12}, nil),
+		},
+	})
+	rejected := errors.New("sanctioned")
+	a := &API{
+		complianceCheck: func(context.Context, *legacy.Tx) error { return rejected },
+	}
+	// Policy can't be disabled.
+	DisableSubmitChecks("serialization", "policy")(a)
+
+	results, err := a.runSubmitChecks(ctx, &submission{tx: tx})
+	if errors.Root(err) != errCompliance {
+		t.Fatalf("runSubmitChecks() error = %v, want %v", err, errCompliance)
+	}
+	if errors.Detail(err) != "sanctioned" {
+		t.Errorf("error detail = %q, want %q", errors.Detail(err), "sanctioned")
+	}
+	if errors.Data(err)["check"] != "compliance" {
+		t.Errorf("error data check = %v, want compliance", errors.Data(err)["check"])
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Check+":"+r.Status)
+	}
+	want := []string{"serialization:skipped", "policy:passed", "compliance:failed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
@@ -2,7 +2,6 @@ package txbuilder
 
 import (
 	"bytes"
-	"math"
 	"time"
 
 	"chain/errors"
@@ -24,11 +23,13 @@ type TemplateBuilder struct {
 	referenceData       []byte
 	rollbacks           []func()
 	callbacks           []func() error
+	builtCallbacks      []func(*legacy.Tx) error
 }
 
 func (b *TemplateBuilder) AddInput(in *legacy.TxInput, sigInstruction *SigningInstruction) error {
-	if in.Amount() > math.MaxInt64 {
-		return errors.WithDetailf(ErrBadAmount, "amount %d exceeds maximum value 2^63", in.Amount())
+	_, err := bc.NewAmount(in.AssetID(), in.Amount())
+	if err != nil {
+		return errors.Sub(ErrBadAmount, err)
 	}
 	b.inputs = append(b.inputs, in)
 	b.signingInstructions = append(b.signingInstructions, sigInstruction)
@@ -36,13 +37,25 @@ func (b *TemplateBuilder) AddInput(in *legacy.TxInput, sigInstruction *SigningIn
 }
 
 func (b *TemplateBuilder) AddOutput(o *legacy.TxOutput) error {
-	if o.Amount > math.MaxInt64 {
-		return errors.WithDetailf(ErrBadAmount, "amount %d exceeds maximum value 2^63", o.Amount)
+	_, err := bc.NewAmount(*o.AssetId, o.Amount)
+	if err != nil {
+		return errors.Sub(ErrBadAmount, err)
 	}
 	b.outputs = append(b.outputs, o)
 	return nil
 }
 
+// Outputs returns the outputs the transaction will have:
+// those of the base transaction, if any, followed by those
+// added so far by actions.
+func (b *TemplateBuilder) Outputs() []*legacy.TxOutput {
+	var outputs []*legacy.TxOutput
+	if b.base != nil {
+		outputs = append(outputs, b.base.Outputs...)
+	}
+	return append(outputs, b.outputs...)
+}
+
 func (b *TemplateBuilder) RestrictMinTime(t time.Time) {
 	if t.After(b.minTime) {
 		b.minTime = t
@@ -59,6 +72,19 @@ func (b *TemplateBuilder) MaxTime() time.Time {
 	return b.maxTime
 }
 
+// MinTime returns the transaction's mintime as restricted
+// so far, including by the base transaction, if any.
+func (b *TemplateBuilder) MinTime() time.Time {
+	t := b.minTime
+	if b.base != nil && b.base.MinTime > 0 {
+		baseMin := time.Unix(0, int64(bc.MillisDuration(b.base.MinTime)))
+		if baseMin.After(t) {
+			t = baseMin
+		}
+	}
+	return t
+}
+
 // OnRollback registers a function that can be
 // used to attempt to undo any side effects of building
 // actions. For example, it might cancel any reservations
@@ -76,6 +102,12 @@ func (b *TemplateBuilder) OnBuild(buildFn func() error) {
 	b.callbacks = append(b.callbacks, buildFn)
 }
 
+// OnBuilt registers a function that will be run with the
+// transaction once it has been built and checked.
+func (b *TemplateBuilder) OnBuilt(builtFn func(*legacy.Tx) error) {
+	b.builtCallbacks = append(b.builtCallbacks, builtFn)
+}
+
 func (b *TemplateBuilder) setReferenceData(data []byte) error {
 	if b.base != nil && len(b.base.ReferenceData) != 0 && !bytes.Equal(b.base.ReferenceData, data) {
 		return errors.Wrap(ErrBadRefData)
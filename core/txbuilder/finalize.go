@@ -7,6 +7,7 @@ import (
 	"chain/core/rpc"
 	"chain/errors"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
 )
@@ -29,7 +30,27 @@ type Submitter interface {
 // assembles a fully signed tx, and stores the effects of
 // its changes on the UTXO set.
 func FinalizeTx(ctx context.Context, c *protocol.Chain, s Submitter, tx *legacy.Tx) error {
-	err := checkTxSighashCommitment(tx)
+	err := CheckTx(ctx, c, tx)
+	if err != nil {
+		return err
+	}
+
+	err = s.Submit(ctx, tx)
+	return errors.Wrap(err)
+}
+
+// CheckTx checks that tx commits to its own sighash, is
+// valid against the current state of c, and hasn't expired,
+// as FinalizeTx does before submitting it.
+func CheckTx(ctx context.Context, c *protocol.Chain, tx *legacy.Tx) error {
+	// Validation passes over the inputs it doesn't understand,
+	// leaving them to the newer software that does, so this
+	// Core can't tell whether such a transaction is valid.
+	if tx.HasUnknownInput() {
+		return errors.Wrap(ErrUnknownInput, "tx rejected")
+	}
+
+	err := checkTxSighashCommitment(tx, c.InitialBlockHash)
 	if err != nil {
 		return err
 	}
@@ -48,9 +69,7 @@ func FinalizeTx(ctx context.Context, c *protocol.Chain, s Submitter, tx *legacy.
 	if tx.Tx.MaxTimeMs > 0 && tx.Tx.MaxTimeMs < c.TimestampMS() {
 		return errors.Wrap(ErrRejected, "tx expired")
 	}
-
-	err = s.Submit(ctx, tx)
-	return errors.Wrap(err)
+	return nil
 }
 
 var (
@@ -70,7 +89,7 @@ var (
 	ErrTxSignatureFailure = errors.New("tx signature was attempted but failed")
 )
 
-func checkTxSighashCommitment(tx *legacy.Tx) error {
+func checkTxSighashCommitment(tx *legacy.Tx, initialBlockID bc.Hash) error {
 	var lastError error
 
 	for i, inp := range tx.Inputs {
@@ -106,7 +125,7 @@ func checkTxSighashCommitment(tx *legacy.Tx) error {
 		if !bytes.Equal(prog[33:], []byte{byte(vm.OP_TXSIGHASH), byte(vm.OP_EQUAL)}) {
 			continue
 		}
-		h := tx.SigHash(uint32(i))
+		h := tx.SigHash(uint32(i), initialBlockID)
 		if !bytes.Equal(h.Bytes(), prog[1:33]) {
 			continue
 		}
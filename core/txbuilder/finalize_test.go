@@ -61,7 +61,7 @@ func TestSighashCheck(t *testing.T) {
 	}
 	tpl1.AllowAdditional = true
 	coretest.SignTxTemplate(t, ctx, tpl1, nil)
-	err = CheckTxSighashCommitment(tpl1.Transaction)
+	err = CheckTxSighashCommitment(tpl1.Transaction, info.Chain.InitialBlockHash)
 	if err == nil {
 		t.Error("unexpected success from checkTxSighashCommitment")
 	}
@@ -74,7 +74,7 @@ func TestSighashCheck(t *testing.T) {
 		t.Fatal(err)
 	}
 	coretest.SignTxTemplate(t, ctx, tpl2a, nil)
-	err = CheckTxSighashCommitment(tpl2a.Transaction)
+	err = CheckTxSighashCommitment(tpl2a.Transaction, info.Chain.InitialBlockHash)
 	if err != nil {
 		t.Errorf("unexpected failure from checkTxSighashCommitment (case 1): %v", err)
 	}
@@ -85,7 +85,7 @@ func TestSighashCheck(t *testing.T) {
 		t.Fatal(err)
 	}
 	coretest.SignTxTemplate(t, ctx, tpl2b, nil)
-	err = CheckTxSighashCommitment(tpl2b.Transaction)
+	err = CheckTxSighashCommitment(tpl2b.Transaction, info.Chain.InitialBlockHash)
 	if err != nil {
 		t.Errorf("unexpected failure from checkTxSighashCommitment (case 2): %v", err)
 	}
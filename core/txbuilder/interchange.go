@@ -0,0 +1,519 @@
+package txbuilder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"chain/encoding/blockchain"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrBadInterchange is returned by DecodeInterchange
+// for data that isn't a valid interchange encoding.
+var ErrBadInterchange = errors.New("invalid template interchange data")
+
+// The template interchange format is a compact, extensible
+// binary encoding of a Template, modeled on Bitcoin's PSBT
+// (BIP 174), for passing partially signed transactions to
+// signing tools outside of Chain Core.
+//
+// An encoding is the magic bytes, a version number (varint31),
+// a global map, then one map for each input and one for each
+// output of the transaction, in order:
+//
+//	interchange = magic version global-map input-map* output-map*
+//
+// A map is a sequence of entries, each a key and a value
+// (both varstr31), ending with an empty key. The key is a key
+// type (varint31) followed by data that depends on the type.
+// Keys in a map must be in increasing bytewise order, so there
+// is exactly one encoding of each Template.
+//
+// Global map key types:
+//
+//	0x00  transaction, serialized; required
+//	0x01  initial block ID, 32 bytes
+//	0x02  local; the value is 0x01, and the key is omitted if false
+//	0x03  allow additional actions; as for local
+//
+// Input map key types:
+//
+//	0x00  signature witness component. The key data is the
+//	      component's index (varint31), counting from 0 with no
+//	      gaps. The value is the quorum (varint31), commitment
+//	      (varstr31), program (varstr31), number of keys (varint31),
+//	      then for each key its 64-byte xpub and derivation path
+//	      (a varstr31 list).
+//	0x01  signature. The key data is the component index and the
+//	      index of the key in it (both varint31). The value is the
+//	      signature.
+//	0x02  witness argument preceding those of the components; in
+//	      version 2 or later. The key data is the argument's index
+//	      (varint31), counting from 0 with no gaps. The value is
+//	      the argument.
+//	0x03  empty signing instruction, with no witness components
+//	      or arguments; in version 3 only, and alone in its map.
+//	      The value is empty. An input map with no entries means
+//	      the input has no signing instruction.
+//
+// No output map key types are defined yet. Key type 0xfc in any
+// map is for proprietary use; DecodeInterchange ignores it. Any
+// other unknown key type is an error: new key types come with a
+// new version number.
+const (
+	// interchangeVersion is the latest version. A template
+	// is encoded as the earliest version that can encode it:
+	// version 3 if it has an empty signing instruction,
+	// version 2 if it has witness arguments, and otherwise
+	// version 1.
+	interchangeVersion = 3
+
+	globalKeyTx              = 0x00
+	globalKeyInitialBlockID  = 0x01
+	globalKeyLocal           = 0x02
+	globalKeyAllowAdditional = 0x03
+
+	inputKeyWitnessComponent = 0x00
+	inputKeySignature        = 0x01
+	inputKeyArgument         = 0x02
+	inputKeyEmpty            = 0x03
+
+	keyProprietary = 0xfc
+)
+
+var interchangeMagic = []byte{'c', 't', 'p', 'l', 0xff}
+
+type interchangeEntry struct {
+	key, value []byte
+}
+
+// EncodeInterchange encodes tpl in the template interchange
+// format. Each of tpl's signing instructions must be for a
+// different input.
+func EncodeInterchange(tpl *Template) ([]byte, error) {
+	if tpl.Transaction == nil {
+		return nil, errors.Wrap(ErrMissingRawTx)
+	}
+	tx := tpl.Transaction
+
+	var txbuf bytes.Buffer
+	_, err := tx.WriteTo(&txbuf)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	global := []interchangeEntry{{key: ikey(globalKeyTx), value: txbuf.Bytes()}}
+	if tpl.InitialBlockID != nil {
+		global = append(global, interchangeEntry{key: ikey(globalKeyInitialBlockID), value: tpl.InitialBlockID.Bytes()})
+	}
+	if tpl.Local {
+		global = append(global, interchangeEntry{key: ikey(globalKeyLocal), value: []byte{1}})
+	}
+	if tpl.AllowAdditional {
+		global = append(global, interchangeEntry{key: ikey(globalKeyAllowAdditional), value: []byte{1}})
+	}
+
+	version := uint64(1)
+	inputs := make([][]interchangeEntry, len(tx.Inputs))
+	for i, si := range tpl.SigningInstructions {
+		if int(si.Position) >= len(tx.Inputs) {
+			return nil, errors.WithDetailf(ErrBadTxInputIdx, "signing instruction %d references missing tx input %d", i, si.Position)
+		}
+		if inputs[si.Position] != nil {
+			return nil, errors.WithDetailf(ErrBadInterchange, "more than one signing instruction for input %d", si.Position)
+		}
+		m := []interchangeEntry{}
+		if len(si.Arguments) == 0 && len(si.SignatureWitnesses) == 0 {
+			m = append(m, interchangeEntry{key: ikey(inputKeyEmpty), value: []byte{}})
+			version = 3
+		}
+		for j, arg := range si.Arguments {
+			m = append(m, interchangeEntry{key: ikey(inputKeyArgument, uint64(j)), value: arg})
+			if version < 2 {
+				version = 2
+			}
+		}
+		for j, sw := range si.SignatureWitnesses {
+			if len(sw.Sigs) > len(sw.Keys) {
+				return nil, errors.WithDetailf(ErrBadWitnessComponent, "witness component %d of input %d has more signatures than keys", j, si.Position)
+			}
+			var w interchangeWriter
+			w.varint(uint64(sw.Quorum))
+			w.varstr([]byte(sw.Commitment))
+			w.varstr(sw.Program)
+			w.varint(uint64(len(sw.Keys)))
+			for _, k := range sw.Keys {
+				w.Write(k.XPub[:])
+				w.varint(uint64(len(k.DerivationPath)))
+				for _, step := range k.DerivationPath {
+					w.varstr(step)
+				}
+			}
+			if w.err != nil {
+				return nil, errors.Wrapf(w.err, "encoding witness component %d of input %d", j, si.Position)
+			}
+			m = append(m, interchangeEntry{key: ikey(inputKeyWitnessComponent, uint64(j)), value: w.Bytes()})
+			for k, sig := range sw.Sigs {
+				if len(sig) > 0 {
+					m = append(m, interchangeEntry{key: ikey(inputKeySignature, uint64(j), uint64(k)), value: sig})
+				}
+			}
+		}
+		inputs[si.Position] = m
+	}
+
+	var w interchangeWriter
+	w.Write(interchangeMagic)
+	w.varint(version)
+	w.writeMap(global)
+	for _, m := range inputs {
+		w.writeMap(m)
+	}
+	for range tx.Outputs {
+		w.writeMap(nil)
+	}
+	return w.Bytes(), errors.Wrap(w.err)
+}
+
+// ikey returns a key of type typ with data
+// made of the varint encodings of vals.
+func ikey(typ uint64, vals ...uint64) []byte {
+	key := appendUvarint(nil, typ)
+	for _, v := range vals {
+		key = appendUvarint(key, v)
+	}
+	return key
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(dst, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// interchangeWriter writes the parts of the interchange
+// format, keeping the first error for the caller to check.
+type interchangeWriter struct {
+	bytes.Buffer
+	err error
+}
+
+func (w *interchangeWriter) varint(v uint64) {
+	if w.err == nil {
+		_, w.err = blockchain.WriteVarint31(&w.Buffer, v)
+	}
+}
+
+func (w *interchangeWriter) varstr(b []byte) {
+	if w.err == nil {
+		_, w.err = blockchain.WriteVarstr31(&w.Buffer, b)
+	}
+}
+
+func (w *interchangeWriter) writeMap(m []interchangeEntry) {
+	sort.Slice(m, func(i, j int) bool { return bytes.Compare(m[i].key, m[j].key) < 0 })
+	for _, e := range m {
+		w.varstr(e.key)
+		w.varstr(e.value)
+	}
+	w.varstr(nil)
+}
+
+// DecodeInterchange decodes a Template from the template
+// interchange format. It returns ErrBadInterchange, with
+// detail, for any data EncodeInterchange wouldn't produce,
+// other than proprietary entries.
+func DecodeInterchange(b []byte) (*Template, error) {
+	tpl, err := decodeInterchange(b)
+	if err != nil && errors.Root(err) != ErrBadInterchange {
+		err = errors.Sub(ErrBadInterchange, err)
+	}
+	return tpl, err
+}
+
+func decodeInterchange(b []byte) (*Template, error) {
+	if !bytes.HasPrefix(b, interchangeMagic) {
+		return nil, errors.WithDetail(ErrBadInterchange, "missing magic bytes")
+	}
+	r := blockchain.NewReader(b[len(interchangeMagic):])
+	version, err := blockchain.ReadVarint31(r)
+	if err != nil {
+		return nil, err
+	}
+	if version < 1 || version > interchangeVersion {
+		return nil, errors.WithDetailf(ErrBadInterchange, "unknown version %d", version)
+	}
+
+	tpl := new(Template)
+	global, err := readInterchangeMap(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading global map")
+	}
+	for _, e := range global {
+		kr := blockchain.NewReader(e.key)
+		typ, _ := blockchain.ReadVarint31(kr)
+		if typ == keyProprietary {
+			continue
+		}
+		if kr.Len() > 0 {
+			return nil, errors.WithDetailf(ErrBadInterchange, "global key type %d has unexpected data", typ)
+		}
+		switch typ {
+		case globalKeyTx:
+			var data legacy.TxData
+			err = data.DecodeLimited(e.value, legacy.DefaultLimits)
+			if err != nil {
+				return nil, errors.Wrap(err, "decoding transaction")
+			}
+			tpl.Transaction = legacy.NewTx(data)
+		case globalKeyInitialBlockID:
+			if len(e.value) != 32 {
+				return nil, errors.WithDetailf(ErrBadInterchange, "initial block ID has %d bytes", len(e.value))
+			}
+			var b32 [32]byte
+			copy(b32[:], e.value)
+			id := bc.NewHash(b32)
+			tpl.InitialBlockID = &id
+		case globalKeyLocal, globalKeyAllowAdditional:
+			if !bytes.Equal(e.value, []byte{1}) {
+				return nil, errors.WithDetailf(ErrBadInterchange, "global key type %d has value %x, want 01", typ, e.value)
+			}
+			tpl.Local = tpl.Local || typ == globalKeyLocal
+			tpl.AllowAdditional = tpl.AllowAdditional || typ == globalKeyAllowAdditional
+		default:
+			return nil, errors.WithDetailf(ErrBadInterchange, "unknown global key type %d", typ)
+		}
+	}
+	if tpl.Transaction == nil {
+		return nil, errors.WithDetail(ErrBadInterchange, "missing transaction")
+	}
+
+	var hasArgs, hasEmpty bool
+	for i := range tpl.Transaction.Inputs {
+		m, err := readInterchangeMap(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading map of input %d", i)
+		}
+		si, err := decodeInterchangeInput(m, version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "input %d", i)
+		}
+		if si != nil {
+			si.Position = uint32(i)
+			tpl.SigningInstructions = append(tpl.SigningInstructions, si)
+			hasArgs = hasArgs || len(si.Arguments) > 0
+			hasEmpty = hasEmpty || (len(si.Arguments) == 0 && len(si.SignatureWitnesses) == 0)
+		}
+	}
+	// EncodeInterchange uses the earliest version it can.
+	want := uint32(1)
+	if hasArgs {
+		want = 2
+	}
+	if hasEmpty {
+		want = 3
+	}
+	if version != want {
+		return nil, errors.WithDetailf(ErrBadInterchange, "version %d for a template needing version %d", version, want)
+	}
+	for i := range tpl.Transaction.Outputs {
+		m, err := readInterchangeMap(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading map of output %d", i)
+		}
+		for _, e := range m {
+			typ, _ := blockchain.ReadVarint31(blockchain.NewReader(e.key))
+			if typ != keyProprietary {
+				return nil, errors.WithDetailf(ErrBadInterchange, "unknown key type %d in output %d", typ, i)
+			}
+		}
+	}
+	if r.Len() > 0 {
+		return nil, errors.WithDetailf(ErrBadInterchange, "trailing garbage (%d bytes)", r.Len())
+	}
+	return tpl, nil
+}
+
+// decodeInterchangeInput returns the signing instruction encoded
+// in m, or nil if m has no entries but proprietary ones.
+func decodeInterchangeInput(m []interchangeEntry, version uint32) (*SigningInstruction, error) {
+	comps := make(map[uint32]*signatureWitness)
+	type sig struct{ comp, key uint32 }
+	sigs := make(map[sig][]byte)
+	args := make(map[uint32][]byte)
+	var empty bool
+	for _, e := range m {
+		kr := blockchain.NewReader(e.key)
+		typ, _ := blockchain.ReadVarint31(kr)
+		switch typ {
+		case keyProprietary:
+			continue
+		case inputKeyWitnessComponent:
+			idx, err := blockchain.ReadVarint31(kr)
+			if err != nil || kr.Len() > 0 {
+				return nil, errors.WithDetail(ErrBadInterchange, "bad witness component key")
+			}
+			comps[idx], err = decodeWitnessComponent(e.value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "witness component %d", idx)
+			}
+		case inputKeySignature:
+			idx, err := blockchain.ReadVarint31(kr)
+			if err != nil {
+				return nil, errors.WithDetail(ErrBadInterchange, "bad signature key")
+			}
+			k, err := blockchain.ReadVarint31(kr)
+			if err != nil || kr.Len() > 0 {
+				return nil, errors.WithDetail(ErrBadInterchange, "bad signature key")
+			}
+			if len(e.value) == 0 {
+				return nil, errors.WithDetailf(ErrBadInterchange, "empty signature for key %d of witness component %d", k, idx)
+			}
+			sigs[sig{idx, k}] = e.value
+		case inputKeyArgument:
+			if version < 2 {
+				return nil, errors.WithDetailf(ErrBadInterchange, "witness argument in version %d", version)
+			}
+			idx, err := blockchain.ReadVarint31(kr)
+			if err != nil || kr.Len() > 0 {
+				return nil, errors.WithDetail(ErrBadInterchange, "bad witness argument key")
+			}
+			args[idx] = e.value
+		case inputKeyEmpty:
+			if version < 3 {
+				return nil, errors.WithDetailf(ErrBadInterchange, "empty signing instruction in version %d", version)
+			}
+			if kr.Len() > 0 || len(e.value) > 0 {
+				return nil, errors.WithDetail(ErrBadInterchange, "bad empty signing instruction entry")
+			}
+			empty = true
+		default:
+			return nil, errors.WithDetailf(ErrBadInterchange, "unknown input key type %d", typ)
+		}
+	}
+	if empty {
+		if len(comps) > 0 || len(sigs) > 0 || len(args) > 0 {
+			return nil, errors.WithDetail(ErrBadInterchange, "empty signing instruction with other entries")
+		}
+		return new(SigningInstruction), nil
+	}
+	if len(comps) == 0 && len(sigs) == 0 && len(args) == 0 {
+		return nil, nil
+	}
+
+	si := new(SigningInstruction)
+	if len(args) > 0 {
+		si.Arguments = make([]chainjson.HexBytes, len(args))
+	}
+	for idx, arg := range args {
+		if int(idx) >= len(si.Arguments) {
+			return nil, errors.WithDetailf(ErrBadInterchange, "witness argument %d of %d", idx, len(args))
+		}
+		si.Arguments[idx] = arg
+	}
+
+	sws := make([]*signatureWitness, len(comps))
+	for idx, sw := range comps {
+		if int(idx) >= len(sws) {
+			return nil, errors.WithDetailf(ErrBadInterchange, "witness component %d of %d", idx, len(comps))
+		}
+		sws[idx] = sw
+	}
+	for s, b := range sigs {
+		if int(s.comp) >= len(sws) || int(s.key) >= len(sws[s.comp].Keys) {
+			return nil, errors.WithDetailf(ErrBadInterchange, "signature for missing key %d of witness component %d", s.key, s.comp)
+		}
+		sws[s.comp].Sigs[s.key] = b
+	}
+	si.SignatureWitnesses = sws
+	return si, nil
+}
+
+func decodeWitnessComponent(b []byte) (*signatureWitness, error) {
+	r := blockchain.NewReader(b)
+	quorum, err := blockchain.ReadVarint31(r)
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := blockchain.ReadVarstr31(r)
+	if err != nil {
+		return nil, err
+	}
+	if !validCommitment(string(commitment)) {
+		return nil, errors.WithDetailf(ErrBadInterchange, "unknown commitment %q", commitment)
+	}
+	prog, err := blockchain.ReadVarstr31(r)
+	if err != nil {
+		return nil, err
+	}
+	nkeys, err := blockchain.ReadVarint31(r)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(nkeys)*64 > uint64(r.Len()) {
+		return nil, errors.WithDetailf(ErrBadInterchange, "%d keys don't fit in %d bytes", nkeys, r.Len())
+	}
+
+	sw := &signatureWitness{
+		Quorum:     int(quorum),
+		Commitment: string(commitment),
+		Keys:       make([]keyID, 0, nkeys),
+		Sigs:       make([]chainjson.HexBytes, nkeys),
+	}
+	if len(prog) > 0 {
+		sw.Program = prog
+	}
+	for i := 0; i < int(nkeys); i++ {
+		var k keyID
+		_, err = io.ReadFull(r, k.XPub[:])
+		if err != nil {
+			return nil, err
+		}
+		path, err := blockchain.ReadVarstrList(r)
+		if err != nil {
+			return nil, err
+		}
+		k.DerivationPath = make([]chainjson.HexBytes, len(path))
+		for j, step := range path {
+			k.DerivationPath[j] = step
+		}
+		sw.Keys = append(sw.Keys, k)
+	}
+	if r.Len() > 0 {
+		return nil, errors.WithDetailf(ErrBadInterchange, "witness component has %d extra bytes", r.Len())
+	}
+	return sw, nil
+}
+
+// readInterchangeMap reads a map, checking
+// that its keys are in increasing order.
+func readInterchangeMap(r *blockchain.Reader) ([]interchangeEntry, error) {
+	var m []interchangeEntry
+	for {
+		key, err := blockchain.ReadVarstr31(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			return m, nil
+		}
+		if len(m) > 0 && bytes.Compare(m[len(m)-1].key, key) >= 0 {
+			return nil, errors.WithDetailf(ErrBadInterchange, "key %x is out of order", key)
+		}
+		kr := blockchain.NewReader(key)
+		_, err = blockchain.ReadVarint31(kr)
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadInterchange, "bad key type in key %x", key)
+		}
+		value, err := blockchain.ReadVarstr31(r)
+		if err != nil {
+			return nil, err
+		}
+		// Copy the value, which the template may keep,
+		// so it doesn't alias the caller's buffer.
+		m = append(m, interchangeEntry{key: key, value: append([]byte(nil), value...)})
+	}
+}
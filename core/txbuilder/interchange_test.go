@@ -0,0 +1,256 @@
+package txbuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func interchangeTestTemplate() *Template {
+	assetID := bc.NewAssetID([32]byte{1})
+	initialBlockID := bc.NewHash([32]byte{2})
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, bc.NewHash([32]byte{3}), assetID, 5, 0, []byte{0x51}, bc.Hash{}, nil),
+			legacy.NewSpendInput(nil, bc.NewHash([32]byte{4}), assetID, 6, 0, []byte{0x51}, bc.Hash{}, nil),
+			legacy.NewSpendInput(nil, bc.NewHash([32]byte{5}), assetID, 7, 0, []byte{0x51}, bc.Hash{}, nil),
+		},
+		Outputs: []*legacy.TxOutput{legacy.NewTxOutput(assetID, 18, []byte("dest"), nil)},
+	})
+	key := func(step byte) keyID {
+		return keyID{XPub: testutil.TestXPub, DerivationPath: []chainjson.HexBytes{{step}, {0, 1}}}
+	}
+	return &Template{
+		Transaction:     tx,
+		AllowAdditional: true,
+		InitialBlockID:  &initialBlockID,
+		SigningInstructions: []*SigningInstruction{{
+			Position: 2,
+			SignatureWitnesses: []*signatureWitness{{
+				Quorum:     2,
+				Keys:       []keyID{key(1), key(2), key(3)},
+				Commitment: CommitOutputs,
+				Program:    []byte{0x51},
+				Sigs:       []chainjson.HexBytes{[]byte("sig1"), nil, []byte("sig3")},
+			}},
+		}, {
+			Position:  0,
+			Arguments: []chainjson.HexBytes{{}, {0x03}},
+			SignatureWitnesses: []*signatureWitness{{
+				Quorum: 1,
+				Keys:   []keyID{key(4)},
+				Sigs:   []chainjson.HexBytes{nil},
+			}, {
+				Quorum:     1,
+				Keys:       []keyID{key(5)},
+				Commitment: CommitTx,
+				Sigs:       []chainjson.HexBytes{nil},
+			}},
+		}},
+	}
+}
+
+func TestInterchangeRoundTrip(t *testing.T) {
+	tpl := interchangeTestTemplate()
+	b, err := EncodeInterchange(tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := DecodeInterchange(b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The decoded template has its signing
+	// instructions in the order of their inputs.
+	tpl.SigningInstructions[0], tpl.SigningInstructions[1] = tpl.SigningInstructions[1], tpl.SigningInstructions[0]
+	wantJSON, err := json.Marshal(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("decoded template:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+
+	b2, err := EncodeInterchange(got)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !bytes.Equal(b2, b) {
+		t.Errorf("re-encoding = %x, want %x", b2, b)
+	}
+}
+
+func TestInterchangeEmptyInstruction(t *testing.T) {
+	tpl := interchangeTestTemplate()
+	tpl.SigningInstructions = append(tpl.SigningInstructions, &SigningInstruction{Position: 1})
+	b, err := EncodeInterchange(tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := DecodeInterchange(b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(got.SigningInstructions) != 3 {
+		t.Fatalf("decoded %d signing instructions, want 3", len(got.SigningInstructions))
+	}
+	si := got.SigningInstructions[1]
+	if si.Position != 1 || len(si.SignatureWitnesses) != 0 || len(si.Arguments) != 0 {
+		t.Errorf("decoded signing instruction = %+v, want an empty one for input 1", si)
+	}
+	b2, err := EncodeInterchange(got)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !bytes.Equal(b2, b) {
+		t.Errorf("re-encoding = %x, want %x", b2, b)
+	}
+}
+
+func TestInterchangeStrict(t *testing.T) {
+	tx, err := EncodeInterchange(&Template{Transaction: interchangeTestTemplate().Transaction})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var txbuf bytes.Buffer
+	_, err = interchangeTestTemplate().Transaction.WriteTo(&txbuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// encode builds a version 1 encoding from a global map
+	// and maps for the test template's 3 inputs and 1 output.
+	encode := func(global []interchangeEntry, maps ...[]interchangeEntry) []byte {
+		var w interchangeWriter
+		w.Write(interchangeMagic)
+		w.varint(1)
+		w.writeMap(global)
+		for len(maps) < 4 {
+			maps = append(maps, nil)
+		}
+		for _, m := range maps {
+			w.writeMap(m)
+		}
+		return w.Bytes()
+	}
+	txEntry := interchangeEntry{ikey(globalKeyTx), txbuf.Bytes()}
+	component := func(nkeys byte) []byte {
+		b := []byte{1, 0, 0, nkeys}
+		for i := byte(0); i < nkeys; i++ {
+			b = append(b, testutil.TestXPub[:]...)
+			b = append(b, 0)
+		}
+		return b
+	}
+	proprietary := interchangeEntry{ikey(keyProprietary, 7), []byte("x")}
+	version := func(v byte, b []byte) []byte {
+		b[len(interchangeMagic)] = v
+		return b
+	}
+	version2 := func(b []byte) []byte { return version(2, b) }
+	argument := interchangeEntry{ikey(inputKeyArgument, 0), []byte{1}}
+	empty := interchangeEntry{ikey(inputKeyEmpty), nil}
+
+	cases := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"valid", tx, nil},
+		{"hand-built", encode([]interchangeEntry{txEntry}), nil},
+		{"proprietary entries", encode([]interchangeEntry{txEntry, proprietary}, []interchangeEntry{proprietary}, nil, nil, []interchangeEntry{proprietary}), nil},
+		{"bad magic", append([]byte("psbt"), tx[4:]...), ErrBadInterchange},
+		{"unknown version", append(append([]byte(nil), interchangeMagic...), append([]byte{4}, tx[len(interchangeMagic)+1:]...)...), ErrBadInterchange},
+		{"trailing data", append(append([]byte(nil), tx...), 0), ErrBadInterchange},
+		{"truncated", tx[:len(tx)-1], ErrBadInterchange},
+		{"missing tx", encode(nil), ErrBadInterchange},
+		{"duplicate key", encode([]interchangeEntry{txEntry, txEntry}), ErrBadInterchange},
+		{"unknown global key", encode([]interchangeEntry{txEntry, {ikey(9), nil}}), ErrBadInterchange},
+		{"bad flag value", encode([]interchangeEntry{txEntry, {ikey(globalKeyLocal), []byte{0}}}), ErrBadInterchange},
+		{"short initial block ID", encode([]interchangeEntry{txEntry, {ikey(globalKeyInitialBlockID), []byte{1}}}), ErrBadInterchange},
+		{"unknown output key", encode([]interchangeEntry{txEntry}, nil, nil, nil, []interchangeEntry{{ikey(0), nil}}), ErrBadInterchange},
+		{
+			"component gap",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{{ikey(inputKeyWitnessComponent, 1), component(1)}}),
+			ErrBadInterchange,
+		},
+		{
+			"signature for missing key",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{
+				{ikey(inputKeyWitnessComponent, 0), component(1)},
+				{ikey(inputKeySignature, 0, 1), []byte("sig")},
+			}),
+			ErrBadInterchange,
+		},
+		{
+			"signature without component",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{{ikey(inputKeySignature, 0, 0), []byte("sig")}}),
+			ErrBadInterchange,
+		},
+		{
+			"extra component bytes",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{{ikey(inputKeyWitnessComponent, 0), append(component(1), 0)}}),
+			ErrBadInterchange,
+		},
+		{
+			"argument gap",
+			version2(encode([]interchangeEntry{txEntry}, []interchangeEntry{{ikey(inputKeyArgument, 1), []byte{1}}})),
+			ErrBadInterchange,
+		},
+		{
+			"argument in version 1",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{argument}),
+			ErrBadInterchange,
+		},
+		{"argument in version 2", version2(encode([]interchangeEntry{txEntry}, []interchangeEntry{argument})), nil},
+		{"version 2 without arguments", version2(encode([]interchangeEntry{txEntry})), ErrBadInterchange},
+		{"empty instruction in version 3", version(3, encode([]interchangeEntry{txEntry}, []interchangeEntry{empty})), nil},
+		{
+			"empty instruction and argument in version 3",
+			version(3, encode([]interchangeEntry{txEntry}, []interchangeEntry{empty}, []interchangeEntry{argument})),
+			nil,
+		},
+		{"empty instruction in version 2", version2(encode([]interchangeEntry{txEntry}, []interchangeEntry{empty}, []interchangeEntry{argument})), ErrBadInterchange},
+		{"empty instruction with argument", version(3, encode([]interchangeEntry{txEntry}, []interchangeEntry{empty, argument})), ErrBadInterchange},
+		{"version 3 without empty instruction", version(3, encode([]interchangeEntry{txEntry}, []interchangeEntry{argument})), ErrBadInterchange},
+		{
+			"unknown commitment",
+			encode([]interchangeEntry{txEntry}, []interchangeEntry{{ikey(inputKeyWitnessComponent, 0), []byte{1, 1, 'x', 0, 0}}}),
+			ErrBadInterchange,
+		},
+	}
+	for _, c := range cases {
+		_, err := DecodeInterchange(c.data)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("%s: DecodeInterchange err = %v, want %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+func TestEncodeInterchangeErrors(t *testing.T) {
+	tpl := interchangeTestTemplate()
+	tpl.SigningInstructions[1].Position = 2
+	_, err := EncodeInterchange(tpl)
+	if errors.Root(err) != ErrBadInterchange {
+		t.Errorf("duplicate position: err = %v, want %v", err, ErrBadInterchange)
+	}
+
+	tpl = interchangeTestTemplate()
+	tpl.SigningInstructions[0].Position = 3
+	_, err = EncodeInterchange(tpl)
+	if errors.Root(err) != ErrBadTxInputIdx {
+		t.Errorf("missing input: err = %v, want %v", err, ErrBadTxInputIdx)
+	}
+}
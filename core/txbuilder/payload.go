@@ -0,0 +1,76 @@
+package txbuilder
+
+import (
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// SigningPayload is one signature a template still needs: the
+// exact bytes a key must sign, and where the signature goes.
+// It lets an external signer, such as an HSM, sign a template
+// without the Chain Core code.
+//
+// To sign, derive the key for XPub along DerivationPath (see
+// package chainkd) and make an Ed25519 signature of the 32 bytes
+// of Payload. Payload is the SHA3-256 hash of Program; there is
+// no other prefix or domain separation. Place the signature at
+//
+//	signing_instructions[SigningInstruction]
+//	  .witness_components[WitnessComponent]
+//	  .signatures[KeyIndex]
+//
+// of the template returned along with the payloads, then submit
+// the template.
+type SigningPayload struct {
+	SigningInstruction int                  `json:"signing_instruction"`
+	Position           uint32               `json:"position"`
+	WitnessComponent   int                  `json:"witness_component"`
+	KeyIndex           int                  `json:"key_index"`
+	XPub               chainkd.XPub         `json:"xpub"`
+	DerivationPath     []chainjson.HexBytes `json:"derivation_path"`
+	Program            chainjson.HexBytes   `json:"program"`
+	Payload            bc.Hash              `json:"payload"`
+}
+
+// SigningPayloads returns a SigningPayload for each key in tpl
+// that has not yet signed, in order of signing instruction,
+// witness component and key. Like Sign, it populates the
+// signature program of each witness component in tpl, and
+// makes room for the signatures; the payloads are valid
+// only for the resulting template.
+func SigningPayloads(tpl *Template) ([]*SigningPayload, error) {
+	if tpl.Transaction == nil {
+		return nil, errors.Wrap(ErrMissingRawTx)
+	}
+
+	var payloads []*SigningPayload
+	for i, sigInst := range tpl.SigningInstructions {
+		if int(sigInst.Position) >= len(tpl.Transaction.Inputs) {
+			return nil, errors.WithDetailf(ErrBadTxInputIdx, "signing instruction %d references missing tx input %d", i, sigInst.Position)
+		}
+		for j, sw := range sigInst.SignatureWitnesses {
+			h, err := sw.prepare(tpl, uint32(i))
+			if err != nil {
+				return nil, errors.WithDetailf(err, "preparing witness component %d of signing instruction %d", j, i)
+			}
+			for k, key := range sw.Keys {
+				if len(sw.Sigs[k]) > 0 {
+					continue
+				}
+				payloads = append(payloads, &SigningPayload{
+					SigningInstruction: i,
+					Position:           sigInst.Position,
+					WitnessComponent:   j,
+					KeyIndex:           k,
+					XPub:               key.XPub,
+					DerivationPath:     key.DerivationPath,
+					Program:            sw.Program,
+					Payload:            bc.NewHash(h),
+				})
+			}
+		}
+	}
+	return payloads, nil
+}
@@ -0,0 +1,80 @@
+package txbuilder
+
+import (
+	"context"
+	"testing"
+
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestSigningPayloads(t *testing.T) {
+	newTpl := func() *Template {
+		return &Template{
+			Transaction: legacy.NewTx(legacy.TxData{
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 123, 0, nil, bc.Hash{}, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					legacy.NewTxOutput(bc.AssetID{}, 123, []byte{10, 11, 12}, nil),
+				},
+			}),
+			SigningInstructions: []*SigningInstruction{{
+				Position: 0,
+				SignatureWitnesses: []*signatureWitness{{
+					Quorum: 1,
+					Keys: []keyID{{
+						XPub:           testutil.TestXPub,
+						DerivationPath: []chainjson.HexBytes{{1}, {2}},
+					}},
+				}},
+			}},
+		}
+	}
+
+	// Sign with the payloads, as an external signer would.
+	tpl := newTpl()
+	payloads, err := SigningPayloads(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(payloads))
+	}
+	p := payloads[0]
+	var path [][]byte
+	for _, b := range p.DerivationPath {
+		path = append(path, b)
+	}
+	sig := testutil.TestXPrv.Derive(path).Sign(p.Payload.Bytes())
+	tpl.SigningInstructions[p.SigningInstruction].SignatureWitnesses[p.WitnessComponent].Sigs[p.KeyIndex] = sig
+	err = materializeWitnesses(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign the same template with Sign.
+	want := newTpl()
+	err = Sign(context.Background(), want, []chainkd.XPub{testutil.TestXPub}, func(_ context.Context, _ chainkd.XPub, path [][]byte, data [32]byte) ([]byte, error) {
+		return testutil.TestXPrv.Derive(path).Sign(data[:]), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !testutil.DeepEqual(tpl, want) {
+		t.Errorf("signing with payloads got %+v, want %+v", tpl.Transaction.Inputs[0], want.Transaction.Inputs[0])
+	}
+
+	// A signed template needs no more payloads.
+	payloads, err = SigningPayloads(tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 0 {
+		t.Errorf("got %d payloads for a signed template, want 0", len(payloads))
+	}
+}
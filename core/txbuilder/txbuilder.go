@@ -21,6 +21,8 @@ var (
 	ErrBlankCheck          = errors.New("unsafe transaction: leaves assets free to control")
 	ErrAction              = errors.New("errors occurred in one or more actions")
 	ErrMissingFields       = errors.New("required field is missing")
+	ErrNoInitialBlockID    = errors.New("template has no initial block ID")
+	ErrUnknownInput        = errors.New("transaction has an input of unknown type")
 )
 
 // Build builds or adds on to a transaction.
@@ -29,6 +31,12 @@ var (
 // The final party must ensure that the transaction is
 // balanced before calling finalize.
 func Build(ctx context.Context, tx *legacy.TxData, actions []Action, maxTime time.Time) (*Template, error) {
+	// The value of an input of unknown type can't be known,
+	// so the transaction can't be checked for balance or safety.
+	if tx != nil && tx.HasUnknownInput() {
+		return nil, ErrUnknownInput
+	}
+
 	builder := TemplateBuilder{
 		base:    tx,
 		maxTime: maxTime,
@@ -63,10 +71,21 @@ func Build(ctx context.Context, tx *legacy.TxData, actions []Action, maxTime tim
 		return nil, err
 	}
 
+	for _, cb := range builder.builtCallbacks {
+		err = cb(tpl.Transaction)
+		if err != nil {
+			builder.rollback()
+			return nil, err
+		}
+	}
+
 	return tpl, nil
 }
 
 func Sign(ctx context.Context, tpl *Template, xpubs []chainkd.XPub, signFn SignFunc) error {
+	if tpl.Transaction != nil && tpl.Transaction.Version >= bc.TxReplayProtectionVersion && tpl.InitialBlockID == nil {
+		return errors.WithDetailf(ErrNoInitialBlockID, "transaction version %d signatures commit to the initial block ID", tpl.Transaction.Version)
+	}
 	for i, sigInst := range tpl.SigningInstructions {
 		for j, sw := range sigInst.SignatureWitnesses {
 			err := sw.sign(ctx, tpl, uint32(i), xpubs, signFn)
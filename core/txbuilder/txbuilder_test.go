@@ -101,6 +101,28 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestBuildUnknownInput(t *testing.T) {
+	ctx := context.Background()
+
+	assetID := bc.NewAssetID([32]byte{1})
+	base := &legacy.TxData{
+		Version: 2,
+		Inputs: []*legacy.TxInput{
+			{AssetVersion: 1, TypedInput: &legacy.UnknownInput{Type: 0xff}},
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 5, []byte("dest"), nil),
+		},
+	}
+	actions := []Action{
+		testAction(bc.AssetAmount{AssetId: &assetID, Amount: 5}),
+	}
+	_, err := Build(ctx, base, actions, time.Now().Add(time.Minute))
+	if errors.Root(err) != ErrUnknownInput {
+		t.Errorf("got error %#v, want ErrUnknownInput", err)
+	}
+}
+
 func TestMaterializeWitnesses(t *testing.T) {
 	var initialBlockHash bc.Hash
 	privkey, pubkey, err := chainkd.NewXKeys(nil)
@@ -160,6 +182,19 @@ func TestMaterializeWitnesses(t *testing.T) {
 	if !testutil.DeepEqual(got, want) {
 		t.Errorf("got input witness %v, want input witness %v", got, want)
 	}
+
+	// Arguments precede the signature witness's, and are
+	// counted among those passed to its program.
+	tpl.SigningInstructions[0].Arguments = []json.HexBytes{{7}}
+	want = [][]byte{{7}, vm.Int64Bytes(1), sig, prog}
+	err = materializeWitnesses(tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got = tpl.Transaction.Inputs[0].Arguments()
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("with arguments, got input witness %v, want input witness %v", got, want)
+	}
 }
 
 func TestSignatureWitnessMaterialize(t *testing.T) {
@@ -313,7 +348,7 @@ func TestTxSighashCommitment(t *testing.T) {
 		MinTime: bc.Millis(time.Now()),
 		MaxTime: bc.Millis(time.Now().Add(time.Hour)),
 	})
-	err := checkTxSighashCommitment(tx)
+	err := checkTxSighashCommitment(tx, bc.Hash{})
 	if err != ErrNoTxSighashAttempt {
 		t.Errorf("no issuance inputs committing to txsighash: got error %s, want ErrNoTxSighashAttempt", err)
 	}
@@ -334,7 +369,7 @@ func TestTxSighashCommitment(t *testing.T) {
 	})
 	tx.Outputs[0].Amount = 4
 	tx = legacy.NewTx(tx.TxData) // recompute the tx hash
-	err = checkTxSighashCommitment(tx)
+	err = checkTxSighashCommitment(tx, bc.Hash{})
 	if err != ErrNoTxSighashAttempt {
 		t.Errorf("no spend inputs committing to txsighash: got error %s, want ErrNoTxSighashAttempt", err)
 	}
@@ -362,7 +397,7 @@ func TestTxSighashCommitment(t *testing.T) {
 		t.Fatal(err)
 	}
 	spendInput.Arguments[2] = prog
-	err = checkTxSighashCommitment(tx)
+	err = checkTxSighashCommitment(tx, bc.Hash{})
 	if err != ErrNoTxSighashCommitment {
 		t.Errorf("spend input committing to the wrong txsighash: got error %s, want ErrNoTxSighashCommitment", err)
 	}
@@ -385,13 +420,13 @@ func TestTxSighashCommitment(t *testing.T) {
 	tx.Outputs[0].Amount = 11
 	tx = legacy.NewTx(tx.TxData) // recompute the tx hash
 	spendInput.Arguments = make([][]byte, 3)
-	h := tx.SigHash(4)
+	h := tx.SigHash(4, bc.Hash{})
 	prog, err = vm.Assemble(fmt.Sprintf("0x%x TXSIGHASH EQUAL", h.Bytes()))
 	if err != nil {
 		t.Fatal(err)
 	}
 	spendInput.Arguments[2] = prog
-	err = checkTxSighashCommitment(tx)
+	err = checkTxSighashCommitment(tx, bc.Hash{})
 	if err != nil {
 		t.Errorf("spend input committing to the right txsighash: got error %s, want no error", err)
 	}
@@ -414,13 +449,13 @@ func TestTxSighashCommitment(t *testing.T) {
 	tx.Outputs[0].Amount = 16
 	tx = legacy.NewTx(tx.TxData) // recompute the tx hash
 	spendInput.Arguments = make([][]byte, 2)
-	h = tx.SigHash(5)
+	h = tx.SigHash(5, bc.Hash{})
 	prog, err = vm.Assemble(fmt.Sprintf("0x%x TXSIGHASH EQUAL", h.Bytes()))
 	if err != nil {
 		t.Fatal(err)
 	}
 	spendInput.Arguments[1] = prog
-	err = checkTxSighashCommitment(tx)
+	err = checkTxSighashCommitment(tx, bc.Hash{})
 	if err != ErrTxSignatureFailure {
 		t.Errorf("spend input missing siguature: got error %s, want ErrTxSignatureFailure", err)
 	}
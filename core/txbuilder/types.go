@@ -28,16 +28,35 @@ type Template struct {
 	// ones cannot be changed. When false, signatures commit to the tx
 	// as a whole, and any change to the tx invalidates the signature.
 	AllowAdditional bool `json:"allow_additional_actions"`
+
+	// InitialBlockID identifies the blockchain the transaction
+	// is for. Signatures on transactions of version
+	// bc.TxReplayProtectionVersion or later commit to it.
+	InitialBlockID *bc.Hash `json:"initial_block_id,omitempty"`
+
+	// ApprovalToken is the token from a review of the
+	// transaction, for a Core that requires approval to
+	// sign it. See core.SigningApproval.
+	ApprovalToken string `json:"approval_token,omitempty"`
 }
 
 func (t *Template) Hash(idx uint32) bc.Hash {
-	return t.Transaction.SigHash(idx)
+	var initialBlockID bc.Hash
+	if t.InitialBlockID != nil {
+		initialBlockID = *t.InitialBlockID
+	}
+	return t.Transaction.SigHash(idx, initialBlockID)
 }
 
 // SigningInstruction gives directions for signing inputs in a TxTemplate.
 type SigningInstruction struct {
 	Position           uint32              `json:"position"`
 	SignatureWitnesses []*signatureWitness `json:"witness_components,omitempty"`
+
+	// Arguments are witness arguments for the input's control
+	// program that precede those of its witness components,
+	// and are passed to their signature programs.
+	Arguments []chainjson.HexBytes `json:"arguments,omitempty"`
 }
 
 func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
@@ -47,6 +66,7 @@ func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 			Type string
 			signatureWitness
 		} `json:"witness_components"`
+		Arguments []chainjson.HexBytes `json:"arguments"`
 	}
 	err := json.Unmarshal(b, &pre)
 	if err != nil {
@@ -54,11 +74,15 @@ func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 	}
 
 	si.Position = pre.Position
+	si.Arguments = pre.Arguments
 	si.SignatureWitnesses = make([]*signatureWitness, 0, len(pre.SignatureWitnesses))
 	for i, w := range pre.SignatureWitnesses {
 		if w.Type != "signature" {
 			return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d has unknown type '%s'", i, w.Type)
 		}
+		if !validCommitment(w.Commitment) {
+			return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d has unknown commitment '%s'", i, w.Commitment)
+		}
 		si.SignatureWitnesses = append(si.SignatureWitnesses, &w.signatureWitness)
 	}
 	return nil
@@ -10,6 +10,7 @@ import (
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
 	"chain/protocol/vm/vmutil"
 )
@@ -38,6 +39,9 @@ func materializeWitnesses(txTemplate *Template) error {
 		}
 
 		var witness [][]byte
+		for _, arg := range sigInst.Arguments {
+			witness = append(witness, arg)
+		}
 		for j, sw := range sigInst.SignatureWitnesses {
 			err := sw.materialize(txTemplate, sigInst.Position, &witness)
 			if err != nil {
@@ -64,6 +68,13 @@ type (
 		// and the current input of the transaction.
 		Program chainjson.HexBytes `json:"program"`
 
+		// Commitment selects what of the transaction an inferred
+		// Program commits to: one of CommitTx, CommitOutputs, and
+		// CommitSingleOutput. If empty, it is CommitOutputs for a
+		// template that allows additional actions and CommitTx
+		// otherwise.
+		Commitment string `json:"commitment,omitempty"`
+
 		// Sigs are signatures of Program made from each of the Keys
 		// during Sign.
 		Sigs []chainjson.HexBytes `json:"signatures"`
@@ -77,37 +88,53 @@ type (
 
 var ErrEmptyProgram = errors.New("empty signature program")
 
+// Commitment modes for a signature witness component, selecting
+// what of the transaction its signatures commit to. All of them
+// commit to the time range of the transaction; those other than
+// CommitTx also commit to the output the current input spends and
+// to the reference data of the input and, if set, the transaction.
+const (
+	// CommitTx commits to the whole transaction, by its
+	// sighash. Any change to it invalidates the signatures.
+	CommitTx = "tx"
+
+	// CommitOutputs commits to every output present at signing.
+	// Other parties may add inputs and outputs afterward.
+	CommitOutputs = "outputs"
+
+	// CommitSingleOutput commits only to the output at the same
+	// position as the current input, which must exist. Other
+	// parties may add inputs and outputs, or change the other
+	// outputs, afterward.
+	CommitSingleOutput = "single_output"
+)
+
+func validCommitment(c string) bool {
+	switch c {
+	case "", CommitTx, CommitOutputs, CommitSingleOutput:
+		return true
+	}
+	return false
+}
+
 // Sign populates sw.Sigs with as many signatures of the predicate in
 // sw.Program as it can from the overlapping set of keys in sw.Keys
 // and xpubs.
 //
 // If sw.Program is empty, it is populated with an _inferred_ predicate:
-// a program committing to aspects of the current
-// transaction. Specifically, the program commits to:
+// a program committing to aspects of the current transaction, as
+// selected by sw.Commitment. Unless that is CommitTx, the program
+// commits to:
 //  - the mintime and maxtime of the transaction (if non-zero)
 //  - the outputID and (if non-empty) reference data of the current input
-//  - the assetID, amount, control program, and (if non-empty) reference data of each output.
+//  - the assetID, amount, control program, and (if non-empty) reference data
+//    of each output, or, for CommitSingleOutput, of the output at the
+//    position of the current input.
 func (sw *signatureWitness) sign(ctx context.Context, tpl *Template, index uint32, xpubs []chainkd.XPub, signFn SignFunc) error {
-	// Compute the predicate to sign. This is either a
-	// txsighash program if tpl.AllowAdditional is false (i.e., the tx is complete
-	// and no further changes are allowed) or a program enforcing
-	// constraints derived from the existing outputs and current input.
-	if len(sw.Program) == 0 {
-		sw.Program = buildSigProgram(tpl, tpl.SigningInstructions[index].Position)
-		if len(sw.Program) == 0 {
-			return ErrEmptyProgram
-		}
+	h, err := sw.prepare(tpl, index)
+	if err != nil {
+		return err
 	}
-	if len(sw.Sigs) < len(sw.Keys) {
-		// Each key in sw.Keys may produce a signature in sw.Sigs. Make
-		// sure there are enough slots in sw.Sigs and that we preserve any
-		// sigs already present.
-		newSigs := make([]chainjson.HexBytes, len(sw.Keys))
-		copy(newSigs, sw.Sigs)
-		sw.Sigs = newSigs
-	}
-	var h [32]byte
-	sha3pool.Sum256(h[:], sw.Program)
 	for i, keyID := range sw.Keys {
 		if len(sw.Sigs[i]) > 0 {
 			// Already have a signature for this key
@@ -129,6 +156,38 @@ func (sw *signatureWitness) sign(ctx context.Context, tpl *Template, index uint3
 	return nil
 }
 
+// prepare populates sw.Program, if empty, and makes room in
+// sw.Sigs for a signature from each key. It returns the hash
+// of sw.Program, which is what each key signs.
+func (sw *signatureWitness) prepare(tpl *Template, index uint32) ([32]byte, error) {
+	var h [32]byte
+
+	// Compute the predicate to sign. This is either a
+	// txsighash program if tpl.AllowAdditional is false (i.e., the tx is complete
+	// and no further changes are allowed) or a program enforcing
+	// constraints derived from the existing outputs and current input.
+	if len(sw.Program) == 0 {
+		prog, err := sw.inferProgram(tpl, tpl.SigningInstructions[index].Position)
+		if err != nil {
+			return h, err
+		}
+		if len(prog) == 0 {
+			return h, ErrEmptyProgram
+		}
+		sw.Program = prog
+	}
+	if len(sw.Sigs) < len(sw.Keys) {
+		// Each key in sw.Keys may produce a signature in sw.Sigs. Make
+		// sure there are enough slots in sw.Sigs and that we preserve any
+		// sigs already present.
+		newSigs := make([]chainjson.HexBytes, len(sw.Keys))
+		copy(newSigs, sw.Sigs)
+		sw.Sigs = newSigs
+	}
+	sha3pool.Sum256(h[:], sw.Program)
+	return h, nil
+}
+
 func contains(list []chainkd.XPub, key chainkd.XPub) bool {
 	for _, k := range list {
 		if bytes.Equal(k[:], key[:]) {
@@ -138,16 +197,48 @@ func contains(list []chainkd.XPub, key chainkd.XPub) bool {
 	return false
 }
 
+// inferProgram returns the signature program for the input at
+// position index that commits to what sw.Commitment selects.
+func (sw *signatureWitness) inferProgram(tpl *Template, index uint32) ([]byte, error) {
+	switch sw.Commitment {
+	case "":
+		return buildSigProgram(tpl, index), nil
+	case CommitTx:
+		return txSighashProgram(tpl, index), nil
+	case CommitOutputs:
+		return constraintProgram(tpl, index, tpl.Transaction.Outputs), nil
+	case CommitSingleOutput:
+		if int(index) >= len(tpl.Transaction.Outputs) {
+			return nil, errors.WithDetailf(ErrBadWitnessComponent, "no output %d for single-output commitment of input %d", index, index)
+		}
+		outs := make([]*legacy.TxOutput, index+1)
+		outs[index] = tpl.Transaction.Outputs[index]
+		return constraintProgram(tpl, index, outs), nil
+	}
+	return nil, errors.WithDetailf(ErrBadWitnessComponent, "unknown commitment %q", sw.Commitment)
+}
+
 func buildSigProgram(tpl *Template, index uint32) []byte {
 	if !tpl.AllowAdditional {
-		h := tpl.Hash(index)
-		builder := vmutil.NewBuilder()
-		builder.AddData(h.Bytes())
-		builder.AddOp(vm.OP_TXSIGHASH).AddOp(vm.OP_EQUAL)
-		prog, _ := builder.Build() // error is impossible
-		return prog
-	}
-	constraints := make([]constraint, 0, 3+len(tpl.Transaction.Outputs))
+		return txSighashProgram(tpl, index)
+	}
+	return constraintProgram(tpl, index, tpl.Transaction.Outputs)
+}
+
+func txSighashProgram(tpl *Template, index uint32) []byte {
+	h := tpl.Hash(index)
+	builder := vmutil.NewBuilder()
+	builder.AddData(h.Bytes())
+	builder.AddOp(vm.OP_TXSIGHASH).AddOp(vm.OP_EQUAL)
+	prog, _ := builder.Build() // error is impossible
+	return prog
+}
+
+// constraintProgram returns a program committing to the
+// input at position index and to each non-nil output in
+// outs, at its position in outs.
+func constraintProgram(tpl *Template, index uint32, outs []*legacy.TxOutput) []byte {
+	constraints := make([]constraint, 0, 3+len(outs))
 	constraints = append(constraints, &timeConstraint{
 		minTimeMS: tpl.Transaction.MinTime,
 		maxTimeMS: tpl.Transaction.MaxTime,
@@ -167,7 +258,10 @@ func buildSigProgram(tpl *Template, index uint32) []byte {
 	}
 	constraints = append(constraints, refdataConstraint{tpl.Transaction.Inputs[index].ReferenceData, false})
 
-	for i, out := range tpl.Transaction.Outputs {
+	for i, out := range outs {
+		if out == nil {
+			continue
+		}
 		c := &payConstraint{
 			Index:       i,
 			AssetAmount: out.AssetAmount,
@@ -211,15 +305,17 @@ func (sw signatureWitness) materialize(tpl *Template, index uint32, args *[][]by
 
 func (sw signatureWitness) MarshalJSON() ([]byte, error) {
 	obj := struct {
-		Type   string               `json:"type"`
-		Quorum int                  `json:"quorum"`
-		Keys   []keyID              `json:"keys"`
-		Sigs   []chainjson.HexBytes `json:"signatures"`
+		Type       string               `json:"type"`
+		Quorum     int                  `json:"quorum"`
+		Keys       []keyID              `json:"keys"`
+		Sigs       []chainjson.HexBytes `json:"signatures"`
+		Commitment string               `json:"commitment,omitempty"`
 	}{
-		Type:   "signature",
-		Quorum: sw.Quorum,
-		Keys:   sw.Keys,
-		Sigs:   sw.Sigs,
+		Type:       "signature",
+		Quorum:     sw.Quorum,
+		Keys:       sw.Keys,
+		Sigs:       sw.Sigs,
+		Commitment: sw.Commitment,
 	}
 	return json.Marshal(obj)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 
 	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -46,6 +47,61 @@ func TestInferConstraints(t *testing.T) {
 	}
 }
 
+func TestInferCommitments(t *testing.T) {
+	tpl := &Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 123, 0, nil, bc.Hash{}, nil),
+				legacy.NewSpendInput(nil, bc.Hash{V0: 1}, bc.AssetID{}, 5, 0, nil, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(bc.AssetID{}, 100, []byte{10}, nil),
+				legacy.NewTxOutput(bc.AssetID{}, 28, []byte{11}, nil),
+			},
+		}),
+		AllowAdditional: true,
+	}
+	spend, err := tpl.Transaction.Tx.Spend(tpl.Transaction.Tx.InputIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := fmt.Sprintf("TRUE VERIFY 0x%x OUTPUTID EQUAL VERIFY 0xa7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a ENTRYDATA EQUAL VERIFY ", spend.SpentOutputId.Bytes())
+	h := tpl.Hash(1)
+
+	cases := []struct {
+		commitment string
+		wantSrc    string
+	}{
+		{"", prefix + "0 0 100 0x0000000000000000000000000000000000000000000000000000000000000000 1 0x0a CHECKOUTPUT VERIFY 1 0 28 0x0000000000000000000000000000000000000000000000000000000000000000 1 0x0b CHECKOUTPUT"},
+		{CommitOutputs, prefix + "0 0 100 0x0000000000000000000000000000000000000000000000000000000000000000 1 0x0a CHECKOUTPUT VERIFY 1 0 28 0x0000000000000000000000000000000000000000000000000000000000000000 1 0x0b CHECKOUTPUT"},
+		{CommitSingleOutput, prefix + "1 0 28 0x0000000000000000000000000000000000000000000000000000000000000000 1 0x0b CHECKOUTPUT"},
+		{CommitTx, fmt.Sprintf("0x%x TXSIGHASH EQUAL", h.Bytes())},
+	}
+	for _, c := range cases {
+		sw := &signatureWitness{Commitment: c.commitment}
+		prog, err := sw.inferProgram(tpl, 1)
+		if err != nil {
+			t.Errorf("commitment %q: %v", c.commitment, err)
+			continue
+		}
+		want, err := vm.Assemble(c.wantSrc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want, prog) {
+			progSrc, _ := vm.Disassemble(prog)
+			t.Errorf("commitment %q: got program [%s], want [%s]", c.commitment, progSrc, c.wantSrc)
+		}
+	}
+
+	tpl.Transaction.Outputs = tpl.Transaction.Outputs[:1]
+	sw := &signatureWitness{Commitment: CommitSingleOutput}
+	_, err = sw.inferProgram(tpl, 1)
+	if errors.Root(err) != ErrBadWitnessComponent {
+		t.Errorf("single output commitment without output: err = %v, want %v", err, ErrBadWitnessComponent)
+	}
+}
+
 func TestWitnessJSON(t *testing.T) {
 	si := &SigningInstruction{
 		Position: 17,
@@ -56,7 +112,8 @@ func TestWitnessJSON(t *testing.T) {
 					XPub:           testutil.TestXPub,
 					DerivationPath: []chainjson.HexBytes{{5, 6, 7}},
 				}},
-				Sigs: []chainjson.HexBytes{{8, 9, 10}},
+				Sigs:       []chainjson.HexBytes{{8, 9, 10}},
+				Commitment: CommitSingleOutput,
 			},
 		},
 	}
@@ -14,9 +14,20 @@ import (
 	"chain/protocol/state"
 )
 
+// evictInterval is how many state tree items decodeSnapshot
+// inserts between moving the tree to disk, if it does.
+const evictInterval = 1 << 16
+
 // DecodeSnapshot decodes a snapshot from the Chain Core's binary,
 // protobuf representation of the snapshot.
 func DecodeSnapshot(data []byte) (*state.Snapshot, error) {
+	return decodeSnapshot(data, nil)
+}
+
+// decodeSnapshot is like DecodeSnapshot, but if ds is not
+// nil, it moves the state tree to ds as it goes, so that
+// the whole tree is never in memory.
+func decodeSnapshot(data []byte, ds *state.DiskStore) (*state.Snapshot, error) {
 	var storedSnapshot storage.Snapshot
 	err := proto.Unmarshal(data, &storedSnapshot)
 	if err != nil {
@@ -24,11 +35,23 @@ func DecodeSnapshot(data []byte) (*state.Snapshot, error) {
 	}
 
 	tree := new(patricia.Tree)
-	for _, node := range storedSnapshot.Nodes {
+	for i, node := range storedSnapshot.Nodes {
 		err = tree.Insert(node.Key)
 		if err != nil {
 			return nil, errors.Wrap(err, "reconstructing state tree")
 		}
+		if ds != nil && (i+1)%evictInterval == 0 {
+			err = ds.Evict(tree)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ds != nil {
+		err = ds.Evict(tree)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	nonces := make(map[bc.Hash]uint64, len(storedSnapshot.Nonces))
@@ -84,7 +107,7 @@ func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot,
 	return errors.Wrap(err, "deleting old snapshots")
 }
 
-func getStateSnapshot(ctx context.Context, db pg.DB) (*state.Snapshot, uint64, error) {
+func getStateSnapshot(ctx context.Context, db pg.DB, ds *state.DiskStore) (*state.Snapshot, uint64, error) {
 	const q = `
 		SELECT data, height FROM snapshots ORDER BY height DESC LIMIT 1
 	`
@@ -100,7 +123,7 @@ func getStateSnapshot(ctx context.Context, db pg.DB) (*state.Snapshot, uint64, e
 		return nil, height, errors.Wrap(err, "retrieving state snapshot blob")
 	}
 
-	snapshot, err := DecodeSnapshot(data)
+	snapshot, err := decodeSnapshot(data, ds)
 	if err != nil {
 		return nil, height, errors.Wrap(err, "decoding snapshot")
 	}
@@ -22,7 +22,7 @@ func TestReadWriteStateSnapshotNonceSet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error writing state snapshot to db: %s\n", err)
 	}
-	got, _, err := getStateSnapshot(ctx, dbtx)
+	got, _, err := getStateSnapshot(ctx, dbtx, nil)
 	if err != nil {
 		t.Fatalf("Error reading state snapshot from db: %s\n", err)
 	}
@@ -90,7 +90,10 @@ func TestReadWriteStateSnapshot(t *testing.T) {
 			}
 		}
 		for _, key := range changeset.deletes {
-			snapshot.Tree.Delete(key.Bytes())
+			err := snapshot.Tree.Delete(key.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
 		}
 
 		err := storeStateSnapshot(ctx, dbtx, snapshot, uint64(i))
@@ -98,13 +101,17 @@ func TestReadWriteStateSnapshot(t *testing.T) {
 			t.Fatalf("Error writing state snapshot to db: %s\n", err)
 		}
 
-		loadedSnapshot, height, err := getStateSnapshot(ctx, dbtx)
+		loadedSnapshot, height, err := getStateSnapshot(ctx, dbtx, nil)
 		if err != nil {
 			t.Fatalf("Error reading state snapshot from db: %s\n", err)
 		}
 
 		for _, lookup := range changeset.lookups {
-			if !snapshot.Tree.Contains(lookup.Bytes()) {
+			ok, err := snapshot.Tree.Contains(lookup.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
 				t.Errorf("Lookup(%s, %s) = false, want true", lookup.String(), lookup.String())
 			}
 		}
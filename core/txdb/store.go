@@ -14,6 +14,10 @@ import (
 // It satisfies the interface protocol.Store, and provides additional
 // methods for querying current data.
 type Store struct {
+	// DiskStore, if set, holds most of the state tree of
+	// snapshots loaded by LatestSnapshot on disk.
+	DiskStore *state.DiskStore
+
 	db pg.DB
 
 	cache blockCache
@@ -59,7 +63,7 @@ func (s *Store) GetBlock(ctx context.Context, height uint64) (*legacy.Block, err
 // LatestSnapshot returns the most recent state snapshot stored in
 // the database and its corresponding block height.
 func (s *Store) LatestSnapshot(ctx context.Context) (*state.Snapshot, uint64, error) {
-	return getStateSnapshot(ctx, s.db)
+	return getStateSnapshot(ctx, s.db, s.DiskStore)
 }
 
 // LatestSnapshotInfo returns the height and size of the most recent
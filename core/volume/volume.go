@@ -0,0 +1,250 @@
+// Package volume keeps rolling in-memory counts of transaction
+// volume per asset, for operational visibility without
+// aggregating over the query tables.
+//
+// Counts start from zero when the process starts, and cover
+// only blocks that land while it runs.
+package volume
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"chain/errors"
+	"chain/log"
+	"chain/math/checked"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Window is the volume of one asset over a period of time.
+type Window struct {
+	// TxCount is the number of transactions
+	// with at least one output of the asset.
+	TxCount uint64 `json:"transaction_count"`
+
+	// Amount is the total amount of the asset in those
+	// outputs. It stops increasing at the maximum uint64.
+	Amount uint64 `json:"amount"`
+}
+
+// Volume is the volume of one asset over the last minute,
+// hour and day. Each window is measured in whole buckets
+// (seconds, minutes and hours, respectively), so it
+// may extend up to one bucket further into the past.
+type Volume struct {
+	AssetID bc.AssetID `json:"asset_id"`
+	Minute  Window     `json:"last_minute"`
+	Hour    Window     `json:"last_hour"`
+	Day     Window     `json:"last_day"`
+}
+
+// Tracker counts transaction volume per asset.
+// It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	assets map[bc.AssetID]*counter
+	now    func() time.Time
+}
+
+// NewTracker returns a new, empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		assets: make(map[bc.AssetID]*counter),
+		now:    time.Now,
+	}
+}
+
+// retryDelay is how long ProcessBlocks waits to get
+// a block again after failing to get it.
+const retryDelay = 500 * time.Millisecond
+
+// ProcessBlocks records the volume of each block that lands
+// after the chain's current height. It returns when ctx is
+// canceled.
+func (t *Tracker) ProcessBlocks(ctx context.Context, c *protocol.Chain) {
+	t.processBlocks(ctx, c, c.Height()+1)
+}
+
+// processBlocks records the volume of each block
+// from height on, retrying a block it fails to get.
+func (t *Tracker) processBlocks(ctx context.Context, c *protocol.Chain, height uint64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.BlockWaiter(height):
+		}
+		b, err := c.GetBlock(ctx, height)
+		if err != nil {
+			log.Error(ctx, errors.Wrapf(err, "getting block %d for volume counts", height))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+		t.RecordBlock(b)
+		height++
+	}
+}
+
+// RecordBlock counts the transactions in b,
+// at the time of the block.
+func (t *Tracker) RecordBlock(b *legacy.Block) {
+	at := b.Time()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tx := range b.Transactions {
+		amounts := make(map[bc.AssetID]uint64)
+		for _, out := range tx.Outputs {
+			amounts[*out.AssetId] = addSat(amounts[*out.AssetId], out.Amount)
+		}
+		for assetID, amount := range amounts {
+			c := t.assets[assetID]
+			if c == nil {
+				c = newCounter()
+				t.assets[assetID] = c
+			}
+			c.add(at, amount)
+		}
+	}
+}
+
+// Volumes returns the volume of each asset with transactions
+// in the last day, ordered by asset ID. Assets with none are
+// forgotten.
+func (t *Tracker) Volumes() []Volume {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make([]Volume, 0, len(t.assets))
+	for assetID, c := range t.assets {
+		v := Volume{
+			AssetID: assetID,
+			Minute:  c.seconds.sum(now),
+			Hour:    c.minutes.sum(now),
+			Day:     c.hours.sum(now),
+		}
+		if v.Day.TxCount == 0 {
+			delete(t.assets, assetID)
+			continue
+		}
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].AssetID.String() < res[j].AssetID.String()
+	})
+	return res
+}
+
+var (
+	txCountDesc = prometheus.NewDesc(
+		"chain_asset_transactions",
+		"Transactions with outputs of the asset in the window.",
+		[]string{"asset_id", "window"}, nil,
+	)
+	amountDesc = prometheus.NewDesc(
+		"chain_asset_amount",
+		"Total amount of the asset in transaction outputs in the window.",
+		[]string{"asset_id", "window"}, nil,
+	)
+)
+
+// Describe implements prometheus.Collector.
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- txCountDesc
+	ch <- amountDesc
+}
+
+// Collect implements prometheus.Collector.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range t.Volumes() {
+		assetID := v.AssetID.String()
+		for _, w := range []struct {
+			name string
+			Window
+		}{{"1m", v.Minute}, {"1h", v.Hour}, {"24h", v.Day}} {
+			ch <- prometheus.MustNewConstMetric(txCountDesc, prometheus.GaugeValue, float64(w.TxCount), assetID, w.name)
+			ch <- prometheus.MustNewConstMetric(amountDesc, prometheus.GaugeValue, float64(w.Amount), assetID, w.name)
+		}
+	}
+}
+
+type counter struct {
+	seconds, minutes, hours *ring
+}
+
+func newCounter() *counter {
+	return &counter{
+		seconds: newRing(time.Second, 60),
+		minutes: newRing(time.Minute, 60),
+		hours:   newRing(time.Hour, 24),
+	}
+}
+
+func (c *counter) add(at time.Time, amount uint64) {
+	c.seconds.add(at, amount)
+	c.minutes.add(at, amount)
+	c.hours.add(at, amount)
+}
+
+// ring is a rolling window of fixed-width buckets.
+type ring struct {
+	width   time.Duration
+	buckets []bucket
+}
+
+type bucket struct {
+	slot int64 // start time, in units of the ring's width
+	Window
+}
+
+func newRing(width time.Duration, n int) *ring {
+	return &ring{width: width, buckets: make([]bucket, n)}
+}
+
+func (r *ring) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(r.width)
+}
+
+func (r *ring) add(at time.Time, amount uint64) {
+	slot := r.slot(at)
+	b := &r.buckets[slot%int64(len(r.buckets))]
+	if b.slot != slot {
+		if b.slot > slot {
+			return // older than the window
+		}
+		*b = bucket{slot: slot}
+	}
+	b.TxCount++
+	b.Amount = addSat(b.Amount, amount)
+}
+
+func (r *ring) sum(now time.Time) Window {
+	var w Window
+	cur := r.slot(now)
+	for _, b := range r.buckets {
+		if b.slot <= cur && b.slot > cur-int64(len(r.buckets)) {
+			w.TxCount += b.TxCount
+			w.Amount = addSat(w.Amount, b.Amount)
+		}
+	}
+	return w
+}
+
+func addSat(a, b uint64) uint64 {
+	sum, ok := checked.AddUint64(a, b)
+	if !ok {
+		return ^uint64(0)
+	}
+	return sum
+}
@@ -0,0 +1,142 @@
+package volume
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/prottest/memstore"
+)
+
+func TestTracker(t *testing.T) {
+	var (
+		asset1 = bc.AssetID(bc.NewHash([32]byte{1}))
+		asset2 = bc.AssetID(bc.NewHash([32]byte{2}))
+		start  = time.Unix(1000000, 0)
+	)
+	block := func(at time.Time, txs ...*legacy.Tx) *legacy.Block {
+		return &legacy.Block{
+			BlockHeader:  legacy.BlockHeader{TimestampMS: bc.Millis(at)},
+			Transactions: txs,
+		}
+	}
+	tx := func(outs ...*legacy.TxOutput) *legacy.Tx {
+		return legacy.NewTx(legacy.TxData{Version: 1, Outputs: outs})
+	}
+
+	tr := NewTracker()
+	tr.RecordBlock(block(start.Add(-2*time.Hour),
+		tx(legacy.NewTxOutput(asset1, 7, nil, nil)),
+	))
+	tr.RecordBlock(block(start.Add(-10*time.Minute),
+		tx(legacy.NewTxOutput(asset1, 5, nil, nil), legacy.NewTxOutput(asset1, 6, nil, nil)),
+		tx(legacy.NewTxOutput(asset2, 1, nil, nil)),
+	))
+	tr.RecordBlock(block(start.Add(-10*time.Second),
+		tx(legacy.NewTxOutput(asset1, 2, nil, nil)),
+	))
+
+	tr.now = func() time.Time { return start }
+	got := tr.Volumes()
+	want := []Volume{{
+		AssetID: asset1,
+		Minute:  Window{TxCount: 1, Amount: 2},
+		Hour:    Window{TxCount: 2, Amount: 13},
+		Day:     Window{TxCount: 3, Amount: 20},
+	}, {
+		AssetID: asset2,
+		Hour:    Window{TxCount: 1, Amount: 1},
+		Day:     Window{TxCount: 1, Amount: 1},
+	}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d volumes, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("volume %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tr)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	if strings.Join(names, ",") != "chain_asset_amount,chain_asset_transactions" {
+		t.Errorf("got metric families %v", names)
+	}
+
+	// A day later, the assets are forgotten.
+	tr.now = func() time.Time { return start.Add(25 * time.Hour) }
+	if got := tr.Volumes(); len(got) != 0 {
+		t.Errorf("got %+v, want no volumes", got)
+	}
+	if len(tr.assets) != 0 {
+		t.Errorf("tracker still holds %d assets", len(tr.assets))
+	}
+}
+
+func TestProcessBlocksRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &flakyStore{Store: memstore.New()}
+	c := prottest.NewChain(t, prottest.WithStore(store))
+	b := prottest.MakeBlock(t, c, nil)
+
+	store.mu.Lock()
+	store.fail = true
+	store.gets = 0
+	store.mu.Unlock()
+	go NewTracker().processBlocks(ctx, c, b.Height)
+
+	// The tracker gets the block again after failing to get it.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store.mu.Lock()
+		gets := store.gets
+		store.mu.Unlock()
+		if gets >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got block %d times, want at least 2", gets)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// flakyStore fails the first GetBlock
+// after fail is set.
+type flakyStore struct {
+	protocol.Store
+
+	mu   sync.Mutex
+	fail bool
+	gets int
+}
+
+func (s *flakyStore) GetBlock(ctx context.Context, height uint64) (*legacy.Block, error) {
+	s.mu.Lock()
+	s.gets++
+	fail := s.fail
+	s.fail = false
+	s.mu.Unlock()
+	if fail {
+		return nil, errors.New("flaky store")
+	}
+	return s.Store.GetBlock(ctx, height)
+}
@@ -1,25 +1,104 @@
 // Package sha3pool is a freelist for SHA3-256 hash objects.
+//
+// By default the freelist is a sync.Pool, which the garbage
+// collector empties. SetMaxIdle replaces it with a freelist of
+// fixed capacity, for sizing the pool under sustained load.
+// The pool's counters are published as the expvar "sha3pool".
 package sha3pool
 
 import (
+	"expvar"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/crypto/sha3"
 )
 
-var pool = &sync.Pool{New: func() interface{} { return sha3.New256() }}
+var pool = &sync.Pool{New: func() interface{} {
+	atomic.AddUint64(&stats.News, 1)
+	return sha3.New256()
+}}
+
+// idle, if it holds a non-nil channel, is the
+// freelist set by SetMaxIdle, used instead of pool.
+var idle atomic.Value // chan sha3.ShakeHash
+
+var stats Stats
+
+// Stats holds counts of the pool's use since the process started.
+type Stats struct {
+	// Gets and Puts count the calls to Get256 and Put256.
+	Gets uint64 `json:"gets"`
+	Puts uint64 `json:"puts"`
+
+	// News counts the hashes allocated because
+	// the freelist was empty.
+	News uint64 `json:"news"`
+
+	// Discards counts the hashes dropped by Put256
+	// because the freelist set by SetMaxIdle was full.
+	Discards uint64 `json:"discards"`
+}
+
+func init() {
+	idle.Store((chan sha3.ShakeHash)(nil))
+	expvar.Publish("sha3pool", expvar.Func(func() interface{} { return ReadStats() }))
+}
+
+// ReadStats returns the pool's counters.
+func ReadStats() Stats {
+	return Stats{
+		Gets:     atomic.LoadUint64(&stats.Gets),
+		Puts:     atomic.LoadUint64(&stats.Puts),
+		News:     atomic.LoadUint64(&stats.News),
+		Discards: atomic.LoadUint64(&stats.Discards),
+	}
+}
+
+// SetMaxIdle sets the number of unused hashes the freelist keeps
+// to n. Hashes beyond that are left to the garbage collector.
+// If n is 0, the freelist goes back to being a sync.Pool.
+// Hashes already in the freelist are dropped.
+func SetMaxIdle(n int) {
+	if n <= 0 {
+		idle.Store((chan sha3.ShakeHash)(nil))
+		return
+	}
+	idle.Store(make(chan sha3.ShakeHash, n))
+}
 
 // Get256 returns an initialized SHA3-256 hash ready to use.
 // It is like sha3.New256 except it uses the freelist.
 // The caller should call Put256 when finished with the returned object.
 func Get256() sha3.ShakeHash {
-	return pool.Get().(sha3.ShakeHash)
+	atomic.AddUint64(&stats.Gets, 1)
+	ch := idle.Load().(chan sha3.ShakeHash)
+	if ch == nil {
+		return pool.Get().(sha3.ShakeHash)
+	}
+	select {
+	case h := <-ch:
+		return h
+	default:
+		atomic.AddUint64(&stats.News, 1)
+		return sha3.New256().(sha3.ShakeHash)
+	}
 }
 
 // Put256 resets h and puts it in the freelist.
 func Put256(h sha3.ShakeHash) {
+	atomic.AddUint64(&stats.Puts, 1)
 	h.Reset()
-	pool.Put(h)
+	ch := idle.Load().(chan sha3.ShakeHash)
+	if ch == nil {
+		pool.Put(h)
+		return
+	}
+	select {
+	case ch <- h:
+	default:
+		atomic.AddUint64(&stats.Discards, 1)
+	}
 }
 
 // Sum256 uses a ShakeHash from the pool to sum into hash.
@@ -0,0 +1,50 @@
+package sha3pool
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestMaxIdle(t *testing.T) {
+	SetMaxIdle(2)
+	defer SetMaxIdle(0)
+
+	before := ReadStats()
+	var hs []sha3.ShakeHash
+	for i := 0; i < 3; i++ {
+		hs = append(hs, Get256())
+	}
+	for _, h := range hs {
+		Put256(h)
+	}
+	Get256()
+	after := ReadStats()
+
+	got := Stats{
+		Gets:     after.Gets - before.Gets,
+		Puts:     after.Puts - before.Puts,
+		News:     after.News - before.News,
+		Discards: after.Discards - before.Discards,
+	}
+	want := Stats{Gets: 4, Puts: 3, News: 3, Discards: 1}
+	if got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestSum256(t *testing.T) {
+	want := sha3.Sum256([]byte("data"))
+	for _, n := range []int{0, 1} {
+		SetMaxIdle(n)
+		for i := 0; i < 2; i++ {
+			got := make([]byte, 32)
+			Sum256(got, []byte("data"))
+			if !bytes.Equal(got, want[:]) {
+				t.Errorf("SetMaxIdle(%d): Sum256 = %x, want %x", n, got, want)
+			}
+		}
+	}
+	SetMaxIdle(0)
+}
@@ -0,0 +1,180 @@
+package pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// QueryClass identifies the kind of work a query does,
+// for choosing its statement timeout.
+type QueryClass int
+
+const (
+	// Interactive queries serve API requests.
+	// It is the class of a context with no class set.
+	Interactive QueryClass = iota
+
+	// Indexer queries ingest and index blocks.
+	Indexer
+
+	// Migration queries change the database schema.
+	Migration
+)
+
+type queryClassKey struct{}
+
+// WithQueryClass returns a context carrying class. Queries made
+// with the returned context, through a driver returned by
+// TimeoutDriver, run under the statement timeout for class.
+func WithQueryClass(ctx context.Context, class QueryClass) context.Context {
+	return context.WithValue(ctx, queryClassKey{}, class)
+}
+
+// QueryClassFromContext returns the query class carried by ctx,
+// or Interactive if there is none.
+func QueryClassFromContext(ctx context.Context) QueryClass {
+	class, _ := ctx.Value(queryClassKey{}).(QueryClass)
+	return class
+}
+
+// Timeouts holds the statement timeout for each query class.
+// A zero duration means no timeout.
+type Timeouts struct {
+	Interactive time.Duration
+	Indexer     time.Duration
+	Migration   time.Duration
+}
+
+func (t Timeouts) forClass(class QueryClass) time.Duration {
+	switch class {
+	case Indexer:
+		return t.Indexer
+	case Migration:
+		return t.Migration
+	}
+	return t.Interactive
+}
+
+// TimeoutDriver returns a Driver that sets the Postgres
+// statement_timeout of each connection according to the
+// query class of the context of each query, before
+// forwarding the query to d. This keeps a slow interactive
+// query from holding locks needed for block processing.
+//
+// A transaction runs under the timeout for the class of the
+// context passed to BeginTx, regardless of the contexts of
+// the queries in it.
+//
+// Only the context-aware methods (QueryContext, ExecContext
+// and BeginTx) apply timeouts; the deadline and cancellation
+// of the context are handled by d.
+func TimeoutDriver(d driver.Driver, t Timeouts) driver.Driver {
+	return &timeoutDriver{d, t}
+}
+
+type timeoutDriver struct {
+	driver   driver.Driver
+	timeouts Timeouts
+}
+
+func (td *timeoutDriver) Open(name string) (driver.Conn, error) {
+	c, err := td.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutConn{Conn: c, timeouts: td.timeouts, current: -1}, nil
+}
+
+type timeoutConn struct {
+	driver.Conn
+	timeouts Timeouts
+
+	// current is the statement timeout in effect for the
+	// session, or -1 if it is not known.
+	current time.Duration
+	inTx    bool
+}
+
+// setTimeout sets the session's statement timeout for the class of
+// ctx, if it isn't already in effect. Inside a transaction it does
+// nothing, so a rollback can't undo a setting we've recorded.
+func (tc *timeoutConn) setTimeout(ctx context.Context) error {
+	if tc.inTx {
+		return nil
+	}
+	timeout := tc.timeouts.forClass(QueryClassFromContext(ctx))
+	if timeout == tc.current {
+		return nil
+	}
+	execer, ok := tc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil
+	}
+	q := fmt.Sprintf("SET statement_timeout = %d", timeout/time.Millisecond)
+	_, err := execer.ExecContext(ctx, q, nil)
+	if err != nil {
+		tc.current = -1
+		return err
+	}
+	tc.current = timeout
+	return nil
+}
+
+func (tc *timeoutConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := tc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err := tc.setTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (tc *timeoutConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := tc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err := tc.setTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (tc *timeoutConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	err := tc.setTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tx driver.Tx
+	if beginner, ok := tc.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = tc.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	tc.inTx = true
+	return &timeoutTx{tx, tc}, nil
+}
+
+type timeoutTx struct {
+	driver.Tx
+	conn *timeoutConn
+}
+
+func (tt *timeoutTx) Commit() error {
+	tt.conn.inTx = false
+	return tt.Tx.Commit()
+}
+
+func (tt *timeoutTx) Rollback() error {
+	tt.conn.inTx = false
+	return tt.Tx.Rollback()
+}
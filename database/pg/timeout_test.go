@@ -0,0 +1,86 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeoutDriver(t *testing.T) {
+	fd := &fakeDriver{}
+	sql.Register("pg-timeout-test", TimeoutDriver(fd, Timeouts{
+		Interactive: 2 * time.Second,
+		Indexer:     time.Minute,
+	}))
+	db, err := sql.Open("pg-timeout-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	indexCtx := WithQueryClass(ctx, Indexer)
+	exec := func(ctx context.Context, q string) {
+		_, err := db.ExecContext(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec(ctx, "a")
+	exec(ctx, "b")
+	exec(indexCtx, "c")
+
+	tx, err := db.BeginTx(WithQueryClass(ctx, Migration), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.ExecContext(ctx, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(indexCtx, "e")
+
+	want := []string{
+		"SET statement_timeout = 2000", "a", "b",
+		"SET statement_timeout = 60000", "c",
+		"SET statement_timeout = 0", "d",
+		"SET statement_timeout = 60000", "e",
+	}
+	if !reflect.DeepEqual(fd.execs, want) {
+		t.Errorf("got statements %q, want %q", fd.execs, want)
+	}
+}
+
+type fakeDriver struct {
+	execs []string
+}
+
+func (fd *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{fd}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (fc *fakeConn) ExecContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	fc.driver.execs = append(fc.driver.execs, q)
+	return driver.RowsAffected(0), nil
+}
+
+func (fc *fakeConn) Begin() (driver.Tx, error)                         { return fakeTx{}, nil }
+func (fc *fakeConn) Close() error                                      { return nil }
+func (fc *fakeConn) Prepare(string) (driver.Stmt, error)               { panic("unexpected Prepare") }
+func (fc *fakeConn) Query(string, []driver.Value) (driver.Rows, error) { panic("unexpected Query") }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
@@ -0,0 +1,31 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	"chain/errors"
+)
+
+// RunInTx calls f with a transaction begun on db. It commits
+// the transaction if f returns nil, and rolls it back otherwise.
+// If db can't begin a transaction, because it is already one,
+// such as the one pgtest.NewTx returns, f runs directly on db.
+func RunInTx(ctx context.Context, db DB, f func(DB) error) error {
+	beginner, ok := db.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return f(db)
+	}
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "begin transaction")
+	}
+	err = f(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "commit transaction")
+}
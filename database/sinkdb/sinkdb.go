@@ -121,6 +121,12 @@ func (db *DB) GetStale(key string, v proto.Message) (Version, error) {
 	return ver, proto.Unmarshal(buf, v)
 }
 
+// NumMembers returns the number of nodes in the cluster,
+// as of the latest update this node has applied.
+func (db *DB) NumMembers() int {
+	return len(db.state.Peers())
+}
+
 // RaftService returns the raft service used for replication.
 func (db *DB) RaftService() *raft.Service {
 	return db.raft
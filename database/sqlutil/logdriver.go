@@ -23,6 +23,14 @@ func logQuery(ctx context.Context, query string, args interface{}) {
 	log.Printkv(ctx, "query", query, "args", s)
 }
 
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
 type logDriver struct {
 	driver driver.Driver
 }
@@ -65,11 +73,30 @@ func (lc *logConn) Query(query string, args []driver.Value) (driver.Rows, error)
 	return queryer.Query(query, args)
 }
 
-// TODO(kr): implement context variants
-// (but don't bother until lib/pq does first).
-//func (lc *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error)
-//func (lc *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error)
-//func (lc *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
+func (lc *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := lc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	logQuery(ctx, query, values(args))
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (lc *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := lc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	logQuery(ctx, query, values(args))
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (lc *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := lc.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return lc.Conn.Begin()
+}
 
 type logStmt struct {
 	query string
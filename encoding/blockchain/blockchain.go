@@ -82,10 +82,19 @@ func ReadVarint63(r *Reader) (uint64, error) {
 }
 
 func ReadVarstr31(r *Reader) ([]byte, error) {
+	return ReadVarstr31Max(r, math.MaxInt32)
+}
+
+// ReadVarstr31Max is like ReadVarstr31, but returns ErrRange
+// if the length prefix is greater than max.
+func ReadVarstr31Max(r *Reader, max int) ([]byte, error) {
 	l, err := ReadVarint31(r)
 	if err != nil {
 		return nil, err
 	}
+	if int(l) > max {
+		return nil, ErrRange
+	}
 	if l == 0 {
 		return nil, nil
 	}
@@ -123,7 +132,13 @@ func ReadVarstrList(r *Reader) (result [][]byte, err error) {
 // bytes from r. It then calls the given function to consume those
 // bytes, returning any unconsumed suffix.
 func ReadExtensibleString(r *Reader, f func(*Reader) error) (suffix []byte, err error) {
-	s, err := ReadVarstr31(r)
+	return ReadExtensibleStringMax(r, math.MaxInt32, f)
+}
+
+// ReadExtensibleStringMax is like ReadExtensibleString, but
+// returns ErrRange if the length prefix is greater than max.
+func ReadExtensibleStringMax(r *Reader, max int, f func(*Reader) error) (suffix []byte, err error) {
+	s, err := ReadVarstr31Max(r, max)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +173,44 @@ func WriteVarint63(w io.Writer, val uint64) (int, error) {
 	return b, err
 }
 
+// AppendVarint31 appends the varint31 encoding of val to dst
+// and returns the extended slice. Unlike WriteVarint31, it
+// needs no temporary buffer.
+func AppendVarint31(dst []byte, val uint64) ([]byte, error) {
+	if val > math.MaxInt32 {
+		return dst, ErrRange
+	}
+	return appendUvarint(dst, val), nil
+}
+
+// AppendVarint63 appends the varint63 encoding of val to dst
+// and returns the extended slice. Unlike WriteVarint63, it
+// needs no temporary buffer.
+func AppendVarint63(dst []byte, val uint64) ([]byte, error) {
+	if val > math.MaxInt64 {
+		return dst, ErrRange
+	}
+	return appendUvarint(dst, val), nil
+}
+
+// AppendVarstr31 appends str to dst with a varint31 length
+// prefix and returns the extended slice.
+func AppendVarstr31(dst []byte, str []byte) ([]byte, error) {
+	dst, err := AppendVarint31(dst, uint64(len(str)))
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, str...), nil
+}
+
+func appendUvarint(dst []byte, val uint64) []byte {
+	for val >= 0x80 {
+		dst = append(dst, byte(val)|0x80)
+		val >>= 7
+	}
+	return append(dst, byte(val))
+}
+
 func WriteVarstr31(w io.Writer, str []byte) (int, error) {
 	n, err := WriteVarint31(w, uint64(len(str)))
 	if err != nil {
@@ -44,6 +44,14 @@ func BenchmarkWriteVarint63(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendVarint63(b *testing.B) {
+	n := uint64(math.MaxInt64)
+	var buf [9]byte
+	for i := 0; i < b.N; i++ {
+		AppendVarint63(buf[:0], n)
+	}
+}
+
 func TestVarint31(t *testing.T) {
 	cases := []struct {
 		n       uint64
@@ -197,6 +205,27 @@ func TestTooLongVarstring31(t *testing.T) {
 	}
 }
 
+func TestVarstring31Max(t *testing.T) {
+	s := []byte{10, 11, 12}
+	b := new(bytes.Buffer)
+	_, err := WriteVarstr31(b, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadVarstr31Max(NewReader(b.Bytes()), 2)
+	if err != ErrRange {
+		t.Errorf("ReadVarstr31Max(2) err = %v, want %v", err, ErrRange)
+	}
+	got, err := ReadVarstr31Max(NewReader(b.Bytes()), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, s) {
+		t.Errorf("ReadVarstr31Max(3) = %x, want %x", got, s)
+	}
+}
+
 func TestVarstrList(t *testing.T) {
 	for i := 0; i < 4; i++ {
 		// make a list of i+1 strs, each with length i+1, each made of repeating byte i
@@ -344,6 +373,43 @@ func TestReadWriteVarint63(t *testing.T) {
 	}
 }
 
+func TestAppendVarint(t *testing.T) {
+	f := func(x uint64) bool {
+		var buf bytes.Buffer
+		_, werr := WriteVarint63(&buf, x)
+		got, aerr := AppendVarint63([]byte{0xff}, x)
+		if werr != aerr {
+			return false
+		}
+		if aerr != nil {
+			return len(got) == 1
+		}
+		if !bytes.Equal(got[1:], buf.Bytes()) {
+			return false
+		}
+
+		buf.Reset()
+		_, werr = WriteVarint31(&buf, x)
+		got, aerr = AppendVarint31(nil, x)
+		return werr == aerr && bytes.Equal(got, buf.Bytes())
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAppendVarstr31(t *testing.T) {
+	str := []byte("hello")
+	got, err := AppendVarstr31([]byte{0xff}, str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xff, 0x05, 'h', 'e', 'l', 'l', 'o'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("AppendVarstr31 = %x, want %x", got, want)
+	}
+}
+
 func TestReadWriteVarstr31(t *testing.T) {
 	f := func(x []byte) bool {
 		var buf bytes.Buffer
@@ -1,12 +1,47 @@
 // Package bufpool is a freelist for bytes.Buffer objects.
+//
+// Buffers are kept in two tiers by capacity. Get returns
+// small buffers, for the many short encodings of hashes,
+// headers and extensible strings; GetLarge returns ones
+// that have held a block or other large encoding. A caller
+// asking for a small buffer then doesn't get one grown to
+// hold a block, and one encoding a block doesn't start
+// small and regrow its buffer every time.
+//
+// Buffers that have grown beyond the limit set by
+// SetMaxRetained are not kept at all, so that one huge
+// block doesn't pin its buffer's memory.
 package bufpool
 
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
 )
 
-var pool = &sync.Pool{New: func() interface{} { return bytes.NewBuffer(nil) }}
+// smallMax is the smallest capacity of a buffer kept in
+// the large tier. GetLarge allocates buffers of this
+// capacity, so they go back to the large tier unused.
+const smallMax = 64 << 10
+
+var (
+	small = &sync.Pool{New: func() interface{} { return bytes.NewBuffer(nil) }}
+	large = &sync.Pool{New: func() interface{} { return bytes.NewBuffer(make([]byte, 0, smallMax)) }}
+)
+
+// DefaultMaxRetained is the largest capacity
+// of a buffer Put keeps, unless SetMaxRetained
+// says otherwise.
+const DefaultMaxRetained = 16 << 20
+
+var maxRetained int64 = DefaultMaxRetained
+
+// SetMaxRetained sets the largest capacity of a buffer
+// Put keeps in the freelist to n bytes. Larger buffers
+// are left to the garbage collector.
+func SetMaxRetained(n int) {
+	atomic.StoreInt64(&maxRetained, int64(n))
+}
 
 // Get returns an initialized bytes.Buffer object.
 // It is like new(bytes.Buffer) except it uses the free list.
@@ -15,13 +50,30 @@ var pool = &sync.Pool{New: func() interface{} { return bytes.NewBuffer(nil) }}
 // it is not safe for that slice to escape the caller.
 // If the bytes need to escape, CopyBytes should be used.
 func Get() *bytes.Buffer {
-	return pool.Get().(*bytes.Buffer)
+	return small.Get().(*bytes.Buffer)
+}
+
+// GetLarge is like Get, but returns a buffer from the
+// large tier, for an encoding expected to need more than
+// a few kilobytes, such as a block.
+func GetLarge() *bytes.Buffer {
+	return large.Get().(*bytes.Buffer)
 }
 
-// Put resets the buffer and adds it to the freelist.
+// Put resets the buffer and adds it to the freelist tier
+// for its capacity, unless its capacity exceeds the limit
+// set by SetMaxRetained.
 func Put(b *bytes.Buffer) {
+	c := int64(b.Cap())
+	if c > atomic.LoadInt64(&maxRetained) {
+		return
+	}
 	b.Reset()
-	pool.Put(b)
+	if c >= smallMax {
+		large.Put(b)
+	} else {
+		small.Put(b)
+	}
 }
 
 // CopyBytes returns a copy of the bytes contained in the buffer.
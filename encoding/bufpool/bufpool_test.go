@@ -0,0 +1,56 @@
+package bufpool
+
+import "testing"
+
+func TestPutMaxRetained(t *testing.T) {
+	SetMaxRetained(1 << 10)
+	defer SetMaxRetained(DefaultMaxRetained)
+
+	// A buffer grown past the limit must
+	// not come back from Get.
+	b := Get()
+	b.Write(make([]byte, 1<<20))
+	Put(b)
+	got := Get()
+	if got.Cap() >= 1<<20 {
+		t.Errorf("Get().Cap() = %d after Put of a large buffer", got.Cap())
+	}
+	if got.Len() != 0 {
+		t.Errorf("Get().Len() = %d, want 0", got.Len())
+	}
+	Put(got)
+}
+
+func TestPutTiers(t *testing.T) {
+	// A buffer grown past the small tier must
+	// not come back from Get.
+	b := Get()
+	b.Write(make([]byte, 2*smallMax))
+	Put(b)
+	got := Get()
+	if got.Cap() >= smallMax {
+		t.Errorf("Get().Cap() = %d after Put of a large buffer, want less than %d", got.Cap(), smallMax)
+	}
+	Put(got)
+
+	got = GetLarge()
+	if got.Cap() < smallMax {
+		t.Errorf("GetLarge().Cap() = %d, want at least %d", got.Cap(), smallMax)
+	}
+	if got.Len() != 0 {
+		t.Errorf("GetLarge().Len() = %d, want 0", got.Len())
+	}
+	Put(got)
+}
+
+func TestPutLargeRoundTrip(t *testing.T) {
+	// An unused buffer from GetLarge must go back
+	// to the large tier, not come back from Get.
+	b := GetLarge()
+	Put(b)
+	got := Get()
+	if got == b || got.Cap() >= smallMax {
+		t.Errorf("Get().Cap() = %d after Put of an unused GetLarge buffer, want less than %d", got.Cap(), smallMax)
+	}
+	Put(got)
+}
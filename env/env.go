@@ -12,7 +12,25 @@ import (
 	"time"
 )
 
-var funcs []func() bool
+// A variable is an env var registered with one of the Var functions.
+type variable struct {
+	name   string
+	secret bool
+
+	// set parses s and assigns the
+	// result to the variable.
+	set func(s string) error
+
+	// format formats the
+	// variable's current value.
+	format func() string
+}
+
+var vars []*variable
+
+func register(v *variable) {
+	vars = append(vars, v)
+}
 
 // Int returns a new int pointer.
 // When Parse is called,
@@ -31,16 +49,17 @@ func Int(name string, value int) *int {
 // value of the environment var.
 func IntVar(p *int, name string, value int) {
 	*p = value
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
+	register(&variable{
+		name: name,
+		set: func(s string) error {
 			v, err := strconv.Atoi(s)
 			if err != nil {
-				log.Println(name, err)
-				return false
+				return err
 			}
 			*p = v
-		}
-		return true
+			return nil
+		},
+		format: func() string { return strconv.Itoa(*p) },
 	})
 }
 
@@ -62,16 +81,17 @@ func Bool(name string, value bool) *bool {
 // of the environment variable.
 func BoolVar(p *bool, name string, value bool) {
 	*p = value
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
+	register(&variable{
+		name: name,
+		set: func(s string) error {
 			v, err := strconv.ParseBool(s)
 			if err != nil {
-				log.Println(name, err)
-				return false
+				return err
 			}
 			*p = v
-		}
-		return true
+			return nil
+		},
+		format: func() string { return strconv.FormatBool(*p) },
 	})
 }
 
@@ -93,16 +113,17 @@ func Duration(name string, value time.Duration) *time.Duration {
 // variable.
 func DurationVar(p *time.Duration, name string, value time.Duration) {
 	*p = value
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
+	register(&variable{
+		name: name,
+		set: func(s string) error {
 			v, err := time.ParseDuration(s)
 			if err != nil {
-				log.Println(name, err)
-				return false
+				return err
 			}
 			*p = v
-		}
-		return true
+			return nil
+		},
+		format: func() string { return p.String() },
 	})
 }
 
@@ -130,16 +151,17 @@ func URLVar(p *url.URL, name string, value string) {
 		panic(err)
 	}
 	*p = *v
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
+	register(&variable{
+		name: name,
+		set: func(s string) error {
 			v, err := url.Parse(s)
 			if err != nil {
-				log.Println(name, err)
-				return false
+				return err
 			}
 			*p = *v
-		}
-		return true
+			return nil
+		},
+		format: func() string { return p.String() },
 	})
 }
 
@@ -159,12 +181,28 @@ func String(name string, value string) *string {
 // which to store the value of the environment
 // var.
 func StringVar(p *string, name string, value string) {
+	stringVar(p, name, value, false)
+}
+
+// SecretString is like String, but Values
+// redacts the value of env var name.
+// Use it for passphrases, keys and tokens.
+func SecretString(name string, value string) *string {
+	p := new(string)
+	stringVar(p, name, value, true)
+	return p
+}
+
+func stringVar(p *string, name string, value string, secret bool) {
 	*p = value
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
+	register(&variable{
+		name:   name,
+		secret: secret,
+		set: func(s string) error {
 			*p = s
-		}
-		return true
+			return nil
+		},
+		format: func() string { return *p },
 	})
 }
 
@@ -186,25 +224,37 @@ func StringSlice(name string, value ...string) *[]string {
 // to store the value of the environment var.
 func StringSliceVar(p *[]string, name string, value ...string) {
 	*p = value
-	funcs = append(funcs, func() bool {
-		if s := os.Getenv(name); s != "" {
-			a := strings.Split(s, ",")
-			*p = a
-		}
-		return true
+	register(&variable{
+		name: name,
+		set: func(s string) error {
+			*p = strings.Split(s, ",")
+			return nil
+		},
+		format: func() string { return strings.Join(*p, ",") },
 	})
 }
 
 // Parse parses known env vars
 // and assigns the values to the variables
 // that were previously registered.
+// A var missing from the environment
+// takes its value from the file
+// loaded with Load, if any.
 // If any values cannot be parsed,
 // Parse prints an error message for each one
 // and exits the process with status 1.
 func Parse() {
 	ok := true
-	for _, f := range funcs {
-		ok = f() && ok
+	for _, v := range vars {
+		s, _ := lookup(v.name)
+		if s == "" {
+			continue
+		}
+		err := v.set(s)
+		if err != nil {
+			log.Println(v.name, err)
+			ok = false
+		}
 	}
 	if !ok {
 		os.Exit(1)
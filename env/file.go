@@ -0,0 +1,190 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// file holds the values loaded with Load,
+// keyed by env var name.
+var file map[string]string
+
+// Load sets values for registered env vars from values, typically
+// decoded from a JSON config file, where each key is the name of
+// an env var in lower case, such as "database_url" for
+// DATABASE_URL. Each value must be a string, number or boolean,
+// or a list of strings, which is joined with commas.
+//
+// Values from the environment take precedence over those loaded.
+// Load must be called after the vars are registered and before
+// Parse. It returns an error describing every unknown key and
+// every value that can't be parsed.
+func Load(values map[string]interface{}) error {
+	byName := make(map[string]*variable)
+	for _, v := range vars {
+		byName[v.name] = v
+	}
+
+	var (
+		keys     []string
+		problems []string
+		loaded   = make(map[string]string)
+	)
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ToUpper(key)
+		v, ok := byName[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown setting %q", key))
+			continue
+		}
+		if key != strings.ToLower(key) {
+			problems = append(problems, fmt.Sprintf("setting %q must be written %q", key, strings.ToLower(key)))
+			continue
+		}
+		s, err := formatValue(values[key])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+			continue
+		}
+		if s != "" {
+			err = v.set(s)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+				continue
+			}
+		}
+		loaded[name] = s
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	file = loaded
+	return nil
+}
+
+func formatValue(x interface{}) (string, error) {
+	switch x := x.(type) {
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case []interface{}:
+		var a []string
+		for _, elem := range x {
+			s, ok := elem.(string)
+			if !ok {
+				return "", errors.New("want a list of strings")
+			}
+			a = append(a, s)
+		}
+		return strings.Join(a, ","), nil
+	}
+	return "", fmt.Errorf("want a string, number, boolean or list of strings, got %v", x)
+}
+
+// lookup returns the value of env var name,
+// and where it came from: "environment",
+// "file", or "default" if it isn't set.
+func lookup(name string) (string, string) {
+	if s := os.Getenv(name); s != "" {
+		return s, "environment"
+	}
+	if s := file[name]; s != "" {
+		return s, "file"
+	}
+	return "", "default"
+}
+
+// A Value is the effective value of a registered env var.
+type Value struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// redacted replaces secret values in Values.
+const redacted = "redacted"
+
+// Values returns the value of each registered env var, sorted by
+// name, for reporting a process's effective configuration. The
+// values of vars registered with SecretString are redacted, as
+// are passwords in URLs. It should be called after Parse.
+func Values() []Value {
+	byName := make(map[string]*variable)
+	for _, v := range vars {
+		byName[v.name] = v // the last registration wins, as in Parse
+	}
+	var values []Value
+	for name, v := range byName {
+		_, source := lookup(name)
+		s := v.format()
+		if v.secret && s != "" {
+			s = redacted
+		} else {
+			s = redactPassword(s)
+		}
+		values = append(values, Value{Name: name, Value: s, Source: source})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// redactPassword redacts the password in s, if s is a database
+// URL or a key=value connection string, such as
+// "host=db user=core password=secret", with one.
+func redactPassword(s string) string {
+	if !strings.Contains(s, "://") {
+		return dsnPassword.ReplaceAllString(s, "${1}password="+redacted)
+	}
+	return redactURL(s)
+}
+
+// dsnPassword matches the password setting in a key=value
+// connection string, whose value may be single-quoted, with
+// backslash escapes, as in lib/pq.
+var dsnPassword = regexp.MustCompile(`(^|\s)password\s*=\s*('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactURL redacts the password in s, if s is a URL with one
+// in its user info or its query, as in a database URL.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	changed := false
+	if u.User != nil {
+		_, ok := u.User.Password()
+		if ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			changed = true
+		}
+	}
+	q := u.Query()
+	if q.Get("password") != "" {
+		q.Set("password", redacted)
+		u.RawQuery = q.Encode()
+		changed = true
+	}
+	if !changed {
+		return s
+	}
+	return u.String()
+}
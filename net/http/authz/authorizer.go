@@ -29,44 +29,54 @@ func NewAuthorizer(l Loader, policyMap map[string][]string) *Authorizer {
 	}
 }
 
-func (a *Authorizer) Authorize(req *http.Request) error {
+// Authorize checks that req satisfies a grant for one of the
+// policies of its route. It returns req with a context that
+// holds every such policy req satisfies; see Policies.
+func (a *Authorizer) Authorize(req *http.Request) (*http.Request, error) {
 	policies, err := a.policiesByRoute(req.RequestURI)
 	if err != nil {
-		return errors.Wrap(err)
+		return req, errors.Wrap(err)
 	}
 
 	grants, err := a.loader.Load(req.Context(), policies)
 	if err != nil {
-		return errors.Wrap(err)
+		return req, errors.Wrap(err)
 	}
 
-	if !authorized(req.Context(), grants) {
-		return ErrNotAuthorized
+	granted := authorized(req.Context(), grants)
+	if len(granted) == 0 {
+		return req, ErrNotAuthorized
 	}
 
-	return nil
+	return req.WithContext(NewContext(req.Context(), granted)), nil
 }
 
-func authorized(ctx context.Context, grants []*Grant) bool {
+// authorized returns the policies of the grants
+// the request with context ctx satisfies.
+func authorized(ctx context.Context, grants []*Grant) (policies []string) {
+	seen := make(map[string]bool)
 	for _, g := range grants {
-		switch g.GuardType {
-		case "access_token":
-			if accessTokenGuardData(g) == authn.Token(ctx) {
-				return true
-			}
-		case "x509":
-			pattern := x509GuardData(g.GuardData)
-			certs := authn.X509Certs(ctx)
-			if len(certs) > 0 && matchesX509(pattern, certs[0].Subject) {
-				return true
-			}
-		case "localhost":
-			if authn.Localhost(ctx) {
-				return true
-			}
-		case "any":
-			return true
+		if seen[g.Policy] || !satisfies(ctx, g) {
+			continue
 		}
+		seen[g.Policy] = true
+		policies = append(policies, g.Policy)
+	}
+	return policies
+}
+
+func satisfies(ctx context.Context, g *Grant) bool {
+	switch g.GuardType {
+	case "access_token":
+		return accessTokenGuardData(g) == authn.Token(ctx)
+	case "x509":
+		pattern := x509GuardData(g.GuardData)
+		certs := authn.X509Certs(ctx)
+		return len(certs) > 0 && matchesX509(pattern, certs[0].Subject)
+	case "localhost":
+		return authn.Localhost(ctx)
+	case "any":
+		return true
 	}
 	return false
 }
@@ -0,0 +1,31 @@
+package authz
+
+import "context"
+
+type key int
+
+const policiesKey key = iota
+
+// NewContext returns a copy of ctx carrying policies,
+// the policies an authorized request was granted.
+func NewContext(ctx context.Context, policies []string) context.Context {
+	return context.WithValue(ctx, policiesKey, policies)
+}
+
+// Policies returns the policies stored in the context
+// by Authorize, if there are any.
+func Policies(ctx context.Context) []string {
+	p, _ := ctx.Value(policiesKey).([]string)
+	return p
+}
+
+// HasPolicy reports whether policy is
+// among the policies stored in ctx.
+func HasPolicy(ctx context.Context, policy string) bool {
+	for _, p := range Policies(ctx) {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,7 @@ import (
 	"chain/errors"
 	"chain/log"
 	"chain/net/http/httpjson"
+	"chain/net/http/reqid"
 )
 
 func init() {
@@ -31,6 +32,10 @@ type Response struct {
 	Detail    string                 `json:"detail,omitempty"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Temporary bool                   `json:"temporary"`
+
+	// RequestID is the ID of the request that failed,
+	// for matching the response with the server's logs.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Parse reads an error Response from the provided reader.
@@ -60,7 +65,7 @@ func (f Formatter) Format(err error) (body Response) {
 	// Just treat it like any other missing entry.
 	defer func() {
 		if err := recover(); err != nil {
-			body = Response{f.Default, "", nil, true}
+			body = Response{Info: f.Default, Temporary: true}
 		}
 	}()
 	info, ok := f.Errors[root]
@@ -84,6 +89,7 @@ func (f Formatter) Format(err error) (body Response) {
 func (f Formatter) Write(ctx context.Context, w http.ResponseWriter, err error) {
 	f.Log(ctx, err)
 	resp := f.Format(err)
+	resp.RequestID = reqid.FromContext(ctx)
 	httpjson.Write(ctx, w, resp.HTTPStatus, resp)
 }
 
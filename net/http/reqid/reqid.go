@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"strings"
 
 	"chain/log"
 )
@@ -75,11 +76,45 @@ func FromSubContext(ctx context.Context) string {
 	return subReqID
 }
 
+// Header is the HTTP header carrying a request ID. Handler
+// uses the ID in an incoming request's header, if it's valid,
+// so a request can be traced from a client, or across Cores.
+const Header = "X-Request-ID"
+
+// maxLen is the length of the longest
+// request ID Handler accepts from a client.
+const maxLen = 128
+
+// Valid reports whether id is acceptable as a request ID
+// from a client: non-empty, at most 128 bytes, and made of
+// letters, digits, and the punctuation "-_.:/+=".
+// This keeps IDs safe to put in log lines.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.ContainsRune("-_.:/+=", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Handler gives each request a request ID, stored in its
+// Context, and sends it back in the response's headers.
+// The ID is the one in the request's X-Request-ID header
+// if that is valid, or a new one otherwise.
 func Handler(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
-		// TODO(kr): take half of request ID from the client
-		id := New()
+		id := req.Header.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
 		ctx = NewContext(ctx, id)
 
 		defer func() {
@@ -92,6 +127,7 @@ func Handler(handler http.Handler) http.Handler {
 			}
 		}()
 		w.Header().Add("Chain-Request-Id", id)
+		w.Header().Set(Header, id)
 		handler.ServeHTTP(w, req.WithContext(ctx))
 	})
 }
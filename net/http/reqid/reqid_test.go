@@ -3,6 +3,8 @@ package reqid
 import (
 	"bytes"
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -23,3 +25,36 @@ func TestPrintkvRequestID(t *testing.T) {
 		t.Errorf("Result did not contain string:\ngot:  %s\nwant: %s", got, want)
 	}
 }
+
+func TestHandlerInboundRequestID(t *testing.T) {
+	cases := []struct {
+		header   string
+		wantSame bool
+	}{
+		{"", false},
+		{"client-trace-1234", true},
+		{"0af7651916cd43dd8448eb211c80319c", true},
+		{"has space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", maxLen+1), false},
+	}
+	for _, c := range cases {
+		var got string
+		h := Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got = FromContext(req.Context())
+		}))
+		req := httptest.NewRequest("POST", "/", nil)
+		if c.header != "" {
+			req.Header.Set(Header, c.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if got == "" || (got == c.header) != c.wantSame {
+			t.Errorf("header %q: request ID = %q, want same = %v", c.header, got, c.wantSame)
+		}
+		if w.Header().Get(Header) != got {
+			t.Errorf("header %q: response %s = %q, want %q", c.header, Header, w.Header().Get(Header), got)
+		}
+	}
+}
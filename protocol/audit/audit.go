@@ -0,0 +1,182 @@
+// Package audit builds and verifies audit bundles: self-contained
+// proofs that a range of blocks was signed by the federation and
+// that selected transactions were included in them.
+//
+// Verifying a bundle needs only the bundle itself and a trusted
+// starting point, so it can be done offline, without access to a
+// Chain Core.
+package audit
+
+import (
+	"bytes"
+
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+)
+
+// ErrBadBundle is returned by Verify when a bundle
+// does not prove what it claims to.
+var ErrBadBundle = errors.New("invalid audit bundle")
+
+// Bundle is an audit bundle for the blocks from StartHeight
+// through EndHeight, inclusive.
+type Bundle struct {
+	StartHeight uint64 `json:"start_height"`
+	EndHeight   uint64 `json:"end_height"`
+
+	// Headers holds the header of each block in the range, in
+	// order, including the signatures in its witness.
+	Headers []*legacy.BlockHeader `json:"headers"`
+
+	// ConsensusPrograms lists each block in the range whose
+	// consensus program differs from that of its predecessor,
+	// starting with the first block.
+	ConsensusPrograms []ProgramChange `json:"consensus_programs"`
+
+	// Proofs holds a merkle path for each selected transaction.
+	Proofs []TxProof `json:"proofs"`
+}
+
+// ProgramChange records the consensus program
+// set by the block at Height.
+type ProgramChange struct {
+	Height  uint64        `json:"height"`
+	Program json.HexBytes `json:"program"`
+}
+
+// TxProof proves that the transaction TxID is at position
+// Index among the Count transactions of the block at Height.
+type TxProof struct {
+	TxID   bc.Hash   `json:"transaction_id"`
+	Height uint64    `json:"height"`
+	Index  int       `json:"index"`
+	Count  int       `json:"count"`
+	Path   []bc.Hash `json:"path"`
+}
+
+// NewBundle builds an audit bundle from the blocks of a
+// contiguous range, proving the inclusion of each of txIDs
+// that appears in them. It returns an error if any of txIDs
+// is not found.
+func NewBundle(blocks []*legacy.Block, txIDs []bc.Hash) (*Bundle, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks")
+	}
+
+	want := make(map[bc.Hash]bool, len(txIDs))
+	for _, id := range txIDs {
+		want[id] = true
+	}
+
+	b := &Bundle{
+		StartHeight: blocks[0].Height,
+		EndHeight:   blocks[len(blocks)-1].Height,
+	}
+	for i, block := range blocks {
+		header := block.BlockHeader
+		b.Headers = append(b.Headers, &header)
+		if i == 0 || !bytes.Equal(block.ConsensusProgram, blocks[i-1].ConsensusProgram) {
+			b.ConsensusPrograms = append(b.ConsensusPrograms, ProgramChange{
+				Height:  block.Height,
+				Program: block.ConsensusProgram,
+			})
+		}
+
+		ids := make([]bc.Hash, len(block.Transactions))
+		for j, tx := range block.Transactions {
+			ids[j] = tx.ID
+		}
+		for j, id := range ids {
+			if !want[id] {
+				continue
+			}
+			delete(want, id)
+			b.Proofs = append(b.Proofs, TxProof{
+				TxID:   id,
+				Height: block.Height,
+				Index:  j,
+				Count:  len(ids),
+				Path:   bc.MerklePath(ids, j),
+			})
+		}
+	}
+	for _, id := range txIDs {
+		if want[id] {
+			return nil, errors.WithDetailf(ErrBadBundle, "transaction %x not found in blocks %d-%d", id.Bytes(), b.StartHeight, b.EndHeight)
+		}
+	}
+	return b, nil
+}
+
+// Verify checks that b is internally consistent: that its
+// headers form a valid chain, that each header is signed according
+// to the consensus program of its predecessor, that its list
+// of consensus programs matches its headers, and that each
+// transaction proof leads to the transactions merkle root of
+// its block.
+//
+// The signatures on the first header are checked against
+// trustedProgram, the consensus program set by the block
+// before StartHeight, which the caller must obtain from a
+// trusted source. If StartHeight is 1, trustedProgram is
+// ignored; the initial block is unsigned, so the caller
+// should instead check the hash of Headers[0] against the
+// blockchain ID.
+func Verify(b *Bundle, trustedProgram []byte) error {
+	if len(b.Headers) == 0 || b.EndHeight < b.StartHeight || uint64(len(b.Headers)) != b.EndHeight-b.StartHeight+1 {
+		return errors.WithDetailf(ErrBadBundle, "got %d headers for blocks %d-%d", len(b.Headers), b.StartHeight, b.EndHeight)
+	}
+
+	if b.Headers[0].Height != b.StartHeight {
+		return errors.WithDetailf(ErrBadBundle, "first header has height %d, want %d", b.Headers[0].Height, b.StartHeight)
+	}
+
+	var (
+		prog     = trustedProgram
+		prev     *bc.Block
+		programs []ProgramChange
+	)
+	for i, h := range b.Headers {
+		height := b.StartHeight + uint64(i)
+		block := legacy.MapBlock(&legacy.Block{BlockHeader: *h})
+		err := validation.ValidateBlockHeader(block, prev)
+		if err != nil {
+			return errors.Sub(ErrBadBundle, errors.Wrapf(err, "block %d", height))
+		}
+		if height > 1 {
+			err = validation.ValidateBlockSig(block, prog)
+			if err != nil {
+				return errors.Sub(ErrBadBundle, errors.Wrapf(err, "block %d", height))
+			}
+		}
+		if i == 0 || !bytes.Equal(h.ConsensusProgram, prog) {
+			programs = append(programs, ProgramChange{Height: height, Program: h.ConsensusProgram})
+		}
+		prog = h.ConsensusProgram
+		prev = block
+	}
+
+	if len(programs) != len(b.ConsensusPrograms) {
+		return errors.WithDetailf(ErrBadBundle, "got %d consensus program changes, headers show %d", len(b.ConsensusPrograms), len(programs))
+	}
+	for i, p := range programs {
+		got := b.ConsensusPrograms[i]
+		if got.Height != p.Height || !bytes.Equal(got.Program, p.Program) {
+			return errors.WithDetailf(ErrBadBundle, "consensus program change %d disagrees with block %d", i, p.Height)
+		}
+	}
+
+	for _, p := range b.Proofs {
+		if p.Height < b.StartHeight || p.Height > b.EndHeight {
+			return errors.WithDetailf(ErrBadBundle, "proof for transaction %x is outside blocks %d-%d", p.TxID.Bytes(), b.StartHeight, b.EndHeight)
+		}
+		h := b.Headers[p.Height-b.StartHeight]
+		if !bc.VerifyMerklePath(h.TransactionsMerkleRoot, p.TxID, p.Index, p.Count, p.Path) {
+			return errors.WithDetailf(ErrBadBundle, "proof for transaction %x does not match block %d", p.TxID.Bytes(), p.Height)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+)
+
+func TestBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, err := protocol.NewInitialBlock([]ed25519.PublicKey{pub}, 1, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate to a new key in block 3.
+	pub2, priv2, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog2, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pub2}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txs []*legacy.Tx
+	for i := 0; i < 3; i++ {
+		txs = append(txs, legacy.NewTx(legacy.TxData{Version: 1, ReferenceData: []byte{byte(i)}}))
+	}
+
+	b2 := nextBlock(t, b1, txs, b1.ConsensusProgram, priv)
+	b3 := nextBlock(t, b2, nil, prog2, priv)
+	b4 := nextBlock(t, b3, txs[1:], prog2, priv2)
+	blocks := []*legacy.Block{b2, b3, b4}
+
+	bundle, err := NewBundle(blocks, []bc.Hash{txs[2].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.ConsensusPrograms) != 2 || bundle.ConsensusPrograms[1].Height != 3 {
+		t.Errorf("got consensus programs %+v, want changes at heights 2 and 3", bundle.ConsensusPrograms)
+	}
+	if len(bundle.Proofs) != 1 || bundle.Proofs[0].Height != 2 || bundle.Proofs[0].Index != 2 {
+		t.Errorf("got proofs %+v, want one proof at height 2 index 2", bundle.Proofs)
+	}
+
+	err = Verify(bundle, b1.ConsensusProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A bundle starting from an untrusted program fails.
+	err = Verify(bundle, prog2)
+	if errors.Root(err) != ErrBadBundle {
+		t.Errorf("Verify with wrong program = %v, want %v", err, ErrBadBundle)
+	}
+
+	// A header that doesn't follow its predecessor fails.
+	headers := bundle.Headers
+	bundle.Headers = []*legacy.BlockHeader{headers[0], headers[0], headers[2]}
+	err = Verify(bundle, b1.ConsensusProgram)
+	if errors.Root(err) != ErrBadBundle {
+		t.Errorf("Verify with misordered headers = %v, want %v", err, ErrBadBundle)
+	}
+	bundle.Headers = headers
+
+	// Tampering with a proof fails.
+	bundle.Proofs[0].Index = 1
+	err = Verify(bundle, b1.ConsensusProgram)
+	if errors.Root(err) != ErrBadBundle {
+		t.Errorf("Verify with bad proof = %v, want %v", err, ErrBadBundle)
+	}
+
+	_, err = NewBundle(blocks, []bc.Hash{{}})
+	if errors.Root(err) != ErrBadBundle {
+		t.Errorf("NewBundle with unknown tx = %v, want %v", err, ErrBadBundle)
+	}
+}
+
+func nextBlock(t *testing.T, prev *legacy.Block, txs []*legacy.Tx, nextProg []byte, priv ed25519.PrivateKey) *legacy.Block {
+	var bcTxs []*bc.Tx
+	for _, tx := range txs {
+		bcTxs = append(bcTxs, tx.Tx)
+	}
+	root, err := bc.MerkleRoot(bcTxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           1,
+			Height:            prev.Height + 1,
+			PreviousBlockHash: prev.Hash(),
+			TimestampMS:       prev.TimestampMS + 1,
+			BlockCommitment: legacy.BlockCommitment{
+				TransactionsMerkleRoot: root,
+				ConsensusProgram:       nextProg,
+			},
+		},
+		Transactions: txs,
+	}
+	h := b.Hash()
+	b.Witness = [][]byte{ed25519.Sign(priv, h.Bytes())}
+	return b
+}
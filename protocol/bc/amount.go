@@ -0,0 +1,142 @@
+package bc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"chain/errors"
+	"chain/math/checked"
+)
+
+var (
+	// ErrAssetMismatch is returned when combining
+	// or comparing amounts of different assets.
+	ErrAssetMismatch = errors.New("amounts are of different assets")
+
+	// ErrAmountRange is returned for an amount outside the
+	// range the protocol allows, 0 through 2^63-1, including
+	// one that results from overflow or underflow.
+	ErrAmountRange = errors.New("amount out of range")
+)
+
+// Amount is a number of units of a particular asset. Unlike a
+// bare uint64, an Amount can only be added to, subtracted from, or
+// compared with an amount of the same asset, and its arithmetic is
+// checked for overflow, so that units of different assets can't be
+// mixed by mistake.
+//
+// Its zero value is zero units of the zero asset ID.
+type Amount struct {
+	assetID AssetID
+	units   uint64
+}
+
+// NewAmount returns the amount of units of assetID.
+// It returns ErrAmountRange if units exceeds 2^63-1.
+func NewAmount(assetID AssetID, units uint64) (Amount, error) {
+	if units > math.MaxInt64 {
+		return Amount{}, errors.WithDetailf(ErrAmountRange, "%d units of asset %x", units, assetID.Bytes())
+	}
+	return Amount{assetID: assetID, units: units}, nil
+}
+
+// ZeroAmount returns zero units of assetID.
+func ZeroAmount(assetID AssetID) Amount {
+	return Amount{assetID: assetID}
+}
+
+// NewAmountFrom converts aa to an Amount. An AssetAmount
+// without an asset ID is an amount of the zero asset ID.
+func NewAmountFrom(aa *AssetAmount) (Amount, error) {
+	var assetID AssetID
+	if aa.AssetId != nil {
+		assetID = *aa.AssetId
+	}
+	return NewAmount(assetID, aa.Amount)
+}
+
+// AssetID returns the asset a is an amount of.
+func (a Amount) AssetID() AssetID { return a.assetID }
+
+// Units returns the number of units of a's asset.
+func (a Amount) Units() uint64 { return a.units }
+
+// AssetAmount converts a to an AssetAmount.
+func (a Amount) AssetAmount() AssetAmount {
+	assetID := a.assetID
+	return AssetAmount{AssetId: &assetID, Amount: a.units}
+}
+
+// Add returns a+b. It returns ErrAssetMismatch if b is of a
+// different asset, and ErrAmountRange if the sum overflows.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a.assetID != b.assetID {
+		return Amount{}, a.mismatch(b)
+	}
+	sum, ok := checked.AddInt64(int64(a.units), int64(b.units))
+	if !ok {
+		return Amount{}, errors.WithDetailf(ErrAmountRange, "adding %d to %d units of asset %x", b.units, a.units, a.assetID.Bytes())
+	}
+	return Amount{assetID: a.assetID, units: uint64(sum)}, nil
+}
+
+// Sub returns a-b. It returns ErrAssetMismatch if b is of a
+// different asset, and ErrAmountRange if b is greater than a.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if a.assetID != b.assetID {
+		return Amount{}, a.mismatch(b)
+	}
+	if b.units > a.units {
+		return Amount{}, errors.WithDetailf(ErrAmountRange, "subtracting %d from %d units of asset %x", b.units, a.units, a.assetID.Bytes())
+	}
+	return Amount{assetID: a.assetID, units: a.units - b.units}, nil
+}
+
+// Cmp compares a and b, returning -1 if a < b, 0 if a == b,
+// and 1 if a > b. It returns ErrAssetMismatch if b is of a
+// different asset.
+func (a Amount) Cmp(b Amount) (int, error) {
+	if a.assetID != b.assetID {
+		return 0, a.mismatch(b)
+	}
+	switch {
+	case a.units < b.units:
+		return -1, nil
+	case a.units > b.units:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (a Amount) mismatch(b Amount) error {
+	return errors.WithDetailf(ErrAssetMismatch, "asset %x and asset %x", a.assetID.Bytes(), b.assetID.Bytes())
+}
+
+func (a Amount) String() string {
+	return fmt.Sprintf("%d units of asset %x", a.units, a.assetID.Bytes())
+}
+
+type amountJSON struct {
+	AssetID AssetID `json:"asset_id"`
+	Amount  uint64  `json:"amount"`
+}
+
+// MarshalJSON encodes a as an object with the
+// fields "asset_id" and "amount", as for AssetAmount.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(amountJSON{AssetID: a.assetID, Amount: a.units})
+}
+
+// UnmarshalJSON decodes an object with the fields "asset_id"
+// and "amount", as for AssetAmount. It returns ErrAmountRange
+// if the amount exceeds 2^63-1.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	var v amountJSON
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	*a, err = NewAmount(v.AssetID, v.Amount)
+	return err
+}
@@ -0,0 +1,92 @@
+package bc
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestAmountArithmetic(t *testing.T) {
+	a := AssetID{V0: 1}
+	b := AssetID{V0: 2}
+	mustAmount := func(assetID AssetID, units uint64) Amount {
+		amt, err := NewAmount(assetID, units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return amt
+	}
+
+	sum, err := mustAmount(a, 3).Add(mustAmount(a, 4))
+	if err != nil || sum != mustAmount(a, 7) {
+		t.Errorf("3+4 = %v, %v, want 7 units", sum, err)
+	}
+	diff, err := mustAmount(a, 7).Sub(mustAmount(a, 4))
+	if err != nil || diff != mustAmount(a, 3) {
+		t.Errorf("7-4 = %v, %v, want 3 units", diff, err)
+	}
+	cmp, err := mustAmount(a, 3).Cmp(mustAmount(a, 4))
+	if err != nil || cmp != -1 {
+		t.Errorf("Cmp(3, 4) = %d, %v, want -1", cmp, err)
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"mixed assets add", second(mustAmount(a, 1).Add(mustAmount(b, 1))), ErrAssetMismatch},
+		{"mixed assets sub", second(mustAmount(a, 1).Sub(mustAmount(b, 1))), ErrAssetMismatch},
+		{"overflow", second(mustAmount(a, math.MaxInt64).Add(mustAmount(a, 1))), ErrAmountRange},
+		{"underflow", second(mustAmount(a, 1).Sub(mustAmount(a, 2))), ErrAmountRange},
+		{"out of range", second(NewAmount(a, math.MaxInt64+1)), ErrAmountRange},
+	}
+	for _, c := range cases {
+		if errors.Root(c.err) != c.want {
+			t.Errorf("%s: err = %v, want %v", c.name, c.err, c.want)
+		}
+	}
+}
+
+func second(_ Amount, err error) error { return err }
+
+func TestAmountJSON(t *testing.T) {
+	assetID := AssetID{V0: 1}
+	amt, err := NewAmount(assetID, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(amt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The encoding must match AssetAmount's.
+	var aa AssetAmount
+	err = json.Unmarshal(b, &aa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *aa.AssetId != assetID || aa.Amount != 5 {
+		t.Errorf("decoded AssetAmount %v, want %v", aa, amt)
+	}
+	b, err = json.Marshal(aa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Amount
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != amt {
+		t.Errorf("decoded Amount %v, want %v", got, amt)
+	}
+
+	err = json.Unmarshal([]byte(`{"asset_id":"`+assetID.String()+`","amount":9223372036854775808}`), &got)
+	if errors.Root(err) != ErrAmountRange {
+		t.Errorf("decoding 2^63: err = %v, want %v", err, ErrAmountRange)
+	}
+}
@@ -47,6 +47,22 @@ func ComputeAssetID(prog []byte, initialBlockID *Hash, vmVersion uint64, data *H
 	return def.ComputeAssetID()
 }
 
+// ErrUnsupportedAssetVersion is returned by ComputeVersionedAssetID
+// for an asset version whose ID derivation isn't known.
+var ErrUnsupportedAssetVersion = errors.New("unsupported asset version")
+
+// ComputeVersionedAssetID derives the ID of the asset with the given
+// issuance program, initial block, VM version, and definition hash
+// under asset version assetVersion, the same way validation does.
+// Only asset version 1 is defined; other versions produce
+// ErrUnsupportedAssetVersion.
+func ComputeVersionedAssetID(prog []byte, initialBlockID *Hash, vmVersion, assetVersion uint64, data *Hash) (AssetID, error) {
+	if assetVersion != 1 {
+		return AssetID{}, ErrUnsupportedAssetVersion
+	}
+	return ComputeAssetID(prog, initialBlockID, vmVersion, data), nil
+}
+
 func (a *AssetAmount) ReadFrom(r *blockchain.Reader) error {
 	var assetID AssetID
 	_, err := assetID.ReadFrom(r)
@@ -23,6 +23,25 @@ func TestComputeAssetID(t *testing.T) {
 	}
 }
 
+func TestComputeVersionedAssetID(t *testing.T) {
+	prog := []byte{1}
+	initialBlockHash := mustDecodeHash("dd506f5d4c3f904d3d4b3c3be597c9198c6193ffd14a28570e4a923ce40cf9e5")
+
+	got, err := ComputeVersionedAssetID(prog, &initialBlockHash, 1, 1, &EmptyStringHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ComputeAssetID(prog, &initialBlockHash, 1, &EmptyStringHash)
+	if got != want {
+		t.Errorf("asset id = %x want %x", got.Bytes(), want.Bytes())
+	}
+
+	_, err = ComputeVersionedAssetID(prog, &initialBlockHash, 1, 2, &EmptyStringHash)
+	if err != ErrUnsupportedAssetVersion {
+		t.Errorf("asset version 2: err = %v want %v", err, ErrUnsupportedAssetVersion)
+	}
+}
+
 var assetIDSink AssetID
 
 func BenchmarkComputeAssetID(b *testing.B) {
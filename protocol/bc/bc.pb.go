@@ -25,6 +25,7 @@ It has these top-level messages:
 	TimeRange
 	Issuance
 	Spend
+	UnknownInput
 */
 package bc
 
@@ -768,6 +769,62 @@ func (m *Spend) GetOrdinal() uint64 {
 	return 0
 }
 
+type UnknownInput struct {
+	Type               uint64            `protobuf:"varint,1,opt,name=type" json:"type,omitempty"`
+	Body               *Hash             `protobuf:"bytes,2,opt,name=body" json:"body,omitempty"`
+	Data               *Hash             `protobuf:"bytes,3,opt,name=data" json:"data,omitempty"`
+	ExtHash            *Hash             `protobuf:"bytes,4,opt,name=ext_hash,json=extHash" json:"ext_hash,omitempty"`
+	WitnessDestination *ValueDestination `protobuf:"bytes,5,opt,name=witness_destination,json=witnessDestination" json:"witness_destination,omitempty"`
+	Ordinal            uint64            `protobuf:"varint,6,opt,name=ordinal" json:"ordinal,omitempty"`
+}
+
+func (m *UnknownInput) Reset()                    { *m = UnknownInput{} }
+func (m *UnknownInput) String() string            { return proto.CompactTextString(m) }
+func (*UnknownInput) ProtoMessage()               {}
+func (*UnknownInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *UnknownInput) GetType() uint64 {
+	if m != nil {
+		return m.Type
+	}
+	return 0
+}
+
+func (m *UnknownInput) GetBody() *Hash {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func (m *UnknownInput) GetData() *Hash {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *UnknownInput) GetExtHash() *Hash {
+	if m != nil {
+		return m.ExtHash
+	}
+	return nil
+}
+
+func (m *UnknownInput) GetWitnessDestination() *ValueDestination {
+	if m != nil {
+		return m.WitnessDestination
+	}
+	return nil
+}
+
+func (m *UnknownInput) GetOrdinal() uint64 {
+	if m != nil {
+		return m.Ordinal
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Hash)(nil), "bc.Hash")
 	proto.RegisterType((*Program)(nil), "bc.Program")
@@ -785,70 +842,74 @@ func init() {
 	proto.RegisterType((*TimeRange)(nil), "bc.TimeRange")
 	proto.RegisterType((*Issuance)(nil), "bc.Issuance")
 	proto.RegisterType((*Spend)(nil), "bc.Spend")
+	proto.RegisterType((*UnknownInput)(nil), "bc.UnknownInput")
 }
 
 func init() { proto.RegisterFile("bc.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 957 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xdf, 0x6e, 0xe3, 0xc4,
-	0x17, 0x96, 0x13, 0x27, 0x76, 0x4e, 0xba, 0x4d, 0x3a, 0xad, 0x2a, 0x6b, 0xf5, 0xfb, 0xa1, 0x62,
-	0x54, 0x76, 0x57, 0xa0, 0xaa, 0xdb, 0x16, 0xc4, 0x05, 0x37, 0x85, 0x02, 0xeb, 0x8b, 0x00, 0xf2,
-	0x56, 0x7b, 0x6b, 0x4d, 0xec, 0xd9, 0xc6, 0x22, 0x9e, 0x09, 0x9e, 0x71, 0xc8, 0x35, 0x8f, 0xc0,
-	0x2d, 0x6f, 0xc1, 0x33, 0xec, 0x03, 0xf0, 0x18, 0x5c, 0xf3, 0x04, 0x68, 0x8e, 0xc7, 0xce, 0x9f,
-	0x26, 0x69, 0x2a, 0x96, 0xbb, 0x9c, 0x39, 0x67, 0xce, 0x9f, 0xef, 0x7c, 0x9f, 0x33, 0xe0, 0x0e,
-	0xe3, 0xb3, 0x49, 0x2e, 0x94, 0x20, 0x8d, 0x61, 0xec, 0x7f, 0x0b, 0xf6, 0x2b, 0x2a, 0x47, 0x64,
-	0x1f, 0x1a, 0xd3, 0x73, 0xcf, 0x3a, 0xb1, 0x9e, 0xb7, 0xc3, 0xc6, 0xf4, 0x1c, 0xed, 0x97, 0x5e,
-	0xc3, 0xd8, 0x2f, 0xd1, 0xbe, 0xf0, 0x9a, 0xc6, 0xbe, 0x40, 0xfb, 0xd2, 0xb3, 0x8d, 0x7d, 0xe9,
-	0x7f, 0x09, 0xce, 0x8f, 0xb9, 0xb8, 0xcb, 0x69, 0x46, 0xfe, 0x0f, 0x30, 0xcd, 0xa2, 0x29, 0xcb,
-	0x65, 0x2a, 0x38, 0xa6, 0xb4, 0xc3, 0xce, 0x34, 0x7b, 0x53, 0x1e, 0x10, 0x02, 0x76, 0x2c, 0x12,
-	0x86, 0xb9, 0xf7, 0x42, 0xfc, 0xed, 0x07, 0xe0, 0x5c, 0x4b, 0xc9, 0x54, 0x70, 0xf3, 0xaf, 0x1b,
-	0x19, 0x40, 0x17, 0x53, 0x5d, 0x67, 0xa2, 0xe0, 0x8a, 0x7c, 0x0c, 0x2e, 0xd5, 0x66, 0x94, 0x26,
-	0x98, 0xb4, 0x7b, 0xd1, 0x3d, 0x1b, 0xc6, 0x67, 0xa6, 0x5a, 0xe8, 0xa0, 0x33, 0x48, 0xc8, 0x31,
-	0xb4, 0x29, 0xde, 0xc0, 0x52, 0x76, 0x68, 0x2c, 0xff, 0x77, 0x0b, 0x7a, 0x18, 0x7c, 0xc3, 0xde,
-	0xa6, 0x3c, 0x55, 0x7a, 0x82, 0x0b, 0xe8, 0xe3, 0x4f, 0x3a, 0x8e, 0x86, 0x63, 0x11, 0xff, 0x34,
-	0xcf, 0xed, 0xea, 0xdc, 0x1a, 0xcf, 0x70, 0xdf, 0x44, 0x7c, 0xa5, 0x03, 0x82, 0x84, 0x7c, 0x0e,
-	0xfd, 0x54, 0xca, 0x82, 0xf2, 0x98, 0x45, 0x93, 0x12, 0x28, 0xac, 0x64, 0xfa, 0x31, 0xd8, 0x85,
-	0xbd, 0x2a, 0xa8, 0x02, 0xf3, 0x7f, 0x60, 0x27, 0x54, 0x51, 0x1c, 0x78, 0x31, 0x3f, 0x9e, 0xfa,
-	0x63, 0xe8, 0xbe, 0xa1, 0xe3, 0x82, 0xbd, 0x16, 0x45, 0x1e, 0x33, 0xf2, 0x14, 0x9a, 0x39, 0x7b,
-	0x7b, 0xaf, 0x17, 0x7d, 0x48, 0x4e, 0xa1, 0x35, 0xd5, 0xa1, 0xa6, 0x6a, 0xaf, 0x46, 0xa1, 0x04,
-	0x2a, 0x2c, 0xbd, 0xe4, 0x29, 0xb8, 0x13, 0x21, 0x71, 0x4e, 0xac, 0x69, 0x87, 0xb5, 0xed, 0xff,
-	0x0c, 0x7d, 0xac, 0x76, 0xc3, 0xa4, 0x4a, 0x39, 0x45, 0x2c, 0xfe, 0xe3, 0x92, 0xbf, 0x36, 0xa1,
-	0x8b, 0x10, 0xbe, 0x62, 0x34, 0x61, 0x39, 0xf1, 0xc0, 0x59, 0x26, 0x56, 0x65, 0xea, 0x05, 0x8e,
-	0x58, 0x7a, 0x37, 0xaa, 0x17, 0x58, 0x5a, 0xe4, 0x0a, 0x0e, 0x26, 0x39, 0x9b, 0xa6, 0xa2, 0x90,
-	0xf3, 0x6d, 0xad, 0xa2, 0xd9, 0xab, 0x42, 0xaa, 0x75, 0x7d, 0x08, 0x7b, 0x2a, 0xcd, 0x98, 0x54,
-	0x34, 0x9b, 0x44, 0x99, 0x44, 0x7e, 0xd9, 0x61, 0xb7, 0x3e, 0x1b, 0x48, 0xf2, 0x19, 0x1c, 0xa8,
-	0x9c, 0x72, 0x49, 0x63, 0xdd, 0xa9, 0x8c, 0x72, 0x21, 0x94, 0xd7, 0x5a, 0x49, 0xdc, 0x5f, 0x0c,
-	0x09, 0x85, 0x50, 0xe4, 0x05, 0x74, 0x91, 0x73, 0xe6, 0x42, 0x7b, 0xe5, 0x02, 0x94, 0x4e, 0x0c,
-	0xbd, 0x82, 0x63, 0xce, 0x66, 0x2a, 0x8a, 0x05, 0x97, 0x8c, 0xcb, 0x42, 0xd6, 0xcc, 0x71, 0x50,
-	0x3b, 0x47, 0xda, 0xfb, 0x75, 0xe5, 0xac, 0x18, 0xf3, 0x11, 0xb8, 0xfa, 0xd2, 0x88, 0xca, 0x91,
-	0xe7, 0xae, 0x64, 0x77, 0xd8, 0x4c, 0xa1, 0xdc, 0x3f, 0x81, 0x83, 0x5f, 0x52, 0xc5, 0x99, 0x94,
-	0x11, 0xcd, 0xef, 0x8a, 0x8c, 0x71, 0x25, 0xbd, 0xce, 0x49, 0xf3, 0xf9, 0x5e, 0xd8, 0x37, 0x8e,
-	0xeb, 0xea, 0xdc, 0xff, 0xd3, 0x02, 0xf7, 0x76, 0xf6, 0xe0, 0x06, 0x9e, 0x01, 0xe4, 0x4c, 0x16,
-	0x63, 0xad, 0x35, 0xe9, 0x35, 0x4e, 0x9a, 0x4b, 0xa5, 0x3b, 0xa5, 0x2f, 0x48, 0xe4, 0x76, 0x4e,
-	0x93, 0x0f, 0xa0, 0x9b, 0xa5, 0x3c, 0xd2, 0x50, 0xcf, 0x91, 0xef, 0x64, 0x29, 0xbf, 0x4d, 0x33,
-	0x36, 0x90, 0xe8, 0xa7, 0xb3, 0xda, 0xdf, 0x32, 0x7e, 0x3a, 0x33, 0xfe, 0xc5, 0xf9, 0xdb, 0x1b,
-	0xe6, 0xf7, 0xff, 0xb6, 0xa0, 0x39, 0x28, 0x66, 0xe4, 0x05, 0x38, 0x12, 0xb5, 0x23, 0x3d, 0x0b,
-	0x1b, 0x46, 0x92, 0x2e, 0x68, 0x2a, 0xac, 0xfc, 0xe4, 0x14, 0x9c, 0x2d, 0xc2, 0xad, 0x7c, 0x4b,
-	0xe5, 0x9b, 0x9b, 0xe0, 0xff, 0x0e, 0x8e, 0x2a, 0xf8, 0x93, 0xb9, 0x98, 0xf4, 0xb0, 0xba, 0x87,
-	0xa3, 0xba, 0x87, 0x05, 0xa5, 0x85, 0x87, 0xe6, 0xc6, 0xc2, 0x99, 0x5c, 0xbf, 0xc7, 0xd6, 0x86,
-	0x3d, 0xfe, 0x65, 0x41, 0xeb, 0x7b, 0xc1, 0x63, 0xb6, 0x38, 0x8b, 0xb5, 0x65, 0x96, 0x4f, 0xe1,
-	0x09, 0xc2, 0x9c, 0x53, 0x7e, 0xc7, 0xb4, 0x6e, 0x1a, 0x2b, 0x03, 0xa1, 0x20, 0x42, 0xed, 0x0d,
-	0x92, 0xdd, 0x26, 0x5f, 0xdb, 0xb0, 0xbd, 0xbe, 0x61, 0xf2, 0x05, 0x1c, 0xd6, 0xc1, 0x3c, 0x1e,
-	0x89, 0x9c, 0x25, 0xba, 0x8b, 0x55, 0x91, 0x55, 0x19, 0xaf, 0x4d, 0x4c, 0x90, 0xf8, 0xef, 0x2c,
-	0x68, 0xff, 0x50, 0xa8, 0x49, 0xa1, 0xc8, 0x33, 0x68, 0x97, 0x2b, 0x34, 0xa3, 0xde, 0xdb, 0xb0,
-	0x71, 0x93, 0x2b, 0xe8, 0xc5, 0x82, 0xab, 0x5c, 0x8c, 0xb7, 0x7d, 0xa1, 0xf7, 0x4d, 0xcc, 0x4e,
-	0x1f, 0xe8, 0x25, 0x4c, 0xec, 0x4d, 0x98, 0x78, 0xe0, 0x88, 0x3c, 0x49, 0x39, 0x1d, 0x1b, 0x36,
-	0x57, 0xa6, 0xff, 0x9b, 0x05, 0x10, 0x32, 0x95, 0xe6, 0x4c, 0x03, 0xb2, 0xfb, 0x28, 0x55, 0x53,
-	0x8d, 0x07, 0x9b, 0x6a, 0xee, 0xd0, 0x94, 0xbd, 0xdc, 0xd4, 0x04, 0x3a, 0xb7, 0xd5, 0xda, 0x57,
-	0xd5, 0x6a, 0x3d, 0xa0, 0xd6, 0xc6, 0x36, 0xb5, 0x6e, 0xea, 0xc5, 0xff, 0xa3, 0x09, 0x6e, 0x60,
-	0xfe, 0x18, 0xc9, 0x29, 0x74, 0x4a, 0x32, 0xac, 0xfb, 0xdb, 0x75, 0x4b, 0x57, 0x90, 0xec, 0xfa,
-	0xe7, 0xf3, 0x1e, 0xd6, 0xf7, 0xcd, 0x9c, 0xa5, 0x0b, 0x62, 0x36, 0x2c, 0x5d, 0xaf, 0x65, 0x72,
-	0x5f, 0xcb, 0x64, 0x00, 0x5e, 0x4d, 0x76, 0x7c, 0xb1, 0x24, 0xf5, 0x8b, 0xc3, 0x7c, 0xc7, 0x0e,
-	0xeb, 0x19, 0xe6, 0x8f, 0x91, 0xf0, 0xb8, 0x22, 0xff, 0xca, 0x23, 0x65, 0xad, 0xd0, 0x9c, 0xc7,
-	0x09, 0xcd, 0x7d, 0x50, 0x68, 0x8b, 0x34, 0xe9, 0x2c, 0xd3, 0xe4, 0x5d, 0x03, 0x5a, 0xaf, 0x27,
-	0x8c, 0x27, 0xe4, 0x1c, 0x7a, 0x72, 0xc2, 0xb8, 0x8a, 0x04, 0x2a, 0x72, 0xdd, 0xde, 0x9e, 0x60,
-	0x40, 0xa9, 0xd8, 0x20, 0x79, 0x1f, 0xfc, 0xdd, 0xb0, 0x15, 0xfb, 0x91, 0x5b, 0x79, 0xcc, 0x07,
-	0x76, 0x13, 0x8c, 0xed, 0x47, 0xc1, 0xe8, 0x2c, 0xc1, 0x38, 0x6c, 0xe3, 0x5b, 0xfd, 0xf2, 0x9f,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x58, 0x32, 0x78, 0x25, 0xb7, 0x0b, 0x00, 0x00,
+	// 995 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x57, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x96, 0x7f, 0x12, 0x3b, 0x27, 0xdd, 0x26, 0x9d, 0x56, 0x95, 0xb5, 0x02, 0x54, 0x8c, 0xca,
+	0xee, 0x0a, 0x54, 0x75, 0xdb, 0x82, 0xb8, 0xe0, 0xa6, 0x50, 0x60, 0x7d, 0x11, 0x40, 0xde, 0xb2,
+	0xb7, 0xd6, 0xc4, 0x9e, 0x6d, 0xac, 0x8d, 0x67, 0x8c, 0x67, 0x9c, 0xcd, 0xde, 0xf2, 0x08, 0xdc,
+	0xf2, 0x16, 0x3c, 0xc3, 0x3e, 0x00, 0x0f, 0xc1, 0x05, 0xd7, 0x3c, 0x01, 0x9a, 0xf1, 0xd8, 0xf9,
+	0x69, 0x92, 0xa6, 0x62, 0x91, 0xb8, 0x9b, 0x33, 0xe7, 0xcc, 0x99, 0x73, 0xbe, 0xf3, 0x7d, 0x13,
+	0x07, 0xdc, 0x61, 0x7c, 0x92, 0x17, 0x4c, 0x30, 0x64, 0x0e, 0x63, 0xff, 0x5b, 0xb0, 0x9f, 0x61,
+	0x3e, 0x42, 0xbb, 0x60, 0x4e, 0x4e, 0x3d, 0xe3, 0xc8, 0x78, 0xdc, 0x0e, 0xcd, 0xc9, 0xa9, 0xb2,
+	0x9f, 0x7a, 0xa6, 0xb6, 0x9f, 0x2a, 0xfb, 0xcc, 0xb3, 0xb4, 0x7d, 0xa6, 0xec, 0x73, 0xcf, 0xd6,
+	0xf6, 0xb9, 0xff, 0x25, 0x38, 0x3f, 0x16, 0xec, 0xa6, 0xc0, 0x19, 0x7a, 0x1f, 0x60, 0x92, 0x45,
+	0x13, 0x52, 0xf0, 0x94, 0x51, 0x95, 0xd2, 0x0e, 0x3b, 0x93, 0xec, 0x45, 0xb5, 0x81, 0x10, 0xd8,
+	0x31, 0x4b, 0x88, 0xca, 0xbd, 0x13, 0xaa, 0xb5, 0x1f, 0x80, 0x73, 0xc9, 0x39, 0x11, 0xc1, 0xd5,
+	0xbf, 0x2e, 0x64, 0x00, 0x5d, 0x95, 0xea, 0x32, 0x63, 0x25, 0x15, 0xe8, 0x63, 0x70, 0xb1, 0x34,
+	0xa3, 0x34, 0x51, 0x49, 0xbb, 0x67, 0xdd, 0x93, 0x61, 0x7c, 0xa2, 0x6f, 0x0b, 0x1d, 0xe5, 0x0c,
+	0x12, 0x74, 0x08, 0x6d, 0xac, 0x4e, 0xa8, 0xab, 0xec, 0x50, 0x5b, 0xfe, 0x6f, 0x06, 0xf4, 0x54,
+	0xf0, 0x15, 0x79, 0x99, 0xd2, 0x54, 0xc8, 0x0e, 0xce, 0xa0, 0xaf, 0x96, 0x78, 0x1c, 0x0d, 0xc7,
+	0x2c, 0x7e, 0x35, 0xcb, 0xed, 0xca, 0xdc, 0x12, 0xcf, 0x70, 0x57, 0x47, 0x7c, 0x25, 0x03, 0x82,
+	0x04, 0x7d, 0x0e, 0xfd, 0x94, 0xf3, 0x12, 0xd3, 0x98, 0x44, 0x79, 0x05, 0x94, 0x67, 0xce, 0xea,
+	0xd1, 0xd8, 0x85, 0xbd, 0x3a, 0xa8, 0x06, 0xf3, 0x3d, 0xb0, 0x13, 0x2c, 0xb0, 0x67, 0x2d, 0xe5,
+	0x57, 0xbb, 0xfe, 0x18, 0xba, 0x2f, 0xf0, 0xb8, 0x24, 0xcf, 0x59, 0x59, 0xc4, 0x04, 0x3d, 0x04,
+	0xab, 0x20, 0x2f, 0x6f, 0xd5, 0x22, 0x37, 0xd1, 0x31, 0xb4, 0x26, 0x32, 0x54, 0xdf, 0xda, 0x6b,
+	0x50, 0xa8, 0x80, 0x0a, 0x2b, 0x2f, 0x7a, 0x08, 0x6e, 0xce, 0xb8, 0xea, 0x53, 0xdd, 0x69, 0x87,
+	0x8d, 0xed, 0xff, 0x0c, 0x7d, 0x75, 0xdb, 0x15, 0xe1, 0x22, 0xa5, 0x58, 0x61, 0xf1, 0x1f, 0x5f,
+	0xf9, 0x8b, 0x05, 0x5d, 0x05, 0xe1, 0x33, 0x82, 0x13, 0x52, 0x20, 0x0f, 0x9c, 0x45, 0x62, 0xd5,
+	0xa6, 0x1c, 0xe0, 0x88, 0xa4, 0x37, 0xa3, 0x66, 0x80, 0x95, 0x85, 0x2e, 0x60, 0x2f, 0x2f, 0xc8,
+	0x24, 0x65, 0x25, 0x9f, 0x4d, 0x6b, 0x19, 0xcd, 0x5e, 0x1d, 0x52, 0x8f, 0xeb, 0x43, 0xd8, 0x11,
+	0x69, 0x46, 0xb8, 0xc0, 0x59, 0x1e, 0x65, 0x5c, 0xf1, 0xcb, 0x0e, 0xbb, 0xcd, 0xde, 0x80, 0xa3,
+	0xcf, 0x60, 0x4f, 0x14, 0x98, 0x72, 0x1c, 0xcb, 0x4a, 0x79, 0x54, 0x30, 0x26, 0xbc, 0xd6, 0x52,
+	0xe2, 0xfe, 0x7c, 0x48, 0xc8, 0x98, 0x40, 0x4f, 0xa0, 0xab, 0x38, 0xa7, 0x0f, 0xb4, 0x97, 0x0e,
+	0x40, 0xe5, 0x54, 0xa1, 0x17, 0x70, 0x48, 0xc9, 0x54, 0x44, 0x31, 0xa3, 0x9c, 0x50, 0x5e, 0xf2,
+	0x86, 0x39, 0x8e, 0xd2, 0xce, 0x81, 0xf4, 0x7e, 0x5d, 0x3b, 0x6b, 0xc6, 0x7c, 0x04, 0xae, 0x3c,
+	0x34, 0xc2, 0x7c, 0xe4, 0xb9, 0x4b, 0xd9, 0x1d, 0x32, 0x15, 0x72, 0x81, 0x3e, 0x81, 0xbd, 0xd7,
+	0xa9, 0xa0, 0x84, 0xf3, 0x08, 0x17, 0x37, 0x65, 0x46, 0xa8, 0xe0, 0x5e, 0xe7, 0xc8, 0x7a, 0xbc,
+	0x13, 0xf6, 0xb5, 0xe3, 0xb2, 0xde, 0xf7, 0xff, 0x30, 0xc0, 0xbd, 0x9e, 0xde, 0x39, 0x81, 0x47,
+	0x00, 0x05, 0xe1, 0xe5, 0x58, 0x6a, 0x8d, 0x7b, 0xe6, 0x91, 0xb5, 0x70, 0x75, 0xa7, 0xf2, 0x05,
+	0x09, 0xdf, 0xcc, 0x69, 0xf4, 0x01, 0x74, 0xb3, 0x94, 0x46, 0x12, 0xea, 0x19, 0xf2, 0x9d, 0x2c,
+	0xa5, 0xd7, 0x69, 0x46, 0x06, 0x5c, 0xf9, 0xf1, 0xb4, 0xf1, 0xb7, 0xb4, 0x1f, 0x4f, 0xb5, 0x7f,
+	0xbe, 0xff, 0xf6, 0x9a, 0xfe, 0xfd, 0xbf, 0x0d, 0xb0, 0x06, 0xe5, 0x14, 0x3d, 0x01, 0x87, 0x2b,
+	0xed, 0x70, 0xcf, 0x38, 0xb2, 0x6a, 0x92, 0xce, 0x69, 0x2a, 0xac, 0xfd, 0xe8, 0x18, 0x9c, 0x0d,
+	0xc2, 0xad, 0x7d, 0x0b, 0xd7, 0x5b, 0xeb, 0xe0, 0xff, 0x0e, 0x0e, 0x6a, 0xf8, 0x93, 0x99, 0x98,
+	0x64, 0xb3, 0xb2, 0x86, 0x83, 0xa6, 0x86, 0x39, 0xa5, 0x85, 0xfb, 0xfa, 0xc4, 0xdc, 0x1e, 0x5f,
+	0x3d, 0xc7, 0xd6, 0x9a, 0x39, 0xfe, 0x65, 0x40, 0xeb, 0x7b, 0x46, 0x63, 0x32, 0xdf, 0x8b, 0xb1,
+	0xa1, 0x97, 0x4f, 0xe1, 0x81, 0x82, 0xb9, 0xc0, 0xf4, 0x86, 0x48, 0xdd, 0x98, 0x4b, 0x0d, 0x29,
+	0x41, 0x84, 0xd2, 0x1b, 0x24, 0xdb, 0x75, 0xbe, 0xb2, 0x60, 0x7b, 0x75, 0xc1, 0xe8, 0x0b, 0xd8,
+	0x6f, 0x82, 0x69, 0x3c, 0x62, 0x05, 0x49, 0x64, 0x15, 0xcb, 0x22, 0xab, 0x33, 0x5e, 0xea, 0x98,
+	0x20, 0xf1, 0xdf, 0x1a, 0xd0, 0xfe, 0xa1, 0x14, 0x79, 0x29, 0xd0, 0x23, 0x68, 0x57, 0x23, 0xd4,
+	0xad, 0xde, 0x9a, 0xb0, 0x76, 0xa3, 0x0b, 0xe8, 0xc5, 0x8c, 0x8a, 0x82, 0x8d, 0x37, 0xbd, 0xd0,
+	0xbb, 0x3a, 0x66, 0xab, 0x07, 0x7a, 0x01, 0x13, 0x7b, 0x1d, 0x26, 0x1e, 0x38, 0xac, 0x48, 0x52,
+	0x8a, 0xc7, 0x9a, 0xcd, 0xb5, 0xe9, 0xff, 0x6a, 0x00, 0x84, 0x44, 0xa4, 0x05, 0x91, 0x80, 0x6c,
+	0xdf, 0x4a, 0x5d, 0x94, 0x79, 0x67, 0x51, 0xd6, 0x16, 0x45, 0xd9, 0x8b, 0x45, 0xe5, 0xd0, 0xb9,
+	0xae, 0xc7, 0xbe, 0xac, 0x56, 0xe3, 0x0e, 0xb5, 0x9a, 0x9b, 0xd4, 0xba, 0xae, 0x16, 0xff, 0x77,
+	0x0b, 0xdc, 0x40, 0xff, 0x30, 0xa2, 0x63, 0xe8, 0x54, 0x64, 0x58, 0xf5, 0xb3, 0xeb, 0x56, 0xae,
+	0x20, 0xd9, 0xf6, 0xc7, 0xe7, 0x1d, 0x8c, 0xef, 0x1b, 0xd8, 0x5f, 0x21, 0x66, 0xcd, 0xd2, 0xd5,
+	0x5a, 0x46, 0xb7, 0xb5, 0x8c, 0x06, 0xe0, 0x35, 0x64, 0x57, 0x5f, 0x2c, 0x49, 0xf3, 0xc5, 0xa1,
+	0xdf, 0xb1, 0xfd, 0xa6, 0x87, 0xd9, 0xc7, 0x48, 0x78, 0x58, 0x93, 0x7f, 0x71, 0x7f, 0xb5, 0xd0,
+	0x9c, 0xfb, 0x09, 0xcd, 0xbd, 0x53, 0x68, 0xf3, 0x34, 0xe9, 0x2c, 0xd2, 0xe4, 0xad, 0x09, 0xad,
+	0xe7, 0x39, 0xa1, 0x09, 0x3a, 0x85, 0x1e, 0xcf, 0x09, 0x15, 0x11, 0x53, 0x8a, 0x5c, 0x35, 0xb7,
+	0x07, 0x2a, 0xa0, 0x52, 0x6c, 0x90, 0xbc, 0x0b, 0xfe, 0xae, 0x99, 0x8a, 0x7d, 0xcf, 0xa9, 0xdc,
+	0xe7, 0x81, 0x5d, 0x07, 0x63, 0xfb, 0x5e, 0x30, 0x3a, 0x8b, 0x30, 0xfe, 0x69, 0xc0, 0xce, 0x4f,
+	0xf4, 0x15, 0x65, 0xaf, 0x69, 0x40, 0xe5, 0x7b, 0x86, 0xc0, 0x16, 0x6f, 0x72, 0xa2, 0xa5, 0xa6,
+	0xd6, 0x12, 0xaf, 0x21, 0x4b, 0xde, 0xdc, 0xc6, 0x4b, 0xee, 0xfe, 0x8f, 0x38, 0x3e, 0xd7, 0x66,
+	0x7b, 0xa1, 0xcd, 0x61, 0x5b, 0xfd, 0x25, 0x39, 0xff, 0x67, 0x00, 0x8c, 0x24, 0x82, 0x45, 0x9e,
+	0x0c, 0x00, 0x00,
 }
@@ -0,0 +1,453 @@
+// Code generated by protoc-gen-go.
+// source: bcpb.proto
+// DO NOT EDIT!
+
+/*
+Package bcpb is a generated protocol buffer package.
+
+It is generated from these files:
+	bcpb.proto
+
+It has these top-level messages:
+	Tx
+	TxInput
+	IssuanceInput
+	SpendInput
+	TxOutput
+	BlockHeader
+	Block
+	Snapshot
+*/
+package bcpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Tx is a transaction, with the same fields as the Chain
+// Protocol wire format. Hashes are 32 bytes.
+type Tx struct {
+	// Id is computed from the other fields. It is ignored
+	// when converting to a transaction, unless it is set,
+	// in which case it must match.
+	Id                        []byte      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version                   uint64      `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	MinTimeMs                 uint64      `protobuf:"varint,3,opt,name=min_time_ms,json=minTimeMs" json:"min_time_ms,omitempty"`
+	MaxTimeMs                 uint64      `protobuf:"varint,4,opt,name=max_time_ms,json=maxTimeMs" json:"max_time_ms,omitempty"`
+	Features                  uint64      `protobuf:"varint,5,opt,name=features" json:"features,omitempty"`
+	Inputs                    []*TxInput  `protobuf:"bytes,6,rep,name=inputs" json:"inputs,omitempty"`
+	Outputs                   []*TxOutput `protobuf:"bytes,7,rep,name=outputs" json:"outputs,omitempty"`
+	ReferenceData             []byte      `protobuf:"bytes,8,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+	DetachedReferenceDataHash []byte      `protobuf:"bytes,9,opt,name=detached_reference_data_hash,json=detachedReferenceDataHash,proto3" json:"detached_reference_data_hash,omitempty"`
+	CommonFieldsSuffix        []byte      `protobuf:"bytes,10,opt,name=common_fields_suffix,json=commonFieldsSuffix,proto3" json:"common_fields_suffix,omitempty"`
+	CommonWitnessSuffix       []byte      `protobuf:"bytes,11,opt,name=common_witness_suffix,json=commonWitnessSuffix,proto3" json:"common_witness_suffix,omitempty"`
+}
+
+func (m *Tx) Reset()                    { *m = Tx{} }
+func (m *Tx) String() string            { return proto.CompactTextString(m) }
+func (*Tx) ProtoMessage()               {}
+func (*Tx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Tx) GetInputs() []*TxInput {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *Tx) GetOutputs() []*TxOutput {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+type TxInput struct {
+	AssetVersion              uint64 `protobuf:"varint,1,opt,name=asset_version,json=assetVersion" json:"asset_version,omitempty"`
+	ReferenceData             []byte `protobuf:"bytes,2,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+	DetachedReferenceDataHash []byte `protobuf:"bytes,3,opt,name=detached_reference_data_hash,json=detachedReferenceDataHash,proto3" json:"detached_reference_data_hash,omitempty"`
+	CommitmentSuffix          []byte `protobuf:"bytes,4,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	WitnessSuffix             []byte `protobuf:"bytes,5,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+	// The type of the input. UnknownType is the type
+	// code of an input of a type this package doesn't know.
+	//
+	// Types that are valid to be assigned to Type:
+	//	*TxInput_Issuance
+	//	*TxInput_Spend
+	//	*TxInput_UnknownType
+	Type isTxInput_Type `protobuf_oneof:"type"`
+}
+
+func (m *TxInput) Reset()                    { *m = TxInput{} }
+func (m *TxInput) String() string            { return proto.CompactTextString(m) }
+func (*TxInput) ProtoMessage()               {}
+func (*TxInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type isTxInput_Type interface{ isTxInput_Type() }
+
+type TxInput_Issuance struct {
+	Issuance *IssuanceInput `protobuf:"bytes,6,opt,name=issuance,oneof"`
+}
+type TxInput_Spend struct {
+	Spend *SpendInput `protobuf:"bytes,7,opt,name=spend,oneof"`
+}
+type TxInput_UnknownType struct {
+	UnknownType uint32 `protobuf:"varint,8,opt,name=unknown_type,json=unknownType,oneof"`
+}
+
+func (*TxInput_Issuance) isTxInput_Type()    {}
+func (*TxInput_Spend) isTxInput_Type()       {}
+func (*TxInput_UnknownType) isTxInput_Type() {}
+
+func (m *TxInput) GetType() isTxInput_Type {
+	if m != nil {
+		return m.Type
+	}
+	return nil
+}
+
+func (m *TxInput) GetIssuance() *IssuanceInput {
+	if x, ok := m.GetType().(*TxInput_Issuance); ok {
+		return x.Issuance
+	}
+	return nil
+}
+
+func (m *TxInput) GetSpend() *SpendInput {
+	if x, ok := m.GetType().(*TxInput_Spend); ok {
+		return x.Spend
+	}
+	return nil
+}
+
+func (m *TxInput) GetUnknownType() uint32 {
+	if x, ok := m.GetType().(*TxInput_UnknownType); ok {
+		return x.UnknownType
+	}
+	return 0
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*TxInput) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _TxInput_OneofMarshaler, _TxInput_OneofUnmarshaler, _TxInput_OneofSizer, []interface{}{
+		(*TxInput_Issuance)(nil),
+		(*TxInput_Spend)(nil),
+		(*TxInput_UnknownType)(nil),
+	}
+}
+
+func _TxInput_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*TxInput)
+	// type
+	switch x := m.Type.(type) {
+	case *TxInput_Issuance:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Issuance); err != nil {
+			return err
+		}
+	case *TxInput_Spend:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Spend); err != nil {
+			return err
+		}
+	case *TxInput_UnknownType:
+		b.EncodeVarint(8<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.UnknownType))
+	case nil:
+	default:
+		return fmt.Errorf("TxInput.Type has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _TxInput_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*TxInput)
+	switch tag {
+	case 6: // type.issuance
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(IssuanceInput)
+		err := b.DecodeMessage(msg)
+		m.Type = &TxInput_Issuance{msg}
+		return true, err
+	case 7: // type.spend
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SpendInput)
+		err := b.DecodeMessage(msg)
+		m.Type = &TxInput_Spend{msg}
+		return true, err
+	case 8: // type.unknown_type
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Type = &TxInput_UnknownType{uint32(x)}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _TxInput_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*TxInput)
+	// type
+	switch x := m.Type.(type) {
+	case *TxInput_Issuance:
+		s := proto.Size(x.Issuance)
+		n += proto.SizeVarint(6<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *TxInput_Spend:
+		s := proto.Size(x.Spend)
+		n += proto.SizeVarint(7<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *TxInput_UnknownType:
+		n += proto.SizeVarint(8<<3 | proto.WireVarint)
+		n += proto.SizeVarint(uint64(x.UnknownType))
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type IssuanceInput struct {
+	Nonce           []byte   `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Amount          uint64   `protobuf:"varint,2,opt,name=amount" json:"amount,omitempty"`
+	InitialBlockId  []byte   `protobuf:"bytes,3,opt,name=initial_block_id,json=initialBlockId,proto3" json:"initial_block_id,omitempty"`
+	AssetDefinition []byte   `protobuf:"bytes,4,opt,name=asset_definition,json=assetDefinition,proto3" json:"asset_definition,omitempty"`
+	VmVersion       uint64   `protobuf:"varint,5,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	IssuanceProgram []byte   `protobuf:"bytes,6,opt,name=issuance_program,json=issuanceProgram,proto3" json:"issuance_program,omitempty"`
+	Arguments       [][]byte `protobuf:"bytes,7,rep,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (m *IssuanceInput) Reset()                    { *m = IssuanceInput{} }
+func (m *IssuanceInput) String() string            { return proto.CompactTextString(m) }
+func (*IssuanceInput) ProtoMessage()               {}
+func (*IssuanceInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type SpendInput struct {
+	SourceId              []byte   `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	SourcePosition        uint64   `protobuf:"varint,2,opt,name=source_position,json=sourcePosition" json:"source_position,omitempty"`
+	AssetId               []byte   `protobuf:"bytes,3,opt,name=asset_id,json=assetId,proto3" json:"asset_id,omitempty"`
+	Amount                uint64   `protobuf:"varint,4,opt,name=amount" json:"amount,omitempty"`
+	VmVersion             uint64   `protobuf:"varint,5,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	ControlProgram        []byte   `protobuf:"bytes,6,opt,name=control_program,json=controlProgram,proto3" json:"control_program,omitempty"`
+	RefDataHash           []byte   `protobuf:"bytes,7,opt,name=ref_data_hash,json=refDataHash,proto3" json:"ref_data_hash,omitempty"`
+	SpendCommitmentSuffix []byte   `protobuf:"bytes,8,opt,name=spend_commitment_suffix,json=spendCommitmentSuffix,proto3" json:"spend_commitment_suffix,omitempty"`
+	Arguments             [][]byte `protobuf:"bytes,9,rep,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (m *SpendInput) Reset()                    { *m = SpendInput{} }
+func (m *SpendInput) String() string            { return proto.CompactTextString(m) }
+func (*SpendInput) ProtoMessage()               {}
+func (*SpendInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type TxOutput struct {
+	AssetVersion              uint64 `protobuf:"varint,1,opt,name=asset_version,json=assetVersion" json:"asset_version,omitempty"`
+	AssetId                   []byte `protobuf:"bytes,2,opt,name=asset_id,json=assetId,proto3" json:"asset_id,omitempty"`
+	Amount                    uint64 `protobuf:"varint,3,opt,name=amount" json:"amount,omitempty"`
+	VmVersion                 uint64 `protobuf:"varint,4,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	ControlProgram            []byte `protobuf:"bytes,5,opt,name=control_program,json=controlProgram,proto3" json:"control_program,omitempty"`
+	ReferenceData             []byte `protobuf:"bytes,6,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+	DetachedReferenceDataHash []byte `protobuf:"bytes,7,opt,name=detached_reference_data_hash,json=detachedReferenceDataHash,proto3" json:"detached_reference_data_hash,omitempty"`
+	CommitmentSuffix          []byte `protobuf:"bytes,8,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	WitnessSuffix             []byte `protobuf:"bytes,9,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+}
+
+func (m *TxOutput) Reset()                    { *m = TxOutput{} }
+func (m *TxOutput) String() string            { return proto.CompactTextString(m) }
+func (*TxOutput) ProtoMessage()               {}
+func (*TxOutput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+// BlockHeader is a block header. Id is computed,
+// and is treated as in Tx.
+type BlockHeader struct {
+	Id                     []byte   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version                uint64   `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	Height                 uint64   `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	PreviousBlockId        []byte   `protobuf:"bytes,4,opt,name=previous_block_id,json=previousBlockId,proto3" json:"previous_block_id,omitempty"`
+	TimestampMs            uint64   `protobuf:"varint,5,opt,name=timestamp_ms,json=timestampMs" json:"timestamp_ms,omitempty"`
+	TransactionsMerkleRoot []byte   `protobuf:"bytes,6,opt,name=transactions_merkle_root,json=transactionsMerkleRoot,proto3" json:"transactions_merkle_root,omitempty"`
+	AssetsMerkleRoot       []byte   `protobuf:"bytes,7,opt,name=assets_merkle_root,json=assetsMerkleRoot,proto3" json:"assets_merkle_root,omitempty"`
+	ConsensusProgram       []byte   `protobuf:"bytes,8,opt,name=consensus_program,json=consensusProgram,proto3" json:"consensus_program,omitempty"`
+	CommitmentSuffix       []byte   `protobuf:"bytes,9,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	Witness                [][]byte `protobuf:"bytes,10,rep,name=witness,proto3" json:"witness,omitempty"`
+	WitnessSuffix          []byte   `protobuf:"bytes,11,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+}
+
+func (m *BlockHeader) Reset()                    { *m = BlockHeader{} }
+func (m *BlockHeader) String() string            { return proto.CompactTextString(m) }
+func (*BlockHeader) ProtoMessage()               {}
+func (*BlockHeader) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type Block struct {
+	Header       *BlockHeader `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
+	Transactions []*Tx        `protobuf:"bytes,2,rep,name=transactions" json:"transactions,omitempty"`
+}
+
+func (m *Block) Reset()                    { *m = Block{} }
+func (m *Block) String() string            { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()               {}
+func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Block) GetHeader() *BlockHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Block) GetTransactions() []*Tx {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+// Snapshot is a snapshot of the blockchain state: the
+// state tree and the recent issuance nonces. It is
+// compatible with the snapshots Chain Core stores and
+// serves to other cores.
+type Snapshot struct {
+	// Nodes holds the key of each item in the state tree,
+	// in order.
+	Nodes  []*Snapshot_StateTreeNode `protobuf:"bytes,1,rep,name=nodes" json:"nodes,omitempty"`
+	Nonces []*Snapshot_Nonce         `protobuf:"bytes,2,rep,name=nonces" json:"nonces,omitempty"`
+}
+
+func (m *Snapshot) Reset()                    { *m = Snapshot{} }
+func (m *Snapshot) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()               {}
+func (*Snapshot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *Snapshot) GetNodes() []*Snapshot_StateTreeNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *Snapshot) GetNonces() []*Snapshot_Nonce {
+	if m != nil {
+		return m.Nonces
+	}
+	return nil
+}
+
+type Snapshot_Nonce struct {
+	Hash     []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	ExpiryMs uint64 `protobuf:"varint,2,opt,name=expiry_ms,json=expiryMs" json:"expiry_ms,omitempty"`
+}
+
+func (m *Snapshot_Nonce) Reset()                    { *m = Snapshot_Nonce{} }
+func (m *Snapshot_Nonce) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot_Nonce) ProtoMessage()               {}
+func (*Snapshot_Nonce) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7, 0} }
+
+type Snapshot_StateTreeNode struct {
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *Snapshot_StateTreeNode) Reset()                    { *m = Snapshot_StateTreeNode{} }
+func (m *Snapshot_StateTreeNode) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot_StateTreeNode) ProtoMessage()               {}
+func (*Snapshot_StateTreeNode) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7, 1} }
+
+func init() {
+	proto.RegisterType((*Tx)(nil), "chain.protocol.bc.bcpb.Tx")
+	proto.RegisterType((*TxInput)(nil), "chain.protocol.bc.bcpb.TxInput")
+	proto.RegisterType((*IssuanceInput)(nil), "chain.protocol.bc.bcpb.IssuanceInput")
+	proto.RegisterType((*SpendInput)(nil), "chain.protocol.bc.bcpb.SpendInput")
+	proto.RegisterType((*TxOutput)(nil), "chain.protocol.bc.bcpb.TxOutput")
+	proto.RegisterType((*BlockHeader)(nil), "chain.protocol.bc.bcpb.BlockHeader")
+	proto.RegisterType((*Block)(nil), "chain.protocol.bc.bcpb.Block")
+	proto.RegisterType((*Snapshot)(nil), "chain.protocol.bc.bcpb.Snapshot")
+	proto.RegisterType((*Snapshot_Nonce)(nil), "chain.protocol.bc.bcpb.Snapshot.Nonce")
+	proto.RegisterType((*Snapshot_StateTreeNode)(nil), "chain.protocol.bc.bcpb.Snapshot.StateTreeNode")
+}
+
+func init() { proto.RegisterFile("bcpb.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1019 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xdd, 0x6e, 0x1b, 0x45,
+	0x14, 0xae, 0xff, 0xd6, 0xf6, 0x59, 0xdb, 0x71, 0x87, 0x36, 0x6c, 0x43, 0x01, 0xd7, 0x51, 0xa8,
+	0x0b, 0xc8, 0x42, 0x41, 0x82, 0xaa, 0x48, 0x45, 0x4a, 0x23, 0x14, 0x5f, 0xa4, 0x44, 0x1b, 0x0b,
+	0x24, 0x6e, 0x56, 0xe3, 0xdd, 0x71, 0x3c, 0x8a, 0x77, 0x66, 0xb5, 0x33, 0x9b, 0x38, 0xf7, 0x3c,
+	0x04, 0xb7, 0x3c, 0x0d, 0x57, 0xbc, 0x06, 0x77, 0xbc, 0x00, 0x57, 0x68, 0x7e, 0x76, 0x63, 0x27,
+	0x36, 0x09, 0xb9, 0xf3, 0x9c, 0xef, 0x7c, 0xe3, 0x73, 0xbe, 0xf3, 0x33, 0x0b, 0x30, 0x09, 0x93,
+	0xc9, 0x30, 0x49, 0xb9, 0xe4, 0x68, 0x3b, 0x9c, 0x61, 0xca, 0xcc, 0x21, 0xe4, 0xf3, 0xe1, 0x24,
+	0x1c, 0x2a, 0xb4, 0xff, 0x67, 0x05, 0xca, 0xe3, 0x05, 0xea, 0x40, 0x99, 0x46, 0x5e, 0xa9, 0x57,
+	0x1a, 0xb4, 0xfc, 0x32, 0x8d, 0x90, 0x07, 0xf5, 0x0b, 0x92, 0x0a, 0xca, 0x99, 0x57, 0xee, 0x95,
+	0x06, 0x55, 0x3f, 0x3f, 0xa2, 0x4f, 0xc0, 0x8d, 0x29, 0x0b, 0x24, 0x8d, 0x49, 0x10, 0x0b, 0xaf,
+	0xa2, 0xd1, 0x66, 0x4c, 0xd9, 0x98, 0xc6, 0xe4, 0x58, 0x68, 0x1c, 0x2f, 0x0a, 0xbc, 0x6a, 0x71,
+	0xbc, 0xb0, 0xf8, 0x0e, 0x34, 0xa6, 0x04, 0xcb, 0x2c, 0x25, 0xc2, 0xab, 0x69, 0xb0, 0x38, 0xa3,
+	0x6f, 0xc1, 0xa1, 0x2c, 0xc9, 0xa4, 0xf0, 0x9c, 0x5e, 0x65, 0xe0, 0xee, 0x7f, 0x3a, 0x5c, 0x1f,
+	0xf5, 0x70, 0xbc, 0x18, 0x29, 0x3f, 0xdf, 0xba, 0xa3, 0x37, 0x50, 0xe7, 0x99, 0xd4, 0xcc, 0xba,
+	0x66, 0xf6, 0x36, 0x33, 0x7f, 0xd4, 0x8e, 0x7e, 0x4e, 0x40, 0x7b, 0xd0, 0x49, 0xc9, 0x94, 0xa4,
+	0x84, 0x85, 0x24, 0x88, 0xb0, 0xc4, 0x5e, 0x43, 0xcb, 0xd0, 0x2e, 0xac, 0x87, 0x58, 0x62, 0xf4,
+	0x3d, 0x3c, 0x8f, 0x88, 0xc4, 0xe1, 0x8c, 0x44, 0xc1, 0xaa, 0x7f, 0x30, 0xc3, 0x62, 0xe6, 0x35,
+	0x35, 0xe9, 0x59, 0xee, 0xe3, 0x2f, 0x93, 0x8f, 0xb0, 0x98, 0xa1, 0xaf, 0xe0, 0x49, 0xc8, 0xe3,
+	0x98, 0xb3, 0x60, 0x4a, 0xc9, 0x3c, 0x12, 0x81, 0xc8, 0xa6, 0x53, 0xba, 0xf0, 0x40, 0x13, 0x91,
+	0xc1, 0x7e, 0xd0, 0xd0, 0xa9, 0x46, 0xd0, 0x3e, 0x3c, 0xb5, 0x8c, 0x4b, 0x2a, 0x19, 0x11, 0x05,
+	0xc5, 0xd5, 0x94, 0x0f, 0x0c, 0xf8, 0xb3, 0xc1, 0x0c, 0xa7, 0xff, 0x5b, 0x05, 0xea, 0x56, 0x1d,
+	0xb4, 0x0b, 0x6d, 0x2c, 0x04, 0x91, 0x41, 0x5e, 0xca, 0x92, 0xd6, 0xbb, 0xa5, 0x8d, 0x3f, 0xd9,
+	0x7a, 0xde, 0x4e, 0xbf, 0xfc, 0x90, 0xf4, 0x2b, 0x77, 0xa5, 0xff, 0x05, 0x3c, 0x56, 0xf1, 0x52,
+	0x19, 0x13, 0x26, 0xf3, 0x44, 0xaa, 0x9a, 0xd5, 0xbd, 0x06, 0x6c, 0xe6, 0x7b, 0xd0, 0xb9, 0x91,
+	0x72, 0xcd, 0x04, 0x75, 0xb9, 0x9c, 0x2c, 0x7a, 0x07, 0x0d, 0x2a, 0x44, 0x86, 0x59, 0x48, 0x3c,
+	0xa7, 0x57, 0x1a, 0xb8, 0xfb, 0x7b, 0x9b, 0xea, 0x3e, 0xb2, 0x7e, 0x5a, 0x99, 0xa3, 0x47, 0x7e,
+	0x41, 0x44, 0x6f, 0xa0, 0x26, 0x12, 0xc2, 0x22, 0xaf, 0xae, 0x6f, 0xe8, 0x6f, 0xba, 0xe1, 0x54,
+	0x39, 0xe5, 0x74, 0x43, 0x41, 0xbb, 0xd0, 0xca, 0xd8, 0x39, 0xe3, 0x97, 0x2c, 0x90, 0x57, 0x09,
+	0xd1, 0x9d, 0xd3, 0x3e, 0x7a, 0xe4, 0xbb, 0xd6, 0x3a, 0xbe, 0x4a, 0xc8, 0x81, 0x03, 0x55, 0x05,
+	0xf6, 0xff, 0x29, 0x41, 0x7b, 0x25, 0x0c, 0xf4, 0x04, 0x6a, 0x8c, 0xab, 0xe0, 0xcd, 0xe0, 0x99,
+	0x03, 0xda, 0x06, 0x07, 0xc7, 0x3c, 0x63, 0xd2, 0x8e, 0x9e, 0x3d, 0xa1, 0x01, 0x74, 0x29, 0xa3,
+	0x92, 0xe2, 0x79, 0x30, 0x99, 0xf3, 0xf0, 0x3c, 0xa0, 0x91, 0x95, 0xbd, 0x63, 0xed, 0x07, 0xca,
+	0x3c, 0x8a, 0xd0, 0x2b, 0xe8, 0x9a, 0xc2, 0x47, 0x64, 0xaa, 0x21, 0xce, 0xac, 0xd4, 0x5b, 0xda,
+	0x7e, 0x58, 0x98, 0xd1, 0xc7, 0x00, 0x17, 0x71, 0xd1, 0x20, 0x66, 0x20, 0x9b, 0x17, 0x71, 0xde,
+	0x1d, 0xaf, 0xa0, 0x9b, 0x0b, 0x15, 0x24, 0x29, 0x3f, 0x4b, 0x71, 0xac, 0x95, 0x6e, 0xf9, 0x5b,
+	0xb9, 0xfd, 0xc4, 0x98, 0xd1, 0x73, 0x68, 0xe2, 0xf4, 0x2c, 0x53, 0x55, 0x34, 0x53, 0xd8, 0xf2,
+	0xaf, 0x0d, 0xfd, 0x3f, 0xca, 0x00, 0xd7, 0x0a, 0xa2, 0x8f, 0xa0, 0x29, 0x78, 0x96, 0x86, 0x24,
+	0x28, 0xd6, 0x4e, 0xc3, 0x18, 0x46, 0x11, 0x7a, 0x09, 0x5b, 0x16, 0x4c, 0xb8, 0x30, 0xd1, 0x1b,
+	0x25, 0x3a, 0xc6, 0x7c, 0x62, 0xad, 0xe8, 0x19, 0x34, 0x4c, 0x9e, 0x85, 0x12, 0x75, 0x7d, 0x1e,
+	0x45, 0x4b, 0x22, 0x56, 0x57, 0x44, 0xbc, 0x23, 0xdf, 0x97, 0xb0, 0x15, 0x72, 0x26, 0x53, 0x3e,
+	0xbf, 0x91, 0x6e, 0xc7, 0x9a, 0xf3, 0x6c, 0xfb, 0xa0, 0x06, 0x64, 0x69, 0x00, 0xea, 0xda, 0xcd,
+	0x4d, 0xc9, 0xb4, 0x68, 0xf9, 0x6f, 0xe0, 0x43, 0xdd, 0x26, 0xc1, 0xed, 0xc6, 0x37, 0x2b, 0xe6,
+	0xa9, 0x86, 0xdf, 0xdd, 0xec, 0xfe, 0x15, 0x25, 0x9b, 0x37, 0x95, 0xfc, 0xab, 0x0c, 0x8d, 0x7c,
+	0x8b, 0xdd, 0x6f, 0xc4, 0x97, 0x65, 0x2a, 0x6f, 0x92, 0xa9, 0xf2, 0x1f, 0x32, 0x55, 0xef, 0x21,
+	0x53, 0x6d, 0xad, 0x4c, 0xb7, 0xb7, 0x8b, 0xf3, 0x90, 0xed, 0x52, 0x7f, 0xd0, 0x76, 0x69, 0xdc,
+	0x7b, 0xbb, 0x34, 0xd7, 0x6c, 0x97, 0xfe, 0xef, 0x15, 0x70, 0xf5, 0x44, 0x1d, 0x11, 0x1c, 0x91,
+	0xf4, 0x7f, 0xbc, 0x91, 0xdb, 0xe0, 0xcc, 0x08, 0x3d, 0x9b, 0x15, 0xaa, 0x9a, 0x13, 0xfa, 0x1c,
+	0x1e, 0x27, 0x29, 0xb9, 0xa0, 0x3c, 0x13, 0xd7, 0x23, 0x6c, 0x07, 0x33, 0x07, 0xf2, 0x19, 0x7e,
+	0x01, 0x2d, 0xf5, 0x86, 0x0a, 0x89, 0xe3, 0x44, 0x3d, 0xa4, 0xa6, 0x55, 0xdd, 0xc2, 0x76, 0x2c,
+	0xd0, 0x6b, 0xf0, 0x64, 0x8a, 0x99, 0xc0, 0xa1, 0x9a, 0x06, 0x11, 0xc4, 0x24, 0x3d, 0x9f, 0x93,
+	0x20, 0xe5, 0x5c, 0x5a, 0x99, 0xb7, 0x97, 0xf1, 0x63, 0x0d, 0xfb, 0x9c, 0x4b, 0xf4, 0x25, 0x20,
+	0xdd, 0x01, 0xab, 0x1c, 0xa3, 0xb2, 0x59, 0x1d, 0xcb, 0xde, 0x5a, 0x5c, 0x26, 0x08, 0x13, 0x99,
+	0x28, 0xea, 0x5d, 0x88, 0x6b, 0x81, 0xbc, 0xe2, 0x6b, 0x2b, 0xd1, 0xdc, 0x50, 0x09, 0x0f, 0xea,
+	0x56, 0x73, 0x0f, 0x74, 0x9f, 0xe7, 0xc7, 0x35, 0x35, 0x72, 0xd7, 0xd5, 0xe8, 0xd7, 0x12, 0xd4,
+	0xb4, 0x62, 0xe8, 0x3b, 0xa5, 0xb9, 0xaa, 0x93, 0xae, 0x90, 0xbb, 0xbf, 0xbb, 0x69, 0x8f, 0x2f,
+	0x95, 0xd4, 0xb7, 0x14, 0xf4, 0x16, 0x5a, 0xcb, 0x4a, 0x79, 0x65, 0xfd, 0x11, 0xb1, 0xb3, 0xf9,
+	0x23, 0xc2, 0x5f, 0xf1, 0xef, 0xff, 0x5d, 0x82, 0xc6, 0x29, 0xc3, 0x89, 0x98, 0x71, 0x89, 0x0e,
+	0xd5, 0x56, 0x8f, 0x88, 0xf0, 0x4a, 0xfa, 0x96, 0xe1, 0xc6, 0x07, 0xc5, 0x12, 0x86, 0xa7, 0x12,
+	0x4b, 0x32, 0x4e, 0x09, 0x79, 0xcf, 0x23, 0xe2, 0x1b, 0x32, 0x7a, 0x0b, 0x8e, 0x7e, 0x0e, 0xf2,
+	0x60, 0x3e, 0xbb, 0xf3, 0x9a, 0xf7, 0xca, 0xdd, 0xb7, 0xac, 0x9d, 0xd7, 0x50, 0xd3, 0x06, 0x84,
+	0xa0, 0xaa, 0x67, 0xc8, 0x34, 0xae, 0xfe, 0xad, 0xd6, 0x2f, 0x59, 0x24, 0x34, 0xbd, 0x52, 0x9d,
+	0x65, 0x9a, 0xb7, 0x61, 0x0c, 0xc7, 0x62, 0xe7, 0x05, 0xb4, 0x57, 0x22, 0x42, 0x5d, 0xa8, 0x9c,
+	0x93, 0x2b, 0x7b, 0x81, 0xfa, 0x79, 0xe0, 0xfc, 0x52, 0x55, 0xff, 0x3d, 0x71, 0x74, 0x34, 0x5f,
+	0xff, 0x3b, 0x00, 0x68, 0x72, 0xbf, 0xf9, 0x6a, 0x0a, 0x00, 0x00,
+}
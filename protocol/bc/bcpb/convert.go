@@ -0,0 +1,351 @@
+package bcpb
+
+//go:generate protoc --go_out=. bcpb.proto
+
+import (
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+	"chain/protocol/state"
+)
+
+// ErrBadProto is returned when converting a protobuf
+// that doesn't represent a valid transaction, block
+// or snapshot.
+var ErrBadProto = errors.New("malformed protobuf")
+
+// TxToProto returns the protobuf form of tx.
+func TxToProto(tx *legacy.Tx) *Tx {
+	p := &Tx{
+		Id:                        tx.ID.Bytes(),
+		Version:                   tx.Version,
+		MinTimeMs:                 tx.MinTime,
+		MaxTimeMs:                 tx.MaxTime,
+		Features:                  uint64(tx.TxData.Features),
+		ReferenceData:             tx.ReferenceData,
+		DetachedReferenceDataHash: optionalHashBytes(tx.TxData.DetachedRefDataHash),
+		CommonFieldsSuffix:        tx.CommonFieldsSuffix,
+		CommonWitnessSuffix:       tx.CommonWitnessSuffix,
+		Inputs:                    make([]*TxInput, 0, len(tx.Inputs)),
+		Outputs:                   make([]*TxOutput, 0, len(tx.Outputs)),
+	}
+	for _, in := range tx.Inputs {
+		p.Inputs = append(p.Inputs, fromTxInput(in))
+	}
+	for _, out := range tx.Outputs {
+		p.Outputs = append(p.Outputs, &TxOutput{
+			AssetVersion:              out.AssetVersion,
+			AssetId:                   out.AssetId.Bytes(),
+			Amount:                    out.Amount,
+			VmVersion:                 out.VMVersion,
+			ControlProgram:            out.ControlProgram,
+			ReferenceData:             out.ReferenceData,
+			DetachedReferenceDataHash: optionalHashBytes(out.DetachedRefDataHash),
+			CommitmentSuffix:          out.CommitmentSuffix,
+			WitnessSuffix:             out.WitnessSuffix,
+		})
+	}
+	return p
+}
+
+func fromTxInput(in *legacy.TxInput) *TxInput {
+	p := &TxInput{
+		AssetVersion:              in.AssetVersion,
+		ReferenceData:             in.ReferenceData,
+		DetachedReferenceDataHash: optionalHashBytes(in.DetachedRefDataHash),
+		CommitmentSuffix:          in.CommitmentSuffix,
+		WitnessSuffix:             in.WitnessSuffix,
+	}
+	switch ti := in.TypedInput.(type) {
+	case *legacy.IssuanceInput:
+		p.Type = &TxInput_Issuance{&IssuanceInput{
+			Nonce:           ti.Nonce,
+			Amount:          ti.Amount,
+			InitialBlockId:  ti.InitialBlock.Bytes(),
+			AssetDefinition: ti.AssetDefinition,
+			VmVersion:       ti.VMVersion,
+			IssuanceProgram: ti.IssuanceProgram,
+			Arguments:       ti.Arguments,
+		}}
+	case *legacy.SpendInput:
+		p.Type = &TxInput_Spend{&SpendInput{
+			SourceId:              ti.SourceID.Bytes(),
+			SourcePosition:        ti.SourcePosition,
+			AssetId:               ti.AssetId.Bytes(),
+			Amount:                ti.Amount,
+			VmVersion:             ti.VMVersion,
+			ControlProgram:        ti.ControlProgram,
+			RefDataHash:           ti.RefDataHash.Bytes(),
+			SpendCommitmentSuffix: ti.SpendCommitmentSuffix,
+			Arguments:             ti.Arguments,
+		}}
+	case *legacy.UnknownInput:
+		p.Type = &TxInput_UnknownType{uint32(ti.Type)}
+	}
+	return p
+}
+
+// TxFromProto converts p to a transaction. It returns ErrBadProto
+// if p is malformed or has an ID that doesn't match.
+func TxFromProto(p *Tx) (*legacy.Tx, error) {
+	data := legacy.TxData{
+		Version:             p.Version,
+		MinTime:             p.MinTimeMs,
+		MaxTime:             p.MaxTimeMs,
+		Features:            bc.TxFeatures(p.Features),
+		ReferenceData:       p.ReferenceData,
+		CommonFieldsSuffix:  p.CommonFieldsSuffix,
+		CommonWitnessSuffix: p.CommonWitnessSuffix,
+	}
+	var err error
+	data.DetachedRefDataHash, err = optionalHash(p.DetachedReferenceDataHash, "detached reference data hash")
+	if err != nil {
+		return nil, err
+	}
+	for i, pin := range p.Inputs {
+		in, err := toTxInput(pin)
+		if err != nil {
+			return nil, errors.Wrapf(err, "input %d", i)
+		}
+		data.Inputs = append(data.Inputs, in)
+	}
+	for i, pout := range p.Outputs {
+		out, err := toTxOutput(pout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "output %d", i)
+		}
+		data.Outputs = append(data.Outputs, out)
+	}
+
+	tx := legacy.NewTx(data)
+	if len(p.Id) > 0 && string(p.Id) != string(tx.ID.Bytes()) {
+		return nil, errors.WithDetailf(ErrBadProto, "transaction ID %x does not match contents, which hash to %x", p.Id, tx.ID.Bytes())
+	}
+	return tx, nil
+}
+
+func toTxInput(p *TxInput) (*legacy.TxInput, error) {
+	in := &legacy.TxInput{
+		AssetVersion:     p.AssetVersion,
+		ReferenceData:    p.ReferenceData,
+		CommitmentSuffix: p.CommitmentSuffix,
+		WitnessSuffix:    p.WitnessSuffix,
+	}
+	var err error
+	in.DetachedRefDataHash, err = optionalHash(p.DetachedReferenceDataHash, "detached reference data hash")
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := p.Type.(type) {
+	case *TxInput_Issuance:
+		iss := t.Issuance
+		initialBlock, err := hash(iss.InitialBlockId, "initial block ID")
+		if err != nil {
+			return nil, err
+		}
+		in.TypedInput = &legacy.IssuanceInput{
+			Nonce:  iss.Nonce,
+			Amount: iss.Amount,
+			IssuanceWitness: legacy.IssuanceWitness{
+				InitialBlock:    initialBlock,
+				AssetDefinition: iss.AssetDefinition,
+				VMVersion:       iss.VmVersion,
+				IssuanceProgram: iss.IssuanceProgram,
+				Arguments:       iss.Arguments,
+			},
+		}
+	case *TxInput_Spend:
+		sp := t.Spend
+		sourceID, err := hash(sp.SourceId, "source ID")
+		if err != nil {
+			return nil, err
+		}
+		assetID, err := hash(sp.AssetId, "asset ID")
+		if err != nil {
+			return nil, err
+		}
+		refDataHash, err := hash(sp.RefDataHash, "output reference data hash")
+		if err != nil {
+			return nil, err
+		}
+		aid := bc.AssetID(assetID)
+		in.TypedInput = &legacy.SpendInput{
+			SpendCommitment: legacy.SpendCommitment{
+				AssetAmount:    bc.AssetAmount{AssetId: &aid, Amount: sp.Amount},
+				SourceID:       sourceID,
+				SourcePosition: sp.SourcePosition,
+				VMVersion:      sp.VmVersion,
+				ControlProgram: sp.ControlProgram,
+				RefDataHash:    refDataHash,
+			},
+			SpendCommitmentSuffix: sp.SpendCommitmentSuffix,
+			Arguments:             sp.Arguments,
+		}
+	case *TxInput_UnknownType:
+		if t.UnknownType > 255 {
+			return nil, errors.WithDetailf(ErrBadProto, "input type %d", t.UnknownType)
+		}
+		in.TypedInput = &legacy.UnknownInput{Type: byte(t.UnknownType)}
+	default:
+		return nil, errors.WithDetail(ErrBadProto, "input has no type")
+	}
+	return in, nil
+}
+
+func toTxOutput(p *TxOutput) (*legacy.TxOutput, error) {
+	assetID, err := hash(p.AssetId, "asset ID")
+	if err != nil {
+		return nil, err
+	}
+	detached, err := optionalHash(p.DetachedReferenceDataHash, "detached reference data hash")
+	if err != nil {
+		return nil, err
+	}
+	aid := bc.AssetID(assetID)
+	return &legacy.TxOutput{
+		AssetVersion: p.AssetVersion,
+		OutputCommitment: legacy.OutputCommitment{
+			AssetAmount:    bc.AssetAmount{AssetId: &aid, Amount: p.Amount},
+			VMVersion:      p.VmVersion,
+			ControlProgram: p.ControlProgram,
+		},
+		CommitmentSuffix:    p.CommitmentSuffix,
+		WitnessSuffix:       p.WitnessSuffix,
+		ReferenceData:       p.ReferenceData,
+		DetachedRefDataHash: detached,
+	}, nil
+}
+
+// BlockToProto returns the protobuf form of b.
+func BlockToProto(b *legacy.Block) *Block {
+	p := &Block{
+		Header: &BlockHeader{
+			Id:                     b.Hash().Bytes(),
+			Version:                b.Version,
+			Height:                 b.Height,
+			PreviousBlockId:        b.PreviousBlockHash.Bytes(),
+			TimestampMs:            b.TimestampMS,
+			TransactionsMerkleRoot: b.TransactionsMerkleRoot.Bytes(),
+			AssetsMerkleRoot:       b.AssetsMerkleRoot.Bytes(),
+			ConsensusProgram:       b.ConsensusProgram,
+			CommitmentSuffix:       b.CommitmentSuffix,
+			Witness:                b.Witness,
+			WitnessSuffix:          b.WitnessSuffix,
+		},
+		Transactions: make([]*Tx, 0, len(b.Transactions)),
+	}
+	for _, tx := range b.Transactions {
+		p.Transactions = append(p.Transactions, TxToProto(tx))
+	}
+	return p
+}
+
+// BlockFromProto converts p to a block. It returns ErrBadProto if p
+// is malformed or if it or any of its transactions has an ID
+// that doesn't match.
+func BlockFromProto(p *Block) (*legacy.Block, error) {
+	h := p.Header
+	if h == nil {
+		return nil, errors.WithDetail(ErrBadProto, "block has no header")
+	}
+	b := new(legacy.Block)
+	b.Version = h.Version
+	b.Height = h.Height
+	b.TimestampMS = h.TimestampMs
+	b.ConsensusProgram = h.ConsensusProgram
+	b.CommitmentSuffix = h.CommitmentSuffix
+	b.Witness = h.Witness
+	b.WitnessSuffix = h.WitnessSuffix
+
+	var err error
+	b.PreviousBlockHash, err = hash(h.PreviousBlockId, "previous block ID")
+	if err != nil {
+		return nil, err
+	}
+	b.TransactionsMerkleRoot, err = hash(h.TransactionsMerkleRoot, "transactions merkle root")
+	if err != nil {
+		return nil, err
+	}
+	b.AssetsMerkleRoot, err = hash(h.AssetsMerkleRoot, "assets merkle root")
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Id) > 0 && string(h.Id) != string(b.Hash().Bytes()) {
+		return nil, errors.WithDetailf(ErrBadProto, "block ID %x does not match header, which hashes to %x", h.Id, b.Hash().Bytes())
+	}
+
+	for i, ptx := range p.Transactions {
+		tx, err := TxFromProto(ptx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction %d", i)
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	return b, nil
+}
+
+// SnapshotToProto returns the protobuf form of s.
+func SnapshotToProto(s *state.Snapshot) (*Snapshot, error) {
+	p := new(Snapshot)
+	err := patricia.Walk(s.Tree, func(key []byte) error {
+		p.Nodes = append(p.Nodes, &Snapshot_StateTreeNode{Key: key})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walking state tree")
+	}
+	p.Nonces = make([]*Snapshot_Nonce, 0, len(s.Nonces))
+	for h, expiry := range s.Nonces {
+		p.Nonces = append(p.Nonces, &Snapshot_Nonce{Hash: h.Bytes(), ExpiryMs: expiry})
+	}
+	return p, nil
+}
+
+// SnapshotFromProto converts p to a snapshot.
+func SnapshotFromProto(p *Snapshot) (*state.Snapshot, error) {
+	s := state.Empty()
+	for i, n := range p.Nodes {
+		err := s.Tree.Insert(n.Key)
+		if err != nil {
+			return nil, errors.Sub(ErrBadProto, errors.Wrapf(err, "state tree node %d", i))
+		}
+	}
+	for _, n := range p.Nonces {
+		h, err := hash(n.Hash, "nonce hash")
+		if err != nil {
+			return nil, err
+		}
+		s.Nonces[h] = n.ExpiryMs
+	}
+	return s, nil
+}
+
+func hash(b []byte, what string) (bc.Hash, error) {
+	if len(b) != 32 {
+		return bc.Hash{}, errors.WithDetailf(ErrBadProto, "%s is %d bytes, want 32", what, len(b))
+	}
+	var b32 [32]byte
+	copy(b32[:], b)
+	return bc.NewHash(b32), nil
+}
+
+// optionalHash is like hash, but returns nil for an empty b.
+func optionalHash(b []byte, what string) (*bc.Hash, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	h, err := hash(b, what)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func optionalHashBytes(h *bc.Hash) []byte {
+	if h == nil {
+		return nil
+	}
+	return h.Bytes()
+}
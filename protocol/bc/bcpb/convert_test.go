@@ -0,0 +1,141 @@
+package bcpb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/golang/protobuf/proto"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func sampleTx() *legacy.Tx {
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		MinTime: 5,
+		MaxTime: 6,
+		Inputs: []*legacy.TxInput{
+			legacy.NewIssuanceInput([]byte{10}, 100, []byte("issref"), bc.NewHash([32]byte{1}), []byte{1}, [][]byte{{2}, {3}}, []byte("{}")),
+			legacy.NewSpendInput([][]byte{{4}}, bc.NewHash([32]byte{2}), bc.AssetID{}, 50, 1, []byte{5}, bc.NewHash([32]byte{3}), nil),
+			{AssetVersion: 2, TypedInput: &legacy.UnknownInput{Type: 9}, CommitmentSuffix: []byte{8}},
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(bc.AssetID{}, 150, []byte{6}, []byte("outref")),
+		},
+		ReferenceData: []byte("txref"),
+	})
+	tx.Outputs[0].CommitmentSuffix = []byte{7}
+	return legacy.NewTx(tx.TxData)
+}
+
+func TestTxRoundTrip(t *testing.T) {
+	tx := sampleTx()
+	tx.DetachReferenceData()
+	tx = legacy.NewTx(tx.TxData)
+
+	b, err := proto.Marshal(TxToProto(tx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Tx
+	err = proto.Unmarshal(b, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := TxFromProto(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tx.ID {
+		t.Errorf("got ID %x, want %x", got.ID.Bytes(), tx.ID.Bytes())
+	}
+	if !testutil.DeepEqual(got.TxData, tx.TxData) {
+		t.Errorf("round trip got:\n%swant:\n%s", spew.Sdump(got.TxData), spew.Sdump(tx.TxData))
+	}
+}
+
+func TestTxFromProtoErrors(t *testing.T) {
+	cases := []func(p *Tx){
+		func(p *Tx) { p.Id = bytes.Repeat([]byte{1}, 32) },
+		func(p *Tx) { p.DetachedReferenceDataHash = []byte{1} },
+		func(p *Tx) { p.Inputs[1].GetSpend().SourceId = nil },
+		func(p *Tx) { p.Inputs[2].Type = &TxInput_UnknownType{256} },
+		func(p *Tx) { p.Inputs[0].Type = nil },
+		func(p *Tx) { p.Outputs[0].AssetId = []byte{1, 2} },
+	}
+	for i, c := range cases {
+		p := TxToProto(sampleTx())
+		c(p)
+		_, err := TxFromProto(p)
+		if errors.Root(err) != ErrBadProto {
+			t.Errorf("case %d: TxFromProto() = %v, want %s", i, err, ErrBadProto)
+		}
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           1,
+			Height:            2,
+			PreviousBlockHash: bc.NewHash([32]byte{4}),
+			TimestampMS:       1000,
+			BlockCommitment: legacy.BlockCommitment{
+				TransactionsMerkleRoot: bc.NewHash([32]byte{5}),
+				AssetsMerkleRoot:       bc.NewHash([32]byte{6}),
+				ConsensusProgram:       []byte{7},
+			},
+			BlockWitness: legacy.BlockWitness{Witness: [][]byte{{8}}},
+		},
+		Transactions: []*legacy.Tx{sampleTx()},
+	}
+
+	p := BlockToProto(b)
+	got, err := BlockFromProto(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash() != b.Hash() {
+		t.Errorf("got block ID %x, want %x", got.Hash().Bytes(), b.Hash().Bytes())
+	}
+	if !testutil.DeepEqual(got, b) {
+		t.Errorf("round trip got:\n%swant:\n%s", spew.Sdump(got), spew.Sdump(b))
+	}
+
+	p.Header.Height++
+	_, err = BlockFromProto(p)
+	if errors.Root(err) != ErrBadProto {
+		t.Errorf("BlockFromProto(wrong ID) = %v, want %s", err, ErrBadProto)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := state.Empty()
+	for i := byte(1); i <= 3; i++ {
+		err := s.Tree.Insert(bc.NewHash([32]byte{i}).Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Nonces[bc.NewHash([32]byte{9})] = 1000
+
+	p, err := SnapshotToProto(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SnapshotFromProto(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tree.RootHash() != s.Tree.RootHash() {
+		t.Errorf("got state tree root %x, want %x", got.Tree.RootHash().Bytes(), s.Tree.RootHash().Bytes())
+	}
+	if !testutil.DeepEqual(got.Nonces, s.Nonces) {
+		t.Errorf("got nonces %v, want %v", got.Nonces, s.Nonces)
+	}
+}
@@ -65,7 +65,7 @@ func NewIssuanceTx(tb testing.TB, initial bc.Hash, opts ...func(*legacy.Tx)) *le
 
 	// Sign with a simple TXSIGHASH signature.
 	builder = vmutil.NewBuilder()
-	h := tx.SigHash(0)
+	h := tx.SigHash(0, initial)
 	builder.AddData(h.Bytes())
 	builder.AddOp(vm.OP_TXSIGHASH).AddOp(vm.OP_EQUAL)
 	sigprog, _ := builder.Build()
@@ -5,3 +5,84 @@ type Block struct {
 	ID           Hash
 	Transactions []*Tx
 }
+
+// SpentOutput is an output spent by a transaction in a block.
+// See Block.Spends.
+type SpentOutput struct {
+	TxID        Hash
+	InputIndex  uint32
+	OutputID    Hash
+	AssetAmount AssetAmount
+
+	// ControlProgram is the spent output's control program.
+	// It is nil if the transaction's entries don't include
+	// the spent output, as when it was decoded without
+	// prevouts.
+	ControlProgram *Program
+
+	Spend *Spend
+}
+
+// CreatedOutput is an output created by a transaction in a
+// block. See Block.NewOutputs.
+type CreatedOutput struct {
+	TxID           Hash
+	OutputIndex    uint32
+	OutputID       Hash
+	AssetAmount    AssetAmount
+	ControlProgram Program
+
+	Output *Output
+}
+
+// Spends returns the outputs spent in b, in transaction
+// and input order. Issuances are skipped.
+func (b *Block) Spends() []SpentOutput {
+	var spends []SpentOutput
+	for _, tx := range b.Transactions {
+		for i, inputID := range tx.InputIDs {
+			sp, ok := tx.Entries[inputID].(*Spend)
+			if !ok {
+				continue
+			}
+			s := SpentOutput{
+				TxID:       tx.ID,
+				InputIndex: uint32(i),
+				OutputID:   *sp.SpentOutputId,
+				Spend:      sp,
+			}
+			if sp.WitnessDestination != nil {
+				s.AssetAmount = *sp.WitnessDestination.Value
+			}
+			if prevout, ok := tx.Entries[*sp.SpentOutputId].(*Output); ok {
+				s.AssetAmount = *prevout.Source.Value
+				s.ControlProgram = prevout.ControlProgram
+			}
+			spends = append(spends, s)
+		}
+	}
+	return spends
+}
+
+// NewOutputs returns the outputs created in b, in transaction
+// and output order. Retirements are skipped.
+func (b *Block) NewOutputs() []CreatedOutput {
+	var outs []CreatedOutput
+	for _, tx := range b.Transactions {
+		for i, id := range tx.ResultIds {
+			o, ok := tx.Entries[*id].(*Output)
+			if !ok {
+				continue
+			}
+			outs = append(outs, CreatedOutput{
+				TxID:           tx.ID,
+				OutputIndex:    uint32(i),
+				OutputID:       *id,
+				AssetAmount:    *o.Source.Value,
+				ControlProgram: *o.ControlProgram,
+				Output:         o,
+			})
+		}
+	}
+	return outs
+}
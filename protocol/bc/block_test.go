@@ -0,0 +1,65 @@
+package bc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockSpendsAndNewOutputs(t *testing.T) {
+	var (
+		assetID = AssetID{V0: 1}
+		value   = &AssetAmount{AssetId: &assetID, Amount: 5}
+		prog    = &Program{VmVersion: 1, Code: []byte{0x51}}
+		data    = &Hash{}
+		entries = make(map[Hash]Entry)
+	)
+	add := func(e Entry) Hash {
+		id := EntryID(e)
+		entries[id] = e
+		return id
+	}
+
+	prevout := NewOutput(&ValueSource{Ref: &Hash{V0: 9}, Value: value}, &Program{VmVersion: 1, Code: []byte{0x52}}, data, 0)
+	prevoutID := add(prevout)
+	sp := NewSpend(&prevoutID, data, 1)
+	spID := add(sp)
+	iss := NewIssuance(&spID, value, data, 0)
+	issID := add(iss)
+
+	out := NewOutput(&ValueSource{Ref: &spID, Value: value}, prog, data, 0)
+	outID := add(out)
+	ret := NewRetirement(&ValueSource{Ref: &issID, Value: value}, data, 1)
+	retID := add(ret)
+
+	tx := &Tx{
+		TxHeader: NewTxHeader(1, []*Hash{&retID, &outID}, data, 0, 0),
+		ID:       Hash{V0: 7},
+		Entries:  entries,
+		InputIDs: []Hash{issID, spID},
+	}
+	b := &Block{Transactions: []*Tx{tx}}
+
+	wantSpends := []SpentOutput{{
+		TxID:           tx.ID,
+		InputIndex:     1,
+		OutputID:       prevoutID,
+		AssetAmount:    *value,
+		ControlProgram: prevout.ControlProgram,
+		Spend:          sp,
+	}}
+	if got := b.Spends(); !reflect.DeepEqual(got, wantSpends) {
+		t.Errorf("Spends() = %+v, want %+v", got, wantSpends)
+	}
+
+	wantOuts := []CreatedOutput{{
+		TxID:           tx.ID,
+		OutputIndex:    1,
+		OutputID:       outID,
+		AssetAmount:    *value,
+		ControlProgram: *prog,
+		Output:         out,
+	}}
+	if got := b.NewOutputs(); !reflect.DeepEqual(got, wantOuts) {
+		t.Errorf("NewOutputs() = %+v, want %+v", got, wantOuts)
+	}
+}
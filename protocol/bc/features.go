@@ -0,0 +1,36 @@
+package bc
+
+import (
+	"chain/crypto/sha3pool"
+	"chain/encoding/blockchain"
+)
+
+// TxFeatureVersion is the first transaction version that carries
+// feature bits. The bits appear in the transaction's common fields,
+// and its header commits to them in ExtHash.
+//
+// Note that version 1 blocks accept only version 1 transactions.
+const TxFeatureVersion = 3
+
+// TxFeatures is a bit vector of optional rule sets that a
+// transaction opts in to. Introducing a rule set as a soft fork
+// takes a new bit and a validation hook, rather than a new
+// transaction version. Nodes that don't know a bit ignore it.
+type TxFeatures uint64
+
+// Has reports whether bit is set in f.
+func (f TxFeatures) Has(bit uint) bool {
+	return bit < 64 && f&(1<<bit) != 0
+}
+
+// Hash returns the commitment to f that
+// goes in the ExtHash of a transaction header.
+func (f TxFeatures) Hash() (h Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write([]byte("txfeatures"))
+	blockchain.WriteVarint63(hasher, uint64(f))
+	h.ReadFrom(hasher)
+	return h
+}
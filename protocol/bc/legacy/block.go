@@ -32,7 +32,7 @@ type Block struct {
 // This guarantees that blocks will get deserialized correctly
 // when being parsed from HTTP requests.
 func (b *Block) MarshalText() ([]byte, error) {
-	buf := bufpool.Get()
+	buf := bufpool.GetLarge()
 	defer bufpool.Put(buf)
 	_, err := b.WriteTo(buf)
 	if err != nil {
@@ -52,8 +52,15 @@ func (b *Block) UnmarshalText(text []byte) error {
 		return err
 	}
 
-	r := blockchain.NewReader(decoded)
-	err = b.readFrom(r)
+	return b.Decode(decoded)
+}
+
+// Decode decodes a serialized block from buf. Unlike transactions
+// submitted to a Core, blocks are decoded without Limits: every
+// node must accept every valid block, however large.
+func (b *Block) Decode(buf []byte) error {
+	r := blockchain.NewReader(buf)
+	err := b.readFrom(r)
 	if err != nil {
 		return err
 	}
@@ -71,15 +78,7 @@ func (b *Block) Scan(val interface{}) error {
 	}
 	buf := make([]byte, len(driverBuf))
 	copy(buf[:], driverBuf)
-	r := blockchain.NewReader(buf)
-	err := b.readFrom(r)
-	if err != nil {
-		return err
-	}
-	if trailing := r.Len(); trailing > 0 {
-		return fmt.Errorf("trailing garbage (%d bytes)", trailing)
-	}
-	return nil
+	return b.Decode(buf)
 }
 
 // Value fulfills the sql.driver.Valuer interface.
@@ -102,16 +101,19 @@ func (b *Block) readFrom(r *blockchain.Reader) error {
 		if err != nil {
 			return errors.Wrap(err, "reading number of transactions")
 		}
+		var datas []*TxData
 		for ; n > 0; n-- {
-			var data TxData
-			err = data.readFrom(r)
+			data := new(TxData)
+			err = data.readFrom(r, Limits{})
 			if err != nil {
-				return errors.Wrapf(err, "reading transaction %d", len(b.Transactions))
+				return errors.Wrapf(err, "reading transaction %d", len(datas))
 			}
-			// TODO(kr): store/reload hashes;
-			// don't compute here if not necessary.
-			tx := NewTx(data)
-			b.Transactions = append(b.Transactions, tx)
+			datas = append(datas, data)
+		}
+		// TODO(kr): store/reload hashes;
+		// don't compute here if not necessary.
+		for i, tx := range HashTxs(datas) {
+			b.Transactions = append(b.Transactions, &Tx{TxData: *datas[i], Tx: tx})
 		}
 	}
 	return nil
@@ -0,0 +1,86 @@
+package legacy
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FuzzTx is an entry point for go-fuzz and similar tools. It
+// decodes data as a serialized transaction and, if that succeeds,
+// checks that re-encoding it is stable: the encoding decodes to a
+// transaction with the same ID, which encodes to the same bytes.
+// It panics if not.
+//
+// The encoding need not equal data, since the decoder accepts
+// varints that aren't minimally encoded.
+//
+// FuzzTx returns 1 if data decodes, and 0 otherwise.
+func FuzzTx(data []byte) int {
+	var tx TxData
+	if tx.DecodeLimited(data, DefaultLimits) != nil {
+		return 0
+	}
+	enc := encodeTx(&tx)
+
+	var tx2 TxData
+	err := tx2.DecodeLimited(enc, DefaultLimits)
+	if err != nil {
+		panic(fmt.Errorf("decoding re-encoded tx %x: %s", enc, err))
+	}
+	if id, id2 := NewTx(tx).ID, NewTx(tx2).ID; id != id2 {
+		panic(fmt.Errorf("tx ID changed from %x to %x on re-encoding %x", id.Bytes(), id2.Bytes(), data))
+	}
+	if enc2 := encodeTx(&tx2); !bytes.Equal(enc, enc2) {
+		panic(fmt.Errorf("tx encoding %x re-encoded as %x", enc, enc2))
+	}
+	return 1
+}
+
+// FuzzBlock is like FuzzTx, for serialized blocks.
+// It checks the block hash and the transaction IDs.
+func FuzzBlock(data []byte) int {
+	var b Block
+	if b.Decode(data) != nil {
+		return 0
+	}
+	enc := encodeBlock(&b)
+
+	var b2 Block
+	err := b2.Decode(enc)
+	if err != nil {
+		panic(fmt.Errorf("decoding re-encoded block %x: %s", enc, err))
+	}
+	if h, h2 := b.Hash(), b2.Hash(); h != h2 {
+		panic(fmt.Errorf("block hash changed from %x to %x on re-encoding %x", h.Bytes(), h2.Bytes(), data))
+	}
+	if len(b.Transactions) != len(b2.Transactions) {
+		panic(fmt.Errorf("block had %d transactions, re-encoded has %d", len(b.Transactions), len(b2.Transactions)))
+	}
+	for i, tx := range b.Transactions {
+		if tx.ID != b2.Transactions[i].ID {
+			panic(fmt.Errorf("block transaction %d ID changed from %x to %x", i, tx.ID.Bytes(), b2.Transactions[i].ID.Bytes()))
+		}
+	}
+	if enc2 := encodeBlock(&b2); !bytes.Equal(enc, enc2) {
+		panic(fmt.Errorf("block encoding %x re-encoded as %x", enc, enc2))
+	}
+	return 1
+}
+
+func encodeTx(tx *TxData) []byte {
+	var buf bytes.Buffer
+	_, err := tx.WriteTo(&buf)
+	if err != nil {
+		panic(fmt.Errorf("encoding decoded tx: %s", err))
+	}
+	return buf.Bytes()
+}
+
+func encodeBlock(b *Block) []byte {
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	if err != nil {
+		panic(fmt.Errorf("encoding decoded block: %s", err))
+	}
+	return buf.Bytes()
+}
@@ -1,6 +1,11 @@
 package legacy
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+
+	"chain/protocol/bc"
+)
 
 func TestFuzzUnknownAssetVersion(t *testing.T) {
 	const rawTx = `07010700f785c1f1b72b0001f1b72b0001012b00089def834ab929327f3f479177e2d8c293f2f7fc4f251db8547896c0eeafb984261a73767178584c246400b50150935a092ffad7ec9fbac4f4486db6c3b8cd5b9f51cf697248584dde286a722000012b766baa20627e83fdad13dd98436fa7cbdd1412d50ef65528edb7e2ed8f2675b2a0b209235151ad696c00c0030040b984261ad6e71876ec4c2464012b766baa209d44ee5b6ebf6c408772ead7713f1a66b9de7655ff452513487be1fb10de7d985151ad696c00c02a7b2274657374223a225175657279546573742e7465737442616c616e636551756572792e74657374227d`
@@ -26,3 +31,68 @@ func TestFuzzUnknownAssetVersion(t *testing.T) {
 		t.Errorf("tx id changed to %s", got.ID.String())
 	}
 }
+
+func TestFuzzEntryPoints(t *testing.T) {
+	tx := NewTx(TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{{1}}, bc.NewHash([32]byte{2}), bc.AssetID{}, 5, 0, []byte{3}, bc.Hash{}, nil),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(bc.AssetID{}, 5, []byte{4}, nil),
+		},
+	})
+	var txBuf bytes.Buffer
+	_, err := tx.WriteTo(&txBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := &Block{
+		BlockHeader:  BlockHeader{Version: 1, Height: 2},
+		Transactions: []*Tx{tx},
+	}
+	var blockBuf bytes.Buffer
+	_, err = block.WriteTo(&blockBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FuzzTx(txBuf.Bytes()); got != 1 {
+		t.Errorf("FuzzTx(valid tx) = %d, want 1", got)
+	}
+	if got := FuzzBlock(blockBuf.Bytes()); got != 1 {
+		t.Errorf("FuzzBlock(valid block) = %d, want 1", got)
+	}
+
+	// Commitment suffixes survive re-encoding. They used to be
+	// written twice.
+	suffixed := NewTx(TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewSpendInput(nil, bc.NewHash([32]byte{2}), bc.AssetID{}, 5, 0, []byte{3}, bc.Hash{}, nil),
+		},
+		Outputs: []*TxOutput{
+			{AssetVersion: 2, CommitmentSuffix: []byte{5, 6, 7}},
+		},
+	})
+	suffixed.Inputs[0].TypedInput.(*SpendInput).SpendCommitmentSuffix = []byte{8, 9}
+	var suffixedBuf bytes.Buffer
+	_, err = suffixed.WriteTo(&suffixedBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FuzzTx(suffixedBuf.Bytes()); got != 1 {
+		t.Errorf("FuzzTx(tx with suffixes) = %d, want 1", got)
+	}
+
+	// Truncations and garbage are rejected without panicking.
+	for _, data := range [][]byte{nil, {0xff}, txBuf.Bytes()[:txBuf.Len()-1], append(txBuf.Bytes(), 0)} {
+		if got := FuzzTx(data); got != 0 {
+			t.Errorf("FuzzTx(%x) = %d, want 0", data, got)
+		}
+	}
+	if got := FuzzBlock(blockBuf.Bytes()[:blockBuf.Len()-1]); got != 0 {
+		t.Errorf("FuzzBlock(truncated) = %d, want 0", got)
+	}
+}
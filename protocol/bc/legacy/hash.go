@@ -0,0 +1,45 @@
+package legacy
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"chain/protocol/bc"
+)
+
+// HashTxs maps each of txs to its entries-based representation,
+// as MapTx does, computing the hashes of different transactions
+// concurrently on up to GOMAXPROCS goroutines. The result is in
+// the same order as txs.
+func HashTxs(txs []*TxData) []*bc.Tx {
+	res := make([]*bc.Tx, len(txs))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	if workers <= 1 {
+		for i, tx := range txs {
+			res[i] = MapTx(tx)
+		}
+		return res
+	}
+
+	next := int64(-1)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(txs) {
+					return
+				}
+				res[i] = MapTx(txs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return res
+}
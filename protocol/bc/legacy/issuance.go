@@ -9,11 +9,12 @@ type IssuanceInput struct {
 	// Commitment
 	Nonce  []byte
 	Amount uint64
-	// Note: as long as we require serflags=0x7, we don't need to
-	// explicitly store the asset ID here even though it's technically
-	// part of the input commitment. We can compute it instead from
-	// values in the witness (which, with serflags other than 0x7,
-	// might not be present).
+	// Note: we don't store the asset ID here even though it's
+	// technically part of the input commitment. We can compute it
+	// instead from values in the witness. Only when the witness is
+	// not present (see DecodePartial) is the asset ID kept, in
+	// assetID.
+	assetID *bc.AssetID
 
 	// Witness
 	IssuanceWitness
@@ -22,6 +23,9 @@ type IssuanceInput struct {
 func (ii *IssuanceInput) IsIssuance() bool { return true }
 
 func (ii *IssuanceInput) AssetID() bc.AssetID {
+	if ii.assetID != nil {
+		return *ii.assetID
+	}
 	defhash := ii.AssetDefinitionHash()
 	return bc.ComputeAssetID(ii.IssuanceProgram, &ii.InitialBlock, ii.VMVersion, &defhash)
 }
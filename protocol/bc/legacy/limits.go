@@ -0,0 +1,54 @@
+package legacy
+
+import (
+	"math"
+
+	"chain/encoding/blockchain"
+	"chain/errors"
+)
+
+// Limits bounds the sizes the decoder accepts for parts of a
+// transaction, so that a peer can't make a node do unbounded
+// work with a crafted count or length prefix. A zero field means
+// no limit. Decoding fails with blockchain.ErrRange if a limit
+// is exceeded.
+type Limits struct {
+	// MaxInputs and MaxOutputs bound the number of inputs
+	// and outputs in each transaction.
+	MaxInputs  int
+	MaxOutputs int
+
+	// MaxRefDataLen bounds the length of the reference data
+	// of each transaction, input, and output.
+	MaxRefDataLen int
+
+	// MaxWitnessLen bounds the length of each input witness.
+	MaxWitnessLen int
+}
+
+// DefaultLimits are the limits used by TxData.UnmarshalText
+// and DecodePartial, for transactions submitted to a Core.
+// No limits apply to the transactions in a block; see
+// Block.Decode.
+var DefaultLimits = Limits{
+	MaxInputs:     10000,
+	MaxOutputs:    10000,
+	MaxRefDataLen: 1 << 20,
+	MaxWitnessLen: 1 << 20,
+}
+
+func checkCount(what string, n uint32, max int) error {
+	if max > 0 && int(n) > max {
+		return errors.WithDetailf(blockchain.ErrRange, "%d %s, limit %d", n, what, max)
+	}
+	return nil
+}
+
+// maxLen converts a limit to the form taken by
+// blockchain.ReadVarstr31Max.
+func maxLen(limit int) int {
+	if limit <= 0 {
+		return math.MaxInt32
+	}
+	return limit
+}
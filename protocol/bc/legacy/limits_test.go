@@ -0,0 +1,59 @@
+package legacy
+
+import (
+	"bytes"
+	"testing"
+
+	"chain/encoding/blockchain"
+	"chain/errors"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	tx := sampleTx()
+	tx.Inputs[0].SetArguments([][]byte{make([]byte, 100)})
+	var buf bytes.Buffer
+	_, err := tx.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		lim     Limits
+		wantErr error
+	}{
+		{Limits{}, nil},
+		{DefaultLimits, nil},
+		{Limits{MaxInputs: 2, MaxOutputs: 2, MaxRefDataLen: 12, MaxWitnessLen: 200}, nil},
+		{Limits{MaxInputs: 1}, blockchain.ErrRange},
+		{Limits{MaxOutputs: 1}, blockchain.ErrRange},
+		{Limits{MaxRefDataLen: 11}, blockchain.ErrRange}, // "distribution"
+		{Limits{MaxWitnessLen: 100}, blockchain.ErrRange},
+	}
+	for i, c := range cases {
+		var got TxData
+		err := got.DecodeLimited(buf.Bytes(), c.lim)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: DecodeLimited(%+v) err = %v, want %v", i, c.lim, err, c.wantErr)
+		}
+	}
+
+	// A block's transactions are decoded without limits.
+	tx.Inputs[0].SetArguments([][]byte{make([]byte, DefaultLimits.MaxWitnessLen+1)})
+	text, err := NewTx(*tx).MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = new(TxData).UnmarshalText(text)
+	if errors.Root(err) != blockchain.ErrRange {
+		t.Errorf("TxData.UnmarshalText err = %v, want %v", err, blockchain.ErrRange)
+	}
+	block := &Block{Transactions: []*Tx{NewTx(*tx)}}
+	text, err = block.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = new(Block).UnmarshalText(text)
+	if err != nil {
+		t.Errorf("Block.UnmarshalText err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,51 @@
+package legacy
+
+import (
+	"chain/crypto/sha3pool"
+	"chain/protocol/bc"
+)
+
+// WitnessHash returns the hash of the complete serialization of tx,
+// witnesses included. Two copies of a transaction with the same ID
+// (see bc.Tx.NonWitnessID) have different witness hashes if and only
+// if their witnesses differ.
+func (tx *TxData) WitnessHash() (hash bc.Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	tx.WriteTo(hasher)
+	hash.ReadFrom(hasher)
+	return hash
+}
+
+// MalleableFields returns the fields of a transaction of the
+// given version that anyone can change without changing its ID,
+// named as in the transaction's JSON form.
+//
+// Control programs and issuance programs may accept many different
+// arguments, so arguments are malleable unless the programs rule
+// that out. The suffixes are extension space for later versions;
+// the ID commits only to the fields a version defines, so no
+// version so far commits to them. The fields of an issuance
+// witness other than its arguments are not malleable: the asset
+// ID, which the ID covers, commits to them.
+//
+// Versions before bc.TxFeatureVersion have no feature bits. Such
+// a transaction's serialization leaves them out, so its ID doesn't
+// cover them either.
+func MalleableFields(txVersion uint64) []string {
+	fields := []string{
+		"common_fields_suffix",
+		"common_witness_suffix",
+		"inputs.arguments",
+		"inputs.commitment_suffix",
+		"inputs.spend_commitment_suffix",
+		"inputs.witness_suffix",
+		"outputs.commitment_suffix",
+		"outputs.witness_suffix",
+	}
+	if txVersion < bc.TxFeatureVersion {
+		fields = append(fields, "features")
+	}
+	return fields
+}
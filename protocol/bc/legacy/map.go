@@ -18,10 +18,13 @@ func MapTx(oldTx *TxData) *bc.Tx {
 		Entries:  entries,
 		InputIDs: make([]bc.Hash, len(oldTx.Inputs)),
 	}
+	if oldTx.Version >= bc.TxFeatureVersion {
+		tx.Features = oldTx.Features
+	}
 
 	var (
 		nonceIDs       = make(map[bc.Hash]bool)
-		spentOutputIDs = make(map[bc.Hash]bool)
+		spentOutputIDs = bc.NewOutpointSet()
 	)
 	for id, e := range entries {
 		var ord uint64
@@ -40,7 +43,11 @@ func MapTx(oldTx *TxData) *bc.Tx {
 			// resume below after the switch
 
 		case *bc.Spend:
-			spentOutputIDs[*e.SpentOutputId] = true
+			spentOutputIDs.Add(*e.SpentOutputId)
+			ord = e.Ordinal
+			// resume below after the switch
+
+		case *bc.UnknownInput:
 			ord = e.Ordinal
 			// resume below after the switch
 
@@ -56,9 +63,7 @@ func MapTx(oldTx *TxData) *bc.Tx {
 	for id := range nonceIDs {
 		tx.NonceIDs = append(tx.NonceIDs, id)
 	}
-	for id := range spentOutputIDs {
-		tx.SpentOutputIDs = append(tx.SpentOutputIDs, id)
-	}
+	tx.SpentOutputIDs = spentOutputIDs.Sorted()
 	return tx
 }
 
@@ -80,6 +85,7 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		firstSpendID bc.Hash
 		spends       []*bc.Spend
 		issuances    []*bc.Issuance
+		unknowns     []*bc.UnknownInput
 		muxSources   = make([]*bc.ValueSource, len(tx.Inputs))
 	)
 
@@ -93,7 +99,7 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 			}
 			out := bc.NewOutput(src, prog, &oldSp.RefDataHash, 0) // ordinal doesn't matter for prevouts, only for result outputs
 			prevoutID := addEntry(out)
-			refdatahash := hashData(inp.ReferenceData)
+			refdatahash := refDataHash(inp.ReferenceData, inp.DetachedRefDataHash)
 			sp := bc.NewSpend(&prevoutID, &refdatahash, uint64(i))
 			sp.WitnessArguments = oldSp.Arguments
 			id := addEntry(sp)
@@ -140,7 +146,7 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 
 			val := inp.AssetAmount()
 
-			refdatahash := hashData(inp.ReferenceData)
+			refdatahash := refDataHash(inp.ReferenceData, inp.DetachedRefDataHash)
 			assetdefhash := hashData(oldIss.AssetDefinition)
 			iss := bc.NewIssuance(&anchorID, &val, &refdatahash, uint64(i))
 			iss.WitnessAssetDefinition = &bc.AssetDefinition{
@@ -163,6 +169,24 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		}
 	}
 
+	// An input of a type this software doesn't understand maps to
+	// an opaque entry committing to its type and the rest of its
+	// commitment, so that it's covered by the tx ID and sighash.
+	// Its value is unknown, so it's given as zero.
+	for i, inp := range tx.Inputs {
+		if oldUI, ok := inp.TypedInput.(*UnknownInput); ok {
+			body := hashData(inp.CommitmentSuffix)
+			refdatahash := refDataHash(inp.ReferenceData, inp.DetachedRefDataHash)
+			ui := bc.NewUnknownInput(uint64(oldUI.Type), &body, &refdatahash, uint64(i))
+			id := addEntry(ui)
+			muxSources[i] = &bc.ValueSource{
+				Ref:   &id,
+				Value: &bc.AssetAmount{AssetId: &bc.AssetID{}},
+			}
+			unknowns = append(unknowns, ui)
+		}
+	}
+
 	mux := bc.NewMux(muxSources, &bc.Program{VmVersion: 1, Code: []byte{byte(vm.OP_TRUE)}})
 	muxID := addEntry(mux)
 
@@ -173,6 +197,9 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 	for _, iss := range issuances {
 		iss.SetDestination(&muxID, iss.Value, iss.Ordinal)
 	}
+	for _, ui := range unknowns {
+		ui.SetDestination(&muxID, muxSources[ui.Ordinal].Value, ui.Ordinal)
+	}
 
 	var resultIDs []*bc.Hash
 
@@ -185,7 +212,7 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		var dest *bc.ValueDestination
 		if vmutil.IsUnspendable(out.ControlProgram) {
 			// retirement
-			refdatahash := hashData(out.ReferenceData)
+			refdatahash := refDataHash(out.ReferenceData, out.DetachedRefDataHash)
 			r := bc.NewRetirement(src, &refdatahash, uint64(i))
 			rID := addEntry(r)
 			resultIDs = append(resultIDs, &rID)
@@ -196,7 +223,7 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		} else {
 			// non-retirement
 			prog := &bc.Program{out.VMVersion, out.ControlProgram}
-			refdatahash := hashData(out.ReferenceData)
+			refdatahash := refDataHash(out.ReferenceData, out.DetachedRefDataHash)
 			o := bc.NewOutput(src, prog, &refdatahash, uint64(i))
 			oID := addEntry(o)
 			resultIDs = append(resultIDs, &oID)
@@ -209,8 +236,12 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		mux.WitnessDestinations = append(mux.WitnessDestinations, dest)
 	}
 
-	refdatahash := hashData(tx.ReferenceData)
+	refdatahash := refDataHash(tx.ReferenceData, tx.DetachedRefDataHash)
 	h := bc.NewTxHeader(tx.Version, resultIDs, &refdatahash, tx.MinTime, tx.MaxTime)
+	if tx.Version >= bc.TxFeatureVersion {
+		featuresHash := tx.Features.Hash()
+		h.ExtHash = &featuresHash
+	}
 	headerID = addEntry(h)
 
 	return headerID, h, entryMap
@@ -20,12 +20,12 @@ type OutputCommitment struct {
 
 func (oc *OutputCommitment) writeExtensibleString(w io.Writer, suffix []byte, assetVersion uint64) error {
 	_, err := blockchain.WriteExtensibleString(w, suffix, func(w io.Writer) error {
-		return oc.writeContents(w, suffix, assetVersion)
+		return oc.writeContents(w, assetVersion)
 	})
 	return err
 }
 
-func (oc *OutputCommitment) writeContents(w io.Writer, suffix []byte, assetVersion uint64) (err error) {
+func (oc *OutputCommitment) writeContents(w io.Writer, assetVersion uint64) (err error) {
 	if assetVersion == 1 {
 		_, err = oc.AssetAmount.WriteTo(w)
 		if err != nil {
@@ -40,12 +40,6 @@ func (oc *OutputCommitment) writeContents(w io.Writer, suffix []byte, assetVersi
 			return errors.Wrap(err, "writing control program")
 		}
 	}
-	if len(suffix) > 0 {
-		_, err = w.Write(suffix)
-		if err != nil {
-			return errors.Wrap(err, "writing suffix")
-		}
-	}
 	return nil
 }
 
@@ -0,0 +1,196 @@
+package legacy
+
+import (
+	"io"
+
+	"chain/encoding/blockchain"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrRefDataMismatch is returned when attaching reference data
+// that does not match the hash a transaction commits to.
+var ErrRefDataMismatch = errors.New("reference data does not match committed hash")
+
+// A transaction's reference data, and that of its inputs and
+// outputs, may be detached: replaced by its hash, to which the
+// transaction commits anyway. Detaching doesn't change the
+// transaction's ID, only its size.
+//
+// Blocks, WriteTo, and UnmarshalText use only the full
+// serialization, so a transaction with detached reference data
+// must have it attached again before it is submitted. It can be
+// written without SerMetadata by WriteToWithFlags, and read by
+// DecodePartial. In that serialization, each piece of nonempty
+// reference data is prefixed with a byte saying whether it is
+// attached (refDataAttached), followed by the data, or detached
+// (refDataDetached), followed by its hash.
+//
+// Keeping the reference data, to attach it again later, is up to
+// the caller.
+//
+// Note: reference data is never stored on-chain as only its hash.
+// Every node decodes block transactions in the full serialization,
+// so a block with detached reference data would be rejected by
+// all nodes that don't have this change: a hard fork. And only the
+// Core that detached the data could annotate it, so there is no
+// local side table for it either. Detaching is for moving
+// transactions around off-chain, such as between signers.
+
+const (
+	refDataAttached = 0
+	refDataDetached = 1
+)
+
+// HasDetachedReferenceData reports whether any
+// of tx's reference data is detached.
+func (tx *TxData) HasDetachedReferenceData() bool {
+	for _, f := range tx.refDataFields() {
+		if *f.detached != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DetachedReferenceData returns the hashes of tx's
+// detached reference data.
+func (tx *TxData) DetachedReferenceData() []bc.Hash {
+	var hashes []bc.Hash
+	for _, f := range tx.refDataFields() {
+		if *f.detached != nil {
+			hashes = append(hashes, **f.detached)
+		}
+	}
+	return hashes
+}
+
+// DetachReferenceData detaches all of tx's nonempty reference
+// data, returning it keyed by its hash.
+func (tx *TxData) DetachReferenceData() map[bc.Hash][]byte {
+	blobs := make(map[bc.Hash][]byte)
+	for _, f := range tx.refDataFields() {
+		if len(*f.data) == 0 {
+			continue
+		}
+		h := hashData(*f.data)
+		blobs[h] = *f.data
+		*f.data = nil
+		*f.detached = &h
+	}
+	return blobs
+}
+
+// AttachReferenceData attaches the blobs, keyed by their hashes,
+// in place of the detached reference data with those hashes.
+// Detached reference data with no blob stays detached. It returns
+// ErrRefDataMismatch, attaching nothing, if a blob needed does not
+// hash to its key.
+func (tx *TxData) AttachReferenceData(blobs map[bc.Hash][]byte) error {
+	fields := tx.refDataFields()
+	for _, f := range fields {
+		if *f.detached == nil {
+			continue
+		}
+		data, ok := blobs[**f.detached]
+		if ok && hashData(data) != **f.detached {
+			return errors.WithDetailf(ErrRefDataMismatch, "hash %x", (*f.detached).Bytes())
+		}
+	}
+	for _, f := range fields {
+		if *f.detached == nil {
+			continue
+		}
+		if data, ok := blobs[**f.detached]; ok {
+			*f.data = data
+			*f.detached = nil
+		}
+	}
+	return nil
+}
+
+// refDataField points to a piece of reference data
+// and the hash that replaces it when it's detached.
+type refDataField struct {
+	data     *[]byte
+	detached **bc.Hash
+}
+
+func (tx *TxData) refDataFields() []refDataField {
+	fields := []refDataField{{&tx.ReferenceData, &tx.DetachedRefDataHash}}
+	for _, in := range tx.Inputs {
+		fields = append(fields, refDataField{&in.ReferenceData, &in.DetachedRefDataHash})
+	}
+	for _, out := range tx.Outputs {
+		fields = append(fields, refDataField{&out.ReferenceData, &out.DetachedRefDataHash})
+	}
+	return fields
+}
+
+// refDataHash returns the hash a transaction commits to for
+// reference data data, or detached if it's set.
+func refDataHash(data []byte, detached *bc.Hash) bc.Hash {
+	if detached != nil {
+		return *detached
+	}
+	return hashData(data)
+}
+
+// readRefData reads reference data serialized with serflags,
+// of length at most max.
+func readRefData(r *blockchain.Reader, serflags uint8, max int) (data []byte, detached *bc.Hash, err error) {
+	if serflags&SerMetadata != 0 {
+		data, err = blockchain.ReadVarstr31Max(r, maxLen(max))
+		return data, nil, err
+	}
+	if max > 0 && max < 32 {
+		max = 32
+	}
+	b, err := blockchain.ReadVarstr31Max(r, maxLen(max)+1)
+	if err != nil || len(b) == 0 {
+		return nil, nil, err
+	}
+	switch b[0] {
+	case refDataAttached:
+		if len(b) == 1 {
+			return nil, nil, errors.WithDetail(errBadRefData, "empty attached reference data")
+		}
+		if max > 0 && len(b)-1 > max {
+			return nil, nil, errors.WithDetailf(blockchain.ErrRange, "%d-byte reference data, limit %d", len(b)-1, max)
+		}
+		return b[1:], nil, nil
+	case refDataDetached:
+		if len(b) != 33 {
+			return nil, nil, errors.WithDetailf(errBadRefData, "%d-byte hash", len(b)-1)
+		}
+		var b32 [32]byte
+		copy(b32[:], b[1:])
+		h := bc.NewHash(b32)
+		return nil, &h, nil
+	}
+	return nil, nil, errors.WithDetailf(errBadRefData, "unknown prefix %#x", b[0])
+}
+
+var errBadRefData = errors.New("bad reference data")
+
+// errRefDataDetached is returned when writing the full
+// serialization of a transaction with detached reference data.
+var errRefDataDetached = errors.New("reference data is detached")
+
+func writeRefData(w io.Writer, data []byte, detached *bc.Hash, serflags byte) error {
+	if serflags&SerMetadata != 0 {
+		if detached != nil {
+			return errRefDataDetached
+		}
+		_, err := blockchain.WriteVarstr31(w, data)
+		return err
+	}
+	var b []byte
+	if detached != nil {
+		b = append([]byte{refDataDetached}, detached.Bytes()...)
+	} else if len(data) > 0 {
+		b = append([]byte{refDataAttached}, data...)
+	}
+	_, err := blockchain.WriteVarstr31(w, b)
+	return err
+}
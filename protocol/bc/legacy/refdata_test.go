@@ -0,0 +1,180 @@
+package legacy
+
+import (
+	"bytes"
+	"testing"
+
+	"chain/errors"
+)
+
+const serDetached = SerValid &^ SerMetadata
+
+func TestDetachReferenceData(t *testing.T) {
+	data := sampleTx()
+	outputRefData := bytes.Repeat([]byte("output"), 40)
+	data.Outputs[0].ReferenceData = outputRefData
+	want := NewTx(*data)
+	fullSize, err := data.SerializedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := data.DetachReferenceData()
+	if len(blobs) != 4 {
+		t.Fatalf("detached %d blobs, want 4", len(blobs))
+	}
+	if !data.HasDetachedReferenceData() {
+		t.Fatal("expected detached reference data")
+	}
+	if got := len(data.DetachedReferenceData()); got != 4 {
+		t.Errorf("got %d detached hashes, want 4", got)
+	}
+	if data.Outputs[1].DetachedRefDataHash != nil {
+		t.Error("detached empty reference data")
+	}
+
+	detached := NewTx(*data)
+	if detached.ID != want.ID {
+		t.Errorf("detaching changed tx ID to %x, want %x", detached.ID.Bytes(), want.ID.Bytes())
+	}
+
+	// The full serialization, used in blocks, can't
+	// leave out reference data.
+	_, err = data.WriteTo(new(bytes.Buffer))
+	if errors.Root(err) != errRefDataDetached {
+		t.Errorf("WriteTo() = %v, want %s", err, errRefDataDetached)
+	}
+
+	var buf bytes.Buffer
+	_, err = data.WriteToWithFlags(&buf, serDetached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(buf.Len()) >= fullSize {
+		t.Errorf("detached tx is %d bytes, want fewer than %d", buf.Len(), fullSize)
+	}
+	size, err := data.SerializedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(buf.Len()) {
+		t.Errorf("SerializedSize() = %d, want %d", size, buf.Len())
+	}
+
+	var decoded TxData
+	err = decoded.DecodeLimited(buf.Bytes(), DefaultLimits)
+	if err == nil {
+		t.Error("DecodeLimited accepted a tx without reference data")
+	}
+	decoded = TxData{}
+	serflags, err := decoded.DecodePartial(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serflags != serDetached {
+		t.Errorf("serflags = %#x, want %#x", serflags, serDetached)
+	}
+	if got := NewTx(decoded).ID; got != want.ID {
+		t.Errorf("decoded tx ID = %x, want %x", got.Bytes(), want.ID.Bytes())
+	}
+	if decoded.ReferenceData != nil || decoded.DetachedRefDataHash == nil {
+		t.Error("decoded tx reference data is not detached")
+	}
+
+	err = decoded.AttachReferenceData(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.HasDetachedReferenceData() {
+		t.Error("reference data still detached after attaching")
+	}
+	if !bytes.Equal(decoded.Outputs[0].ReferenceData, outputRefData) {
+		t.Errorf("output 0 reference data = %q, want %q", decoded.Outputs[0].ReferenceData, outputRefData)
+	}
+	size, err = decoded.SerializedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != fullSize {
+		t.Errorf("attached tx is %d bytes, want %d", size, fullSize)
+	}
+}
+
+func TestDetachSomeReferenceData(t *testing.T) {
+	data := sampleTx()
+	outputRefData := bytes.Repeat([]byte("output"), 40)
+	data.Outputs[0].ReferenceData = outputRefData
+	want := NewTx(*data)
+
+	// Detach only the first output's reference data.
+	h := hashData(outputRefData)
+	data.Outputs[0].ReferenceData = nil
+	data.Outputs[0].DetachedRefDataHash = &h
+
+	var buf bytes.Buffer
+	_, err := data.WriteToWithFlags(&buf, serDetached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TxData
+	_, err = decoded.DecodePartial(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := NewTx(decoded).ID; got != want.ID {
+		t.Errorf("decoded tx ID = %x, want %x", got.Bytes(), want.ID.Bytes())
+	}
+	if got := decoded.DetachedReferenceData(); len(got) != 1 || got[0] != h {
+		t.Errorf("detached hashes = %x, want [%x]", got, h.Bytes())
+	}
+	if !bytes.Equal(decoded.ReferenceData, data.ReferenceData) {
+		t.Errorf("tx reference data = %q, want %q", decoded.ReferenceData, data.ReferenceData)
+	}
+	if !bytes.Equal(decoded.Inputs[0].ReferenceData, data.Inputs[0].ReferenceData) {
+		t.Errorf("input 0 reference data = %q, want %q", decoded.Inputs[0].ReferenceData, data.Inputs[0].ReferenceData)
+	}
+}
+
+func TestAttachReferenceDataMismatch(t *testing.T) {
+	data := sampleTx()
+	blobs := data.DetachReferenceData()
+	for h := range blobs {
+		blobs[h] = []byte("wrong")
+		break
+	}
+	err := data.AttachReferenceData(blobs)
+	if errors.Root(err) != ErrRefDataMismatch {
+		t.Errorf("AttachReferenceData() = %v, want %s", err, ErrRefDataMismatch)
+	}
+	if len(data.DetachedReferenceData()) != 3 {
+		t.Error("attached some reference data despite mismatch")
+	}
+}
+
+func TestBadDetachedRefDataHash(t *testing.T) {
+	data := sampleTx()
+	data.DetachReferenceData()
+	var buf bytes.Buffer
+	_, err := data.WriteToWithFlags(&buf, serDetached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shorten the tx reference data hash by a byte.
+	b := buf.Bytes()
+	h := append([]byte{33, refDataDetached}, data.DetachedRefDataHash.Bytes()...)
+	i := bytes.Index(b, h)
+	if i < 0 {
+		t.Fatal("tx reference data hash not found")
+	}
+	bad := append([]byte(nil), b[:i]...)
+	bad = append(bad, 32, refDataDetached)
+	bad = append(bad, h[3:]...)
+	bad = append(bad, b[i+len(h):]...)
+
+	var decoded TxData
+	_, err = decoded.DecodePartial(bad)
+	if errors.Root(err) != errBadRefData {
+		t.Errorf("decoding = %v, want %s", err, errBadRefData)
+	}
+}
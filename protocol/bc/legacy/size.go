@@ -0,0 +1,66 @@
+package legacy
+
+// WitnessScale is how many times more a byte of a transaction
+// counts toward its weight than a byte of an input witness.
+const WitnessScale = 4
+
+// SerializedSize returns the length in bytes of tx's
+// serialization, as written by WriteTo. It counts the bytes
+// without buffering them. For a transaction decoded without
+// some of its witnesses, it counts only the witnesses present,
+// and for one with detached reference data, it counts the
+// serialization without SerMetadata. It returns an error if
+// tx can't be serialized.
+func (tx *TxData) SerializedSize() (int64, error) {
+	var c sizeCounter
+	err := tx.count(&c)
+	if err != nil {
+		return 0, err
+	}
+	return c.n, nil
+}
+
+// Weight returns tx's fee weight: its serialized size, with
+// each byte of the input witnesses counted once and each other
+// byte counted WitnessScale times. Witnesses are cheaper because
+// nodes need not keep them once the transaction is validated.
+func (tx *TxData) Weight() (int64, error) {
+	var c sizeCounter
+	err := tx.count(&c)
+	if err != nil {
+		return 0, err
+	}
+	return (c.n-c.witness)*WitnessScale + c.witness, nil
+}
+
+// count serializes tx to c, as described for SerializedSize.
+func (tx *TxData) count(c *sizeCounter) error {
+	serflags := uint8(SerValid)
+	if tx.HasDetachedReferenceData() {
+		serflags &^= SerMetadata
+	}
+	for _, in := range tx.Inputs {
+		if !in.HasWitness() {
+			serflags &^= SerWitness
+			break
+		}
+	}
+	return tx.writeTo(c, serflags, c)
+}
+
+// sizeCounter is an io.Writer that counts the bytes written
+// to it. TxInput.writeTo, given the sizeCounter, sets inWitness
+// while it writes an input witness, so those bytes are counted
+// in witness too.
+type sizeCounter struct {
+	n, witness int64
+	inWitness  bool
+}
+
+func (c *sizeCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	if c.inWitness {
+		c.witness += int64(len(p))
+	}
+	return len(p), nil
+}
@@ -0,0 +1,81 @@
+package legacy
+
+import (
+	"bytes"
+	"testing"
+
+	"chain/protocol/bc"
+)
+
+func TestSize(t *testing.T) {
+	tx := NewTx(TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewIssuanceInput([]byte{10}, 100, []byte("issref"), bc.NewHash([32]byte{1}), []byte{1}, [][]byte{{2}, {3}}, []byte("{}")),
+			NewSpendInput([][]byte{{4}, bytes.Repeat([]byte{5}, 64)}, bc.NewHash([32]byte{2}), bc.AssetID{}, 50, 1, []byte{5}, bc.NewHash([32]byte{3}), nil),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(bc.AssetID{}, 150, []byte{6}, []byte("outref")),
+		},
+	})
+
+	var full, base bytes.Buffer
+	_, err := tx.WriteTo(&full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.WriteToWithFlags(&base, SerMetadata|SerPrevout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := tx.SerializedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(full.Len()) {
+		t.Errorf("SerializedSize() = %d, want %d", size, full.Len())
+	}
+	witness := full.Len() - base.Len()
+	want := int64(base.Len()*WitnessScale + witness)
+	weight, err := tx.Weight()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weight != want {
+		t.Errorf("Weight() = %d, want %d", weight, want)
+	}
+
+	// Decoded without its witnesses, the transaction
+	// loses only their weight.
+	var partial TxData
+	_, err = partial.DecodePartial(base.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err = partial.SerializedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(base.Len()) {
+		t.Errorf("partial SerializedSize() = %d, want %d", size, base.Len())
+	}
+	weight, err = partial.Weight()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weight != want-int64(witness) {
+		t.Errorf("partial Weight() = %d, want %d", weight, want-int64(witness))
+	}
+
+	// A transaction that can't be serialized has no size.
+	bad := TxData{Version: 1, MinTime: 1 << 63}
+	_, err = bad.SerializedSize()
+	if err == nil {
+		t.Error("SerializedSize() of unserializable tx returned no error")
+	}
+	_, err = bad.Weight()
+	if err == nil {
+		t.Error("Weight() of unserializable tx returned no error")
+	}
+}
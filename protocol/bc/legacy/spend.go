@@ -20,10 +20,18 @@ type SpendInput struct {
 
 	// Witness
 	Arguments [][]byte
+
+	// prevoutHash is the hash of the spend commitment,
+	// for a spend decoded without it. See DecodePartial.
+	prevoutHash *bc.Hash
 }
 
 func (si *SpendInput) IsIssuance() bool { return false }
 
+// HasPrevout reports whether si has its spend commitment.
+// It is false for a spend decoded without prevouts.
+func (si *SpendInput) HasPrevout() bool { return si.prevoutHash == nil }
+
 func NewSpendInput(arguments [][]byte, sourceID bc.Hash, assetID bc.AssetID, amount uint64, sourcePos uint64, controlProgram []byte, outRefDataHash bc.Hash, referenceData []byte) *TxInput {
 	const (
 		vmver    = 1
@@ -63,12 +71,12 @@ type SpendCommitment struct {
 
 func (sc *SpendCommitment) writeExtensibleString(w io.Writer, suffix []byte, assetVersion uint64) error {
 	_, err := blockchain.WriteExtensibleString(w, suffix, func(w io.Writer) error {
-		return sc.writeContents(w, suffix, assetVersion)
+		return sc.writeContents(w, assetVersion)
 	})
 	return err
 }
 
-func (sc *SpendCommitment) writeContents(w io.Writer, suffix []byte, assetVersion uint64) (err error) {
+func (sc *SpendCommitment) writeContents(w io.Writer, assetVersion uint64) (err error) {
 	if assetVersion == 1 {
 		_, err = sc.SourceID.WriteTo(w)
 		if err != nil {
@@ -95,12 +103,6 @@ func (sc *SpendCommitment) writeContents(w io.Writer, suffix []byte, assetVersio
 			return errors.Wrap(err, "writing reference data hash")
 		}
 	}
-	if len(suffix) > 0 {
-		_, err = w.Write(suffix)
-		if err != nil {
-			return errors.Wrap(err, "writing suffix")
-		}
-	}
 	return nil
 }
 
@@ -5,8 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"sync"
 
-	"chain/crypto/sha3pool"
 	"chain/encoding/blockchain"
 	"chain/errors"
 	"chain/protocol/bc"
@@ -55,7 +55,14 @@ func (tx *Tx) OutputID(outputIndex int) *bc.Hash {
 // NewTx returns a new Tx containing data and its hash.
 // If you have already computed the hash, use struct literal
 // notation to make a Tx object directly.
+//
+// NewTx panics if a spend in data was decoded without its
+// prevout (see DecodePartial), since the transaction's IDs
+// can't be computed. Use HasPrevouts to check first.
 func NewTx(data TxData) *Tx {
+	if !data.HasPrevouts() {
+		panic(errNoPrevout)
+	}
 	return &Tx{
 		TxData: data,
 		Tx:     MapTx(&data),
@@ -88,6 +95,10 @@ type TxData struct {
 	MinTime uint64
 	MaxTime uint64
 
+	// Features is present only in transactions of
+	// version bc.TxFeatureVersion or later.
+	Features bc.TxFeatures
+
 	// The unconsumed suffix of the common fields extensible string
 	CommonFieldsSuffix []byte
 
@@ -95,6 +106,10 @@ type TxData struct {
 	CommonWitnessSuffix []byte
 
 	ReferenceData []byte
+
+	// DetachedRefDataHash is the hash of the reference
+	// data, if it is detached. See DetachReferenceData.
+	DetachedRefDataHash *bc.Hash
 }
 
 // HasIssuance returns true if this transaction has an issuance input.
@@ -107,6 +122,17 @@ func (tx *TxData) HasIssuance() bool {
 	return false
 }
 
+// HasUnknownInput reports whether tx has an input of a type
+// this software doesn't understand. See UnknownInput.
+func (tx *TxData) HasUnknownInput() bool {
+	for _, in := range tx.Inputs {
+		if _, ok := in.TypedInput.(*UnknownInput); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (tx *TxData) UnmarshalText(p []byte) error {
 	b := make([]byte, hex.DecodedLen(len(p)))
 	_, err := hex.Decode(b, p)
@@ -114,8 +140,14 @@ func (tx *TxData) UnmarshalText(p []byte) error {
 		return err
 	}
 
+	return tx.DecodeLimited(b, DefaultLimits)
+}
+
+// DecodeLimited decodes a fully serialized transaction from b,
+// failing if it exceeds lim.
+func (tx *TxData) DecodeLimited(b []byte, lim Limits) error {
 	r := blockchain.NewReader(b)
-	err = tx.readFrom(r)
+	err := tx.readFrom(r, lim)
 	if err != nil {
 		return err
 	}
@@ -125,19 +157,84 @@ func (tx *TxData) UnmarshalText(p []byte) error {
 	return nil
 }
 
-func (tx *TxData) readFrom(r *blockchain.Reader) error {
+func (tx *TxData) readFrom(r *blockchain.Reader, lim Limits) error {
+	_, err := tx.readParts(r, false, lim,
+		func(_ int, ti *TxInput) error {
+			tx.Inputs = append(tx.Inputs, ti)
+			return nil
+		},
+		func(_ int, to *TxOutput) error {
+			tx.Outputs = append(tx.Outputs, to)
+			return nil
+		},
+	)
+	return err
+}
+
+// DecodePartial decodes a transaction from b that may have been
+// serialized without witnesses or prevouts, as by WriteToWithFlags,
+// or without reference data. It applies DefaultLimits.
+// It returns the serialization flags of b.
+//
+// Inputs decoded without witnesses report false from HasWitness;
+// use SetWitness to restore them. A transaction decoded without
+// witnesses can still be passed to NewTx, since witnesses don't
+// affect its ID. One decoded without prevouts cannot: the IDs of
+// the outputs it spends can't be computed from the prevout hashes,
+// so NewTx panics. HasPrevouts reports which case applies.
+func (tx *TxData) DecodePartial(b []byte) (serflags uint8, err error) {
+	r := blockchain.NewReader(b)
+	serflags, err = tx.readParts(r, true, DefaultLimits,
+		func(_ int, ti *TxInput) error {
+			tx.Inputs = append(tx.Inputs, ti)
+			return nil
+		},
+		func(_ int, to *TxOutput) error {
+			tx.Outputs = append(tx.Outputs, to)
+			return nil
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if trailing := r.Len(); trailing > 0 {
+		return 0, fmt.Errorf("trailing garbage (%d bytes)", trailing)
+	}
+	return serflags, nil
+}
+
+// HasPrevouts reports whether every spend in tx has its
+// spend commitment. It is false for a transaction decoded
+// without prevouts.
+func (tx *TxData) HasPrevouts() bool {
+	for _, in := range tx.Inputs {
+		si, ok := in.TypedInput.(*SpendInput)
+		if ok && !si.HasPrevout() {
+			return false
+		}
+	}
+	return true
+}
+
+// readParts reads a transaction from r, storing everything but
+// its inputs and outputs in tx. It passes each input to onInput
+// and each output to onOutput as they are read. Unless partial
+// is set, it accepts only fully serialized transactions.
+// It fails if the transaction exceeds lim.
+// It returns the serialization flags it read.
+func (tx *TxData) readParts(r *blockchain.Reader, partial bool, lim Limits, onInput func(int, *TxInput) error, onOutput func(int, *TxOutput) error) (uint8, error) {
 	var serflags [1]byte
 	_, err := io.ReadFull(r, serflags[:])
 	if err != nil {
-		return errors.Wrap(err, "reading serialization flags")
+		return 0, errors.Wrap(err, "reading serialization flags")
 	}
-	if err == nil && serflags[0] != serRequired {
-		return fmt.Errorf("unsupported serflags %#x", serflags[0])
+	if !partial && serflags[0] != serRequired || !validPartialFlags(serflags[0]) {
+		return 0, fmt.Errorf("unsupported serflags %#x", serflags[0])
 	}
 
 	tx.Version, err = blockchain.ReadVarint63(r)
 	if err != nil {
-		return errors.Wrap(err, "reading transaction version")
+		return 0, errors.Wrap(err, "reading transaction version")
 	}
 
 	// Common fields
@@ -147,46 +244,68 @@ func (tx *TxData) readFrom(r *blockchain.Reader) error {
 			return errors.Wrap(err, "reading transaction mintime")
 		}
 		tx.MaxTime, err = blockchain.ReadVarint63(r)
-		return errors.Wrap(err, "reading transaction maxtime")
+		if err != nil {
+			return errors.Wrap(err, "reading transaction maxtime")
+		}
+		if tx.Version < bc.TxFeatureVersion {
+			return nil
+		}
+		features, err := blockchain.ReadVarint63(r)
+		tx.Features = bc.TxFeatures(features)
+		return errors.Wrap(err, "reading transaction features")
 	})
 	if err != nil {
-		return errors.Wrap(err, "reading transaction common fields")
+		return 0, errors.Wrap(err, "reading transaction common fields")
 	}
 
 	// Common witness
 	tx.CommonWitnessSuffix, err = blockchain.ReadExtensibleString(r, tx.readCommonWitness)
 	if err != nil {
-		return errors.Wrap(err, "reading transaction common witness")
+		return 0, errors.Wrap(err, "reading transaction common witness")
 	}
 
 	n, err := blockchain.ReadVarint31(r)
 	if err != nil {
-		return errors.Wrap(err, "reading number of transaction inputs")
+		return 0, errors.Wrap(err, "reading number of transaction inputs")
 	}
-	for ; n > 0; n-- {
+	err = checkCount("inputs", n, lim.MaxInputs)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < int(n); i++ {
 		ti := new(TxInput)
-		err = ti.readFrom(r)
+		err = ti.readFrom(r, tx.Version, serflags[0], lim)
 		if err != nil {
-			return errors.Wrapf(err, "reading input %d", len(tx.Inputs))
+			return 0, errors.Wrapf(err, "reading input %d", i)
+		}
+		err = onInput(i, ti)
+		if err != nil {
+			return 0, errors.Wrapf(err, "processing input %d", i)
 		}
-		tx.Inputs = append(tx.Inputs, ti)
 	}
 
 	n, err = blockchain.ReadVarint31(r)
 	if err != nil {
-		return errors.Wrap(err, "reading number of transaction outputs")
+		return 0, errors.Wrap(err, "reading number of transaction outputs")
 	}
-	for ; n > 0; n-- {
+	err = checkCount("outputs", n, lim.MaxOutputs)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < int(n); i++ {
 		to := new(TxOutput)
-		err = to.readFrom(r, tx.Version)
+		err = to.readFrom(r, tx.Version, serflags[0], lim)
+		if err != nil {
+			return 0, errors.Wrapf(err, "reading output %d", i)
+		}
+		err = onOutput(i, to)
 		if err != nil {
-			return errors.Wrapf(err, "reading output %d", len(tx.Outputs))
+			return 0, errors.Wrapf(err, "processing output %d", i)
 		}
-		tx.Outputs = append(tx.Outputs, to)
 	}
 
-	tx.ReferenceData, err = blockchain.ReadVarstr31(r)
-	return errors.Wrap(err, "reading transaction reference data")
+	tx.ReferenceData, tx.DetachedRefDataHash, err = readRefData(r, serflags[0], lim.MaxRefDataLen)
+	return serflags[0], errors.Wrap(err, "reading transaction reference data")
 }
 
 // does not read the enclosing extensible string
@@ -196,58 +315,109 @@ func (tx *TxData) readCommonWitness(r *blockchain.Reader) error {
 
 func (tx *TxData) MarshalText() ([]byte, error) {
 	var buf bytes.Buffer
-	tx.WriteTo(&buf) // error is impossible
+	_, err := tx.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
 	b := make([]byte, hex.EncodedLen(buf.Len()))
 	hex.Encode(b, buf.Bytes())
 	return b, nil
 }
 
-// WriteTo writes tx to w.
-func (tx *TxData) WriteTo(w io.Writer) (int64, error) {
+// validPartialFlags reports whether serflags is a serialization
+// that DecodePartial accepts: one with or without witnesses,
+// prevouts and reference data.
+func validPartialFlags(serflags uint8) bool {
+	return serflags&^SerValid == 0
+}
+
+// WriteToWithFlags writes tx to w using the given serialization
+// flags. Leaving out SerWitness omits the input witnesses, and
+// leaving out SerPrevout replaces the spent output commitment of
+// each spend with its hash. SerMetadata must be left out if tx
+// has detached reference data; leaving it out keeps detached
+// reference data detached and the rest attached. Use DecodePartial
+// to read the result.
+func (tx *TxData) WriteToWithFlags(w io.Writer, serflags uint8) (int64, error) {
+	if !validPartialFlags(serflags) {
+		return 0, fmt.Errorf("unsupported serflags %#x", serflags)
+	}
 	ew := errors.NewWriter(w)
-	err := tx.writeTo(ew, serRequired)
+	err := tx.writeTo(ew, serflags, nil)
 	if err != nil {
-		return ew.Written(), ew.Err()
+		return ew.Written(), err
 	}
 	return ew.Written(), ew.Err()
 }
 
-func (tx *TxData) writeTo(w io.Writer, serflags byte) error {
-	_, err := w.Write([]byte{serflags})
+// WriteTo writes tx to w.
+func (tx *TxData) WriteTo(w io.Writer) (int64, error) {
+	ew := errors.NewWriter(w)
+	err := tx.writeTo(ew, serRequired, nil)
 	if err != nil {
-		return errors.Wrap(err, "writing serialization flags")
+		return ew.Written(), err
 	}
+	return ew.Written(), ew.Err()
+}
+
+var scratchPool = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+// writeTo writes tx to w. If c is non-nil, it is the sizeCounter
+// underlying w; see TxInput.writeTo.
+func (tx *TxData) writeTo(w io.Writer, serflags byte, c *sizeCounter) error {
+	// The fixed-size prefix of the transaction is encoded into a
+	// pooled scratch buffer and written with a single call.
+	scratch := scratchPool.Get().(*[]byte)
+	defer scratchPool.Put(scratch)
+	b := append((*scratch)[:0], serflags)
 
-	_, err = blockchain.WriteVarint63(w, tx.Version)
+	b, err := blockchain.AppendVarint63(b, tx.Version)
 	if err != nil {
 		return errors.Wrap(err, "writing transaction version")
 	}
 
 	// common fields
-	_, err = blockchain.WriteExtensibleString(w, tx.CommonFieldsSuffix, func(w io.Writer) error {
-		_, err := blockchain.WriteVarint63(w, tx.MinTime)
+	var fieldsBuf [27]byte
+	fields, err := blockchain.AppendVarint63(fieldsBuf[:0], tx.MinTime)
+	if err != nil {
+		return errors.Wrap(err, "writing transaction min time")
+	}
+	fields, err = blockchain.AppendVarint63(fields, tx.MaxTime)
+	if err != nil {
+		return errors.Wrap(err, "writing transaction max time")
+	}
+	if tx.Version >= bc.TxFeatureVersion {
+		fields, err = blockchain.AppendVarint63(fields, uint64(tx.Features))
 		if err != nil {
-			return errors.Wrap(err, "writing transaction min time")
+			return errors.Wrap(err, "writing transaction features")
 		}
-		_, err = blockchain.WriteVarint63(w, tx.MaxTime)
-		return errors.Wrap(err, "writing transaction max time")
-	})
+	}
+	b, err = blockchain.AppendVarint31(b, uint64(len(fields)+len(tx.CommonFieldsSuffix)))
 	if err != nil {
 		return errors.Wrap(err, "writing common fields")
 	}
+	b = append(b, fields...)
+	b = append(b, tx.CommonFieldsSuffix...)
 
 	// common witness
-	_, err = blockchain.WriteExtensibleString(w, tx.CommonWitnessSuffix, tx.writeCommonWitness)
+	// Future protocol versions may add fields here.
+	b, err = blockchain.AppendVarstr31(b, tx.CommonWitnessSuffix)
 	if err != nil {
 		return errors.Wrap(err, "writing common witness")
 	}
 
-	_, err = blockchain.WriteVarint31(w, uint64(len(tx.Inputs)))
+	b, err = blockchain.AppendVarint31(b, uint64(len(tx.Inputs)))
 	if err != nil {
 		return errors.Wrap(err, "writing tx input count")
 	}
+	*scratch = b
+	_, err = w.Write(b)
+	if err != nil {
+		return errors.Wrap(err, "writing transaction prefix")
+	}
+
 	for i, ti := range tx.Inputs {
-		err = ti.writeTo(w, serflags)
+		err = ti.writeTo(w, serflags, c)
 		if err != nil {
 			return errors.Wrapf(err, "writing tx input %d", i)
 		}
@@ -264,30 +434,5 @@ func (tx *TxData) writeTo(w io.Writer, serflags byte) error {
 		}
 	}
 
-	return writeRefData(w, tx.ReferenceData, serflags)
-}
-
-// does not write the enclosing extensible string
-func (tx *TxData) writeCommonWitness(w io.Writer) error {
-	// Future protocol versions may add fields here.
-	return nil
-}
-
-func writeRefData(w io.Writer, data []byte, serflags byte) error {
-	if serflags&SerMetadata != 0 {
-		_, err := blockchain.WriteVarstr31(w, data)
-		return err
-	}
-	return writeFastHash(w, data)
-}
-
-func writeFastHash(w io.Writer, d []byte) error {
-	if len(d) == 0 {
-		_, err := blockchain.WriteVarstr31(w, nil)
-		return err
-	}
-	var h [32]byte
-	sha3pool.Sum256(h[:], d)
-	_, err := blockchain.WriteVarstr31(w, h[:])
-	return err
+	return writeRefData(w, tx.ReferenceData, tx.DetachedRefDataHash, serflags)
 }
@@ -241,6 +241,48 @@ func TestTransaction(t *testing.T) {
 	}
 }
 
+func TestTransactionFeatures(t *testing.T) {
+	tx := NewTx(TxData{Version: bc.TxFeatureVersion, Features: 1<<2 | 1<<40})
+	got := serialize(t, tx)
+	want, _ := hex.DecodeString("07" + // serflags
+		"03" + // transaction version
+		"08" + // common fields extensible string length
+		"00" + // common fields, mintime
+		"00" + // common fields, maxtime
+		"848080808020" + // common fields, features
+		"00" + // common witness extensible string length
+		"00" + // inputs count
+		"00" + // outputs count
+		"00") // reference data
+	if !bytes.Equal(got, want) {
+		t.Errorf("bytes = %x want %x", got, want)
+	}
+
+	var tx1 Tx
+	err := tx1.UnmarshalText([]byte(hex.EncodeToString(got)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.DeepEqual(tx1.TxData, tx.TxData) {
+		t.Errorf("got:\n%swant:\n%s", spew.Sdump(tx1.TxData), spew.Sdump(tx.TxData))
+	}
+	if tx1.Tx.Features != tx.TxData.Features || *tx1.ExtHash != tx.TxData.Features.Hash() {
+		t.Errorf("entries features = %#x, ext hash %x", uint64(tx1.Tx.Features), tx1.ExtHash.Bytes())
+	}
+
+	// The features are part of the transaction ID.
+	other := NewTx(TxData{Version: bc.TxFeatureVersion, Features: 1 << 2})
+	if other.ID == tx.ID {
+		t.Error("changing features did not change the transaction ID")
+	}
+
+	// Earlier versions have no features field.
+	old := NewTx(TxData{Version: 2, Features: 1})
+	if got := serialize(t, old); !bytes.Equal(got[2:5], []byte{2, 0, 0}) {
+		t.Errorf("version 2 common fields = %x, want 020000", got[2:5])
+	}
+}
+
 func TestHasIssuance(t *testing.T) {
 	cases := []struct {
 		tx   *TxData
@@ -320,17 +362,212 @@ func TestInvalidIssuance(t *testing.T) {
 	}
 }
 
+func TestUnknownInputType(t *testing.T) {
+	const body = ("02" + // common fields extensible string length
+		"00" + // common fields, mintime
+		"00" + // common fields, maxtime
+		"00" + // common witness extensible string length
+		"01" + // inputs count
+		"01" + // input 0, asset version
+		"03" + // input 0, input commitment length prefix
+		"05" + // input 0, input commitment, unknown type
+		"beef" + // input 0, input commitment, opaque remainder
+		"00" + // input 0, reference data
+		"02" + // input 0, input witness length prefix
+		"aabb" + // input 0, input witness, opaque
+		"00" + // outputs count
+		"00") // reference data
+
+	// Unknown input types are an error in current-version transactions.
+	tx := new(TxData)
+	err := tx.UnmarshalText([]byte("07" + "01" + body))
+	if err == nil {
+		t.Error("want error for unknown input type in version 1 transaction")
+	}
+
+	// ...but are preserved in transactions with a newer version.
+	hex := "07" + "02" + body
+	var newTx Tx
+	err = newTx.UnmarshalText([]byte(hex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TxInput{
+		AssetVersion:     1,
+		TypedInput:       &UnknownInput{Type: 5},
+		ReferenceData:    []byte{},
+		CommitmentSuffix: []byte{0xbe, 0xef},
+		WitnessSuffix:    []byte{0xaa, 0xbb},
+	}
+	if !testutil.DeepEqual(newTx.Inputs[0], want) {
+		t.Errorf("got input:\n%s\nwant:\n%s", spew.Sdump(newTx.Inputs[0]), spew.Sdump(want))
+	}
+
+	got, err := newTx.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != hex {
+		t.Errorf("got serialized tx %s, want %s", got, hex)
+	}
+}
+
+func TestUnknownInputID(t *testing.T) {
+	spend := NewSpendInput(nil, bc.NewHash([32]byte{1}), bc.AssetID{V0: 2}, 3, 0, []byte{1}, bc.Hash{}, nil)
+	unknown := func(typ byte, rest ...byte) *TxInput {
+		return &TxInput{
+			AssetVersion:     1,
+			TypedInput:       &UnknownInput{Type: typ},
+			CommitmentSuffix: rest,
+		}
+	}
+	outputs := []*TxOutput{NewTxOutput(bc.AssetID{V0: 2}, 3, []byte{1}, nil)}
+	txs := []*Tx{
+		NewTx(TxData{Version: 2, Inputs: []*TxInput{spend}, Outputs: outputs}),
+		NewTx(TxData{Version: 2, Inputs: []*TxInput{spend, unknown(5, 0xbe, 0xef)}, Outputs: outputs}),
+		NewTx(TxData{Version: 2, Inputs: []*TxInput{spend, unknown(5, 0xbe, 0xee)}, Outputs: outputs}),
+		NewTx(TxData{Version: 2, Inputs: []*TxInput{spend, unknown(6, 0xbe, 0xef)}, Outputs: outputs}),
+	}
+
+	// Transactions that differ only in an unknown input
+	// have different IDs and spend signature hashes.
+	for i := range txs {
+		for j := i + 1; j < len(txs); j++ {
+			if txs[i].ID == txs[j].ID {
+				t.Errorf("transactions %d and %d have the same ID %x", i, j, txs[i].ID.Bytes())
+			}
+			if txs[i].SigHash(0, bc.Hash{}) == txs[j].SigHash(0, bc.Hash{}) {
+				t.Errorf("transactions %d and %d have the same sighash for input 0", i, j)
+			}
+		}
+	}
+
+	for i, tx := range txs[1:] {
+		if _, ok := tx.Entries[tx.InputIDs[1]].(*bc.UnknownInput); !ok {
+			t.Errorf("transaction %d: input 1 maps to %T, want *bc.UnknownInput", i+1, tx.Entries[tx.InputIDs[1]])
+		}
+	}
+}
+
+func TestPartialSerialization(t *testing.T) {
+	iw := IssuanceWitness{
+		InitialBlock:    bc.NewHash([32]byte{1}),
+		AssetDefinition: []byte("{}"),
+		VMVersion:       1,
+		IssuanceProgram: []byte{1},
+		Arguments:       [][]byte{{2}},
+	}
+	spendArgs := [][]byte{{4}, {5}}
+	tx := NewTx(TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewIssuanceInput([]byte{10}, 100, []byte("issref"), iw.InitialBlock, iw.IssuanceProgram, iw.Arguments, iw.AssetDefinition),
+			NewSpendInput(spendArgs, bc.NewHash([32]byte{2}), bc.AssetID{}, 50, 1, []byte{5}, bc.NewHash([32]byte{3}), nil),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(bc.AssetID{}, 150, []byte{6}, nil),
+		},
+	})
+
+	for _, serflags := range []uint8{SerValid, SerMetadata | SerPrevout, SerMetadata | SerWitness, SerMetadata} {
+		var buf bytes.Buffer
+		_, err := tx.WriteToWithFlags(&buf, serflags)
+		if err != nil {
+			t.Fatalf("serflags %#x: %s", serflags, err)
+		}
+		b := buf.Bytes()
+
+		var got TxData
+		gotFlags, err := got.DecodePartial(b)
+		if err != nil {
+			t.Fatalf("serflags %#x: %s", serflags, err)
+		}
+		if gotFlags != serflags {
+			t.Errorf("serflags %#x: decoded flags %#x", serflags, gotFlags)
+		}
+		if got.Inputs[0].HasWitness() != (serflags&SerWitness != 0) {
+			t.Errorf("serflags %#x: HasWitness() = %v", serflags, got.Inputs[0].HasWitness())
+		}
+		if got.Inputs[0].AssetID() != tx.Inputs[0].AssetID() {
+			t.Errorf("serflags %#x: issuance asset ID = %x, want %x", serflags, got.Inputs[0].AssetID().Bytes(), tx.Inputs[0].AssetID().Bytes())
+		}
+		if got.Inputs[1].TypedInput.(*SpendInput).HasPrevout() != (serflags&SerPrevout != 0) {
+			t.Errorf("serflags %#x: HasPrevout() = %v", serflags, serflags&SerPrevout == 0)
+		}
+
+		// The partial transaction reserializes to the same bytes.
+		var buf2 bytes.Buffer
+		_, err = got.WriteToWithFlags(&buf2, serflags)
+		if err != nil {
+			t.Fatalf("serflags %#x: reserializing: %s", serflags, err)
+		}
+		if !bytes.Equal(buf2.Bytes(), b) {
+			t.Errorf("serflags %#x: reserialized to %x, want %x", serflags, buf2.Bytes(), b)
+		}
+
+		// Strict decoding accepts only full serializations.
+		var strict Tx
+		err = strict.UnmarshalText([]byte(hex.EncodeToString(b)))
+		if (err == nil) != (serflags == SerValid) {
+			t.Errorf("serflags %#x: UnmarshalText error = %v", serflags, err)
+		}
+
+		if got.HasPrevouts() != (serflags&SerPrevout != 0) {
+			t.Errorf("serflags %#x: HasPrevouts() = %v", serflags, got.HasPrevouts())
+		}
+		if serflags&SerPrevout == 0 {
+			func() {
+				defer func() {
+					if r := recover(); r != errNoPrevout {
+						t.Errorf("serflags %#x: NewTx panicked with %v, want %s", serflags, r, errNoPrevout)
+					}
+				}()
+				NewTx(got)
+			}()
+			continue
+		}
+		if serflags&SerWitness == 0 {
+			// Witnesses don't affect the transaction ID.
+			if id := NewTx(got).ID; id != tx.ID {
+				t.Errorf("serflags %#x: ID = %x, want %x", serflags, id.Bytes(), tx.ID.Bytes())
+			}
+			_, err = got.WriteToWithFlags(ioutil.Discard, SerValid)
+			if errors.Root(err) != errNoWitness {
+				t.Errorf("serflags %#x: writing with witness got error %v, want %s", serflags, err, errNoWitness)
+			}
+
+			badIW := iw
+			badIW.IssuanceProgram = []byte{2}
+			err = got.Inputs[0].SetWitness(&badIW, iw.Arguments)
+			if err != errBadAssetID {
+				t.Errorf("serflags %#x: SetWitness with wrong issuance program got error %v, want %s", serflags, err, errBadAssetID)
+			}
+			err = got.Inputs[0].SetWitness(&iw, iw.Arguments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = got.Inputs[1].SetWitness(nil, spendArgs)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !testutil.DeepEqual(got, tx.TxData) {
+			t.Errorf("serflags %#x: got:\n%swant:\n%s", serflags, spew.Sdump(got), spew.Sdump(tx.TxData))
+		}
+	}
+}
+
 func BenchmarkTxWriteToTrue(b *testing.B) {
 	tx := &Tx{}
 	for i := 0; i < b.N; i++ {
-		tx.writeTo(ioutil.Discard, 0)
+		tx.writeTo(ioutil.Discard, 0, nil)
 	}
 }
 
 func BenchmarkTxWriteToFalse(b *testing.B) {
 	tx := &Tx{}
 	for i := 0; i < b.N; i++ {
-		tx.writeTo(ioutil.Discard, serRequired)
+		tx.writeTo(ioutil.Discard, serRequired, nil)
 	}
 }
 
@@ -341,7 +578,7 @@ func BenchmarkTxWriteToTrue200(b *testing.B) {
 		tx.Outputs = append(tx.Outputs, NewTxOutput(bc.AssetID{}, 0, nil, nil))
 	}
 	for i := 0; i < b.N; i++ {
-		tx.writeTo(ioutil.Discard, 0)
+		tx.writeTo(ioutil.Discard, 0, nil)
 	}
 }
 
@@ -352,7 +589,7 @@ func BenchmarkTxWriteToFalse200(b *testing.B) {
 		tx.Outputs = append(tx.Outputs, NewTxOutput(bc.AssetID{}, 0, nil, nil))
 	}
 	for i := 0; i < b.N; i++ {
-		tx.writeTo(ioutil.Discard, serRequired)
+		tx.writeTo(ioutil.Discard, serRequired, nil)
 	}
 }
 
@@ -360,7 +597,7 @@ func BenchmarkTxInputWriteToTrue(b *testing.B) {
 	input := NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 0, 0, nil, bc.Hash{}, nil)
 	ew := errors.NewWriter(ioutil.Discard)
 	for i := 0; i < b.N; i++ {
-		input.writeTo(ew, 0)
+		input.writeTo(ew, 0, nil)
 	}
 }
 
@@ -368,7 +605,7 @@ func BenchmarkTxInputWriteToFalse(b *testing.B) {
 	input := NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 0, 0, nil, bc.Hash{}, nil)
 	ew := errors.NewWriter(ioutil.Discard)
 	for i := 0; i < b.N; i++ {
-		input.writeTo(ew, serRequired)
+		input.writeTo(ew, serRequired, nil)
 	}
 }
 
@@ -394,3 +631,92 @@ func BenchmarkAssetAmountWriteTo(b *testing.B) {
 		aa.WriteTo(ioutil.Discard)
 	}
 }
+
+func TestWitnessHash(t *testing.T) {
+	assetID := bc.ComputeAssetID(nil, &bc.Hash{}, 1, &bc.EmptyStringHash)
+	data := TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{{1}}, bc.Hash{V0: 1}, assetID, 5, 0, []byte{0x51}, bc.Hash{}, nil),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(assetID, 5, []byte{0x52}, nil),
+		},
+	}
+	orig := NewTx(data)
+	origID, origHash := orig.NonWitnessID(), orig.WitnessHash()
+
+	data.Inputs[0].SetArguments([][]byte{{2}})
+	changedArgs := NewTx(data)
+	data.CommonWitnessSuffix = []byte{3}
+	changedSuffix := NewTx(data)
+
+	for _, tx := range []*Tx{changedArgs, changedSuffix} {
+		if tx.NonWitnessID() != origID {
+			t.Errorf("NonWitnessID changed with witness to %x", tx.NonWitnessID().Bytes())
+		}
+		if tx.WitnessHash() == origHash {
+			t.Errorf("WitnessHash unchanged with witness")
+		}
+	}
+	if changedArgs.WitnessHash() == changedSuffix.WitnessHash() {
+		t.Errorf("WitnessHash unchanged with common witness suffix")
+	}
+}
+
+func TestMalleableFields(t *testing.T) {
+	assetID := bc.ComputeAssetID(nil, &bc.Hash{}, 1, &bc.EmptyStringHash)
+	newData := func(version uint64) TxData {
+		return TxData{
+			Version: version,
+			Inputs: []*TxInput{
+				NewSpendInput([][]byte{{1}}, bc.Hash{V0: 1}, assetID, 5, 0, []byte{0x51}, bc.Hash{}, nil),
+			},
+			Outputs: []*TxOutput{
+				NewTxOutput(assetID, 5, []byte{0x52}, nil),
+			},
+		}
+	}
+	change := map[string]func(*TxData){
+		"common_fields_suffix":  func(tx *TxData) { tx.CommonFieldsSuffix = []byte{1} },
+		"common_witness_suffix": func(tx *TxData) { tx.CommonWitnessSuffix = []byte{1} },
+		"features":              func(tx *TxData) { tx.Features = 1 },
+		"inputs.arguments":      func(tx *TxData) { tx.Inputs[0].SetArguments([][]byte{{2}}) },
+		"inputs.commitment_suffix": func(tx *TxData) {
+			tx.Inputs[0].CommitmentSuffix = []byte{1}
+		},
+		"inputs.spend_commitment_suffix": func(tx *TxData) {
+			tx.Inputs[0].TypedInput.(*SpendInput).SpendCommitmentSuffix = []byte{1}
+		},
+		"inputs.witness_suffix":     func(tx *TxData) { tx.Inputs[0].WitnessSuffix = []byte{1} },
+		"outputs.commitment_suffix": func(tx *TxData) { tx.Outputs[0].CommitmentSuffix = []byte{1} },
+		"outputs.witness_suffix":    func(tx *TxData) { tx.Outputs[0].WitnessSuffix = []byte{1} },
+	}
+
+	for _, version := range []uint64{1, bc.TxFeatureVersion} {
+		fields := MalleableFields(version)
+		malleable := make(map[string]bool)
+		for _, f := range fields {
+			malleable[f] = true
+		}
+		wantFeatures := version < bc.TxFeatureVersion
+		if malleable["features"] != wantFeatures {
+			t.Errorf("version %d: features malleable = %v, want %v", version, malleable["features"], wantFeatures)
+		}
+
+		orig := NewTx(newData(version)).ID
+		for name, f := range change {
+			data := newData(version)
+			f(&data)
+			unchanged := NewTx(data).ID == orig
+			if unchanged != malleable[name] {
+				t.Errorf("version %d: changing %s left ID unchanged = %v, want %v", version, name, unchanged, malleable[name])
+			}
+		}
+		for _, name := range fields {
+			if change[name] == nil {
+				t.Errorf("version %d: no test change for malleable field %s", version, name)
+			}
+		}
+	}
+}
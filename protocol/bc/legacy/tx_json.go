@@ -0,0 +1,279 @@
+package legacy
+
+import (
+	"fmt"
+
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+)
+
+// TxJSON is a structured JSON form of a transaction, for tools
+// that need to inspect one without decoding the wire format
+// themselves. Every field of the transaction appears in it,
+// including its witnesses and any unconsumed suffixes of its
+// extensible strings, so a transaction survives a round trip
+// through TxJSON unchanged.
+//
+// The JSON field names are stable. Byte strings are hex-encoded.
+//
+// Note that Tx and TxData still marshal to JSON as a hex string
+// of the wire format; that is what the Chain Core API uses.
+type TxJSON struct {
+	// ID is set by NewTxJSON. When converting back to a
+	// transaction, it is checked if present.
+	ID *bc.Hash `json:"id,omitempty"`
+
+	Version       uint64             `json:"version"`
+	MinTime       uint64             `json:"min_time"`
+	MaxTime       uint64             `json:"max_time"`
+	Features      uint64             `json:"features,omitempty"`
+	Inputs        []*TxInputJSON     `json:"inputs"`
+	Outputs       []*TxOutputJSON    `json:"outputs"`
+	ReferenceData chainjson.HexBytes `json:"reference_data"`
+
+	// DetachedRefDataHash is set, and ReferenceData is empty,
+	// when the reference data is detached. The same goes for
+	// inputs and outputs.
+	DetachedRefDataHash *bc.Hash `json:"detached_reference_data_hash,omitempty"`
+
+	CommonFieldsSuffix  chainjson.HexBytes `json:"common_fields_suffix,omitempty"`
+	CommonWitnessSuffix chainjson.HexBytes `json:"common_witness_suffix,omitempty"`
+}
+
+// TxInputJSON is the structured JSON form of a transaction input.
+// Type is "issuance", "spend" or "unknown"; only the fields for
+// that type are set.
+type TxInputJSON struct {
+	Type                string             `json:"type"`
+	AssetVersion        uint64             `json:"asset_version"`
+	ReferenceData       chainjson.HexBytes `json:"reference_data"`
+	DetachedRefDataHash *bc.Hash           `json:"detached_reference_data_hash,omitempty"`
+
+	// AssetID is computed for issuances, and is
+	// ignored when converting an issuance back.
+	AssetID *bc.AssetID `json:"asset_id,omitempty"`
+	Amount  uint64      `json:"amount,omitempty"`
+
+	// Issuance fields
+	Nonce           chainjson.HexBytes `json:"nonce,omitempty"`
+	InitialBlockID  *bc.Hash           `json:"initial_block_id,omitempty"`
+	AssetDefinition chainjson.HexBytes `json:"asset_definition,omitempty"`
+	IssuanceProgram chainjson.HexBytes `json:"issuance_program,omitempty"`
+
+	// Spend fields
+	SourceID              *bc.Hash           `json:"source_id,omitempty"`
+	SourcePosition        uint64             `json:"source_position,omitempty"`
+	ControlProgram        chainjson.HexBytes `json:"control_program,omitempty"`
+	OutputRefDataHash     *bc.Hash           `json:"output_reference_data_hash,omitempty"`
+	SpendCommitmentSuffix chainjson.HexBytes `json:"spend_commitment_suffix,omitempty"`
+
+	// VMVersion and Arguments apply to both issuances and spends.
+	VMVersion uint64               `json:"vm_version,omitempty"`
+	Arguments []chainjson.HexBytes `json:"arguments,omitempty"`
+
+	// TypeCode is the type byte of an unknown input.
+	TypeCode byte `json:"type_code,omitempty"`
+
+	CommitmentSuffix chainjson.HexBytes `json:"commitment_suffix,omitempty"`
+	WitnessSuffix    chainjson.HexBytes `json:"witness_suffix,omitempty"`
+}
+
+// TxOutputJSON is the structured JSON form of a transaction output.
+type TxOutputJSON struct {
+	AssetVersion   uint64             `json:"asset_version"`
+	AssetID        bc.AssetID         `json:"asset_id"`
+	Amount         uint64             `json:"amount"`
+	VMVersion      uint64             `json:"vm_version"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	ReferenceData  chainjson.HexBytes `json:"reference_data"`
+
+	DetachedRefDataHash *bc.Hash `json:"detached_reference_data_hash,omitempty"`
+
+	CommitmentSuffix chainjson.HexBytes `json:"commitment_suffix,omitempty"`
+	WitnessSuffix    chainjson.HexBytes `json:"witness_suffix,omitempty"`
+}
+
+// NewTxJSON returns the structured JSON form of tx.
+func NewTxJSON(tx *Tx) *TxJSON {
+	id := tx.ID
+	j := &TxJSON{
+		ID:                  &id,
+		Version:             tx.Version,
+		MinTime:             tx.MinTime,
+		MaxTime:             tx.MaxTime,
+		Features:            uint64(tx.TxData.Features),
+		ReferenceData:       tx.ReferenceData,
+		DetachedRefDataHash: tx.TxData.DetachedRefDataHash,
+		CommonFieldsSuffix:  tx.CommonFieldsSuffix,
+		CommonWitnessSuffix: tx.CommonWitnessSuffix,
+		Inputs:              make([]*TxInputJSON, 0, len(tx.Inputs)),
+		Outputs:             make([]*TxOutputJSON, 0, len(tx.Outputs)),
+	}
+	for _, in := range tx.Inputs {
+		j.Inputs = append(j.Inputs, newTxInputJSON(in))
+	}
+	for _, out := range tx.Outputs {
+		j.Outputs = append(j.Outputs, &TxOutputJSON{
+			AssetVersion:        out.AssetVersion,
+			AssetID:             *out.AssetId,
+			Amount:              out.Amount,
+			VMVersion:           out.VMVersion,
+			ControlProgram:      out.ControlProgram,
+			ReferenceData:       out.ReferenceData,
+			DetachedRefDataHash: out.DetachedRefDataHash,
+			CommitmentSuffix:    out.CommitmentSuffix,
+			WitnessSuffix:       out.WitnessSuffix,
+		})
+	}
+	return j
+}
+
+func newTxInputJSON(in *TxInput) *TxInputJSON {
+	j := &TxInputJSON{
+		AssetVersion:        in.AssetVersion,
+		ReferenceData:       in.ReferenceData,
+		DetachedRefDataHash: in.DetachedRefDataHash,
+		CommitmentSuffix:    in.CommitmentSuffix,
+		WitnessSuffix:       in.WitnessSuffix,
+	}
+	switch ti := in.TypedInput.(type) {
+	case *IssuanceInput:
+		assetID := ti.AssetID()
+		initialBlock := ti.InitialBlock
+		j.Type = "issuance"
+		j.AssetID = &assetID
+		j.Amount = ti.Amount
+		j.Nonce = ti.Nonce
+		j.InitialBlockID = &initialBlock
+		j.AssetDefinition = ti.AssetDefinition
+		j.IssuanceProgram = ti.IssuanceProgram
+		j.VMVersion = ti.VMVersion
+		j.Arguments = hexList(ti.Arguments)
+	case *SpendInput:
+		sourceID := ti.SourceID
+		refDataHash := ti.RefDataHash
+		j.Type = "spend"
+		j.AssetID = ti.AssetId
+		j.Amount = ti.Amount
+		j.SourceID = &sourceID
+		j.SourcePosition = ti.SourcePosition
+		j.ControlProgram = ti.ControlProgram
+		j.OutputRefDataHash = &refDataHash
+		j.SpendCommitmentSuffix = ti.SpendCommitmentSuffix
+		j.VMVersion = ti.VMVersion
+		j.Arguments = hexList(ti.Arguments)
+	case *UnknownInput:
+		j.Type = "unknown"
+		j.TypeCode = ti.Type
+	}
+	return j
+}
+
+// Tx converts j back to a transaction. It returns an error
+// if j is malformed, or if it has an ID that does not match
+// the transaction.
+func (j *TxJSON) Tx() (*Tx, error) {
+	data := TxData{
+		Version:             j.Version,
+		MinTime:             j.MinTime,
+		MaxTime:             j.MaxTime,
+		Features:            bc.TxFeatures(j.Features),
+		ReferenceData:       j.ReferenceData,
+		DetachedRefDataHash: j.DetachedRefDataHash,
+		CommonFieldsSuffix:  j.CommonFieldsSuffix,
+		CommonWitnessSuffix: j.CommonWitnessSuffix,
+	}
+	for i, in := range j.Inputs {
+		ti, err := in.txInput()
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %s", i, err)
+		}
+		data.Inputs = append(data.Inputs, ti)
+	}
+	for _, out := range j.Outputs {
+		assetID := out.AssetID
+		data.Outputs = append(data.Outputs, &TxOutput{
+			AssetVersion: out.AssetVersion,
+			OutputCommitment: OutputCommitment{
+				AssetAmount:    bc.AssetAmount{AssetId: &assetID, Amount: out.Amount},
+				VMVersion:      out.VMVersion,
+				ControlProgram: out.ControlProgram,
+			},
+			CommitmentSuffix:    out.CommitmentSuffix,
+			WitnessSuffix:       out.WitnessSuffix,
+			ReferenceData:       out.ReferenceData,
+			DetachedRefDataHash: out.DetachedRefDataHash,
+		})
+	}
+
+	tx := NewTx(data)
+	if j.ID != nil && *j.ID != tx.ID {
+		return nil, fmt.Errorf("transaction ID %x does not match contents, which hash to %x", j.ID.Bytes(), tx.ID.Bytes())
+	}
+	return tx, nil
+}
+
+func (j *TxInputJSON) txInput() (*TxInput, error) {
+	in := &TxInput{
+		AssetVersion:        j.AssetVersion,
+		ReferenceData:       j.ReferenceData,
+		DetachedRefDataHash: j.DetachedRefDataHash,
+		CommitmentSuffix:    j.CommitmentSuffix,
+		WitnessSuffix:       j.WitnessSuffix,
+	}
+	switch j.Type {
+	case "issuance":
+		if j.InitialBlockID == nil {
+			return nil, fmt.Errorf("issuance has no initial_block_id")
+		}
+		in.TypedInput = &IssuanceInput{
+			Nonce:  j.Nonce,
+			Amount: j.Amount,
+			IssuanceWitness: IssuanceWitness{
+				InitialBlock:    *j.InitialBlockID,
+				AssetDefinition: j.AssetDefinition,
+				VMVersion:       j.VMVersion,
+				IssuanceProgram: j.IssuanceProgram,
+				Arguments:       byteList(j.Arguments),
+			},
+		}
+	case "spend":
+		if j.AssetID == nil || j.SourceID == nil || j.OutputRefDataHash == nil {
+			return nil, fmt.Errorf("spend needs asset_id, source_id and output_reference_data_hash")
+		}
+		assetID := *j.AssetID
+		in.TypedInput = &SpendInput{
+			SpendCommitment: SpendCommitment{
+				AssetAmount:    bc.AssetAmount{AssetId: &assetID, Amount: j.Amount},
+				SourceID:       *j.SourceID,
+				SourcePosition: j.SourcePosition,
+				VMVersion:      j.VMVersion,
+				ControlProgram: j.ControlProgram,
+				RefDataHash:    *j.OutputRefDataHash,
+			},
+			SpendCommitmentSuffix: j.SpendCommitmentSuffix,
+			Arguments:             byteList(j.Arguments),
+		}
+	case "unknown":
+		in.TypedInput = &UnknownInput{Type: j.TypeCode}
+	default:
+		return nil, fmt.Errorf("unknown input type %q", j.Type)
+	}
+	return in, nil
+}
+
+func hexList(l [][]byte) []chainjson.HexBytes {
+	var res []chainjson.HexBytes
+	for _, b := range l {
+		res = append(res, b)
+	}
+	return res
+}
+
+func byteList(l []chainjson.HexBytes) [][]byte {
+	var res [][]byte
+	for _, b := range l {
+		res = append(res, b)
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package legacy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestTxJSON(t *testing.T) {
+	tx := NewTx(TxData{
+		Version: 1,
+		MinTime: 5,
+		MaxTime: 6,
+		Inputs: []*TxInput{
+			NewIssuanceInput([]byte{10}, 100, []byte("issref"), bc.NewHash([32]byte{1}), []byte{1}, [][]byte{{2}, {3}}, []byte("{}")),
+			NewSpendInput([][]byte{{4}}, bc.NewHash([32]byte{2}), bc.AssetID{}, 50, 1, []byte{5}, bc.NewHash([32]byte{3}), nil),
+		},
+		Outputs: []*TxOutput{
+			NewTxOutput(bc.AssetID{}, 150, []byte{6}, []byte("outref")),
+		},
+		ReferenceData: []byte("txref"),
+	})
+	tx.Outputs[0].CommitmentSuffix = []byte{7}
+	tx = NewTx(tx.TxData)
+
+	b, err := json.Marshal(NewTxJSON(tx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"type":"issuance"`, `"type":"spend"`, `"commitment_suffix":"07"`, `"reference_data":"7478726566"`} {
+		if !strings.Contains(string(b), field) {
+			t.Errorf("JSON %s does not contain %s", b, field)
+		}
+	}
+
+	var j TxJSON
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := j.Tx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tx.ID {
+		t.Errorf("got ID %x, want %x", got.ID.Bytes(), tx.ID.Bytes())
+	}
+	if !testutil.DeepEqual(got.TxData, tx.TxData) {
+		t.Errorf("round trip got:\n%swant:\n%s", spew.Sdump(got.TxData), spew.Sdump(tx.TxData))
+	}
+
+	// Changing the contents without the ID fails.
+	j.MaxTime++
+	_, err = j.Tx()
+	if err == nil {
+		t.Error("expected error for mismatched ID")
+	}
+}
@@ -0,0 +1,65 @@
+package legacy
+
+import (
+	"fmt"
+
+	"chain/encoding/blockchain"
+)
+
+// TxReader decodes a serialized transaction one input or output
+// at a time, passing each to a callback instead of collecting
+// them in a TxData. This lets a caller process a transaction with
+// many inputs or outputs without holding all of them, decoded,
+// in memory at once.
+//
+// TxReader does not compute the transaction's ID or any other
+// entry hashes; those need the whole transaction. Use NewTx for
+// that.
+type TxReader struct {
+	// Input, if non-nil, is called with each input, in order.
+	// If it returns an error, Read stops and returns it.
+	Input func(index int, in *TxInput) error
+
+	// Output, if non-nil, is called with each output, in order.
+	// If it returns an error, Read stops and returns it.
+	Output func(index int, out *TxOutput) error
+
+	// Limits bounds the transaction. Read imposes no limits
+	// if it is zero.
+	Limits Limits
+
+	buf []byte
+}
+
+// NewTxReader returns a TxReader for the serialized transaction
+// in b, as written by TxData.WriteTo. The inputs and outputs it
+// produces may refer to the memory of b, so the caller must not
+// modify b while they are in use.
+func NewTxReader(b []byte) *TxReader {
+	return &TxReader{buf: b}
+}
+
+// Read decodes the transaction, calling tr.Input and tr.Output
+// as each input and output is read. It returns the rest of the
+// transaction, with Inputs and Outputs left empty.
+func (tr *TxReader) Read() (*TxData, error) {
+	onInput := tr.Input
+	if onInput == nil {
+		onInput = func(int, *TxInput) error { return nil }
+	}
+	onOutput := tr.Output
+	if onOutput == nil {
+		onOutput = func(int, *TxOutput) error { return nil }
+	}
+
+	r := blockchain.NewReader(tr.buf)
+	tx := new(TxData)
+	_, err := tx.readParts(r, false, tr.Limits, onInput, onOutput)
+	if err != nil {
+		return nil, err
+	}
+	if trailing := r.Len(); trailing > 0 {
+		return nil, fmt.Errorf("trailing garbage (%d bytes)", trailing)
+	}
+	return tx, nil
+}
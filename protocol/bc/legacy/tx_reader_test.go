@@ -0,0 +1,87 @@
+package legacy
+
+import (
+	"bytes"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestTxReader(t *testing.T) {
+	data := TxData{
+		Version: 1,
+		MinTime: 3,
+		MaxTime: 4,
+		Inputs: []*TxInput{
+			NewSpendInput([][]byte{{1}}, bc.NewHash([32]byte{1}), bc.AssetID{}, 10, 0, []byte{2}, bc.Hash{}, nil),
+			NewIssuanceInput([]byte{3}, 5, nil, bc.Hash{}, []byte{4}, nil, nil),
+		},
+		ReferenceData: []byte("refdata"),
+	}
+	for i := 0; i < 100; i++ {
+		data.Outputs = append(data.Outputs, NewTxOutput(bc.AssetID{}, uint64(i), []byte{5}, nil))
+	}
+	var buf bytes.Buffer
+	_, err := data.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ins  []*TxInput
+		outs []*TxOutput
+	)
+	tr := NewTxReader(buf.Bytes())
+	tr.Input = func(i int, in *TxInput) error {
+		if i != len(ins) {
+			t.Errorf("got input index %d, want %d", i, len(ins))
+		}
+		ins = append(ins, in)
+		return nil
+	}
+	tr.Output = func(i int, out *TxOutput) error {
+		if i != len(outs) {
+			t.Errorf("got output index %d, want %d", i, len(outs))
+		}
+		outs = append(outs, out)
+		return nil
+	}
+	got, err := tr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Inputs) != 0 || len(got.Outputs) != 0 {
+		t.Errorf("got %d inputs and %d outputs in TxData, want none", len(got.Inputs), len(got.Outputs))
+	}
+	got.Inputs, got.Outputs = ins, outs
+
+	var want TxData
+	err = want.DecodeLimited(buf.Bytes(), DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.DeepEqual(*got, want) {
+		t.Errorf("TxReader produced %+v, want %+v", got, want)
+	}
+
+	// A callback error stops decoding.
+	stop := errors.New("stop")
+	tr = NewTxReader(buf.Bytes())
+	n := 0
+	tr.Output = func(i int, out *TxOutput) error {
+		n++
+		if i == 10 {
+			return stop
+		}
+		return nil
+	}
+	_, err = tr.Read()
+	if errors.Root(err) != stop {
+		t.Errorf("Read with failing callback = %v, want %v", err, stop)
+	}
+	if n != 11 {
+		t.Errorf("got %d output callbacks, want 11", n)
+	}
+}
@@ -66,3 +66,35 @@ func sampleTx() *TxData {
 		ReferenceData: []byte("distribution"),
 	}
 }
+
+func TestHashTxs(t *testing.T) {
+	var txs []*TxData
+	for i := 0; i < 50; i++ {
+		tx := sampleTx()
+		tx.MinTime += uint64(i)
+		txs = append(txs, tx)
+	}
+	got := HashTxs(txs)
+	if len(got) != len(txs) {
+		t.Fatalf("got %d txs, want %d", len(got), len(txs))
+	}
+	for i, tx := range txs {
+		want := MapTx(tx)
+		if got[i].ID != want.ID {
+			t.Errorf("tx %d: got txid %x, want %x", i, got[i].ID.Bytes(), want.ID.Bytes())
+		}
+	}
+	if len(HashTxs(nil)) != 0 {
+		t.Error("expected no txs")
+	}
+}
+
+func BenchmarkHashTxs500(b *testing.B) {
+	txs := make([]*TxData, 500)
+	for i := range txs {
+		txs[i] = sampleTx()
+	}
+	for i := 0; i < b.N; i++ {
+		_ = HashTxs(txs)
+	}
+}
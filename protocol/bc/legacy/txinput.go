@@ -15,45 +15,83 @@ type (
 		ReferenceData []byte
 		TypedInput
 
+		// DetachedRefDataHash is the hash of the reference data,
+		// if it is detached. See TxData.DetachReferenceData.
+		DetachedRefDataHash *bc.Hash
+
 		// Unconsumed suffixes of the commitment and witness extensible
 		// strings.
 		CommitmentSuffix []byte
 		WitnessSuffix    []byte
+
+		// witnessless is set for an input decoded
+		// without its witness. See DecodePartial.
+		witnessless bool
 	}
 
 	TypedInput interface {
 		IsIssuance() bool
 	}
+
+	// UnknownInput satisfies the TypedInput interface and represents
+	// an input whose type code is not understood by this software. It
+	// only appears in transactions with a version greater than
+	// CurrentTransactionVersion. The remainder of the input commitment
+	// and the entire input witness are preserved in the enclosing
+	// TxInput's CommitmentSuffix and WitnessSuffix, so the input can be
+	// reserialized unchanged.
+	UnknownInput struct {
+		Type byte
+	}
 )
 
-var errBadAssetID = errors.New("asset ID does not match other issuance parameters")
+func (ui *UnknownInput) IsIssuance() bool { return false }
 
+var (
+	errBadAssetID = errors.New("asset ID does not match other issuance parameters")
+	errNoWitness  = errors.New("input has no witness")
+	errNoPrevout  = errors.New("spend has no prevout")
+)
+
+// AssetAmount returns the asset ID and amount of t. An input of
+// unknown type has no value this software can know; like MapTx,
+// it reports the zero asset ID and amount for one.
 func (t *TxInput) AssetAmount() bc.AssetAmount {
-	if ii, ok := t.TypedInput.(*IssuanceInput); ok {
-		assetID := ii.AssetID()
+	switch inp := t.TypedInput.(type) {
+	case *IssuanceInput:
+		assetID := inp.AssetID()
 		return bc.AssetAmount{
 			AssetId: &assetID,
-			Amount:  ii.Amount,
+			Amount:  inp.Amount,
 		}
+	case *SpendInput:
+		return inp.AssetAmount
 	}
-	si := t.TypedInput.(*SpendInput)
-	return si.AssetAmount
+	return bc.AssetAmount{AssetId: &bc.AssetID{}}
 }
 
+// AssetID returns the asset ID of t, or the zero
+// asset ID for an input of unknown type.
 func (t *TxInput) AssetID() bc.AssetID {
-	if ii, ok := t.TypedInput.(*IssuanceInput); ok {
-		return ii.AssetID()
+	switch inp := t.TypedInput.(type) {
+	case *IssuanceInput:
+		return inp.AssetID()
+	case *SpendInput:
+		return *inp.AssetId
 	}
-	si := t.TypedInput.(*SpendInput)
-	return *si.AssetId
+	return bc.AssetID{}
 }
 
+// Amount returns the amount of t, or
+// zero for an input of unknown type.
 func (t *TxInput) Amount() uint64 {
-	if ii, ok := t.TypedInput.(*IssuanceInput); ok {
-		return ii.Amount
+	switch inp := t.TypedInput.(type) {
+	case *IssuanceInput:
+		return inp.Amount
+	case *SpendInput:
+		return inp.Amount
 	}
-	si := t.TypedInput.(*SpendInput)
-	return si.Amount
+	return 0
 }
 
 func (t *TxInput) ControlProgram() []byte {
@@ -89,7 +127,41 @@ func (t *TxInput) SetArguments(args [][]byte) {
 	}
 }
 
-func (t *TxInput) readFrom(r *blockchain.Reader) (err error) {
+// HasWitness reports whether t has its witness. It is false for
+// an input decoded without one, until SetWitness is called.
+func (t *TxInput) HasWitness() bool {
+	return !t.witnessless
+}
+
+// SetWitness restores the witness of an input decoded without one.
+// For an issuance, iw holds the issuance witness; its asset ID must
+// match the input's. For a spend, iw must be nil. In both cases,
+// args are the input's witness arguments.
+func (t *TxInput) SetWitness(iw *IssuanceWitness, args [][]byte) error {
+	switch inp := t.TypedInput.(type) {
+	case *IssuanceInput:
+		if iw == nil {
+			return errors.New("issuance needs an issuance witness")
+		}
+		restored := IssuanceInput{Nonce: inp.Nonce, Amount: inp.Amount, IssuanceWitness: *iw}
+		restored.Arguments = args
+		if inp.assetID != nil && restored.AssetID() != *inp.assetID {
+			return errBadAssetID
+		}
+		*inp = restored
+	case *SpendInput:
+		if iw != nil {
+			return errors.New("spend cannot have an issuance witness")
+		}
+		inp.Arguments = args
+	default:
+		return errors.New("cannot set the witness of an unknown input type")
+	}
+	t.witnessless = false
+	return nil
+}
+
+func (t *TxInput) readFrom(r *blockchain.Reader, txVersion uint64, serflags uint8, lim Limits) (err error) {
 	t.AssetVersion, err = blockchain.ReadVarint63(r)
 	if err != nil {
 		return err
@@ -98,6 +170,7 @@ func (t *TxInput) readFrom(r *blockchain.Reader) (err error) {
 	var (
 		ii      *IssuanceInput
 		si      *SpendInput
+		ui      *UnknownInput
 		assetID bc.AssetID
 	)
 
@@ -126,16 +199,31 @@ func (t *TxInput) readFrom(r *blockchain.Reader) (err error) {
 			if err != nil {
 				return err
 			}
+			if serflags&SerWitness == 0 {
+				// Without the witness, the asset ID
+				// can't be computed, so keep it.
+				ii.assetID = &assetID
+			}
 
 		case 1:
 			si = new(SpendInput)
+			if serflags&SerPrevout == 0 {
+				si.prevoutHash = new(bc.Hash)
+				_, err = si.prevoutHash.ReadFrom(r)
+				return err
+			}
 			si.SpendCommitmentSuffix, err = si.SpendCommitment.readFrom(r, 1)
 			if err != nil {
 				return err
 			}
 
 		default:
-			return fmt.Errorf("unsupported input type %d", icType[0])
+			if txVersion <= CurrentTransactionVersion {
+				return fmt.Errorf("unsupported input type %d", icType[0])
+			}
+			// Leave the rest of the commitment unread; it becomes
+			// the commitment suffix.
+			ui = &UnknownInput{Type: icType[0]}
 		}
 		return nil
 	})
@@ -143,14 +231,19 @@ func (t *TxInput) readFrom(r *blockchain.Reader) (err error) {
 		return err
 	}
 
-	t.ReferenceData, err = blockchain.ReadVarstr31(r)
+	t.ReferenceData, t.DetachedRefDataHash, err = readRefData(r, serflags, lim.MaxRefDataLen)
 	if err != nil {
 		return err
 	}
 
-	t.WitnessSuffix, err = blockchain.ReadExtensibleString(r, func(r *blockchain.Reader) error {
-		// TODO(bobg): test that serialization flags include SerWitness, when we relax the serflags-must-be-0x7 rule
-		if t.AssetVersion != 1 {
+	if serflags&SerWitness == 0 {
+		t.witnessless = true
+		t.setTypedInput(ii, si, ui)
+		return nil
+	}
+
+	t.WitnessSuffix, err = blockchain.ReadExtensibleStringMax(r, maxLen(lim.MaxWitnessLen), func(r *blockchain.Reader) error {
+		if t.AssetVersion != 1 || ui != nil {
 			return nil
 		}
 
@@ -194,15 +287,24 @@ func (t *TxInput) readFrom(r *blockchain.Reader) (err error) {
 	if err != nil {
 		return err
 	}
+	t.setTypedInput(ii, si, ui)
+	return nil
+}
+
+func (t *TxInput) setTypedInput(ii *IssuanceInput, si *SpendInput, ui *UnknownInput) {
 	if ii != nil {
 		t.TypedInput = ii
 	} else if si != nil {
 		t.TypedInput = si
+	} else if ui != nil {
+		t.TypedInput = ui
 	}
-	return nil
 }
 
-func (t *TxInput) writeTo(w io.Writer, serflags uint8) error {
+// writeTo writes t to w. If c is non-nil, it is the sizeCounter
+// underlying w, and writeTo counts the input witness in it
+// separately, for the transaction's weight.
+func (t *TxInput) writeTo(w io.Writer, serflags uint8, c *sizeCounter) error {
 	_, err := blockchain.WriteVarint63(w, t.AssetVersion)
 	if err != nil {
 		return errors.Wrap(err, "writing asset version")
@@ -216,13 +318,22 @@ func (t *TxInput) writeTo(w io.Writer, serflags uint8) error {
 		return errors.Wrap(err, "writing input commitment")
 	}
 
-	_, err = blockchain.WriteVarstr31(w, t.ReferenceData)
+	err = writeRefData(w, t.ReferenceData, t.DetachedRefDataHash, serflags)
 	if err != nil {
 		return errors.Wrap(err, "writing reference data")
 	}
 
 	if serflags&SerWitness != 0 {
+		if t.witnessless {
+			return errNoWitness
+		}
+		if c != nil {
+			c.inWitness = true
+		}
 		_, err = blockchain.WriteExtensibleString(w, t.WitnessSuffix, t.writeInputWitness)
+		if c != nil {
+			c.inWitness = false
+		}
 		if err != nil {
 			return errors.Wrap(err, "writing input witness")
 		}
@@ -258,13 +369,22 @@ func (t *TxInput) WriteInputCommitment(w io.Writer, serflags uint8) error {
 		if err != nil {
 			return err
 		}
-		if serflags&SerPrevout != 0 {
+		if inp.prevoutHash != nil {
+			if serflags&SerPrevout != 0 {
+				return errNoPrevout
+			}
+			_, err = inp.prevoutHash.WriteTo(w)
+		} else if serflags&SerPrevout != 0 {
 			err = inp.SpendCommitment.writeExtensibleString(w, inp.SpendCommitmentSuffix, t.AssetVersion)
 		} else {
 			prevouthash := inp.SpendCommitment.Hash(inp.SpendCommitmentSuffix, t.AssetVersion)
 			_, err = prevouthash.WriteTo(w)
 		}
 		return err
+
+	case *UnknownInput:
+		_, err := w.Write([]byte{inp.Type})
+		return err
 	}
 	return nil
 }
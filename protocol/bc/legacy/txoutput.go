@@ -20,6 +20,10 @@ type TxOutput struct {
 	WitnessSuffix    []byte
 
 	ReferenceData []byte
+
+	// DetachedRefDataHash is the hash of the reference data,
+	// if it is detached. See TxData.DetachReferenceData.
+	DetachedRefDataHash *bc.Hash
 }
 
 func NewTxOutput(assetID bc.AssetID, amount uint64, controlProgram, referenceData []byte) *TxOutput {
@@ -37,7 +41,7 @@ func NewTxOutput(assetID bc.AssetID, amount uint64, controlProgram, referenceDat
 	}
 }
 
-func (to *TxOutput) readFrom(r *blockchain.Reader, txVersion uint64) (err error) {
+func (to *TxOutput) readFrom(r *blockchain.Reader, txVersion uint64, serflags uint8, lim Limits) (err error) {
 	to.AssetVersion, err = blockchain.ReadVarint63(r)
 	if err != nil {
 		return errors.Wrap(err, "reading asset version")
@@ -48,7 +52,7 @@ func (to *TxOutput) readFrom(r *blockchain.Reader, txVersion uint64) (err error)
 		return errors.Wrap(err, "reading output commitment")
 	}
 
-	to.ReferenceData, err = blockchain.ReadVarstr31(r)
+	to.ReferenceData, to.DetachedRefDataHash, err = readRefData(r, serflags, lim.MaxRefDataLen)
 	if err != nil {
 		return errors.Wrap(err, "reading reference data")
 	}
@@ -70,7 +74,7 @@ func (to *TxOutput) writeTo(w io.Writer, serflags byte) error {
 		return errors.Wrap(err, "writing output commitment")
 	}
 
-	err = writeRefData(w, to.ReferenceData, serflags)
+	err = writeRefData(w, to.ReferenceData, to.DetachedRefDataHash, serflags)
 	if err != nil {
 		return errors.Wrap(err, "writing reference data")
 	}
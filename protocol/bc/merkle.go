@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"chain/crypto/sha3pool"
+	"chain/errors"
 )
 
 var (
@@ -62,3 +63,121 @@ func prevPowerOfTwo(n int) int {
 	exponent := uint(math.Log2(float64(n)))
 	return 1 << exponent // 2^exponent
 }
+
+// MerklePath returns the hashes needed to prove that ids[index]
+// is included in the merkle tree of ids, ordered from the leaf's
+// sibling up to the child of the root. Together with the index
+// and the number of ids, the path determines the root; see
+// VerifyMerklePath.
+func MerklePath(ids []Hash, index int) []Hash {
+	if len(ids) <= 1 {
+		return nil
+	}
+	k := prevPowerOfTwo(len(ids))
+	if index < k {
+		return append(MerklePath(ids[:k], index), merkleRootIDs(ids[k:]))
+	}
+	return append(MerklePath(ids[k:], index-k), merkleRootIDs(ids[:k]))
+}
+
+// VerifyMerklePath reports whether path proves that id is at
+// position index in a merkle tree of count leaves with the
+// given root.
+func VerifyMerklePath(root, id Hash, index, count int, path []Hash) bool {
+	if index < 0 || index >= count {
+		return false
+	}
+	got, rest, ok := pathRoot(id, index, count, path)
+	return ok && len(rest) == 0 && got == root
+}
+
+// TxMerkleProof proves that a transaction is included in a
+// block, given the block's transactions merkle root. It is
+// much smaller than the block: the path holds one hash per
+// level of the merkle tree.
+type TxMerkleProof struct {
+	// Index is the position of the transaction among
+	// the Count transactions of the block.
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+	Path  []Hash `json:"path"`
+}
+
+// TxMerkleProof returns a proof that the transaction at
+// index in b is included in b's transactions merkle root.
+func (b *Block) TxMerkleProof(index int) (*TxMerkleProof, error) {
+	if index < 0 || index >= len(b.Transactions) {
+		return nil, errors.WithDetailf(errInvalidValue, "transaction index %d out of range; block has %d transactions", index, len(b.Transactions))
+	}
+	ids := make([]Hash, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		ids[i] = tx.ID
+	}
+	return &TxMerkleProof{
+		Index: index,
+		Count: len(ids),
+		Path:  MerklePath(ids, index),
+	}, nil
+}
+
+// VerifyTxMerkleProof reports whether proof shows that the
+// transaction with ID txID is included in a block with the
+// transactions merkle root root.
+//
+// The leaves of the merkle tree are transaction IDs, which
+// don't depend on witnesses; a proof holds for the transaction
+// with any valid witness.
+func VerifyTxMerkleProof(root Hash, proof *TxMerkleProof, txID Hash) bool {
+	return VerifyMerklePath(root, txID, proof.Index, proof.Count, proof.Path)
+}
+
+// pathRoot computes the root of a subtree of count leaves from
+// the leaf at index and the tail of path. It returns the part
+// of path not consumed by the subtree.
+func pathRoot(id Hash, index, count int, path []Hash) (root Hash, rest []Hash, ok bool) {
+	if count == 1 {
+		return leafHash(id), path, true
+	}
+	k := prevPowerOfTwo(count)
+	var sub Hash
+	if index < k {
+		sub, path, ok = pathRoot(id, index, k, path)
+	} else {
+		sub, path, ok = pathRoot(id, index-k, count-k, path)
+	}
+	if !ok || len(path) == 0 {
+		return root, nil, false
+	}
+	sibling, rest := path[0], path[1:]
+	if index < k {
+		return interiorHash(sub, sibling), rest, true
+	}
+	return interiorHash(sibling, sub), rest, true
+}
+
+func merkleRootIDs(ids []Hash) Hash {
+	if len(ids) == 1 {
+		return leafHash(ids[0])
+	}
+	k := prevPowerOfTwo(len(ids))
+	return interiorHash(merkleRootIDs(ids[:k]), merkleRootIDs(ids[k:]))
+}
+
+func leafHash(id Hash) (h Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+	hasher.Write(leafPrefix)
+	id.WriteTo(hasher)
+	h.ReadFrom(hasher)
+	return h
+}
+
+func interiorHash(left, right Hash) (h Hash) {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+	hasher.Write(interiorPrefix)
+	left.WriteTo(hasher)
+	right.WriteTo(hasher)
+	h.ReadFrom(hasher)
+	return h
+}
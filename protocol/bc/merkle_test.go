@@ -139,6 +139,63 @@ func TestAllDuplicateLeaves(t *testing.T) {
 	}
 }
 
+func TestMerklePath(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var (
+			ids []Hash
+			txs []*Tx
+		)
+		for i := 0; i < n; i++ {
+			id := NewHash([32]byte{byte(i + 1)})
+			ids = append(ids, id)
+			txs = append(txs, &Tx{ID: id})
+		}
+		root, err := MerkleRoot(txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range ids {
+			path := MerklePath(ids, i)
+			if !VerifyMerklePath(root, ids[i], i, n, path) {
+				t.Errorf("n=%d i=%d: valid path rejected", n, i)
+			}
+			if n > 1 && VerifyMerklePath(root, ids[i], (i+1)%n, n, path) {
+				t.Errorf("n=%d i=%d: path accepted at wrong index", n, i)
+			}
+			if VerifyMerklePath(root, NewHash([32]byte{0xff}), i, n, path) {
+				t.Errorf("n=%d i=%d: path accepted for wrong id", n, i)
+			}
+		}
+	}
+}
+
+func TestTxMerkleProof(t *testing.T) {
+	b := &Block{BlockHeader: &BlockHeader{}}
+	for i := 0; i < 5; i++ {
+		b.Transactions = append(b.Transactions, &Tx{ID: NewHash([32]byte{byte(i + 1)})})
+	}
+	root, err := MerkleRoot(b.Transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := b.TxMerkleProof(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyTxMerkleProof(root, proof, b.Transactions[3].ID) {
+		t.Error("valid proof rejected")
+	}
+	if VerifyTxMerkleProof(root, proof, b.Transactions[2].ID) {
+		t.Error("proof accepted for another transaction")
+	}
+
+	_, err = b.TxMerkleProof(5)
+	if err == nil {
+		t.Error("expected error for out-of-range index")
+	}
+}
+
 func mustDecodeHash(s string) (h Hash) {
 	err := h.UnmarshalText([]byte(s))
 	if err != nil {
@@ -0,0 +1,111 @@
+package bc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"chain/encoding/blockchain"
+)
+
+// OutpointSet is a set of output IDs, such as the outputs spent
+// by a transaction or a block. Adding and membership tests take
+// constant time. The zero value is not usable; see NewOutpointSet.
+type OutpointSet struct {
+	m map[Hash]struct{}
+}
+
+// NewOutpointSet returns a set holding ids.
+func NewOutpointSet(ids ...Hash) *OutpointSet {
+	s := &OutpointSet{m: make(map[Hash]struct{}, len(ids))}
+	for _, id := range ids {
+		s.Add(id)
+	}
+	return s
+}
+
+// Add adds id to s. It returns false if id was already in s,
+// as when an output is spent twice.
+func (s *OutpointSet) Add(id Hash) bool {
+	if _, ok := s.m[id]; ok {
+		return false
+	}
+	s.m[id] = struct{}{}
+	return true
+}
+
+// Contains reports whether id is in s.
+func (s *OutpointSet) Contains(id Hash) bool {
+	_, ok := s.m[id]
+	return ok
+}
+
+// Remove removes id from s.
+func (s *OutpointSet) Remove(id Hash) {
+	delete(s.m, id)
+}
+
+// Len returns the number of IDs in s.
+func (s *OutpointSet) Len() int {
+	return len(s.m)
+}
+
+// Sorted returns the IDs in s in increasing byte order.
+func (s *OutpointSet) Sorted() []Hash {
+	ids := make([]Hash, 0, len(s.m))
+	for id := range s.m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i].Byte32(), ids[j].Byte32()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	return ids
+}
+
+// MarshalBinary encodes s canonically: the number of IDs as a
+// varint, then each 32-byte ID, in increasing byte order. Equal
+// sets have equal encodings.
+func (s *OutpointSet) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := blockchain.WriteVarint31(&buf, uint64(len(s.m)))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range s.Sorted() {
+		_, err = id.WriteTo(&buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes b, as encoded by MarshalBinary, into s.
+// It rejects encodings that are not canonical.
+func (s *OutpointSet) UnmarshalBinary(b []byte) error {
+	r := blockchain.NewReader(b)
+	n, err := blockchain.ReadVarint31(r)
+	if err != nil {
+		return err
+	}
+	if r.Len() != int(n)*32 {
+		return fmt.Errorf("outpoint set of %d IDs has %d bytes", n, r.Len())
+	}
+	s.m = make(map[Hash]struct{}, n)
+	var prev []byte
+	for i := 0; i < int(n); i++ {
+		var id Hash
+		_, err = id.ReadFrom(r)
+		if err != nil {
+			return err
+		}
+		cur := id.Bytes()
+		if prev != nil && bytes.Compare(prev, cur) >= 0 {
+			return fmt.Errorf("outpoint set IDs out of order at %d", i)
+		}
+		prev = cur
+		s.m[id] = struct{}{}
+	}
+	return nil
+}
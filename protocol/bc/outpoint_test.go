@@ -0,0 +1,57 @@
+package bc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutpointSet(t *testing.T) {
+	a, b, c := NewHash([32]byte{3}), NewHash([32]byte{1}), NewHash([32]byte{2})
+	s := NewOutpointSet(a, b)
+	if !s.Add(c) {
+		t.Error("Add of a new ID returned false")
+	}
+	if s.Add(a) {
+		t.Error("Add of an ID already present returned true")
+	}
+	if !s.Contains(b) || s.Len() != 3 {
+		t.Errorf("got set of %d, containing %x: %v; want 3 containing it", s.Len(), b.Bytes(), s.Contains(b))
+	}
+
+	enc, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Insertion order doesn't affect the encoding.
+	enc2, err := NewOutpointSet(c, b, a).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, enc2) {
+		t.Errorf("encodings differ: %x and %x", enc, enc2)
+	}
+
+	var got OutpointSet
+	err = got.UnmarshalBinary(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := got.Sorted()
+	if len(sorted) != 3 || sorted[0] != b || sorted[1] != c || sorted[2] != a {
+		t.Errorf("decoded %v, want [%v %v %v]", sorted, b, c, a)
+	}
+
+	// Out-of-order encodings are not canonical.
+	swapped := append([]byte{enc[0]}, enc[33:65]...)
+	swapped = append(swapped, enc[1:33]...)
+	swapped = append(swapped, enc[65:]...)
+	err = got.UnmarshalBinary(swapped)
+	if err == nil {
+		t.Error("expected error for out-of-order encoding")
+	}
+
+	s.Remove(a)
+	if s.Contains(a) {
+		t.Error("Contains after Remove returned true")
+	}
+}
@@ -12,17 +12,56 @@ type Tx struct {
 	Entries  map[Hash]Entry
 	InputIDs []Hash // 1:1 correspondence with TxData.Inputs
 
+	// Features holds the feature bits of a transaction of
+	// version TxFeatureVersion or later. The header's ExtHash
+	// commits to them.
+	Features TxFeatures
+
 	// IDs of reachable entries of various kinds
 	NonceIDs       []Hash
 	SpentOutputIDs []Hash
 }
 
-func (tx *Tx) SigHash(n uint32) (hash Hash) {
+// NonWitnessID returns the hash of the parts of tx that its
+// signatures and validation rules fix in advance. It is the same as
+// tx.ID: an entry's ID covers only its non-witness fields, so
+// nothing in a witness, such as the arguments to a control program,
+// can change the ID of a transaction. Wallets can track a
+// transaction by this ID before it's confirmed, even if someone
+// relaying it alters its witnesses.
+//
+// See legacy.MalleableFields for the parts of a
+// transaction the ID does not cover.
+func (tx *Tx) NonWitnessID() Hash {
+	return tx.ID
+}
+
+// TxReplayProtectionVersion is the first transaction version
+// whose signature hashes commit to the initial block ID of the
+// blockchain. A signature made for one blockchain is then invalid
+// on every other, even one that shares its keys.
+//
+// Version 1 blocks accept only version 1 transactions, so this
+// takes effect once the generator makes blocks of version 2 or
+// later (see protocol.Chain.BlockVersion). Until then, the
+// generator leaves later transaction versions out of its blocks.
+//
+// Blocks need no such rule: a block ID commits to the initial
+// block ID through the chain of previous block IDs.
+const TxReplayProtectionVersion = 4
+
+// SigHash returns the hash that the TXSIGHASH instruction
+// produces for input n of tx, on the blockchain with the
+// given initial block ID.
+func (tx *Tx) SigHash(n uint32, initialBlockID Hash) (hash Hash) {
 	hasher := sha3pool.Get256()
 	defer sha3pool.Put256(hasher)
 
 	tx.InputIDs[n].WriteTo(hasher)
 	tx.ID.WriteTo(hasher)
+	if tx.Version >= TxReplayProtectionVersion {
+		initialBlockID.WriteTo(hasher)
+	}
 	hash.ReadFrom(hasher)
 	return hash
 }
@@ -0,0 +1,37 @@
+package bc
+
+import "io"
+
+// UnknownInput stands in for an input whose type is not
+// understood by this software, in a transaction with a newer
+// version. It commits to the input's type code and to the
+// hash of the rest of its commitment, so transactions that
+// differ only in such an input have different IDs. Its value
+// can't be known, so it can't be validated. It satisfies the
+// Entry interface.
+
+func (UnknownInput) typ() string { return "unknowninput1" }
+func (u *UnknownInput) writeForHash(w io.Writer) {
+	mustWriteForHash(w, u.Type)
+	mustWriteForHash(w, u.Body)
+	mustWriteForHash(w, u.Data)
+	mustWriteForHash(w, u.ExtHash)
+}
+
+func (u *UnknownInput) SetDestination(id *Hash, val *AssetAmount, pos uint64) {
+	u.WitnessDestination = &ValueDestination{
+		Ref:      id,
+		Value:    val,
+		Position: pos,
+	}
+}
+
+// NewUnknownInput creates a new UnknownInput.
+func NewUnknownInput(typ uint64, body, data *Hash, ordinal uint64) *UnknownInput {
+	return &UnknownInput{
+		Type:    typ,
+		Body:    body,
+		Data:    data,
+		Ordinal: ordinal,
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"chain/log"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
 	"chain/protocol/state"
 	"chain/protocol/validation"
 	"chain/protocol/vm/vmutil"
@@ -58,9 +59,16 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 	newSnapshot := state.Copy(c.state.snapshot)
 	newSnapshot.PruneNonces(timestampMS)
 
+	// A block's version can't be lower than its predecessor's,
+	// so once raised, it stays raised.
+	version := prev.Version
+	if c.BlockVersion > version {
+		version = c.BlockVersion
+	}
+
 	b := &legacy.Block{
 		BlockHeader: legacy.BlockHeader{
-			Version:           1,
+			Version:           version,
 			Height:            prev.Height + 1,
 			PreviousBlockHash: prev.Hash(),
 			TimestampMS:       timestampMS,
@@ -84,6 +92,18 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 			continue
 		}
 
+		// Version 1 blocks accept only version 1 transactions.
+		if b.Version == 1 && tx.Version != 1 {
+			continue
+		}
+
+		// Filter out transactions with inputs this software
+		// can't validate. Blocks from newer generators may
+		// have them, but this generator can't vouch for them.
+		if tx.HasUnknownInput() {
+			continue
+		}
+
 		// Filter out transactions that are not yet valid, or no longer
 		// valid, per the block's timestamp.
 		if tx.Tx.MinTimeMs > 0 && tx.Tx.MinTimeMs > b.TimestampMS {
@@ -97,6 +117,12 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 
 		// Filter out double-spends etc.
 		err = newSnapshot.ApplyTx(tx.Tx)
+		if errors.Root(err) == patricia.ErrLoad {
+			// An I/O error reading the state tree, not a bad tx.
+			// Skipping the tx would leave the snapshot partly
+			// updated, so give up on the block.
+			return nil, nil, errors.Wrap(err, "applying tx")
+		}
 		if err != nil {
 			// TODO(bobg): log this?
 			continue
@@ -183,12 +209,24 @@ func (c *Chain) CommitBlock(ctx context.Context, block *legacy.Block) error {
 }
 
 func (c *Chain) finalizeCommitBlock(ctx context.Context, block *legacy.Block, snapshot *state.Snapshot) error {
+	if c.DiskStore != nil {
+		snapshot = state.Copy(snapshot)
+		err := c.DiskStore.Evict(snapshot.Tree)
+		if err != nil {
+			return errors.Wrap(err, "moving state tree to disk")
+		}
+	}
+
 	// Save the blockchain state tree snapshot to persistent storage
 	// if we haven't done it recently.
 	if block.Time().After(c.lastQueuedSnapshot.Add(saveSnapshotFrequency)) {
 		c.queueSnapshot(ctx, block.Height, block.Time(), snapshot)
 	}
 
+	if c.OnCommit != nil {
+		c.OnCommit(ctx, block)
+	}
+
 	// setState will update c's current block and snapshot, or no-op
 	// if another goroutine has already updated the state.
 	c.setState(block, snapshot)
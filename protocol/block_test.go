@@ -336,3 +336,46 @@ func mustDecodeHash(s string) (h bc.Hash) {
 	}
 	return h
 }
+
+func TestGenerateBlockVersion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(233400000, 0)
+	c, b1 := newTestChain(t, now)
+	now = now.Add(time.Second)
+
+	initialBlockHash := b1.Hash()
+	assetID := bc.ComputeAssetID(nil, &initialBlockHash, 1, &bc.EmptyStringHash)
+	tx := legacy.NewTx(legacy.TxData{
+		Version: bc.TxReplayProtectionVersion,
+		MinTime: bc.Millis(now),
+		MaxTime: bc.Millis(now) + 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewIssuanceInput([]byte{1}, 50, nil, initialBlockHash, nil, [][]byte{{1}}, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 50, []byte{1}, nil),
+		},
+	})
+
+	// Version 1 blocks leave out later transaction versions.
+	got, _, err := c.GenerateBlock(ctx, b1, state.Empty(), now, []*legacy.Tx{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 1 || len(got.Transactions) != 0 {
+		t.Errorf("got version %d block with %d txs, want version 1 block with none", got.Version, len(got.Transactions))
+	}
+
+	c.BlockVersion = 2
+	got, _, err = c.GenerateBlock(ctx, b1, state.Empty(), now, []*legacy.Tx{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 2 || len(got.Transactions) != 1 {
+		t.Errorf("got version %d block with %d txs, want version 2 block with 1", got.Version, len(got.Transactions))
+	}
+	err = c.ValidateBlockForSig(ctx, got)
+	if err != nil {
+		t.Errorf("validating version 2 block: %s", err)
+	}
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/validation"
+)
+
+// VerifyBlockHeaderChain validates a contiguous chain of block
+// headers, in increasing order of height, without a Chain or a
+// Store. It is meant for light clients that track the blockchain
+// from headers alone.
+//
+// The first header must satisfy consensusProgram, which the
+// caller trusts: the next consensus program of the block before
+// it. Each later header must follow from the one before it and
+// satisfy its next consensus program. The initial block is not
+// signed; if its header is first, consensusProgram is unused.
+//
+// Headers carry no transactions, so VerifyBlockHeaderChain
+// checks neither transactions nor merkle roots.
+func VerifyBlockHeaderChain(headers []bc.BlockHeader, consensusProgram []byte) error {
+	var prev *bc.Block
+	for i := range headers {
+		b := &bc.Block{ID: bc.EntryID(&headers[i]), BlockHeader: &headers[i]}
+
+		// The block before the first is unknown; prev is nil
+		// for it, and its header is checked on its own.
+		err := validation.ValidateBlockHeader(b, prev)
+		if err == nil && b.Height > 1 {
+			prog := consensusProgram
+			if prev != nil {
+				prog = prev.NextConsensusProgram
+			}
+			err = validation.ValidateBlockSig(b, prog)
+		}
+		if err != nil {
+			return errors.Sub(ErrBadBlock, errors.Wrapf(err, "header %d (height %d)", i, b.Height))
+		}
+		prev = b
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest/memstore"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestVerifyBlockHeaderChain(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Minute)
+	b1, err := NewInitialBlock([]ed25519.PublicKey{pub}, 1, start)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	c, err := NewChain(ctx, b1.Hash(), memstore.New(), nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = c.CommitAppliedBlock(ctx, b1, state.Empty())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	blocks := []*legacy.Block{b1}
+	for i := 0; i < 3; i++ {
+		prev := blocks[len(blocks)-1]
+		b, s, err := c.GenerateBlock(ctx, prev, state.Empty(), start.Add(time.Duration(i+1)*time.Second), nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		h := b.Hash()
+		b.Witness = [][]byte{ed25519.Sign(priv, h.Bytes())}
+		err = c.CommitAppliedBlock(ctx, b, s)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	headers := func(blocks []*legacy.Block) []bc.BlockHeader {
+		var hs []bc.BlockHeader
+		for _, b := range blocks {
+			hs = append(hs, *legacy.MapBlock(b).BlockHeader)
+		}
+		return hs
+	}
+
+	err = VerifyBlockHeaderChain(headers(blocks), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyBlockHeaderChain(headers(blocks[2:]), blocks[1].ConsensusProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A gap in the chain fails.
+	hs := headers([]*legacy.Block{blocks[1], blocks[3]})
+	err = VerifyBlockHeaderChain(hs, b1.ConsensusProgram)
+	if errors.Root(err) != ErrBadBlock {
+		t.Errorf("gap: got error %v, want %s", err, ErrBadBlock)
+	}
+
+	// So does a bad signature.
+	hs = headers(blocks[1:])
+	hs[1].WitnessArguments = [][]byte{make([]byte, ed25519.SignatureSize)}
+	err = VerifyBlockHeaderChain(hs, b1.ConsensusProgram)
+	if errors.Root(err) != ErrBadBlock {
+		t.Errorf("bad signature: got error %v, want %s", err, ErrBadBlock)
+	}
+
+	// And an untrusted first header.
+	_, otherPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *blocks[1]
+	h := forged.Hash()
+	forged.Witness = [][]byte{ed25519.Sign(otherPriv, h.Bytes())}
+	err = VerifyBlockHeaderChain(headers([]*legacy.Block{&forged}), b1.ConsensusProgram)
+	if errors.Root(err) != ErrBadBlock {
+		t.Errorf("forged header: got error %v, want %s", err, ErrBadBlock)
+	}
+}
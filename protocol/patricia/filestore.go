@@ -0,0 +1,179 @@
+package patricia
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+
+	"chain/errors"
+)
+
+const (
+	fileStoreBufSize = 1 << 20
+	minMapSize       = 64 << 20
+)
+
+var (
+	errFileStoreClosed = errors.New("file store is closed")
+	errBadRef          = errors.New("bad node reference")
+)
+
+// FileStore is a Store in a temporary file. It reads nodes
+// through a memory mapping of the file, so the operating system
+// keeps the nodes in use in memory and can page out the rest.
+//
+// The file is removed as soon as it is created, and its space is
+// reclaimed when the FileStore is closed, or garbage collected
+// once no tree refers to it. Nodes are never removed from the
+// file, so it grows as the trees stored in it change.
+type FileStore struct {
+	mu      sync.Mutex
+	f       *os.File
+	size    int64  // bytes appended, including buf
+	flushed int64  // bytes written to f
+	buf     []byte // appended but not yet written to f
+	mapped  []byte // the latest mapping of f
+	old     [][]byte
+	closed  bool
+}
+
+// NewFileStore creates a FileStore in a new file in dir.
+func NewFileStore(dir string) (*FileStore, error) {
+	f, err := ioutil.TempFile(dir, "statetree")
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	err = os.Remove(f.Name())
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err)
+	}
+	fs := &FileStore{f: f}
+	runtime.SetFinalizer(fs, (*FileStore).Close)
+	return fs, nil
+}
+
+// Append implements Store.
+func (fs *FileStore) Append(data []byte) (uint64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed {
+		return 0, errFileStoreClosed
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	ref := fs.size
+	fs.buf = append(fs.buf, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(data)))]...)
+	fs.buf = append(fs.buf, data...)
+	fs.size = fs.flushed + int64(len(fs.buf))
+	if len(fs.buf) >= fileStoreBufSize {
+		err := fs.flush()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return uint64(ref), nil
+}
+
+// Load implements Store. The data it returns
+// remains valid until fs is closed.
+func (fs *FileStore) Load(ref uint64) ([]byte, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed {
+		return nil, errFileStoreClosed
+	}
+	if ref >= uint64(fs.size) {
+		return nil, errors.WithDetailf(errBadRef, "ref %d, store size %d", ref, fs.size)
+	}
+	if int64(ref) >= fs.flushed {
+		err := fs.flush()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if int64(len(fs.mapped)) < fs.flushed {
+		err := fs.remap()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := fs.mapped[ref:fs.flushed]
+	n, k := binary.Uvarint(b)
+	if k <= 0 || n > uint64(len(b)-k) {
+		return nil, errors.WithDetailf(errBadRef, "ref %d", ref)
+	}
+	end := k + int(n)
+	return b[k:end:end], nil
+}
+
+// Size returns the number of bytes appended to fs.
+func (fs *FileStore) Size() int64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.size
+}
+
+// Close closes fs and frees its file.
+// Data returned by Load is no longer valid.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed {
+		return nil
+	}
+	fs.closed = true
+	runtime.SetFinalizer(fs, nil)
+
+	var firstErr error
+	for _, m := range append(fs.old, fs.mapped) {
+		if m == nil {
+			continue
+		}
+		err := munmap(m)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	fs.old, fs.mapped, fs.buf = nil, nil, nil
+	err := fs.f.Close()
+	if firstErr == nil {
+		firstErr = err
+	}
+	return errors.Wrap(firstErr)
+}
+
+func (fs *FileStore) flush() error {
+	if len(fs.buf) == 0 {
+		return nil
+	}
+	_, err := fs.f.WriteAt(fs.buf, fs.flushed)
+	if err != nil {
+		return errors.Wrap(err, "writing state tree file")
+	}
+	fs.flushed += int64(len(fs.buf))
+	fs.buf = fs.buf[:0]
+	return nil
+}
+
+// remap maps enough of the file to cover everything flushed,
+// with room to grow. Earlier mappings stay in place until fs is
+// closed, since data returned by Load may refer to them.
+func (fs *FileStore) remap() error {
+	size := 2 * fs.flushed
+	if size < minMapSize {
+		size = minMapSize
+	}
+	m, err := mmap(fs.f, int(size))
+	if err != nil {
+		return errors.Wrap(err, "mapping state tree file")
+	}
+	if fs.mapped != nil {
+		fs.old = append(fs.old, fs.mapped)
+	}
+	fs.mapped = m
+	return nil
+}
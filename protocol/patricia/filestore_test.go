@@ -0,0 +1,76 @@
+package patricia
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	// Enough data to flush the write buffer several times.
+	refs := make(map[uint64][]byte)
+	for i := 0; i < 3*fileStoreBufSize/100; i++ {
+		data := []byte(fmt.Sprintf("%0100d", i))
+		ref, err := fs.Append(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[ref] = data
+
+		// Load unflushed data sometimes too.
+		if i%10000 == 0 {
+			got, err := fs.Load(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("Load(%d) = %q, want %q", ref, got, data)
+			}
+		}
+	}
+	for ref, data := range refs {
+		got, err := fs.Load(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Load(%d) = %q, want %q", ref, got, data)
+		}
+	}
+
+	_, err = fs.Load(uint64(fs.Size()))
+	if err == nil {
+		t.Error("expected error loading past the end")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("store left %d files in its directory", len(files))
+	}
+
+	err = fs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fs.Load(0)
+	if err != errFileStoreClosed {
+		t.Errorf("Load after Close error = %v, want %s", err, errFileStoreClosed)
+	}
+}
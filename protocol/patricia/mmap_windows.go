@@ -0,0 +1,17 @@
+package patricia
+
+import (
+	"os"
+
+	"chain/errors"
+)
+
+var errNoMmap = errors.New("memory-mapped files are not supported on windows")
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+func munmap(b []byte) error {
+	return errNoMmap
+}
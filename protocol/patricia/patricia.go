@@ -17,6 +17,9 @@
 // which contains the root of the tree, to obtain a new tree
 // with the same contents. The time to make such a copy is
 // independent of the size of the tree.
+//
+// The lower levels of a tree can be moved out of memory
+// into a Store; see Evict.
 package patricia
 
 import (
@@ -32,6 +35,11 @@ var (
 	interiorPrefix = []byte{0x01}
 )
 
+// ErrLoad is the root of errors loading a node evicted from
+// a tree. Such an error means the tree's store failed, not
+// that the item looked for is absent.
+var ErrLoad = errors.New("cannot load evicted state tree node")
+
 // Tree implements a patricia tree.
 type Tree struct {
 	root *node
@@ -52,11 +60,15 @@ func Walk(t *Tree, walkFn WalkFunc) error {
 }
 
 func walk(n *node, walkFn WalkFunc) error {
+	n, err := load(n)
+	if err != nil {
+		return err
+	}
 	if n.isLeaf {
 		return walkFn(n.Key())
 	}
 
-	err := walk(n.children[0], walkFn)
+	err = walk(n.children[0], walkFn)
 	if err != nil {
 		return err
 	}
@@ -65,14 +77,18 @@ func walk(n *node, walkFn WalkFunc) error {
 	return err
 }
 
-// Contains returns whether t contains item.
-func (t *Tree) Contains(item []byte) bool {
+// Contains returns whether t contains item. It returns an
+// error if it can't load a node evicted from t.
+func (t *Tree) Contains(item []byte) (bool, error) {
 	if t.root == nil {
-		return false
+		return false, nil
 	}
 
 	key := bitKey(item)
-	n := lookup(t.root, key)
+	n, err := lookup(t.root, key)
+	if err != nil {
+		return false, err
+	}
 
 	var hash bc.Hash
 	h := sha3pool.Get256()
@@ -80,18 +96,22 @@ func (t *Tree) Contains(item []byte) bool {
 	h.Write(item)
 	hash.ReadFrom(h)
 	sha3pool.Put256(h)
-	return n != nil && n.Hash() == hash
+	return n != nil && n.Hash() == hash, nil
 }
 
-func lookup(n *node, key []uint8) *node {
+func lookup(n *node, key []uint8) (*node, error) {
+	n, err := load(n)
+	if err != nil {
+		return nil, err
+	}
 	if bytes.Equal(n.key, key) {
 		if !n.isLeaf {
-			return nil
+			return nil, nil
 		}
-		return n
+		return n, nil
 	}
 	if !bytes.HasPrefix(key, n.key) {
-		return nil
+		return nil, nil
 	}
 
 	bit := key[len(n.key)]
@@ -125,6 +145,12 @@ func (t *Tree) Insert(item []byte) error {
 }
 
 func insert(n *node, key []uint8, hash *bc.Hash) (*node, error) {
+	loaded, err := load(n)
+	if err != nil {
+		return n, err
+	}
+	n = loaded
+
 	if bytes.Equal(n.key, key) {
 		if !n.isLeaf {
 			return n, errors.Wrap(errors.New("key provided is a prefix to other keys"))
@@ -169,32 +195,50 @@ func insert(n *node, key []uint8, hash *bc.Hash) (*node, error) {
 	return newNode, nil
 }
 
-// Delete removes item from t, if present.
-func (t *Tree) Delete(item []byte) {
+// Delete removes item from t, if present. It returns an error,
+// leaving t unchanged, if it can't load a node evicted from t.
+func (t *Tree) Delete(item []byte) error {
 	key := bitKey(item)
 
-	if t.root != nil {
-		t.root = delete(t.root, key)
+	if t.root == nil {
+		return nil
+	}
+	root, err := delete(t.root, key)
+	if err != nil {
+		return err
 	}
+	t.root = root
+	return nil
 }
 
-func delete(n *node, key []uint8) *node {
+func delete(n *node, key []uint8) (*node, error) {
+	n, err := load(n)
+	if err != nil {
+		return nil, err
+	}
 	if bytes.Equal(key, n.key) {
 		if !n.isLeaf {
-			return n
+			return n, nil
 		}
-		return nil
+		return nil, nil
 	}
 
 	if !bytes.HasPrefix(key, n.key) {
-		return n
+		return n, nil
 	}
 
 	bit := key[len(n.key)]
-	newChild := delete(n.children[bit], key)
+	newChild, err := delete(n.children[bit], key)
+	if err != nil {
+		return nil, err
+	}
 
 	if newChild == nil {
-		return n.children[1-bit]
+		return n.children[1-bit], nil
+	}
+	newChild, err = load(newChild)
+	if err != nil {
+		return nil, err
 	}
 
 	newNode := new(node)
@@ -203,7 +247,7 @@ func delete(n *node, key []uint8) *node {
 	newNode.children[bit] = newChild
 	newNode.hash = nil
 
-	return newNode
+	return newNode, nil
 }
 
 // RootHash returns the Merkle root of the tree.
@@ -258,6 +302,11 @@ type node struct {
 	hash     *bc.Hash
 	isLeaf   bool
 	children [2]*node
+
+	// If src is set, the node has been evicted: only its
+	// hash is in memory, and the rest is in src at ref.
+	src Store
+	ref uint64
 }
 
 // Key returns the key for the current node as bytes, as it
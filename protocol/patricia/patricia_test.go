@@ -138,7 +138,10 @@ func TestLookup(t *testing.T) {
 	tr := &Tree{
 		root: &node{key: bools("11111111"), hash: hashPtr(hashForLeaf(bits("11111111"))), isLeaf: true},
 	}
-	got := lookup(tr.root, bitKey(bits("11111111")))
+	got, err := lookup(tr.root, bitKey(bits("11111111")))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !testutil.DeepEqual(got, tr.root) {
 		t.Log("lookup on 1-node tree")
 		t.Fatalf("got:\n%swant:\n%s", prettyNode(got, 0), prettyNode(tr.root, 0))
@@ -147,7 +150,10 @@ func TestLookup(t *testing.T) {
 	tr = &Tree{
 		root: &node{key: bools("11111110"), hash: hashPtr(hashForLeaf(bits("11111110"))), isLeaf: true},
 	}
-	got = lookup(tr.root, bitKey(bits("11111111")))
+	got, err = lookup(tr.root, bitKey(bits("11111111")))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if got != nil {
 		t.Log("lookup nonexistent key on 1-node tree")
 		t.Fatalf("got:\n%swant nil", prettyNode(got, 0))
@@ -163,7 +169,10 @@ func TestLookup(t *testing.T) {
 			},
 		},
 	}
-	got = lookup(tr.root, bitKey(bits("11110000")))
+	got, err = lookup(tr.root, bitKey(bits("11110000")))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !testutil.DeepEqual(got, tr.root.children[0]) {
 		t.Log("lookup root's first child")
 		t.Fatalf("got:\n%swant:\n%s", prettyNode(got, 0), prettyNode(tr.root.children[0], 0))
@@ -189,7 +198,10 @@ func TestLookup(t *testing.T) {
 			},
 		},
 	}
-	got = lookup(tr.root, bitKey(bits("11111100")))
+	got, err = lookup(tr.root, bitKey(bits("11111100")))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !testutil.DeepEqual(got, tr.root.children[1].children[0]) {
 		t.Fatalf("got:\n%swant:\n%s", prettyNode(got, 0), prettyNode(tr.root.children[1].children[0], 0))
 	}
@@ -200,14 +212,22 @@ func TestContains(t *testing.T) {
 	tr.Insert(bits("00000011"))
 	tr.Insert(bits("00000010"))
 
-	if v := bits("00000011"); !tr.Contains(v) {
-		t.Errorf("expected tree to contain %x, but did not", v)
-	}
-	if v := bits("00000000"); tr.Contains(v) {
-		t.Errorf("expected tree to not contain %x, but did", v)
+	cases := []struct {
+		item []byte
+		want bool
+	}{
+		{bits("00000011"), true},
+		{bits("00000000"), false},
+		{bits("00000010"), true},
 	}
-	if v := bits("00000010"); !tr.Contains(v) {
-		t.Errorf("expected tree to contain %x, but did not", v)
+	for _, c := range cases {
+		got, err := tr.Contains(c.item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("Contains(%x) = %v, want %v", c.item, got, c.want)
+		}
 	}
 }
 
@@ -482,7 +502,10 @@ func TestDeletePrefix(t *testing.T) {
 		},
 	}
 
-	got := delete(root, bools("111111"))
+	got, err := delete(root, bools("111111"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	got.calcHash()
 	if !testutil.DeepEqual(got, root) {
 		t.Fatalf("got:\n%swant:\n%s", prettyNode(got, 0), prettyNode(root, 0))
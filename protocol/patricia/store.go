@@ -0,0 +1,207 @@
+package patricia
+
+import (
+	"encoding/binary"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+var errBadNode = errors.New("malformed stored node")
+
+// A Store holds nodes evicted from trees. See Evict.
+//
+// Stored nodes are immutable, and any number of trees may
+// refer to them. A Store must be safe for concurrent use.
+type Store interface {
+	// Append stores data and returns a reference
+	// for retrieving it with Load.
+	Append(data []byte) (ref uint64, err error)
+
+	// Load returns the data stored at ref.
+	// Callers must not modify it.
+	Load(ref uint64) ([]byte, error)
+}
+
+// Evict moves the nodes of t that are depth or more levels
+// below the root into s, leaving only their hashes in memory.
+// Nodes already in s stay where they are; nodes evicted to a
+// different store are copied into s.
+//
+// Evicted nodes are loaded from s as needed, and are not
+// kept in memory afterward, except for those along the path
+// to an item inserted or deleted in t. Calling Evict again
+// moves those to s as well.
+//
+// Evict affects only t, not copies of it, which continue to
+// refer to their own nodes.
+func (t *Tree) Evict(s Store, depth int) error {
+	if t.root == nil {
+		return nil
+	}
+	root, err := evict(t.root, s, depth)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// evict returns n, with its descendants depth levels below it
+// and further stored in s. It copies only nodes that change.
+func evict(n *node, s Store, depth int) (*node, error) {
+	if depth <= 0 {
+		return storeNode(n, s)
+	}
+	if n.src == s {
+		return n, nil
+	}
+	n, err := load(n)
+	if err != nil {
+		return nil, err
+	}
+	if n.isLeaf {
+		return n, nil
+	}
+
+	var children [2]*node
+	for i, c := range n.children {
+		children[i], err = evict(c, s, depth-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if children == n.children {
+		return n, nil
+	}
+	newNode := new(node)
+	*newNode = *n
+	newNode.children = children
+	return newNode, nil
+}
+
+// storeNode writes n and its descendants to s, children first,
+// and returns an evicted node in its place.
+func storeNode(n *node, s Store) (*node, error) {
+	if n.src == s {
+		return n, nil
+	}
+	n, err := load(n)
+	if err != nil {
+		return nil, err
+	}
+	n.calcHash()
+
+	var children [2]*node
+	if !n.isLeaf {
+		for i, c := range n.children {
+			children[i], err = storeNode(c, s)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	ref, err := s.Append(encodeNode(n, children))
+	if err != nil {
+		return nil, errors.Wrap(err, "storing node")
+	}
+	return &node{hash: n.hash, src: s, ref: ref}, nil
+}
+
+// load returns n, reading it from its store if it was evicted.
+// The returned node's children remain evicted.
+func load(n *node) (*node, error) {
+	if n.src == nil {
+		return n, nil
+	}
+	data, err := n.src.Load(n.ref)
+	if err != nil {
+		return nil, errors.Wrapf(errors.Sub(ErrLoad, err), "loading node %x", n.hash.Bytes())
+	}
+	loaded, err := decodeNode(data, n.src)
+	if err != nil {
+		return nil, errors.Wrapf(errors.Sub(ErrLoad, err), "decoding node %x", n.hash.Bytes())
+	}
+	loaded.hash = n.hash
+	return loaded, nil
+}
+
+// encodeNode encodes n as a leaf flag, the length of its key in
+// bits, the key packed eight bits to a byte, and, if n is not a
+// leaf, the hash and reference of each of its stored children.
+func encodeNode(n *node, children [2]*node) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	data := make([]byte, 0, 1+binary.MaxVarintLen64+(len(n.key)+7)/8+2*(32+binary.MaxVarintLen64))
+
+	var leaf byte
+	if n.isLeaf {
+		leaf = 1
+	}
+	data = append(data, leaf)
+	data = append(data, buf[:binary.PutUvarint(buf[:], uint64(len(n.key)))]...)
+	data = append(data, packBits(n.key)...)
+	if !n.isLeaf {
+		for _, c := range children {
+			data = append(data, c.hash.Bytes()...)
+			data = append(data, buf[:binary.PutUvarint(buf[:], c.ref)]...)
+		}
+	}
+	return data
+}
+
+func decodeNode(data []byte, src Store) (*node, error) {
+	if len(data) < 1 || data[0] > 1 {
+		return nil, errBadNode
+	}
+	n := &node{isLeaf: data[0] == 1}
+	data = data[1:]
+
+	keyLen, k := binary.Uvarint(data)
+	if k <= 0 || keyLen > uint64(len(data)-k)*8 {
+		return nil, errBadNode
+	}
+	data = data[k:]
+	packed := (int(keyLen) + 7) / 8
+	n.key = unpackBits(data[:packed], int(keyLen))
+	data = data[packed:]
+
+	if !n.isLeaf {
+		for i := range n.children {
+			if len(data) < 32 {
+				return nil, errBadNode
+			}
+			var b32 [32]byte
+			copy(b32[:], data)
+			hash := bc.NewHash(b32)
+			ref, k := binary.Uvarint(data[32:])
+			if k <= 0 {
+				return nil, errBadNode
+			}
+			n.children[i] = &node{hash: &hash, src: src, ref: ref}
+			data = data[32+k:]
+		}
+	}
+	if len(data) > 0 {
+		return nil, errBadNode
+	}
+	return n, nil
+}
+
+// packBits is like byteKey, but allows any number of bits,
+// padding the last byte with zeros.
+func packBits(bits []uint8) []byte {
+	b := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		b[i/8] |= bit << (7 - uint(i%8))
+	}
+	return b
+}
+
+// unpackBits is the inverse of packBits.
+func unpackBits(b []byte, n int) []uint8 {
+	bits := make([]uint8, n)
+	for i := range bits {
+		bits[i] = (b[i/8] >> (7 - uint(i%8))) & 1
+	}
+	return bits
+}
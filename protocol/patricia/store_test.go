@@ -0,0 +1,185 @@
+package patricia
+
+import (
+	"bytes"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"chain/errors"
+)
+
+type memStore struct {
+	mu    sync.Mutex
+	nodes [][]byte
+	fail  bool
+}
+
+func (s *memStore) Append(data []byte) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = append(s.nodes, append([]byte(nil), data...))
+	return uint64(len(s.nodes) - 1), nil
+}
+
+func (s *memStore) Load(ref uint64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return nil, errors.New("load failed")
+	}
+	return s.nodes[ref], nil
+}
+
+func randomItems(r *rand.Rand, n int) [][]byte {
+	items := make([][]byte, n)
+	for i := range items {
+		items[i] = make([]byte, 32)
+		r.Read(items[i])
+	}
+	return items
+}
+
+func TestEvict(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	items := randomItems(r, 500)
+
+	var mem, evicted Tree
+	for _, item := range items {
+		mustInsert(t, &mem, item)
+		mustInsert(t, &evicted, item)
+	}
+	before := evicted
+	s := new(memStore)
+
+	for _, depth := range []int{0, 3, 8} {
+		err := evicted.Evict(s, depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evicted.RootHash() != mem.RootHash() {
+			t.Fatalf("depth %d: root hash changed by Evict", depth)
+		}
+
+		// Change the trees the same way, then evict again
+		// at the next depth.
+		for _, item := range items[:50] {
+			mustDelete(t, &mem, item)
+			mustDelete(t, &evicted, item)
+		}
+		for _, item := range randomItems(r, 50) {
+			mustInsert(t, &mem, item)
+			mustInsert(t, &evicted, item)
+		}
+		items = items[50:]
+		if evicted.RootHash() != mem.RootHash() {
+			t.Fatalf("depth %d: root hashes differ after changes", depth)
+		}
+	}
+	if len(s.nodes) == 0 {
+		t.Fatal("nothing was evicted")
+	}
+
+	for _, item := range items {
+		ok, err := evicted.Contains(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("evicted tree does not contain %x", item)
+		}
+	}
+	ok, err := evicted.Contains(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("evicted tree contains item never inserted")
+	}
+
+	var memItems, evictedItems [][]byte
+	Walk(&mem, func(item []byte) error { memItems = append(memItems, item); return nil })
+	Walk(&evicted, func(item []byte) error { evictedItems = append(evictedItems, item); return nil })
+	if len(memItems) != len(evictedItems) {
+		t.Fatalf("walked %d items of evicted tree, want %d", len(evictedItems), len(memItems))
+	}
+	for i := range memItems {
+		if !bytes.Equal(memItems[i], evictedItems[i]) {
+			t.Fatalf("item %d = %x, want %x", i, evictedItems[i], memItems[i])
+		}
+	}
+
+	// Copies made before evicting keep their own nodes.
+	if before.root.src != nil || before.root.children[0].src != nil {
+		t.Error("Evict changed a copy of the tree")
+	}
+
+	// Evicting into a new store copies everything into it,
+	// leaving the old store unused.
+	s2 := new(memStore)
+	err = evicted.Evict(s2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.fail = true
+	for _, item := range items {
+		ok, err := evicted.Contains(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("tree does not contain %x after moving stores", item)
+		}
+	}
+	if evicted.RootHash() != mem.RootHash() {
+		t.Fatal("root hash changed moving stores")
+	}
+
+	// Load errors are returned to the caller.
+	s2.fail = true
+	_, err = evicted.Contains(items[0])
+	if errors.Root(err) != ErrLoad {
+		t.Errorf("Contains error = %v, want %v", err, ErrLoad)
+	}
+	err = evicted.Delete(items[0])
+	if errors.Root(err) != ErrLoad {
+		t.Errorf("Delete error = %v, want %v", err, ErrLoad)
+	}
+}
+
+func TestNodeEncoding(t *testing.T) {
+	s := new(memStore)
+	leaf := &node{key: bools("1011"), isLeaf: true, hash: hashPtr(hashForLeaf(bits("1011")))}
+	stored, err := storeNode(leaf, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := load(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.key, leaf.key) || !got.isLeaf || *got.hash != *leaf.hash {
+		t.Errorf("got %s, want %s", prettyNode(got, 0), prettyNode(leaf, 0))
+	}
+
+	for _, data := range [][]byte{nil, {2}, {1, 9, 0xff}, {0, 0}, {1, 0, 0}} {
+		_, err := decodeNode(data, s)
+		if err != errBadNode {
+			t.Errorf("decodeNode(%x) error = %v, want %s", data, err, errBadNode)
+		}
+	}
+}
+
+func mustInsert(t *testing.T, tr *Tree, item []byte) {
+	err := tr.Insert(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustDelete(t *testing.T, tr *Tree, item []byte) {
+	err := tr.Delete(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
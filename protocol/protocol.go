@@ -89,6 +89,7 @@ import (
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 )
 
 // maxCachedValidatedTxs is the max number of validated txs to cache.
@@ -124,6 +125,19 @@ type Store interface {
 type Chain struct {
 	InitialBlockHash  bc.Hash
 	MaxIssuanceWindow time.Duration // only used by generators
+	BlockVersion      uint64        // only used by generators; blocks never go below the previous version
+	Costs             *vm.CostTable // nil means vm.DefaultCostTable
+
+	// DiskStore, if set, holds most of the state tree on
+	// disk instead of in memory. The state tree of each
+	// committed block is moved to it.
+	DiskStore *state.DiskStore
+
+	// OnCommit, if set, is called with each block this
+	// process commits, after the block is stored and before
+	// c's height advances to it. It may be called more than
+	// once for the same block.
+	OnCommit func(context.Context, *legacy.Block)
 
 	state struct {
 		cond     sync.Cond // protects height, block, snapshot
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"sync"
+
+	"chain/errors"
+	"chain/protocol/patricia"
+)
+
+const (
+	// residentDepth is the number of levels of a state tree
+	// kept in memory by a DiskStore. It keeps up to 2^16
+	// interior nodes resident, and only the few levels below
+	// them are read from disk to look up an output.
+	residentDepth = 16
+
+	// A DiskStore compacts its file when it has grown
+	// to compactRatio times its size after the last
+	// compaction, and is at least compactMinSize.
+	compactRatio   = 4
+	compactMinSize = 1 << 30
+)
+
+// A DiskStore moves most of the state tree of snapshots into
+// memory-mapped files, so that nodes whose state tree is larger
+// than memory can still validate blocks, at the cost of reading
+// from disk to look up outputs.
+//
+// The files are temporary, and the tree is loaded into them from
+// a snapshot again when a node starts. A DiskStore is safe for
+// concurrent use.
+type DiskStore struct {
+	dir string
+
+	mu   sync.Mutex
+	cur  *patricia.FileStore
+	base int64 // size of cur after its first eviction
+}
+
+// NewDiskStore returns a DiskStore that
+// keeps its files in the directory dir.
+func NewDiskStore(dir string) *DiskStore {
+	return &DiskStore{dir: dir}
+}
+
+// Evict moves all but the top levels of tree to disk.
+// It affects tree only, not copies of it.
+//
+// Nodes already on disk are not written again, but nodes
+// replaced by changes to the tree remain in the file. Once
+// the file has grown enough, Evict starts a new one, copying
+// the tree into it. The old file is freed once no tree refers
+// to it.
+func (d *DiskStore) Evict(tree *patricia.Tree) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	compact := d.cur == nil || d.cur.Size() > compactRatio*d.base && d.cur.Size() > compactMinSize
+	if compact {
+		fs, err := patricia.NewFileStore(d.dir)
+		if err != nil {
+			return errors.Wrap(err, "creating state tree file")
+		}
+		d.cur = fs
+	}
+	err := tree.Evict(d.cur, residentDepth)
+	if err != nil {
+		return errors.Wrap(err, "evicting state tree")
+	}
+	if compact {
+		d.base = d.cur.Size()
+	}
+	return nil
+}
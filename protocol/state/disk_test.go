@@ -0,0 +1,80 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestDiskStoreSpend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds := NewDiskStore(dir)
+
+	assetID := bc.AssetID{}
+	var (
+		snap    = Empty()
+		mem     = Empty()
+		spends  []*bc.Tx
+		spentID bc.Hash
+	)
+	for i := 0; i < 1000; i++ {
+		sourceID := bc.NewHash([32]byte{byte(i), byte(i >> 8)})
+		sc := legacy.SpendCommitment{
+			AssetAmount: bc.AssetAmount{AssetId: &assetID, Amount: 100},
+			SourceID:    sourceID,
+			VMVersion:   1,
+		}
+		outputID, err := legacy.ComputeOutputID(&sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap.Tree.Insert(outputID.Bytes())
+		mem.Tree.Insert(outputID.Bytes())
+		if i%100 == 0 {
+			spentID = outputID
+			spends = append(spends, legacy.MapTx(&legacy.TxData{
+				Version: 1,
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, sourceID, assetID, 100, 0, nil, bc.Hash{}, nil),
+				},
+			}))
+		}
+	}
+
+	err = ds.Evict(snap.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range spends {
+		err = snap.ApplyTx(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mem.ApplyTx(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok, err := snap.Tree.Contains(spentID.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("snapshot contains spent output")
+	}
+	if snap.Tree.RootHash() != mem.Tree.RootHash() {
+		t.Error("root hash of evicted snapshot differs from in-memory snapshot")
+	}
+
+	err = snap.ApplyTx(spends[0])
+	if err == nil {
+		t.Error("expected error applying spend twice")
+	}
+}
@@ -92,10 +92,17 @@ func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
 
 	// Remove spent outputs. Each output must be present.
 	for _, prevout := range tx.SpentOutputIDs {
-		if !s.Tree.Contains(prevout.Bytes()) {
+		ok, err := s.Tree.Contains(prevout.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "reading state tree")
+		}
+		if !ok {
 			return fmt.Errorf("invalid prevout %x", prevout.Bytes())
 		}
-		s.Tree.Delete(prevout.Bytes())
+		err = s.Tree.Delete(prevout.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "removing spent outputs")
+		}
 	}
 
 	// Add new outputs. They must not yet be present.
@@ -42,7 +42,11 @@ func TestApplyTxSpend(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if snap.Tree.Contains(spentOutputID.Bytes()) {
+	ok, err := snap.Tree.Contains(spentOutputID.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
 		t.Error("snapshot contains spent prevout")
 	}
 	err = snap.ApplyTx(tx)
@@ -48,7 +48,7 @@ func newDest(t testing.TB) *testDest {
 }
 
 func (d *testDest) sign(t testing.TB, tx *legacy.Tx, index uint32) {
-	txsighash := tx.SigHash(index)
+	txsighash := tx.SigHash(index, bc.Hash{})
 	prog, _ := vm.Assemble(fmt.Sprintf("0x%x TXSIGHASH EQUAL", txsighash.Bytes()))
 	h := sha3.Sum256(prog)
 	sig := ed25519.Sign(d.privKey, h[:])
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -48,6 +49,34 @@ func TestValidateBlock2Err(t *testing.T) {
 	}
 }
 
+func TestValidateBlockDoubleSpend(t *testing.T) {
+	b1 := newInitialBlock(t)
+	b2 := generate(t, b1)
+	spend := func(refdata string) *bc.Tx {
+		return legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.NewHash([32]byte{1}), bc.AssetID{}, 10, 0, nil, bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(bc.AssetID{}, 10, []byte{1}, nil),
+			},
+			ReferenceData: []byte(refdata),
+		}).Tx
+	}
+	b2.Transactions = []*bc.Tx{spend("a"), spend("b")}
+	root, err := bc.MerkleRoot(b2.Transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2.TransactionsRoot = &root
+
+	err = ValidateBlock(b2, b1, b1.ID, dummyValidateTx)
+	if errors.Root(err) != errDoubleSpend {
+		t.Errorf("ValidateBlock with a double spend = %v, want %s", err, errDoubleSpend)
+	}
+}
+
 func TestValidateBlockSig2(t *testing.T) {
 	b1 := newInitialBlock(t)
 	b2 := generate(t, b1)
@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+var errMismatchedFeatures = errors.New("mismatched transaction features")
+
+// txFeatures maps each feature bit known to this node to the
+// extra rules a transaction opting in to it must satisfy. A
+// soft fork introduces a feature by adding an entry here; the
+// hook runs after the rest of the transaction has been checked.
+//
+// Bits with no entry are accepted without further checks, so
+// that nodes that predate a feature still accept transactions
+// that use it.
+var txFeatures = map[uint]func(vs *validationState) error{}
+
+// checkTxFeatures checks the feature bits of a transaction of
+// version bc.TxFeatureVersion or later: that the header commits
+// to them, and that the transaction satisfies the rules of each
+// known feature it sets.
+func checkTxFeatures(vs *validationState, hdr *bc.TxHeader) error {
+	if hdr.Version < bc.TxFeatureVersion {
+		return nil
+	}
+	want := vs.tx.Features.Hash()
+	if hdr.ExtHash == nil || *hdr.ExtHash != want {
+		return errors.WithDetailf(errMismatchedFeatures, "features %#x", uint64(vs.tx.Features))
+	}
+	for bit := uint(0); bit < 64; bit++ {
+		check, ok := txFeatures[bit]
+		if !ok || !vs.tx.Features.Has(bit) {
+			continue
+		}
+		err := check(vs)
+		if err != nil {
+			return errors.Wrapf(err, "checking feature %d", bit)
+		}
+	}
+	return nil
+}
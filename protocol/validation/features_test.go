@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestTxFeatures(t *testing.T) {
+	const bit = 5
+	var hookRan bool
+	errHook := errors.New("hook failed")
+	txFeatures[bit] = func(vs *validationState) error {
+		hookRan = true
+		if len(vs.tx.ResultIds) > 1 {
+			return errHook
+		}
+		return nil
+	}
+	defer delete(txFeatures, bit)
+
+	cases := []struct {
+		desc     string
+		features bc.TxFeatures
+		f        func(*bc.Tx)
+		err      error
+		hookRan  bool
+	}{
+		{
+			desc: "no features",
+		},
+		{
+			desc:     "unknown feature",
+			features: 1 << 9,
+		},
+		{
+			desc:     "known feature",
+			features: 1<<bit | 1<<9,
+			err:      errHook,
+			hookRan:  true,
+		},
+		{
+			desc:     "features not committed",
+			features: 1 << 9,
+			f: func(tx *bc.Tx) {
+				tx.Features = 0
+			},
+			err: errMismatchedFeatures,
+		},
+		{
+			desc: "missing ext hash",
+			f: func(tx *bc.Tx) {
+				tx.ExtHash = nil
+			},
+			err: errMismatchedFeatures,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			hookRan = false
+			fixture := sample(t, &txFixture{txVersion: bc.TxFeatureVersion})
+			fixture.tx.Features = c.features
+			tx := legacy.NewTx(*fixture.tx).Tx
+			if c.f != nil {
+				c.f(tx)
+			}
+			vs := &validationState{
+				blockchainID: fixture.initialBlockID,
+				tx:           tx,
+				entryID:      tx.ID,
+				cache:        make(map[bc.Hash]error),
+			}
+			err := checkValid(vs, tx.TxHeader)
+			if rootErr(err) != c.err {
+				t.Errorf("got error %v, want %v", err, c.err)
+			}
+			if hookRan != c.hookRan {
+				t.Errorf("hook ran = %t, want %t", hookRan, c.hookRan)
+			}
+		})
+	}
+}
@@ -25,5 +25,5 @@ func TestFuzzAssetIdNilPointer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ValidateTx(tx.Tx, testBlockchainID)
+	ValidateTx(tx.Tx, testBlockchainID, nil)
 }
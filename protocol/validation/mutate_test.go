@@ -0,0 +1,256 @@
+package validation
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
+)
+
+// A blockMutator alters a valid block so that it becomes invalid.
+// It may consult r to choose what to alter. After the mutation,
+// the harness recomputes the block's transaction merkle root if
+// recommit is set, and signs the block again if resign is set,
+// so that the mutation gets past those checks to the ones behind
+// them.
+type blockMutator struct {
+	name     string
+	mutate   func(r *rand.Rand, b *legacy.Block)
+	recommit bool
+	resign   bool
+
+	// want lists the acceptable root errors. Validation must
+	// reject the mutated block with one of them.
+	want []error
+}
+
+var blockMutators = []blockMutator{{
+	name: "flip witness byte",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		flipBit(r, b.Witness[0])
+	},
+	want: []error{vm.ErrFalseVMResult, vm.ErrVerifyFailed},
+}, {
+	name: "drop witness",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.Witness = nil
+	},
+	want: []error{vm.ErrDataStackUnderflow, vm.ErrFalseVMResult, vm.ErrVerifyFailed},
+}, {
+	name: "tweak signed header",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.TimestampMS += uint64(r.Intn(1000)) + 1
+	},
+	want: []error{vm.ErrFalseVMResult, vm.ErrVerifyFailed},
+}, {
+	name: "tweak merkle root",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.TransactionsMerkleRoot = flipHashBit(r, b.TransactionsMerkleRoot)
+	},
+	resign: true,
+	want:   []error{errMismatchedMerkleRoot},
+}, {
+	name: "tweak previous block hash",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.PreviousBlockHash = flipHashBit(r, b.PreviousBlockHash)
+	},
+	resign: true,
+	want:   []error{errMismatchedBlock},
+}, {
+	name: "tweak height",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.Height += uint64(r.Intn(10)) + 1
+	},
+	resign: true,
+	want:   []error{errMisorderedBlockHeight},
+}, {
+	name: "rewind timestamp",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		b.TimestampMS -= uint64(r.Intn(1000)) + 1
+	},
+	resign: true,
+	want:   []error{errMisorderedBlockTime},
+}, {
+	name: "permute outputs",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		tx := b.Transactions[r.Intn(len(b.Transactions))]
+		outs := tx.Outputs
+		outs[0], outs[len(outs)-1] = outs[len(outs)-1], outs[0]
+		*tx = *legacy.NewTx(tx.TxData)
+	},
+	resign: true,
+	want:   []error{errMismatchedMerkleRoot},
+}, {
+	name: "inflate output",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		tx := b.Transactions[r.Intn(len(b.Transactions))]
+		out := tx.Outputs[r.Intn(len(tx.Outputs))]
+		out.Amount += uint64(r.Intn(100)) + 1
+		*tx = *legacy.NewTx(tx.TxData)
+	},
+	recommit: true,
+	resign:   true,
+	want:     []error{errUnbalanced},
+}, {
+	name: "tweak issuance argument",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		tx := b.Transactions[r.Intn(len(b.Transactions))]
+		iss := tx.Inputs[0].TypedInput.(*legacy.IssuanceInput)
+		iss.Arguments[0] = []byte{byte(r.Intn(255)) + 3}
+		*tx = *legacy.NewTx(tx.TxData)
+	},
+	recommit: true,
+	resign:   true,
+	want:     []error{vm.ErrFalseVMResult},
+}, {
+	name: "shrink time range",
+	mutate: func(r *rand.Rand, b *legacy.Block) {
+		tx := b.Transactions[r.Intn(len(b.Transactions))]
+		tx.MaxTime = b.TimestampMS - uint64(r.Intn(1000)) - 1
+		*tx = *legacy.NewTx(tx.TxData)
+	},
+	recommit: true,
+	resign:   true,
+	want:     []error{errUntimelyTransaction},
+}}
+
+// TestBlockMutations applies each of blockMutators to a valid block,
+// several times with different random choices, and checks that
+// validation rejects the result for the expected reason. It guards
+// against consensus checks that quietly stop checking anything.
+func TestBlockMutations(t *testing.T) {
+	iterations := 50
+	if testing.Short() {
+		iterations = 5
+	}
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+	r := rand.New(rand.NewSource(seed))
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{pub}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := sample(t, nil)
+	prev := legacy.MapBlock(&legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:     1,
+			Height:      1,
+			TimestampMS: bc.Millis(time.Now()) - 1000,
+			BlockCommitment: legacy.BlockCommitment{
+				ConsensusProgram: prog,
+			},
+		},
+	})
+
+	validate := func(b *legacy.Block) error {
+		bcBlock := legacy.MapBlock(b)
+		err := ValidateBlockSig(bcBlock, prev.NextConsensusProgram)
+		if err != nil {
+			return err
+		}
+		return ValidateBlock(bcBlock, prev, fixture.initialBlockID, func(tx *bc.Tx) error {
+			return ValidateTx(tx, fixture.initialBlockID, nil)
+		})
+	}
+
+	newBlock := func() *legacy.Block {
+		// Copy the fixture's inputs and outputs so mutations
+		// don't leak from one block to the next.
+		fx := sample(t, &txFixture{
+			txMinTime: fixture.txMinTime,
+			txMaxTime: fixture.txMaxTime,
+		})
+		b := &legacy.Block{
+			BlockHeader: legacy.BlockHeader{
+				Version:           1,
+				Height:            2,
+				PreviousBlockHash: prev.ID,
+				TimestampMS:       prev.TimestampMs + 1,
+				BlockCommitment: legacy.BlockCommitment{
+					ConsensusProgram: prog,
+				},
+			},
+			Transactions: []*legacy.Tx{legacy.NewTx(*fx.tx)},
+		}
+		commit(t, b)
+		sign(b, priv)
+		return b
+	}
+
+	err = validate(newBlock())
+	if err != nil {
+		t.Fatalf("unmutated block: %v", err)
+	}
+
+	for _, m := range blockMutators {
+		for i := 0; i < iterations; i++ {
+			b := newBlock()
+			m.mutate(r, b)
+			if m.recommit {
+				commit(t, b)
+			}
+			if m.resign {
+				sign(b, priv)
+			}
+			err := validate(b)
+			if err == nil {
+				t.Errorf("%s (iteration %d): validation accepted mutated block", m.name, i)
+				break
+			}
+			if !isOneOf(rootErr(err), m.want) {
+				t.Errorf("%s (iteration %d): got error %v, want one of %v", m.name, i, err, m.want)
+				break
+			}
+		}
+	}
+}
+
+func commit(tb testing.TB, b *legacy.Block) {
+	var txs []*bc.Tx
+	for _, tx := range b.Transactions {
+		txs = append(txs, tx.Tx)
+	}
+	root, err := bc.MerkleRoot(txs)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	b.TransactionsMerkleRoot = root
+}
+
+func sign(b *legacy.Block, priv ed25519.PrivateKey) {
+	h := b.Hash()
+	b.Witness = [][]byte{ed25519.Sign(priv, h.Bytes())}
+}
+
+func flipBit(r *rand.Rand, b []byte) {
+	i := r.Intn(len(b) * 8)
+	b[i/8] ^= 1 << uint(i%8)
+}
+
+func flipHashBit(r *rand.Rand, h bc.Hash) bc.Hash {
+	b := h.Bytes()
+	flipBit(r, b)
+	var b32 [32]byte
+	copy(b32[:], b)
+	return bc.NewHash(b32)
+}
+
+func isOneOf(err error, errs []error) bool {
+	for _, e := range errs {
+		if err == e {
+			return true
+		}
+	}
+	return false
+}
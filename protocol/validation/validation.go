@@ -15,6 +15,9 @@ type validationState struct {
 	// The ID of the blockchain
 	blockchainID bc.Hash
 
+	// The opcode costs in effect on the blockchain
+	costs *vm.CostTable
+
 	// The enclosing transaction object
 	tx *bc.Tx
 
@@ -33,6 +36,7 @@ type validationState struct {
 
 var (
 	errBadTimeRange          = errors.New("bad time range")
+	errDoubleSpend           = errors.New("output spent twice")
 	errEmptyResults          = errors.New("transaction has no results")
 	errMismatchedAssetID     = errors.New("mismatched asset id")
 	errMismatchedBlock       = errors.New("mismatched block")
@@ -50,6 +54,7 @@ var (
 	errPosition              = errors.New("invalid source or destination position")
 	errTxVersion             = errors.New("invalid transaction version")
 	errUnbalanced            = errors.New("unbalanced")
+	errUnknownInput          = errors.New("input of unknown type")
 	errUntimelyTransaction   = errors.New("block timestamp outside transaction time range")
 	errVersionRegression     = errors.New("version regression")
 	errWrongBlockchain       = errors.New("wrong blockchain")
@@ -97,8 +102,13 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			}
 		}
 
+		err = checkTxFeatures(vs, e)
+		if err != nil {
+			return err
+		}
+
 	case *bc.Mux:
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
+		err = vs.verify(e, e.Program, e.WitnessArguments)
 		if err != nil {
 			return errors.Wrap(err, "checking mux program")
 		}
@@ -153,7 +163,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		}
 
 	case *bc.Nonce:
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
+		err = vs.verify(e, e.Program, e.WitnessArguments)
 		if err != nil {
 			return errors.Wrap(err, "checking nonce program")
 		}
@@ -226,7 +236,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			return errors.Wrapf(bc.ErrMissingEntry, "entry for issuance anchor %x not found", e.AnchorId.Bytes())
 		}
 
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments))
+		err = vs.verify(e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments)
 		if err != nil {
 			return errors.Wrap(err, "checking issuance program")
 		}
@@ -276,7 +286,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		if err != nil {
 			return errors.Wrap(err, "getting spend prevout")
 		}
-		err = vm.Verify(NewTxVMContext(vs.tx, e, spentOutput.ControlProgram, e.WitnessArguments))
+		err = vs.verify(e, spentOutput.ControlProgram, e.WitnessArguments)
 		if err != nil {
 			return errors.Wrap(err, "checking control program")
 		}
@@ -307,6 +317,28 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			return errNonemptyExtHash
 		}
 
+	case *bc.UnknownInput:
+		// An input of a type this software doesn't understand
+		// can only appear in a newer version of transaction,
+		// whose rules are for newer software to enforce. Only
+		// its place in the transaction's value flow is checked.
+		// Its value can't be known, so it must be given as zero.
+		// Then the mux balances the known inputs against the
+		// outputs, and the input can't create value.
+		if vs.tx.Version == 1 {
+			return errors.WithDetailf(errUnknownInput, "input type %d", e.Type)
+		}
+
+		vs2 := *vs
+		vs2.destPos = 0
+		err = checkValidDest(&vs2, e.WitnessDestination)
+		if err != nil {
+			return errors.Wrap(err, "checking unknown input destination")
+		}
+		if e.WitnessDestination.Value.Amount != 0 {
+			return errors.WithDetailf(errUnknownInput, "input type %d has nonzero amount %d", e.Type, e.WitnessDestination.Value.Amount)
+		}
+
 	default:
 		return fmt.Errorf("entry has unexpected type %T", e)
 	}
@@ -357,6 +389,12 @@ func checkValidSrc(vstate *validationState, vs *bc.ValueSource) error {
 		}
 		dest = ref.WitnessDestination
 
+	case *bc.UnknownInput:
+		if vs.Position != 0 {
+			return errors.Wrapf(errPosition, "invalid position %d for unknown input source", vs.Position)
+		}
+		dest = ref.WitnessDestination
+
 	case *bc.Mux:
 		if vs.Position >= uint64(len(ref.WitnessDestinations)) {
 			return errors.Wrapf(errPosition, "invalid position %d for %d-destination mux source", vs.Position, len(ref.WitnessDestinations))
@@ -364,7 +402,7 @@ func checkValidSrc(vstate *validationState, vs *bc.ValueSource) error {
 		dest = ref.WitnessDestinations[vs.Position]
 
 	default:
-		return errors.Wrapf(bc.ErrEntryType, "value source is %T, should be issuance, spend, or mux", e)
+		return errors.Wrapf(bc.ErrEntryType, "value source is %T, should be issuance, spend, unknown input, or mux", e)
 	}
 
 	if dest.Ref == nil || *dest.Ref != vstate.entryID {
@@ -454,22 +492,24 @@ func ValidateBlockSig(b *bc.Block, prog []byte) error {
 // ValidateBlock validates a block and the transactions within.
 // It does not run the consensus program; for that, see ValidateBlockSig.
 func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*bc.Tx) error) error {
-	if b.Height > 1 {
-		if prev == nil {
-			return errors.WithDetailf(errNoPrevBlock, "height %d", b.Height)
-		}
-		err := validateBlockAgainstPrev(b, prev)
-		if err != nil {
-			return err
-		}
+	if b.Height > 1 && prev == nil {
+		return errors.WithDetailf(errNoPrevBlock, "height %d", b.Height)
 	}
-
-	err := checkValidBlockHeader(b.BlockHeader)
+	if b.Height <= 1 {
+		prev = nil
+	}
+	err := ValidateBlockHeader(b, prev)
 	if err != nil {
-		return errors.Wrap(err, "checking block header")
+		return err
 	}
 
+	spent := bc.NewOutpointSet()
 	for i, tx := range b.Transactions {
+		for _, id := range tx.SpentOutputIDs {
+			if !spent.Add(id) {
+				return errors.WithDetailf(errDoubleSpend, "transaction %d spends output %x, already spent in this block", i, id.Bytes())
+			}
+		}
 		if b.Version == 1 && tx.Version != 1 {
 			return errors.WithDetailf(errTxVersion, "block version %d, transaction version %d", b.Version, tx.Version)
 		}
@@ -498,6 +538,23 @@ func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*b
 	return nil
 }
 
+// ValidateBlockHeader validates the header of b against
+// the header of prev, the block before it. If prev is nil,
+// it checks the header of b on its own. It checks neither
+// the transactions in b nor the consensus program;
+// for those, see ValidateBlock and ValidateBlockSig.
+func ValidateBlockHeader(b, prev *bc.Block) error {
+	if prev != nil {
+		err := validateBlockAgainstPrev(b, prev)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := checkValidBlockHeader(b.BlockHeader)
+	return errors.Wrap(err, "checking block header")
+}
+
 func validateBlockAgainstPrev(b, prev *bc.Block) error {
 	if b.Version < prev.Version {
 		return errors.WithDetailf(errVersionRegression, "previous block verson %d, current block version %d", prev.Version, b.Version)
@@ -514,10 +571,20 @@ func validateBlockAgainstPrev(b, prev *bc.Block) error {
 	return nil
 }
 
-// ValidateTx validates a transaction.
-func ValidateTx(tx *bc.Tx, initialBlockID bc.Hash) error {
+// verify runs prog with args in the context of entry e,
+// using the blockchain's opcode costs.
+func (vs *validationState) verify(e bc.Entry, prog *bc.Program, args [][]byte) error {
+	context := NewTxVMContext(vs.tx, vs.blockchainID, e, prog, args)
+	context.Costs = vs.costs
+	return vm.Verify(context)
+}
+
+// ValidateTx validates a transaction. A nil costs means
+// vm.DefaultCostTable.
+func ValidateTx(tx *bc.Tx, initialBlockID bc.Hash, costs *vm.CostTable) error {
 	vs := &validationState{
 		blockchainID: initialBlockID,
+		costs:        costs,
 		tx:           tx,
 		entryID:      tx.ID,
 
@@ -368,12 +368,94 @@ func TestNoncelessIssuance(t *testing.T) {
 		tx.Inputs[0].TypedInput.(*legacy.IssuanceInput).Nonce = nil
 	})
 
-	err := ValidateTx(legacy.MapTx(&tx.TxData), bc.EmptyStringHash)
+	err := ValidateTx(legacy.MapTx(&tx.TxData), bc.EmptyStringHash, nil)
 	if errors.Root(err) != bc.ErrMissingEntry {
 		t.Fatalf("got %s, want %s", err, bc.ErrMissingEntry)
 	}
 }
 
+func TestMultipleIssuances(t *testing.T) {
+	fixture := sample(t, nil)
+
+	// Issue two assets in one transaction. Only one issuance
+	// may go without a nonce, anchored by the spend.
+	assetDef2 := []byte{3}
+	assetDefHash2 := hashData(assetDef2)
+	assetID2 := bc.ComputeAssetID(fixture.issuanceProg.Code, &fixture.initialBlockID, fixture.issuanceProg.VmVersion, &assetDefHash2)
+	spendProg, err := vm.Assemble("ADD 9 NUMEQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(nonce []byte, amount uint64, assetDef []byte) *legacy.TxInput {
+		return legacy.NewIssuanceInput(nonce, amount, nil, fixture.initialBlockID, fixture.issuanceProg.Code, fixture.issuanceArgs, assetDef)
+	}
+	fixture.txInputs = []*legacy.TxInput{
+		issue(nil, 10, fixture.assetDef),
+		issue([]byte{1}, 5, assetDef2),
+		issue([]byte{3}, 7, assetDef2),
+		issue([]byte{2}, 3, fixture.assetDef),
+		legacy.NewSpendInput([][]byte{{4}, {5}}, *newHash(5), fixture.assetID, 20, 0, spendProg, *newHash(6), nil),
+	}
+	fixture.txOutputs = []*legacy.TxOutput{
+		legacy.NewTxOutput(fixture.assetID, 33, []byte{byte(vm.OP_TRUE)}, nil),
+		legacy.NewTxOutput(assetID2, 12, []byte{byte(vm.OP_TRUE)}, nil),
+	}
+	fixture = sample(t, fixture)
+
+	err = ValidateTx(legacy.MapTx(fixture.tx), fixture.initialBlockID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnknownInput(t *testing.T) {
+	unknown := &legacy.TxInput{
+		AssetVersion:     1,
+		TypedInput:       &legacy.UnknownInput{Type: 5},
+		CommitmentSuffix: []byte{1, 2, 3},
+	}
+	// An output of 5 units of an asset no known input has.
+	created := legacy.NewTxOutput(*newAssetID(255), 5, []byte{byte(vm.OP_TRUE)}, nil)
+
+	cases := []struct {
+		desc    string
+		version uint64
+		extra   *legacy.TxOutput
+		f       func(*bc.Tx)
+		err     error
+	}{
+		{desc: "version 1", version: 1, err: errUnknownInput},
+		{desc: "version 2", version: 2},
+		{desc: "creating value", version: 2, extra: created, err: errNoSource},
+		{
+			desc:    "nonzero value",
+			version: 2,
+			f: func(tx *bc.Tx) {
+				ui := tx.Entries[tx.InputIDs[3]].(*bc.UnknownInput)
+				ui.WitnessDestination.Value.Amount = 5
+			},
+			err: errUnknownInput,
+		},
+	}
+	for _, c := range cases {
+		fixture := sample(t, nil)
+		fixture.txVersion = c.version
+		fixture.txInputs = append(fixture.txInputs, unknown)
+		if c.extra != nil {
+			fixture.txOutputs = append(fixture.txOutputs, c.extra)
+		}
+		fixture = sample(t, fixture)
+		tx := legacy.MapTx(fixture.tx)
+		if c.f != nil {
+			c.f(tx)
+		}
+		err := ValidateTx(tx, fixture.initialBlockID, nil)
+		if rootErr(err) != c.err {
+			t.Errorf("%s: got error %s, want %v", c.desc, err, c.err)
+		}
+	}
+}
+
 func TestBlockHeaderValid(t *testing.T) {
 	base := bc.NewBlockHeader(1, 1, &bc.Hash{}, 1, &bc.Hash{}, &bc.Hash{}, nil)
 	baseBytes, _ := proto.Marshal(base)
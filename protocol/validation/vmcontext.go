@@ -22,7 +22,7 @@ func newBlockVMContext(block *bc.Block, prog []byte, args [][]byte) *vm.Context
 	}
 }
 
-func NewTxVMContext(tx *bc.Tx, entry bc.Entry, prog *bc.Program, args [][]byte) *vm.Context {
+func NewTxVMContext(tx *bc.Tx, initialBlockID bc.Hash, entry bc.Entry, prog *bc.Program, args [][]byte) *vm.Context {
 	var (
 		numResults = uint64(len(tx.ResultIds))
 		txData     = tx.Data.Bytes()
@@ -83,6 +83,9 @@ func NewTxVMContext(tx *bc.Tx, entry bc.Entry, prog *bc.Program, args [][]byte)
 
 			entryID.WriteTo(hasher)
 			tx.ID.WriteTo(hasher)
+			if tx.Version >= bc.TxReplayProtectionVersion {
+				initialBlockID.WriteTo(hasher)
+			}
 
 			var hash bc.Hash
 			hash.ReadFrom(hasher)
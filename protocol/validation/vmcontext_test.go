@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -110,3 +111,30 @@ func mustDecodeHex(h string) []byte {
 	}
 	return bits
 }
+
+func TestTxSigHashReplayProtection(t *testing.T) {
+	chainA, chainB := bc.Hash{V0: 1}, bc.Hash{V0: 2}
+	for _, version := range []uint64{1, bc.TxReplayProtectionVersion} {
+		tx := legacy.NewTx(legacy.TxData{
+			Version: version,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.Hash{}, bc.NewAssetID([32]byte{1}), 5, 1, []byte("spendprog"), bc.Hash{}, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(bc.NewAssetID([32]byte{1}), 5, []byte("controlprog"), nil),
+			},
+		}).Tx
+		spend := tx.Entries[tx.InputIDs[0]]
+		prog := &bc.Program{VmVersion: 1}
+
+		hashA := NewTxVMContext(tx, chainA, spend, prog, nil).TxSigHash()
+		hashB := NewTxVMContext(tx, chainB, spend, prog, nil).TxSigHash()
+		if want := tx.SigHash(0, chainA); !bytes.Equal(hashA, want.Bytes()) {
+			t.Errorf("version %d: TXSIGHASH = %x, want %x", version, hashA, want.Bytes())
+		}
+		separated := !bytes.Equal(hashA, hashB)
+		if wantSeparated := version >= bc.TxReplayProtectionVersion; separated != wantSeparated {
+			t.Errorf("version %d: sighashes differ across chains = %v, want %v", version, separated, wantSeparated)
+		}
+	}
+}
@@ -3,10 +3,6 @@ package vm
 import "bytes"
 
 func opInvert(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	top, err := vm.top()
 	if err != nil {
 		return err
@@ -26,10 +22,6 @@ func opInvert(vm *virtualMachine) error {
 }
 
 func opAnd(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -62,10 +54,6 @@ func opXor(vm *virtualMachine) error {
 }
 
 func doOr(vm *virtualMachine, xor bool) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -126,10 +114,6 @@ func opEqualVerify(vm *virtualMachine) error {
 }
 
 func doEqual(vm *virtualMachine) (bool, error) {
-	err := vm.applyCost(1)
-	if err != nil {
-		return false, err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return false, err
@@ -20,6 +20,9 @@ type Context struct {
 	// (such as spends and issuances).
 	TxVersion *uint64
 
+	// Costs, if non-nil, re-prices opcodes for this blockchain.
+	Costs *CostTable
+
 	// These fields must be present when verifying block headers.
 
 	BlockHash            *[]byte
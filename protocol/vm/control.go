@@ -5,10 +5,6 @@ import (
 )
 
 func opVerify(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	p, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -20,19 +16,11 @@ func opVerify(vm *virtualMachine) error {
 }
 
 func opFail(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	return ErrReturn
 }
 
 func opCheckPredicate(vm *virtualMachine) error {
-	err := vm.applyCost(256)
-	if err != nil {
-		return err
-	}
-	vm.deferCost(-256 + 64) // get most of that cost back at the end
+	vm.deferCost(-256 + 64) // get most of the base cost back at the end
 	limit, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -79,20 +67,12 @@ func opCheckPredicate(vm *virtualMachine) error {
 }
 
 func opJump(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	address := binary.LittleEndian.Uint32(vm.data)
 	vm.nextPC = address
 	return nil
 }
 
 func opJumpIf(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	p, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -203,7 +203,7 @@ func TestControlOps(t *testing.T) {
 	}
 
 	for i, c := range cases {
-		err := ops[c.op].fn(c.startVM)
+		err := c.startVM.execute(c.op)
 
 		if err != c.wantErr {
 			t.Errorf("case %d, op %s: got err = %v want %v", i, c.op.String(), err, c.wantErr)
@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"chain/crypto/sha3pool"
+)
+
+// A CostTable re-prices opcodes for a particular blockchain.
+//
+// Each opcode has a fixed base cost, charged before it executes,
+// plus in some cases a cost that depends on its operands (e.g. the
+// length of the data hashed by SHA3, or the number of public keys
+// checked by CHECKMULTISIG). Costs replaces the base cost of the
+// given opcodes; operand-dependent costs are unaffected.
+//
+// All nodes on a blockchain must use the same table, or they will
+// disagree about which programs succeed. A nil *CostTable means
+// DefaultCostTable.
+type CostTable struct {
+	Version uint64
+	Costs   map[Op]int64
+}
+
+// DefaultCostTable is the cost table built into this version of
+// the VM.
+var DefaultCostTable = &CostTable{Version: 1}
+
+var baseCosts [256]int64
+
+func init() {
+	for i := range baseCosts {
+		baseCosts[i] = 1
+	}
+	for op, cost := range map[Op]int64{
+		OP_CHECKPREDICATE: 256,
+
+		OP_TOALTSTACK:   2,
+		OP_FROMALTSTACK: 2,
+		OP_2DROP:        2,
+		OP_2DUP:         2,
+		OP_3DUP:         3,
+		OP_2OVER:        2,
+		OP_2ROT:         2,
+		OP_2SWAP:        2,
+		OP_PICK:         2,
+		OP_ROLL:         2,
+		OP_ROT:          2,
+
+		OP_CAT:         4,
+		OP_SUBSTR:      4,
+		OP_LEFT:        4,
+		OP_RIGHT:       4,
+		OP_CATPUSHDATA: 4,
+
+		OP_1ADD:               2,
+		OP_1SUB:               2,
+		OP_2MUL:               2,
+		OP_2DIV:               2,
+		OP_NEGATE:             2,
+		OP_ABS:                2,
+		OP_NOT:                2,
+		OP_0NOTEQUAL:          2,
+		OP_ADD:                2,
+		OP_SUB:                2,
+		OP_MUL:                8,
+		OP_DIV:                8,
+		OP_MOD:                8,
+		OP_LSHIFT:             8,
+		OP_RSHIFT:             8,
+		OP_BOOLAND:            2,
+		OP_BOOLOR:             2,
+		OP_NUMEQUAL:           2,
+		OP_NUMEQUALVERIFY:     2,
+		OP_NUMNOTEQUAL:        2,
+		OP_LESSTHAN:           2,
+		OP_GREATERTHAN:        2,
+		OP_LESSTHANOREQUAL:    2,
+		OP_GREATERTHANOREQUAL: 2,
+		OP_MIN:                2,
+		OP_MAX:                2,
+		OP_WITHIN:             4,
+
+		OP_SHA256:        0,
+		OP_SHA3:          0,
+		OP_CHECKSIG:      1024,
+		OP_CHECKMULTISIG: 0,
+		OP_TXSIGHASH:     256,
+
+		OP_CHECKOUTPUT: 16,
+	} {
+		baseCosts[op] = cost
+	}
+}
+
+// Cost returns the base cost of op under t.
+func (t *CostTable) Cost(op Op) int64 {
+	if t != nil {
+		if cost, ok := t.Costs[op]; ok {
+			return cost
+		}
+	}
+	return baseCosts[op]
+}
+
+// Validate checks that every cost in t is at least 1, or for
+// the opcodes priced by their operands alone, at least 0. A
+// free opcode such as JUMP would let a program run forever.
+func (t *CostTable) Validate() error {
+	for op, cost := range t.Costs {
+		min := int64(1)
+		if baseCosts[op] == 0 {
+			min = 0
+		}
+		if cost < min {
+			return fmt.Errorf("cost %d for opcode %s is less than the minimum %d", cost, op, min)
+		}
+	}
+	return nil
+}
+
+// Hash returns a commitment to the version and effective
+// costs of t. Tables that price every opcode the same have
+// the same hash.
+func (t *CostTable) Hash() (hash [32]byte) {
+	if t == nil {
+		t = DefaultCostTable
+	}
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], t.Version)])
+	for i := range baseCosts {
+		op := Op(i)
+		cost := t.Cost(op)
+		if cost == baseCosts[op] {
+			continue
+		}
+		h.Write([]byte{byte(op)})
+		h.Write(buf[:binary.PutVarint(buf[:], cost)])
+	}
+	h.Read(hash[:])
+	return hash
+}
+
+// baseCost returns the base cost of op under vm's cost table.
+func (vm *virtualMachine) baseCost(op Op) int64 {
+	if vm.context == nil {
+		return baseCosts[op]
+	}
+	return vm.context.Costs.Cost(op)
+}
+
+// execute charges the base cost of op and then runs it. The
+// opcode implementations charge only their operand-dependent
+// costs, so the base cost is charged once, from the table.
+func (vm *virtualMachine) execute(op Op) error {
+	err := vm.applyCost(vm.baseCost(op))
+	if err != nil {
+		return err
+	}
+	return ops[op].fn(vm)
+}
@@ -0,0 +1,173 @@
+package vm
+
+import "testing"
+
+func TestCostTable(t *testing.T) {
+	run := func(costs *CostTable, op Op) int64 {
+		vm := &virtualMachine{
+			program:  []byte{byte(op)},
+			runLimit: 50000,
+			context:  &Context{Costs: costs},
+		}
+		err := vm.step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return 50000 - vm.runLimit
+	}
+
+	repriced := &CostTable{
+		Version: 2,
+		Costs: map[Op]int64{
+			OP_TRUE: 10,
+			0xd0:    5, // expansion opcode
+		},
+	}
+	for _, op := range []Op{OP_TRUE, 0xd0, OP_DEPTH} {
+		got := run(repriced, op) - run(nil, op)
+		want := repriced.Cost(op) - DefaultCostTable.Cost(op)
+		if got != want {
+			t.Errorf("%s: repriced cost differs from default by %d, want %d", op, got, want)
+		}
+	}
+
+	// A cheaper table lets an opcode run with less than
+	// its default cost left, so the default is never charged.
+	cheap := &CostTable{Version: 2, Costs: map[Op]int64{OP_TXSIGHASH: 10}}
+	vm := &virtualMachine{
+		program:  []byte{byte(OP_TXSIGHASH)},
+		runLimit: 100,
+		context: &Context{
+			Costs:     cheap,
+			TxSigHash: func() []byte { return make([]byte, 32) },
+		},
+	}
+	err := vm.step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(100 - 10 - (8 + 32)); vm.runLimit != want {
+		t.Errorf("repriced TXSIGHASH left run limit %d, want %d", vm.runLimit, want)
+	}
+
+	if repriced.Hash() == DefaultCostTable.Hash() {
+		t.Error("repriced table has same hash as default table")
+	}
+	var nilTable *CostTable
+	if nilTable.Hash() != DefaultCostTable.Hash() {
+		t.Error("nil table has different hash from default table")
+	}
+	noop := &CostTable{Version: 1, Costs: map[Op]int64{OP_CHECKSIG: 1024}}
+	if noop.Hash() != DefaultCostTable.Hash() {
+		t.Error("table repeating default costs has different hash from default table")
+	}
+
+	for _, costs := range []map[Op]int64{
+		{OP_CHECKSIG: -1},
+		{OP_JUMP: 0},
+		{OP_JUMPIF: 0},
+		{OP_SHA3: -1},
+	} {
+		bad := &CostTable{Costs: costs}
+		if bad.Validate() == nil {
+			t.Errorf("expected error validating %v", costs)
+		}
+	}
+	free := &CostTable{Costs: map[Op]int64{OP_SHA3: 0, OP_JUMP: 1}}
+	if err := free.Validate(); err != nil {
+		t.Errorf("validating %v: %s", free.Costs, err)
+	}
+}
+
+// TestDefaultCostTable checks the default base costs against
+// the costs the VM charged before cost tables existed.
+func TestDefaultCostTable(t *testing.T) {
+	baseline := map[Op]int64{
+		OP_CHECKPREDICATE: 256,
+
+		OP_TOALTSTACK:   2,
+		OP_FROMALTSTACK: 2,
+		OP_2DROP:        2,
+		OP_2DUP:         2,
+		OP_3DUP:         3,
+		OP_2OVER:        2,
+		OP_2ROT:         2,
+		OP_2SWAP:        2,
+		OP_PICK:         2,
+		OP_ROLL:         2,
+		OP_ROT:          2,
+
+		OP_CAT:         4,
+		OP_SUBSTR:      4,
+		OP_LEFT:        4,
+		OP_RIGHT:       4,
+		OP_CATPUSHDATA: 4,
+
+		OP_1ADD:               2,
+		OP_1SUB:               2,
+		OP_2MUL:               2,
+		OP_2DIV:               2,
+		OP_NEGATE:             2,
+		OP_ABS:                2,
+		OP_NOT:                2,
+		OP_0NOTEQUAL:          2,
+		OP_ADD:                2,
+		OP_SUB:                2,
+		OP_MUL:                8,
+		OP_DIV:                8,
+		OP_MOD:                8,
+		OP_LSHIFT:             8,
+		OP_RSHIFT:             8,
+		OP_BOOLAND:            2,
+		OP_BOOLOR:             2,
+		OP_NUMEQUAL:           2,
+		OP_NUMEQUALVERIFY:     2,
+		OP_NUMNOTEQUAL:        2,
+		OP_LESSTHAN:           2,
+		OP_GREATERTHAN:        2,
+		OP_LESSTHANOREQUAL:    2,
+		OP_GREATERTHANOREQUAL: 2,
+		OP_MIN:                2,
+		OP_MAX:                2,
+		OP_WITHIN:             4,
+
+		// Priced entirely by their operands.
+		OP_SHA256:        0,
+		OP_SHA3:          0,
+		OP_CHECKMULTISIG: 0,
+
+		OP_CHECKSIG:    1024,
+		OP_TXSIGHASH:   256,
+		OP_CHECKOUTPUT: 16,
+	}
+	for i := 0; i < 256; i++ {
+		op := Op(i)
+		want, ok := baseline[op]
+		if !ok {
+			want = 1
+		}
+		if got := DefaultCostTable.Cost(op); got != want {
+			t.Errorf("%s: default cost %d, want %d", op, got, want)
+		}
+	}
+}
+
+func TestDefaultCostTableRunLimit(t *testing.T) {
+	// 2 3 ADD 5 NUMEQUAL
+	//   2, 3, 5:  base 1, push 9                      = 10 each
+	//   ADD:      base 2, pop 2 and 3 -18, push 5 9   = -7
+	//   NUMEQUAL: base 2, pop 5 and 5 -18, push 1 9   = -7
+	prog := []byte{byte(OP_2), byte(OP_3), byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)}
+	vm := &virtualMachine{
+		program:  prog,
+		runLimit: 1000,
+		context:  &Context{},
+	}
+	err := vm.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(1000 - 16); vm.runLimit != want {
+		t.Errorf("run limit %d after run, want %d", vm.runLimit, want)
+	}
+}
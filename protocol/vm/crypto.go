@@ -40,10 +40,6 @@ func doHash(vm *virtualMachine, hashFactory func() hash.Hash) error {
 }
 
 func opCheckSig(vm *virtualMachine) error {
-	err := vm.applyCost(1024)
-	if err != nil {
-		return err
-	}
 	pubkeyBytes, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -127,10 +123,6 @@ func opCheckMultiSig(vm *virtualMachine) error {
 }
 
 func opTxSigHash(vm *virtualMachine) error {
-	err := vm.applyCost(256)
-	if err != nil {
-		return err
-	}
 	if vm.context.TxSigHash == nil {
 		return ErrContext
 	}
@@ -138,10 +130,6 @@ func opTxSigHash(vm *virtualMachine) error {
 }
 
 func opBlockHash(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	if vm.context.BlockHash == nil {
 		return ErrContext
 	}
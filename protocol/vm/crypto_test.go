@@ -439,7 +439,7 @@ func TestCryptoOps(t *testing.T) {
 	for i, c := range cases {
 		t.Logf("case %d", i)
 
-		err := ops[c.op].fn(c.startVM)
+		err := c.startVM.execute(c.op)
 		gotVM := c.startVM
 
 		if err != c.wantErr {
@@ -3,10 +3,6 @@ package vm
 import "math"
 
 func opCheckOutput(vm *virtualMachine) error {
-	err := vm.applyCost(16)
-	if err != nil {
-		return err
-	}
 
 	code, err := vm.pop(true)
 	if err != nil {
@@ -54,10 +50,6 @@ func opCheckOutput(vm *virtualMachine) error {
 }
 
 func opAsset(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.AssetID == nil {
 		return ErrContext
@@ -66,10 +58,6 @@ func opAsset(vm *virtualMachine) error {
 }
 
 func opAmount(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.Amount == nil {
 		return ErrContext
@@ -78,19 +66,11 @@ func opAmount(vm *virtualMachine) error {
 }
 
 func opProgram(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	return vm.push(vm.context.Code, true)
 }
 
 func opMinTime(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.MinTimeMS == nil {
 		return ErrContext
@@ -99,10 +79,6 @@ func opMinTime(vm *virtualMachine) error {
 }
 
 func opMaxTime(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.MaxTimeMS == nil {
 		return ErrContext
@@ -116,10 +92,6 @@ func opMaxTime(vm *virtualMachine) error {
 }
 
 func opEntryData(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.EntryData == nil {
 		return ErrContext
@@ -129,10 +101,6 @@ func opEntryData(vm *virtualMachine) error {
 }
 
 func opTxData(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.TxData == nil {
 		return ErrContext
@@ -141,10 +109,6 @@ func opTxData(vm *virtualMachine) error {
 }
 
 func opIndex(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.DestPos == nil {
 		return ErrContext
@@ -153,18 +117,10 @@ func opIndex(vm *virtualMachine) error {
 }
 
 func opEntryID(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	return vm.push(vm.context.EntryID, true)
 }
 
 func opOutputID(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.SpentOutputID == nil {
 		return ErrContext
@@ -173,10 +129,6 @@ func opOutputID(vm *virtualMachine) error {
 }
 
 func opNonce(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.AnchorID == nil {
 		return ErrContext
@@ -185,10 +137,6 @@ func opNonce(vm *virtualMachine) error {
 }
 
 func opNextProgram(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.NextConsensusProgram == nil {
 		return ErrContext
@@ -197,10 +145,6 @@ func opNextProgram(vm *virtualMachine) error {
 }
 
 func opBlockTime(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 
 	if vm.context.BlockTimeMS == nil {
 		return ErrContext
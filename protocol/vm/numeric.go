@@ -7,10 +7,6 @@ import (
 )
 
 func op1Add(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -23,10 +19,6 @@ func op1Add(vm *virtualMachine) error {
 }
 
 func op1Sub(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -39,10 +31,6 @@ func op1Sub(vm *virtualMachine) error {
 }
 
 func op2Mul(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -55,10 +43,6 @@ func op2Mul(vm *virtualMachine) error {
 }
 
 func op2Div(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -67,10 +51,6 @@ func op2Div(vm *virtualMachine) error {
 }
 
 func opNegate(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -83,10 +63,6 @@ func opNegate(vm *virtualMachine) error {
 }
 
 func opAbs(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -101,10 +77,6 @@ func opAbs(vm *virtualMachine) error {
 }
 
 func opNot(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -113,10 +85,6 @@ func opNot(vm *virtualMachine) error {
 }
 
 func op0NotEqual(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -125,10 +93,6 @@ func op0NotEqual(vm *virtualMachine) error {
 }
 
 func opAdd(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -145,10 +109,6 @@ func opAdd(vm *virtualMachine) error {
 }
 
 func opSub(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -165,10 +125,6 @@ func opSub(vm *virtualMachine) error {
 }
 
 func opMul(vm *virtualMachine) error {
-	err := vm.applyCost(8)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -185,10 +141,6 @@ func opMul(vm *virtualMachine) error {
 }
 
 func opDiv(vm *virtualMachine) error {
-	err := vm.applyCost(8)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -208,10 +160,6 @@ func opDiv(vm *virtualMachine) error {
 }
 
 func opMod(vm *virtualMachine) error {
-	err := vm.applyCost(8)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -239,10 +187,6 @@ func opMod(vm *virtualMachine) error {
 }
 
 func opLshift(vm *virtualMachine) error {
-	err := vm.applyCost(8)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -267,10 +211,6 @@ func opLshift(vm *virtualMachine) error {
 }
 
 func opRshift(vm *virtualMachine) error {
-	err := vm.applyCost(8)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -286,10 +226,6 @@ func opRshift(vm *virtualMachine) error {
 }
 
 func opBoolAnd(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -302,10 +238,6 @@ func opBoolAnd(vm *virtualMachine) error {
 }
 
 func opBoolOr(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -331,10 +263,6 @@ func opNumEqual(vm *virtualMachine) error {
 }
 
 func opNumEqualVerify(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -370,10 +298,6 @@ func opGreaterThanOrEqual(vm *virtualMachine) error {
 }
 
 func doNumCompare(vm *virtualMachine, op int) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -401,10 +325,6 @@ func doNumCompare(vm *virtualMachine, op int) error {
 }
 
 func opMin(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -420,10 +340,6 @@ func opMin(vm *virtualMachine) error {
 }
 
 func opMax(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	y, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -439,10 +355,6 @@ func opMax(vm *virtualMachine) error {
 }
 
 func opWithin(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	max, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -3,33 +3,21 @@ package vm
 import "encoding/binary"
 
 func opFalse(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	return vm.pushBool(false, false)
 }
 
 func opPushdata(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	d := make([]byte, len(vm.data))
 	copy(d, vm.data)
 	return vm.push(d, false)
 }
 
 func op1Negate(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	return vm.pushInt64(-1, false)
 }
 
 func opNop(vm *virtualMachine) error {
-	return vm.applyCost(1)
+	return nil
 }
 
 func PushdataBytes(in []byte) []byte {
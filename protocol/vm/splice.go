@@ -3,10 +3,6 @@ package vm
 import "chain/math/checked"
 
 func opCat(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
@@ -29,10 +25,6 @@ func opCat(vm *virtualMachine) error {
 }
 
 func opSubstr(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	size, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -68,10 +60,6 @@ func opSubstr(vm *virtualMachine) error {
 }
 
 func opLeft(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	size, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -99,10 +87,6 @@ func opLeft(vm *virtualMachine) error {
 }
 
 func opRight(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	size, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -131,10 +115,6 @@ func opRight(vm *virtualMachine) error {
 }
 
 func opSize(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	str, err := vm.top()
 	if err != nil {
 		return err
@@ -147,10 +127,6 @@ func opSize(vm *virtualMachine) error {
 }
 
 func opCatpushdata(vm *virtualMachine) error {
-	err := vm.applyCost(4)
-	if err != nil {
-		return err
-	}
 	b, err := vm.pop(true)
 	if err != nil {
 		return err
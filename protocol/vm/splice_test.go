@@ -175,7 +175,7 @@ func TestSpliceOps(t *testing.T) {
 	}
 
 	for i, c := range cases {
-		err := ops[c.op].fn(c.startVM)
+		err := c.startVM.execute(c.op)
 
 		if err != c.wantErr {
 			t.Errorf("case %d, op %s: got err = %v want %v", i, ops[c.op].name, err, c.wantErr)
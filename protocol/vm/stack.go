@@ -3,10 +3,6 @@ package vm
 import "chain/math/checked"
 
 func opToAltStack(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) == 0 {
 		return ErrDataStackUnderflow
 	}
@@ -17,10 +13,6 @@ func opToAltStack(vm *virtualMachine) error {
 }
 
 func opFromAltStack(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	if len(vm.altStack) == 0 {
 		return ErrAltStackUnderflow
 	}
@@ -31,12 +23,8 @@ func opFromAltStack(vm *virtualMachine) error {
 }
 
 func op2Drop(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	for i := 0; i < 2; i++ {
-		_, err = vm.pop(false)
+		_, err := vm.pop(false)
 		if err != nil {
 			return err
 		}
@@ -53,15 +41,11 @@ func op3Dup(vm *virtualMachine) error {
 }
 
 func nDup(vm *virtualMachine, n int) error {
-	err := vm.applyCost(int64(n))
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < n {
 		return ErrDataStackUnderflow
 	}
 	for i := 0; i < n; i++ {
-		err = vm.push(vm.dataStack[len(vm.dataStack)-n], false)
+		err := vm.push(vm.dataStack[len(vm.dataStack)-n], false)
 		if err != nil {
 			return err
 		}
@@ -70,15 +54,11 @@ func nDup(vm *virtualMachine, n int) error {
 }
 
 func op2Over(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < 4 {
 		return ErrDataStackUnderflow
 	}
 	for i := 0; i < 2; i++ {
-		err = vm.push(vm.dataStack[len(vm.dataStack)-4], false)
+		err := vm.push(vm.dataStack[len(vm.dataStack)-4], false)
 		if err != nil {
 			return err
 		}
@@ -87,10 +67,6 @@ func op2Over(vm *virtualMachine) error {
 }
 
 func op2Rot(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < 6 {
 		return ErrDataStackUnderflow
 	}
@@ -104,10 +80,6 @@ func op2Rot(vm *virtualMachine) error {
 }
 
 func op2Swap(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < 4 {
 		return ErrDataStackUnderflow
 	}
@@ -121,10 +93,6 @@ func op2Swap(vm *virtualMachine) error {
 }
 
 func opIfDup(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	item, err := vm.top()
 	if err != nil {
 		return err
@@ -139,11 +107,7 @@ func opIfDup(vm *virtualMachine) error {
 }
 
 func opDepth(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
-	err = vm.pushInt64(int64(len(vm.dataStack)), false)
+	err := vm.pushInt64(int64(len(vm.dataStack)), false)
 	if err != nil {
 		return err
 	}
@@ -151,11 +115,7 @@ func opDepth(vm *virtualMachine) error {
 }
 
 func opDrop(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
-	_, err = vm.pop(false)
+	_, err := vm.pop(false)
 	if err != nil {
 		return err
 	}
@@ -167,10 +127,6 @@ func opDup(vm *virtualMachine) error {
 }
 
 func opNip(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	top, err := vm.top()
 	if err != nil {
 		return err
@@ -187,14 +143,10 @@ func opNip(vm *virtualMachine) error {
 }
 
 func opOver(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < 2 {
 		return ErrDataStackUnderflow
 	}
-	err = vm.push(vm.dataStack[len(vm.dataStack)-2], false)
+	err := vm.push(vm.dataStack[len(vm.dataStack)-2], false)
 	if err != nil {
 		return err
 	}
@@ -202,10 +154,6 @@ func opOver(vm *virtualMachine) error {
 }
 
 func opPick(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(false)
 	if err != nil {
 		return err
@@ -228,10 +176,6 @@ func opPick(vm *virtualMachine) error {
 }
 
 func opRoll(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
 	n, err := vm.popInt64(false)
 	if err != nil {
 		return err
@@ -251,11 +195,7 @@ func opRoll(vm *virtualMachine) error {
 }
 
 func opRot(vm *virtualMachine) error {
-	err := vm.applyCost(2)
-	if err != nil {
-		return err
-	}
-	err = rot(vm, 3)
+	err := rot(vm, 3)
 	if err != nil {
 		return err
 	}
@@ -279,10 +219,6 @@ func rot(vm *virtualMachine, n int64) error {
 }
 
 func opSwap(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	l := len(vm.dataStack)
 	if l < 2 {
 		return ErrDataStackUnderflow
@@ -292,10 +228,6 @@ func opSwap(vm *virtualMachine) error {
 }
 
 func opTuck(vm *virtualMachine) error {
-	err := vm.applyCost(1)
-	if err != nil {
-		return err
-	}
 	if len(vm.dataStack) < 2 {
 		return ErrDataStackUnderflow
 	}
@@ -303,7 +235,7 @@ func opTuck(vm *virtualMachine) error {
 	copy(top2, vm.dataStack[len(vm.dataStack)-2:])
 	// temporarily remove the top two items without standard memory accounting
 	vm.dataStack = vm.dataStack[:len(vm.dataStack)-2]
-	err = vm.push(top2[1], false)
+	err := vm.push(top2[1], false)
 	if err != nil {
 		return err
 	}
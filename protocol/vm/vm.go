@@ -116,12 +116,12 @@ func (vm *virtualMachine) step() error {
 			return ErrDisallowedOpcode
 		}
 		vm.pc = vm.nextPC
-		return vm.applyCost(1)
+		return vm.applyCost(vm.baseCost(inst.Op))
 	}
 
 	vm.deferredCost = 0
 	vm.data = inst.Data
-	err = ops[inst.Op].fn(vm)
+	err = vm.execute(inst.Op)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,236 @@
+package vmutil
+
+import (
+	"bytes"
+	"math"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/vm"
+)
+
+// ErrVaultLimit is returned by Vault.After when a withdrawal
+// would take more out of a vault than its limit allows.
+var ErrVaultLimit = errors.New("vault spending limit exceeded")
+
+// Vault describes a rate-limited vault: an output whose control
+// program lets at most Limit units of its value leave in each
+// period of PeriodMS milliseconds, and requires the rest to be
+// returned to the vault, under a program with the same keys,
+// limit, and period. Spending it also needs Quorum signatures
+// from the keys in PubKeys, as for P2SPMultiSigProgram.
+//
+// A period starts at a transaction's mintime, which may be up
+// to PeriodMS before the time of the block that includes it, so
+// the limit holds for periods of transaction time, not of block
+// time: whoever gets hold of the keys can take up to 2*Limit
+// in a short time, by backdating one withdrawal a full period,
+// and no more than Limit per period after that.
+//
+// WindowStartMS and Spent are the vault's state: the time the
+// current period started, and how much has left the vault in
+// it. A new vault has a zero state, and its first withdrawal
+// starts a new period.
+type Vault struct {
+	PubKeys  []ed25519.PublicKey
+	Quorum   int
+	Limit    uint64
+	PeriodMS uint64
+
+	WindowStartMS uint64
+	Spent         uint64
+}
+
+// VaultProgram returns the control program of v.
+//
+// A transaction spending the vault must have a mintime, which it
+// takes as the current time, and a maxtime no more than PeriodMS
+// after that. When the mintime is PeriodMS or more after the
+// start of the current period, a new period starts at the mintime
+// (see Vault about backdated withdrawals).
+// The witness arguments are the index of an output returning
+// value to the vault, the amount it returns, and then those of
+// a signature witness as for P2SPMultiSigProgram:
+//
+//	INDEX AMOUNT N SIG... PREDICATE
+//
+// The returned output must be of the vault's asset, with the
+// spent output's ID as its reference data, and controlled by the
+// vault's program with the next state: the amount it returns
+// less than the spent amount counts toward the period's limit.
+// Returning more, adding value from other inputs, tops the vault
+// up. Emptying the vault leaves an output of zero.
+func VaultProgram(v *Vault) ([]byte, error) {
+	err := checkMultiSigParams(int64(v.Quorum), int64(len(v.PubKeys)))
+	if err != nil {
+		return nil, err
+	}
+	if v.PeriodMS == 0 || v.PeriodMS > math.MaxInt64 || v.Limit > math.MaxInt64 {
+		return nil, errors.WithDetail(ErrBadValue, "vault limit or period out of range")
+	}
+	if v.WindowStartMS > math.MaxInt64-v.PeriodMS || v.Spent > v.Limit {
+		return nil, errors.WithDetail(ErrBadValue, "vault state out of range")
+	}
+	multisig, err := P2SPMultiSigProgram(v.PubKeys, v.Quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	// The depth, below the state, of the witness argument
+	// with the amount returned to the vault.
+	amountDepth := int64(v.Quorum) + 4
+
+	builder := NewBuilder()
+
+	// The state is pushed with the same encoding CATPUSHDATA
+	// uses, so the program can find its own body, after the
+	// state, and append it to the next state.
+	builder.AddData(vm.Int64Bytes(int64(v.WindowStartMS)))
+	builder.AddData(vm.Int64Bytes(int64(v.Spent))) // stack is now [... AMOUNT N SIG... PREDICATE W S]
+
+	// The time range must be no longer than a period.
+	builder.AddOp(vm.OP_MAXTIME).AddOp(vm.OP_MINTIME).AddOp(vm.OP_SUB)
+	builder.AddInt64(int64(v.PeriodMS)).AddOp(vm.OP_LESSTHANOREQUAL).AddOp(vm.OP_VERIFY)
+
+	// Stash the program's body.
+	builder.AddInt64(0).AddInt64(2).AddOp(vm.OP_PICK).AddOp(vm.OP_CATPUSHDATA)
+	builder.AddInt64(1).AddOp(vm.OP_PICK).AddOp(vm.OP_CATPUSHDATA)                // stack is now [... W S STATE]
+	builder.AddOp(vm.OP_SIZE).AddOp(vm.OP_PROGRAM).AddOp(vm.OP_SIZE)              // stack is now [... W S STATE STATELEN PROG PROGLEN]
+	builder.AddOp(vm.OP_ROT).AddOp(vm.OP_SUB).AddOp(vm.OP_RIGHT).AddOp(vm.OP_NIP) // stack is now [... W S BODY]
+	builder.AddOp(vm.OP_TOALTSTACK)
+
+	// The amount withdrawn, or zero for a top-up.
+	builder.AddInt64(amountDepth).AddOp(vm.OP_PICK)                                             // stack is now [... W S R]
+	builder.AddOp(vm.OP_AMOUNT).AddOp(vm.OP_OVER).AddOp(vm.OP_SUB).AddInt64(0).AddOp(vm.OP_MAX) // stack is now [... W S R X]
+
+	// Whether a new period starts.
+	builder.AddOp(vm.OP_MINTIME).AddInt64(4).AddOp(vm.OP_PICK).AddInt64(int64(v.PeriodMS)).AddOp(vm.OP_ADD)
+	builder.AddOp(vm.OP_GREATERTHANOREQUAL) // stack is now [... W S R X NEW]
+
+	// The next spent amount is X plus S, unless a new period
+	// starts, and must be within the limit.
+	builder.AddOp(vm.OP_DUP).AddInt64(4).AddOp(vm.OP_PICK).AddOp(vm.OP_MUL)
+	builder.AddInt64(4).AddOp(vm.OP_PICK).AddOp(vm.OP_SWAP).AddOp(vm.OP_SUB)
+	builder.AddInt64(2).AddOp(vm.OP_PICK).AddOp(vm.OP_ADD) // stack is now [... W S R X NEW S']
+	builder.AddOp(vm.OP_DUP).AddInt64(int64(v.Limit)).AddOp(vm.OP_LESSTHANOREQUAL).AddOp(vm.OP_VERIFY)
+
+	// The next period start is the mintime if a new period
+	// starts, and W otherwise.
+	builder.AddOp(vm.OP_SWAP).AddOp(vm.OP_MINTIME).AddInt64(6).AddOp(vm.OP_PICK).AddOp(vm.OP_SUB).AddOp(vm.OP_MUL)
+	builder.AddInt64(5).AddOp(vm.OP_PICK).AddOp(vm.OP_ADD) // stack is now [... W S R X S' W']
+
+	// The next program is the next state followed by the body.
+	builder.AddInt64(0).AddOp(vm.OP_SWAP).AddOp(vm.OP_CATPUSHDATA)
+	builder.AddOp(vm.OP_SWAP).AddOp(vm.OP_CATPUSHDATA)
+	builder.AddOp(vm.OP_FROMALTSTACK).AddOp(vm.OP_CAT).AddOp(vm.OP_TOALTSTACK) // stack is now [... W S R X]
+
+	// Check the output returning R to the vault.
+	builder.AddOp(vm.OP_DROP).AddInt64(amountDepth + 2).AddOp(vm.OP_PICK).AddOp(vm.OP_SWAP) // stack is now [... W S INDEX R]
+	builder.AddOp(vm.OP_OUTPUTID).AddOp(vm.OP_SHA3).AddOp(vm.OP_SWAP)
+	builder.AddOp(vm.OP_ASSET).AddInt64(1).AddOp(vm.OP_FROMALTSTACK)
+	builder.AddOp(vm.OP_CHECKOUTPUT).AddOp(vm.OP_VERIFY)
+	builder.AddOp(vm.OP_2DROP) // stack is now [... INDEX R N SIG... PREDICATE]
+
+	builder.AddRawBytes(multisig)
+	return builder.Build()
+}
+
+// ParseVaultProgram returns the vault whose control program
+// is prog. It returns an error if prog isn't a vault program.
+func ParseVaultProgram(prog []byte) (*Vault, error) {
+	pops, err := vm.ParseProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+	if len(pops) < 6 || pops[2].Op != vm.OP_MAXTIME {
+		return nil, errors.WithDetail(ErrBadValue, "not a vault program")
+	}
+	pubkeys, quorum, err := ParseP2SPMultiSigProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+
+	// The limit is the operand of the second LESSTHANOREQUAL,
+	// and the period of the first.
+	var params []int64
+	for i := 1; i < len(pops) && len(params) < 2; i++ {
+		if pops[i].Op != vm.OP_LESSTHANOREQUAL {
+			continue
+		}
+		n, err := vm.AsInt64(pops[i-1].Data)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, n)
+	}
+	if len(params) < 2 {
+		return nil, errors.WithDetail(ErrBadValue, "not a vault program")
+	}
+	windowStart, err := vm.AsInt64(pops[0].Data)
+	if err != nil {
+		return nil, err
+	}
+	spent, err := vm.AsInt64(pops[1].Data)
+	if err != nil {
+		return nil, err
+	}
+	if params[0] < 0 || params[1] < 0 || windowStart < 0 || spent < 0 {
+		return nil, errors.WithDetail(ErrBadValue, "not a vault program")
+	}
+
+	v := &Vault{
+		PubKeys:       pubkeys,
+		Quorum:        quorum,
+		Limit:         uint64(params[1]),
+		PeriodMS:      uint64(params[0]),
+		WindowStartMS: uint64(windowStart),
+		Spent:         uint64(spent),
+	}
+	want, err := VaultProgram(v)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prog, want) {
+		return nil, errors.WithDetail(ErrBadValue, "not a vault program")
+	}
+	return v, nil
+}
+
+// Available returns how much may leave v in a transaction
+// with the given mintime. It is the whole limit once the mintime
+// is a period past the start of v's period, however recently v
+// was last spent.
+func (v *Vault) Available(minTimeMS uint64) uint64 {
+	if minTimeMS >= v.WindowStartMS+v.PeriodMS {
+		return v.Limit
+	}
+	return v.Limit - v.Spent
+}
+
+// After returns v with the state it has after a transaction with
+// the given mintime withdraws the given amount, which may be zero
+// for a top-up. It returns ErrVaultLimit if the amount is more
+// than is available.
+func (v *Vault) After(minTimeMS, withdrawn uint64) (*Vault, error) {
+	avail := v.Available(minTimeMS)
+	if withdrawn > avail {
+		return nil, errors.WithDetailf(ErrVaultLimit, "withdrawing %d with %d available", withdrawn, avail)
+	}
+	next := *v
+	if minTimeMS >= v.WindowStartMS+v.PeriodMS {
+		next.WindowStartMS = minTimeMS
+		next.Spent = 0
+	}
+	next.Spent += withdrawn
+	return &next, nil
+}
+
+// Zero returns v with a zero state. Every state of a
+// vault has the same keys, limit, and period, so they
+// identify the vault.
+func (v *Vault) Zero() *Vault {
+	zero := *v
+	zero.WindowStartMS = 0
+	zero.Spent = 0
+	return &zero
+}
@@ -0,0 +1,118 @@
+package vmutil
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	"chain/errors"
+	"chain/protocol/vm"
+)
+
+func TestVaultProgram(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &Vault{
+		PubKeys:       []ed25519.PublicKey{pub},
+		Quorum:        1,
+		Limit:         100,
+		PeriodMS:      1000,
+		WindowStartMS: 5000,
+		Spent:         30,
+	}
+	prog, err := VaultProgram(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseVaultProgram(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("ParseVaultProgram = %+v want %+v", got, v)
+	}
+
+	pred := []byte{byte(vm.OP_TRUE)}
+	var h [32]byte
+	sha3pool.Sum256(h[:], pred)
+	sig := ed25519.Sign(priv, h[:])
+
+	var (
+		amount   uint64 = 500
+		assetID         = bytes.Repeat([]byte{1}, 32)
+		outputID        = bytes.Repeat([]byte{2}, 32)
+	)
+	cases := []struct {
+		minTime, maxTime, returned uint64
+		wantErr                    bool
+	}{
+		{minTime: 5500, maxTime: 6000, returned: 430},                // 70 more in the same period
+		{minTime: 5500, maxTime: 6000, returned: 429, wantErr: true}, // 71 more is over the limit
+		{minTime: 6000, maxTime: 6500, returned: 400},                // 100 in a new period
+		{minTime: 5500, maxTime: 6000, returned: 600},                // a top-up
+		{minTime: 5500, maxTime: 7000, returned: 500, wantErr: true}, // a time range over a period
+	}
+	for i, c := range cases {
+		var gotIndex, gotAmount uint64
+		var gotData, gotProg []byte
+		context := &vm.Context{
+			VMVersion:     1,
+			Code:          prog,
+			Arguments:     [][]byte{vm.Int64Bytes(3), vm.Int64Bytes(int64(c.returned)), vm.Int64Bytes(2), sig, pred},
+			Amount:        &amount,
+			AssetID:       &assetID,
+			MinTimeMS:     &c.minTime,
+			MaxTimeMS:     &c.maxTime,
+			SpentOutputID: &outputID,
+			CheckOutput: func(index uint64, data []byte, amount uint64, asset []byte, vmVersion uint64, code []byte, expansion bool) (bool, error) {
+				gotIndex, gotData, gotAmount, gotProg = index, data, amount, code
+				return bytes.Equal(asset, assetID) && vmVersion == 1, nil
+			},
+		}
+		err := vm.Verify(context)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("case %d: got no error, want one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+
+		var withdrawn uint64
+		if c.returned < amount {
+			withdrawn = amount - c.returned
+		}
+		next, err := v.After(c.minTime, withdrawn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantProg, err := VaultProgram(next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wantData [32]byte
+		sha3pool.Sum256(wantData[:], outputID)
+		if gotIndex != 3 || gotAmount != c.returned || !bytes.Equal(gotData, wantData[:]) {
+			t.Errorf("case %d: checked output %d of %d with data %x, want output 3 of %d with data %x", i, gotIndex, gotAmount, gotData, c.returned, wantData[:])
+		}
+		if !bytes.Equal(gotProg, wantProg) {
+			t.Errorf("case %d: checked program %x, want %x", i, gotProg, wantProg)
+		}
+	}
+
+	_, err = v.After(5500, 71)
+	if errors.Root(err) != ErrVaultLimit {
+		t.Errorf("After(5500, 71) error = %v want %v", err, ErrVaultLimit)
+	}
+	_, err = ParseVaultProgram(pred)
+	if err == nil {
+		t.Error("ParseVaultProgram(TRUE) = success want error")
+	}
+}